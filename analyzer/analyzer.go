@@ -2,9 +2,11 @@ package analyzer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,30 +19,91 @@ const (
 	DefaultSelectorTimeout  = 10000.0
 	DefaultLoadStateTimeout = 15000.0
 	MaxWalletsPerScan       = 50 // Limit wallets per scan cycle
+
+	// DefaultWalletURLTemplate is used when no analyzer target is configured
+	DefaultWalletURLTemplate = "https://dexcheck.ai/app/wallet-analyzer/%s"
 )
 
 type WalletStats struct {
-	Wallet      string  `json:"wallet"`
-	Winrate     float64 `json:"winrate"`
-	RealizedPnL float64 `json:"realized_pnl"`
+	Wallet         string  `json:"wallet"`
+	Winrate        float64 `json:"winrate"`
+	RealizedPnLPct float64 `json:"realized_pnl"`     // percent return on cost
+	RealizedPnLUSD float64 `json:"realized_pnl_usd"` // absolute profit in USD
+	TradeCount     int     `json:"trade_count"`
 }
 
+// ErrBelowThreshold is returned for a wallet whose stats miss the
+// analyzer's minimum win rate or realized PnL
+var ErrBelowThreshold = errors.New("below threshold")
+
+// errNoMetrics marks a wallet whose page yielded no stats at all
+var errNoMetrics = errors.New("no metrics extracted")
+
 type Analyzer struct {
-	numPages       int
-	minWinrate     float64
-	minRealizedPnL float64
-	scannedWallets sync.Map
+	numPages         int
+	minWinrate       float64
+	minRealizedPnL   float64
+	urlTemplate      string
+	scannedWallets   sync.Map
+	onBelowThreshold func(wallet string)
+	priority         func(wallet string) int
 }
 
-func NewAnalyzer(numPages int, minWinrate, minRealizedPnL float64) *Analyzer {
+// NewAnalyzer creates an analyzer that scrapes urlTemplate, where %s is
+// replaced by the wallet address. An empty template uses DexCheck.
+func NewAnalyzer(numPages int, minWinrate, minRealizedPnL float64, urlTemplate string) *Analyzer {
+	if urlTemplate == "" {
+		urlTemplate = DefaultWalletURLTemplate
+	}
 	return &Analyzer{
 		numPages:       numPages,
 		minWinrate:     minWinrate,
 		minRealizedPnL: minRealizedPnL,
+		urlTemplate:    urlTemplate,
 	}
 }
 
+// OnBelowThreshold sets a function called with each wallet that was
+// analyzed but missed the thresholds, e.g. to skip it in later scans
+func (a *Analyzer) OnBelowThreshold(fn func(wallet string)) {
+	a.onBelowThreshold = fn
+}
+
+// SetPriority sets a function ranking wallets; AnalyzeWallets hands the
+// highest ranked wallets to its pages first and keeps them when it has to
+// limit the scan
+func (a *Analyzer) SetPriority(fn func(wallet string) int) {
+	a.priority = fn
+}
+
+// prioritize orders wallets by priority, highest first, keeping the
+// given order among equals
+func (a *Analyzer) prioritize(wallets []string) []string {
+	if a.priority == nil {
+		return wallets
+	}
+	ranks := make(map[string]int, len(wallets))
+	for _, w := range wallets {
+		ranks[w] = a.priority(w)
+	}
+	sorted := append([]string(nil), wallets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return ranks[sorted[i]] > ranks[sorted[j]]
+	})
+	return sorted
+}
+
+// WalletURL builds the analyzer page URL for a wallet. Only the %s
+// placeholder is substituted, so other percent signs in the template
+// (e.g. URL escapes) are kept as-is.
+func WalletURL(template, wallet string) string {
+	return strings.Replace(template, "%s", wallet, 1)
+}
+
 func (a *Analyzer) AnalyzeWallets(ctx context.Context, wallets []string, onResult func(*WalletStats)) ([]WalletStats, error) {
+	// The pages take wallets from the channel in priority order
+	wallets = a.prioritize(wallets)
+
 	// Limit wallets to process
 	if len(wallets) > MaxWalletsPerScan {
 		log.Printf("⚠️ Limiting scan to %d wallets (requested %d)", MaxWalletsPerScan, len(wallets))
@@ -106,6 +169,9 @@ func (a *Analyzer) AnalyzeWallets(ctx context.Context, wallets []string, onResul
 						}
 					} else {
 						log.Printf("❌ Worker %d: Error analyzing %s: %v", workerID, wallet, err)
+						if errors.Is(err, ErrBelowThreshold) && a.onBelowThreshold != nil {
+							a.onBelowThreshold(wallet)
+						}
 					}
 					continue
 				}
@@ -118,7 +184,7 @@ func (a *Analyzer) AnalyzeWallets(ctx context.Context, wallets []string, onResul
 					if onResult != nil {
 						onResult(stats)
 					}
-					log.Printf("✅ Worker %d: %s - WR: %.2f%%, PnL: %.2f%%", workerID, wallet[:8], stats.Winrate, stats.RealizedPnL)
+					log.Printf("✅ Worker %d: %.8s - WR: %.2f%%, PnL: %.2f%% ($%.2f)", workerID, wallet, stats.Winrate, stats.RealizedPnLPct, stats.RealizedPnLUSD)
 				}
 			}
 		}(i)
@@ -135,8 +201,8 @@ func (a *Analyzer) analyzeSingleWallet(ctx context.Context, page playwright.Page
 		return nil, ctx.Err()
 	}
 
-	// Navigate to DexCheck
-	url := fmt.Sprintf("https://dexcheck.ai/app/wallet-analyzer/%s", wallet)
+	// Navigate to the analyzer page
+	url := WalletURL(a.urlTemplate, wallet)
 	if _, err := page.Goto(url, playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
 		Timeout:   playwright.Float(DefaultPageTimeout),
@@ -186,35 +252,33 @@ func (a *Analyzer) analyzeSingleWallet(ctx context.Context, page playwright.Page
 
 	// Extract WR and PnL using the helper functions
 	winrate := extractWinrate(html)
-	realizedPnL := extractRealizedPnL(html)
+	realizedPnL, realizedPnLUSD := extractRealizedPnL(html)
 
-	// Observability for 0 values
+	// Observability for 0 values. Zero metrics usually mean the page
+	// didn't parse, so the wallet isn't known to be below threshold.
+	belowErr := ErrBelowThreshold
 	if winrate == 0 && realizedPnL == 0 {
 		log.Printf("⚠️ Worker: Zero metrics extracted for %s. HTML snippet: %s", wallet, html[:min(len(html), 200)])
+		belowErr = errNoMetrics
 	}
 
 	// Check if wallet meets the minimum criteria
 	if winrate < a.minWinrate {
-		return nil, fmt.Errorf("winrate %.2f%% below minimum %.2f%%", winrate, a.minWinrate)
+		return nil, fmt.Errorf("%w: winrate %.2f%% below minimum %.2f%%", belowErr, winrate, a.minWinrate)
 	}
 	if realizedPnL < a.minRealizedPnL {
-		return nil, fmt.Errorf("realized PnL %.2f%% below minimum %.2f%%", realizedPnL, a.minRealizedPnL)
+		return nil, fmt.Errorf("%w: realized PnL %.2f%% below minimum %.2f%%", belowErr, realizedPnL, a.minRealizedPnL)
 	}
 
 	return &WalletStats{
-		Wallet:      wallet,
-		Winrate:     winrate,
-		RealizedPnL: realizedPnL,
+		Wallet:         wallet,
+		Winrate:        winrate,
+		RealizedPnLPct: realizedPnL,
+		RealizedPnLUSD: realizedPnLUSD,
+		TradeCount:     extractTradeCount(html),
 	}, nil
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 func extractWinrate(html string) float64 {
 	// Match: <h3...>Win Rate</h3><p class="...text-2xl...">XX.XX%</p>
 	re := regexp.MustCompile(`(?i)Win Rate</h3><p[^>]*text-2xl[^>]*>([\d\.]+)%`)
@@ -229,17 +293,31 @@ func extractWinrate(html string) float64 {
 	return 0
 }
 
-func extractRealizedPnL(html string) float64 {
+// pnlUSDMultipliers expands abbreviated dollar amounts such as $1.2K
+var pnlUSDMultipliers = map[string]float64{"": 1, "K": 1e3, "M": 1e6, "B": 1e9}
+
+// extractRealizedPnL returns the realized PnL as a percentage and as an
+// absolute USD amount
+func extractRealizedPnL(html string) (pct, usd float64) {
 	// Match: Realized</p><p...>$XXX <span...>(Y.YY%)</span> or (-Y.YY%)
-	// Handles -$XXX and $XXX
-	re := regexp.MustCompile(`(?i)Realized</p><p[^>]*>-?\$[\d,\.]+\s*<span[^>]*>\((-?[\d\.]+)%\)</span>`)
-	if matches := re.FindStringSubmatch(html); len(matches) > 1 {
-		val, err := strconv.ParseFloat(matches[1], 64)
+	// Handles -$XXX and $XXX, with optional K/M/B suffix
+	re := regexp.MustCompile(`(?i)Realized</p><p[^>]*>(-?)\$([\d,\.]+)([KMB]?)\s*<span[^>]*>\((-?[\d\.]+)%\)</span>`)
+	if matches := re.FindStringSubmatch(html); len(matches) > 4 {
+		pct, err := strconv.ParseFloat(matches[4], 64)
 		if err != nil {
-			log.Printf("⚠️ Failed to parse PnL value '%s': %v", matches[1], err)
-			return 0
+			log.Printf("⚠️ Failed to parse PnL value '%s': %v", matches[4], err)
+			return 0, 0
 		}
-		return val
+		usd, err := strconv.ParseFloat(strings.ReplaceAll(matches[2], ",", ""), 64)
+		if err != nil {
+			log.Printf("⚠️ Failed to parse PnL USD value '%s': %v", matches[2], err)
+			return pct, 0
+		}
+		usd *= pnlUSDMultipliers[strings.ToUpper(matches[3])]
+		if matches[1] == "-" {
+			usd = -usd
+		}
+		return pct, usd
 	}
 
 	// Debug logging for missed PnL
@@ -250,5 +328,19 @@ func extractRealizedPnL(html string) float64 {
 			fmt.Printf("⚠️ PnL Missed: %s\n", match)
 		}
 	}
+	return 0, 0
+}
+
+func extractTradeCount(html string) int {
+	// Match: <h3...>Total Trades</h3><p...>1,234</p>
+	re := regexp.MustCompile(`(?i)Trades</h3><p[^>]*>([\d,]+)<`)
+	if matches := re.FindStringSubmatch(html); len(matches) > 1 {
+		val, err := strconv.Atoi(strings.ReplaceAll(matches[1], ",", ""))
+		if err != nil {
+			log.Printf("⚠️ Failed to parse trade count '%s': %v", matches[1], err)
+			return 0
+		}
+		return val
+	}
 	return 0
 }
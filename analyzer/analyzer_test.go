@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"slices"
 	"testing"
 )
 
@@ -49,52 +50,60 @@ func TestExtractWinrate(t *testing.T) {
 
 func TestExtractRealizedPnL(t *testing.T) {
 	tests := []struct {
-		name     string
-		html     string
-		expected float64
+		name        string
+		html        string
+		expected    float64
+		expectedUSD float64
 	}{
 		{
-			name:     "Positive PnL",
-			html:     `<p>Realized</p><p>$1,234.56 <span class="text-green-500">(245.80%)</span></p>`,
-			expected: 245.80,
+			name:        "Positive PnL",
+			html:        `<p>Realized</p><p>$1,234.56 <span class="text-green-500">(245.80%)</span></p>`,
+			expected:    245.80,
+			expectedUSD: 1234.56,
 		},
 		{
-			name:     "Negative PnL",
-			html:     `<p>Realized</p><p>-$500.00 <span class="text-red-500">(-25.50%)</span></p>`,
-			expected: -25.50,
+			name:        "Negative PnL",
+			html:        `<p>Realized</p><p>-$500.00 <span class="text-red-500">(-25.50%)</span></p>`,
+			expected:    -25.50,
+			expectedUSD: -500.00,
 		},
 		{
-			name:     "Large positive PnL",
-			html:     `<p>Realized</p><p>$10,000.00 <span>(1500.75%)</span></p>`,
-			expected: 1500.75,
+			name:        "Large positive PnL",
+			html:        `<p>Realized</p><p>$10,000.00 <span>(1500.75%)</span></p>`,
+			expected:    1500.75,
+			expectedUSD: 10000.00,
 		},
 		{
-			name:     "Small positive PnL",
-			html:     `<p>Realized</p><p>$50.25 <span>(5.25%)</span></p>`,
-			expected: 5.25,
+			name:        "Small positive PnL",
+			html:        `<p>Realized</p><p>$50.25 <span>(5.25%)</span></p>`,
+			expected:    5.25,
+			expectedUSD: 50.25,
 		},
 		{
-			name:     "No PnL found",
-			html:     `<div><p>Other Metric</p><p>$100</p></div>`,
-			expected: 0,
+			name:        "No PnL found",
+			html:        `<div><p>Other Metric</p><p>$100</p></div>`,
+			expected:    0,
+			expectedUSD: 0,
 		},
 		{
-			name:     "Zero PnL",
-			html:     `<p>Realized</p><p>$0.00 <span>(0.00%)</span></p>`,
-			expected: 0.00,
+			name:        "Zero PnL",
+			html:        `<p>Realized</p><p>$0.00 <span>(0.00%)</span></p>`,
+			expected:    0.00,
+			expectedUSD: 0,
 		},
 		{
-			name:     "PnL with decimal precision",
-			html:     `<p>Realized</p><p>$2,567.89 <span class="text-green-600">(356.123%)</span></p>`,
-			expected: 356.123,
+			name:        "PnL with decimal precision",
+			html:        `<p>Realized</p><p>$2,567.89 <span class="text-green-600">(356.123%)</span></p>`,
+			expected:    356.123,
+			expectedUSD: 2567.89,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := extractRealizedPnL(tt.html)
-			if result != tt.expected {
-				t.Errorf("extractRealizedPnL() = %v, want %v", result, tt.expected)
+			pct, usd := extractRealizedPnL(tt.html)
+			if pct != tt.expected || usd != tt.expectedUSD {
+				t.Errorf("extractRealizedPnL() = %v, $%v, want %v, $%v", pct, usd, tt.expected, tt.expectedUSD)
 			}
 		})
 	}
@@ -134,39 +143,150 @@ func TestExtractWinrateEdgeCases(t *testing.T) {
 }
 
 func TestExtractRealizedPnLEdgeCases(t *testing.T) {
+	tests := []struct {
+		name        string
+		html        string
+		expected    float64
+		expectedUSD float64
+	}{
+		{
+			name:        "Case insensitive Realized",
+			html:        `<p>realized</p><p>$100.00 <span>(50.00%)</span></p>`,
+			expected:    50.00,
+			expectedUSD: 100.00,
+		},
+		{
+			name:        "Multiple occurrences - should match first",
+			html:        `<p>Realized</p><p>$100 <span>(50%)</span></p><p>Realized</p><p>$200 <span>(100%)</span></p>`,
+			expected:    50.00,
+			expectedUSD: 100.00,
+		},
+		{
+			name:        "Empty string",
+			html:        ``,
+			expected:    0,
+			expectedUSD: 0,
+		},
+		{
+			name:        "Large dollar PnL with thousands separators",
+			html:        `<p>Realized</p><p>$1,234,567.89 <span>(812.40%)</span></p>`,
+			expected:    812.40,
+			expectedUSD: 1234567.89,
+		},
+		{
+			name:        "Abbreviated dollar PnL",
+			html:        `<p>Realized</p><p>$2.5M <span>(340.00%)</span></p>`,
+			expected:    340.00,
+			expectedUSD: 2500000,
+		},
+		{
+			name:        "Abbreviated negative dollar PnL",
+			html:        `<p>Realized</p><p>-$12.4K <span>(-61.20%)</span></p>`,
+			expected:    -61.20,
+			expectedUSD: -12400,
+		},
+		{
+			name:        "Large negative PnL",
+			html:        `<p>Realized</p><p>-$5,000.00 <span class="text-red-600">(-99.99%)</span></p>`,
+			expected:    -99.99,
+			expectedUSD: -5000.00,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pct, usd := extractRealizedPnL(tt.html)
+			if pct != tt.expected || usd != tt.expectedUSD {
+				t.Errorf("extractRealizedPnL() = %v, $%v, want %v, $%v", pct, usd, tt.expected, tt.expectedUSD)
+			}
+		})
+	}
+}
+
+func TestExtractTradeCount(t *testing.T) {
 	tests := []struct {
 		name     string
 		html     string
-		expected float64
+		expected int
 	}{
 		{
-			name:     "Case insensitive Realized",
-			html:     `<p>realized</p><p>$100.00 <span>(50.00%)</span></p>`,
-			expected: 50.00,
+			name:     "Valid trade count",
+			html:     `<h3 class="text-sm">Total Trades</h3><p class="text-2xl">142</p>`,
+			expected: 142,
 		},
 		{
-			name:     "Multiple occurrences - should match first",
-			html:     `<p>Realized</p><p>$100 <span>(50%)</span></p><p>Realized</p><p>$200 <span>(100%)</span></p>`,
-			expected: 50.00,
+			name:     "Trade count with separator",
+			html:     `<h3>Trades</h3><p class="text-2xl">1,234</p>`,
+			expected: 1234,
 		},
 		{
-			name:     "Empty string",
-			html:     ``,
+			name:     "No trade count found",
+			html:     `<h3>Win Rate</h3><p class="text-2xl">50%</p>`,
 			expected: 0,
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := extractTradeCount(tt.html)
+			if result != tt.expected {
+				t.Errorf("extractTradeCount() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWalletURL(t *testing.T) {
+	const wallet = "7xKXtg2CW87d97TXJSDpbD5jBkheTqA83TZRuJosgAsU"
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "DexCheck default",
+			template: DefaultWalletURLTemplate,
+			expected: "https://dexcheck.ai/app/wallet-analyzer/" + wallet,
+		},
+		{
+			name:     "Placeholder in query",
+			template: "http://localhost:8080/cache?wallet=%s&fresh=1",
+			expected: "http://localhost:8080/cache?wallet=" + wallet + "&fresh=1",
+		},
 		{
-			name:     "Large negative PnL",
-			html:     `<p>Realized</p><p>-$5,000.00 <span class="text-red-600">(-99.99%)</span></p>`,
-			expected: -99.99,
+			name:     "Escapes kept",
+			template: "https://mirror.example/a%20b/%s",
+			expected: "https://mirror.example/a%20b/" + wallet,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := extractRealizedPnL(tt.html)
-			if result != tt.expected {
-				t.Errorf("extractRealizedPnL() = %v, want %v", result, tt.expected)
+			if result := WalletURL(tt.template, wallet); result != tt.expected {
+				t.Errorf("WalletURL() = %v, want %v", result, tt.expected)
 			}
 		})
 	}
+
+	if a := NewAnalyzer(1, 0, 0, ""); a.urlTemplate != DefaultWalletURLTemplate {
+		t.Errorf("Expected empty template to fall back to DexCheck, got %q", a.urlTemplate)
+	}
+}
+
+func TestPrioritize(t *testing.T) {
+	wallets := []string{"a", "b", "c", "d", "e"}
+
+	a := NewAnalyzer(1, 0, 0, "")
+	if got := a.prioritize(wallets); !slices.Equal(got, wallets) {
+		t.Errorf("Expected the given order without a priority, got %v", got)
+	}
+
+	ranks := map[string]int{"c": 2, "d": 1, "e": 2}
+	a.SetPriority(func(wallet string) int { return ranks[wallet] })
+	if got, want := a.prioritize(wallets), []string{"c", "e", "d", "a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if wallets[0] != "a" {
+		t.Error("Expected prioritize to leave the caller's slice alone")
+	}
 }
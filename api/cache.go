@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenCachePrefix namespaces cached token lists in Redis
+const tokenCachePrefix = "api:tokens:"
+
+// ResponseCache keeps encoded API results for a short time. Scan cycles
+// fetch largely the same token lists every time, so reusing a recent
+// list saves quota.
+type ResponseCache interface {
+	// Get returns the value cached under key and whether it was fresh
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// memoryCache is a ResponseCache local to this process
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache returns a ResponseCache held in memory
+func NewMemoryCache() ResponseCache {
+	return newMemoryCache()
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || !m.now().Before(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (m *memoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	// Only a few token list queries exist, so pruning on write is cheap
+	for k, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+	m.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+}
+
+// redisCache shares cached results across instances through Redis. While
+// Redis is unreachable it falls back to memory.
+type redisCache struct {
+	rdb      *redis.Client
+	fallback *memoryCache
+}
+
+// NewRedisCache returns a ResponseCache stored in Redis, or in memory if
+// rdb is nil
+func NewRedisCache(rdb *redis.Client) ResponseCache {
+	if rdb == nil {
+		return newMemoryCache()
+	}
+	return &redisCache{rdb: rdb, fallback: newMemoryCache()}
+}
+
+func (r *redisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := r.rdb.Get(ctx, key).Bytes()
+	if err == nil {
+		return value, true
+	}
+	if !errors.Is(err, redis.Nil) {
+		debugf("cache get %s: %v", key, err)
+	}
+	return r.fallback.Get(ctx, key)
+}
+
+func (r *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if err := r.rdb.Set(ctx, key, value, ttl).Err(); err != nil {
+		debugf("cache set %s: %v", key, err)
+		r.fallback.Set(ctx, key, value, ttl)
+	}
+}
+
+type bypassCacheKey struct{}
+
+// WithoutCache returns a context whose token list fetches skip cached
+// results, e.g. for a manual refresh. What they fetch is still cached.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
+
+// SetCache makes the client reuse token lists fetched within ttl. A nil
+// cache or non-positive ttl disables caching.
+func (c *Client) SetCache(cache ResponseCache, ttl time.Duration) {
+	c.cache = cache
+	c.cacheTTL = ttl
+}
+
+// cachedTokens returns the token list cached for url, or calls fetch and
+// caches what it returns. Empty lists aren't cached, as they usually
+// mean the API had nothing to give right then.
+func (c *Client) cachedTokens(ctx context.Context, url string, fetch func() ([]Token, error)) ([]Token, error) {
+	if c.cache == nil || c.cacheTTL <= 0 {
+		return fetch()
+	}
+
+	key := tokenCachePrefix + url
+	if !cacheBypassed(ctx) {
+		if data, ok := c.cache.Get(ctx, key); ok {
+			var tokens []Token
+			if err := json.Unmarshal(data, &tokens); err == nil {
+				debugf("token list cache hit: %s", url)
+				return tokens, nil
+			}
+		}
+	}
+
+	tokens, err := fetch()
+	if err != nil || len(tokens) == 0 {
+		return tokens, err
+	}
+	if data, err := json.Marshal(tokens); err == nil {
+		c.cache.Set(ctx, key, data, c.cacheTTL)
+	}
+	return tokens, nil
+}
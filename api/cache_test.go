@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestTokenListCache(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"success": true, "data": {"tokens": [{"address": "token1", "liquidity": 250000}]}}`))
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1700000000, 0)
+	cache := newMemoryCache()
+	cache.now = func() time.Time { return now }
+
+	client := NewClient("key", "key", 0, nil)
+	client.SetBaseURLs("", srv.URL)
+	client.SetCache(cache, 5*time.Minute)
+	ctx := context.Background()
+
+	fetch := func(ctx context.Context, limit int) []Token {
+		t.Helper()
+		tokens, err := client.FetchBirdeyeTokens(ctx, limit)
+		if err != nil {
+			t.Fatalf("FetchBirdeyeTokens failed: %v", err)
+		}
+		if len(tokens) != 1 || tokens[0].TokenAddress != "token1" || tokens[0].Liquidity == nil || *tokens[0].Liquidity != 250000 {
+			t.Fatalf("Unexpected tokens: %+v", tokens)
+		}
+		return tokens
+	}
+
+	fetch(ctx, 30)
+	fetch(ctx, 30)
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected the second fetch served from cache, got %d requests", n)
+	}
+
+	// Other query parameters are cached separately
+	fetch(ctx, 50)
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Expected a request for a new limit, got %d requests", n)
+	}
+
+	// A manual refresh skips the cache
+	fetch(WithoutCache(ctx), 30)
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected a bypassing fetch to hit the API, got %d requests", n)
+	}
+
+	now = now.Add(5 * time.Minute)
+	fetch(ctx, 30)
+	if n := requests.Load(); n != 4 {
+		t.Errorf("Expected an expired list refetched, got %d requests", n)
+	}
+
+	// Without a TTL every fetch hits the API
+	client.SetCache(cache, 0)
+	fetch(ctx, 30)
+	if n := requests.Load(); n != 5 {
+		t.Errorf("Expected caching disabled, got %d requests", n)
+	}
+}
+
+func TestRedisCacheFallsBackToMemory(t *testing.T) {
+	// Nothing listens on port 1
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer rdb.Close()
+	cache := NewRedisCache(rdb)
+	ctx := context.Background()
+
+	if _, ok := cache.Get(ctx, "key"); ok {
+		t.Fatal("Expected a miss on an empty cache")
+	}
+	cache.Set(ctx, "key", []byte("value"), time.Minute)
+	if value, ok := cache.Get(ctx, "key"); !ok || string(value) != "value" {
+		t.Errorf("Expected the value from the memory fallback, got %q, %v", value, ok)
+	}
+
+	if _, ok := NewRedisCache(nil).(*memoryCache); !ok {
+		t.Error("Expected a memory cache without Redis")
+	}
+}
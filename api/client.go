@@ -5,15 +5,34 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"math/rand"
+	"net"
 	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Token struct {
-	TokenAddress string `json:"tokenAddress"`
+	TokenAddress string   `json:"tokenAddress"`
+	Liquidity    *float64 `json:"liquidity,omitempty"`    // USD, nil if the source didn't report it
+	Volume24hUSD *float64 `json:"volume24hUSD,omitempty"` // nil if the source didn't report it
+}
+
+// BirdeyeTokenQuery selects the tokens FetchBirdeyeTokens asks Birdeye for
+type BirdeyeTokenQuery struct {
+	SortBy       string  // liquidity, v24hUSD, mc or v24hChangePercent, descending
+	MinLiquidity float64 // USD; 0 leaves the bound out
+	MaxLiquidity float64 // USD; 0 leaves the bound out
+	MinVolume    float64 // 24h USD, applied to the response as the API can't filter on it
 }
 
+// DefaultBirdeyeTokenQuery targets mid-sized pools by liquidity
+var DefaultBirdeyeTokenQuery = BirdeyeTokenQuery{SortBy: "liquidity", MinLiquidity: 100000, MaxLiquidity: 500000}
+
+// Holder is one of a token's top holders; Balance is in raw token units
 type Holder struct {
 	OwnerAddress string `json:"ownerAddress"`
 	Balance      string `json:"balance"`
@@ -24,36 +43,127 @@ type Client struct {
 	moralisKey      string
 	fallbackKeys    []string
 	birdeyeKey      string
+	moralisBaseURL  string
+	birdeyeBaseURL  string
+	birdeyeQuery    BirdeyeTokenQuery
+	cache           ResponseCache
+	cacheTTL        time.Duration
 	httpClient      *http.Client
 	maxRetries      int
+	retryBackoff    time.Duration // first retry waits 2x this, plus jitter up to 1x
 	currentKeyIndex int
 }
 
+// Default API endpoints, overridable with SetBaseURLs
+const (
+	DefaultMoralisBaseURL = "https://solana-gateway.moralis.io"
+	DefaultBirdeyeBaseURL = "https://public-api.birdeye.so"
+)
+
+// Connection pool tuning. Scans fetch holders and traders from a handful
+// of hosts with many goroutines at once; the default of 2 idle
+// connections per host made most of those requests dial and handshake
+// anew.
+const (
+	dialTimeout         = 10 * time.Second
+	dialKeepAlive       = 30 * time.Second
+	maxIdleConns        = 100
+	maxIdleConnsPerHost = 32
+	idleConnTimeout     = 90 * time.Second
+	tlsHandshakeTimeout = 10 * time.Second
+)
+
+// sharedTransport is the connection pool every Client uses, so clients
+// made per request still reuse connections
+var sharedTransport = newTransport()
+
+// newTransport returns an http.Transport tuned for concurrent fetching
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
 func NewClient(moralisKey, birdeyeKey string, maxRetries int, fallbackKeys []string) *Client {
 	return &Client{
 		moralisKey:      moralisKey,
 		fallbackKeys:    fallbackKeys,
 		birdeyeKey:      birdeyeKey,
-		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		moralisBaseURL:  DefaultMoralisBaseURL,
+		birdeyeBaseURL:  DefaultBirdeyeBaseURL,
+		birdeyeQuery:    DefaultBirdeyeTokenQuery,
+		httpClient:      &http.Client{Timeout: 30 * time.Second, Transport: sharedTransport},
 		maxRetries:      maxRetries,
+		retryBackoff:    time.Second,
 		currentKeyIndex: 0,
 	}
 }
 
+// SetBaseURLs points the client at other Moralis and Birdeye endpoints,
+// e.g. a test server or a caching gateway. An empty URL keeps the
+// current one.
+func (c *Client) SetBaseURLs(moralis, birdeye string) {
+	if moralis != "" {
+		c.moralisBaseURL = strings.TrimRight(moralis, "/")
+	}
+	if birdeye != "" {
+		c.birdeyeBaseURL = strings.TrimRight(birdeye, "/")
+	}
+}
+
+// SetBirdeyeTokenQuery changes which tokens FetchBirdeyeTokens asks for.
+// An empty SortBy keeps sorting by liquidity.
+func (c *Client) SetBirdeyeTokenQuery(q BirdeyeTokenQuery) {
+	if q.SortBy == "" {
+		q.SortBy = DefaultBirdeyeTokenQuery.SortBy
+	}
+	c.birdeyeQuery = q
+}
+
+// Close releases idle connections. The pool is shared, so only call it
+// once no client is needed for a while, e.g. on shutdown; clients keep
+// working afterwards and redial as needed.
+func (c *Client) Close() {
+	c.httpClient.CloseIdleConnections()
+}
+
 // DoRequest performs an HTTP request with retries and context cancellation
 func (c *Client) DoRequest(ctx context.Context, req *http.Request) ([]byte, error) {
+	body, _, err := c.doRequest(ctx, req)
+	return body, err
+}
+
+// doRequest is DoRequest that also returns the final HTTP status code
+func (c *Client) doRequest(ctx context.Context, req *http.Request) ([]byte, int, error) {
 	var lastErr error
 
 	for attempt := 0; attempt <= c.maxRetries; attempt++ {
 		if attempt > 0 {
 			// Exponential backoff with jitter
-			backoff := time.Duration(1<<attempt) * time.Second
-			jitter := time.Duration(rand.Intn(1000)) * time.Millisecond
+			backoff := time.Duration(1<<attempt) * c.retryBackoff
+			jitter := time.Duration(rand.Int63n(int64(c.retryBackoff) + 1))
 			select {
 			case <-ctx.Done():
-				return nil, ctx.Err()
+				return nil, 0, ctx.Err()
 			case <-time.After(backoff + jitter):
 			}
+
+			// Resend the body, which the last attempt consumed
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, 0, err
+				}
+				req.Body = body
+			}
 		}
 
 		// Attach context to request
@@ -73,7 +183,7 @@ func (c *Client) DoRequest(ctx context.Context, req *http.Request) ([]byte, erro
 		}
 
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return body, nil
+			return body, resp.StatusCode, nil
 		}
 
 		if resp.StatusCode == 429 || resp.StatusCode >= 500 {
@@ -81,21 +191,53 @@ func (c *Client) DoRequest(ctx context.Context, req *http.Request) ([]byte, erro
 			continue
 		}
 
-		return nil, fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
+		return nil, resp.StatusCode, fmt.Errorf("API error: %d - %s", resp.StatusCode, snippet(body))
 	}
 
-	return nil, fmt.Errorf("max retries exceeded: %v", lastErr)
+	return nil, 0, fmt.Errorf("max retries exceeded: %v", lastErr)
 }
 
+// FetchBirdeyeTokens returns Birdeye's token list for the client's
+// BirdeyeTokenQuery, reusing a cached list if the client has a cache
 func (c *Client) FetchBirdeyeTokens(ctx context.Context, limit int) ([]Token, error) {
-	url := fmt.Sprintf("https://public-api.birdeye.so/defi/tokenlist?sort_by=liquidity&sort_type=desc&offset=0&limit=%d&min_liquidity=100000&max_liquidity=500000", limit)
+	url := c.birdeyeTokenListURL(limit)
+	tokens, err := c.cachedTokens(ctx, url, func() ([]Token, error) {
+		return c.fetchBirdeyeTokens(ctx, url)
+	})
+	if err != nil || c.birdeyeQuery.MinVolume <= 0 {
+		return tokens, err
+	}
 
+	// Tokens without a reported volume are kept, as FilterTokens does
+	kept := make([]Token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Volume24hUSD == nil || *t.Volume24hUSD >= c.birdeyeQuery.MinVolume {
+			kept = append(kept, t)
+		}
+	}
+	return kept, nil
+}
+
+// birdeyeTokenListURL builds the token list request for the client's query
+func (c *Client) birdeyeTokenListURL(limit int) string {
+	q := c.birdeyeQuery
+	url := fmt.Sprintf("%s/defi/tokenlist?sort_by=%s&sort_type=desc&offset=0&limit=%d", c.birdeyeBaseURL, neturl.QueryEscape(q.SortBy), limit)
+	if q.MinLiquidity > 0 {
+		url += "&min_liquidity=" + strconv.FormatFloat(q.MinLiquidity, 'f', -1, 64)
+	}
+	if q.MaxLiquidity > 0 {
+		url += "&max_liquidity=" + strconv.FormatFloat(q.MaxLiquidity, 'f', -1, 64)
+	}
+	return url
+}
+
+func (c *Client) fetchBirdeyeTokens(ctx context.Context, url string) ([]Token, error) {
 	req, _ := http.NewRequest("GET", url, nil)
 	req.Header.Set("X-API-KEY", c.birdeyeKey)
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("x-chain", "solana")
 
-	body, err := c.DoRequest(ctx, req)
+	body, status, err := c.doRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -103,32 +245,45 @@ func (c *Client) FetchBirdeyeTokens(ctx context.Context, limit int) ([]Token, er
 	var result struct {
 		Success bool `json:"success"`
 		Data    struct {
-			Tokens []struct {
-				Address string `json:"address"`
-			} `json:"tokens"`
+			Tokens []json.RawMessage `json:"tokens"`
 		} `json:"data"`
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		fmt.Printf("❌ JSON Unmarshal Error: %v\nBody: %s\n", err, string(body))
+	if err := decodeResponse("birdeye tokenlist", status, body, &result); err != nil {
 		return nil, err
 	}
 
 	if !result.Success {
-		fmt.Printf("❌ Birdeye API reported failure: %s\n", string(body))
+		log.Printf("⚠️ Birdeye API reported failure (status %d)", status)
+		debugf("birdeye tokenlist: %s", snippet(body))
 	}
 
-	tokens := make([]Token, len(result.Data.Tokens))
-	for i, t := range result.Data.Tokens {
-		tokens[i] = Token{TokenAddress: t.Address}
+	items := decodeItems[struct {
+		Address   string   `json:"address"`
+		Liquidity *float64 `json:"liquidity"`
+		Volume    *float64 `json:"v24hUSD"`
+	}]("birdeye tokenlist", result.Data.Tokens)
+
+	tokens := make([]Token, 0, len(items))
+	for _, t := range items {
+		if t.Address != "" {
+			tokens = append(tokens, Token{TokenAddress: t.Address, Liquidity: t.Liquidity, Volume24hUSD: t.Volume})
+		}
 	}
 
 	return tokens, nil
 }
 
+// FetchGraduatedTokens returns tokens recently graduated from pump.fun,
+// reusing a cached list if the client has a cache
 func (c *Client) FetchGraduatedTokens(ctx context.Context, limit int) ([]Token, error) {
-	url := fmt.Sprintf("https://solana-gateway.moralis.io/token/mainnet/exchange/pumpfun/graduated?limit=%d", limit)
+	url := fmt.Sprintf("%s/token/mainnet/exchange/pumpfun/graduated?limit=%d", c.moralisBaseURL, limit)
+	return c.cachedTokens(ctx, url, func() ([]Token, error) {
+		return c.fetchGraduatedTokens(ctx, url)
+	})
+}
 
+func (c *Client) fetchGraduatedTokens(ctx context.Context, url string) ([]Token, error) {
 	// Try primary key
 	apiKey := c.moralisKey
 	keyName := "primary"
@@ -168,12 +323,10 @@ func (c *Client) FetchGraduatedTokens(ctx context.Context, limit int) ([]Token,
 		}
 
 		var result struct {
-			Result []struct {
-				TokenAddress string `json:"tokenAddress"`
-			} `json:"result"`
+			Result []json.RawMessage `json:"result"`
 		}
 
-		if err := json.Unmarshal(body, &result); err != nil {
+		if err := decodeResponse("moralis graduated", resp.StatusCode, body, &result); err != nil {
 			return nil, err
 		}
 
@@ -183,9 +336,17 @@ func (c *Client) FetchGraduatedTokens(ctx context.Context, limit int) ([]Token,
 			fmt.Printf("✅ Switched to Moralis %s key\n", keyName)
 		}
 
-		tokens := make([]Token, len(result.Result))
-		for i, t := range result.Result {
-			tokens[i] = Token{TokenAddress: t.TokenAddress}
+		// Moralis reports liquidity as a decimal string
+		items := decodeItems[struct {
+			TokenAddress string     `json:"tokenAddress"`
+			Liquidity    *flexFloat `json:"liquidity"`
+		}]("moralis graduated", result.Result)
+
+		tokens := make([]Token, 0, len(items))
+		for _, t := range items {
+			if t.TokenAddress != "" {
+				tokens = append(tokens, Token{TokenAddress: t.TokenAddress, Liquidity: t.Liquidity.value()})
+			}
 		}
 
 		return tokens, nil
@@ -195,14 +356,14 @@ func (c *Client) FetchGraduatedTokens(ctx context.Context, limit int) ([]Token,
 }
 
 func (c *Client) FetchTopTraders(ctx context.Context, tokenAddress string) ([]string, error) {
-	url := fmt.Sprintf("https://public-api.birdeye.so/defi/v2/tokens/top_traders?address=%s&time_frame=24h&sort_by=volume&sort_type=desc&offset=0&limit=100", tokenAddress)
+	url := fmt.Sprintf("%s/defi/v2/tokens/top_traders?address=%s&time_frame=24h&sort_by=volume&sort_type=desc&offset=0&limit=100", c.birdeyeBaseURL, tokenAddress)
 
 	req, _ := http.NewRequest("GET", url, nil)
 	req.Header.Set("X-API-KEY", c.birdeyeKey)
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("x-chain", "solana")
 
-	body, err := c.DoRequest(ctx, req)
+	body, status, err := c.doRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -210,19 +371,21 @@ func (c *Client) FetchTopTraders(ctx context.Context, tokenAddress string) ([]st
 	var result struct {
 		Success bool `json:"success"`
 		Data    struct {
-			Items []struct {
-				Owner string `json:"owner"`
-			} `json:"items"`
+			Items []json.RawMessage `json:"items"`
 		} `json:"data"`
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := decodeResponse("birdeye top_traders", status, body, &result); err != nil {
 		return nil, err
 	}
 
+	items := decodeItems[struct {
+		Owner string `json:"owner"`
+	}]("birdeye top_traders", result.Data.Items)
+
 	var traders []string
 	seen := make(map[string]bool)
-	for _, item := range result.Data.Items {
+	for _, item := range items {
 		if item.Owner != "" && !seen[item.Owner] {
 			traders = append(traders, item.Owner)
 			seen[item.Owner] = true
@@ -233,39 +396,26 @@ func (c *Client) FetchTopTraders(ctx context.Context, tokenAddress string) ([]st
 }
 
 func (c *Client) GetTokenHolders(ctx context.Context, tokenAddress string) ([]Holder, error) {
-	url := fmt.Sprintf("https://solana-gateway.moralis.io/token/mainnet/%s/top-holders?limit=100", tokenAddress)
+	url := fmt.Sprintf("%s/token/mainnet/%s/top-holders?limit=100", c.moralisBaseURL, tokenAddress)
 
 	req, _ := http.NewRequest("GET", url, nil)
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("X-API-Key", c.moralisKey)
 
-	body, err := c.DoRequest(ctx, req)
+	body, status, err := c.doRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
 	var result struct {
-		Result []struct {
-			OwnerAddress string `json:"ownerAddress"`
-			Balance      string `json:"balance"`
-			USDValue     string `json:"usdValue"`
-		} `json:"result"`
+		Result []json.RawMessage `json:"result"`
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := decodeResponse("moralis top-holders", status, body, &result); err != nil {
 		return nil, err
 	}
 
-	holders := make([]Holder, len(result.Result))
-	for i, h := range result.Result {
-		holders[i] = Holder{
-			OwnerAddress: h.OwnerAddress,
-			Balance:      h.Balance,
-			USDValue:     h.USDValue,
-		}
-	}
-
-	return holders, nil
+	return decodeItems[Holder]("moralis top-holders", result.Result), nil
 }
 
 type WalletToken struct {
@@ -279,22 +429,22 @@ type WalletToken struct {
 }
 
 func (c *Client) GetWalletTokenBalances(ctx context.Context, walletAddress string) ([]WalletToken, error) {
-	url := fmt.Sprintf("https://solana-gateway.moralis.io/account/mainnet/%s/tokens", walletAddress)
+	url := fmt.Sprintf("%s/account/mainnet/%s/tokens", c.moralisBaseURL, walletAddress)
 
 	req, _ := http.NewRequest("GET", url, nil)
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("X-API-Key", c.moralisKey)
 
-	body, err := c.DoRequest(ctx, req)
+	body, status, err := c.doRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	var result []WalletToken
-	if err := json.Unmarshal(body, &result); err != nil {
+	var raws []json.RawMessage
+	if err := decodeResponse("moralis wallet tokens", status, body, &raws); err != nil {
 		return nil, err
 	}
 
 	// Filter out spam tokens if needed, but for now return all
-	return result, nil
+	return decodeItems[WalletToken]("moralis wallet tokens", raws), nil
 }
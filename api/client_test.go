@@ -1,6 +1,15 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -39,7 +48,7 @@ func TestAPIClient(t *testing.T) {
 	// Can't test live API calls without valid keys, but we can test error handling
 	t.Run("FetchBirdeyeTokens_ErrorHandling", func(t *testing.T) {
 		// With invalid key, should get error
-		_, err := client.FetchBirdeyeTokens(10)
+		_, err := client.FetchBirdeyeTokens(context.Background(), 10)
 		if err != nil {
 			t.Logf("Expected error with test key: %v", err)
 			// This is expected
@@ -48,13 +57,13 @@ func TestAPIClient(t *testing.T) {
 
 	t.Run("InvalidTokenLimit", func(t *testing.T) {
 		// Test with limit > 50 (Birdeye max)
-		_, err := client.FetchBirdeyeTokens(100)
+		_, err := client.FetchBirdeyeTokens(context.Background(), 100)
 		if err != nil {
 			t.Logf("Large limit error: %v", err)
 		}
 
 		// Test with limit = 0
-		_, err = client.FetchBirdeyeTokens(0)
+		_, err = client.FetchBirdeyeTokens(context.Background(), 0)
 		if err != nil {
 			t.Logf("Zero limit error: %v", err)
 		}
@@ -66,7 +75,7 @@ func TestRetryLogic(t *testing.T) {
 
 	t.Run("GetTokenHolders_Retry", func(t *testing.T) {
 		// This should retry with invalid token
-		_, err := client.GetTokenHolders("InvalidTokenAddress123")
+		_, err := client.GetTokenHolders(context.Background(), "InvalidTokenAddress123")
 		if err != nil {
 			t.Logf("Expected error after retries: %v", err)
 		}
@@ -93,3 +102,288 @@ func TestFallbackKeys(t *testing.T) {
 		}
 	})
 }
+
+// stubTransport answers every request with a fixed status and body
+type stubTransport struct {
+	status int
+	body   string
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: s.status,
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func newStubClient(status int, body string) *Client {
+	client := NewClient("test_key", "test_key", 0, nil)
+	client.httpClient = &http.Client{Transport: &stubTransport{status: status, body: body}}
+	return client
+}
+
+func TestPartialResponses(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GetTokenHolders_SkipsMalformedEntries", func(t *testing.T) {
+		client := newStubClient(200, `{"result": [
+			{"ownerAddress": "holder1", "balance": "10", "usdValue": "1.5"},
+			{"ownerAddress": 42, "balance": "20"},
+			"garbage",
+			{"ownerAddress": "holder2", "balance": "30", "usdValue": "4.5"}
+		]}`)
+
+		holders, err := client.GetTokenHolders(ctx, "mint")
+		if err != nil {
+			t.Fatalf("Expected partial result, got error: %v", err)
+		}
+		if len(holders) != 2 {
+			t.Fatalf("Expected 2 valid holders, got %d", len(holders))
+		}
+		if holders[0].OwnerAddress != "holder1" || holders[1].OwnerAddress != "holder2" {
+			t.Errorf("Unexpected holders: %+v", holders)
+		}
+	})
+
+	t.Run("FetchBirdeyeTokens_SkipsMalformedEntries", func(t *testing.T) {
+		client := newStubClient(200, `{"success": true, "data": {"tokens": [
+			{"address": "token1"},
+			{"address": ["bad"]},
+			{"address": "token2"}
+		]}}`)
+
+		tokens, err := client.FetchBirdeyeTokens(ctx, 10)
+		if err != nil {
+			t.Fatalf("Expected partial result, got error: %v", err)
+		}
+		if len(tokens) != 2 || tokens[0].TokenAddress != "token1" || tokens[1].TokenAddress != "token2" {
+			t.Errorf("Unexpected tokens: %+v", tokens)
+		}
+	})
+
+	t.Run("GetWalletTokenBalances_SkipsMalformedEntries", func(t *testing.T) {
+		client := newStubClient(200, `[
+			{"tokenAddress": "mint1", "symbol": "AAA", "decimals": 6},
+			{"tokenAddress": "mint2", "decimals": "six"}
+		]`)
+
+		balances, err := client.GetWalletTokenBalances(ctx, "wallet")
+		if err != nil {
+			t.Fatalf("Expected partial result, got error: %v", err)
+		}
+		if len(balances) != 1 || balances[0].Symbol != "AAA" {
+			t.Errorf("Unexpected balances: %+v", balances)
+		}
+	})
+
+	t.Run("MalformedEnvelope_ReturnsDecodeError", func(t *testing.T) {
+		client := newStubClient(200, `<html>Bad Gateway</html>`)
+
+		_, err := client.FetchTopTraders(ctx, "mint")
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Fatalf("Expected DecodeError, got %v", err)
+		}
+		if decodeErr.StatusCode != 200 {
+			t.Errorf("Expected status 200, got %d", decodeErr.StatusCode)
+		}
+		if !strings.Contains(decodeErr.Snippet, "Bad Gateway") {
+			t.Errorf("Expected body snippet, got %q", decodeErr.Snippet)
+		}
+	})
+
+	t.Run("SnippetIsTruncated", func(t *testing.T) {
+		body := strings.Repeat("x", maxSnippetLen*2)
+		err := decodeResponse("test", 200, []byte(body), &struct{}{})
+
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Fatalf("Expected DecodeError, got %v", err)
+		}
+		if len(decodeErr.Snippet) > maxSnippetLen+3 {
+			t.Errorf("Snippet not truncated: %d bytes", len(decodeErr.Snippet))
+		}
+	})
+}
+
+// countingServer is a test server that counts the connections dialed to it
+func countingServer(tb testing.TB) (*httptest.Server, *atomic.Int64) {
+	var dials atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":[]}`))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			dials.Add(1)
+		}
+	}
+	srv.Start()
+	tb.Cleanup(srv.Close)
+	return srv, &dials
+}
+
+func TestSharedTransport(t *testing.T) {
+	a := NewClient("key", "key", 0, nil)
+	b := NewClient("key", "key", 0, nil)
+	if a.httpClient.Transport != sharedTransport || b.httpClient.Transport != sharedTransport {
+		t.Fatal("Expected every client to use the shared transport")
+	}
+
+	srv, dials := countingServer(t)
+	for _, c := range []*Client{a, b, a} {
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		if _, err := c.DoRequest(context.Background(), req); err != nil {
+			t.Fatalf("DoRequest failed: %v", err)
+		}
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("Expected sequential requests from both clients to share 1 connection, got %d", n)
+	}
+
+	// Clients keep working after Close, on a new connection
+	a.Close()
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	if _, err := b.DoRequest(context.Background(), req); err != nil {
+		t.Fatalf("DoRequest after Close failed: %v", err)
+	}
+	if n := dials.Load(); n != 2 {
+		t.Errorf("Expected a redial after Close, got %d connections", n)
+	}
+}
+
+// BenchmarkConcurrentFetch compares connection churn of the default
+// transport against the tuned one with 8 fetchers per CPU, as a scan
+// fetching holders runs. With -cpu 4 against a local plain HTTP server:
+//
+//	DefaultTransport-4   72µs/op   0.058 dials/op
+//	TunedTransport-4     63µs/op   0.001 dials/op
+//
+// The default keeps 2 idle connections per host, so about 1 in 17
+// requests dials anew; against the real APIs each of those also pays a
+// TLS handshake, which the local numbers leave out.
+func BenchmarkConcurrentFetch(b *testing.B) {
+	for _, bc := range []struct {
+		name      string
+		transport *http.Transport
+	}{
+		{"DefaultTransport", &http.Transport{}},
+		{"TunedTransport", newTransport()},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			srv, dials := countingServer(b)
+			client := NewClient("key", "key", 0, nil)
+			client.httpClient = &http.Client{Transport: bc.transport}
+			defer bc.transport.CloseIdleConnections()
+
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					req, _ := http.NewRequest("GET", srv.URL, nil)
+					if _, err := client.DoRequest(context.Background(), req); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(dials.Load())/float64(b.N), "dials/op")
+		})
+	}
+}
+
+func TestSetBaseURLs(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/moralis/account/"):
+			w.Write([]byte(`[]`))
+		case strings.HasPrefix(r.URL.Path, "/moralis/"):
+			w.Write([]byte(`{"result": []}`))
+		default:
+			w.Write([]byte(`{"success": true, "data": {}}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient("key", "key", 0, []string{})
+	if client.moralisBaseURL != DefaultMoralisBaseURL || client.birdeyeBaseURL != DefaultBirdeyeBaseURL {
+		t.Fatalf("Expected default base URLs, got %q and %q", client.moralisBaseURL, client.birdeyeBaseURL)
+	}
+	client.SetBaseURLs(srv.URL+"/moralis/", srv.URL+"/birdeye")
+
+	ctx := context.Background()
+	calls := []func() error{
+		func() error { _, err := client.FetchBirdeyeTokens(ctx, 10); return err },
+		func() error { _, err := client.FetchGraduatedTokens(ctx, 10); return err },
+		func() error { _, err := client.FetchTopTraders(ctx, "mint"); return err },
+		func() error { _, err := client.GetTokenHolders(ctx, "mint"); return err },
+		func() error { _, err := client.GetWalletTokenBalances(ctx, "wallet"); return err },
+	}
+	for _, call := range calls {
+		if err := call(); err != nil {
+			t.Fatalf("Request to the test server failed: %v", err)
+		}
+	}
+
+	want := []string{
+		"/birdeye/defi/tokenlist",
+		"/moralis/token/mainnet/exchange/pumpfun/graduated",
+		"/birdeye/defi/v2/tokens/top_traders",
+		"/moralis/token/mainnet/mint/top-holders",
+		"/moralis/account/mainnet/wallet/tokens",
+	}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected requests to %v, got %v", want, paths)
+	}
+
+	// An empty URL keeps the current one
+	client.SetBaseURLs("", "")
+	if client.moralisBaseURL != srv.URL+"/moralis" {
+		t.Errorf("Expected the Moralis URL kept, got %q", client.moralisBaseURL)
+	}
+}
+
+func TestBirdeyeTokenQuery(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"success": true, "data": {"tokens": [
+			{"address": "busy", "liquidity": 800000, "v24hUSD": 120000},
+			{"address": "quiet", "liquidity": 900000, "v24hUSD": 5000},
+			{"address": "unreported", "liquidity": 700000}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient("key", "key", 0, nil)
+	client.SetBaseURLs("", srv.URL)
+	ctx := context.Background()
+
+	if _, err := client.FetchBirdeyeTokens(ctx, 30); err != nil {
+		t.Fatalf("FetchBirdeyeTokens failed: %v", err)
+	}
+	if query.Get("sort_by") != "liquidity" || query.Get("min_liquidity") != "100000" || query.Get("max_liquidity") != "500000" {
+		t.Errorf("Expected the default query, got %v", query)
+	}
+
+	client.SetBirdeyeTokenQuery(BirdeyeTokenQuery{SortBy: "v24hUSD", MinLiquidity: 250000.5, MinVolume: 10000})
+	tokens, err := client.FetchBirdeyeTokens(ctx, 30)
+	if err != nil {
+		t.Fatalf("FetchBirdeyeTokens failed: %v", err)
+	}
+	if query.Get("sort_by") != "v24hUSD" || query.Get("min_liquidity") != "250000.5" || query.Has("max_liquidity") || query.Get("limit") != "30" {
+		t.Errorf("Expected the configured query without max_liquidity, got %v", query)
+	}
+
+	// Volume is filtered locally, keeping tokens without a reported volume
+	var got []string
+	for _, tok := range tokens {
+		got = append(got, tok.TokenAddress)
+	}
+	if strings.Join(got, " ") != "busy unreported" {
+		t.Errorf("Expected busy and unreported tokens, got %v", got)
+	}
+}
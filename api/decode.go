@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+)
+
+// maxSnippetLen caps how much of a bad response body is logged
+const maxSnippetLen = 256
+
+// debugEnabled turns on verbose response logging when API_DEBUG is set
+var debugEnabled = os.Getenv("API_DEBUG") != ""
+
+// DecodeError is returned when an API response body can't be decoded
+type DecodeError struct {
+	Endpoint   string
+	StatusCode int
+	Snippet    string
+	Err        error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s: failed to decode response (status %d): %v", e.Endpoint, e.StatusCode, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// debugf logs only when API_DEBUG is set
+func debugf(format string, args ...interface{}) {
+	if debugEnabled {
+		log.Printf("[DEBUG] "+format, args...)
+	}
+}
+
+// snippet truncates a response body for logging
+func snippet(body []byte) string {
+	if len(body) <= maxSnippetLen {
+		return string(body)
+	}
+	return string(body[:maxSnippetLen]) + "..."
+}
+
+// decodeResponse unmarshals body into v, wrapping failures in a DecodeError
+func decodeResponse(endpoint string, statusCode int, body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		s := snippet(body)
+		debugf("%s: decode failed (status %d): %v: %s", endpoint, statusCode, err, s)
+		return &DecodeError{Endpoint: endpoint, StatusCode: statusCode, Snippet: s, Err: err}
+	}
+	return nil
+}
+
+// flexFloat decodes a number that may be sent either as a JSON number or
+// as a quoted decimal string
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if s == "" {
+			return nil
+		}
+		data = []byte(s)
+	}
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+// value returns f as a *float64, keeping nil for absent fields
+func (f *flexFloat) value() *float64 {
+	if f == nil {
+		return nil
+	}
+	v := float64(*f)
+	return &v
+}
+
+// decodeItems decodes each raw array entry independently, skipping
+// malformed ones so a single bad entry doesn't discard the whole batch
+func decodeItems[T any](endpoint string, raws []json.RawMessage) []T {
+	items := make([]T, 0, len(raws))
+	skipped := 0
+	for i, raw := range raws {
+		var item T
+		if err := json.Unmarshal(raw, &item); err != nil {
+			skipped++
+			debugf("%s: skipping malformed entry %d: %v: %s", endpoint, i, err, snippet(raw))
+			continue
+		}
+		items = append(items, item)
+	}
+	if skipped > 0 {
+		log.Printf("⚠️ %s: skipped %d of %d malformed entries", endpoint, skipped, len(raws))
+	}
+	return items
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// First-seen lookup limits. getSignaturesForAddress returns newest first,
+// so finding the oldest transaction means paging back through history.
+const (
+	signaturesPageSize = 1000
+	maxSignaturePages  = 20
+)
+
+// FirstSeenStore persists first-seen timestamps between runs
+type FirstSeenStore interface {
+	GetWalletFirstSeen(wallet string) (int64, error)
+	SaveWalletFirstSeen(wallet string, firstSeen int64) error
+}
+
+// FirstSeenResolver finds when a wallet first transacted. Complete results
+// never change, so they are cached in memory and in the store
+// indefinitely.
+type FirstSeenResolver struct {
+	store FirstSeenStore
+	fetch func(ctx context.Context, wallet string) (firstSeen int64, truncated bool, err error)
+	cache sync.Map // wallet -> int64 unix seconds
+}
+
+// NewFirstSeenResolver creates a resolver that looks wallets up over RPC.
+// store may be nil to cache in memory only.
+func NewFirstSeenResolver(rpcURL string, store FirstSeenStore) *FirstSeenResolver {
+	client := rpc.New(rpcURL)
+	return &FirstSeenResolver{
+		store: store,
+		fetch: func(ctx context.Context, wallet string) (int64, bool, error) {
+			return FetchFirstSeen(ctx, client, wallet)
+		},
+	}
+}
+
+// FirstSeen returns the unix time of the wallet's first transaction, or 0
+// if the wallet has no history yet. truncated is set for wallets with more
+// history than FetchFirstSeen pages through; their time is only a lower
+// bound on their age, so it is returned but never cached as final.
+func (r *FirstSeenResolver) FirstSeen(ctx context.Context, wallet string) (firstSeen int64, truncated bool, err error) {
+	if v, ok := r.cache.Load(wallet); ok {
+		return v.(int64), false, nil
+	}
+
+	if r.store != nil {
+		if firstSeen, err := r.store.GetWalletFirstSeen(wallet); err == nil && firstSeen > 0 {
+			r.cache.Store(wallet, firstSeen)
+			return firstSeen, false, nil
+		}
+	}
+
+	firstSeen, truncated, err = r.fetch(ctx, wallet)
+	if err != nil || firstSeen == 0 || truncated {
+		// Wallets without history may transact later, and truncated
+		// lookups stopped short of the oldest signature, so only
+		// complete timestamps are cached
+		return firstSeen, truncated, err
+	}
+
+	r.cache.Store(wallet, firstSeen)
+	if r.store != nil {
+		if err := r.store.SaveWalletFirstSeen(wallet, firstSeen); err != nil {
+			log.Printf("⚠️ Failed to cache first_seen for %s: %v", wallet, err)
+		}
+	}
+	return firstSeen, false, nil
+}
+
+// FetchFirstSeen pages back through a wallet's signatures and returns the
+// block time of the oldest one. Very active wallets stop after
+// maxSignaturePages with truncated set, making the result a lower bound on
+// their age.
+func FetchFirstSeen(ctx context.Context, client *rpc.Client, wallet string) (firstSeen int64, truncated bool, err error) {
+	pubkey, err := solana.PublicKeyFromBase58(wallet)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid wallet address: %w", err)
+	}
+
+	limit := signaturesPageSize
+	opts := &rpc.GetSignaturesForAddressOpts{Limit: &limit, Commitment: rpc.CommitmentFinalized}
+	for page := 0; ; page++ {
+		sigs, err := client.GetSignaturesForAddressWithOpts(ctx, pubkey, opts)
+		if err != nil {
+			return 0, false, fmt.Errorf("getSignaturesForAddress: %w", err)
+		}
+		for _, sig := range sigs {
+			if sig.BlockTime != nil && int64(*sig.BlockTime) > 0 {
+				firstSeen = int64(*sig.BlockTime)
+			}
+		}
+		if len(sigs) < signaturesPageSize {
+			return firstSeen, false, nil
+		}
+		if page == maxSignaturePages-1 {
+			return firstSeen, true, nil
+		}
+		opts.Before = sigs[len(sigs)-1].Signature
+	}
+}
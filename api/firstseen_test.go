@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// memFirstSeenStore is an in-memory FirstSeenStore
+type memFirstSeenStore map[string]int64
+
+func (m memFirstSeenStore) GetWalletFirstSeen(wallet string) (int64, error) {
+	return m[wallet], nil
+}
+
+func (m memFirstSeenStore) SaveWalletFirstSeen(wallet string, firstSeen int64) error {
+	m[wallet] = firstSeen
+	return nil
+}
+
+func TestFirstSeenResolver(t *testing.T) {
+	store := memFirstSeenStore{"stored": 500}
+	calls := map[string]int{}
+	r := &FirstSeenResolver{
+		store: store,
+		fetch: func(ctx context.Context, wallet string) (int64, bool, error) {
+			calls[wallet]++
+			switch wallet {
+			case "fresh":
+				return 0, false, nil
+			case "broken":
+				return 0, false, errors.New("rpc down")
+			case "busy":
+				return 2000, true, nil
+			}
+			return 1000, false, nil
+		},
+	}
+	ctx := context.Background()
+
+	t.Run("FetchedOnceThenCached", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			if got, truncated, err := r.FirstSeen(ctx, "old"); err != nil || got != 1000 || truncated {
+				t.Fatalf("Expected a complete 1000, got %d, truncated %v (%v)", got, truncated, err)
+			}
+		}
+		if calls["old"] != 1 {
+			t.Errorf("Expected one RPC lookup, got %d", calls["old"])
+		}
+		if store["old"] != 1000 {
+			t.Errorf("Expected first_seen persisted to the store, got %d", store["old"])
+		}
+	})
+
+	t.Run("StoreHitSkipsRPC", func(t *testing.T) {
+		if got, _, _ := r.FirstSeen(ctx, "stored"); got != 500 || calls["stored"] != 0 {
+			t.Errorf("Expected stored value 500 without RPC, got %d after %d calls", got, calls["stored"])
+		}
+	})
+
+	t.Run("NoHistoryNotCached", func(t *testing.T) {
+		r.FirstSeen(ctx, "fresh")
+		r.FirstSeen(ctx, "fresh")
+		if calls["fresh"] != 2 {
+			t.Errorf("Expected wallets without history to be looked up again, got %d calls", calls["fresh"])
+		}
+		if _, ok := store["fresh"]; ok {
+			t.Error("Expected no stored value for a wallet without history")
+		}
+	})
+
+	t.Run("ErrorReturned", func(t *testing.T) {
+		if _, _, err := r.FirstSeen(ctx, "broken"); err == nil {
+			t.Error("Expected RPC error to be returned")
+		}
+	})
+
+	t.Run("TruncatedNotCached", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if got, truncated, _ := r.FirstSeen(ctx, "busy"); got != 2000 || !truncated {
+				t.Fatalf("Expected a truncated lower bound of 2000, got %d, truncated %v", got, truncated)
+			}
+		}
+		if calls["busy"] != 2 {
+			t.Errorf("Expected truncated lookups to be repeated, got %d calls", calls["busy"])
+		}
+		if _, ok := store["busy"]; ok {
+			t.Error("Expected no stored value for a truncated lookup")
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// TopHolderCount is how many of the largest holders count towards a
+// token's concentration
+const TopHolderCount = 10
+
+// ErrNoHolderBalances means none of a token's holders had a usable balance
+var ErrNoHolderBalances = errors.New("no holder balances")
+
+// HolderConcentration is the percentage of totalSupply held by the n
+// largest holders. Balances and totalSupply are both in raw token units;
+// holders with unreadable balances are left out.
+func HolderConcentration(holders []Holder, totalSupply uint64, n int) (float64, error) {
+	if totalSupply == 0 {
+		return 0, errors.New("total supply is zero")
+	}
+
+	balances := make([]float64, 0, len(holders))
+	for _, h := range holders {
+		balance, err := strconv.ParseFloat(h.Balance, 64)
+		if err != nil || balance < 0 {
+			continue
+		}
+		balances = append(balances, balance)
+	}
+	if len(balances) == 0 {
+		return 0, ErrNoHolderBalances
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(balances)))
+
+	var top float64
+	for _, balance := range balances[:min(n, len(balances))] {
+		top += balance
+	}
+	return min(top/float64(totalSupply)*100, 100), nil
+}
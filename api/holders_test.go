@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestHolderConcentration(t *testing.T) {
+	holders := func(balances ...string) []Holder {
+		hs := make([]Holder, len(balances))
+		for i, b := range balances {
+			hs[i] = Holder{OwnerAddress: "holder", Balance: b}
+		}
+		return hs
+	}
+
+	tests := []struct {
+		name    string
+		holders []Holder
+		supply  uint64
+		n       int
+		want    float64
+		wantErr bool
+	}{
+		{"SumsTopN", holders("100", "300", "200", "50"), 1000, 2, 50, false},
+		{"FewerThanN", holders("100", "150"), 1000, 10, 25, false},
+		{"SkipsUnreadable", holders("abc", "", "-5", "400"), 1000, 10, 40, false},
+		{"RawUnits", holders("250000000000000", "150000000000000"), 1_000_000_000_000_000, 10, 40, false},
+		{"CapsAtSupply", holders("900", "900"), 1000, 10, 100, false},
+		{"ZeroSupply", holders("100"), 0, 10, 0, true},
+		{"NoBalances", holders("n/a"), 1000, 10, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HolderConcentration(tt.holders, tt.supply, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Expected %g%%, got %g%%", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := HolderConcentration(nil, 1000, 10); !errors.Is(err, ErrNoHolderBalances) {
+		t.Errorf("Expected ErrNoHolderBalances without holders, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package api
+
+// FilterTokens drops tokens whose reported liquidity is below minLiquidity
+// USD, so holder lookups aren't spent on dead pools. Tokens without a
+// reported liquidity are kept. A minLiquidity of 0 keeps everything.
+func FilterTokens(tokens []Token, minLiquidity float64) (kept []Token, skipped int) {
+	if minLiquidity <= 0 {
+		return tokens, 0
+	}
+	kept = make([]Token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Liquidity != nil && *t.Liquidity < minLiquidity {
+			skipped++
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept, skipped
+}
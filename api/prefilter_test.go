@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFilterTokens(t *testing.T) {
+	liq := func(v float64) *float64 { return &v }
+	tokens := []Token{
+		{TokenAddress: "deep", Liquidity: liq(250000)},
+		{TokenAddress: "dead", Liquidity: liq(12)},
+		{TokenAddress: "unknown"},
+		{TokenAddress: "edge", Liquidity: liq(5000)},
+	}
+
+	tests := []struct {
+		name    string
+		min     float64
+		kept    []string
+		skipped int
+	}{
+		{"Disabled", 0, []string{"deep", "dead", "unknown", "edge"}, 0},
+		{"DropsShallowPools", 5000, []string{"deep", "unknown", "edge"}, 1},
+		{"KeepsUnreported", 1e6, []string{"unknown"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, skipped := FilterTokens(tokens, tt.min)
+			if skipped != tt.skipped || len(kept) != len(tt.kept) {
+				t.Fatalf("Expected %d kept / %d skipped, got %d / %d", len(tt.kept), tt.skipped, len(kept), skipped)
+			}
+			for i, want := range tt.kept {
+				if kept[i].TokenAddress != want {
+					t.Errorf("Expected %s at %d, got %s", want, i, kept[i].TokenAddress)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenLiquidity(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Birdeye", func(t *testing.T) {
+		client := newStubClient(200, `{"success": true, "data": {"tokens": [
+			{"address": "token1", "liquidity": 150000.5},
+			{"address": "token2"}
+		]}}`)
+
+		tokens, err := client.FetchBirdeyeTokens(ctx, 10)
+		if err != nil {
+			t.Fatalf("FetchBirdeyeTokens failed: %v", err)
+		}
+		if len(tokens) != 2 || tokens[0].Liquidity == nil || *tokens[0].Liquidity != 150000.5 || tokens[1].Liquidity != nil {
+			t.Errorf("Unexpected tokens: %+v", tokens)
+		}
+	})
+
+	t.Run("MoralisStringLiquidity", func(t *testing.T) {
+		client := newStubClient(200, `{"result": [
+			{"tokenAddress": "token1", "liquidity": "8123.25"},
+			{"tokenAddress": "token2", "liquidity": 42},
+			{"tokenAddress": "token3"}
+		]}`)
+
+		tokens, err := client.FetchGraduatedTokens(ctx, 10)
+		if err != nil {
+			t.Fatalf("FetchGraduatedTokens failed: %v", err)
+		}
+		if len(tokens) != 3 {
+			t.Fatalf("Expected 3 tokens, got %d", len(tokens))
+		}
+		if tokens[0].Liquidity == nil || *tokens[0].Liquidity != 8123.25 {
+			t.Errorf("Expected string liquidity parsed, got %v", tokens[0].Liquidity)
+		}
+		if tokens[1].Liquidity == nil || *tokens[1].Liquidity != 42 {
+			t.Errorf("Expected numeric liquidity parsed, got %v", tokens[1].Liquidity)
+		}
+		if tokens[2].Liquidity != nil {
+			t.Errorf("Expected missing liquidity to stay nil, got %v", *tokens[2].Liquidity)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/gagliardetto/solana-go"
@@ -134,3 +135,19 @@ func ExtractAPIKey(wsURL string) string {
 	}
 	return ""
 }
+
+// GetRawTokenSupply returns a mint's total supply in raw token units
+func GetRawTokenSupply(ctx context.Context, rpcURL, mintAddress string) (uint64, error) {
+	mint, err := solana.PublicKeyFromBase58(mintAddress)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mint address: %w", err)
+	}
+	result, err := rpc.New(rpcURL).GetTokenSupply(ctx, mint, rpc.CommitmentFinalized)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch supply: %w", err)
+	}
+	if result == nil || result.Value == nil {
+		return 0, fmt.Errorf("no supply for %s", mintAddress)
+	}
+	return strconv.ParseUint(result.Value.Amount, 10, 64)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+// request body keyed by the webhook secret, so receivers can check a
+// payload came from this deployment
+const SignatureHeader = "X-Signature-256"
+
+// FoundWallet is the JSON payload POSTed for each wallet the scanner finds
+type FoundWallet struct {
+	Wallet         string  `json:"wallet"`
+	Winrate        float64 `json:"winrate"`
+	RealizedPnLPct float64 `json:"realized_pnl"`     // percent return on cost
+	RealizedPnLUSD float64 `json:"realized_pnl_usd"` // absolute profit in USD
+	TradeCount     int     `json:"trade_count"`
+	Timestamp      int64   `json:"timestamp"` // unix seconds the wallet was scanned
+}
+
+// Webhook pushes found wallets to an external URL, retrying 429s, 5xx
+// responses and network errors like the API calls do
+type Webhook struct {
+	url    string
+	secret string
+	client *Client
+}
+
+// NewWebhook creates a webhook posting to url, signed with secret
+func NewWebhook(url, secret string, maxRetries int) *Webhook {
+	return &Webhook{url: url, secret: secret, client: NewClient("", "", maxRetries, nil)}
+}
+
+// Send POSTs w to the webhook URL
+func (h *Webhook) Send(ctx context.Context, w FoundWallet) error {
+	body, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, SignPayload(h.secret, body))
+
+	_, err = h.client.DoRequest(ctx, req)
+	return err
+}
+
+// SignPayload returns the SignatureHeader value for body
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookSend(t *testing.T) {
+	const secret = "webhook-secret"
+	type delivery struct {
+		body      []byte
+		signature string
+	}
+	var deliveries []delivery
+	failures := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries = append(deliveries, delivery{body, r.Header.Get(SignatureHeader)})
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	hook := NewWebhook(srv.URL, secret, 2)
+	hook.client.retryBackoff = time.Millisecond
+
+	found := FoundWallet{
+		Wallet:         "7xKXtg2CW87d97TXJSDpbD5jBkheTqA83TZRuJosgAsU",
+		Winrate:        72.5,
+		RealizedPnLPct: 180,
+		RealizedPnLUSD: 12500,
+		TradeCount:     42,
+		Timestamp:      1700000000,
+	}
+	if err := hook.Send(context.Background(), found); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	// The first attempt failed, so the payload was sent twice
+	if len(deliveries) != 2 {
+		t.Fatalf("Expected a retry after the 503, got %d deliveries", len(deliveries))
+	}
+	for i, d := range deliveries {
+		var got FoundWallet
+		if err := json.Unmarshal(d.body, &got); err != nil {
+			t.Fatalf("Delivery %d isn't JSON: %v", i, err)
+		}
+		if got != found {
+			t.Errorf("Delivery %d: expected %+v, got %+v", i, found, got)
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(d.body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); d.signature != want {
+			t.Errorf("Delivery %d: expected signature %s, got %s", i, want, d.signature)
+		}
+	}
+
+	// Client errors aren't retried
+	deliveries = nil
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries = append(deliveries, delivery{})
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	if err := hook.Send(context.Background(), found); err == nil {
+		t.Error("Expected an error for a rejected payload")
+	}
+	if len(deliveries) != 1 {
+		t.Errorf("Expected 1 delivery of a rejected payload, got %d", len(deliveries))
+	}
+}
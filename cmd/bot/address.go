@@ -0,0 +1,23 @@
+package main
+
+// Addresses come from user input and API data, so they are abbreviated
+// through these helpers rather than sliced directly: a short or empty
+// value is shown whole instead of panicking.
+
+// shortAddr abbreviates an address for messages and buttons, e.g.
+// "7xKX...gAsU"
+func shortAddr(addr string) string {
+	return abbreviate(addr, "...")
+}
+
+// shortMint abbreviates a mint address for lists, e.g. "EPjF…Dt1v"
+func shortMint(mint string) string {
+	return abbreviate(mint, "…")
+}
+
+func abbreviate(s, sep string) string {
+	if len(s) <= 8 {
+		return s
+	}
+	return s[:4] + sep + s[len(s)-4:]
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestShortAddr(t *testing.T) {
+	tests := []struct {
+		addr, short, mint string
+	}{
+		{"", "", ""},
+		{"abc", "abc", "abc"},
+		{"12345678", "12345678", "12345678"},
+		{"123456789", "1234...6789", "1234…6789"},
+		{"EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", "EPjF...Dt1v", "EPjF…Dt1v"},
+	}
+	for _, tt := range tests {
+		if got := shortAddr(tt.addr); got != tt.short {
+			t.Errorf("shortAddr(%q) = %q, want %q", tt.addr, got, tt.short)
+		}
+		if got := shortMint(tt.addr); got != tt.mint {
+			t.Errorf("shortMint(%q) = %q, want %q", tt.addr, got, tt.mint)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"solana-orchestrator/storage"
 	"strconv"
 	"strings"
 	"time"
@@ -64,6 +70,11 @@ func handleAdminCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery)
 			planType := parts[2]
 			handleAdminSetPlan(bot, chatID, targetUserID, planType)
 		}
+	} else if data == "admin_restore_confirm" {
+		handleRestoreConfirm(bot, chatID)
+	} else if data == "admin_restore_cancel" {
+		cancelRestore(chatID)
+		send(bot, chatID, "❌ Restore cancelled.")
 	} else if strings.HasPrefix(data, "admin_add_credits:") {
 		targetUserID, _ := strconv.ParseInt(strings.TrimPrefix(data, "admin_add_credits:"), 10, 64)
 		sessMu.Lock()
@@ -90,6 +101,11 @@ func handleAdminInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		return
 	}
 
+	if session.State == "admin_awaiting_restore_file" {
+		handleRestoreUpload(bot, msg)
+		return
+	}
+
 	if session.State == "admin_awaiting_userid" {
 		targetUserID, err := strconv.ParseInt(text, 10, 64)
 		if err != nil {
@@ -148,11 +164,15 @@ func showUserDetails(bot *tgbotapi.BotAPI, adminChatID, targetUserID int64) {
 
 	// Format Plan Info
 	planInfo := "None"
-	if user.PlanType == "credits_1000" {
-		planInfo = fmt.Sprintf("💎 Credits (Bal: %d)", user.Credits)
-	} else if user.PlanType == "trial_3day" {
-		timeLeft := time.Until(time.Unix(user.TrialExpiresAt, 0))
-		planInfo = fmt.Sprintf("⏳ Trial (Expires in %.1fh)", timeLeft.Hours())
+	if plan := userPlan(user); plan != nil {
+		if plan.IsCredits() {
+			planInfo = fmt.Sprintf("💎 %s (Bal: %d)", plan.Name, user.Credits)
+		} else if plan.IsTrial() {
+			timeLeft := user.TrialTimeLeft(scanner.db.Now())
+			planInfo = fmt.Sprintf("⏳ %s (Expires in %.1fh)", plan.Name, timeLeft.Hours())
+		}
+	} else if user.PlanType != "" {
+		planInfo = fmt.Sprintf("Unknown (`%s`)", user.PlanType)
 	}
 
 	text := fmt.Sprintf("👤 *User Details*\n\n"+
@@ -164,15 +184,16 @@ func showUserDetails(bot *tgbotapi.BotAPI, adminChatID, targetUserID int64) {
 		time.Unix(user.JoinedAt, 0).Format("2006-01-02"),
 		planInfo)
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💎 Set 1000 Credits", fmt.Sprintf("admin_set_plan:%d:credits_1000", targetUserID)),
-			tgbotapi.NewInlineKeyboardButtonData("⏳ Set 3-Day Trial", fmt.Sprintf("admin_set_plan:%d:trial_3day", targetUserID)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("➕ Add Credits", fmt.Sprintf("admin_add_credits:%d", targetUserID)),
-		),
-	)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, plan := range globalCfg.Plans {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s Set %s", planEmoji(&plan), plan.Name), fmt.Sprintf("admin_set_plan:%d:%s", targetUserID, plan.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Add Credits", fmt.Sprintf("admin_add_credits:%d", targetUserID)),
+	))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
 	msg := tgbotapi.NewMessage(adminChatID, text)
 	msg.ParseMode = "Markdown"
@@ -182,22 +203,327 @@ func showUserDetails(bot *tgbotapi.BotAPI, adminChatID, targetUserID int64) {
 
 // handleAdminSetPlan updates the user's plan
 func handleAdminSetPlan(bot *tgbotapi.BotAPI, adminChatID, targetUserID int64, planType string) {
-	var err error
-	if planType == "credits_1000" {
-		err = scanner.db.SetUserPlan(targetUserID, "credits_1000", 1000, 0)
-		if err == nil {
-			send(bot, adminChatID, fmt.Sprintf("✅ User `%d` set to **1000 Credits** plan.", targetUserID))
+	plan := globalCfg.FindPlan(planType)
+	if plan == nil {
+		send(bot, adminChatID, fmt.Sprintf("❌ Unknown plan `%s`.", planType))
+		return
+	}
+
+	if err := activatePlan(targetUserID, plan); err != nil {
+		log.Printf("Error setting plan: %v", err)
+		send(bot, adminChatID, "❌ Error updating plan.")
+		return
+	}
+	send(bot, adminChatID, fmt.Sprintf("✅ User `%d` set to **%s** plan.", targetUserID, plan.Name))
+}
+
+// handleBackupCommand snapshots the database and sends it to the admin
+func handleBackupCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	if !isAdmin(chatID) {
+		return
+	}
+
+	backupPath := filepath.Join(os.TempDir(), fmt.Sprintf("bot-backup-%s.db", time.Now().Format("20060102-150405")))
+	defer os.Remove(backupPath)
+
+	if err := scanner.db.Backup(backupPath); err != nil {
+		log.Printf("❌ Backup failed: %v", err)
+		sendError(bot, chatID, "Backup failed. Check logs.")
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(backupPath))
+	doc.Caption = fmt.Sprintf("🗄 Database backup (%s)", time.Now().Format("2006-01-02 15:04:05"))
+	if _, err := bot.Send(doc); err != nil {
+		log.Printf("❌ Failed to send backup: %v", err)
+		sendError(bot, chatID, "Backup created but could not be sent.")
+	}
+}
+
+// handleRestoreCommand asks the admin to upload a backup file
+func handleRestoreCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	if !isAdmin(chatID) {
+		return
+	}
+
+	sessMu.Lock()
+	sessions[chatID] = &UserSession{
+		State:       "admin_awaiting_restore_file",
+		RequestedAt: time.Now().Unix(),
+	}
+	sessMu.Unlock()
+
+	send(bot, chatID, "♻️ *Restore Database*\n\nSend the backup `.db` file as a document.\n\n⚠️ The restore is applied on the next restart.")
+}
+
+// Restore upload limits. Telegram bots can't download files over 20 MB
+// anyway.
+const (
+	restoreDownloadTimeout = 2 * time.Minute
+	maxRestoreUploadBytes  = 20 << 20
+)
+
+// handleRestoreUpload downloads the uploaded backup and asks for confirmation
+func handleRestoreUpload(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	if msg.Document == nil {
+		sendWarning(bot, chatID, "Please send the backup as a document, or /menu to cancel.")
+		return
+	}
+
+	fileURL, err := bot.GetFileDirectURL(msg.Document.FileID)
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Failed to get file: %v", err))
+		return
+	}
+
+	if msg.Document.FileSize > maxRestoreUploadBytes {
+		sendError(bot, chatID, fmt.Sprintf("Backup is too large (max %d MB).", maxRestoreUploadBytes>>20))
+		return
+	}
+
+	client := &http.Client{Timeout: restoreDownloadTimeout}
+	resp, err := client.Get(fileURL)
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Failed to download file: %v", err))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		sendError(bot, chatID, fmt.Sprintf("Failed to download file: %s", resp.Status))
+		return
+	}
+
+	uploadPath := filepath.Join(os.TempDir(), fmt.Sprintf("bot-restore-%d.db", time.Now().UnixNano()))
+	f, err := os.Create(uploadPath)
+	if err != nil {
+		sendError(bot, chatID, "Failed to save upload.")
+		return
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxRestoreUploadBytes+1))
+	f.Close()
+	if err != nil {
+		os.Remove(uploadPath)
+		sendError(bot, chatID, "Failed to save upload.")
+		return
+	}
+	if n > maxRestoreUploadBytes {
+		os.Remove(uploadPath)
+		sendError(bot, chatID, fmt.Sprintf("Backup is too large (max %d MB).", maxRestoreUploadBytes>>20))
+		return
+	}
+
+	sessMu.Lock()
+	sessions[chatID] = &UserSession{
+		State:       "admin_confirm_restore",
+		RequestedAt: time.Now().Unix(),
+		TempData:    map[string]interface{}{"restore_path": uploadPath},
+	}
+	sessMu.Unlock()
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Stage Restore", "admin_restore_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "admin_restore_cancel"),
+		),
+	)
+	sendWithKeyboard(bot, chatID, fmt.Sprintf("⚠️ *Confirm Restore*\n\nFile: `%s` (%d bytes)\n\nThe current database will be replaced on the next restart. The old file is kept alongside.",
+		msg.Document.FileName, msg.Document.FileSize), keyboard)
+}
+
+// handleRestoreConfirm validates and stages the uploaded backup
+func handleRestoreConfirm(bot *tgbotapi.BotAPI, chatID int64) {
+	sessMu.Lock()
+	session, exists := sessions[chatID]
+	sessMu.Unlock()
+
+	if !exists || session.State != "admin_confirm_restore" {
+		sendError(bot, chatID, "No upload pending. Use /restore first.")
+		return
+	}
+
+	uploadPath, _ := session.TempData["restore_path"].(string)
+	defer cancelRestore(chatID)
+
+	f, err := os.Open(uploadPath)
+	if err != nil {
+		sendError(bot, chatID, "Upload expired. Use /restore again.")
+		return
+	}
+	defer f.Close()
+
+	if err := storage.StageRestore(botDBPath, f); err != nil {
+		log.Printf("❌ Restore staging failed: %v", err)
+		sendError(bot, chatID, fmt.Sprintf("Backup rejected: %v", err))
+		return
+	}
+
+	log.Printf("♻️ Admin %d staged a database restore", chatID)
+	send(bot, chatID, "✅ *Restore staged*\n\nRestart the bot to apply it.")
+}
+
+// cancelRestore clears the restore session and its uploaded file
+func cancelRestore(chatID int64) {
+	sessMu.Lock()
+	session, exists := sessions[chatID]
+	if exists && session.TempData != nil {
+		if path, ok := session.TempData["restore_path"].(string); ok {
+			os.Remove(path)
+		}
+	}
+	delete(sessions, chatID)
+	sessMu.Unlock()
+}
+
+// tradingMaintenanceMessage is shown when the kill switch blocks a trade
+const tradingMaintenanceMessage = "🛠 *Trading Paused*\n\nTrading is temporarily disabled for maintenance. Your funds are safe; please try again later."
+
+// tradingHalted reports whether the kill switch is off, telling the user
+// if so
+func tradingHalted(bot *tgbotapi.BotAPI, chatID int64) bool {
+	if killSwitch.TradingEnabled(context.Background()) {
+		return false
+	}
+	send(bot, chatID, tradingMaintenanceMessage)
+	return true
+}
+
+// handleKillSwitchCommand shows or sets the global trading flag:
+// /killswitch [on|off]. "off" halts all buys, sells, copy trades and
+// janitor cancellations; scanning is unaffected.
+func handleKillSwitchCommand(bot *tgbotapi.BotAPI, chatID int64, args string) {
+	if !isAdmin(chatID) {
+		return // Silent ignore for non-admins
+	}
+
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "":
+		status := "🟢 *ON* - trading enabled"
+		if !killSwitch.TradingEnabled(context.Background()) {
+			status = "🔴 *OFF* - trading halted"
+		}
+		send(bot, chatID, fmt.Sprintf("🛑 *Kill Switch*\n\nStatus: %s\n\nUsage: `/killswitch on|off`", status))
+		return
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		sendWarning(bot, chatID, "Usage: `/killswitch on|off`")
+		return
+	}
+
+	if err := killSwitch.SetTradingEnabled(context.Background(), enabled); err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Failed to update kill switch: %v", err))
+		return
+	}
+
+	if enabled {
+		log.Printf("🟢 Trading re-enabled by admin %d", chatID)
+		send(bot, chatID, "🟢 *Trading enabled*\n\nBuys, sells and copy trades are running again.")
+	} else {
+		log.Printf("🔴 Trading halted by admin %d", chatID)
+		send(bot, chatID, "🔴 *Trading halted*\n\nAll buys, sells and copy trades are refused until `/killswitch on`.")
+	}
+}
+
+// Scan history limits for /scanhistory
+const (
+	defaultScanHistory = 10
+	maxScanHistory     = 50
+)
+
+// handleScanHistoryCommand lists the most recent scan cycles
+func handleScanHistoryCommand(bot *tgbotapi.BotAPI, chatID int64, args string) {
+	if !isAdmin(chatID) {
+		return
+	}
+
+	limit := defaultScanHistory
+	if args = strings.TrimSpace(args); args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n <= 0 {
+			sendWarning(bot, chatID, "Usage: `/scanhistory [count]`")
+			return
+		}
+		limit = n
+	}
+	if limit > maxScanHistory {
+		limit = maxScanHistory
+	}
+
+	cycles, err := scanner.db.GetRecentScanCycles(limit)
+	if err != nil {
+		log.Printf("Error reading scan history: %v", err)
+		sendError(bot, chatID, "Failed to read scan history")
+		return
+	}
+	if len(cycles) == 0 {
+		send(bot, chatID, "📜 *Scan History*\n\nNo scan cycles recorded yet.")
+		return
+	}
+
+	message := fmt.Sprintf("📜 *Scan History* (last %d)\n\n", len(cycles))
+	for _, c := range cycles {
+		started := time.Unix(c.StartedAt, 0).Format("01-02 15:04")
+		duration := (time.Duration(c.DurationMs) * time.Millisecond).Truncate(time.Second)
+		errText := strings.ReplaceAll(c.Error, "`", "'")
+		if c.Error != "" && c.TokensFetched == 0 {
+			message += fmt.Sprintf("❌ `%s` · %s · `%s`\n", started, duration, errText)
+			continue
 		}
-	} else if planType == "trial_3day" {
-		expiresAt := time.Now().Add(3 * 24 * time.Hour).Unix()
-		err = scanner.db.SetUserPlan(targetUserID, "trial_3day", 0, expiresAt)
-		if err == nil {
-			send(bot, adminChatID, fmt.Sprintf("✅ User `%d` set to **3-Day Trial**.", targetUserID))
+		message += fmt.Sprintf("✅ `%s` · %s · %d tokens · %d scanned · %d found\n",
+			started, duration, c.TokensFetched, c.WalletsScanned, c.WalletsFound)
+		if c.Error != "" {
+			message += fmt.Sprintf("   ⚠️ `%s`\n", errText)
 		}
 	}
+	sendLong(bot, chatID, message)
+}
+
+// handleRescanCommand starts a scan cycle now instead of waiting out the
+// pause, fetching token lists fresh rather than from the cache
+func handleRescanCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	if !isAdmin(chatID) {
+		return
+	}
+
+	scanner.mu.RLock()
+	scanning := scanner.isScanning
+	scanner.mu.RUnlock()
+	if scanning {
+		sendWarning(bot, chatID, "A scan cycle is already running. Try again once it finishes.")
+		return
+	}
+
+	select {
+	case rescanRequests <- struct{}{}:
+		log.Printf("🔄 Rescan requested by admin %d", chatID)
+		send(bot, chatID, "🔄 *Rescan started*\n\nFetching fresh token lists now.")
+	default:
+		send(bot, chatID, "🔄 A rescan is already queued.")
+	}
+}
+
+// handleDeliverCommand delivers a user's pending slow scan right away:
+// /deliver <userID>
+func handleDeliverCommand(bot *tgbotapi.BotAPI, chatID int64, args string) {
+	if !isAdmin(chatID) {
+		return
+	}
 
+	userID, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
 	if err != nil {
-		log.Printf("Error setting plan: %v", err)
-		send(bot, adminChatID, "❌ Error updating plan.")
+		sendWarning(bot, chatID, "Usage: `/deliver <userID>`")
+		return
+	}
+
+	if !deliverPendingScanResults(bot, userID) {
+		send(bot, chatID, fmt.Sprintf("📭 User `%d` has no pending slow scan.", userID))
+		return
 	}
+	log.Printf("📬 Admin %d delivered pending slow scan for %d", chatID, userID)
+	send(bot, chatID, fmt.Sprintf("📬 Delivered pending slow scan to user `%d`.", userID))
 }
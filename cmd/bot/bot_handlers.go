@@ -2,13 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"solana-orchestrator/api"
 	"solana-orchestrator/config"
+	"solana-orchestrator/storage"
 	"solana-orchestrator/trading"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/gagliardetto/solana-go"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -48,11 +49,11 @@ func handleBalanceCommand(bot *tgbotapi.BotAPI, chatID int64) {
 	}
 
 	// Initialize API client
-	apiClient := api.NewClient(cfg.MoralisAPIKey, cfg.BirdeyeAPIKey, cfg.APISettings.MaxRetries, cfg.MoralisFallbackKeys)
+	apiClient := newAPIClient(cfg)
 
 	// Initialize balance manager
-	balanceMgr := trading.NewBalanceManager(
-		"https://rpc.shyft.to?api_key="+extractAPIKey(cfg.WebSocketSettings.ShyftWSURL),
+	balanceMgr := trading.NewBalanceManagerWithAPI(
+		cfg.ShyftRPCURL(),
 		nil, // WS client not needed for one-off check
 		apiClient,
 	)
@@ -79,7 +80,7 @@ func handleBalanceCommand(bot *tgbotapi.BotAPI, chatID int64) {
 	message += "       📊 *WALLET  BALANCE*\n"
 	message += "╚═══════════════════════╝\n\n"
 
-	message += fmt.Sprintf("💼 *Wallet Name*\n`%s`\n\n", activeWallet.WalletName)
+	message += fmt.Sprintf("💼 *Wallet Name*\n`%s`\n\n", escapeMarkdownCode(activeWallet.WalletName))
 	message += fmt.Sprintf("🏛 *Address*\n`%s`\n\n", activeWallet.WalletAddress)
 
 	message += "━━━━━━━━━━━━━━━━━━━━\n"
@@ -99,14 +100,15 @@ func handleBalanceCommand(bot *tgbotapi.BotAPI, chatID int64) {
 	message += "🎯 *ACCOUNT STATUS*\n"
 	message += "━━━━━━━━━━━━━━━━━━━━\n\n"
 	if user != nil {
-		if user.PlanType == "credits_1000" {
-			message += fmt.Sprintf("▫️ *Plan:* Credits Plan\n")
+		plan := userPlan(user)
+		if plan != nil && plan.IsCredits() {
+			message += fmt.Sprintf("▫️ *Plan:* %s\n", plan.Name)
 			message += fmt.Sprintf("▫️ *Credits:* `%d remaining`\n", user.Credits)
-		} else if user.PlanType == "trial_3day" {
-			timeLeft := time.Until(time.Unix(user.TrialExpiresAt, 0))
+		} else if plan != nil && plan.IsTrial() {
+			timeLeft := user.TrialTimeLeft(scanner.db.Now())
 			days := int(timeLeft.Hours() / 24)
 			hours := int(timeLeft.Hours()) % 24
-			message += "▫️ *Plan:* Free Trial\n"
+			message += fmt.Sprintf("▫️ *Plan:* %s\n", plan.Name)
 			message += fmt.Sprintf("▫️ *Time Left:* `%dd %dh`\n", days, hours)
 		} else {
 			message += "▫️ *Plan:* No active plan\n"
@@ -121,6 +123,9 @@ func handleBalanceCommand(bot *tgbotapi.BotAPI, chatID int64) {
 			tgbotapi.NewInlineKeyboardButtonData("💎 Top Up Credits", "top_up_credits"),
 			tgbotapi.NewInlineKeyboardButtonData("🔄 Refresh", "refresh_balance"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💼 Portfolio", "portfolio"),
+		),
 	)
 
 	// Use Send instead of Edit for the final message to attach keyboard if needed,
@@ -202,15 +207,15 @@ func handleWalletsCommand(bot *tgbotapi.BotAPI, chatID int64) {
 			name = fmt.Sprintf("Wallet %d", i+1)
 		}
 
-		shortAddr := wallet.WalletAddress[:4] + "..." + wallet.WalletAddress[len(wallet.WalletAddress)-4:]
-		message += fmt.Sprintf("%s%s*%s* `%s`\n", status, tradingIcon, name, shortAddr)
+		message += fmt.Sprintf("%s%s*%s* `%s`\n", status, tradingIcon, escapeMarkdown(name), shortAddr(wallet.WalletAddress))
 
-		// Add button for this wallet
+		// Add buttons for this wallet
 		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
 				fmt.Sprintf("%s%s%s", status, tradingIcon, name),
 				fmt.Sprintf("select_wallet:%s", wallet.WalletAddress),
 			),
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Rename", "rename_wallet:"+wallet.WalletAddress),
 		))
 	}
 
@@ -230,13 +235,88 @@ func handleWalletsCommand(bot *tgbotapi.BotAPI, chatID int64) {
 	bot.Send(msg)
 }
 
-// extractAPIKey extracts API key from WebSocket URL
-func extractAPIKey(wsURL string) string {
-	parts := strings.Split(wsURL, "api_key=")
-	if len(parts) > 1 {
-		return parts[1]
+// maxRankedWallets is how many wallets /rank lists
+const maxRankedWallets = 10
+
+// handleRankCommand shows the top recent wallets by composite score. Like
+// search results, each wallet listed costs a credit unless the user is on
+// an active trial.
+func handleRankCommand(bot *tgbotapi.BotAPI, chatID int64, args string) {
+	profiles := globalCfg.Ranking.Profiles
+	profile := strings.ToLower(strings.TrimSpace(args))
+	if profile == "" {
+		profile = globalCfg.Ranking.DefaultProfile
+	}
+
+	weights, ok := profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		sendError(bot, chatID, fmt.Sprintf("Unknown scoring profile.\n\nUsage: /rank [%s]", strings.Join(names, "|")))
+		return
 	}
-	return ""
+
+	user, err := scanner.db.GetUser(chatID)
+	if err != nil || user == nil {
+		sendWarning(bot, chatID, "Please send /start to register")
+		return
+	}
+	plan := userPlan(user)
+	paid := plan == nil || plan.IsCredits()
+	limit := maxRankedWallets
+	if plan != nil && plan.IsTrial() && user.TrialExpired(scanner.db.Now()) {
+		sendError(bot, chatID, fmt.Sprintf("Trial Expired\n\nYour %s has ended.\nPlease upgrade to continue.", plan.Name))
+		return
+	}
+	if paid {
+		limit = min(limit, user.Credits)
+		if limit <= 0 {
+			sendError(bot, chatID, "Insufficient Credits\n\nEach ranked wallet costs 1 credit.\nPlease purchase more credits to continue.")
+			return
+		}
+	}
+
+	ranked, err := scanner.db.GetWalletsRanked(weights, storage.WalletFilters{Limit: limit})
+	if err != nil {
+		sendError(bot, chatID, "Error ranking wallets")
+		log.Printf("Error ranking wallets: %v", err)
+		return
+	}
+
+	if paid && len(ranked) > 0 {
+		// The balance may have been spent since it was read; list only
+		// what was paid for
+		spent, err := scanner.db.SpendCredits(chatID, len(ranked))
+		if err != nil {
+			if !errors.Is(err, storage.ErrInsufficientCredits) {
+				log.Printf("Error spending credits for %d: %v", chatID, err)
+			}
+			sendError(bot, chatID, "Insufficient Credits\n\nEach ranked wallet costs 1 credit.\nPlease purchase more credits to continue.")
+			return
+		}
+		ranked = ranked[:spent]
+		notifyLowCredits(bot, chatID, spent)
+	}
+
+	if len(ranked) == 0 {
+		sendWarning(bot, chatID, "No recently scanned wallets to rank yet.\n\nTry again after the next scan cycle.")
+		return
+	}
+
+	message := fmt.Sprintf("🏆 *Top Wallets* (_%s_)\n\n", profile)
+	for i, w := range ranked {
+		message += fmt.Sprintf("%d. `%s`\n⭐ Score: %.1f | 💹 WR: %.2f%% | 💰 PnL: %+.2f%% (%s)",
+			i+1, w.Wallet, w.Score, w.Winrate, w.RealizedPnLPct, formatUSD(w.RealizedPnLUSD))
+		if w.TradeCount > 0 {
+			message += fmt.Sprintf(" | 🔁 %d trades", w.TradeCount)
+		}
+		message += "\n\n"
+	}
+
+	send(bot, chatID, message)
 }
 
 // editMessage edits an existing message
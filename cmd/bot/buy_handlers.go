@@ -2,20 +2,18 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"log"
 	"runtime"
 	"solana-orchestrator/api"
 	"solana-orchestrator/config"
-	"solana-orchestrator/crypto"
+	"solana-orchestrator/engine"
 	"solana-orchestrator/storage"
 	"solana-orchestrator/trading"
 	"strconv"
 	"strings"
 	"time"
 
-	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -23,24 +21,32 @@ import (
 
 // handleStartBuy initiates the buy flow
 func handleStartBuy(bot *tgbotapi.BotAPI, chatID int64) {
+	if !startBuySession(bot, chatID) {
+		return
+	}
+
+	msg := "✅ *Buy Token*\n\n" +
+		"Enter the token address you want to buy:"
+
+	send(bot, chatID, msg)
+}
+
+// startBuySession starts a buy session waiting for the token, telling the
+// user when they have no wallet to buy with
+func startBuySession(bot *tgbotapi.BotAPI, chatID int64) bool {
 	// Check if user has encrypted wallet
 	if !scanner.db.HasEncryptedWallet(chatID) {
 		send(bot, chatID, "⚠️ No wallet found!\n\nUse /wallets to create or import a wallet first.")
-		return
+		return false
 	}
 
-	// Start buy flow
 	sessMu.Lock()
 	sessions[chatID] = &UserSession{
 		State:       "awaiting_buy_token",
 		RequestedAt: time.Now().Unix(),
 	}
 	sessMu.Unlock()
-
-	msg := "✅ *Buy Token*\n\n" +
-		"Enter the token address you want to buy:"
-
-	send(bot, chatID, msg)
+	return true
 }
 
 // handleBuyTokenInput processes token address for buying
@@ -55,63 +61,50 @@ func handleBuyTokenInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		return
 	}
 
+	showBuyPreview(bot, chatID, tokenAddress)
+}
+
+// showBuyPreview shows the token the user is buying and asks for the
+// amount
+func showBuyPreview(bot *tgbotapi.BotAPI, chatID int64, tokenAddress string) {
 	// Show loading
 	loadingMsg := tgbotapi.NewMessage(chatID, "⏳ Looking up token...")
 	loadingMsg.ParseMode = "Markdown"
 	sentMsg, _ := bot.Send(loadingMsg)
 
-	// Fetch token info from DexScreener
-	// 1. Try DexScreener First
-	tokenInfo, err := trading.GetTokenInfo(context.Background(), tokenAddress)
-	if err != nil {
-		log.Printf("DexScreener failed for %s: %v", tokenAddress, err)
-		// Fallback: Create empty token info and rely on Shyft
-		tokenInfo = &trading.TokenInfo{
-			Address:   tokenAddress,
-			Name:      "Unknown",
-			Symbol:    "Unknown",
-			PriceUSD:  "N/A",
-			Liquidity: 0,
-		}
-	}
-
-	// 2. Fetch Metadata & Supply from Shyft (Always try to augment/fix)
-	rpcURL := "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR" // Hardcoded for now as per user request, or load from config
-	shyftMeta, errShyft := api.GetShyftMetadata(rpcURL, tokenAddress)
-	if errShyft == nil {
-		// Overwrite/Augment with Shyft Data
-		tokenInfo.Name = shyftMeta.Name
-		tokenInfo.Symbol = shyftMeta.Symbol
-		tokenInfo.TotalSupply = shyftMeta.TotalSupply
-	} else {
-		fmt.Printf("⚠️ Shyft Metadata Error: %v\n", errShyft)
-	}
-
-	// If both failed (Name is still Unknown), then we can't proceed safely
-	if tokenInfo.Name == "Unknown" && tokenInfo.Symbol == "Unknown" {
+	// DexScreener, Shyft and the safety checks, reused for a short while
+	// after /token
+	report := loadTokenReport(tokenAddress)
+	tokenInfo := report.TokenInfo()
+	if tokenInfo == nil {
 		editMessage(bot, chatID, sentMsg.MessageID, "❌ Token not found on DexScreener or Solana Chain. Please check the address.")
 		return
 	}
 
 	// Store token info in temp storage
-	tempBuyData[chatID] = &BuyData{
+	tempBuyData.Set(chatID, &BuyData{
 		TokenAddress: tokenAddress,
 		TokenInfo:    tokenInfo,
-	}
+	})
 
 	// Show token info and ask for amount
-	message := fmt.Sprintf("🪙 *%s (%s)*\n\n", tokenInfo.Name, tokenInfo.Symbol)
+	message := fmt.Sprintf("🪙 *%s (%s)*\n\n", escapeMarkdown(tokenInfo.Name), escapeMarkdown(tokenInfo.Symbol))
 	message += fmt.Sprintf("💰 *Price:* $%s\n", tokenInfo.PriceUSD)
 	message += fmt.Sprintf("📦 *Supply:* %s\n", tokenInfo.TotalSupply)
 	message += fmt.Sprintf("📊 *24h Change:* %.2f%%\n", tokenInfo.Change24h)
 	message += fmt.Sprintf("💧 *Liquidity:* $%.0f\n", tokenInfo.Liquidity)
 	message += fmt.Sprintf("📈 *Volume 24h:* $%.0f\n\n", tokenInfo.Volume24h)
 	message += fmt.Sprintf("🔥 *Buys (5m):* %d | *Sells:* %d\n\n", tokenInfo.Buys5m, tokenInfo.Sells5m)
-	message += "💵 *Enter SOL amount to spend:*"
+	if report.Safety != nil {
+		message += safetyText(report.Safety)
+	}
+	message += "💵 *Enter SOL amount to spend* (or *max*):"
 
 	// Update session state
 	sessMu.Lock()
-	sessions[chatID].State = "awaiting_buy_amount"
+	if session, ok := sessions[chatID]; ok {
+		session.State = "awaiting_buy_amount"
+	}
 	sessMu.Unlock()
 
 	editMessage(bot, chatID, sentMsg.MessageID, message)
@@ -121,24 +114,23 @@ func handleBuyTokenInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 func handleBuyAmountInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
 
-	// Parse SOL amount
+	// Parse SOL amount; "max" spends everything above the reserve
 	amountStr := strings.TrimSpace(msg.Text)
+	buyMax := strings.EqualFold(amountStr, "max")
 	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || amount <= 0 {
-		sendError(bot, chatID, "Invalid amount!\n\nPlease enter a valid SOL amount (e.g., 0.1):")
+	if !buyMax && (err != nil || amount <= 0) {
+		sendError(bot, chatID, "Invalid amount!\n\nPlease enter a valid SOL amount (e.g., 0.1) or *max*:")
 		return
 	}
 
 	// Get buy data
-	buyData, ok := tempBuyData[chatID]
+	buyData, ok := tempBuyData.Get(chatID)
 	if !ok {
 		sendError(bot, chatID, "Session expired. Please start over with /buy")
 		cleanupBuySession(chatID)
 		return
 	}
 
-	buyData.SOLAmount = amount
-
 	// Check balance
 	wallet, err := scanner.db.GetEncryptedWallet(chatID)
 	if err != nil || wallet == nil {
@@ -148,8 +140,13 @@ func handleBuyAmountInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	}
 
 	// Get SOL balance via Shyft
-	walletPubkey, _ := solana.PublicKeyFromBase58(wallet.PublicKey)
-	rpcURL := "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR"
+	walletPubkey, err := solana.PublicKeyFromBase58(wallet.PublicKey)
+	if err != nil {
+		sendError(bot, chatID, "Failed to load wallet")
+		cleanupBuySession(chatID)
+		return
+	}
+	rpcURL := getShyftRPCURL()
 	wsClient := trading.NewWSClient(getShyftWSURL())
 
 	// Load config to get API keys
@@ -158,8 +155,8 @@ func handleBuyAmountInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		send(bot, chatID, "❌ Failed to load config")
 		return
 	}
-	apiClient := api.NewClient(cfg.MoralisAPIKey, cfg.BirdeyeAPIKey, cfg.APISettings.MaxRetries, cfg.MoralisFallbackKeys)
-	balanceMgr := trading.NewBalanceManager(rpcURL, wsClient, apiClient)
+	apiClient := newAPIClient(cfg)
+	balanceMgr := trading.NewBalanceManagerWithAPI(rpcURL, wsClient, apiClient)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -173,26 +170,66 @@ func handleBuyAmountInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 
 	solBalanceFloat := trading.FormatSOL(solBalance)
 
-	// Check if enough balance (including fees)
-	const estimatedFees = 0.001 // ~0.001 SOL for transaction fees
-	if solBalanceFloat < amount+estimatedFees {
+	// Buying into a token we've never held needs a new token account
+	var ataRent float64
+	tokenMint, err := solana.PublicKeyFromBase58(buyData.TokenAddress)
+	if err != nil {
+		sendError(bot, chatID, "Invalid token address")
+		cleanupBuySession(chatID)
+		return
+	}
+	ataStatus, err := balanceMgr.EnsureATA(ctx, walletPubkey, tokenMint)
+	if err != nil {
+		log.Printf("⚠️ ATA check failed for %s: %v", buyData.TokenAddress, err)
+	} else if ataStatus.NeedsCreation {
+		ataRent = trading.FormatSOL(ataStatus.RentLamports)
+	}
+
+	// Get user settings for slippage and the SOL reserve
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err != nil {
+		settings = &storage.UserSettings{SlippageBps: 500, JitoTipLamports: 10000, MaxPriceImpactPct: storage.DefaultMaxPriceImpactPct, MinSOLReserve: storage.DefaultMinSOLReserve} // defaults
+	}
+
+	// Check if enough balance (including fees), keeping the reserve
+	estimatedFees := 0.001 + ataRent // ~0.001 SOL for transaction fees plus account rent
+	feeLamports, _ := trading.ToRawAmount(estimatedFees, trading.SOLDecimals)
+	if buyMax {
+		amount = trading.FormatSOL(settings.SpendableLamports(solBalance, feeLamports))
+	}
+	amountLamports, _ := trading.ToRawAmount(amount, trading.SOLDecimals)
+	if amount <= 0 || !settings.LeavesReserve(solBalance, amountLamports, feeLamports) {
+		needed := amount + estimatedFees + settings.MinSOLReserve
 		message := fmt.Sprintf("❌ *Insufficient Balance!*\n\n")
 		message += fmt.Sprintf("💰 Your Balance: %.6f SOL\n", solBalanceFloat)
-		message += fmt.Sprintf("💸 Required: %.6f SOL\n", amount)
+		if amount > 0 {
+			message += fmt.Sprintf("💸 Required: %.6f SOL\n", amount)
+		}
+		if ataRent > 0 {
+			message += fmt.Sprintf("🏦 Token Account Rent: %.6f SOL\n", ataRent)
+		}
 		message += fmt.Sprintf("⚡ Est. Fees: %.6f SOL\n", estimatedFees)
-		message += fmt.Sprintf("📊 Total Needed: %.6f SOL\n\n", amount+estimatedFees)
-		message += fmt.Sprintf("⚠️ You need %.6f more SOL", (amount+estimatedFees)-solBalanceFloat)
+		if settings.MinSOLReserve > 0 {
+			message += fmt.Sprintf("🛟 SOL Reserve: %g SOL\n", settings.MinSOLReserve)
+		}
+		message += fmt.Sprintf("📊 Total Needed: %.6f SOL\n\n", needed)
+		message += fmt.Sprintf("⚠️ You need %.6f more SOL", needed-solBalanceFloat)
 
 		send(bot, chatID, message)
 		cleanupBuySession(chatID)
 		return
 	}
+	buyData.SOLAmount = amount
 
-	// Get user settings for slippage
-	settings, err := scanner.db.GetUserSettings(chatID)
-	if err != nil {
-		settings = &storage.UserSettings{SlippageBps: 500, JitoTipLamports: 10000} // defaults
+	// Quote the buy so the user sees how far it moves the price
+	var impact float64
+	var impactOK bool
+	if amountLamports > 0 {
+		impact, impactOK = buyImpact(ctx, buyData.TokenAddress, amountLamports, settings.SlippageBps)
 	}
+	impactExceeded := impactOK && settings.PriceImpactExceeded(impact)
+	buyData.ImpactPct, buyData.ImpactAccepted = impact, false
+	buyData.ConfirmLarge, buyData.LargeConfirmed = settings.NeedsLargeTradeConfirm(amount), false
 
 	// Calculate expected tokens (rough estimate)
 	priceSOL, _ := strconv.ParseFloat(buyData.TokenInfo.PriceSOL, 64)
@@ -203,22 +240,39 @@ func handleBuyAmountInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 
 	// Show confirmation
 	message := "⚠️ *Confirm Purchase*\n\n"
-	message += fmt.Sprintf("🪙 *Token:* %s (%s)\n", buyData.TokenInfo.Name, buyData.TokenInfo.Symbol)
+	message += fmt.Sprintf("🪙 *Token:* %s (%s)\n", escapeMarkdown(buyData.TokenInfo.Name), escapeMarkdown(buyData.TokenInfo.Symbol))
 	message += fmt.Sprintf("💰 *Spend:* %.6f SOL\n", amount)
+	if settings.MinSOLReserve > 0 {
+		message += fmt.Sprintf("🛟 *Reserve Kept:* %g SOL\n", settings.MinSOLReserve)
+	}
 	if expectedTokens > 0 {
-		message += fmt.Sprintf("📊 *Receive:* ~%.2f %s\n", expectedTokens, buyData.TokenInfo.Symbol)
+		message += fmt.Sprintf("📊 *Receive:* ~%.2f %s\n", expectedTokens, escapeMarkdown(buyData.TokenInfo.Symbol))
 	}
+	message += priceImpactText(impact, impactOK, settings)
 	message += fmt.Sprintf("⚙️ *Slippage:* %.1f%%\n", float64(settings.SlippageBps)/100)
-	message += fmt.Sprintf("💎 *Jito Tip:* %.6f SOL\n\n", float64(settings.JitoTipLamports)/1e9)
+	message += fmt.Sprintf("⚡ *Priority Fee:* %s\n", tradePriorityFeeText(settings, buyData.TokenAddress, buyData.TokenInfo))
+	message += fmt.Sprintf("🛡️ *Execution:* %s\n", executionText(tradeUsesJito(settings, buyData.TokenInfo), settings))
+	if ataRent > 0 {
+		message += fmt.Sprintf("🏦 *Token Account Rent:* %.6f SOL\n", ataRent)
+	}
+	message += "\n"
 	message += "⚠️ Slippage: Final amount may vary based on market\n\n"
-	message += "Click Confirm to proceed:"
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Confirm", "confirm_buy"),
-			tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "cancel_buy"),
-		),
-	)
+	// Small trades skip the Confirm button for users who auto-confirm
+	if settings.SkipsConfirmation(amount) && !impactExceeded {
+		message += "⚡ Auto-confirmed"
+		send(bot, chatID, message)
+		handleConfirmBuy(bot, chatID)
+		return
+	}
+
+	if impactExceeded {
+		message += "🚨 This buy moves the price more than your limit. Accept the impact to proceed:"
+	} else {
+		message += "Click Confirm to proceed:"
+	}
+
+	keyboard := confirmKeyboard("confirm_buy", "cancel_buy", impact, impactExceeded)
 
 	msgConfig := tgbotapi.NewMessage(chatID, message)
 	msgConfig.ParseMode = "Markdown"
@@ -233,6 +287,19 @@ func handleBuyAmountInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 
 // handleConfirmBuy executes the buy after password
 func handleConfirmBuy(bot *tgbotapi.BotAPI, chatID int64) {
+	if tradingHalted(bot, chatID) {
+		cleanupBuySession(chatID)
+		return
+	}
+
+	// Large buys need the amount typed back first
+	if buyData, ok := tempBuyData.Get(chatID); ok && buyData.ConfirmLarge && !buyData.LargeConfirmed {
+		askLargeTradeConfirm(bot, chatID, "awaiting_buy_large_confirm",
+			fmt.Sprintf("✋ *Large buy:* %g SOL is above your confirmation threshold.", buyData.SOLAmount),
+			fmt.Sprintf("%g", buyData.SOLAmount))
+		return
+	}
+
 	// Ask for password
 	sessMu.Lock()
 	sessions[chatID].State = "awaiting_buy_password"
@@ -250,8 +317,14 @@ func handleBuyPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	deleteMsg := tgbotapi.NewDeleteMessage(chatID, msg.MessageID)
 	bot.Request(deleteMsg)
 
+	// The switch may have been flipped while the password was typed
+	if tradingHalted(bot, chatID) {
+		cleanupBuySession(chatID)
+		return
+	}
+
 	// Get buy data
-	buyData, ok := tempBuyData[chatID]
+	buyData, ok := tempBuyData.Get(chatID)
 	if !ok {
 		send(bot, chatID, "❌ Session expired")
 		cleanupBuySession(chatID)
@@ -259,84 +332,41 @@ func handleBuyPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	}
 
 	send(bot, chatID, "⏳ Processing transaction...\n\nThis may take a few seconds")
+	trade := tradeLogger.Begin(chatID, "buy")
 
 	// 1. Decrypt private key
-	encWallet, err := scanner.db.GetEncryptedWalletForDecryption(chatID)
+	privateKey, err := unlockWallet(chatID, password)
 	if err != nil {
-		send(bot, chatID, "❌ Failed to load wallet")
+		send(bot, chatID, unlockErrorMessage(err))
 		cleanupBuySession(chatID)
 		return
 	}
 
-	// Convert storage.EncryptedWallet to crypto.EncryptedWallet
-	cryptoWallet := &crypto.EncryptedWallet{
-		EncryptedKey: []byte(encWallet.EncryptedPrivateKey),
-		Salt:         []byte(encWallet.EncryptionSalt),
-		Nonce:        []byte(encWallet.Nonce),
-		PasswordHash: encWallet.PasswordHash,
-	}
-
-	privateKeyStr, err := crypto.DecryptPrivateKey(cryptoWallet, password)
-	if err != nil {
-		send(bot, chatID, "❌ Incorrect password!")
-		// Don't cleanup session, let them try again?
-		// For now, cleanup to be safe
-		cleanupBuySession(chatID)
-		return
-	}
-
-	// Parse private key
-	privateKey, err := solana.PrivateKeyFromBase58(privateKeyStr)
-	if err != nil {
-		send(bot, chatID, "❌ Invalid private key in wallet")
-		cleanupBuySession(chatID)
-		return
-	}
-
-	// Clear private key string from memory (best effort)
-	crypto.ZeroString(&privateKeyStr)
-
 	// 2. Get User Settings
 	settings, err := scanner.db.GetUserSettings(chatID)
 	if err != nil {
-		settings = &storage.UserSettings{SlippageBps: 500, JitoTipLamports: 10000}
+		settings = &storage.UserSettings{SlippageBps: 500, JitoTipLamports: 10000, MaxPriceImpactPct: storage.DefaultMaxPriceImpactPct}
 	}
 
-	// 3. Get Jupiter Quote
-	solAmountLamports := uint64(buyData.SOLAmount * 1e9)
-	quote, err := trading.GetBuyQuote(context.Background(), buyData.TokenAddress, solAmountLamports, settings.SlippageBps)
+	// 3. Build the swap through Jupiter, or Pump.fun for tokens still on
+	// their bonding curve
+	solAmountLamports, err := trading.ToRawAmount(buyData.SOLAmount, trading.SOLDecimals)
 	if err != nil {
-		send(bot, chatID, fmt.Sprintf("❌ Failed to get quote: %v", err))
+		trade.Stage(engine.StageQuote, err, "")
+		sendError(bot, chatID, "Invalid SOL amount")
 		cleanupBuySession(chatID)
 		return
 	}
-
-	// 4. Get Swap Transaction
-	// Use Jito tip as priority fee if < 0.001 SOL, otherwise use standard
-	priorityFee := int64(10000) // Default low priority fee
-	swapResp, err := trading.GetSwapTransaction(context.Background(), quote, privateKey.PublicKey().String(), priorityFee)
-	if err != nil {
-		send(bot, chatID, fmt.Sprintf("❌ Failed to build transaction: %v", err))
-		cleanupBuySession(chatID)
-		return
-	}
-
-	// 5. Deserialize and Sign
-	txBytes, err := base64.StdEncoding.DecodeString(swapResp.SwapTransaction)
-	if err != nil {
-		send(bot, chatID, "❌ Failed to decode transaction")
-		cleanupBuySession(chatID)
-		return
+	settings.PriorityFeeLamports = tradePriorityFee(settings, buyData.TokenAddress, buyData.TokenInfo)
+	if buyData.ImpactAccepted {
+		acceptImpact(settings, buyData.ImpactPct)
 	}
-
-	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(txBytes))
-	if err != nil {
-		// Try versioned transaction if standard fails
-		// For now, assume standard or handle error
-		send(bot, chatID, fmt.Sprintf("❌ Failed to deserialize transaction: %v", err))
+	swap, ok := buildBuyTx(bot, chatID, trade, privateKey.PublicKey(), buyData.TokenAddress, buyData.TokenInfo, solAmountLamports, settings)
+	if !ok {
 		cleanupBuySession(chatID)
 		return
 	}
+	tx := swap.tx
 
 	// Sign transaction
 	_, err = tx.Sign(
@@ -347,22 +377,21 @@ func handleBuyPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 			return nil
 		},
 	)
+	if err == nil {
+		trade.SetSignature(tx.Signatures[0].String())
+	}
+	trade.Stage(engine.StageSign, err, "")
 	if err != nil {
 		send(bot, chatID, fmt.Sprintf("❌ Failed to sign transaction: %v", err))
 		cleanupBuySession(chatID)
 		return
 	}
 
-	// 6. Submit via Jito (if tip > 0) or RPC
-	// For now, we'll use Jito if tip is configured, otherwise RPC
-	// But we need a Jito client.
-	// We'll assume Jito is preferred for reliability.
-
-	// Initialize Jito Client (using Amsterdam endpoint as default)
-	jitoClient := trading.NewJitoClient("https://amsterdam.mainnet.block-engine.jito.wtf/api/v1/bundles", uint64(settings.JitoTipLamports))
+	// 4. Submit via Jito or RPC, as the user's execution mode picks for
+	// this token
+	jitoClient := trading.NewJitoClient("https://amsterdam.mainnet.block-engine.jito.wtf/api/v1/bundles", settings.JitoTip())
 
-	// Create Tip Instruction if using Jito
-	if settings.JitoTipLamports > 0 {
+	if tradeUsesJito(settings, buyData.TokenInfo) {
 		tipInst, err := jitoClient.CreateTipInstruction(privateKey.PublicKey())
 		if err == nil {
 			// Add tip instruction to transaction
@@ -390,11 +419,15 @@ func handleBuyPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 				// Submit Bundle
 				bundleRes, err := jitoClient.SubmitBundle(context.Background(), []solana.Transaction{*tx, *tipTx})
 				if err != nil {
-					send(bot, chatID, fmt.Sprintf("❌ Jito submission failed: %v", err))
+					trade.Stage(engine.StageSubmit, err, "jito bundle")
+					send(bot, chatID, tradeErrorMessage("Jito submission failed", err))
 					cleanupBuySession(chatID)
 					return
 				}
 
+				trade.Stage(engine.StageSubmit, nil, "jito bundle "+bundleRes.BundleID)
+				go trackConfirmation(bot, chatID, trade, getShyftRPCURL(), tx.Signatures[0], swap.lastValidBlockHeight)
+
 				send(bot, chatID, fmt.Sprintf("✅ *Bundle Submitted!*\n\nBundle ID: `%s`\n\nWaiting for confirmation...", bundleRes.BundleID))
 				cleanupBuySession(chatID)
 				return
@@ -403,18 +436,21 @@ func handleBuyPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	}
 
 	// Fallback to standard RPC if Jito fails or no tip
-	rpcURL := "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR"
+	rpcURL := getShyftRPCURL()
 	rpcClient := rpc.New(rpcURL)
 
 	sig, err := rpcClient.SendTransaction(context.Background(), tx)
+	trade.Stage(engine.StageSubmit, err, "rpc")
 	if err != nil {
-		send(bot, chatID, fmt.Sprintf("❌ Transaction failed: %v", err))
+		send(bot, chatID, tradeErrorMessage("Transaction failed", err))
 		cleanupBuySession(chatID)
 		return
 	}
 
+	go trackConfirmation(bot, chatID, trade, rpcURL, sig, swap.lastValidBlockHeight)
+
 	message := "✅ *Transaction Submitted!*\n\n"
-	message += fmt.Sprintf("🪙 Token: %s\n", buyData.TokenInfo.Symbol)
+	message += fmt.Sprintf("🪙 Token: %s\n", escapeMarkdown(buyData.TokenInfo.Symbol))
 	message += fmt.Sprintf("💰 Amount: %.6f SOL\n\n", buyData.SOLAmount)
 	message += fmt.Sprintf("🔗 Signature: `%s`\n", sig.String())
 	message += "⏳ Waiting for confirmation..."
@@ -428,9 +464,14 @@ type BuyData struct {
 	TokenAddress string
 	TokenInfo    *trading.TokenInfo
 	SOLAmount    float64
+
+	ImpactPct      float64 // price impact quoted on the confirmation
+	ImpactAccepted bool    // the user accepted ImpactPct over their limit
+	ConfirmLarge   bool    // over the user's threshold, so the amount must be typed
+	LargeConfirmed bool    // the user typed the amount
 }
 
-var tempBuyData = make(map[int64]*BuyData)
+var tempBuyData = newChatStore[*BuyData]()
 
 // cleanupBuySession cleans up buy session data
 func cleanupBuySession(chatID int64) {
@@ -438,11 +479,30 @@ func cleanupBuySession(chatID int64) {
 	delete(sessions, chatID)
 	sessMu.Unlock()
 
-	delete(tempBuyData, chatID)
+	tempBuyData.Delete(chatID)
 	runtime.GC()
 }
 
-// Helper to get Shyft WS URL
+// getShyftRPCURL returns the Shyft RPC URL built from the loaded config
+func getShyftRPCURL() string {
+	return globalCfg.ShyftRPCURL()
+}
+
+// getShyftWSURL returns the Shyft WebSocket URL built from the loaded config
 func getShyftWSURL() string {
-	return "wss://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR"
+	return globalCfg.ShyftWSURL()
+}
+
+// newAPIClient creates a Moralis/Birdeye client from cfg, using any
+// configured base URLs and Birdeye token list query
+func newAPIClient(cfg *config.Config) *api.Client {
+	client := api.NewClient(cfg.MoralisAPIKey, cfg.BirdeyeAPIKey, cfg.APISettings.MaxRetries, cfg.MoralisFallbackKeys)
+	client.SetBaseURLs(cfg.APISettings.MoralisBaseURL, cfg.APISettings.BirdeyeBaseURL)
+	client.SetBirdeyeTokenQuery(api.BirdeyeTokenQuery{
+		SortBy:       cfg.APISettings.BirdeyeSortBy,
+		MinLiquidity: cfg.APISettings.BirdeyeMinLiquidity,
+		MaxLiquidity: cfg.APISettings.BirdeyeMaxLiquidity,
+		MinVolume:    cfg.APISettings.BirdeyeMinVolumeUSD,
+	})
+	return client
 }
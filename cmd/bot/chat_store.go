@@ -0,0 +1,36 @@
+package main
+
+import "sync"
+
+// chatStore is a mutex-guarded map of per-chat values, for state that
+// handlers running on different goroutines read and write
+type chatStore[V any] struct {
+	mu     sync.RWMutex
+	values map[int64]V
+}
+
+func newChatStore[V any]() *chatStore[V] {
+	return &chatStore[V]{values: make(map[int64]V)}
+}
+
+// Get returns the value stored for chatID and whether there was one
+func (s *chatStore[V]) Get(chatID int64) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[chatID]
+	return v, ok
+}
+
+// Set stores v for chatID, replacing any previous value
+func (s *chatStore[V]) Set(chatID int64, v V) {
+	s.mu.Lock()
+	s.values[chatID] = v
+	s.mu.Unlock()
+}
+
+// Delete removes the value stored for chatID
+func (s *chatStore[V]) Delete(chatID int64) {
+	s.mu.Lock()
+	delete(s.values, chatID)
+	s.mu.Unlock()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestChatStoreConcurrent tests that the temp flow stores survive many
+// chats being handled at once; run with -race
+func TestChatStoreConcurrent(t *testing.T) {
+	store := newChatStore[*BuyData]()
+	addrs := newChatStore[string]()
+
+	const chats = 100
+	var wg sync.WaitGroup
+	for chatID := int64(0); chatID < chats; chatID++ {
+		wg.Add(1)
+		go func(chatID int64) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				store.Set(chatID, &BuyData{SOLAmount: float64(i)})
+				addrs.Set(chatID, "wallet")
+				if data, ok := store.Get(chatID); !ok || data == nil {
+					t.Errorf("Chat %d lost its buy data", chatID)
+					return
+				}
+				addrs.Get(chatID)
+				if i%2 == 0 {
+					addrs.Delete(chatID)
+				}
+			}
+			store.Delete(chatID)
+		}(chatID)
+	}
+	wg.Wait()
+
+	for chatID := int64(0); chatID < chats; chatID++ {
+		if _, ok := store.Get(chatID); ok {
+			t.Errorf("Chat %d still has buy data after Delete", chatID)
+		}
+		if addr, ok := addrs.Get(chatID); !ok || addr != "wallet" {
+			t.Errorf("Chat %d expected its last address, got %q, %v", chatID, addr, ok)
+		}
+	}
+}
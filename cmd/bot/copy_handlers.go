@@ -1,12 +1,16 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"solana-orchestrator/storage"
+
 	"github.com/gagliardetto/solana-go"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -32,6 +36,9 @@ func handleCopyTradeCommand(bot *tgbotapi.BotAPI, chatID int64) {
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("📊 Trade History", "copy_history"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📈 Target Performance", "copy_stats"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🔙 Back to Menu", "back_to_menu"),
 		),
@@ -44,7 +51,7 @@ func handleCopyTradeCommand(bot *tgbotapi.BotAPI, chatID int64) {
 	text += "Automatically mirror trades from successful wallets in real-time.\n\n"
 	text += "━━━━━━━━━━━━━━━━━━━━\n"
 	text += "✓ Monitor profitable wallets\n"
-	text += "✓ Auto-copy their buy orders\n"
+	text += "✓ Copy their buys, sells or both\n"
 	text += "✓ Set custom SOL amounts\n"
 	text += "━━━━━━━━━━━━━━━━━━━━"
 
@@ -52,6 +59,22 @@ func handleCopyTradeCommand(bot *tgbotapi.BotAPI, chatID int64) {
 	if fanoutEngine != nil && fanoutEngine.IsRunning() {
 		text += "🟢 *Engine Status*: Active\n"
 		text += fmt.Sprintf("📡 *Monitoring*: %d wallets\n", fanoutEngine.GetMonitoredCount())
+		if shards := fanoutEngine.ConnectionStatus(); len(shards) > 0 {
+			up, backups := 0, 0
+			for _, s := range shards {
+				if s.Connected {
+					up++
+				}
+				if s.UsingBackup {
+					backups++
+				}
+			}
+			text += fmt.Sprintf("📶 *Connections*: %d/%d up", up, len(shards))
+			if backups > 0 {
+				text += fmt.Sprintf(" (%d on backup)", backups)
+			}
+			text += "\n"
+		}
 	} else {
 		text += "🔴 *Engine Status*: Offline\n"
 	}
@@ -126,8 +149,46 @@ func handleCopyAmountInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 
 	sessMu.Lock()
 	session := sessions[chatID]
-	targetWallet, ok := session.TempData["target_wallet"].(string)
-	delete(sessions, chatID) // Clear session
+	_, ok := session.TempData["target_wallet"].(string)
+	if ok {
+		session.State = "awaiting_copy_sides"
+		session.TempData["copy_amount"] = amount
+	} else {
+		delete(sessions, chatID)
+	}
+	sessMu.Unlock()
+
+	if !ok {
+		send(bot, chatID, "❌ Session error. Please start over.")
+		return
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Buys & Sells", "copy_sides:both"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📥 Buys Only", "copy_sides:buys"),
+			tgbotapi.NewInlineKeyboardButtonData("📤 Sells Only", "copy_sides:sells"),
+		),
+	)
+	reply := tgbotapi.NewMessage(chatID, "🔁 *What to Copy*\n\nCopy this wallet's entries, its exits, or both?")
+	reply.ParseMode = "Markdown"
+	reply.ReplyMarkup = keyboard
+	bot.Send(reply)
+}
+
+// handleCopySidesChoice records the chosen sides and asks for the
+// smallest target trade worth copying
+func handleCopySidesChoice(bot *tgbotapi.BotAPI, chatID int64, choice string) {
+	sessMu.Lock()
+	session := sessions[chatID]
+	ok := session != nil && session.State == "awaiting_copy_sides"
+	if ok {
+		session.State = "awaiting_copy_min_sol"
+		session.TempData["copy_buys"] = choice != "sells"
+		session.TempData["copy_sells"] = choice != "buys"
+	}
 	sessMu.Unlock()
 
 	if !ok {
@@ -135,8 +196,51 @@ func handleCopyAmountInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		return
 	}
 
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Copy Every Trade", "copy_min_skip"),
+		),
+	)
+	reply := tgbotapi.NewMessage(chatID, "📏 *Minimum Trade Size*\n\nIgnore this wallet's swaps worth less than how much SOL? (e.g., 0.5)\n\n_Filters out dust buys and sells._")
+	reply.ParseMode = "Markdown"
+	reply.ReplyMarkup = keyboard
+	bot.Send(reply)
+}
+
+// handleCopyMinSOLInput processes the minimum target trade size
+func handleCopyMinSOLInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	minSOL, err := strconv.ParseFloat(strings.TrimSpace(msg.Text), 64)
+	if err != nil || minSOL < 0 {
+		send(bot, msg.Chat.ID, "❌ Invalid amount. Please enter a number (e.g., 0.5) or 0 to copy every trade:")
+		return
+	}
+	saveCopyTarget(bot, msg.Chat.ID, minSOL)
+}
+
+// saveCopyTarget adds the target collected by the add-target flow
+func saveCopyTarget(bot *tgbotapi.BotAPI, chatID int64, minSOL float64) {
+	sessMu.Lock()
+	session := sessions[chatID]
+	var targetWallet string
+	var amount float64
+	var buys, sells bool
+	ok := session != nil && session.State == "awaiting_copy_min_sol"
+	if ok {
+		targetWallet, _ = session.TempData["target_wallet"].(string)
+		amount, _ = session.TempData["copy_amount"].(float64)
+		buys, _ = session.TempData["copy_buys"].(bool)
+		sells, _ = session.TempData["copy_sells"].(bool)
+		delete(sessions, chatID) // Clear session
+	}
+	sessMu.Unlock()
+
+	if !ok || targetWallet == "" {
+		send(bot, chatID, "❌ Session error. Please start over.")
+		return
+	}
+
 	// Save to DB
-	err = scanner.db.AddCopyTarget(chatID, targetWallet, amount)
+	err := scanner.db.AddCopyTargetWithFilters(chatID, targetWallet, amount, buys, sells, minSOL)
 	if err != nil {
 		send(bot, chatID, fmt.Sprintf("❌ Database error: %v", err))
 		return
@@ -149,7 +253,36 @@ func handleCopyAmountInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		}
 	}
 
-	send(bot, chatID, fmt.Sprintf("✅ *Target Added Successfully!*\n\n━━━━━━━━━━━━━━━━━━━━\n🎯 *Wallet*\n`%s`\n\n💰 *Amount per Trade*\n`%.2f SOL`\n━━━━━━━━━━━━━━━━━━━━\n\n🔔 I'm now monitoring this wallet in real-time!", targetWallet, amount))
+	send(bot, chatID, fmt.Sprintf("✅ *Target Added Successfully!*\n\n━━━━━━━━━━━━━━━━━━━━\n🎯 *Wallet*\n`%s`\n\n💰 *Amount per Trade*\n`%.2f SOL`\n\n🔁 *Copying*\n%s\n\n📏 *Minimum Target Trade*\n%s\n━━━━━━━━━━━━━━━━━━━━\n\n🔔 I'm now monitoring this wallet in real-time!",
+		targetWallet, amount, copySidesLabel(buys, sells), minTargetLabel(minSOL)))
+}
+
+// minTargetLabel describes a target's minimum trade size
+func minTargetLabel(minSOL float64) string {
+	if minSOL <= 0 {
+		return "Every trade"
+	}
+	return fmt.Sprintf("`%.2f SOL`", minSOL)
+}
+
+// copySidesLabel describes which sides of a target's trades are copied
+func copySidesLabel(buys, sells bool) string {
+	switch {
+	case buys && sells:
+		return "Buys & Sells"
+	case buys:
+		return "Buys only"
+	default:
+		return "Sells only"
+	}
+}
+
+// onOff renders a toggle state for buttons
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
 }
 
 // handleListCopyTargets shows active targets
@@ -173,16 +306,35 @@ func handleListCopyTargets(bot *tgbotapi.BotAPI, chatID int64) {
 	msg += "    📋 *YOUR TARGETS*\n"
 	msg += "╚═══════════════════════╝\n\n"
 	var buttons [][]tgbotapi.InlineKeyboardButton
+	now, idle := time.Now(), inactiveAfter()
 
 	for i, t := range targets {
-		shortAddr := t.TargetWallet[:4] + "..." + t.TargetWallet[len(t.TargetWallet)-4:]
 		msg += fmt.Sprintf("━━━━━━━━━━━━━━━━━━━━\n")
 		msg += fmt.Sprintf("*Target #%d*\n", i+1)
 		msg += fmt.Sprintf("▫️ Wallet: `%s`\n", t.TargetWallet)
 		msg += fmt.Sprintf("▫️ Amount: `%.2f SOL`\n", t.CopyAmountSOL)
+		msg += fmt.Sprintf("▫️ Copying: %s\n", copySidesLabel(t.CopyBuys, t.CopySells))
+		msg += fmt.Sprintf("▫️ Min Target Trade: %s\n", minTargetLabel(t.MinTargetSOL))
+		if t.Paper {
+			msg += "▫️ Mode: 📝 Paper (simulated)\n"
+		}
+		msg += fmt.Sprintf("▫️ Activity: %s\n", activityLabel(t, now, idle))
+		if t.AutoSells() {
+			msg += fmt.Sprintf("▫️ Auto-Sell: %g%% at %gx\n", t.AutoSellPct, t.AutoSellMultiple)
+		}
 
+		stopLabel := fmt.Sprintf("🛑 Stop %s", shortAddr(t.TargetWallet))
+		if t.Inactive(now, idle) {
+			stopLabel = fmt.Sprintf("🗑 Remove %s", shortAddr(t.TargetWallet))
+		}
+
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📥 Buys: %s", onOff(t.CopyBuys)), fmt.Sprintf("copy_toggle_buys:%s", t.TargetWallet)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📤 Sells: %s", onOff(t.CopySells)), fmt.Sprintf("copy_toggle_sells:%s", t.TargetWallet)),
+		))
 		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🛑 Stop %s", shortAddr), fmt.Sprintf("stop_copy:%s", t.TargetWallet)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📝 Paper: %s", onOff(t.Paper)), fmt.Sprintf("copy_toggle_paper:%s", t.TargetWallet)),
+			tgbotapi.NewInlineKeyboardButtonData(stopLabel, fmt.Sprintf("stop_copy:%s", t.TargetWallet)),
 		))
 	}
 
@@ -196,10 +348,62 @@ func handleListCopyTargets(bot *tgbotapi.BotAPI, chatID int64) {
 	))
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
-	reply := tgbotapi.NewMessage(chatID, msg)
-	reply.ParseMode = "Markdown"
-	reply.ReplyMarkup = keyboard
-	bot.Send(reply)
+	sendLongWithKeyboard(bot, chatID, msg, &keyboard)
+}
+
+// handleToggleCopySide flips copying of a target's buys (buys true) or
+// sells. Turning off the last copied side is refused; Stop removes the
+// target instead.
+func handleToggleCopySide(bot *tgbotapi.BotAPI, chatID int64, targetWallet string, buys bool) {
+	target, err := scanner.db.GetCopyTarget(chatID, targetWallet)
+	if err != nil {
+		sendError(bot, chatID, "Error loading target")
+		return
+	}
+	if target == nil {
+		sendWarning(bot, chatID, "You are not copying this wallet anymore.")
+		return
+	}
+
+	copyBuys, copySells := target.CopyBuys, target.CopySells
+	if buys {
+		copyBuys = !copyBuys
+	} else {
+		copySells = !copySells
+	}
+
+	err = scanner.db.SetCopyTargetSides(chatID, targetWallet, copyBuys, copySells)
+	if errors.Is(err, storage.ErrNoCopySides) {
+		sendWarning(bot, chatID, "A target has to copy buys or sells.\n\nUse 🛑 Stop to remove it instead.")
+		return
+	}
+	if err != nil {
+		sendError(bot, chatID, "Error updating target")
+		return
+	}
+	handleListCopyTargets(bot, chatID) // Refresh list
+}
+
+// handleToggleCopyPaper switches a target between paper and live copying
+func handleToggleCopyPaper(bot *tgbotapi.BotAPI, chatID int64, targetWallet string) {
+	target, err := scanner.db.GetCopyTarget(chatID, targetWallet)
+	if err != nil {
+		sendError(bot, chatID, "Error loading target")
+		return
+	}
+	if target == nil {
+		sendWarning(bot, chatID, "You are not copying this wallet anymore.")
+		return
+	}
+
+	if err := scanner.db.SetCopyTargetPaper(chatID, targetWallet, !target.Paper); err != nil {
+		sendError(bot, chatID, "Error updating target")
+		return
+	}
+	if !target.Paper {
+		send(bot, chatID, fmt.Sprintf("📝 Paper trading `%s`\n\nIts trades are simulated, never sent. See /papertrade report for results.", targetWallet))
+	}
+	handleListCopyTargets(bot, chatID) // Refresh list
 }
 
 // handleStopCopyTarget removes a target
@@ -246,11 +450,11 @@ func handleCopyTradeHistory(bot *tgbotapi.BotAPI, chatID int64) {
 		msg += fmt.Sprintf("━━━━━━━━━━━━━━━━━━━━\n")
 		msg += fmt.Sprintf("*Trade #%d*\n", i+1)
 		msg += fmt.Sprintf("▫️ Type: %s\n", strings.ToUpper(t.TradeType))
-		msg += fmt.Sprintf("▫️ Token: `%s`\n", t.TokenAddress)
+		msg += fmt.Sprintf("▫️ Token: *%s* `%s`\n", escapeMarkdown(tokenLabel(t.TokenAddress)), t.TokenAddress)
 		msg += fmt.Sprintf("▫️ Amount: %.2f SOL\n", t.SolAmount)
 		msg += fmt.Sprintf("▫️ Status: %s %s\n", statusIcon, strings.Title(t.Status))
 		if t.TxSignature != "" {
-			msg += fmt.Sprintf("▫️ Signature: `%s`\n", t.TxSignature[:8]+"...")
+			msg += fmt.Sprintf("▫️ Signature: `%.8s...`\n", t.TxSignature)
 		}
 	}
 	msg += "━━━━━━━━━━━━━━━━━━━━"
@@ -261,8 +465,162 @@ func handleCopyTradeHistory(bot *tgbotapi.BotAPI, chatID int64) {
 		),
 	)
 
-	reply := tgbotapi.NewMessage(chatID, msg)
-	reply.ParseMode = "Markdown"
-	reply.ReplyMarkup = keyboard
-	bot.Send(reply)
+	sendLongWithKeyboard(bot, chatID, msg, &keyboard)
+}
+
+// handleCopyStatsCommand shows the realized PnL of each copied target
+func handleCopyStatsCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	window := globalCfg.CopyTrading.WindowHours
+	since := time.Now().Add(-time.Duration(window) * time.Hour).Unix()
+
+	stats, err := scanner.db.GetCopyTargetStats(chatID, since)
+	if err != nil {
+		sendError(bot, chatID, "Error fetching copy trade stats")
+		return
+	}
+
+	// Active targets without closed trades still get a line
+	active := make(map[string]*storage.CopyTradeTarget)
+	targets, _ := scanner.db.GetCopyTargets(chatID)
+	for _, t := range targets {
+		active[t.TargetWallet] = t
+	}
+	seen := make(map[string]bool)
+	for _, s := range stats {
+		seen[s.TargetWallet] = true
+	}
+	for _, t := range targets {
+		if !seen[t.TargetWallet] {
+			stats = append(stats, &storage.CopyTargetStats{TargetWallet: t.TargetWallet})
+		}
+	}
+
+	if len(stats) == 0 {
+		send(bot, chatID, "📈 No copy targets yet.\n\n💡 Use /copytrade to add one!")
+		return
+	}
+
+	msg := "╔═══════════════════════╗\n"
+	msg += "   📈 *TARGET PERFORMANCE*\n"
+	msg += "╚═══════════════════════╝\n\n"
+	now, idle := time.Now(), inactiveAfter()
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	for _, s := range stats {
+		status := "🟢 Active"
+		if t := active[s.TargetWallet]; t == nil {
+			status = "⏸ Paused"
+		} else if t.Inactive(now, idle) {
+			status = activityLabel(t, now, idle)
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 Remove inactive %s", shortAddr(s.TargetWallet)), fmt.Sprintf("stop_copy:%s", s.TargetWallet)),
+			))
+		}
+		pnlIcon := "🟢"
+		if s.RealizedPnLSOL < 0 {
+			pnlIcon = "🔴"
+		}
+
+		msg += "━━━━━━━━━━━━━━━━━━━━\n"
+		msg += fmt.Sprintf("▫️ Wallet: `%s`\n", s.TargetWallet)
+		msg += fmt.Sprintf("▫️ Status: %s\n", status)
+		msg += fmt.Sprintf("▫️ Closed Trades: %d (%dW / %dL)\n", s.Trades, s.Wins, s.Losses)
+		msg += fmt.Sprintf("▫️ Realized PnL: %s `%+.4f SOL`\n", pnlIcon, s.RealizedPnLSOL)
+		msg += fmt.Sprintf("▫️ Last %dh: `%+.4f SOL`\n", window, s.WindowPnLSOL)
+	}
+	msg += "━━━━━━━━━━━━━━━━━━━━"
+
+	if globalCfg.CopyTrading.AutoDisable {
+		msg += fmt.Sprintf("\n\n🛡 Targets pause automatically after losing %.2f SOL in %dh.", globalCfg.CopyTrading.MaxLossSOL, window)
+	}
+
+	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "copytrade"),
+	))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
+
+	sendLongWithKeyboard(bot, chatID, msg, &keyboard)
+}
+
+// handlePaperTradeCommand shows the simulated PnL of paper targets
+func handlePaperTradeCommand(bot *tgbotapi.BotAPI, chatID int64, args string) {
+	if arg := strings.TrimSpace(args); arg != "" && arg != "report" {
+		sendWarning(bot, chatID, "Usage: `/papertrade report`")
+		return
+	}
+
+	reports, err := scanner.db.GetPaperTradeReport(chatID)
+	if err != nil {
+		sendError(bot, chatID, "Error fetching paper trades")
+		return
+	}
+	if len(reports) == 0 {
+		send(bot, chatID, "📝 No paper trades yet.\n\n💡 Turn on 📝 Paper for a target in /copytrade to simulate copying it.")
+		return
+	}
+
+	msg := "╔═══════════════════════╗\n"
+	msg += "    📝 *PAPER TRADING*\n"
+	msg += "╚═══════════════════════╝\n\n"
+	for _, r := range reports {
+		pnlIcon := "🟢"
+		if r.RealizedPnLSOL < 0 {
+			pnlIcon = "🔴"
+		}
+
+		msg += "━━━━━━━━━━━━━━━━━━━━\n"
+		msg += fmt.Sprintf("▫️ Wallet: `%s`\n", r.TargetWallet)
+		msg += fmt.Sprintf("▫️ Buys: %d · Sells: %d (%dW / %dL)\n", r.Buys, r.Sells, r.Wins, r.Losses)
+		msg += fmt.Sprintf("▫️ Simulated Spend: `%.4f SOL`\n", r.SOLSpent)
+		msg += fmt.Sprintf("▫️ Realized PnL: %s `%+.4f SOL`\n", pnlIcon, r.RealizedPnLSOL)
+		if r.OpenCostSOL > 0 {
+			msg += fmt.Sprintf("▫️ Still Open: `%.4f SOL`\n", r.OpenCostSOL)
+		}
+	}
+	msg += "━━━━━━━━━━━━━━━━━━━━\n\n"
+	msg += "_Simulated at each target's own fill price; real copies would see extra slippage and fees._"
+
+	sendLong(bot, chatID, msg)
+}
+
+// handleAutoSellCommand sets the take-profit registered for a target's
+// copied buys: /autosell <wallet> <multiple> [percent], or off
+func handleAutoSellCommand(bot *tgbotapi.BotAPI, chatID int64, args string) {
+	usage := "Usage: `/autosell <wallet> <multiple> [percent]`\n" +
+		"e.g. `/autosell <wallet> 2 50` sells half of each copied buy at 2x.\n" +
+		"`/autosell <wallet> off` turns it off."
+	fields := strings.Fields(args)
+	if len(fields) < 2 || len(fields) > 3 {
+		sendWarning(bot, chatID, usage)
+		return
+	}
+
+	wallet := fields[0]
+	var multiple, pct float64
+	if !strings.EqualFold(fields[1], "off") {
+		var err error
+		multiple, err = strconv.ParseFloat(strings.TrimSuffix(fields[1], "x"), 64)
+		pct = 100
+		if err == nil && len(fields) == 3 {
+			pct, err = strconv.ParseFloat(strings.TrimSuffix(fields[2], "%"), 64)
+		}
+		if err != nil {
+			sendWarning(bot, chatID, usage)
+			return
+		}
+	}
+
+	err := scanner.db.SetCopyTargetAutoSell(chatID, wallet, pct, multiple)
+	if errors.Is(err, sql.ErrNoRows) {
+		sendWarning(bot, chatID, "You are not copying this wallet.")
+		return
+	}
+	if err != nil {
+		sendWarning(bot, chatID, fmt.Sprintf("Invalid auto-sell: %v", err))
+		return
+	}
+	if multiple == 0 {
+		send(bot, chatID, fmt.Sprintf("✅ Auto-sell off for `%s`", wallet))
+		return
+	}
+	send(bot, chatID, fmt.Sprintf("✅ Copied buys from `%s` will take profit on %g%% at %gx", wallet, pct, multiple))
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -10,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"solana-orchestrator/config"
 	"solana-orchestrator/storage"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -20,7 +22,8 @@ type SearchSession struct {
 	ChatID             int64
 	MessageID          int
 	Winrate            float64
-	PnL                float64
+	PnL                pnlFilter
+	MinAgeDays         int
 	StartCount         int
 	FoundWallets       []*storage.WalletData
 	LastProcessedIndex int
@@ -28,8 +31,9 @@ type SearchSession struct {
 	LastUpdateTime     time.Time
 	CancelRequested    bool
 	Active             bool
-	MaxCredits         int // Max credits to spend in this session
-	CreditsSpent       int // Credits spent so far
+	MaxCredits         int                // Max credits to spend in this session
+	CreditsSpent       int                // Credits spent so far
+	Plan               *config.PlanConfig // the user's plan when the search started, nil without one
 	totalWallets       int
 	isScanning         bool
 	mu                 sync.RWMutex
@@ -41,20 +45,13 @@ var (
 )
 
 const (
-	ScanDelayTrialMin   = 300
-	ScanDelayTrialMax   = 600
-	ScanDelayNormalMin  = 300
-	ScanDelayNormalMax  = 3600
-	BatchSize           = 5
-	TickerInterval      = 3 * time.Second
-	MaxIterations       = 100
-	MaxCreditsPerSearch = 200 // Cap credits per search
+	BatchSize      = 5
+	TickerInterval = 3 * time.Second
+	MaxIterations  = 100
 )
 
-// ... (startDevFinderImproved and handlers remain mostly the same, just setting MaxCredits)
-
 // startRealTimeSearch begins searching and shows results in real-time or queues for slow delivery
-func startRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float64, startCount int, scanType string) {
+func startRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64, winrate float64, pnl pnlFilter, minAgeDays, startCount int, scanType string) {
 	// Check user plan and credits
 	user, err := scanner.db.GetUser(chatID)
 	if err != nil {
@@ -69,19 +66,21 @@ func startRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float6
 	}
 
 	// Enforce Plan Logic
-	if user.PlanType == "trial_3day" {
-		// 3-Day Trial: Force Slow Scan with 5-10 min delay
-		if scanType == "realtime" {
-			send(bot, chatID, "⚠️ *3-Day Trial Limitation*\n\nReal-Time scans are not available on the trial plan.\nSwitching to Slow Scan (5-10 min delay).")
-			startRealTimeSearch(bot, chatID, winrate, pnl, startCount, "slow")
-			return
-		}
+	plan := userPlan(user)
+	if plan != nil && !plan.RealtimeScans && scanType == "realtime" {
+		// Plans without real-time access are forced onto the delayed slow scan
+		send(bot, chatID, fmt.Sprintf("⚠️ *%s Limitation*\n\nReal-Time scans are not available on this plan.\nSwitching to Slow Scan (%s delay).", plan.Name, planDelayText(plan)))
+		startRealTimeSearch(bot, chatID, winrate, pnl, minAgeDays, startCount, "slow")
+		return
+	}
+
+	if plan != nil && plan.IsTrial() {
 		// Check expiry
-		if time.Now().Unix() > user.TrialExpiresAt {
-			sendError(bot, chatID, "Trial Expired\n\nYour 3-Day Free Trial has ended.\nPlease upgrade to continue.")
+		if user.TrialExpired(scanner.db.Now()) {
+			sendError(bot, chatID, fmt.Sprintf("Trial Expired\n\nYour %s has ended.\nPlease upgrade to continue.", plan.Name))
 			return
 		}
-	} else if user.PlanType == "credits_1000" {
+	} else if plan != nil && plan.IsCredits() {
 		// Credit Plan: Check balance
 		if user.Credits <= 0 {
 			sendError(bot, chatID, "Insufficient Credits\n\nYou have 0 credits left.\nPlease purchase more credits to continue.")
@@ -91,10 +90,27 @@ func startRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float6
 		send(bot, chatID, fmt.Sprintf("💎 *Credit Balance*: %d\n1 Credit will be deducted for each wallet found.", user.Credits))
 	}
 
+	// Fixed for the whole search so a top-up mid-search can't raise it
+	maxWallets := searchBudget(plan, user)
+	if maxWallets <= 0 {
+		sendError(bot, chatID, "Insufficient Credits\n\nYou have 0 credits left.\nPlease purchase more credits to continue.")
+		return
+	}
+
 	if scanType == "slow" {
+		// Only one slow scan may wait for delivery; refuse before any
+		// credits are spent on a second one
+		if eta, pending := pendingScanETA(chatID); pending {
+			sendWarning(bot, chatID, fmt.Sprintf("You already have a pending slow scan.\n\nIts results arrive in ~%d minutes. Start a new scan once they're delivered.", int(eta.Minutes())+1))
+			return
+		}
+		if !beginSlowScan(chatID) {
+			sendWarning(bot, chatID, "You already have a slow scan running.\n\nStart a new scan once its results are delivered.")
+			return
+		}
 		// ... (Slow scan logic)
 		// Start background scan
-		go runSlowScan(context.Background(), bot, chatID, winrate, pnl, startCount)
+		go runSlowScan(context.Background(), bot, chatID, winrate, pnl, minAgeDays, maxWallets)
 		return
 	}
 
@@ -107,12 +123,12 @@ func startRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float6
 	)
 
 	text := fmt.Sprintf("🔍 *Searching for Wallets...*\n\n"+
-		"Filters: WR ≥ %.2f%%, PnL ≥ %.2f%%\n\n"+
+		"Filters: WR ≥ %.2f%%, %s%s\n\n"+
 		"█░░░░░░░░░░░░░░░░░░░\n"+
 		"Progress: 0.0%%\n\n"+
-		"📊 Wallets Found: 0\n"+
+		"📊 Wallets Found: 0 (up to %d)\n"+
 		"⏱️ Status: Starting...",
-		winrate, pnl)
+		winrate, pnl, ageFilterText(minAgeDays), maxWallets)
 
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
@@ -127,14 +143,16 @@ func startRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float6
 		MessageID:          sentMsg.MessageID,
 		Winrate:            winrate,
 		PnL:                pnl,
+		MinAgeDays:         minAgeDays,
 		StartCount:         len(scanner.walletsCache),
 		FoundWallets:       make([]*storage.WalletData, 0),
 		LastProcessedIndex: 0, // Start from beginning to scan existing wallets
 		ProcessedCount:     0,
 		LastUpdateTime:     time.Now(),
 		Active:             true,
-		MaxCredits:         MaxCreditsPerSearch, // Set budget
+		MaxCredits:         maxWallets,
 		CreditsSpent:       0,
+		Plan:               plan,
 	}
 	activeSearches[chatID] = search
 	searchMu.Unlock()
@@ -144,7 +162,12 @@ func startRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float6
 }
 
 // runSlowScan performs scan in background and queues results for delayed delivery
-func runSlowScan(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float64, startCount int) {
+func runSlowScan(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, winrate float64, pnl pnlFilter, minAgeDays, maxWallets int) {
+	// The running cycle favors this scan as the user's plan allows
+	user, _ := scanner.db.GetUser(chatID)
+	done := addScanDemand(userPlan(user), winrate, pnl, minAgeDays)
+	defer done()
+
 	// Poll for scan completion
 	timeout := time.After(5 * time.Minute)
 	ticker := time.NewTicker(2 * time.Second)
@@ -163,68 +186,78 @@ loop:
 		case <-timeout:
 			break loop
 		case <-ctx.Done():
+			endSlowScan(chatID)
 			return
 		}
 	}
+	done() // the delivery delay doesn't need the scanner
 
 	// Collect matching wallets
 	scanner.mu.RLock()
 	var potentialMatches []*storage.WalletData
-	for _, w := range scanner.walletsCache {
-		if w.Winrate >= winrate && w.RealizedPnL >= pnl {
+	now := time.Now()
+	for _, w := range scanner.index.match(winrate, pnl) {
+		if w.MeetsMinAge(minAgeDays, now) {
 			potentialMatches = append(potentialMatches, w)
 		}
 	}
 	scanner.mu.RUnlock()
+	if len(potentialMatches) > maxWallets {
+		potentialMatches = potentialMatches[:maxWallets]
+	}
 
 	// Apply Credit Logic Atomically
 	var confirmedMatches []*storage.WalletData
-	user, _ := scanner.db.GetUser(chatID)
-
-	if user != nil && user.PlanType == "credits_1000" {
-		// Deduct 1 credit per wallet
-		for _, w := range potentialMatches {
-			if err := scanner.db.DecrementUserCredits(chatID, 1); err == nil {
-				confirmedMatches = append(confirmedMatches, w)
-			} else {
-				// Stop if out of credits
-				break
-			}
+	creditsSpent := 0
+	user, _ = scanner.db.GetUser(chatID)
+
+	if plan := userPlan(user); plan != nil && plan.IsCredits() {
+		// Deduct 1 credit per wallet, keeping as many as the balance covers
+		spent, err := scanner.db.SpendCredits(chatID, len(potentialMatches))
+		if err != nil && !errors.Is(err, storage.ErrInsufficientCredits) {
+			log.Printf("Error spending credits for %d: %v", chatID, err)
 		}
+		confirmedMatches = potentialMatches[:spent]
+		creditsSpent = spent
+		notifyLowCredits(bot, chatID, creditsSpent)
 	} else {
 		// Unlimited or Trial
 		confirmedMatches = potentialMatches
 	}
 
 	// Generate random delay
-	var delaySeconds int
 	user, _ = scanner.db.GetUser(chatID) // Refresh user
+	delaySeconds := planDelaySeconds(userPlan(user))
 
-	if user != nil && user.PlanType == "trial_3day" {
-		// 5-10 minutes for trial
-		delaySeconds = rand.Intn(ScanDelayTrialMax-ScanDelayTrialMin) + ScanDelayTrialMin
-	} else {
-		// 5-60 minutes for others
-		delaySeconds = rand.Intn(ScanDelayNormalMax-ScanDelayNormalMin) + ScanDelayNormalMin
-	}
-
-	deliverAt := time.Now().Add(time.Duration(delaySeconds) * time.Second)
+	deliverAt := scanner.db.Now().Add(time.Duration(delaySeconds) * time.Second)
 
-	// Store in pending queue - check for existing scan first
+	// Store in pending queue. A scan queued while this one ran absorbs
+	// its results; wallets it already holds are refunded.
 	pendingScansMu.Lock()
-	if _, exists := pendingScans[chatID]; exists {
+	delete(runningScans, chatID)
+	if existing, exists := pendingScans[chatID]; exists {
+		added := mergeScanResults(existing, confirmedMatches)
+		charged := min(creditsSpent, added)
+		existing.CreditsSpent += charged
 		pendingScansMu.Unlock()
-		sendWarning(bot, chatID, "You already have a pending slow scan. This scan was cancelled.")
+		if refund := creditsSpent - charged; refund > 0 {
+			if err := scanner.db.RefundUserCredits(chatID, refund); err != nil {
+				log.Printf("Error refunding %d credits to %d: %v", refund, chatID, err)
+			}
+		}
+		send(bot, chatID, fmt.Sprintf("⏱️ *Scan Complete - Results Pending*\n\nAdded *%d wallets* to your pending slow scan.\nThey'll be delivered together.", added))
 		return
 	}
-	pendingScans[chatID] = &PendingScan{
-		UserID:      chatID,
-		Results:     confirmedMatches,
-		DeliverAt:   deliverAt,
-		ScanType:    "slow",
-		Winrate:     winrate,
-		RealizedPnL: pnl,
+	scan := &PendingScan{
+		UserID:       chatID,
+		Results:      confirmedMatches,
+		DeliverAt:    deliverAt,
+		ScanType:     "slow",
+		Winrate:      winrate,
+		PnL:          pnl,
+		CreditsSpent: creditsSpent,
 	}
+	pendingScans[chatID] = scan
 	pendingScansMu.Unlock()
 
 	// Notify user about estimated wait time
@@ -234,10 +267,17 @@ loop:
 		"Estimated delivery time: *~%d minutes*\n\n"+
 		"You'll be notified when results are ready!",
 		len(confirmedMatches), etaMinutes)
-	send(bot, chatID, updateText)
+	msg := tgbotapi.NewMessage(chatID, updateText)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("❌ Cancel Pending Scan", "cancel_pending_scan"),
+		),
+	)
+	bot.Send(msg)
 
 	// Schedule delayed delivery
-	deliverDelayedResults(ctx, bot, chatID, delaySeconds)
+	deliverDelayedResults(ctx, bot, scan, delaySeconds)
 }
 
 // runRealTimeSearch continuously searches and updates results
@@ -249,6 +289,7 @@ func runRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64) {
 	if !exists {
 		return
 	}
+	defer addScanDemand(search.Plan, search.Winrate, search.PnL, search.MinAgeDays)()
 
 	// Jitter to avoid synchronized bursts
 	time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
@@ -283,23 +324,32 @@ func runRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64) {
 		scanner.mu.RLock()
 		var newMatches []*storage.WalletData
 
-		// Scalable Iteration: Only process wallets we haven't seen yet
+		// Scalable Iteration: the first tick takes the wallets already
+		// cached from the index, later ticks only the wallets added since
 		currentLen := len(scanner.walletsList)
 		startIndex := search.LastProcessedIndex
 
 		var walletsToProcess []*storage.WalletData
-		if startIndex < currentLen {
+		processed := 0
+		if startIndex == 0 {
+			walletsToProcess = scanner.index.match(search.Winrate, search.PnL)
+			processed = currentLen
+		} else if startIndex < currentLen {
 			walletsToProcess = scanner.walletsList[startIndex:currentLen]
+			processed = len(walletsToProcess)
 		}
 		scanner.mu.RUnlock()
 
 		// Process new wallets
 		var validMatches []*storage.WalletData
+		now := time.Now()
 		for _, w := range walletsToProcess {
-			if w.Winrate >= search.Winrate && w.RealizedPnL >= search.PnL {
+			if w.Winrate >= search.Winrate && search.PnL.Matches(w) && w.MeetsMinAge(search.MinAgeDays, now) {
 				validMatches = append(validMatches, w)
 			}
 		}
+		// A wallet rescanned in a later cycle is only charged once
+		validMatches = search.unseen(validMatches)
 
 		// Batch Credit Deduction
 		if len(validMatches) > 0 {
@@ -317,29 +367,31 @@ func runRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64) {
 			}
 
 			if creditsNeeded > 0 {
-				// Atomic batch deduction
-				if err := scanner.db.DecrementUserCredits(chatID, creditsNeeded); err != nil {
-					// Stop search immediately
-					search.mu.Lock()
-					search.Active = false
-					search.mu.Unlock()
-
+				// Atomic batch deduction; another search for the same user
+				// may have spent part of the balance, so keep what it covers
+				spent, err := scanner.db.SpendCredits(chatID, creditsNeeded)
+				if err != nil {
+					if !errors.Is(err, storage.ErrInsufficientCredits) {
+						log.Printf("Error spending credits for %d: %v", chatID, err)
+					}
 					send(bot, chatID, "⚠️ *Search Stopped: Insufficient Credits*\n\n"+
 						"You have run out of credits. Please top up to continue searching.")
+					sendSearchSummary(bot, chatID, "credits")
 					return
 				}
 
 				// Update spent
 				search.mu.Lock()
-				search.CreditsSpent += creditsNeeded
+				search.CreditsSpent += spent
 				search.mu.Unlock()
+				notifyLowCredits(bot, chatID, spent)
 
-				newMatches = validMatches
+				newMatches = validMatches[:spent]
 			}
 		}
 
 		search.mu.Lock()
-		search.ProcessedCount += len(walletsToProcess)
+		search.ProcessedCount += processed
 		search.mu.Unlock()
 
 		// Update Index
@@ -367,7 +419,7 @@ func runRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64) {
 				for i, wallet := range newMatches {
 					// Add to batch message
 					batchMessage.WriteString(fmt.Sprintf("*%d.* `%s`\n", i+1, wallet.Wallet))
-					batchMessage.WriteString(fmt.Sprintf("💹 WR: %.2f%% | 💰 PnL: %.2f%%\n\n", wallet.Winrate, wallet.RealizedPnL))
+					batchMessage.WriteString(walletStatsLine(wallet) + "\n\n")
 					processedCount++
 				}
 
@@ -386,7 +438,7 @@ func runRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64) {
 		search.mu.RUnlock()
 
 		if foundCount != lastFoundCount || iterations%5 == 0 { // Update every 15 seconds or when new wallet found
-			updateSearchProgress(bot, search, processedTotal, totalWallets, isScanning)
+			updateSearchProgress(bot, search, processedTotal, totalWallets, isScanning, iterations)
 			lastFoundCount = foundCount
 		}
 
@@ -400,9 +452,9 @@ func runRealTimeSearch(bot *tgbotapi.BotAPI, chatID int64) {
 	sendSearchSummary(bot, chatID, "exhausted")
 }
 
-// updateSearchProgress updates the progress message
-// updateSearchProgress updates the progress message
-func updateSearchProgress(bot *tgbotapi.BotAPI, search *SearchSession, processedTotal, totalWallets int, isScanning bool) {
+// updateSearchProgress updates the progress message; tick advances the
+// spinner shown while the scanner is still collecting wallets
+func updateSearchProgress(bot *tgbotapi.BotAPI, search *SearchSession, processedTotal, totalWallets int, isScanning bool, tick int) {
 	search.mu.RLock()
 	defer search.mu.RUnlock()
 
@@ -410,30 +462,20 @@ func updateSearchProgress(bot *tgbotapi.BotAPI, search *SearchSession, processed
 		return
 	}
 
-	progress := 0.0
-	if totalWallets > 0 {
-		// Estimate progress based on processed count vs total wallets
-		// Since we don't know exactly where we are in the map iteration, this is an approximation
-		// or we can just show processed count.
-		// For now, let's just cap it at 100% if it goes over (which it shouldn't if totalWallets is correct)
-		progress = float64(processedTotal) / float64(totalWallets) * 100
-		if progress > 100 {
-			progress = 100
-		}
-	}
-
-	progressBar := createProgressBar(progress)
+	// processedTotal spans every cycle the search has followed, so it can
+	// outrun the current cycle's total; scanProgress clamps it
+	progressBar, progress := scanProgress(processedTotal, totalWallets, tick)
 	foundCount := len(search.FoundWallets)
 
 	text := fmt.Sprintf("🔍 *Searching for Wallets...*\n\n"+
-		"Filters: WR ≥ %.2f%%, PnL ≥ %.2f%%\n\n"+
+		"Filters: WR ≥ %.2f%%, %s%s\n\n"+
 		"%s\n"+
-		"Progress: %.1f%%\n\n"+
-		"✅ Wallets Found: *%d*\n"+
+		"Progress: %s\n\n"+
+		"✅ Wallets Found: *%d* (up to %d)\n"+
 		"📊 Wallets Processed: *%d*\n"+
 		"⏱️ Status: %s",
-		search.Winrate, search.PnL, progressBar, progress,
-		foundCount, processedTotal,
+		search.Winrate, search.PnL, ageFilterText(search.MinAgeDays), progressBar, progress,
+		foundCount, search.MaxCredits, processedTotal,
 		map[bool]string{true: "Scanning...", false: "Waiting"}[isScanning])
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
@@ -526,14 +568,56 @@ func handleContinueSearch(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery) {
 	bot.Send(tgbotapi.NewCallback(query.ID, "Continuing search..."))
 }
 
+// unseen returns the wallets the search hasn't found yet, each once, so
+// nothing already paid for is charged again
+func (s *SearchSession) unseen(wallets []*storage.WalletData) []*storage.WalletData {
+	s.mu.RLock()
+	seen := make(map[string]bool, len(s.FoundWallets)+len(wallets))
+	for _, w := range s.FoundWallets {
+		seen[w.Wallet] = true
+	}
+	s.mu.RUnlock()
+
+	var fresh []*storage.WalletData
+	for _, w := range wallets {
+		if !seen[w.Wallet] {
+			seen[w.Wallet] = true
+			fresh = append(fresh, w)
+		}
+	}
+	return fresh
+}
+
+// settle refunds whatever the search charged beyond the unique wallets it
+// delivers and returns those wallets with the refund
+func (s *SearchSession) settle(db *storage.DB) ([]*storage.WalletData, int, error) {
+	s.mu.RLock()
+	found := storage.UniqueWallets(s.FoundWallets)
+	charged := s.CreditsSpent
+	s.mu.RUnlock()
+
+	// Only delivered wallets are paid for
+	refund, err := db.SettleCredits(s.ChatID, charged, len(found))
+	if err != nil {
+		return found, 0, err
+	}
+	if refund > 0 {
+		log.Printf("💎 Search for %d charged %d credits for %d wallets, refunded %d", s.ChatID, charged, len(found), refund)
+	}
+	return found, refund, nil
+}
+
 // sendSearchSummary sends final summary of found wallets
 func sendSearchSummary(bot *tgbotapi.BotAPI, chatID int64, stopReason string) {
+	// Removing the search claims it, so a cancel racing the search loop
+	// settles and summarizes it only once
 	searchMu.Lock()
 	search, exists := activeSearches[chatID]
 	if exists {
 		search.mu.Lock()
 		search.Active = false
 		search.mu.Unlock()
+		delete(activeSearches, chatID)
 	}
 	searchMu.Unlock()
 
@@ -541,17 +625,19 @@ func sendSearchSummary(bot *tgbotapi.BotAPI, chatID int64, stopReason string) {
 		return
 	}
 
+	foundWallets, refund, err := search.settle(scanner.db)
+	if err != nil {
+		log.Printf("Error refunding search credits for %d: %v", chatID, err)
+	} else if refund > 0 {
+		send(bot, chatID, fmt.Sprintf("💎 %d credits refunded for duplicate results.", refund))
+	}
+
 	search.mu.RLock()
-	foundWallets := search.FoundWallets
 	winrate := search.Winrate
 	pnl := search.PnL
+	ageFilter := ageFilterText(search.MinAgeDays)
 	search.mu.RUnlock()
 
-	// Remove from active searches
-	searchMu.Lock()
-	delete(activeSearches, chatID)
-	searchMu.Unlock()
-
 	// Send summary
 	statusIcon := "✅"
 	statusText := "Search Complete"
@@ -562,6 +648,9 @@ func sendSearchSummary(bot *tgbotapi.BotAPI, chatID int64, stopReason string) {
 	} else if stopReason == "exhausted" {
 		statusIcon = "✅"
 		statusText = "Search Complete (Source Exhausted)"
+	} else if stopReason == "budget_limit" {
+		statusIcon = "✅"
+		statusText = "Search Complete (Wallet Cap Reached)"
 	} else if stopReason == "credits" {
 		statusIcon = "⚠️"
 		statusText = "Search Stopped (Insufficient Credits)"
@@ -569,20 +658,20 @@ func sendSearchSummary(bot *tgbotapi.BotAPI, chatID int64, stopReason string) {
 
 	if len(foundWallets) == 0 {
 		text := fmt.Sprintf("%s *%s*\n\n"+
-			"Filters: WR ≥ %.2f%%, PnL ≥ %.2f%%\n\n"+
+			"Filters: WR ≥ %.2f%%, %s%s\n\n"+
 			"❌ No wallets found matching your criteria.\n\n"+
 			"Try lowering your filters or wait for the next scan cycle.",
-			statusIcon, statusText, winrate, pnl)
+			statusIcon, statusText, winrate, pnl, ageFilter)
 		send(bot, chatID, text)
 		return
 	}
 
 	// Send header
 	headerText := fmt.Sprintf("%s *%s*\n\n"+
-		"Filters: WR ≥ %.2f%%, PnL ≥ %.2f%%\n\n"+
+		"Filters: WR ≥ %.2f%%, %s%s\n\n"+
 		"✅ Found *%d wallets* matching your criteria!\n\n"+
 		"━━━━━━━━━━━━━━━━━━━━",
-		statusIcon, statusText, winrate, pnl, len(foundWallets))
+		statusIcon, statusText, winrate, pnl, ageFilter, len(foundWallets))
 	send(bot, chatID, headerText)
 
 	// Send wallets in batches
@@ -598,8 +687,8 @@ func sendSearchSummary(bot *tgbotapi.BotAPI, chatID int64, stopReason string) {
 			w := foundWallets[j]
 			text += fmt.Sprintf("*Wallet %d*\n"+
 				"`%s`\n"+
-				"💹 WR: %.2f%% | 💰 PnL: %.2f%%\n\n",
-				j+1, w.Wallet, w.Winrate, w.RealizedPnL)
+				"%s\n\n",
+				j+1, w.Wallet, walletStatsLine(w))
 		}
 
 		if i+batchSize >= len(foundWallets) {
@@ -607,7 +696,7 @@ func sendSearchSummary(bot *tgbotapi.BotAPI, chatID int64, stopReason string) {
 				"🎉 End of results"
 		}
 
-		send(bot, chatID, text)
+		sendLong(bot, chatID, text)
 		time.Sleep(500 * time.Millisecond) // Avoid rate limiting
 	}
 }
@@ -653,21 +742,20 @@ func handleWinrateInputV2(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 
 	sessMu.Lock()
 	if sessions[chatID] == nil {
-		sessions[chatID] = &UserSession{}
+		sessions[chatID] = &UserSession{RequestedAt: time.Now().Unix()}
 	}
 	sessions[chatID].State = "awaiting_pnl_v2"
 	sessions[chatID].Winrate = winrate
 	sessMu.Unlock()
 
-	send(bot, chatID, "✅ Enter minimum *PnL* (e.g. 100):")
+	send(bot, chatID, pnlPrompt)
 }
 
 func handlePnlInputV2(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
-	pnl, err := strconv.ParseFloat(msg.Text, 64)
-
-	if err != nil || pnl < 25 {
-		sendError(bot, chatID, "Invalid input.")
+	pnl, err := parsePnLFilter(msg.Text)
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Invalid input: %v\n\n%s", err, pnlPrompt))
 		return
 	}
 
@@ -678,35 +766,56 @@ func handlePnlInputV2(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		sendError(bot, chatID, "Session expired.")
 		return
 	}
-	winrate := session.Winrate
-	scanType := session.ScanType
-	delete(sessions, chatID)
+	session.State = "awaiting_min_age_v2"
+	session.PnL = pnl
 	sessMu.Unlock()
 
-	startRealTimeSearch(bot, chatID, winrate, pnl, 0, scanType)
+	send(bot, chatID, "✅ Enter minimum *wallet age* in days (e.g. 30), or 0 to skip:")
 }
 
-func deliverDelayedResults(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, delaySeconds int) {
+// deliverDelayedResults delivers scan after delaySeconds unless it was
+// cancelled or delivered early in the meantime
+func deliverDelayedResults(ctx context.Context, bot *tgbotapi.BotAPI, scan *PendingScan, delaySeconds int) {
 	go func() {
 		select {
 		case <-time.After(time.Duration(delaySeconds) * time.Second):
-			deliverPendingScanResults(bot, chatID)
+			if takePendingScan(scan.UserID, scan) != nil {
+				sendPendingScan(bot, scan)
+			}
 		case <-ctx.Done():
 			return
 		}
 	}()
 }
 
-func deliverPendingScanResults(bot *tgbotapi.BotAPI, chatID int64) {
+// deliverPendingScanResults sends a user's pending slow scan and reports
+// whether there was one
+func deliverPendingScanResults(bot *tgbotapi.BotAPI, chatID int64) bool {
+	scan := takePendingScan(chatID, nil)
+	if scan == nil {
+		return false
+	}
+	sendPendingScan(bot, scan)
+	return true
+}
+
+// takePendingScan removes and returns chatID's pending scan. If want is
+// set, only that exact scan is taken, so a stale timer can't deliver a
+// scan queued after its own was cancelled.
+func takePendingScan(chatID int64, want *PendingScan) *PendingScan {
 	pendingScansMu.Lock()
+	defer pendingScansMu.Unlock()
 	scan, exists := pendingScans[chatID]
-	if !exists {
-		pendingScansMu.Unlock()
-		return
+	if !exists || (want != nil && scan != want) {
+		return nil
 	}
 	delete(pendingScans, chatID)
-	pendingScansMu.Unlock()
+	return scan
+}
 
+// sendPendingScan sends the results of a slow scan
+func sendPendingScan(bot *tgbotapi.BotAPI, scan *PendingScan) {
+	chatID := scan.UserID
 	if len(scan.Results) == 0 {
 		send(bot, chatID, "❌ Slow Scan Complete: No wallets found.")
 		return
@@ -725,8 +834,83 @@ func deliverPendingScanResults(bot *tgbotapi.BotAPI, chatID int64) {
 		text := ""
 		for j := i; j < end; j++ {
 			w := scan.Results[j]
-			text += fmt.Sprintf("*Wallet %d*\n`%s`\n💹 WR: %.2f%% | 💰 PnL: %.2f%%\n\n", j+1, w.Wallet, w.Winrate, w.RealizedPnL)
+			text += fmt.Sprintf("*Wallet %d*\n`%s`\n%s\n\n", j+1, w.Wallet, walletStatsLine(w))
 		}
 		send(bot, chatID, text)
 	}
 }
+
+// handleCancelPendingScan drops the user's pending slow scan and refunds
+// the credits its results cost
+func handleCancelPendingScan(bot *tgbotapi.BotAPI, chatID int64) {
+	scan := takePendingScan(chatID, nil)
+	if scan == nil {
+		send(bot, chatID, "📭 You have no pending slow scan.")
+		return
+	}
+
+	text := "🛑 *Pending Scan Cancelled*"
+	if scan.CreditsSpent > 0 {
+		if err := scanner.db.RefundUserCredits(chatID, scan.CreditsSpent); err != nil {
+			log.Printf("Error refunding %d credits to %d: %v", scan.CreditsSpent, chatID, err)
+			sendError(bot, chatID, "Scan cancelled, but the credit refund failed. Please contact the admin.")
+			return
+		}
+		text += fmt.Sprintf("\n\n💎 %d credits refunded.", scan.CreditsSpent)
+	}
+	send(bot, chatID, text)
+}
+
+// pendingScanETA reports whether chatID has a slow scan waiting for
+// delivery and how long until it arrives
+func pendingScanETA(chatID int64) (time.Duration, bool) {
+	pendingScansMu.RLock()
+	defer pendingScansMu.RUnlock()
+	scan, exists := pendingScans[chatID]
+	if !exists {
+		return 0, false
+	}
+	eta := scan.DeliverAt.Sub(scanner.db.Now())
+	if eta < 0 {
+		eta = 0
+	}
+	return eta, true
+}
+
+// beginSlowScan marks chatID as running a slow scan unless one is already
+// running or waiting for delivery, so two scans can't both be charged
+func beginSlowScan(chatID int64) bool {
+	pendingScansMu.Lock()
+	defer pendingScansMu.Unlock()
+	if runningScans[chatID] || pendingScans[chatID] != nil {
+		return false
+	}
+	runningScans[chatID] = true
+	return true
+}
+
+// endSlowScan clears the running mark of a slow scan that stopped before
+// queueing its results
+func endSlowScan(chatID int64) {
+	pendingScansMu.Lock()
+	delete(runningScans, chatID)
+	pendingScansMu.Unlock()
+}
+
+// mergeScanResults appends the wallets scan doesn't already hold and
+// returns how many were added. Callers hold pendingScansMu.
+func mergeScanResults(scan *PendingScan, results []*storage.WalletData) int {
+	seen := make(map[string]bool, len(scan.Results))
+	for _, w := range scan.Results {
+		seen[w.Wallet] = true
+	}
+	added := 0
+	for _, w := range results {
+		if !seen[w.Wallet] {
+			seen[w.Wallet] = true
+			scan.Results = append(scan.Results, w)
+			added++
+		}
+	}
+	return added
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"runtime/debug"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handlerFailedText is sent to a user whose update crashed its handler
+const handlerFailedText = "Something went wrong handling that. Please try again, or /start to return to the menu."
+
+// dispatchUpdate routes one Telegram update to its handler. Handlers run
+// in the update loop, so a panic in one is recovered here instead of
+// taking the whole bot down.
+func dispatchUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+	notify := func(chatID int64) { sendError(bot, chatID, handlerFailedText) }
+
+	if update.Message != nil {
+		msg := update.Message
+		safeHandle("message", msg.Chat.ID, notify, func() { handleMessage(bot, msg) })
+	} else if update.CallbackQuery != nil {
+		callback := update.CallbackQuery
+		var chatID int64
+		if callback.Message != nil {
+			chatID = callback.Message.Chat.ID
+		}
+		safeHandle("callback "+callback.Data, chatID, notify, func() { handleCallback(bot, callback) })
+	} else if update.InlineQuery != nil {
+		query := update.InlineQuery
+		// Inline queries have no chat to report the failure to
+		safeHandle("inline query", 0, notify, func() { handleInlineQuery(bot, query) })
+	}
+}
+
+// safeHandle runs handler, recovering a panic by logging it with its stack
+// and calling notify for chatID, if known. It reports whether the handler
+// panicked.
+func safeHandle(name string, chatID int64, notify func(chatID int64), handler func()) (panicked bool) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		panicked = true
+		log.Printf("❌ Panic in %s handler (chat %d): %v\n%s", name, chatID, r, debug.Stack())
+		if chatID != 0 {
+			// Reporting must not panic again, e.g. on a nil bot
+			safeHandle("panic report", 0, nil, func() { notify(chatID) })
+		}
+	}()
+	handler()
+	return false
+}
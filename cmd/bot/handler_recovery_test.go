@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestSafeHandleRecoversPanics(t *testing.T) {
+	var notified []int64
+	notify := func(chatID int64) { notified = append(notified, chatID) }
+
+	if safeHandle("ok", 1, notify, func() {}) {
+		t.Error("Expected a handler that returns not to be reported as panicked")
+	}
+
+	var tempData map[int64]string
+	if !safeHandle("nil map", 2, notify, func() { tempData[2] = "x" }) {
+		t.Error("Expected the nil map write to be recovered")
+	}
+	var addresses []string
+	if !safeHandle("index", 3, notify, func() { _ = addresses[0] }) {
+		t.Error("Expected the out of range index to be recovered")
+	}
+	// Without a chat there is no one to tell
+	safeHandle("inline query", 0, notify, func() { panic("boom") })
+
+	if len(notified) != 2 || notified[0] != 2 || notified[1] != 3 {
+		t.Errorf("Expected chats 2 and 3 notified, got %v", notified)
+	}
+
+	// A failing report doesn't take the bot down either
+	safeHandle("callback", 4, func(int64) { panic("send failed") }, func() { panic("boom") })
+
+	// The bot is still handling updates
+	handled := false
+	safeHandle("next", 5, notify, func() { handled = true })
+	if !handled {
+		t.Error("Expected the next update to be handled")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-orchestrator/trading"
+
+	"github.com/gagliardetto/solana-go"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Inline query limits. Telegram sends a query per keystroke, so only the
+// last one a user sends within inlineDebounce is answered; answers are
+// cached by Telegram for inlineCacheTime seconds on top of tokenInfoCache.
+const (
+	inlineDebounce  = 500 * time.Millisecond
+	inlineCacheTime = 30
+	inlineTimeout   = 8 * time.Second
+)
+
+// inlinePending holds the latest inline query ID per user
+var (
+	inlineMu      sync.Mutex
+	inlinePending = make(map[int64]string)
+)
+
+// handleInlineQuery answers "@bot <address>" from any chat with token info
+// or, for users with a plan, cached scan stats of a wallet
+func handleInlineQuery(bot *tgbotapi.BotAPI, query *tgbotapi.InlineQuery) {
+	if query.From == nil {
+		return
+	}
+	userID := query.From.ID
+
+	inlineMu.Lock()
+	inlinePending[userID] = query.ID
+	inlineMu.Unlock()
+
+	go func() {
+		time.Sleep(inlineDebounce)
+
+		inlineMu.Lock()
+		latest := inlinePending[userID] == query.ID
+		if latest {
+			delete(inlinePending, userID)
+		}
+		inlineMu.Unlock()
+		if !latest {
+			return // superseded by a later keystroke
+		}
+		// Only debounced queries count against the flood limit
+		if allowed, _ := updateLimiter.Allow(userID); !allowed {
+			return
+		}
+
+		answerInlineQuery(bot, query)
+	}()
+}
+
+func answerInlineQuery(bot *tgbotapi.BotAPI, query *tgbotapi.InlineQuery) {
+	text := strings.TrimSpace(query.Query)
+	if text == "" {
+		return // Telegram shows the inline placeholder until something is typed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inlineTimeout)
+	defer cancel()
+
+	result, personal := inlineLookup(ctx, query.From.ID, text)
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       []interface{}{result},
+		CacheTime:     inlineCacheTime,
+		IsPersonal:    personal,
+	}
+	if _, err := bot.Request(answer); err != nil {
+		log.Printf("⚠️ Failed to answer inline query from %d: %v", query.From.ID, err)
+	}
+}
+
+// inlineLookup resolves an address to a single article. Scanned wallets
+// take precedence over tokens since both are plain public keys; wallet
+// stats are personal because only users with a plan may see them.
+func inlineLookup(ctx context.Context, userID int64, address string) (tgbotapi.InlineQueryResultArticle, bool) {
+	if _, err := solana.PublicKeyFromBase58(address); err != nil {
+		return inlineNotFound("Not a valid Solana address"), false
+	}
+
+	scanner.mu.RLock()
+	wallet := scanner.walletsCache[address]
+	scanner.mu.RUnlock()
+	if wallet != nil {
+		user, err := scanner.db.GetUser(userID)
+		if err != nil {
+			log.Printf("Error getting user %d for inline query: %v", userID, err)
+		}
+		if userPlan(user) == nil {
+			return inlineNotFound("Start the bot and pick a plan to look up wallets"), true
+		}
+		text := fmt.Sprintf("👛 *Wallet* `%s`\n\n%s\n📈 Trades: %d\n🕐 Scanned: %s",
+			wallet.Wallet, walletStatsLine(wallet), wallet.TradeCount,
+			time.Unix(wallet.ScannedAt, 0).UTC().Format("2006-01-02 15:04 UTC"))
+		article := tgbotapi.NewInlineQueryResultArticleMarkdown("wallet:"+address, "👛 Wallet "+shortMint(address), text)
+		article.Description = fmt.Sprintf("WR %.1f%% · PnL %+.1f%%", wallet.Winrate, wallet.RealizedPnLPct)
+		return article, true
+	}
+
+	info, err := tokenInfoCache.Get(ctx, address)
+	if err != nil {
+		return inlineNotFound("No token or scanned wallet found"), false
+	}
+	return inlineTokenArticle(address, info), false
+}
+
+// inlineTokenArticle renders DexScreener token info as an article
+func inlineTokenArticle(mint string, info *trading.TokenInfo) tgbotapi.InlineQueryResultArticle {
+	price := "n/a"
+	if p, err := strconv.ParseFloat(info.PriceUSD, 64); err == nil {
+		price = "$" + strconv.FormatFloat(p, 'g', 6, 64)
+	}
+
+	text := fmt.Sprintf("🪙 *%s* (%s)\n`%s`\n\n"+
+		"💵 Price: %s (%+.2f%% 24h)\n"+
+		"💧 Liquidity: %s\n"+
+		"🏦 Market Cap: %s\n"+
+		"📊 Volume 24h: %s",
+		escapeMarkdown(info.Name), escapeMarkdown(info.Symbol), mint, price, info.Change24h,
+		formatUSD(info.Liquidity), formatUSD(info.MarketCap), formatUSD(info.Volume24h))
+	article := tgbotapi.NewInlineQueryResultArticleMarkdown("token:"+mint, fmt.Sprintf("🪙 %s (%s)", info.Name, info.Symbol), text)
+	article.Description = fmt.Sprintf("%s · Liq %s · MC %s", price, formatUSD(info.Liquidity), formatUSD(info.MarketCap))
+	return article
+}
+
+// inlineNotFound is the single article returned for input that can't be
+// resolved
+func inlineNotFound(reason string) tgbotapi.InlineQueryResultArticle {
+	article := tgbotapi.NewInlineQueryResultArticle("notfound", "❌ Not found", "❌ "+reason)
+	article.Description = reason
+	return article
+}
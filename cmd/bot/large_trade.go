@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"solana-orchestrator/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// confirmAboveOptions are the large-trade thresholds in settings, in SOL;
+// 0 turns the extra step off
+var confirmAboveOptions = []float64{0.5, 1, 2, 5, 10, 0}
+
+// confirmAboveText describes a large-trade threshold
+func confirmAboveText(sol float64) string {
+	if sol == 0 {
+		return "Off"
+	}
+	return fmt.Sprintf("%g SOL", sol)
+}
+
+// askLargeTradeConfirm asks the user to type want back before a large
+// trade goes on to the password
+func askLargeTradeConfirm(bot *tgbotapi.BotAPI, chatID int64, state, summary, want string) {
+	sessMu.Lock()
+	if session, ok := sessions[chatID]; ok {
+		session.State = state
+	}
+	sessMu.Unlock()
+
+	send(bot, chatID, fmt.Sprintf("%s\n\nType `%s` to confirm, or anything else to cancel:", summary, want))
+}
+
+// confirmsAmount reports whether text is the amount want typed back,
+// allowing a trailing unit or percent sign
+func confirmsAmount(text string, want float64) bool {
+	text = strings.TrimSpace(strings.ToLower(text))
+	text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(text, "sol"), "%"))
+	got, err := strconv.ParseFloat(text, 64)
+	return err == nil && math.Abs(got-want) < 1e-9
+}
+
+// handleBuyLargeConfirmInput continues a large buy once its amount is
+// typed back, and cancels it otherwise
+func handleBuyLargeConfirmInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	buyData, ok := tempBuyData.Get(chatID)
+	if !ok {
+		send(bot, chatID, "❌ Session expired")
+		cleanupBuySession(chatID)
+		return
+	}
+	if !confirmsAmount(msg.Text, buyData.SOLAmount) {
+		sendWarning(bot, chatID, "Amount didn't match. Purchase cancelled.")
+		cleanupBuySession(chatID)
+		return
+	}
+	buyData.LargeConfirmed = true
+	handleConfirmBuy(bot, chatID)
+}
+
+// handleSellLargeConfirmInput continues a large sale once its percentage
+// is typed back, and cancels it otherwise
+func handleSellLargeConfirmInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	sellData, ok := tempSellData.Get(chatID)
+	if !ok {
+		send(bot, chatID, "❌ Session expired")
+		cleanupSellSession(chatID)
+		return
+	}
+	if !confirmsAmount(msg.Text, float64(sellData.Percentage)) {
+		sendWarning(bot, chatID, "Percentage didn't match. Sale cancelled.")
+		cleanupSellSession(chatID)
+		return
+	}
+	sellData.LargeConfirmed = true
+	handleConfirmSell(bot, chatID)
+}
+
+// handleSettingsConfirm shows the large-trade threshold and auto-confirm
+func handleSettingsConfirm(bot *tgbotapi.BotAPI, chatID int64) {
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err != nil {
+		log.Printf("Error loading settings: %v", err)
+		settings = &storage.UserSettings{ConfirmAboveSOL: storage.DefaultConfirmAboveSOL}
+	}
+
+	message := "✋ *Trade Confirmation*\n\n"
+	message += "Trades worth more than the threshold need the amount typed back before the password.\n\n"
+	message += "With auto-confirm on, smaller trades skip the Confirm button."
+
+	thresholdButton := func(sol float64) tgbotapi.InlineKeyboardButton {
+		label := confirmAboveText(sol)
+		if settings.ConfirmAboveSOL == sol {
+			label = "✅ " + label
+		}
+		return tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("set_confirm_above:%g", sol))
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, sol := range confirmAboveOptions {
+		row = append(row, thresholdButton(sol))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("⚡ Auto-Confirm Small Trades: %s", onOff(settings.AutoConfirm)), "toggle_auto_confirm"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "open_settings"),
+		),
+	)
+
+	msgConfig := tgbotapi.NewMessage(chatID, message)
+	msgConfig.ParseMode = "Markdown"
+	msgConfig.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	bot.Send(msgConfig)
+}
+
+// handleSetConfirmAbove updates the large-trade threshold
+func handleSetConfirmAbove(bot *tgbotapi.BotAPI, chatID int64, value string) {
+	sol, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		err = scanner.db.UpdateConfirmAboveSOL(chatID, sol)
+	}
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Error updating confirmation threshold: %v", err))
+		return
+	}
+	send(bot, chatID, fmt.Sprintf("✅ Large trade confirmation: %s", confirmAboveText(sol)))
+	handleSettingsConfirm(bot, chatID)
+}
+
+// handleToggleAutoConfirm flips auto-confirm for small trades
+func handleToggleAutoConfirm(bot *tgbotapi.BotAPI, chatID int64) {
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err == nil {
+		err = scanner.db.UpdateAutoConfirm(chatID, !settings.AutoConfirm)
+	}
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Error updating auto-confirm: %v", err))
+		return
+	}
+	handleSettingsConfirm(bot, chatID)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestConfirmsAmount(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+		ok   bool
+	}{
+		{"2.5", 2.5, true},
+		{" 2.50 SOL ", 2.5, true},
+		{"100%", 100, true},
+		{"25", 2.5, false},
+		{"yes", 2.5, false},
+		{"", 2.5, false},
+	}
+	for _, tt := range tests {
+		if got := confirmsAmount(tt.text, tt.want); got != tt.ok {
+			t.Errorf("confirmsAmount(%q, %g) = %v, want %v", tt.text, tt.want, got, tt.ok)
+		}
+	}
+}
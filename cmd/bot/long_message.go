@@ -0,0 +1,180 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxMessageLen is Telegram's limit on message text, in UTF-16 code units
+const maxMessageLen = 4096
+
+const codeFence = "```"
+
+// sendLong sends text as Markdown, split into as many messages as it
+// takes to stay under Telegram's length limit
+func sendLong(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	sendLongWithKeyboard(bot, chatID, text, nil)
+}
+
+// sendLongWithKeyboard is sendLong with an inline keyboard attached to the
+// last message
+func sendLongWithKeyboard(bot *tgbotapi.BotAPI, chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	chunks := splitMessage(text, maxMessageLen)
+	for i, chunk := range chunks {
+		msg := tgbotapi.NewMessage(chatID, chunk)
+		msg.ParseMode = "Markdown"
+		if keyboard != nil && i == len(chunks)-1 {
+			msg.ReplyMarkup = *keyboard
+		}
+		if _, err := bot.Send(msg); err != nil {
+			log.Printf("Error sending message part %d/%d to %d: %v", i+1, len(chunks), chatID, err)
+		}
+	}
+}
+
+// splitMessage breaks text into chunks of at most limit UTF-16 code units.
+// Chunks end on line boundaries, and a code block is only split when it
+// can't fit in a chunk of its own, in which case every part is closed and
+// reopened so each chunk stays valid Markdown. Lines longer than limit
+// are broken at the last space that fits.
+func splitMessage(text string, limit int) []string {
+	if textLen(text) <= limit {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current []string
+	currentLen := 0
+	flush := func() {
+		if chunk := strings.Join(current, "\n"); strings.TrimSpace(chunk) != "" {
+			chunks = append(chunks, chunk)
+		}
+		current, currentLen = nil, 0
+	}
+
+	for _, segment := range messageSegments(text) {
+		for _, piece := range fitSegment(segment, limit) {
+			n := textLen(piece)
+			if len(current) > 0 && currentLen+1+n > limit {
+				flush()
+			}
+			if len(current) > 0 {
+				currentLen++ // joining newline
+			}
+			current = append(current, piece)
+			currentLen += n
+		}
+	}
+	flush()
+	return chunks
+}
+
+// messageSegments splits text into single lines and whole code blocks. An
+// unterminated code block runs to the end of the text.
+func messageSegments(text string) []string {
+	var segments []string
+	var block []string
+	for _, line := range strings.Split(text, "\n") {
+		fence := strings.HasPrefix(strings.TrimSpace(line), codeFence)
+		switch {
+		case block != nil:
+			block = append(block, line)
+			if fence {
+				segments = append(segments, strings.Join(block, "\n"))
+				block = nil
+			}
+		case fence && strings.Count(line, codeFence) == 1:
+			block = []string{line}
+		default:
+			segments = append(segments, line)
+		}
+	}
+	if block != nil {
+		segments = append(segments, strings.Join(block, "\n"))
+	}
+	return segments
+}
+
+// fitSegment splits a segment longer than limit into pieces that fit
+func fitSegment(segment string, limit int) []string {
+	if textLen(segment) <= limit {
+		return []string{segment}
+	}
+	if !strings.HasPrefix(strings.TrimSpace(segment), codeFence) {
+		return splitLine(segment, limit)
+	}
+
+	lines := strings.Split(segment, "\n")
+	open, body := lines[0], lines[1:]
+	if n := len(body); n > 0 && strings.HasPrefix(strings.TrimSpace(body[n-1]), codeFence) {
+		body = body[:n-1]
+	}
+
+	// Each piece is wrapped in its own fences
+	budget := limit - textLen(open) - len(codeFence) - 2
+	var pieces []string
+	var current []string
+	currentLen := 0
+	flush := func() {
+		pieces = append(pieces, open+"\n"+strings.Join(current, "\n")+"\n"+codeFence)
+		current, currentLen = nil, 0
+	}
+	for _, line := range body {
+		for _, part := range splitLine(line, budget) {
+			n := textLen(part)
+			if len(current) > 0 && currentLen+1+n > budget {
+				flush()
+			}
+			if len(current) > 0 {
+				currentLen++
+			}
+			current = append(current, part)
+			currentLen += n
+		}
+	}
+	if len(current) > 0 {
+		flush()
+	}
+	return pieces
+}
+
+// splitLine breaks a single line into parts of at most limit, preferring
+// to break after a space
+func splitLine(line string, limit int) []string {
+	var parts []string
+	runes := []rune(line)
+	for textLen(string(runes)) > limit {
+		cut, n, lastSpace := 0, 0, -1
+		for cut < len(runes) {
+			w := utf16.RuneLen(runes[cut])
+			if w < 0 {
+				w = 1
+			}
+			if n+w > limit {
+				break
+			}
+			if runes[cut] == ' ' {
+				lastSpace = cut
+			}
+			n += w
+			cut++
+		}
+		if lastSpace > 0 {
+			cut = lastSpace + 1
+		}
+		if cut == 0 {
+			cut = 1 // always make progress, even past the limit
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	return append(parts, string(runes))
+}
+
+// textLen is the length of s as Telegram counts it
+func textLen(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
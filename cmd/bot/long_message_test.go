@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestSplitMessage tests that a long message is split into chunks under
+// the limit without losing lines or leaving code blocks open
+func TestSplitMessage(t *testing.T) {
+	var b strings.Builder
+	for i := 0; b.Len() < 6000; i++ {
+		fmt.Fprintf(&b, "*Wallet %d* `So11111111111111111111111111111111111111112` 💹 WR: 61.00%%\n", i)
+	}
+	b.WriteString("```\n")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, "line %02d of a code block that must stay fenced\n", i)
+	}
+	b.WriteString("```\n")
+	for b.Len() < 10000 {
+		b.WriteString("━━━━━━━━━━━━━━━━━━━━\n")
+	}
+	text := b.String()
+
+	chunks := splitMessage(text, maxMessageLen)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected multiple chunks for %d chars, got %d", len(text), len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if n := textLen(chunk); n > maxMessageLen {
+			t.Errorf("Chunk %d is %d units, over the %d limit", i, n, maxMessageLen)
+		}
+		if strings.Count(chunk, codeFence)%2 != 0 {
+			t.Errorf("Chunk %d leaves a code block open", i)
+		}
+	}
+
+	joined := strings.Join(chunks, "\n")
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if !strings.Contains(joined, line) {
+			t.Fatalf("Line %q was lost", line)
+		}
+	}
+}
+
+// TestSplitMessageLongCodeBlock tests that a code block too big for one
+// chunk is closed and reopened in every part
+func TestSplitMessageLongCodeBlock(t *testing.T) {
+	text := "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 50) + "```"
+
+	chunks := splitMessage(text, 200)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the code block to be split, got %d chunk", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if textLen(chunk) > 200 {
+			t.Errorf("Chunk %d is over the limit: %d", i, textLen(chunk))
+		}
+		if !strings.HasPrefix(chunk, "```go\n") || !strings.HasSuffix(chunk, "\n```") {
+			t.Errorf("Chunk %d is not fenced: %q", i, chunk)
+		}
+	}
+}
+
+// TestSplitMessageShort tests that short messages and overlong lines are
+// handled
+func TestSplitMessageShort(t *testing.T) {
+	if chunks := splitMessage("hello", maxMessageLen); len(chunks) != 1 || chunks[0] != "hello" {
+		t.Errorf("Expected short text untouched, got %q", chunks)
+	}
+
+	line := strings.Repeat("word ", 100)
+	chunks := splitMessage(line, 64)
+	for i, chunk := range chunks {
+		if textLen(chunk) > 64 {
+			t.Errorf("Chunk %d is over the limit: %d", i, textLen(chunk))
+		}
+		if strings.HasPrefix(chunk, "ord") {
+			t.Errorf("Chunk %d splits a word: %q", i, chunk)
+		}
+	}
+}
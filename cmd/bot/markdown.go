@@ -0,0 +1,26 @@
+package main
+
+import "strings"
+
+// markdownEscaper backslash-escapes the characters legacy Telegram
+// Markdown treats as entity markers
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"_", `\_`,
+	"*", `\*`,
+	"`", "\\`",
+	"[", `\[`,
+)
+
+// escapeMarkdown makes user- or token-supplied text safe to interpolate
+// into a Markdown message, outside of code spans
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeMarkdownCode makes text safe inside a `code` span. Legacy
+// Markdown has no escapes within code, so backticks are swapped for
+// quotes instead.
+func escapeMarkdownCode(s string) string {
+	return strings.ReplaceAll(s, "`", "'")
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+// TestEscapeMarkdown tests escaping of Markdown entity markers
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"BONK", "BONK"},
+		{"my_wallet", `my\_wallet`},
+		{"*PEPE*", `\*PEPE\*`},
+		{"a`b", "a\\`b"},
+		{"[link](x)", `\[link](x)`},
+		{`back\slash_`, `back\\slash\_`},
+		{"🚀 MOON_*", `🚀 MOON\_\*`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := escapeMarkdown(tt.in); got != tt.want {
+			t.Errorf("escapeMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestEscapeMarkdownCode tests that code spans can't be closed early
+func TestEscapeMarkdownCode(t *testing.T) {
+	if got := escapeMarkdownCode("main`wallet_1*"); got != "main'wallet_1*" {
+		t.Errorf("Unexpected code text %q", got)
+	}
+}
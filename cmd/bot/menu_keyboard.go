@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Persistent reply keyboard labels, matched in handleMessage
+const (
+	menuDevFinder = "🔍 Dev Finder"
+	menuCredits   = "💰 My Credits"
+	menuSettings  = "⚙️ Settings"
+	menuHelp      = "❓ Help/FAQ"
+)
+
+// botCommands are the slash commands Telegram suggests to every user
+var botCommands = []tgbotapi.BotCommand{
+	{Command: "start", Description: "Start the bot"},
+	{Command: "menu", Description: "Open the main menu"},
+	{Command: "status", Description: "Scanner status"},
+	{Command: "balance", Description: "Credits and wallet balance"},
+	{Command: "portfolio", Description: "Token holdings with live prices"},
+	{Command: "monitor", Description: "Watch scan progress live"},
+	{Command: "wallets", Description: "Manage trading wallets"},
+	{Command: "buy", Description: "Buy a token"},
+	{Command: "sell", Description: "Sell a token"},
+	{Command: "panic", Description: "Sell every token in the wallet"},
+	{Command: "orders", Description: "Open limit orders"},
+	{Command: "cancelorder", Description: "Cancel a limit order"},
+	{Command: "token", Description: "Look up a token"},
+	{Command: "copytrade", Description: "Copy trading targets"},
+	{Command: "copystats", Description: "Copy trading results"},
+	{Command: "papertrade", Description: "Simulated copy trading results"},
+	{Command: "rank", Description: "Rank scanned wallets"},
+	{Command: "cancelscan", Description: "Cancel your pending slow scan"},
+	{Command: "ping", Description: "Check the bot is online"},
+	{Command: "hidekeyboard", Description: "Hide the quick menu keyboard"},
+}
+
+// adminCommands are added to the list in the admin's chat only
+var adminCommands = []tgbotapi.BotCommand{
+	{Command: "admin", Description: "Admin dashboard"},
+	{Command: "killswitch", Description: "Halt or resume all trading"},
+	{Command: "tradelog", Description: "Inspect a trade's stages"},
+	{Command: "scanhistory", Description: "Recent scan cycles"},
+	{Command: "deliver", Description: "Deliver a user's pending scan now"},
+	{Command: "backup", Description: "Back up the database"},
+	{Command: "restore", Description: "Restore a database backup"},
+}
+
+// registerCommands publishes the command list so Telegram shows hints
+// when users type "/". Failures are logged; the bot works without them.
+func registerCommands(bot *tgbotapi.BotAPI) {
+	if _, err := bot.Request(tgbotapi.NewSetMyCommands(botCommands...)); err != nil {
+		log.Printf("⚠️ Failed to register bot commands: %v", err)
+		return
+	}
+
+	admin := append(append([]tgbotapi.BotCommand{}, botCommands...), adminCommands...)
+	scope := tgbotapi.NewBotCommandScopeChat(AdminUserID)
+	if _, err := bot.Request(tgbotapi.NewSetMyCommandsWithScope(scope, admin...)); err != nil {
+		log.Printf("⚠️ Failed to register admin commands: %v", err)
+	}
+}
+
+// persistentKeyboard is the reply keyboard kept under the input field
+func persistentKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	keyboard := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(menuDevFinder),
+			tgbotapi.NewKeyboardButton(menuCredits),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(menuSettings),
+			tgbotapi.NewKeyboardButton(menuHelp),
+		),
+	)
+	keyboard.InputFieldPlaceholder = "Choose an option or type a command"
+	return keyboard
+}
+
+// sendMenuKeyboard shows the persistent reply keyboard. Telegram only
+// attaches a reply keyboard to a message, so it comes with a short note.
+func sendMenuKeyboard(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, "⌨️ Quick menu enabled. Send /hidekeyboard to hide it, /menu to bring it back.")
+	msg.ReplyMarkup = persistentKeyboard()
+	bot.Send(msg)
+}
+
+// handleHideKeyboard removes the persistent reply keyboard
+func handleHideKeyboard(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, "⌨️ Quick menu hidden. Send /menu to show it again.")
+	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(false)
+	bot.Send(msg)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-orchestrator/engine"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Live monitor limits
+const (
+	monitorRefresh = 3 * time.Second
+	monitorMaxLife = 10 * time.Minute
+)
+
+// monitorLoop is one running /monitor refresh loop
+type monitorLoop struct {
+	cancel context.CancelFunc
+}
+
+// activeMonitors tracks chats with a live /monitor message so repeated
+// commands don't stack refresh loops
+var (
+	monitorMu      sync.Mutex
+	activeMonitors = make(map[int64]*monitorLoop)
+)
+
+// handleMonitorCommand shows scan progress and keeps the message updated
+// until the scan finishes
+func handleMonitorCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	if redisClient == nil {
+		sendWarning(bot, chatID, "Scan monitor unavailable: Redis is not connected")
+		return
+	}
+
+	progress, err := engine.GetScanProgress(context.Background(), redisClient)
+	if err != nil {
+		sendError(bot, chatID, "Failed to read scan progress")
+		log.Printf("Error reading scan progress: %v", err)
+		return
+	}
+	if progress == nil {
+		send(bot, chatID, "📡 *Scan Monitor*\n\nNo scan activity in the last hour.")
+		return
+	}
+
+	text := formatScanProgress(progress, time.Now())
+	msgConfig := tgbotapi.NewMessage(chatID, text)
+	msgConfig.ParseMode = "Markdown"
+	sent, err := bot.Send(msgConfig)
+	if err != nil || !progress.IsScanning {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), monitorMaxLife)
+	loop := &monitorLoop{cancel: cancel}
+	monitorMu.Lock()
+	if prev, ok := activeMonitors[chatID]; ok {
+		prev.cancel()
+	}
+	activeMonitors[chatID] = loop
+	monitorMu.Unlock()
+
+	go runMonitor(ctx, loop, bot, chatID, sent.MessageID, text)
+}
+
+// runMonitor edits the monitor message every few seconds while the scan
+// runs. Unchanged text is skipped since Telegram rejects no-op edits.
+func runMonitor(ctx context.Context, loop *monitorLoop, bot *tgbotapi.BotAPI, chatID int64, messageID int, last string) {
+	defer func() {
+		loop.cancel()
+		// A newer /monitor may have replaced this loop already
+		monitorMu.Lock()
+		if activeMonitors[chatID] == loop {
+			delete(activeMonitors, chatID)
+		}
+		monitorMu.Unlock()
+	}()
+
+	ticker := time.NewTicker(monitorRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		progress, err := engine.GetScanProgress(ctx, redisClient)
+		if err != nil || progress == nil {
+			return
+		}
+
+		text := formatScanProgress(progress, time.Now())
+		if text != last {
+			editMessage(bot, chatID, messageID, text)
+			last = text
+		}
+		if !progress.IsScanning {
+			return
+		}
+	}
+}
+
+// formatScanProgress renders a progress snapshot with a text progress bar
+func formatScanProgress(p *engine.ScanProgress, now time.Time) string {
+	const barWidth = 20
+
+	status := "✅ Finished"
+	if p.IsScanning {
+		status = "🔄 Scanning"
+	}
+
+	filled := int(p.Percent() / 100 * barWidth)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+
+	message := "📡 *Scan Monitor*\n\n"
+	message += fmt.Sprintf("*Status:* %s\n", status)
+	message += fmt.Sprintf("`%s` %.0f%%\n\n", bar, p.Percent())
+	message += fmt.Sprintf("🔍 *Scanned:* %d / %d\n", p.ScannedCount, p.TotalWallets)
+	message += fmt.Sprintf("💎 *Found:* %d wallets\n", p.FoundWallets)
+	message += fmt.Sprintf("⏱ *Elapsed:* %s\n", p.Elapsed(now).Truncate(time.Second))
+	if p.IsScanning {
+		message += fmt.Sprintf("\n_Updated every %ds_", int(monitorRefresh.Seconds()))
+	}
+	return message
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// TestRunMonitorKeepsNewerLoop tests that a replaced monitor loop exiting
+// doesn't unregister the loop that replaced it
+func TestRunMonitorKeepsNewerLoop(t *testing.T) {
+	const chatID = 42
+	oldCtx, oldCancel := context.WithCancel(context.Background())
+	old := &monitorLoop{cancel: oldCancel}
+	_, newCancel := context.WithCancel(context.Background())
+	defer newCancel()
+	newer := &monitorLoop{cancel: newCancel}
+
+	monitorMu.Lock()
+	activeMonitors[chatID] = newer
+	monitorMu.Unlock()
+	defer func() {
+		monitorMu.Lock()
+		delete(activeMonitors, chatID)
+		monitorMu.Unlock()
+	}()
+
+	// The old loop was cancelled when the newer one started
+	oldCancel()
+	runMonitor(oldCtx, old, nil, chatID, 0, "")
+
+	monitorMu.Lock()
+	defer monitorMu.Unlock()
+	if activeMonitors[chatID] != newer {
+		t.Error("Expected the newer monitor to stay registered")
+	}
+}
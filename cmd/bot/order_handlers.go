@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	iengine "solana-orchestrator/internal/engine"
+	isolana "solana-orchestrator/internal/solana"
+	"solana-orchestrator/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// orderAction is a cancel or edit of a limit order waiting for the
+// wallet password
+type orderAction struct {
+	OrderID  int64
+	NewPrice float64 // target price in SOL for an edit, 0 to cancel
+}
+
+var pendingOrderActions = newChatStore[*orderAction]()
+
+// handleOrdersCommand lists the user's open limit orders with buttons to
+// cancel or edit each
+func handleOrdersCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	orders, err := scanner.db.GetOpenOrders(chatID)
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Failed to load orders: %v", err))
+		return
+	}
+	if len(orders) == 0 {
+		send(bot, chatID, "📋 You have no open limit orders.")
+		return
+	}
+
+	message := "📋 *Open Limit Orders*\n\n"
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, o := range orders {
+		message += fmt.Sprintf("*#%d* %s %s @ %.9g SOL\n", o.ID, strings.ToUpper(o.Side), escapeMarkdown(orderToken(o)), o.Price)
+		message += fmt.Sprintf("   Amount: %g | Expires in %s\n\n", o.Amount, time.Until(time.Unix(o.ExpiresAt, 0)).Round(time.Minute))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("❌ Cancel #%d", o.ID), fmt.Sprintf("order_cancel:%d", o.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✏️ Edit #%d", o.ID), fmt.Sprintf("order_edit:%d", o.ID)),
+		))
+	}
+	sendWithKeyboard(bot, chatID, message, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// orderToken names an order's token by symbol, or by mint when unknown
+func orderToken(o *storage.LimitOrder) string {
+	if o.TokenSymbol != "" {
+		return o.TokenSymbol
+	}
+	return shortAddr(o.TokenMint)
+}
+
+// handleCancelOrderCommand cancels an order by ID: /cancelorder <id>.
+// Without an ID it lists the open orders to pick from.
+func handleCancelOrderCommand(bot *tgbotapi.BotAPI, chatID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		handleOrdersCommand(bot, chatID)
+		return
+	}
+	handleOrderCancel(bot, chatID, strings.TrimPrefix(strings.TrimSpace(args), "#"))
+}
+
+// openOrder loads one of the user's orders by ID, telling them when it is
+// unknown or no longer open
+func openOrder(bot *tgbotapi.BotAPI, chatID int64, idStr string) *storage.LimitOrder {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		sendWarning(bot, chatID, "Usage: `/cancelorder <order id>`")
+		return nil
+	}
+	order, err := scanner.db.GetLimitOrder(chatID, id)
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Failed to load order: %v", err))
+		return nil
+	}
+	if order == nil {
+		sendWarning(bot, chatID, fmt.Sprintf("You have no order #%d.", id))
+		return nil
+	}
+	if order.Status != storage.OrderOpen {
+		sendWarning(bot, chatID, fmt.Sprintf("Order #%d is no longer open (%s).", id, order.Status))
+		return nil
+	}
+	return order
+}
+
+// handleOrderCancel asks for the password to cancel an order
+func handleOrderCancel(bot *tgbotapi.BotAPI, chatID int64, idStr string) {
+	if tradingHalted(bot, chatID) {
+		return
+	}
+	order := openOrder(bot, chatID, idStr)
+	if order == nil {
+		return
+	}
+
+	pendingOrderActions.Set(chatID, &orderAction{OrderID: order.ID})
+	setOrderState(chatID, "awaiting_order_password")
+	send(bot, chatID, fmt.Sprintf("❌ Cancelling order #%d\n\n🔐 *Enter your wallet password:*\n\n⚠️ Message will be deleted for security", order.ID))
+}
+
+// handleOrderEdit asks for an order's new target price
+func handleOrderEdit(bot *tgbotapi.BotAPI, chatID int64, idStr string) {
+	if tradingHalted(bot, chatID) {
+		return
+	}
+	order := openOrder(bot, chatID, idStr)
+	if order == nil {
+		return
+	}
+
+	pendingOrderActions.Set(chatID, &orderAction{OrderID: order.ID})
+	setOrderState(chatID, "awaiting_order_price")
+	send(bot, chatID, fmt.Sprintf("✏️ *Edit order #%d*\n\nCurrent target: %.9g SOL\n\nEnter the new target price in SOL:", order.ID, order.Price))
+}
+
+// handleOrderPriceInput takes the new target price of an order being
+// edited and asks for the password
+func handleOrderPriceInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	action, ok := pendingOrderActions.Get(chatID)
+	if !ok {
+		send(bot, chatID, "❌ Session expired. Send /orders again.")
+		cleanupOrderSession(chatID)
+		return
+	}
+
+	price, err := strconv.ParseFloat(strings.TrimSpace(msg.Text), 64)
+	if err != nil || price <= 0 {
+		sendError(bot, chatID, "Invalid price!\n\nEnter the new target price in SOL (e.g., 0.00001):")
+		return
+	}
+	action.NewPrice = price
+
+	setOrderState(chatID, "awaiting_order_password")
+	send(bot, chatID, "🔐 *Enter your wallet password:*\n\n⚠️ Message will be deleted for security")
+}
+
+// handleOrderPassword unlocks the wallet and cancels or replaces the order
+func handleOrderPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	password := msg.Text
+	bot.Request(tgbotapi.NewDeleteMessage(chatID, msg.MessageID))
+	defer cleanupOrderSession(chatID)
+
+	action, ok := pendingOrderActions.Get(chatID)
+	if !ok {
+		send(bot, chatID, "❌ Session expired. Send /orders again.")
+		return
+	}
+	if tradingHalted(bot, chatID) {
+		return
+	}
+
+	privateKey, err := unlockWallet(chatID, password)
+	if err != nil {
+		send(bot, chatID, unlockErrorMessage(err))
+		return
+	}
+
+	order := openOrder(bot, chatID, strconv.FormatInt(action.OrderID, 10))
+	if order == nil {
+		return
+	}
+
+	send(bot, chatID, "⏳ Sending to the network...")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if action.NewPrice == 0 {
+		err = limitOrders.CancelLimitOrder(ctx, order, &privateKey)
+	} else {
+		err = limitOrders.UpdateLimitOrder(ctx, order, action.NewPrice, &privateKey)
+	}
+	if errors.Is(err, isolana.ErrOrderClosed) {
+		sendWarning(bot, chatID, fmt.Sprintf("Order #%d already filled or closed, nothing was changed.", order.ID))
+		return
+	}
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Failed to update order #%d: %v", order.ID, err))
+		return
+	}
+
+	if action.NewPrice == 0 {
+		send(bot, chatID, fmt.Sprintf("✅ Order #%d cancelled", order.ID))
+		return
+	}
+	send(bot, chatID, fmt.Sprintf("✅ Order #%d replaced at %.9g SOL. See /orders", order.ID, action.NewPrice))
+}
+
+// forwardJanitorNotices delivers the janitor's order notices, such as
+// expiry refunds, to their users. Notices are plain text, so symbols
+// aren't parsed as Markdown.
+func forwardJanitorNotices(bot *tgbotapi.BotAPI, notices <-chan iengine.Notification) {
+	for note := range notices {
+		bot.Send(tgbotapi.NewMessage(note.UserID, note.Msg))
+	}
+}
+
+// setOrderState moves the chat's session to an order flow state
+func setOrderState(chatID int64, state string) {
+	sessMu.Lock()
+	sessions[chatID] = &UserSession{
+		State:       state,
+		RequestedAt: time.Now().Unix(),
+	}
+	sessMu.Unlock()
+}
+
+// cleanupOrderSession ends an order cancel or edit
+func cleanupOrderSession(chatID int64) {
+	sessMu.Lock()
+	delete(sessions, chatID)
+	sessMu.Unlock()
+
+	pendingOrderActions.Delete(chatID)
+}
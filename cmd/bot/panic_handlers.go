@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-orchestrator/engine"
+	"solana-orchestrator/storage"
+	"solana-orchestrator/trading"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Panic sell limits
+const (
+	// panicDustLamports skips positions worth less than their sell fee
+	panicDustLamports = 100_000
+	// panicFeeLamports is reserved per sell, as in the single sell flow
+	panicFeeLamports = 1_000_000
+	panicTimeout     = 15 * time.Second
+)
+
+// panicPosition is a token queued for a panic sell
+type panicPosition struct {
+	Mint        string
+	Amount      uint64 // raw units; the whole balance is sold
+	UIAmount    float64
+	EstLamports uint64
+}
+
+// panicPlans holds the positions a user confirmed with /panic until they
+// enter their password
+var (
+	panicMu    sync.Mutex
+	panicPlans = make(map[int64][]panicPosition)
+)
+
+// handlePanicCommand quotes a 100% sell of every token in the wallet and
+// asks for confirmation
+func handlePanicCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	if tradingHalted(bot, chatID) {
+		return
+	}
+	if !scanner.db.HasEncryptedWallet(chatID) {
+		sendWarning(bot, chatID, "No wallet found!\n\nUse /wallets to create or import a wallet first.")
+		return
+	}
+	wallet, err := scanner.db.GetEncryptedWallet(chatID)
+	if err != nil || wallet == nil {
+		sendError(bot, chatID, "Failed to load wallet")
+		return
+	}
+	walletPubkey, err := solana.PublicKeyFromBase58(wallet.PublicKey)
+	if err != nil {
+		sendError(bot, chatID, "Invalid wallet address")
+		return
+	}
+
+	send(bot, chatID, "🚨 Quoting a sell of every token you hold...")
+
+	apiClient := newAPIClient(globalCfg)
+	balanceMgr := trading.NewBalanceManagerWithAPI(getShyftRPCURL(), nil, apiClient)
+
+	ctx, cancel := context.WithTimeout(context.Background(), panicTimeout)
+	tokenBalances, err := balanceMgr.GetTokenBalances(ctx, walletPubkey)
+	cancel()
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Failed to fetch token balances: %v", err))
+		return
+	}
+
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err != nil {
+		settings = &storage.UserSettings{SlippageBps: 500}
+	}
+
+	var plan []panicPosition
+	var skipped []string
+	var totalLamports uint64
+	for _, tb := range tokenBalances {
+		if tb.Amount == 0 {
+			continue
+		}
+		mint := tb.Mint.String()
+
+		ctx, cancel := context.WithTimeout(context.Background(), panicTimeout)
+		quote, err := trading.GetSellQuote(ctx, mint, tb.Amount, settings.SlippageBps)
+		cancel()
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("`%s` – %s", shortMint(mint), tradeErrorReason(err)))
+			continue
+		}
+
+		out, _ := strconv.ParseUint(quote.OutAmount, 10, 64)
+		if out < panicDustLamports {
+			skipped = append(skipped, fmt.Sprintf("`%s` – dust", shortMint(mint)))
+			continue
+		}
+
+		plan = append(plan, panicPosition{Mint: mint, Amount: tb.Amount, UIAmount: tb.UIAmount, EstLamports: out})
+		totalLamports += out
+	}
+
+	if len(plan) == 0 {
+		message := "🚨 *Panic Sell*\n\nNothing to sell."
+		if len(skipped) > 0 {
+			message += "\n\n*Skipped:*\n" + strings.Join(skipped, "\n")
+		}
+		send(bot, chatID, message)
+		return
+	}
+
+	panicMu.Lock()
+	panicPlans[chatID] = plan
+	panicMu.Unlock()
+
+	message := "🚨 *Panic Sell – Confirm*\n\n"
+	message += fmt.Sprintf("Sell *100%%* of %d tokens:\n\n", len(plan))
+	for _, p := range plan {
+		message += fmt.Sprintf("▫️ `%s` · %.4f → ~%.6f SOL\n", shortMint(p.Mint), p.UIAmount, trading.FormatSOL(p.EstLamports))
+	}
+	message += fmt.Sprintf("\n💵 *Est. total:* ~%.6f SOL\n", trading.FormatSOL(totalLamports))
+	if len(skipped) > 0 {
+		message += "\n*Skipped:*\n" + strings.Join(skipped, "\n") + "\n"
+	}
+	message += "\n⚠️ Final amounts depend on market slippage"
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🚨 Sell All", "panic_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "panic_cancel"),
+		),
+	)
+	msgConfig := tgbotapi.NewMessage(chatID, message)
+	msgConfig.ParseMode = "Markdown"
+	msgConfig.ReplyMarkup = keyboard
+	bot.Send(msgConfig)
+}
+
+// handlePanicConfirm asks for the wallet password once for the whole batch
+func handlePanicConfirm(bot *tgbotapi.BotAPI, chatID int64) {
+	if tradingHalted(bot, chatID) {
+		cleanupPanicSession(chatID)
+		return
+	}
+
+	panicMu.Lock()
+	_, ok := panicPlans[chatID]
+	panicMu.Unlock()
+	if !ok {
+		send(bot, chatID, "❌ Session expired. Send /panic again.")
+		return
+	}
+
+	sessMu.Lock()
+	sessions[chatID] = &UserSession{
+		State:       "awaiting_panic_password",
+		RequestedAt: time.Now().Unix(),
+	}
+	sessMu.Unlock()
+
+	send(bot, chatID, "🔐 *Enter your wallet password:*\n\n⚠️ Message will be deleted for security")
+}
+
+// handlePanicCancel drops a pending panic sell
+func handlePanicCancel(bot *tgbotapi.BotAPI, chatID int64) {
+	cleanupPanicSession(chatID)
+	send(bot, chatID, "✅ Panic sell cancelled. Nothing was sold.")
+}
+
+// handlePanicPassword unlocks the wallet and sells each planned position
+// in turn, then reports every result
+func handlePanicPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	password := msg.Text
+	bot.Request(tgbotapi.NewDeleteMessage(chatID, msg.MessageID))
+
+	panicMu.Lock()
+	plan, ok := panicPlans[chatID]
+	panicMu.Unlock()
+	if !ok {
+		send(bot, chatID, "❌ Session expired. Send /panic again.")
+		cleanupPanicSession(chatID)
+		return
+	}
+	if tradingHalted(bot, chatID) {
+		cleanupPanicSession(chatID)
+		return
+	}
+
+	privateKey, err := unlockWallet(chatID, password)
+	if err != nil {
+		send(bot, chatID, unlockErrorMessage(err))
+		cleanupPanicSession(chatID)
+		return
+	}
+
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err != nil {
+		settings = &storage.UserSettings{SlippageBps: 500}
+	}
+
+	rpcURL := getShyftRPCURL()
+	rpcClient := rpc.New(rpcURL)
+
+	// Every sell pays a fee and may open the temporary wSOL account
+	ctx, cancel := context.WithTimeout(context.Background(), panicTimeout)
+	wsolATA, err := trading.EnsureATA(ctx, rpcClient, privateKey.PublicKey(), solana.SolMint)
+	if err == nil {
+		var solBalance *rpc.GetBalanceResult
+		solBalance, err = rpcClient.GetBalance(ctx, privateKey.PublicKey(), rpc.CommitmentConfirmed)
+		if err == nil {
+			required := wsolATA.RentLamports + panicFeeLamports*uint64(len(plan))
+			if solBalance.Value < required {
+				cancel()
+				send(bot, chatID, fmt.Sprintf("❌ *Insufficient SOL for fees!*\n\nSelling %d tokens needs ~%.6f SOL, you have %.6f SOL.",
+					len(plan), trading.FormatSOL(required), trading.FormatSOL(solBalance.Value)))
+				cleanupPanicSession(chatID)
+				return
+			}
+		}
+	}
+	cancel()
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Failed to check balance: %v", err))
+		cleanupPanicSession(chatID)
+		return
+	}
+
+	send(bot, chatID, fmt.Sprintf("⏳ Selling %d tokens...", len(plan)))
+
+	var report []string
+	succeeded := 0
+	for i, pos := range plan {
+		// The switch may be flipped mid-batch; stop before the next sell
+		if !killSwitch.TradingEnabled(context.Background()) {
+			for _, rest := range plan[i:] {
+				report = append(report, fmt.Sprintf("⏸ `%s` – trading halted", shortMint(rest.Mint)))
+			}
+			break
+		}
+
+		sig, err := panicSell(bot, chatID, rpcClient, rpcURL, privateKey, pos, settings)
+		if err != nil {
+			log.Printf("Panic sell of %s for %d failed: %v", pos.Mint, chatID, err)
+			report = append(report, fmt.Sprintf("❌ `%s` – %s", shortMint(pos.Mint), tradeErrorReason(err)))
+			continue
+		}
+		succeeded++
+		report = append(report, fmt.Sprintf("✅ `%s` – `%s`", shortMint(pos.Mint), engine.AbbreviateSignature(sig.String())))
+	}
+	cleanupPanicSession(chatID)
+
+	message := fmt.Sprintf("🚨 *Panic Sell Finished*\n\n%d of %d sells submitted:\n\n", succeeded, len(plan))
+	message += strings.Join(report, "\n")
+	message += "\n\n⏳ Check /portfolio once the transactions confirm."
+	send(bot, chatID, message)
+}
+
+// panicSell re-quotes and submits a 100% sell of one position. A fresh
+// quote is taken since the ones shown for confirmation may be stale.
+func panicSell(bot *tgbotapi.BotAPI, chatID int64, rpcClient *rpc.Client, rpcURL string, privateKey solana.PrivateKey, pos panicPosition, settings *storage.UserSettings) (solana.Signature, error) {
+	trade := tradeLogger.Begin(chatID, "sell")
+	ctx, cancel := context.WithTimeout(context.Background(), panicTimeout)
+	defer cancel()
+
+	quote, err := trading.GetSellQuote(ctx, pos.Mint, pos.Amount, settings.SlippageBps)
+	trade.Stage(engine.StageQuote, err, fmt.Sprintf("panic: %d units of %s, slippage %d bps", pos.Amount, pos.Mint, settings.SlippageBps))
+	if err != nil {
+		return solana.Signature{}, err
+	}
+
+	priorityFee := settings.PriorityFeeLamports
+	if mint, err := solana.PublicKeyFromBase58(pos.Mint); err == nil {
+		priorityFee = trading.ResolvePriorityFee(ctx, rpcClient, priorityFee, []solana.PublicKey{mint})
+	}
+	swapResp, err := trading.GetSwapTransaction(ctx, quote, privateKey.PublicKey().String(), priorityFee)
+	trade.Stage(engine.StageBuild, err, "")
+	if err != nil {
+		return solana.Signature{}, err
+	}
+
+	txBytes, err := base64.StdEncoding.DecodeString(swapResp.SwapTransaction)
+	if err != nil {
+		trade.Stage(engine.StageBuild, err, "decode transaction")
+		return solana.Signature{}, fmt.Errorf("decode transaction: %w", err)
+	}
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(txBytes))
+	if err != nil {
+		trade.Stage(engine.StageBuild, err, "deserialize transaction")
+		return solana.Signature{}, fmt.Errorf("deserialize transaction: %w", err)
+	}
+
+	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(privateKey.PublicKey()) {
+			return &privateKey
+		}
+		return nil
+	})
+	if err == nil {
+		trade.SetSignature(tx.Signatures[0].String())
+	}
+	trade.Stage(engine.StageSign, err, "")
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("sign transaction: %w", err)
+	}
+
+	sig, err := rpcClient.SendTransaction(ctx, tx)
+	trade.Stage(engine.StageSubmit, err, "rpc")
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	go trackConfirmation(bot, chatID, trade, rpcURL, sig, swapResp.LastValidBlockHeight)
+	return sig, nil
+}
+
+// cleanupPanicSession drops the pending plan and password prompt
+func cleanupPanicSession(chatID int64) {
+	panicMu.Lock()
+	delete(panicPlans, chatID)
+	panicMu.Unlock()
+
+	sessMu.Lock()
+	delete(sessions, chatID)
+	sessMu.Unlock()
+}
+
+// tokenLabel returns a mint's cached symbol, or the shortened mint when
+// it can't be resolved
+func tokenLabel(mint string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	symbol, err := trading.ResolveSymbol(ctx, mint)
+	if err != nil || symbol == "" {
+		return shortMint(mint)
+	}
+	return symbol
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"solana-orchestrator/storage"
+	"solana-orchestrator/trading"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// paymentVerifyTimeout bounds the RPC lookup of a submitted payment
+const paymentVerifyTimeout = 20 * time.Second
+
+// handleTopUpCredits shows the credit packages and the treasury address
+func handleTopUpCredits(bot *tgbotapi.BotAPI, chatID int64) {
+	packages := globalCfg.CreditPackages()
+
+	text := "💎 *Top Up Credits*\n\n"
+	text += "Packages:\n"
+	for _, p := range packages {
+		text += fmt.Sprintf("• %d Credits: %g SOL\n", p.Credits, p.PriceSOL)
+	}
+
+	treasury := globalCfg.Payments.TreasuryAddress
+	if treasury == "" || len(packages) == 0 {
+		text += "\nTo purchase more credits, please contact the admin:\n@AdminUser"
+		send(bot, chatID, text)
+		return
+	}
+
+	sessMu.Lock()
+	sessions[chatID] = &UserSession{
+		State:       "awaiting_payment_signature",
+		RequestedAt: time.Now().Unix(),
+	}
+	sessMu.Unlock()
+
+	text += "\n━━━━━━━━━━━━━━━━━━━━\n"
+	text += "1️⃣ Send exactly the package price in SOL to:\n"
+	text += fmt.Sprintf("`%s`\n", treasury)
+	text += fmt.Sprintf("with this memo: `%s`\n\n", paymentReference(chatID))
+	text += "2️⃣ Paste the transaction signature here.\n\n"
+	text += "_Payments without your memo can't be credited to you._\n"
+	text += fmt.Sprintf("_Payments older than %d hours are not accepted._", globalCfg.Payments.MaxTxAgeHours)
+	send(bot, chatID, text)
+}
+
+// handlePaymentSignatureInput verifies a submitted payment and credits the user
+func handlePaymentSignatureInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	signature := strings.TrimSpace(msg.Text)
+
+	if _, err := solana.SignatureFromBase58(signature); err != nil {
+		send(bot, chatID, "❌ Invalid transaction signature. Please try again:")
+		return
+	}
+
+	treasury, err := solana.PublicKeyFromBase58(globalCfg.Payments.TreasuryAddress)
+	if err != nil {
+		log.Printf("Invalid treasury address in config: %v", err)
+		sendError(bot, chatID, "Payments are not available right now. Please contact the admin.")
+		return
+	}
+
+	send(bot, chatID, "⏳ Verifying payment on-chain...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), paymentVerifyTimeout)
+	defer cancel()
+
+	receipt, err := trading.VerifySOLPayment(ctx, rpc.New(globalCfg.Payments.RPCURL), signature, trading.PaymentCheck{
+		Treasury:  treasury,
+		Reference: paymentReference(chatID),
+		Prices:    globalCfg.PackagePrices(),
+		MaxAge:    time.Duration(globalCfg.Payments.MaxTxAgeHours) * time.Hour,
+		Processed: scanner.db.IsPaymentProcessed,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, trading.ErrPaymentReplayed):
+			sessMu.Lock()
+			delete(sessions, chatID)
+			sessMu.Unlock()
+			send(bot, chatID, "⚠️ This payment has already been credited.")
+		case errors.Is(err, trading.ErrPaymentNotFound):
+			send(bot, chatID, "❌ Transaction not found or not finalized yet. Wait a moment and send the signature again:")
+		case errors.Is(err, trading.ErrPaymentFailed):
+			send(bot, chatID, "❌ That transaction failed on-chain. Please send a successful payment signature:")
+		case errors.Is(err, trading.ErrPaymentNoTransfer):
+			send(bot, chatID, "❌ That transaction did not send SOL to the treasury address.")
+		case errors.Is(err, trading.ErrPaymentTooOld):
+			send(bot, chatID, "❌ That transaction is too old. Please contact the admin.")
+		case errors.Is(err, trading.ErrPaymentNoReference):
+			send(bot, chatID, fmt.Sprintf("❌ That transaction doesn't carry your memo `%s`, so it can't be credited to you. Please contact the admin.", paymentReference(chatID)))
+		case errors.Is(err, trading.ErrPaymentAmount):
+			send(bot, chatID, "❌ The amount paid doesn't match a package price exactly, so no credits were added. Please contact the admin for a refund.")
+		default:
+			log.Printf("Error verifying payment %s: %v", signature, err)
+			sendError(bot, chatID, "Could not verify the payment right now. Please try again later.")
+		}
+		return
+	}
+
+	pkg := globalCfg.PackageForPayment(receipt.Lamports)
+	if pkg == nil {
+		// VerifySOLPayment only accepts package prices
+		log.Printf("Payment %s of %d lamports matched no package", signature, receipt.Lamports)
+		sendError(bot, chatID, "Payment verified but crediting failed. Please contact the admin.")
+		return
+	}
+
+	err = scanner.db.CreditPayment(&storage.Payment{
+		Signature: signature,
+		UserID:    chatID,
+		Lamports:  receipt.Lamports,
+		PlanID:    pkg.ID,
+		Credits:   pkg.Credits,
+	})
+
+	sessMu.Lock()
+	delete(sessions, chatID)
+	sessMu.Unlock()
+
+	if errors.Is(err, storage.ErrPaymentAlreadyProcessed) {
+		send(bot, chatID, "⚠️ This payment has already been credited.")
+		return
+	}
+	if err != nil {
+		log.Printf("Error crediting payment %s for %d: %v", signature, chatID, err)
+		sendError(bot, chatID, "Payment verified but crediting failed. Please contact the admin.")
+		return
+	}
+
+	log.Printf("💎 Credited %d credits to %d for payment %s (%d lamports)", pkg.Credits, chatID, signature, receipt.Lamports)
+
+	text := fmt.Sprintf("✅ *Payment Received*\n\n📦 *Package:* %s\n💰 *Paid:* `%.4f SOL`\n➕ *Credits Added:* `%d`",
+		pkg.Name, float64(receipt.Lamports)/1e9, pkg.Credits)
+	if user, err := scanner.db.GetUser(chatID); err == nil && user != nil {
+		text += fmt.Sprintf("\n💎 *Balance:* `%d credits`", user.Credits)
+	}
+	send(bot, chatID, text)
+}
+
+// paymentReference is the memo that ties a payment to chatID. It only has
+// to be unique, not secret: paying with someone else's memo credits them.
+func paymentReference(chatID int64) string {
+	return fmt.Sprintf("topup-%d", chatID)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"solana-orchestrator/trading"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pingTimeout bounds each subsystem check of /ping
+const pingTimeout = 5 * time.Second
+
+// pingCheck is the outcome of one timed subsystem check
+type pingCheck struct {
+	Name    string
+	Latency time.Duration
+	Err     error
+}
+
+// handlePingCommand reports that the bot is online. Admins also get the
+// latency of every dependency and the fan-out engine's status.
+func handlePingCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	if !isAdmin(chatID) {
+		send(bot, chatID, "🏓 *Pong!* The bot is online.")
+		return
+	}
+
+	checks := map[string]func(ctx context.Context) error{
+		"RPC": func(ctx context.Context) error {
+			_, err := rpc.New(getShyftRPCURL()).GetHealth(ctx)
+			return err
+		},
+		"Jupiter": trading.PingJupiter,
+		"DB": func(ctx context.Context) error {
+			return scanner.db.PingContext(ctx)
+		},
+	}
+	if redisClient != nil {
+		checks["Redis"] = func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		}
+	}
+
+	send(bot, chatID, formatPing(runPingChecks(checks)))
+}
+
+// runPingChecks runs every check concurrently and returns the results in
+// a fixed order
+func runPingChecks(checks map[string]func(ctx context.Context) error) []pingCheck {
+	order := []string{"RPC", "Jupiter", "Redis", "DB"}
+	results := make([]pingCheck, len(order))
+
+	var wg sync.WaitGroup
+	for i, name := range order {
+		results[i].Name = name
+		check, ok := checks[name]
+		if !ok {
+			results[i].Err = fmt.Errorf("not configured")
+			continue
+		}
+		wg.Add(1)
+		go func(r *pingCheck, check func(ctx context.Context) error) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+			defer cancel()
+			start := time.Now()
+			r.Err = check(ctx)
+			r.Latency = time.Since(start)
+		}(&results[i], check)
+	}
+	wg.Wait()
+	return results
+}
+
+// formatPing renders the admin diagnostics
+func formatPing(results []pingCheck) string {
+	message := "🏓 *Pong!*\n\n"
+	for _, r := range results {
+		if r.Err != nil {
+			message += fmt.Sprintf("🔴 *%s:* `%v`\n", r.Name, r.Err)
+			continue
+		}
+		message += fmt.Sprintf("🟢 *%s:* %dms\n", r.Name, r.Latency.Milliseconds())
+	}
+
+	message += "\n━━━━━━━━━━━━━━━━━━━━\n"
+	if fanoutEngine == nil || !fanoutEngine.IsRunning() {
+		message += "🔴 *Fan-Out Engine:* offline\n"
+		return message
+	}
+	message += fmt.Sprintf("🟢 *Fan-Out Engine:* %d wallets monitored\n", fanoutEngine.GetMonitoredCount())
+	for _, s := range fanoutEngine.ConnectionStatus() {
+		state := "🟢 up"
+		if !s.Connected {
+			state = "🔴 down"
+		}
+		if s.UsingBackup {
+			state += " (backup)"
+		}
+		message += fmt.Sprintf("📶 Shard %d: %s · %d accounts\n", s.ID, state, s.Accounts)
+	}
+	return message
+}
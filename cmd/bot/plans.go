@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// userPlan returns the catalog entry for the user's plan, or nil
+func userPlan(user *storage.User) *config.PlanConfig {
+	if user == nil || user.PlanType == "" || globalCfg == nil {
+		return nil
+	}
+	return globalCfg.FindPlan(user.PlanType)
+}
+
+// searchBudget returns how many wallets one search may return: the plan's
+// cap, further limited by the credit balance where results cost credits
+func searchBudget(plan *config.PlanConfig, user *storage.User) int {
+	budget := config.DefaultMaxWalletsPerSearch
+	if plan != nil {
+		budget = plan.SearchCap()
+	}
+	if (plan == nil || plan.IsCredits()) && user.Credits < budget {
+		budget = user.Credits
+	}
+	return budget
+}
+
+// notifyLowCredits warns a credit user whose balance just dropped to the
+// low-credit threshold after deducted credits were spent. The notice goes
+// out at most once a day.
+func notifyLowCredits(bot *tgbotapi.BotAPI, chatID int64, deducted int) {
+	threshold := globalCfg.Payments.LowCreditThreshold
+	if threshold < 0 || deducted <= 0 {
+		return
+	}
+
+	// Re-read the live balance; other searches may be spending too
+	user, err := scanner.db.GetUser(chatID)
+	if err != nil || user == nil {
+		return
+	}
+	if user.Credits > threshold || user.Credits+deducted <= threshold {
+		return
+	}
+
+	ok, err := scanner.db.MarkLowCreditNotified(chatID, scanner.db.Now())
+	if err != nil {
+		log.Printf("Error recording low-credit notice for %d: %v", chatID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("🔋 *Running Low on Credits*\n\nYou have *%d* credits left.\nTop up to keep your searches running.", user.Credits))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💎 Top Up Credits", "top_up_credits"),
+		),
+	)
+	bot.Send(msg)
+}
+
+// activatePlan assigns a plan to a user, granting its credits and trial window
+func activatePlan(userID int64, plan *config.PlanConfig) error {
+	return scanner.db.SetUserPlan(userID, plan.ID, plan.Credits, plan.ExpiresAt(scanner.db.Now()))
+}
+
+// planEmoji returns the icon used for a plan in menus
+func planEmoji(plan *config.PlanConfig) string {
+	if plan.IsTrial() {
+		return "⏳"
+	}
+	return "💎"
+}
+
+// planActivatedText describes what the user got after activating a plan
+func planActivatedText(plan *config.PlanConfig) string {
+	text := fmt.Sprintf("✅ *Plan Activated: %s*\n\n", plan.Name)
+	if plan.IsCredits() {
+		text += fmt.Sprintf("You have %d credits. Each scan costs 1 credit.\n", plan.Credits)
+	} else {
+		text += fmt.Sprintf("You have unlimited scans for %s.\n", formatPlanDuration(plan.Duration()))
+	}
+	if plan.RealtimeScans {
+		text += "You can use both Real-Time and Slow scans."
+	} else {
+		text += fmt.Sprintf("⚠️ *Note:* All your scans will have a %s delay.", planDelayText(plan))
+	}
+	return text
+}
+
+// planDelaySeconds picks a random slow-scan delivery delay for a plan
+func planDelaySeconds(plan *config.PlanConfig) int {
+	min, max := config.DefaultScanDelayMinSec, config.DefaultScanDelayMaxSec
+	if plan != nil {
+		min, max = plan.ScanDelayRange()
+	}
+	if max <= min {
+		return min
+	}
+	return rand.Intn(max-min) + min
+}
+
+// planDelayText formats a plan's slow-scan delay range, e.g. "5-10 minute"
+func planDelayText(plan *config.PlanConfig) string {
+	min, max := plan.ScanDelayRange()
+	return fmt.Sprintf("%d-%d minute", min/60, max/60)
+}
+
+// formatPlanDuration renders a trial length in days or hours
+func formatPlanDuration(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d days", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%d hours", int(d.Hours()))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"solana-orchestrator/storage"
+)
+
+// Dev Finder PnL input bounds
+const (
+	minPnLPctFilter = 25.0
+	minPnLUSDFilter = 1.0
+)
+
+// pnlPrompt asks for a Dev Finder PnL filter, spelling out both units
+const pnlPrompt = "✅ Enter minimum *PnL*:\n• `150` → at least +150% return\n• `$500` → at least $500 profit"
+
+// pnlFilter is a minimum realized PnL, either a percent return on cost or
+// an absolute profit in USD
+type pnlFilter struct {
+	Min float64
+	USD bool
+}
+
+// parsePnLFilter reads "150" or "150%" as a percent and "$500" or
+// "500 usd" as a USD amount
+func parsePnLFilter(text string) (pnlFilter, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	var f pnlFilter
+	switch {
+	case strings.HasPrefix(text, "$"):
+		f.USD = true
+		text = strings.TrimPrefix(text, "$")
+	case strings.HasSuffix(text, "usd"):
+		f.USD = true
+		text = strings.TrimSuffix(text, "usd")
+	default:
+		text = strings.TrimSuffix(text, "%")
+	}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(text), ",", ""), 64)
+	if err != nil {
+		return f, fmt.Errorf("not a number")
+	}
+	f.Min = value
+	if f.USD && value < minPnLUSDFilter {
+		return f, fmt.Errorf("USD PnL must be at least $%.0f", minPnLUSDFilter)
+	}
+	if !f.USD && value < minPnLPctFilter {
+		return f, fmt.Errorf("PnL %% must be at least %.0f", minPnLPctFilter)
+	}
+	return f, nil
+}
+
+// Matches reports whether a wallet's realized PnL meets the filter
+func (f pnlFilter) Matches(w *storage.WalletData) bool {
+	if f.USD {
+		return w.RealizedPnLUSD >= f.Min
+	}
+	return w.RealizedPnLPct >= f.Min
+}
+
+// String renders the filter with its unit for filter summaries
+func (f pnlFilter) String() string {
+	if f.USD {
+		return "PnL ≥ " + formatUSD(f.Min)
+	}
+	return fmt.Sprintf("PnL ≥ %.2f%%", f.Min)
+}
+
+// formatUSD renders a dollar amount with thousands separators, e.g. -$1,234.50
+func formatUSD(v float64) string {
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return sign + "$" + whole + frac
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"solana-orchestrator/trading"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxPortfolioRows caps how many holdings are listed in one message
+const maxPortfolioRows = 15
+
+// tokenInfoCache is shared by portfolio views so refreshes stay cheap
+var tokenInfoCache = trading.NewTokenInfoCache()
+
+// handlePortfolioCommand shows the active wallet's holdings valued in USD
+func handlePortfolioCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	activeWallet, err := scanner.db.GetActiveWallet(chatID)
+	if err != nil {
+		sendError(bot, chatID, "Error retrieving active wallet")
+		log.Printf("Error getting active wallet: %v", err)
+		return
+	}
+	if activeWallet == nil {
+		sendWarning(bot, chatID, "No active wallet set!\n\nUse /wallets to add a wallet first.")
+		return
+	}
+
+	walletPubkey, err := solana.PublicKeyFromBase58(activeWallet.WalletAddress)
+	if err != nil {
+		sendError(bot, chatID, "Invalid wallet address")
+		return
+	}
+
+	loadingMsgConfig := tgbotapi.NewMessage(chatID, "⏳ Valuing portfolio...")
+	loadingMsg, _ := bot.Send(loadingMsgConfig)
+
+	apiClient := newAPIClient(globalCfg)
+	balanceMgr := trading.NewBalanceManagerWithAPI(getShyftRPCURL(), nil, apiClient)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fullBalance, err := balanceMgr.GetFullBalance(ctx, walletPubkey)
+	if err != nil {
+		editMessage(bot, chatID, loadingMsg.MessageID, fmt.Sprintf("❌ Error fetching balance: %v", err))
+		return
+	}
+
+	portfolio := trading.BuildPortfolio(ctx, fullBalance, tokenInfoCache.Get, trading.DefaultPriceWorkers)
+
+	text := formatPortfolio(activeWallet.WalletName, portfolio) + livePortfolioFooter
+	if _, err := bot.Send(portfolioEdit(chatID, loadingMsg.MessageID, text)); err != nil {
+		return
+	}
+
+	// Rebuilds the portfolio when live prices fall back to polling
+	refresh := func(ctx context.Context) (*trading.Portfolio, error) {
+		balance, err := balanceMgr.GetFullBalance(ctx, walletPubkey)
+		if err != nil {
+			return nil, err
+		}
+		return trading.BuildPortfolio(ctx, balance, tokenInfoCache.Get, trading.DefaultPriceWorkers), nil
+	}
+	startLivePortfolio(bot, chatID, loadingMsg.MessageID, activeWallet.WalletName, portfolio, text, refresh)
+}
+
+// portfolioEdit replaces a message with a portfolio view and its buttons
+func portfolioEdit(chatID int64, messageID int, text string) tgbotapi.EditMessageTextConfig {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Refresh", "refresh_portfolio"),
+		),
+	)
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = "Markdown"
+	edit.ReplyMarkup = &keyboard
+	return edit
+}
+
+// formatPortfolio renders a portfolio for Telegram
+func formatPortfolio(walletName string, p *trading.Portfolio) string {
+	message := "╔═══════════════════════╗\n"
+	message += "       💼 *PORTFOLIO*\n"
+	message += "╚═══════════════════════╝\n\n"
+	message += fmt.Sprintf("👛 *Wallet:* `%s`\n\n", escapeMarkdownCode(walletName))
+
+	message += fmt.Sprintf("💰 *Total:* `$%.2f` (`%.4f SOL`)\n", p.TotalUSD, p.TotalSOL)
+	message += fmt.Sprintf("%s *24h:* `%+.2f USD` (`%+.2f%%`)\n\n", changeEmoji(p.Change24hUSD), p.Change24hUSD, p.Change24hPct)
+
+	message += "━━━━━━━━━━━━━━━━━━━━\n"
+	if p.SOLPriceUSD > 0 {
+		message += fmt.Sprintf("◎ *SOL:* `%.4f` · `$%.2f`\n", p.SOL, p.SOL*p.SOLPriceUSD)
+	} else {
+		message += fmt.Sprintf("◎ *SOL:* `%.4f` · _unpriced_\n", p.SOL)
+	}
+
+	for i, h := range p.Holdings {
+		if i == maxPortfolioRows {
+			message += fmt.Sprintf("\n_…and %d more_\n", len(p.Holdings)-maxPortfolioRows)
+			break
+		}
+		symbol := escapeMarkdown(h.Symbol)
+		if symbol == "" {
+			symbol = shortMint(h.Mint)
+		}
+		if !h.Priced {
+			message += fmt.Sprintf("▫️ *%s:* `%.4f` · _unpriced_\n", symbol, h.UIAmount)
+			continue
+		}
+		message += fmt.Sprintf("▫️ *%s:* `%.4f` · `$%.2f` (%+.1f%%)\n", symbol, h.UIAmount, h.ValueUSD, h.Change24h)
+	}
+
+	if len(p.Holdings) == 0 {
+		message += "\n_No token holdings_\n"
+	}
+	return message
+}
+
+// changeEmoji picks an arrow for a price move
+func changeEmoji(change float64) string {
+	if change < 0 {
+		return "📉"
+	}
+	return "📈"
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"solana-orchestrator/trading"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Live portfolio limits. Streamed prices are batched into one edit per
+// portfolioLiveEdit to stay under Telegram's edit rate limit.
+const (
+	portfolioLiveEdit    = 3 * time.Second
+	portfolioLivePoll    = 30 * time.Second
+	portfolioLiveMaxLife = 10 * time.Minute
+)
+
+var livePortfolioFooter = fmt.Sprintf("\n_🔴 Live prices for %d min_", int(portfolioLiveMaxLife.Minutes()))
+
+// livePortfolio is one running /portfolio refresh loop
+type livePortfolio struct {
+	cancel context.CancelFunc
+}
+
+// activeLivePortfolios tracks chats with a live /portfolio message so
+// each user has at most one set of pool subscriptions
+var (
+	livePortfolioMu      sync.Mutex
+	activeLivePortfolios = make(map[int64]*livePortfolio)
+)
+
+// portfolioRefresher rebuilds a portfolio from fresh balances and prices
+type portfolioRefresher func(ctx context.Context) (*trading.Portfolio, error)
+
+// startLivePortfolio keeps a sent portfolio message updated, replacing
+// any live portfolio already running in the chat
+func startLivePortfolio(bot *tgbotapi.BotAPI, chatID int64, messageID int, walletName string, p *trading.Portfolio, text string, refresh portfolioRefresher) {
+	ctx, cancel := context.WithTimeout(context.Background(), portfolioLiveMaxLife)
+	live := &livePortfolio{cancel: cancel}
+	livePortfolioMu.Lock()
+	if prev, ok := activeLivePortfolios[chatID]; ok {
+		prev.cancel()
+	}
+	activeLivePortfolios[chatID] = live
+	livePortfolioMu.Unlock()
+
+	go runLivePortfolio(ctx, live, bot, chatID, messageID, walletName, p, text, refresh)
+}
+
+// runLivePortfolio streams pool prices for the largest positions over
+// the WebSocket, falling back to polling when it can't connect or drops
+func runLivePortfolio(ctx context.Context, live *livePortfolio, bot *tgbotapi.BotAPI, chatID int64, messageID int, walletName string, p *trading.Portfolio, last string, refresh portfolioRefresher) {
+	defer func() {
+		live.cancel()
+		// A newer /portfolio may have replaced this loop already
+		livePortfolioMu.Lock()
+		if activeLivePortfolios[chatID] == live {
+			delete(activeLivePortfolios, chatID)
+		}
+		livePortfolioMu.Unlock()
+	}()
+
+	render := func(p *trading.Portfolio) {
+		text := formatPortfolio(walletName, p) + livePortfolioFooter
+		if text != last {
+			bot.Send(portfolioEdit(chatID, messageID, text))
+			last = text
+		}
+	}
+
+	ws := trading.NewWSClient(getShyftWSURL())
+	if err := ws.Connect(ctx); err != nil {
+		log.Printf("Live portfolio for %d polling, WebSocket unavailable: %v", chatID, err)
+		pollPortfolio(ctx, render, refresh)
+		return
+	}
+	defer ws.Close()
+
+	stream := trading.NewPriceStream(rpc.New(getShyftRPCURL()), ws, p.SOLPriceUSD)
+	defer stream.Close()
+	if watchPortfolio(ctx, stream, p) == 0 {
+		pollPortfolio(ctx, render, refresh)
+		return
+	}
+
+	ticker := time.NewTicker(portfolioLiveEdit)
+	defer ticker.Stop()
+
+	dirty := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case u := <-stream.Updates():
+			if p.SetPrice(u.Mint, u.PriceUSD) {
+				dirty = true
+			}
+		case <-ticker.C:
+			if !ws.IsConnected() {
+				log.Printf("Live portfolio for %d polling, WebSocket dropped", chatID)
+				pollPortfolio(ctx, render, refresh)
+				return
+			}
+			if dirty {
+				render(p)
+				dirty = false
+			}
+		}
+	}
+}
+
+// watchPortfolio subscribes to the pools of the most valuable holdings,
+// up to trading.MaxStreamedPositions, and returns how many it watches
+func watchPortfolio(ctx context.Context, stream *trading.PriceStream, p *trading.Portfolio) int {
+	watched := 0
+	for _, h := range p.Holdings {
+		info, err := tokenInfoCache.Get(ctx, h.Mint)
+		if err != nil || info == nil || info.PairAddress == "" {
+			continue
+		}
+		err = stream.Watch(ctx, h.Mint, info.PairAddress)
+		if errors.Is(err, trading.ErrTooManyPositions) {
+			break
+		}
+		if err != nil {
+			if !errors.Is(err, trading.ErrUnsupportedPool) {
+				log.Printf("Live portfolio: can't watch %s: %v", h.Mint, err)
+			}
+			continue
+		}
+		watched++
+	}
+	return watched
+}
+
+// pollPortfolio rebuilds the portfolio every portfolioLivePoll until ctx
+// ends
+func pollPortfolio(ctx context.Context, render func(*trading.Portfolio), refresh portfolioRefresher) {
+	ticker := time.NewTicker(portfolioLivePoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p, err := refresh(ctx)
+		if err != nil {
+			log.Printf("Live portfolio refresh failed: %v", err)
+			continue
+		}
+		render(p)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"solana-orchestrator/engine"
+	"solana-orchestrator/storage"
+	"solana-orchestrator/trading"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// priceImpactLimits are the max price impact options in settings; 0 is
+// no limit
+var priceImpactLimits = []float64{1, 3, 5, 10, 25, 0}
+
+// priceImpactLimitText describes a max price impact setting
+func priceImpactLimitText(pct float64) string {
+	if pct == 0 {
+		return "Off"
+	}
+	return fmt.Sprintf("%g%%", pct)
+}
+
+// handleSettingsPriceImpact shows the max price impact options
+func handleSettingsPriceImpact(bot *tgbotapi.BotAPI, chatID int64) {
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err != nil {
+		log.Printf("Error loading settings: %v", err)
+		settings = &storage.UserSettings{MaxPriceImpactPct: storage.DefaultMaxPriceImpactPct}
+	}
+
+	message := "📉 *Max Price Impact*\n\n"
+	message += "How far a trade may move the token's price before you have to confirm it again.\n\n"
+	message += "Thin tokens can move tens of percent on a single buy or sell."
+
+	limitButton := func(pct float64) tgbotapi.InlineKeyboardButton {
+		label := priceImpactLimitText(pct)
+		if settings.MaxPriceImpactPct == pct {
+			label = "✅ " + label
+		}
+		return tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("set_impact:%g", pct))
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, pct := range priceImpactLimits {
+		row = append(row, limitButton(pct))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "open_settings"),
+	))
+
+	msgConfig := tgbotapi.NewMessage(chatID, message)
+	msgConfig.ParseMode = "Markdown"
+	msgConfig.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	bot.Send(msgConfig)
+}
+
+// handleSetPriceImpact updates the max price impact
+func handleSetPriceImpact(bot *tgbotapi.BotAPI, chatID int64, value string) {
+	pct, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		err = scanner.db.UpdateMaxPriceImpact(chatID, pct)
+	}
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Error updating max price impact: %v", err))
+		return
+	}
+	send(bot, chatID, fmt.Sprintf("✅ Max price impact set to: %s", priceImpactLimitText(pct)))
+	handleSettings(bot, chatID)
+}
+
+// quoteImpact returns a quote's price impact for a confirmation, ok false
+// when there is no quote to judge it by
+func quoteImpact(quote *trading.JupiterQuote, quoteErr error) (impact float64, ok bool) {
+	if quoteErr != nil {
+		return 0, false
+	}
+	impact, err := quote.PriceImpact()
+	return impact, err == nil
+}
+
+// priceImpactText is the confirmation line for a quote's price impact,
+// with a warning when it is over the user's limit
+func priceImpactText(impact float64, ok bool, settings *storage.UserSettings) string {
+	if !ok {
+		return "📉 *Price Impact:* unavailable\n"
+	}
+	if settings.PriceImpactExceeded(impact) {
+		return fmt.Sprintf("🚨 *Price Impact: %.2f%%* – above your %s limit!\n", impact, priceImpactLimitText(settings.MaxPriceImpactPct))
+	}
+	return fmt.Sprintf("📉 *Price Impact:* %.2f%%\n", impact)
+}
+
+// confirmKeyboard offers to confirm a trade, asking the user to accept its
+// price impact explicitly when it is over their limit
+func confirmKeyboard(confirm, cancel string, impact float64, exceeded bool) tgbotapi.InlineKeyboardMarkup {
+	confirmButton := tgbotapi.NewInlineKeyboardButtonData("✅ Confirm", confirm)
+	if exceeded {
+		confirmButton = tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("⚠️ Accept %.1f%% Impact", impact), confirm+"_impact")
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			confirmButton,
+			tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", cancel),
+		),
+	)
+}
+
+// buyImpact quotes a buy for its price impact, from Jupiter or, for tokens
+// Jupiter can't route yet, the Pump.fun bonding curve. ok is false when
+// neither can price it.
+func buyImpact(ctx context.Context, mint string, lamports uint64, slippageBps int) (impact float64, ok bool) {
+	quote, err := trading.GetBuyQuote(ctx, mint, lamports, slippageBps)
+	if curve := pumpFunCurve(ctx, mint, err); curve != nil {
+		return curve.BuyImpact(lamports), true
+	}
+	return quoteImpact(quote, err)
+}
+
+// sellImpact quotes a sale of amount raw units for its price impact, like
+// buyImpact
+func sellImpact(ctx context.Context, mint string, amount uint64, slippageBps int) (impact float64, ok bool) {
+	quote, err := trading.GetSellQuote(ctx, mint, amount, slippageBps)
+	if curve := pumpFunCurve(ctx, mint, err); curve != nil {
+		return curve.SellImpact(amount), true
+	}
+	return quoteImpact(quote, err)
+}
+
+// pumpFunCurve returns mint's bonding curve when Jupiter's quote failed
+// because the token hasn't graduated yet, nil otherwise
+func pumpFunCurve(ctx context.Context, mint string, quoteErr error) *trading.BondingCurve {
+	if quoteErr == nil || !trading.UsePumpFun(quoteErr) {
+		return nil
+	}
+	mintKey, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return nil
+	}
+	curve, err := trading.NewPumpFunSwap(rpc.New(getShyftRPCURL())).BondingCurve(ctx, mintKey)
+	if err != nil {
+		return nil
+	}
+	return curve
+}
+
+// impactBlocked stops a swap whose fresh quote moves the price more than
+// the user's limit, telling the user
+func impactBlocked(bot *tgbotapi.BotAPI, chatID int64, trade *engine.TradeLog, impact float64, settings *storage.UserSettings) bool {
+	if !settings.PriceImpactExceeded(impact) {
+		return false
+	}
+	trade.Stage(engine.StageQuote, fmt.Errorf("price impact %.2f%% over the %g%% limit", impact, settings.MaxPriceImpactPct), "")
+	send(bot, chatID, fmt.Sprintf("🚨 Price impact is now %.2f%%, above the %s you accepted. Trade cancelled; start again to review it.",
+		impact, priceImpactLimitText(settings.MaxPriceImpactPct)))
+	return true
+}
+
+// acceptImpact raises the user's limit for one trade to the impact they
+// accepted, so the fresh quote at execution only stops the trade if the
+// price impact got worse
+func acceptImpact(settings *storage.UserSettings, accepted float64) {
+	if settings.PriceImpactExceeded(accepted) {
+		settings.MaxPriceImpactPct = accepted
+	}
+}
+
+// handleAcceptBuyImpact confirms a buy whose price impact is over the
+// user's limit
+func handleAcceptBuyImpact(bot *tgbotapi.BotAPI, chatID int64) {
+	buyData, ok := tempBuyData.Get(chatID)
+	if !ok {
+		send(bot, chatID, "❌ Session expired")
+		cleanupBuySession(chatID)
+		return
+	}
+	buyData.ImpactAccepted = true
+	handleConfirmBuy(bot, chatID)
+}
+
+// handleAcceptSellImpact confirms a sell whose price impact is over the
+// user's limit
+func handleAcceptSellImpact(bot *tgbotapi.BotAPI, chatID int64) {
+	sellData, ok := tempSellData.Get(chatID)
+	if !ok {
+		send(bot, chatID, "❌ Session expired")
+		cleanupSellSession(chatID)
+		return
+	}
+	sellData.ImpactAccepted = true
+	handleConfirmSell(bot, chatID)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"solana-orchestrator/config"
+
+	"golang.org/x/time/rate"
+)
+
+// slowDownInterval limits how often a flooding user is told to slow down
+const slowDownInterval = 10 * time.Second
+
+// userLimiter is a per-chat token bucket for incoming updates
+type userLimiter struct {
+	mu          sync.Mutex
+	buckets     map[int64]*userBucket
+	limit       rate.Limit
+	burst       int
+	idleTimeout time.Duration
+}
+
+type userBucket struct {
+	limiter    *rate.Limiter
+	lastSeen   time.Time
+	lastWarned time.Time
+}
+
+var updateLimiter *userLimiter
+
+// newUserLimiter creates a limiter from the configured per-user rate
+func newUserLimiter(cfg config.RateLimits) *userLimiter {
+	return &userLimiter{
+		buckets:     make(map[int64]*userBucket),
+		limit:       rate.Limit(cfg.UserUpdatesPerSec),
+		burst:       cfg.UserBurst,
+		idleTimeout: time.Duration(cfg.UserIdleTimeoutSec) * time.Second,
+	}
+}
+
+// Allow reports whether chatID may send another update. When it may not,
+// warn is true at most once per slowDownInterval so the reply itself
+// doesn't flood the chat.
+func (l *userLimiter) Allow(chatID int64) (allowed, warn bool) {
+	if isAdmin(chatID) {
+		return true, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[chatID]
+	if !ok {
+		b = &userBucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[chatID] = b
+	}
+	b.lastSeen = now
+
+	if b.limiter.AllowN(now, 1) {
+		return true, false
+	}
+	if now.Sub(b.lastWarned) >= slowDownInterval {
+		b.lastWarned = now
+		return false, true
+	}
+	return false, false
+}
+
+// cleanupRoutine drops buckets for chats that have been idle
+func (l *userLimiter) cleanupRoutine() {
+	ticker := time.NewTicker(l.idleTimeout)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mu.Lock()
+		cutoff := time.Now().Add(-l.idleTimeout)
+		for chatID, b := range l.buckets {
+			if b.lastSeen.Before(cutoff) {
+				delete(l.buckets, chatID)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/engine"
+	"solana-orchestrator/storage"
+)
+
+// apiKeyHeader carries the results API key
+const apiKeyHeader = "X-API-Key"
+
+// resultsRequests counts results API requests by endpoint and status,
+// e.g. "wallets:200"; GET /debug/vars serves it with the other expvars
+var resultsRequests = expvar.NewMap("results_api_requests")
+
+// resultsStore is the storage the results API reads
+type resultsStore interface {
+	GetWallets() ([]*storage.WalletData, error)
+	GetWallet(address string) (*storage.WalletData, error)
+}
+
+// resultsAPI serves scan results to external consumers:
+//
+//	GET /wallets?min_wr=&min_pnl=&min_pnl_usd=&page=&per_page=
+//	GET /wallet/{addr}
+//	GET /status
+//	GET /debug/vars
+type resultsAPI struct {
+	db          resultsStore
+	apiKey      string
+	maxPageSize int
+	status      func() engine.ScanProgress
+}
+
+// walletsPage is the GET /wallets response
+type walletsPage struct {
+	Wallets []*storage.WalletData `json:"wallets"`
+	Page    int                   `json:"page"`
+	PerPage int                   `json:"per_page"`
+	Total   int                   `json:"total"`
+}
+
+func newResultsAPI(db resultsStore, cfg config.ResultsAPIConfig, status func() engine.ScanProgress) http.Handler {
+	a := &resultsAPI{db: db, apiKey: cfg.APIKey, maxPageSize: cfg.MaxPageSize, status: status}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /wallets", a.counted("wallets", a.handleWallets))
+	mux.HandleFunc("GET /wallet/{addr}", a.counted("wallet", a.handleWallet))
+	mux.HandleFunc("GET /status", a.counted("status", a.handleStatus))
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	return a.authenticated(mux)
+}
+
+// startResultsAPI serves the results API in the background if
+// results_api.listen_addr is set
+func startResultsAPI(cfg config.ResultsAPIConfig, db *storage.DB) {
+	if cfg.ListenAddr == "" {
+		return
+	}
+	srv := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           newResultsAPI(db, cfg, scannerProgress),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Printf("🌐 Results API listening on %s", cfg.ListenAddr)
+		if err := srv.ListenAndServe(); err != nil {
+			log.Printf("❌ Results API stopped: %v", err)
+		}
+	}()
+}
+
+// scannerProgress snapshots the scanner for GET /status
+func scannerProgress() engine.ScanProgress {
+	scanner.mu.RLock()
+	defer scanner.mu.RUnlock()
+	return engine.ScanProgress{
+		IsScanning:    scanner.isScanning,
+		ScannedCount:  scanner.scannedCount,
+		TotalWallets:  scanner.totalWallets,
+		FoundWallets:  len(scanner.walletsList),
+		LastUpdate:    time.Now().Unix(),
+		ScanStartTime: scanner.lastScanStart,
+	}
+}
+
+// authenticated rejects requests without the configured API key
+func (a *resultsAPI) authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(apiKeyHeader)
+		if a.apiKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(a.apiKey)) != 1 {
+			resultsRequests.Add("unauthorized", 1)
+			writeJSONError(w, http.StatusUnauthorized, "missing or invalid "+apiKeyHeader)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder remembers the status code a handler wrote
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// counted wraps a handler to count its requests by status
+func (a *resultsAPI) counted(endpoint string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler(rec, r)
+		resultsRequests.Add(endpoint+":"+strconv.Itoa(rec.status), 1)
+	}
+}
+
+func (a *resultsAPI) handleWallets(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var minWinrate, minPnL, minPnLUSD float64
+	page, perPage := 1, a.maxPageSize
+	for _, p := range []struct {
+		name string
+		dest *float64
+	}{{"min_wr", &minWinrate}, {"min_pnl", &minPnL}, {"min_pnl_usd", &minPnLUSD}} {
+		if raw := q.Get(p.name); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, p.name+" must be a number")
+				return
+			}
+			*p.dest = v
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dest *int
+	}{{"page", &page}, {"per_page", &perPage}} {
+		if raw := q.Get(p.name); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil || v < 1 {
+				writeJSONError(w, http.StatusBadRequest, p.name+" must be a positive integer")
+				return
+			}
+			*p.dest = v
+		}
+	}
+	if perPage > a.maxPageSize {
+		perPage = a.maxPageSize
+	}
+
+	wallets, err := a.db.GetWallets()
+	if err != nil {
+		log.Printf("Results API: failed to read wallets: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to read wallets")
+		return
+	}
+
+	// GetWallets is ordered best PnL first, so matches keep that order
+	matches := make([]*storage.WalletData, 0)
+	for _, wd := range wallets {
+		if wd.Winrate >= minWinrate && wd.RealizedPnLPct >= minPnL && wd.RealizedPnLUSD >= minPnLUSD {
+			matches = append(matches, wd)
+		}
+	}
+
+	resp := walletsPage{Wallets: []*storage.WalletData{}, Page: page, PerPage: perPage, Total: len(matches)}
+	if start := (page - 1) * perPage; start < len(matches) {
+		resp.Wallets = matches[start:min(start+perPage, len(matches))]
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *resultsAPI) handleWallet(w http.ResponseWriter, r *http.Request) {
+	wallet, err := a.db.GetWallet(r.PathValue("addr"))
+	if err != nil {
+		log.Printf("Results API: failed to read wallet: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to read wallet")
+		return
+	}
+	if wallet == nil {
+		writeJSONError(w, http.StatusNotFound, "wallet not scanned")
+		return
+	}
+	writeJSON(w, http.StatusOK, wallet)
+}
+
+func (a *resultsAPI) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.status())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/engine"
+	"solana-orchestrator/storage"
+)
+
+// fakeResultsStore serves wallets already ordered best PnL first
+type fakeResultsStore struct {
+	wallets []*storage.WalletData
+}
+
+func (f *fakeResultsStore) GetWallets() ([]*storage.WalletData, error) {
+	return f.wallets, nil
+}
+
+func (f *fakeResultsStore) GetWallet(address string) (*storage.WalletData, error) {
+	for _, w := range f.wallets {
+		if w.Wallet == address {
+			return w, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestResultsAPI(t *testing.T) {
+	store := &fakeResultsStore{}
+	for i := 0; i < 10; i++ {
+		store.wallets = append(store.wallets, &storage.WalletData{
+			Wallet:         fmt.Sprintf("wallet%d", i),
+			Winrate:        float64(40 + 5*i),
+			RealizedPnLPct: float64(500 - 40*i),
+			RealizedPnLUSD: float64(1000 * (10 - i)),
+		})
+	}
+	status := func() engine.ScanProgress {
+		return engine.ScanProgress{IsScanning: true, ScannedCount: 12, TotalWallets: 40, FoundWallets: 10}
+	}
+	srv := httptest.NewServer(newResultsAPI(store, config.ResultsAPIConfig{APIKey: "secret", MaxPageSize: 3}, status))
+	defer srv.Close()
+
+	get := func(path, key string, into interface{}) int {
+		t.Helper()
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if into != nil && resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+				t.Fatalf("GET %s returned bad JSON: %v", path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	t.Run("RequiresAPIKey", func(t *testing.T) {
+		for _, key := range []string{"", "wrong"} {
+			if code := get("/status", key, nil); code != http.StatusUnauthorized {
+				t.Errorf("Expected 401 with key %q, got %d", key, code)
+			}
+		}
+	})
+
+	t.Run("FiltersAndPages", func(t *testing.T) {
+		// WR >= 55 and PnL >= 200% leaves wallet3 through wallet7
+		var page walletsPage
+		if code := get("/wallets?min_wr=55&min_pnl=200&per_page=2&page=2", "secret", &page); code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", code)
+		}
+		if page.Total != 5 || page.Page != 2 || page.PerPage != 2 {
+			t.Errorf("Unexpected page %+v", page)
+		}
+		if len(page.Wallets) != 2 || page.Wallets[0].Wallet != "wallet5" || page.Wallets[1].Wallet != "wallet6" {
+			t.Errorf("Expected wallet5 and wallet6, got %+v", page.Wallets)
+		}
+
+		// Pages are capped at max_page_size, and past the end are empty
+		get("/wallets?per_page=100", "secret", &page)
+		if len(page.Wallets) != 3 || page.PerPage != 3 || page.Total != 10 {
+			t.Errorf("Expected a capped page of 3 of 10, got %d of %d", len(page.Wallets), page.Total)
+		}
+		get("/wallets?min_pnl_usd=8000&page=5", "secret", &page)
+		if len(page.Wallets) != 0 || page.Total != 3 {
+			t.Errorf("Expected an empty page past 3 matches, got %+v", page)
+		}
+
+		if code := get("/wallets?min_wr=high", "secret", nil); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a bad filter, got %d", code)
+		}
+	})
+
+	t.Run("Wallet", func(t *testing.T) {
+		var w storage.WalletData
+		if code := get("/wallet/wallet4", "secret", &w); code != http.StatusOK || w.Winrate != 60 {
+			t.Errorf("Expected wallet4 with WR 60, got %d %+v", code, w)
+		}
+		if code := get("/wallet/unknown", "secret", nil); code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unscanned wallet, got %d", code)
+		}
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		var p engine.ScanProgress
+		if code := get("/status", "secret", &p); code != http.StatusOK || !p.IsScanning || p.ScannedCount != 12 {
+			t.Errorf("Unexpected status %d %+v", code, p)
+		}
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		if got := resultsRequests.Get("wallet:404"); got == nil || got.String() != "1" {
+			t.Errorf("Expected 1 counted 404 for /wallet, got %v", got)
+		}
+		if got := resultsRequests.Get("unauthorized"); got == nil || got.String() != "2" {
+			t.Errorf("Expected 2 unauthorized requests, got %v", got)
+		}
+		var vars map[string]json.RawMessage
+		if code := get("/debug/vars", "secret", &vars); code != http.StatusOK || vars["results_api_requests"] == nil {
+			t.Errorf("Expected request counts in /debug/vars, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/storage"
+)
+
+// scanDemand is a user's search waiting on scan cycles, with the scan
+// performance their plan pays for
+type scanDemand struct {
+	winrate    float64
+	pnl        pnlFilter
+	minAgeDays int
+	priority   int
+	pages      int
+}
+
+// matches reports whether a wallet's last known stats meet the search's
+// filters
+func (d scanDemand) matches(w *storage.WalletData, now time.Time) bool {
+	return w.Winrate >= d.winrate && d.pnl.Matches(w) && w.MeetsMinAge(d.minAgeDays, now)
+}
+
+// scanDemands are the searches currently running, by registration ID
+var scanDemands = struct {
+	sync.Mutex
+	next int
+	m    map[int]scanDemand
+}{m: make(map[int]scanDemand)}
+
+// addScanDemand registers a search so scan cycles favor it as its plan
+// allows, until the returned func is called. Users without a plan get the
+// base pages and no priority.
+func addScanDemand(plan *config.PlanConfig, winrate float64, pnl pnlFilter, minAgeDays int) (done func()) {
+	d := scanDemand{winrate: winrate, pnl: pnl, minAgeDays: minAgeDays}
+	if plan != nil {
+		d.priority = plan.Priority()
+		d.pages = plan.ScanPages(0)
+	}
+
+	scanDemands.Lock()
+	id := scanDemands.next
+	scanDemands.next++
+	scanDemands.m[id] = d
+	scanDemands.Unlock()
+
+	return func() {
+		scanDemands.Lock()
+		delete(scanDemands.m, id)
+		scanDemands.Unlock()
+	}
+}
+
+// activeScanDemands returns the searches currently running
+func activeScanDemands() []scanDemand {
+	scanDemands.Lock()
+	defer scanDemands.Unlock()
+	demands := make([]scanDemand, 0, len(scanDemands.m))
+	for _, d := range scanDemands.m {
+		demands = append(demands, d)
+	}
+	return demands
+}
+
+// scanPages returns how many analyzer pages a scan cycle uses: the most
+// any running search's plan allows, and at least base
+func scanPages(demands []scanDemand, base int) int {
+	pages := base
+	for _, d := range demands {
+		pages = max(pages, d.pages)
+	}
+	return pages
+}
+
+// demandPriority ranks a wallet by the highest priority search its last
+// known stats match, 0 when none do
+func demandPriority(demands []scanDemand, w *storage.WalletData, now time.Time) int {
+	priority := 0
+	for _, d := range demands {
+		if d.priority > priority && d.matches(w, now) {
+			priority = d.priority
+		}
+	}
+	return priority
+}
+
+// walletPriorities ranks a scan cycle's wallets for the analyzer. Wallets
+// already scanned whose stats match a running search are analyzed first,
+// so the freshest results reach the highest plans soonest.
+func walletPriorities(demands []scanDemand, wallets []string) func(wallet string) int {
+	if len(demands) == 0 {
+		return nil
+	}
+
+	ranks := make(map[string]int)
+	now := time.Now()
+	scanner.mu.RLock()
+	for _, wallet := range wallets {
+		if known, ok := scanner.walletsCache[wallet]; ok {
+			if p := demandPriority(demands, known, now); p > 0 {
+				ranks[wallet] = p
+			}
+		}
+	}
+	scanner.mu.RUnlock()
+
+	return func(wallet string) int { return ranks[wallet] }
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/storage"
+)
+
+// TestScanDemands tests how running searches set a scan cycle's pages and
+// rank the wallets their plans want
+func TestScanDemands(t *testing.T) {
+	credits := &config.PlanConfig{Kind: config.PlanKindCredits}
+	trial := &config.PlanConfig{Kind: config.PlanKindTrial}
+
+	doneTrial := addScanDemand(trial, 60, pnlFilter{Min: 100}, 0)
+	doneCredits := addScanDemand(credits, 80, pnlFilter{Min: 200}, 0)
+	doneNoPlan := addScanDemand(nil, 50, pnlFilter{Min: 50}, 0)
+	demands := activeScanDemands()
+	if len(demands) != 3 {
+		t.Fatalf("Expected 3 running searches, got %d", len(demands))
+	}
+
+	if got := scanPages(demands, config.DefaultAnalyzerPages); got != config.DefaultCreditsAnalyzerPages {
+		t.Errorf("Expected the credit plan's %d pages, got %d", config.DefaultCreditsAnalyzerPages, got)
+	}
+	if got := scanPages(demands, 20); got != 20 {
+		t.Errorf("Expected the base to win when it is higher, got %d", got)
+	}
+	if got := scanPages(nil, config.DefaultAnalyzerPages); got != config.DefaultAnalyzerPages {
+		t.Errorf("Expected the base without searches, got %d", got)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name string
+		w    *storage.WalletData
+		want int
+	}{
+		{"matches credits and trial", &storage.WalletData{Winrate: 90, RealizedPnLPct: 300}, config.DefaultCreditsScanPriority},
+		{"matches trial only", &storage.WalletData{Winrate: 70, RealizedPnLPct: 150}, config.DefaultTrialScanPriority},
+		{"matches the planless search only", &storage.WalletData{Winrate: 55, RealizedPnLPct: 60}, 0},
+		{"matches nothing", &storage.WalletData{Winrate: 10, RealizedPnLPct: 5}, 0},
+	}
+	for _, tt := range tests {
+		if got := demandPriority(demands, tt.w, now); got != tt.want {
+			t.Errorf("%s: expected priority %d, got %d", tt.name, tt.want, got)
+		}
+	}
+
+	doneCredits()
+	doneCredits() // releasing twice is harmless
+	doneTrial()
+	doneNoPlan()
+	if demands := activeScanDemands(); len(demands) != 0 {
+		t.Errorf("Expected no searches once released, got %d", len(demands))
+	}
+}
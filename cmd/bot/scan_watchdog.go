@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// scanCancelGrace is how long a cycle past its maximum runtime gets to
+// return after its context is cancelled before it is abandoned
+const scanCancelGrace = 30 * time.Second
+
+// errScanTimeout is returned for a cycle that ran past its maximum runtime
+var errScanTimeout = errors.New("scan cycle exceeded its maximum runtime")
+
+// guardCycle runs one scan cycle with a context cancelled after
+// maxRuntime. A panicking cycle is recovered and reported as an error. A
+// cycle that ignores cancellation, e.g. stuck in a browser call, is
+// abandoned after grace so the scanner can move on; its context stays
+// cancelled so it can tell its results are no longer wanted.
+func guardCycle(maxRuntime, grace time.Duration, cycle func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), maxRuntime)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("❌ Scan cycle panicked: %v\n%s", r, debug.Stack())
+				done <- fmt.Errorf("scan cycle panicked: %v", r)
+			}
+		}()
+		done <- cycle(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return err
+		}
+	case <-ctx.Done():
+		select {
+		case <-done:
+		case <-time.After(grace):
+			log.Printf("⚠️ Scan cycle ignored cancellation for %s, abandoning it", grace)
+		}
+	}
+	return fmt.Errorf("%w (%s)", errScanTimeout, maxRuntime)
+}
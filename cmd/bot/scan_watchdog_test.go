@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuardCycle(t *testing.T) {
+	failed := errors.New("token fetch failed")
+
+	t.Run("Completes", func(t *testing.T) {
+		if err := guardCycle(time.Second, time.Second, func(ctx context.Context) error { return nil }); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		err := guardCycle(time.Second, time.Second, func(ctx context.Context) error { return failed })
+		if !errors.Is(err, failed) {
+			t.Errorf("Expected the cycle's error, got %v", err)
+		}
+	})
+
+	t.Run("Panics", func(t *testing.T) {
+		err := guardCycle(time.Second, time.Second, func(ctx context.Context) error { panic("nil map") })
+		if err == nil || !strings.Contains(err.Error(), "nil map") {
+			t.Errorf("Expected the panic as an error, got %v", err)
+		}
+	})
+
+	t.Run("CancelledInTime", func(t *testing.T) {
+		err := guardCycle(10*time.Millisecond, time.Second, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if !errors.Is(err, errScanTimeout) {
+			t.Errorf("Expected a timeout, got %v", err)
+		}
+	})
+
+	t.Run("IgnoresCancellation", func(t *testing.T) {
+		stuck := make(chan struct{})
+		defer close(stuck)
+
+		start := time.Now()
+		err := guardCycle(10*time.Millisecond, 20*time.Millisecond, func(ctx context.Context) error {
+			<-stuck
+			return nil
+		})
+		if !errors.Is(err, errScanTimeout) {
+			t.Errorf("Expected a timeout, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected a stuck cycle abandoned after the grace period, took %s", elapsed)
+		}
+	})
+}
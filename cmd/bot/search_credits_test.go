@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"solana-orchestrator/storage"
+)
+
+// TestSearchCredits tests that a real-time search charges each wallet once
+// and that cancelling it refunds anything charged beyond what it delivers
+func TestSearchCredits(t *testing.T) {
+	search := &SearchSession{ChatID: 42}
+	search.FoundWallets = []*storage.WalletData{{Wallet: "W1"}, {Wallet: "W2"}}
+
+	fresh := search.unseen([]*storage.WalletData{{Wallet: "W2"}, {Wallet: "W3"}, {Wallet: "W3"}, {Wallet: "W4"}})
+	if len(fresh) != 2 || fresh[0].Wallet != "W3" || fresh[1].Wallet != "W4" {
+		t.Fatalf("Expected only W3 and W4 to be charged, got %+v", fresh)
+	}
+
+	db, err := storage.New(filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	db.CreateUser(42)
+	db.UpdateUserCredits(42, 10)
+
+	// A cancelled search that was charged for W2 twice
+	search.FoundWallets = append(search.FoundWallets, &storage.WalletData{Wallet: "W2"}, &storage.WalletData{Wallet: "W3"})
+	search.CreditsSpent = 4
+	search.CancelRequested = true
+	if err := db.DecrementUserCredits(42, search.CreditsSpent); err != nil {
+		t.Fatalf("DecrementUserCredits failed: %v", err)
+	}
+
+	found, refund, err := search.settle(db)
+	if err != nil {
+		t.Fatalf("settle failed: %v", err)
+	}
+	if len(found) != 3 || refund != 1 {
+		t.Errorf("Expected 3 wallets delivered and 1 credit refunded, got %d and %d", len(found), refund)
+	}
+	if user, _ := db.GetUser(42); user.Credits != 7 {
+		t.Errorf("Expected 7 credits after cancelling, got %d", user.Credits)
+	}
+}
+
+// TestBeginSlowScan tests that a chat can't start a second slow scan while
+// one is running or waiting for delivery
+func TestBeginSlowScan(t *testing.T) {
+	const chatID = 77
+	defer endSlowScan(chatID)
+
+	if !beginSlowScan(chatID) {
+		t.Fatal("Expected the first slow scan to start")
+	}
+	if beginSlowScan(chatID) {
+		t.Error("A second slow scan started while the first was running")
+	}
+
+	// Queued for delivery still blocks a new scan
+	endSlowScan(chatID)
+	pendingScansMu.Lock()
+	pendingScans[chatID] = &PendingScan{UserID: chatID}
+	pendingScansMu.Unlock()
+	if beginSlowScan(chatID) {
+		t.Error("A slow scan started while another was pending delivery")
+	}
+
+	takePendingScan(chatID, nil)
+	if !beginSlowScan(chatID) {
+		t.Error("Expected a slow scan to start once the last one was delivered")
+	}
+}
@@ -2,16 +2,13 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"runtime"
-	"solana-orchestrator/api"
-	"solana-orchestrator/crypto"
+	"solana-orchestrator/engine"
 	"solana-orchestrator/storage"
 	"solana-orchestrator/trading"
 	"time"
 
-	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -36,10 +33,10 @@ func handleStartSell(bot *tgbotapi.BotAPI, chatID int64) {
 
 	// Get token balances
 	walletPubkey, _ := solana.PublicKeyFromBase58(wallet.PublicKey)
-	rpcURL := "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR"
+	rpcURL := getShyftRPCURL()
 	wsClient := trading.NewWSClient(getShyftWSURL())
-	apiClient := api.NewClient(globalCfg.MoralisAPIKey, globalCfg.BirdeyeAPIKey, globalCfg.APISettings.MaxRetries, globalCfg.MoralisFallbackKeys)
-	balanceMgr := trading.NewBalanceManager(rpcURL, wsClient, apiClient)
+	apiClient := newAPIClient(globalCfg)
+	balanceMgr := trading.NewBalanceManagerWithAPI(rpcURL, wsClient, apiClient)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
@@ -83,12 +80,19 @@ func handleStartSell(bot *tgbotapi.BotAPI, chatID int64) {
 			}
 		}
 
-		// Format token display
-		tokenDisplay := fmt.Sprintf("%d. `%s...%s`\n   Amount: %.2f%s",
+		// Format token display, with the symbol from whichever lookup had it
+		symbol := token.Symbol
+		if symbol == "" && tokenInfo != nil {
+			symbol = tokenInfo.Symbol
+		}
+		if symbol != "" {
+			symbol = "*" + escapeMarkdown(symbol) + "* "
+		}
+		tokenDisplay := fmt.Sprintf("%d. %s`%s`\n   Amount: %.2f%s",
 			i+1,
-			tokenMintStr[:4],
-			tokenMintStr[len(tokenMintStr)-4:],
-			float64(token.Amount)/1e9, // Convert uint64 to tokenswith decimals
+			symbol,
+			shortAddr(tokenMintStr),
+			token.UIAmount,
 			priceInfo,
 		)
 		message += tokenDisplay + "\n\n"
@@ -124,32 +128,32 @@ func handleSellToken(bot *tgbotapi.BotAPI, chatID int64, tokenMint string) {
 	wallet, _ := scanner.db.GetEncryptedWallet(chatID)
 	walletPubkey, _ := solana.PublicKeyFromBase58(wallet.PublicKey)
 
-	rpcURL := "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR"
+	rpcURL := getShyftRPCURL()
 	wsClient := trading.NewWSClient(getShyftWSURL())
-	apiClient := api.NewClient(globalCfg.MoralisAPIKey, globalCfg.BirdeyeAPIKey, globalCfg.APISettings.MaxRetries, globalCfg.MoralisFallbackKeys)
-	balanceMgr := trading.NewBalanceManager(rpcURL, wsClient, apiClient)
+	apiClient := newAPIClient(globalCfg)
+	balanceMgr := trading.NewBalanceManagerWithAPI(rpcURL, wsClient, apiClient)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	tokenBalances, _ := balanceMgr.GetTokenBalances(ctx, walletPubkey)
 
-	var tokenBalance float64
+	var tokenBalance trading.TokenBalance
 	for _, tb := range tokenBalances {
 		if tb.Mint.String() == tokenMint {
-			tokenBalance = float64(tb.Amount) / 1e9
+			tokenBalance = tb
 			break
 		}
 	}
 
-	if tokenBalance == 0 {
+	if tokenBalance.Amount == 0 {
 		send(bot, chatID, "❌ You don't own this token anymore")
 		return
 	}
 
 	// Show sell options
-	message := fmt.Sprintf("❌ *Sell %s*\n\n", tokenInfo.Symbol)
-	message += fmt.Sprintf("💰 *Balance:* %.2f tokens\n", tokenBalance)
+	message := fmt.Sprintf("❌ *Sell %s*\n\n", escapeMarkdown(tokenInfo.Symbol))
+	message += fmt.Sprintf("💰 *Balance:* %.4f tokens\n", tokenBalance.UIAmount)
 	message += fmt.Sprintf("💵 *Price:* $%s\n", tokenInfo.PriceUSD)
 	message += fmt.Sprintf("📊 *24h:* %.2f%%\n\n", tokenInfo.Change24h)
 	message += "*Select amount to sell:*"
@@ -174,39 +178,68 @@ func handleSellToken(bot *tgbotapi.BotAPI, chatID int64, tokenMint string) {
 	bot.Send(msgConfig)
 
 	// Store in temp
-	tempSellData[chatID] = &SellData{
-		TokenMint:    tokenMint,
-		TokenInfo:    tokenInfo,
-		TokenBalance: tokenBalance,
-	}
+	tempSellData.Set(chatID, &SellData{
+		TokenMint: tokenMint,
+		TokenInfo: tokenInfo,
+		Balance:   tokenBalance,
+	})
 }
 
 // handleSellPercentage confirms sell with percentage
 func handleSellPercentage(bot *tgbotapi.BotAPI, chatID int64, tokenMint string, percentage int) {
-	sellData, ok := tempSellData[chatID]
+	sellData, ok := tempSellData.Get(chatID)
 	if !ok || sellData.TokenMint != tokenMint {
 		send(bot, chatID, "❌ Session expired. Please start over.")
 		return
 	}
 
-	sellAmount := sellData.TokenBalance * float64(percentage) / 100.0
+	sellData.SellRaw = sellData.Balance.Portion(percentage)
+	sellAmount := sellData.Balance.ToUI(sellData.SellRaw)
 	sellData.SellAmount = sellAmount
 	sellData.Percentage = percentage
 
 	// Show confirmation
 	message := "⚠️ *Confirm Sale*\n\n"
-	message += fmt.Sprintf("🪙 *Token:* %s\n", sellData.TokenInfo.Symbol)
+	message += fmt.Sprintf("🪙 *Token:* %s\n", escapeMarkdown(sellData.TokenInfo.Symbol))
 	message += fmt.Sprintf("💰 *Sell:* %.2f tokens (%d%%)\n", sellAmount, percentage)
-	message += fmt.Sprintf("💵 *Est. Receive:* ~%.6f SOL\n\n", sellAmount*parseFloat(sellData.TokenInfo.PriceSOL))
+	message += fmt.Sprintf("💵 *Est. Receive:* ~%.6f SOL\n", sellAmount*parseFloat(sellData.TokenInfo.PriceSOL))
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err == nil {
+		message += fmt.Sprintf("⚡ *Priority Fee:* %s\n", tradePriorityFeeText(settings, sellData.TokenMint, sellData.TokenInfo))
+		message += fmt.Sprintf("🛡️ *Execution:* %s\n", executionText(tradeUsesJito(settings, sellData.TokenInfo), settings))
+	} else {
+		settings = &storage.UserSettings{SlippageBps: 500, MaxPriceImpactPct: storage.DefaultMaxPriceImpactPct}
+	}
+
+	// Quote the sell so the user sees how far it moves the price
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	impact, impactOK := sellImpact(ctx, sellData.TokenMint, sellData.SellRaw, settings.SlippageBps)
+	impactExceeded := impactOK && settings.PriceImpactExceeded(impact)
+	sellData.ImpactPct, sellData.ImpactAccepted = impact, false
+	message += priceImpactText(impact, impactOK, settings)
+
+	estSOL := sellAmount * parseFloat(sellData.TokenInfo.PriceSOL)
+	sellData.ConfirmLarge, sellData.LargeConfirmed = settings.NeedsLargeTradeConfirm(estSOL), false
+
+	message += "\n"
 	message += "⚠️ Final amount depends on market slippage\n\n"
-	message += "Click Confirm to proceed:"
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Confirm", "confirm_sell"),
-			tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "start_sell"),
-		),
-	)
+	// Small trades skip the Confirm button for users who auto-confirm
+	if settings.SkipsConfirmation(estSOL) && !impactExceeded {
+		message += "⚡ Auto-confirmed"
+		send(bot, chatID, message)
+		handleConfirmSell(bot, chatID)
+		return
+	}
+
+	if impactExceeded {
+		message += "🚨 This sale moves the price more than your limit. Accept the impact to proceed:"
+	} else {
+		message += "Click Confirm to proceed:"
+	}
+
+	keyboard := confirmKeyboard("confirm_sell", "start_sell", impact, impactExceeded)
 
 	msgConfig := tgbotapi.NewMessage(chatID, message)
 	msgConfig.ParseMode = "Markdown"
@@ -216,6 +249,19 @@ func handleSellPercentage(bot *tgbotapi.BotAPI, chatID int64, tokenMint string,
 
 // handleConfirmSell asks for password
 func handleConfirmSell(bot *tgbotapi.BotAPI, chatID int64) {
+	if tradingHalted(bot, chatID) {
+		cleanupSellSession(chatID)
+		return
+	}
+
+	// Large sales need the percentage typed back first
+	if sellData, ok := tempSellData.Get(chatID); ok && sellData.ConfirmLarge && !sellData.LargeConfirmed {
+		askLargeTradeConfirm(bot, chatID, "awaiting_sell_large_confirm",
+			fmt.Sprintf("✋ *Large sale:* ~%.4f SOL is above your confirmation threshold.", sellData.SellAmount*parseFloat(sellData.TokenInfo.PriceSOL)),
+			fmt.Sprintf("%d", sellData.Percentage))
+		return
+	}
+
 	// Update state
 	sessMu.Lock()
 	sessions[chatID].State = "awaiting_sell_password"
@@ -233,8 +279,14 @@ func handleSellPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	deleteMsg := tgbotapi.NewDeleteMessage(chatID, msg.MessageID)
 	bot.Request(deleteMsg)
 
+	// The switch may have been flipped while the password was typed
+	if tradingHalted(bot, chatID) {
+		cleanupSellSession(chatID)
+		return
+	}
+
 	// Get sell data
-	sellData, ok := tempSellData[chatID]
+	sellData, ok := tempSellData.Get(chatID)
 	if !ok {
 		send(bot, chatID, "❌ Session expired")
 		cleanupSellSession(chatID)
@@ -242,95 +294,85 @@ func handleSellPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	}
 
 	send(bot, chatID, "⏳ Processing transaction...\n\nThis may take a few seconds")
+	trade := tradeLogger.Begin(chatID, "sell")
 
 	// 1. Decrypt private key
-	encWallet, err := scanner.db.GetEncryptedWalletForDecryption(chatID)
+	privateKey, err := unlockWallet(chatID, password)
 	if err != nil {
-		send(bot, chatID, "❌ Failed to load wallet")
+		send(bot, chatID, unlockErrorMessage(err))
 		cleanupSellSession(chatID)
 		return
 	}
 
-	// Convert storage.EncryptedWallet to crypto.EncryptedWallet
-	cryptoWallet := &crypto.EncryptedWallet{
-		EncryptedKey: []byte(encWallet.EncryptedPrivateKey),
-		Salt:         []byte(encWallet.EncryptionSalt),
-		Nonce:        []byte(encWallet.Nonce),
-		PasswordHash: encWallet.PasswordHash,
-	}
-
-	privateKeyStr, err := crypto.DecryptPrivateKey(cryptoWallet, password)
-	if err != nil {
-		send(bot, chatID, "❌ Incorrect password!")
-		cleanupSellSession(chatID)
-		return
-	}
-
-	// Parse private key
-	privateKey, err := solana.PrivateKeyFromBase58(privateKeyStr)
-	if err != nil {
-		send(bot, chatID, "❌ Invalid private key in wallet")
-		cleanupSellSession(chatID)
-		return
-	}
-
-	// Clear private key string
-	crypto.ZeroString(&privateKeyStr)
-
 	// 2. Get User Settings
 	settings, err := scanner.db.GetUserSettings(chatID)
 	if err != nil {
-		settings = &storage.UserSettings{SlippageBps: 500, JitoTipLamports: 10000}
+		settings = &storage.UserSettings{SlippageBps: 500, JitoTipLamports: 10000, MaxPriceImpactPct: storage.DefaultMaxPriceImpactPct}
+	}
+	if sellData.ImpactAccepted {
+		acceptImpact(settings, sellData.ImpactPct)
 	}
 
-	// 3. Get Jupiter Quote
-	// Fetch decimals from RPC to convert token amount to raw amount
-	rpcURL := "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR"
+	// 3. Build the swap
+	rpcURL := getShyftRPCURL()
 	rpcClient := rpc.New(rpcURL)
 
 	mintPubkey := solana.MustPublicKeyFromBase58(sellData.TokenMint)
-	supply, err := rpcClient.GetTokenSupply(context.Background(), mintPubkey, rpc.CommitmentFinalized)
-	var decimals uint8
-	if err == nil {
-		decimals = uint8(supply.Value.Decimals)
-	} else {
-		sendError(bot, chatID, "Failed to get token decimals")
+
+	// Make sure the source token account exists and we can cover the
+	// temporary wSOL account Jupiter opens to unwrap the proceeds
+	ataCtx, ataCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer ataCancel()
+
+	sourceATA, err := trading.EnsureATA(ataCtx, rpcClient, privateKey.PublicKey(), mintPubkey)
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Failed to check token account: %v", err))
+		cleanupSellSession(chatID)
+		return
+	}
+	if sourceATA.NeedsCreation {
+		sendError(bot, chatID, "No token account found for this token in your wallet.\n\nNothing to sell.")
 		cleanupSellSession(chatID)
 		return
 	}
 
-	tokenAmountRaw := uint64(sellData.SellAmount * float64(pow10(int(decimals))))
-
-	quote, err := trading.GetSellQuote(context.Background(), sellData.TokenMint, tokenAmountRaw, settings.SlippageBps)
+	wsolATA, err := trading.EnsureATA(ataCtx, rpcClient, privateKey.PublicKey(), solana.SolMint)
 	if err != nil {
-		send(bot, chatID, fmt.Sprintf("❌ Failed to get quote: %v", err))
+		sendError(bot, chatID, fmt.Sprintf("Failed to check token account: %v", err))
 		cleanupSellSession(chatID)
 		return
 	}
 
-	// 4. Get Swap Transaction
-	priorityFee := int64(10000)
-	swapResp, err := trading.GetSwapTransaction(context.Background(), quote, privateKey.PublicKey().String(), priorityFee)
+	solBalance, err := rpcClient.GetBalance(ataCtx, privateKey.PublicKey(), rpc.CommitmentConfirmed)
 	if err != nil {
-		send(bot, chatID, fmt.Sprintf("❌ Failed to build transaction: %v", err))
+		sendError(bot, chatID, fmt.Sprintf("Failed to check balance: %v", err))
 		cleanupSellSession(chatID)
 		return
 	}
 
-	// 5. Deserialize and Sign
-	txBytes, err := base64.StdEncoding.DecodeString(swapResp.SwapTransaction)
-	if err != nil {
-		send(bot, chatID, "❌ Failed to decode transaction")
+	const estimatedFeeLamports = 1000000 // ~0.001 SOL for transaction fees
+	requiredLamports := wsolATA.RentLamports + estimatedFeeLamports
+	if solBalance.Value < requiredLamports {
+		message := "❌ *Insufficient SOL for fees!*\n\n"
+		message += fmt.Sprintf("💰 Your Balance: %.6f SOL\n", trading.FormatSOL(solBalance.Value))
+		if wsolATA.NeedsCreation {
+			message += fmt.Sprintf("🏦 Account Rent: %.6f SOL\n", trading.FormatSOL(wsolATA.RentLamports))
+		}
+		message += fmt.Sprintf("⚡ Est. Fees: %.6f SOL\n\n", trading.FormatSOL(estimatedFeeLamports))
+		message += "⚠️ Top up a little SOL to pay for the sale"
+		send(bot, chatID, message)
 		cleanupSellSession(chatID)
 		return
 	}
 
-	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(txBytes))
-	if err != nil {
-		send(bot, chatID, fmt.Sprintf("❌ Failed to deserialize transaction: %v", err))
+	// Through Jupiter, or Pump.fun for tokens still on their bonding curve
+	settings.PriorityFeeLamports = tradePriorityFee(settings, sellData.TokenMint, sellData.TokenInfo)
+	swap, ok := buildSellTx(bot, chatID, trade, privateKey.PublicKey(), sellData.TokenMint, sellData.TokenInfo, sellData.SellRaw, settings)
+	if !ok {
 		cleanupSellSession(chatID)
 		return
 	}
+	tx := swap.tx
 
 	// Sign transaction
 	_, err = tx.Sign(
@@ -341,17 +383,21 @@ func handleSellPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 			return nil
 		},
 	)
+	if err == nil {
+		trade.SetSignature(tx.Signatures[0].String())
+	}
+	trade.Stage(engine.StageSign, err, "")
 	if err != nil {
 		send(bot, chatID, fmt.Sprintf("❌ Failed to sign transaction: %v", err))
 		cleanupSellSession(chatID)
 		return
 	}
 
-	// 6. Submit via Jito (if tip > 0) or RPC
-	// Initialize Jito Client
-	jitoClient := trading.NewJitoClient("https://amsterdam.mainnet.block-engine.jito.wtf/api/v1/bundles", uint64(settings.JitoTipLamports))
+	// 4. Submit via Jito or RPC, as the user's execution mode picks for
+	// this token
+	jitoClient := trading.NewJitoClient("https://amsterdam.mainnet.block-engine.jito.wtf/api/v1/bundles", settings.JitoTip())
 
-	if settings.JitoTipLamports > 0 {
+	if tradeUsesJito(settings, sellData.TokenInfo) {
 		tipInst, err := jitoClient.CreateTipInstruction(privateKey.PublicKey())
 		if err == nil {
 			recentBlockhash := tx.Message.RecentBlockhash
@@ -370,11 +416,15 @@ func handleSellPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 
 				bundleRes, err := jitoClient.SubmitBundle(context.Background(), []solana.Transaction{*tx, *tipTx})
 				if err != nil {
-					send(bot, chatID, fmt.Sprintf("❌ Jito submission failed: %v", err))
+					trade.Stage(engine.StageSubmit, err, "jito bundle")
+					send(bot, chatID, tradeErrorMessage("Jito submission failed", err))
 					cleanupSellSession(chatID)
 					return
 				}
 
+				trade.Stage(engine.StageSubmit, nil, "jito bundle "+bundleRes.BundleID)
+				go trackConfirmation(bot, chatID, trade, rpcURL, tx.Signatures[0], swap.lastValidBlockHeight)
+
 				send(bot, chatID, fmt.Sprintf("✅ *Bundle Submitted!*\n\nBundle ID: `%s`\n\nWaiting for confirmation...", bundleRes.BundleID))
 				cleanupSellSession(chatID)
 				return
@@ -384,14 +434,17 @@ func handleSellPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 
 	// Fallback to RPC
 	sig, err := rpcClient.SendTransaction(context.Background(), tx)
+	trade.Stage(engine.StageSubmit, err, "rpc")
 	if err != nil {
-		send(bot, chatID, fmt.Sprintf("❌ Transaction failed: %v", err))
+		send(bot, chatID, tradeErrorMessage("Transaction failed", err))
 		cleanupSellSession(chatID)
 		return
 	}
 
+	go trackConfirmation(bot, chatID, trade, rpcURL, sig, swap.lastValidBlockHeight)
+
 	message := "✅ *Transaction Submitted!*\n\n"
-	message += fmt.Sprintf("🪙 Token: %s\n", sellData.TokenInfo.Symbol)
+	message += fmt.Sprintf("🪙 Token: %s\n", escapeMarkdown(sellData.TokenInfo.Symbol))
 	message += fmt.Sprintf("💰 Sold: %.2f tokens\n\n", sellData.SellAmount)
 	message += fmt.Sprintf("🔗 Signature: `%s`\n", sig.String())
 	message += "⏳ Waiting for confirmation..."
@@ -402,14 +455,20 @@ func handleSellPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 
 // Temporary storage for sell flow
 type SellData struct {
-	TokenMint    string
-	TokenInfo    *trading.TokenInfo
-	TokenBalance float64
-	SellAmount   float64
-	Percentage   int
+	TokenMint  string
+	TokenInfo  *trading.TokenInfo
+	Balance    trading.TokenBalance
+	SellRaw    uint64  // base units sent to Jupiter
+	SellAmount float64 // SellRaw in UI units, for display
+	Percentage int
+
+	ImpactPct      float64 // price impact quoted on the confirmation
+	ImpactAccepted bool    // the user accepted ImpactPct over their limit
+	ConfirmLarge   bool    // over the user's threshold, so the percentage must be typed
+	LargeConfirmed bool    // the user typed the percentage
 }
 
-var tempSellData = make(map[int64]*SellData)
+var tempSellData = newChatStore[*SellData]()
 
 // Helper functions
 func parseFloat(s string) float64 {
@@ -418,15 +477,7 @@ func parseFloat(s string) float64 {
 	return f
 }
 
-func pow10(n int) uint64 {
-	result := uint64(1)
-	for i := 0; i < n; i++ {
-		result *= 10
-	}
-	return result
-}
-
 func cleanupSellSession(chatID int64) {
-	delete(tempSellData, chatID)
+	tempSellData.Delete(chatID)
 	runtime.GC()
 }
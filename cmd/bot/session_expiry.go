@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sessionSweepInterval is how often idle sessions are swept
+const sessionSweepInterval = time.Minute
+
+const sessionExpiredText = "⌛ Your previous action timed out. Please start again."
+
+// sessionStale reports whether a session has waited for input longer
+// than ttl
+func sessionStale(s *UserSession, now time.Time, ttl time.Duration) bool {
+	return now.Sub(time.Unix(s.RequestedAt, 0)) > ttl
+}
+
+// clearFlowState drops the temp data multi-step flows keep outside the
+// session. The caller removes the session itself.
+func clearFlowState(chatID int64) {
+	tempBuyData.Delete(chatID)
+	tempSellData.Delete(chatID)
+	tempWalletAddr.Delete(chatID)
+	pendingOrderActions.Delete(chatID)
+}
+
+// freshSession returns chatID's session and marks it active now. A
+// session idle past the TTL is deleted instead, and returned with expired
+// set so the caller can tell the user.
+func freshSession(chatID int64, now time.Time) (session *UserSession, expired bool) {
+	sessMu.Lock()
+	session, exists := sessions[chatID]
+	if !exists {
+		sessMu.Unlock()
+		return nil, false
+	}
+	if sessionStale(session, now, globalCfg.Sessions.TTL()) {
+		delete(sessions, chatID)
+		sessMu.Unlock()
+		clearFlowState(chatID)
+		return session, true
+	}
+	session.RequestedAt = now.Unix()
+	sessMu.Unlock()
+	return session, false
+}
+
+// handleExpiredSession tells the user their flow timed out. A message
+// sent to an expired password prompt is deleted like the prompt would
+// have done.
+func handleExpiredSession(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string) {
+	if strings.HasSuffix(state, "_password") {
+		bot.Request(tgbotapi.NewDeleteMessage(msg.Chat.ID, msg.MessageID))
+	}
+	send(bot, msg.Chat.ID, sessionExpiredText)
+}
+
+// sweepSessions deletes sessions idle past the TTL, tells their users and
+// returns how many were removed
+func sweepSessions(bot *tgbotapi.BotAPI, now time.Time) int {
+	ttl := globalCfg.Sessions.TTL()
+
+	sessMu.Lock()
+	var expired []int64
+	for chatID, s := range sessions {
+		if sessionStale(s, now, ttl) {
+			delete(sessions, chatID)
+			expired = append(expired, chatID)
+		}
+	}
+	sessMu.Unlock()
+
+	for _, chatID := range expired {
+		clearFlowState(chatID)
+		send(bot, chatID, sessionExpiredText)
+	}
+	return len(expired)
+}
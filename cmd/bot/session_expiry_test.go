@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSessionStale tests the idle cutoff for multi-step flows
+func TestSessionStale(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ttl := 15 * time.Minute
+
+	tests := []struct {
+		idle time.Duration
+		want bool
+	}{
+		{0, false},
+		{14 * time.Minute, false},
+		{ttl, false},
+		{ttl + time.Second, true},
+		{time.Hour, true},
+	}
+	for _, tt := range tests {
+		s := &UserSession{RequestedAt: now.Add(-tt.idle).Unix()}
+		if got := sessionStale(s, now, ttl); got != tt.want {
+			t.Errorf("sessionStale after %v idle = %v, want %v", tt.idle, got, tt.want)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"solana-orchestrator/storage"
+	"solana-orchestrator/trading"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -14,13 +20,17 @@ func handleSettings(bot *tgbotapi.BotAPI, chatID int64) {
 	settings, err := scanner.db.GetUserSettings(chatID)
 	if err != nil {
 		log.Printf("Error loading settings: %v", err)
-		settings = &storage.UserSettings{SlippageBps: 500, JitoTipLamports: 10000}
+		settings = &storage.UserSettings{SlippageBps: 500, JitoTipLamports: 10000, MaxPriceImpactPct: storage.DefaultMaxPriceImpactPct, MinSOLReserve: storage.DefaultMinSOLReserve, ConfirmAboveSOL: storage.DefaultConfirmAboveSOL}
 	}
 
 	message := "⚙️ *Settings*\n\n"
 	message += fmt.Sprintf("📊 *Slippage:* %.1f%%\n", float64(settings.SlippageBps)/100)
 	message += fmt.Sprintf("💎 *Jito Tip:* %.6f SOL\n", float64(settings.JitoTipLamports)/1e9)
-	message += fmt.Sprintf("⚡ *Priority Fee:* %.6f SOL\n\n", float64(settings.PriorityFeeLamports)/1e9)
+	message += fmt.Sprintf("⚡ *Priority Fee:* %s\n", priorityFeeText(settings.PriorityFeeLamports))
+	message += fmt.Sprintf("📉 *Max Price Impact:* %s\n", priceImpactLimitText(settings.MaxPriceImpactPct))
+	message += fmt.Sprintf("🛟 *SOL Reserve:* %s\n", solReserveText(settings.MinSOLReserve))
+	message += fmt.Sprintf("✋ *Confirm Above:* %s (auto-confirm %s)\n", confirmAboveText(settings.ConfirmAboveSOL), onOff(settings.AutoConfirm))
+	message += fmt.Sprintf("🛡️ *Execution:* %s\n\n", executionModeNames[settings.ExecutionMode])
 	message += "Click below to change settings:"
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
@@ -33,9 +43,24 @@ func handleSettings(bot *tgbotapi.BotAPI, chatID int64) {
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("⚡ Change Priority Fee", "settings_priority"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📉 Max Price Impact", "settings_impact"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🛟 SOL Reserve", "settings_reserve"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✋ Trade Confirmation", "settings_confirm"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🛡️ Execution Mode", "settings_execution"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🤖 Copy Trade Settings", "settings_copytrade"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔔 Notifications", "settings_notify"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "back_to_menu"),
 		),
@@ -133,7 +158,8 @@ func handleSetJito(bot *tgbotapi.BotAPI, chatID int64, lamports int64) {
 func handleSettingsPriority(bot *tgbotapi.BotAPI, chatID int64) {
 	message := "⚡ *Set Priority Fee*\n\n"
 	message += "Fee paid to miners to prioritize your transaction.\n"
-	message += "Higher fees = faster confirmation during congestion."
+	message += "Higher fees = faster confirmation during congestion.\n"
+	message += "Auto prices each trade from the fees recently paid on its token."
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -144,6 +170,9 @@ func handleSettingsPriority(bot *tgbotapi.BotAPI, chatID int64) {
 			tgbotapi.NewInlineKeyboardButtonData("0.005 SOL", "set_prio_5000000"),
 			tgbotapi.NewInlineKeyboardButtonData("0.01 SOL", "set_prio_10000000"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🤖 Auto", fmt.Sprintf("set_prio_%d", trading.PriorityFeeAuto)),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "open_settings"),
 		),
@@ -163,10 +192,124 @@ func handleSetPriority(bot *tgbotapi.BotAPI, chatID int64, lamports int64) {
 		return
 	}
 
-	send(bot, chatID, fmt.Sprintf("✅ Priority fee set to %.6f SOL", float64(lamports)/1e9))
+	send(bot, chatID, fmt.Sprintf("✅ Priority fee set to %s", priorityFeeText(lamports)))
+	handleSettings(bot, chatID)
+}
+
+// executionModeNames describes each execution mode in the settings menu
+var executionModeNames = map[string]string{
+	storage.ExecutionAuto: "Auto",
+	storage.ExecutionJito: "Always Jito",
+	storage.ExecutionRPC:  "Always RPC",
+}
+
+// handleSettingsExecution shows the execution mode options
+func handleSettingsExecution(bot *tgbotapi.BotAPI, chatID int64) {
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err != nil {
+		log.Printf("Error loading settings: %v", err)
+		settings = &storage.UserSettings{ExecutionMode: storage.ExecutionAuto}
+	}
+
+	message := "🛡️ *Execution Mode*\n\n"
+	message += "How your buys, sells and copy trades are sent:\n\n"
+	message += "_Always Jito:_ private bundles, protected from sandwich bots, paying your Jito tip\n"
+	message += "_Always RPC:_ plain transactions, no tip, visible to MEV bots\n"
+	message += fmt.Sprintf("_Auto:_ Jito for tokens under $%dk liquidity or moving more than %d%% in an hour, RPC otherwise",
+		storage.AutoJitoMaxLiquidityUSD/1000, storage.AutoJitoMinMovePct)
+
+	modeButton := func(mode string) tgbotapi.InlineKeyboardButton {
+		label := executionModeNames[mode]
+		if settings.ExecutionMode == mode {
+			label = "✅ " + label
+		}
+		return tgbotapi.NewInlineKeyboardButtonData(label, "set_exec:"+mode)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(modeButton(storage.ExecutionAuto)),
+		tgbotapi.NewInlineKeyboardRow(modeButton(storage.ExecutionJito)),
+		tgbotapi.NewInlineKeyboardRow(modeButton(storage.ExecutionRPC)),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "open_settings"),
+		),
+	)
+
+	msgConfig := tgbotapi.NewMessage(chatID, message)
+	msgConfig.ParseMode = "Markdown"
+	msgConfig.ReplyMarkup = keyboard
+	bot.Send(msgConfig)
+}
+
+// handleSetExecutionMode updates the execution mode
+func handleSetExecutionMode(bot *tgbotapi.BotAPI, chatID int64, mode string) {
+	if err := scanner.db.UpdateExecutionMode(chatID, mode); err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Error updating execution mode: %v", err))
+		return
+	}
+	send(bot, chatID, fmt.Sprintf("✅ Execution mode set to: %s", executionModeNames[mode]))
 	handleSettings(bot, chatID)
 }
 
+// tradeUsesJito applies the user's execution mode to a token, treating
+// a token without market data as thin
+func tradeUsesJito(settings *storage.UserSettings, info *trading.TokenInfo) bool {
+	if info == nil {
+		return settings.UseJito(0, 0)
+	}
+	return settings.UseJito(info.Liquidity, info.Change1h)
+}
+
+// executionText describes how a trade will be sent, for confirmations
+func executionText(useJito bool, settings *storage.UserSettings) string {
+	if useJito {
+		return fmt.Sprintf("Jito bundle, %.6f SOL tip", trading.FormatSOL(settings.JitoTip()))
+	}
+	return "RPC"
+}
+
+// priorityFeeText describes a priority fee setting
+func priorityFeeText(lamports int64) string {
+	if lamports == trading.PriorityFeeAuto {
+		return "Auto (recent network fees)"
+	}
+	return fmt.Sprintf("%.6f SOL", float64(lamports)/1e9)
+}
+
+// tradePriorityFee resolves the user's priority fee for a trade in mint,
+// estimating it from the token and its pool when set to auto
+func tradePriorityFee(settings *storage.UserSettings, mint string, info *trading.TokenInfo) int64 {
+	if settings.PriorityFeeLamports != trading.PriorityFeeAuto {
+		return settings.PriorityFeeLamports
+	}
+	var accounts []solana.PublicKey
+	for _, addr := range []string{mint, pairAddress(info)} {
+		if key, err := solana.PublicKeyFromBase58(addr); err == nil {
+			accounts = append(accounts, key)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return trading.ResolvePriorityFee(ctx, rpc.New(getShyftRPCURL()), settings.PriorityFeeLamports, accounts)
+}
+
+// tradePriorityFeeText describes the priority fee a trade in mint will pay
+func tradePriorityFeeText(settings *storage.UserSettings, mint string, info *trading.TokenInfo) string {
+	text := fmt.Sprintf("%.6f SOL", float64(trading.ClampPriorityFee(tradePriorityFee(settings, mint, info)))/1e9)
+	if settings.PriorityFeeLamports == trading.PriorityFeeAuto {
+		text += " (auto)"
+	}
+	return text
+}
+
+// pairAddress returns the token's DexScreener pool, "" if unknown
+func pairAddress(info *trading.TokenInfo) string {
+	if info == nil {
+		return ""
+	}
+	return info.PairAddress
+}
+
 // Helper to parse slippage from callback data
 func parseSlippageCallback(data string) int {
 	// Format: set_slip_XXX where XXX is bps
@@ -254,3 +397,143 @@ func handleToggleCopyTradeAutoBuy(bot *tgbotapi.BotAPI, chatID int64, enable boo
 	}
 	handleSettingsCopyTrade(bot, chatID)
 }
+
+// notifyLevelNames describes each notification level in the settings menu
+var notifyLevelNames = map[string]string{
+	storage.NotifyAll:    "All swaps",
+	storage.NotifyTrades: "Executed trades only",
+	storage.NotifyDigest: "Daily digest",
+}
+
+// quietHoursText describes the user's quiet hours
+func quietHoursText(settings *storage.UserSettings) string {
+	if !settings.QuietHoursSet() {
+		return "Off"
+	}
+	return fmt.Sprintf("%02d:00-%02d:00", settings.QuietStartHour, settings.QuietEndHour)
+}
+
+// handleSettingsNotifications shows notification level and quiet hours
+func handleSettingsNotifications(bot *tgbotapi.BotAPI, chatID int64) {
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err != nil {
+		log.Printf("Error loading settings: %v", err)
+		settings = &storage.UserSettings{NotifyLevel: storage.NotifyAll, QuietStartHour: storage.QuietHoursOff, QuietEndHour: storage.QuietHoursOff,
+			Timezone: "UTC", DigestHour: storage.DefaultDigestHour}
+	}
+
+	message := "🔔 *Notification Settings*\n\n"
+	message += fmt.Sprintf("📣 *Level:* %s\n", notifyLevelNames[settings.NotifyLevel])
+	message += fmt.Sprintf("🌙 *Quiet Hours:* %s\n", quietHoursText(settings))
+	message += fmt.Sprintf("📬 *Digest Time:* %02d:00\n", settings.DigestHour)
+	message += fmt.Sprintf("🌍 *Timezone:* %s\n\n", settings.Timezone)
+	message += "_All swaps:_ every trade by a copied wallet\n"
+	message += "_Executed trades only:_ just trades made for you\n"
+	message += "_Daily digest:_ one summary a day of your trades, PnL and the target swaps you missed\n\n"
+	message += "During quiet hours only trade confirmations are sent; everything else is held until they end."
+
+	levelButton := func(level string) tgbotapi.InlineKeyboardButton {
+		label := notifyLevelNames[level]
+		if settings.NotifyLevel == level {
+			label = "✅ " + label
+		}
+		return tgbotapi.NewInlineKeyboardButtonData(label, "set_notify:"+level)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(levelButton(storage.NotifyAll)),
+		tgbotapi.NewInlineKeyboardRow(levelButton(storage.NotifyTrades)),
+		tgbotapi.NewInlineKeyboardRow(levelButton(storage.NotifyDigest)),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🌙 22-07", "set_quiet:22-7"),
+			tgbotapi.NewInlineKeyboardButtonData("🌙 00-08", "set_quiet:0-8"),
+			tgbotapi.NewInlineKeyboardButtonData("🔔 Off", "set_quiet:off"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📬 08:00", "set_digest_hour:8"),
+			tgbotapi.NewInlineKeyboardButtonData("📬 12:00", "set_digest_hour:12"),
+			tgbotapi.NewInlineKeyboardButtonData("📬 20:00", "set_digest_hour:20"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🌍 Set Timezone", "set_timezone"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "open_settings"),
+		),
+	)
+
+	msgConfig := tgbotapi.NewMessage(chatID, message)
+	msgConfig.ParseMode = "Markdown"
+	msgConfig.ReplyMarkup = keyboard
+	bot.Send(msgConfig)
+}
+
+// handleSetNotifyLevel updates the notification level
+func handleSetNotifyLevel(bot *tgbotapi.BotAPI, chatID int64, level string) {
+	if err := scanner.db.UpdateNotifyLevel(chatID, level); err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Error updating notifications: %v", err))
+		return
+	}
+	send(bot, chatID, fmt.Sprintf("✅ Notifications set to: %s", notifyLevelNames[level]))
+	handleSettingsNotifications(bot, chatID)
+}
+
+// handleSetQuietHours updates quiet hours from callback data, either
+// "off" or "START-END" in the user's local hours
+func handleSetQuietHours(bot *tgbotapi.BotAPI, chatID int64, window string) {
+	start, end := storage.QuietHoursOff, storage.QuietHoursOff
+	if window != "off" {
+		if _, err := fmt.Sscanf(window, "%d-%d", &start, &end); err != nil {
+			sendError(bot, chatID, "Invalid quiet hours")
+			return
+		}
+	}
+
+	if err := scanner.db.UpdateQuietHours(chatID, start, end); err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Error updating quiet hours: %v", err))
+		return
+	}
+	handleSettingsNotifications(bot, chatID)
+}
+
+// handleSetDigestHour updates the local hour of the daily digest
+func handleSetDigestHour(bot *tgbotapi.BotAPI, chatID int64, hourStr string) {
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil {
+		sendError(bot, chatID, "Invalid digest time")
+		return
+	}
+	if err := scanner.db.UpdateDigestHour(chatID, hour); err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Error updating digest time: %v", err))
+		return
+	}
+	handleSettingsNotifications(bot, chatID)
+}
+
+// handleSetTimezoneStart asks for the timezone quiet hours and the digest
+// follow
+func handleSetTimezoneStart(bot *tgbotapi.BotAPI, chatID int64) {
+	sessMu.Lock()
+	sessions[chatID] = &UserSession{
+		State:       "awaiting_timezone",
+		RequestedAt: time.Now().Unix(),
+	}
+	sessMu.Unlock()
+
+	send(bot, chatID, "🌍 *Timezone*\n\nEnter your timezone name (e.g., Europe/Berlin, America/Chicago, Asia/Tokyo):")
+}
+
+// handleTimezoneInput saves the timezone entered by the user
+func handleTimezoneInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	if err := scanner.db.UpdateTimezone(chatID, strings.TrimSpace(msg.Text)); err != nil {
+		send(bot, chatID, "❌ Unknown timezone. Please try again (e.g., Europe/Berlin):")
+		return
+	}
+
+	sessMu.Lock()
+	delete(sessions, chatID)
+	sessMu.Unlock()
+
+	handleSettingsNotifications(bot, chatID)
+}
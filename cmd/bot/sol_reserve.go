@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"solana-orchestrator/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// solReserveOptions are the SOL reserve options in settings
+var solReserveOptions = []float64{0, 0.005, 0.01, 0.02, 0.05, 0.1}
+
+// solReserveText describes a SOL reserve setting
+func solReserveText(sol float64) string {
+	if sol == 0 {
+		return "Off"
+	}
+	return fmt.Sprintf("%g SOL", sol)
+}
+
+// handleSettingsSOLReserve shows the SOL reserve options
+func handleSettingsSOLReserve(bot *tgbotapi.BotAPI, chatID int64) {
+	settings, err := scanner.db.GetUserSettings(chatID)
+	if err != nil {
+		log.Printf("Error loading settings: %v", err)
+		settings = &storage.UserSettings{MinSOLReserve: storage.DefaultMinSOLReserve}
+	}
+
+	message := "🛟 *SOL Reserve*\n\n"
+	message += "SOL your buys, including copy trades and *max*, always leave in the wallet.\n\n"
+	message += "Keep enough to pay the fees for selling what you buy."
+
+	reserveButton := func(sol float64) tgbotapi.InlineKeyboardButton {
+		label := solReserveText(sol)
+		if settings.MinSOLReserve == sol {
+			label = "✅ " + label
+		}
+		return tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("set_reserve:%g", sol))
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, sol := range solReserveOptions {
+		row = append(row, reserveButton(sol))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "open_settings"),
+	))
+
+	msgConfig := tgbotapi.NewMessage(chatID, message)
+	msgConfig.ParseMode = "Markdown"
+	msgConfig.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	bot.Send(msgConfig)
+}
+
+// handleSetSOLReserve updates the SOL reserve
+func handleSetSOLReserve(bot *tgbotapi.BotAPI, chatID int64, value string) {
+	sol, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		err = scanner.db.UpdateMinSOLReserve(chatID, sol)
+	}
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Error updating SOL reserve: %v", err))
+		return
+	}
+	send(bot, chatID, fmt.Sprintf("✅ SOL reserve set to: %s", solReserveText(sol)))
+	handleSettings(bot, chatID)
+}
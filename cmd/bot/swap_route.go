@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"solana-orchestrator/engine"
+	"solana-orchestrator/storage"
+	"solana-orchestrator/trading"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// swapTx is an unsigned swap ready to sign and submit
+type swapTx struct {
+	tx                   *solana.Transaction
+	lastValidBlockHeight uint64
+}
+
+// buildBuyTx builds a buy of lamports of SOL into mint through Jupiter, or
+// on the Pump.fun bonding curve for tokens Jupiter can't route before they
+// graduate. Failures are reported to the user, with info's liquidity when
+// there is no route; ok is false after one.
+func buildBuyTx(bot *tgbotapi.BotAPI, chatID int64, trade *engine.TradeLog, user solana.PublicKey, mint string, info *trading.TokenInfo, lamports uint64, settings *storage.UserSettings) (*swapTx, bool) {
+	quote, err := trading.GetBuyQuote(context.Background(), mint, lamports, settings.SlippageBps)
+	trade.Stage(engine.StageQuote, err, fmt.Sprintf("%d lamports of %s, slippage %d bps", lamports, mint, settings.SlippageBps))
+	if err == nil {
+		return jupiterSwapTx(bot, chatID, trade, quote, user, settings)
+	}
+	if trading.UsePumpFun(err) {
+		if swap, handled := pumpFunSwapTx(bot, chatID, trade, mint, settings, func(pump *trading.PumpFunSwap, mintKey solana.PublicKey) (*trading.PumpFunTx, error) {
+			return pump.BuildBuy(context.Background(), user, mintKey, lamports, settings.SlippageBps, settings.PriorityFeeLamports)
+		}); handled {
+			return swap, swap != nil
+		}
+	}
+	send(bot, chatID, quoteErrorMessage(err, info))
+	return nil, false
+}
+
+// buildSellTx builds a sale of amount raw units of mint for SOL, through
+// Jupiter or the Pump.fun bonding curve like buildBuyTx
+func buildSellTx(bot *tgbotapi.BotAPI, chatID int64, trade *engine.TradeLog, user solana.PublicKey, mint string, info *trading.TokenInfo, amount uint64, settings *storage.UserSettings) (*swapTx, bool) {
+	quote, err := trading.GetSellQuote(context.Background(), mint, amount, settings.SlippageBps)
+	trade.Stage(engine.StageQuote, err, fmt.Sprintf("%d units of %s, slippage %d bps", amount, mint, settings.SlippageBps))
+	if err == nil {
+		return jupiterSwapTx(bot, chatID, trade, quote, user, settings)
+	}
+	if trading.UsePumpFun(err) {
+		if swap, handled := pumpFunSwapTx(bot, chatID, trade, mint, settings, func(pump *trading.PumpFunSwap, mintKey solana.PublicKey) (*trading.PumpFunTx, error) {
+			return pump.BuildSell(context.Background(), user, mintKey, amount, settings.SlippageBps, settings.PriorityFeeLamports)
+		}); handled {
+			return swap, swap != nil
+		}
+	}
+	send(bot, chatID, quoteErrorMessage(err, info))
+	return nil, false
+}
+
+// jupiterSwapTx fetches and decodes the Jupiter transaction for quote,
+// unless the quote moves the price more than the user allows
+func jupiterSwapTx(bot *tgbotapi.BotAPI, chatID int64, trade *engine.TradeLog, quote *trading.JupiterQuote, user solana.PublicKey, settings *storage.UserSettings) (*swapTx, bool) {
+	if impact, err := quote.PriceImpact(); err == nil && impactBlocked(bot, chatID, trade, impact, settings) {
+		return nil, false
+	}
+
+	// GetSwapTransaction clamps the user's fee and defaults it when unset
+	swapResp, err := trading.GetSwapTransaction(context.Background(), quote, user.String(), settings.PriorityFeeLamports)
+	trade.Stage(engine.StageBuild, err, "")
+	if err != nil {
+		send(bot, chatID, tradeErrorMessage("Failed to build transaction", err))
+		return nil, false
+	}
+
+	txBytes, err := base64.StdEncoding.DecodeString(swapResp.SwapTransaction)
+	if err != nil {
+		trade.Stage(engine.StageBuild, err, "decode transaction")
+		send(bot, chatID, "❌ Failed to decode transaction")
+		return nil, false
+	}
+
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(txBytes))
+	if err != nil {
+		trade.Stage(engine.StageBuild, err, "deserialize transaction")
+		send(bot, chatID, fmt.Sprintf("❌ Failed to deserialize transaction: %v", err))
+		return nil, false
+	}
+	return &swapTx{tx: tx, lastValidBlockHeight: swapResp.LastValidBlockHeight}, true
+}
+
+// pumpFunSwapTx builds a bonding curve swap after Jupiter found no route,
+// unless it moves the curve's price more than the user allows. handled is
+// false if mint isn't on an active curve, leaving the Jupiter error to be
+// reported; otherwise a nil swap means a reported failure.
+func pumpFunSwapTx(bot *tgbotapi.BotAPI, chatID int64, trade *engine.TradeLog, mint string, settings *storage.UserSettings, build func(*trading.PumpFunSwap, solana.PublicKey) (*trading.PumpFunTx, error)) (swap *swapTx, handled bool) {
+	mintKey, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return nil, false
+	}
+
+	pumpTx, err := build(trading.NewPumpFunSwap(rpc.New(getShyftRPCURL())), mintKey)
+	if errors.Is(err, trading.ErrNotOnBondingCurve) {
+		return nil, false
+	}
+	trade.Stage(engine.StageBuild, err, "pump.fun bonding curve")
+	if err != nil {
+		send(bot, chatID, tradeErrorMessage("Failed to build Pump.fun transaction", err))
+		return nil, true
+	}
+	if impactBlocked(bot, chatID, trade, pumpTx.PriceImpactPct, settings) {
+		return nil, true
+	}
+	return &swapTx{tx: pumpTx.Transaction, lastValidBlockHeight: pumpTx.LastValidBlockHeight}, true
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"solana-orchestrator/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// inactiveAfter is how long a copy target can go without a swap before it
+// is flagged inactive
+func inactiveAfter() time.Duration {
+	return time.Duration(globalCfg.CopyTrading.InactiveAfterHours) * time.Hour
+}
+
+// idleLabel renders a time since, e.g. "45m", "20h" or "9d"
+func idleLabel(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// activityLabel describes how recently a target swapped, flagging it once
+// it has been idle for idle
+func activityLabel(t *storage.CopyTradeTarget, now time.Time, idle time.Duration) string {
+	label := "no swaps since added"
+	if t.LastActivity > 0 {
+		label = fmt.Sprintf("last swap %s ago", idleLabel(now.Sub(time.Unix(t.LastActivity, 0))))
+	}
+	if t.Inactive(now, idle) {
+		return "💤 Inactive, " + label
+	}
+	return "🟢 " + label
+}
+
+// newlyInactive returns the targets that went idle for idle within the
+// last every, so a check run every that often flags each one once
+func newlyInactive(targets []*storage.CopyTradeTarget, now time.Time, idle, every time.Duration) []*storage.CopyTradeTarget {
+	var flagged []*storage.CopyTradeTarget
+	for _, t := range targets {
+		if t.Inactive(now, idle) && !t.Inactive(now.Add(-every), idle) {
+			flagged = append(flagged, t)
+		}
+	}
+	return flagged
+}
+
+// suggestPruningInactiveTargets offers to remove copy targets that went
+// inactive since the last check, every ago
+func suggestPruningInactiveTargets(bot *tgbotapi.BotAPI, db *storage.DB, now time.Time, every time.Duration) {
+	targets, err := db.GetAllActiveCopyTargets()
+	if err != nil {
+		log.Printf("❌ Failed to load copy targets for the activity check: %v", err)
+		return
+	}
+
+	idle := inactiveAfter()
+	for _, t := range newlyInactive(targets, now, idle, every) {
+		text := "💤 *Inactive Copy Target*\n\n"
+		text += fmt.Sprintf("`%s` hasn't swapped in %s, so there is nothing to copy.\n\n", t.TargetWallet, idleLabel(idle))
+		text += "Remove it to keep your target list tidy?"
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🗑 Remove Target", fmt.Sprintf("stop_copy:%s", t.TargetWallet)),
+				tgbotapi.NewInlineKeyboardButtonData("📋 My Targets", "copy_list_targets"),
+			),
+		)
+		sendWithKeyboard(bot, t.UserID, text, keyboard)
+	}
+}
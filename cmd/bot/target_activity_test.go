@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"solana-orchestrator/storage"
+)
+
+func TestTargetActivity(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	idle := 7 * 24 * time.Hour
+	ago := func(d time.Duration) int64 { return now.Add(-d).Unix() }
+
+	fresh := &storage.CopyTradeTarget{TargetWallet: "fresh", CreatedAt: ago(time.Hour)}
+	busy := &storage.CopyTradeTarget{TargetWallet: "busy", CreatedAt: ago(30 * 24 * time.Hour), LastActivity: ago(3 * time.Hour)}
+	justIdle := &storage.CopyTradeTarget{TargetWallet: "justIdle", CreatedAt: ago(30 * 24 * time.Hour), LastActivity: ago(idle + 10*time.Minute)}
+	longIdle := &storage.CopyTradeTarget{TargetWallet: "longIdle", CreatedAt: ago(30 * 24 * time.Hour), LastActivity: ago(9 * 24 * time.Hour)}
+
+	tests := []struct {
+		target *storage.CopyTradeTarget
+		want   string
+	}{
+		{fresh, "🟢 no swaps since added"},
+		{busy, "🟢 last swap 3h ago"},
+		{longIdle, "💤 Inactive, last swap 9d ago"},
+	}
+	for _, tt := range tests {
+		if got := activityLabel(tt.target, now, idle); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.target.TargetWallet, tt.want, got)
+		}
+	}
+
+	// Only targets that crossed the threshold since the last hourly check
+	// are suggested for pruning
+	flagged := newlyInactive([]*storage.CopyTradeTarget{fresh, busy, justIdle, longIdle}, now, idle, time.Hour)
+	var names []string
+	for _, target := range flagged {
+		names = append(names, target.TargetWallet)
+	}
+	if strings.Join(names, ",") != "justIdle" {
+		t.Errorf("Expected only justIdle flagged, got %v", names)
+	}
+}
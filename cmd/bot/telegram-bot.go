@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"solana-orchestrator/analyzer"
 	"solana-orchestrator/api"
@@ -19,16 +20,22 @@ import (
 	iengine "solana-orchestrator/internal/engine"
 	isolana "solana-orchestrator/internal/solana"
 	"solana-orchestrator/storage"
+	"solana-orchestrator/trading"
 
 	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/redis/go-redis/v9"
 )
 
+// botDBPath is the SQLite database file used by the bot
+const botDBPath = "bot.db"
+
 type UserSession struct {
 	State       string
 	RequestedAt int64
 	Winrate     float64
+	PnL         pnlFilter
 	StartCount  int
 	ScanType    string // "realtime" or "slow"
 	TempData    map[string]interface{}
@@ -43,28 +50,62 @@ type Scanner struct {
 	isScanning    bool
 	walletsCache  map[string]*storage.WalletData // In-memory cache for fast lookups
 	walletsList   []*storage.WalletData          // Ordered list for scalable iteration
+	index         walletIndex                    // walletsCache sorted for filter searches
+}
+
+// addWallet puts a scanned wallet in the cache and index. Only wallets
+// missing from the cache are appended to the list, so re-scanning a
+// wallet doesn't add it twice. The caller must hold s.mu.
+func (s *Scanner) addWallet(w *storage.WalletData) {
+	old, exists := s.walletsCache[w.Wallet]
+	if !exists {
+		s.walletsList = append(s.walletsList, w)
+	}
+	s.walletsCache[w.Wallet] = w
+	s.index.put(old, w)
+}
+
+// warmCache loads the wallets still inside the display window into the
+// cache, list and index
+func (s *Scanner) warmCache() (int, error) {
+	wallets, err := s.db.GetWallets()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, w := range wallets {
+		s.addWallet(w)
+	}
+	return len(s.walletsList), nil
 }
 
 type PendingScan struct {
-	UserID      int64
-	Results     []*storage.WalletData
-	DeliverAt   time.Time
-	ScanType    string
-	Winrate     float64
-	RealizedPnL float64
+	UserID    int64
+	Results   []*storage.WalletData
+	DeliverAt time.Time
+	ScanType  string
+	Winrate   float64
+	PnL       pnlFilter
+	// CreditsSpent is refunded if the scan is cancelled before delivery
+	CreditsSpent int
 }
 
 var (
 	scanner        *Scanner
 	sessions       = make(map[int64]*UserSession)
 	sessMu         sync.RWMutex
-	tempWalletAddr = make(map[int64]string) // Temporary storage for wallet addresses during input
+	tempWalletAddr = newChatStore[string]() // Temporary storage for wallet addresses during input
 	globalCfg      *config.Config           // Global config for use in handlers
 	pendingScans   = make(map[int64]*PendingScan)
+	runningScans   = make(map[int64]bool) // chats with a slow scan not yet queued; guarded by pendingScansMu
 	pendingScansMu sync.RWMutex
 	// copyEngine     *trading.CopyTradeEngine // Deprecated
 	fanoutEngine *engine.FanOutEngine
 	redisClient  *redis.Client
+	killSwitch   *engine.KillSwitch
+	limitOrders  *isolana.LimitOrderManager
 )
 
 func main() {
@@ -75,15 +116,27 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
 
 	// Store config globally for handlers
 	globalCfg = cfg
 
+	// Swap in a backup staged by /restore before opening the DB
+	if restored, err := storage.ApplyPendingRestore(botDBPath); err != nil {
+		log.Printf("❌ Failed to apply pending restore: %v", err)
+	} else if restored {
+		log.Printf("✅ Restored database from staged backup")
+	}
+
 	// Initialize DB
-	db, err := storage.New("bot.db")
+	db, err := storage.New(botDBPath)
 	if err != nil {
 		log.Fatal(err)
 	}
+	db.SetWalletRetention(cfg.ScanSettings.WalletDisplayWindow(), cfg.ScanSettings.WalletRetention())
+	trading.SetSymbolCache(db, rpc.New(getShyftRPCURL()))
 
 	// Initialize scanner with DB and cache
 	scanner = &Scanner{
@@ -92,12 +145,25 @@ func main() {
 		walletsList:  make([]*storage.WalletData, 0),
 	}
 
-	log.Printf("📦 Scanner initialized with empty cache")
+	// Warm the cache so searches find wallets before the first cycle ends
+	if warmed, err := scanner.warmCache(); err != nil {
+		log.Printf("⚠️ Failed to warm wallet cache: %v", err)
+	} else {
+		log.Printf("📦 Scanner initialized with %d cached wallets", warmed)
+	}
+
+	// Serve scan results over HTTP if configured
+	startResultsAPI(cfg.ResultsAPI, db)
+
+	tradeLogger = engine.NewTradeLogger(db)
 
 	// Get bot token from environment
-	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	botToken := os.Getenv(config.EnvPrefix + "TELEGRAM_BOT_TOKEN")
 	if botToken == "" {
-		log.Fatal("TELEGRAM_BOT_TOKEN environment variable not set")
+		botToken = os.Getenv("TELEGRAM_BOT_TOKEN")
+	}
+	if botToken == "" {
+		log.Fatal("SOLORCH_TELEGRAM_BOT_TOKEN environment variable not set")
 	}
 
 	// Initialize bot
@@ -106,40 +172,40 @@ func main() {
 		log.Fatal(err)
 	}
 	log.Printf("Bot started: @%s", bot.Self.UserName)
+	registerCommands(bot)
 
 	// Start cleanup routine
-	go cleanupRoutine(db)
+	go cleanupRoutine(bot, db)
 
-	// Start continuous scanning with reduced concurrency
-	go continuousScanner(cfg, bot)
+	// Per-user flood protection for incoming updates
+	updateLimiter = newUserLimiter(cfg.RateLimits)
+	go updateLimiter.cleanupRoutine()
 
-	// Initialize Redis
-	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr == "" {
-		redisAddr = "localhost:6379"
-	}
-	redisClient, err = engine.NewRedisClient(redisAddr, "", 0)
+	// Initialize Redis (SOLORCH_REDIS_ADDR / REDIS_ADDR are applied by config.Load)
+	redisClient, err = engine.NewRedisClient(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB)
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 	// defer redisClient.Close() // We let it run until exit
+	killSwitch = engine.NewKillSwitch(redisClient)
+	if !killSwitch.TradingEnabled(context.Background()) {
+		log.Println("⏸️ Trading is disabled by the kill switch")
+	}
+
+	// Start continuous scanning with reduced concurrency; it caches token
+	// lists in Redis
+	go continuousScanner(cfg, bot)
 
 	// Initialize Fan-Out Engine
-	shyftAPIKey := os.Getenv("SHYFT_API_KEY")
-	if shyftAPIKey == "" {
-		// Fallback to config if env not set, though env is preferred
-		shyftAPIKey = cfg.ShyftAPIKey
-		if shyftAPIKey == "" {
-			// Extract from URL as last resort
-			shyftAPIKey = api.ExtractAPIKey(cfg.WebSocketSettings.ShyftWSURL)
-		}
+	// config.Load has already applied SOLORCH_SHYFT_API_KEY / SHYFT_API_KEY
+	if cfg.ShyftKey() == "" {
+		log.Fatal("SOLORCH_SHYFT_API_KEY environment variable required")
 	}
 
-	if shyftAPIKey == "" {
-		log.Fatal("SHYFT_API_KEY environment variable required")
+	fanoutEngine, err = engine.NewFanOutEngine(db, bot, redisClient, cfg)
+	if err != nil {
+		log.Fatalf("❌ Failed to start the Fan-Out Engine: %v", err)
 	}
-
-	fanoutEngine = engine.NewFanOutEngine(db, bot, redisClient, cfg)
 	fanoutEngine.Start()
 	// defer fanoutEngine.Shutdown()
 
@@ -167,12 +233,13 @@ func main() {
 	// For now, let's use a hardcoded public RPC or add to config.
 	// Better to add to config, but for bug fix, let's use a known public one or empty string if Manager handles it.
 	rpcURL := "https://api.mainnet-beta.solana.com" // Fallback
-	limitOrderManager := isolana.NewLimitOrderManager(rpcURL, jitoClient, db)
+	limitOrders = isolana.NewLimitOrderManager(rpcURL, jitoClient, db)
 
 	// Initialize Janitor
 	// Janitor needs JitoClient and LimitOrderManager
-	janitor := iengine.NewJanitor(db, jitoClient, limitOrderManager)
+	janitor := iengine.NewJanitor(db, jitoClient, limitOrders, killSwitch, cfg.Janitor)
 	janitor.Start()
+	go forwardJanitorNotices(bot, janitor.Notify)
 	log.Println("🧹 Janitor service started")
 
 	// Start Copy Trade Engine (DEPRECATED - Replaced by Fan-Out Engine)
@@ -186,155 +253,341 @@ func main() {
 	updates := bot.GetUpdatesChan(u)
 
 	for update := range updates {
-		if update.Message != nil {
-			handleMessage(bot, update.Message)
-		} else if update.CallbackQuery != nil {
-			handleCallback(bot, update.CallbackQuery)
-		}
+		dispatchUpdate(bot, update)
 	}
 }
 
-func cleanupRoutine(db *storage.DB) {
-	ticker := time.NewTicker(1 * time.Hour)
+// cleanupInterval is how often old data is cleaned up and copy targets
+// are checked for inactivity
+const cleanupInterval = 1 * time.Hour
+
+func cleanupRoutine(bot *tgbotapi.BotAPI, db *storage.DB) {
+	ticker := time.NewTicker(cleanupInterval)
 	defer ticker.Stop()
+	sessionTicker := time.NewTicker(sessionSweepInterval)
+	defer sessionTicker.Stop()
 
-	for range ticker.C {
-		deleted, err := db.CleanupOldData()
-		if err != nil {
-			log.Printf("❌ Cleanup error: %v", err)
-			continue
+	for {
+		select {
+		case <-sessionTicker.C:
+			if expired := sweepSessions(bot, time.Now()); expired > 0 {
+				log.Printf("🧹 Expired %d idle sessions", expired)
+			}
+		case now := <-ticker.C:
+			suggestPruningInactiveTargets(bot, db, now, cleanupInterval)
+			deleted, err := db.CleanupOldData()
+			if err != nil {
+				log.Printf("❌ Cleanup error: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("🧹 Cleaned up %d old wallet records", deleted)
+			}
 		}
-		if deleted > 0 {
-			log.Printf("🧹 Cleaned up %d old wallet records", deleted)
+	}
+}
+
+// recordScanCycle stores a finished scan cycle for /scanhistory
+func recordScanCycle(start time.Time, tokens, scanned, found int, cycleErr error) {
+	cycle := &storage.ScanCycle{
+		StartedAt:      start.Unix(),
+		DurationMs:     time.Since(start).Milliseconds(),
+		TokensFetched:  tokens,
+		WalletsScanned: scanned,
+		WalletsFound:   found,
+	}
+	if cycleErr != nil {
+		cycle.Error = cycleErr.Error()
+	}
+	if err := scanner.db.SaveScanCycle(cycle); err != nil {
+		log.Printf("❌ Failed to record scan cycle: %v", err)
+	}
+}
+
+// Pauses between scan cycles
+const (
+	scanInterval   = 30 * time.Minute
+	scanRetryPause = 5 * time.Minute
+)
+
+// foundWebhook receives found wallets when webhook.url is configured
+var foundWebhook *api.Webhook
+
+// pushFoundWallet sends a found wallet to the webhook in the background
+// if it passes the webhook's filters
+func pushFoundWallet(hook config.WebhookConfig, w *storage.WalletData) {
+	if foundWebhook == nil || !hook.Matches(w.Winrate, w.RealizedPnLPct) {
+		return
+	}
+	found := api.FoundWallet{
+		Wallet:         w.Wallet,
+		Winrate:        w.Winrate,
+		RealizedPnLPct: w.RealizedPnLPct,
+		RealizedPnLUSD: w.RealizedPnLUSD,
+		TradeCount:     w.TradeCount,
+		Timestamp:      w.ScannedAt,
+	}
+	go func() {
+		if err := foundWebhook.Send(context.Background(), found); err != nil {
+			log.Printf("⚠️ Webhook delivery failed for %s: %v", shortAddr(w.Wallet), err)
 		}
+	}()
+}
+
+// rescanRequests wakes the scanner for a cycle with fresh token lists;
+// see /rescan
+var rescanRequests = make(chan struct{}, 1)
+
+// waitForNextCycle sleeps for d or until a rescan is requested, and
+// reports whether one was
+func waitForNextCycle(d time.Duration) bool {
+	select {
+	case <-rescanRequests:
+		return true
+	case <-time.After(d):
+		return false
 	}
 }
 
+// continuousScanner runs scan cycles forever. Each cycle is bounded by
+// scan_settings.max_cycle_minutes; a cycle that times out, panics or
+// fails is reported on scan:progress and retried after a short pause.
+// Token lists are reused for api_settings.token_cache_ttl_sec unless the
+// cycle was requested with /rescan.
 func continuousScanner(cfg *config.Config, bot *tgbotapi.BotAPI) {
-	client := api.NewClient(cfg.MoralisAPIKey, cfg.BirdeyeAPIKey, cfg.APISettings.MaxRetries, cfg.MoralisFallbackKeys)
+	client := newAPIClient(cfg)
+	client.SetCache(api.NewRedisCache(redisClient), cfg.APISettings.TokenCacheTTL())
+	firstSeen := api.NewFirstSeenResolver(getShyftRPCURL(), scanner.db)
+	if cfg.Webhook.URL != "" {
+		foundWebhook = api.NewWebhook(cfg.Webhook.URL, cfg.Webhook.Secret, cfg.Webhook.MaxRetries)
+	}
 
+	fresh := false
 	for {
-		log.Println("🔄 Starting new scan cycle...")
+		cycleStart := time.Now()
+		bypassCache := fresh
+		err := guardCycle(cfg.ScanSettings.MaxCycle(), scanCancelGrace, func(ctx context.Context) error {
+			if bypassCache {
+				ctx = api.WithoutCache(ctx)
+			}
+			return runScanCycle(ctx, cfg, client, firstSeen, cycleStart)
+		})
+		if err == nil {
+			fresh = waitForNextCycle(scanInterval)
+			continue
+		}
+
+		log.Printf("❌ Scan cycle failed: %v", err)
+		if !errors.Is(err, errTokenFetch) {
+			// Token fetch failures record their own cycle
+			recordScanCycle(cycleStart, 0, 0, 0, err)
+		}
 		scanner.mu.Lock()
-		scanner.lastScanStart = time.Now().Unix()
-		scanner.scannedCount = 0
-		scanner.isScanning = true
+		scanner.isScanning = false
+		found := len(scanner.walletsList)
 		scanner.mu.Unlock()
+		publishScanFailure(err, found)
+		fresh = waitForNextCycle(scanRetryPause)
+	}
+}
 
-		// Publish scan start to Redis
-		publishScanProgress(0, 0, true, 0)
+// errTokenFetch marks a cycle that ended because no tokens could be fetched
+var errTokenFetch = errors.New("token fetch")
+
+// runScanCycle fetches tokens, collects their holders and traders and
+// analyses those wallets. Results arriving after ctx is cancelled are
+// dropped, so an abandoned cycle can't touch the next one's counters.
+func runScanCycle(ctx context.Context, cfg *config.Config, client *api.Client, firstSeen *api.FirstSeenResolver, cycleStart time.Time) error {
+	log.Println("🔄 Starting new scan cycle...")
+	scanner.mu.Lock()
+	scanner.lastScanStart = cycleStart.Unix()
+	scanner.scannedCount = 0
+	scanner.totalWallets = 0 // unknown until the wallet list is built
+	scanner.isScanning = true
+	scanner.mu.Unlock()
+
+	// Publish scan start to Redis
+	publishScanProgress(0, 0, true, 0)
+
+	var tokens []api.Token
+	var err error
+
+	if cfg.APISettings.TokenSource == "moralis" {
+		log.Printf("Fetching graduated tokens from Moralis...")
+		tokens, err = client.FetchGraduatedTokens(ctx, cfg.APISettings.TokenLimit)
+	} else {
+		log.Printf("Fetching tokens from Birdeye...")
+		tokens, err = client.FetchBirdeyeTokens(ctx, cfg.APISettings.TokenLimit)
+	}
 
-		var tokens []api.Token
-		var err error
+	if err != nil {
+		err = fmt.Errorf("%w: %v", errTokenFetch, err)
+		recordScanCycle(cycleStart, 0, 0, 0, err)
+		return err
+	}
 
-		if cfg.APISettings.TokenSource == "moralis" {
-			log.Printf("Fetching graduated tokens from Moralis...")
-			tokens, err = client.FetchGraduatedTokens(context.Background(), cfg.APISettings.TokenLimit)
-		} else {
-			log.Printf("Fetching tokens from Birdeye...")
-			tokens, err = client.FetchBirdeyeTokens(context.Background(), cfg.APISettings.TokenLimit)
-		}
+	fetched := len(tokens)
+	tokens, skipped := api.FilterTokens(tokens, cfg.APISettings.MinTokenLiquidityUSD)
+	if skipped > 0 {
+		log.Printf("⏭️ Skipped %d/%d tokens below $%.0f liquidity", skipped, fetched, cfg.APISettings.MinTokenLiquidityUSD)
+	}
 
-		if err != nil {
-			log.Printf("❌ Token fetch error: %v", err)
-			time.Sleep(5 * time.Minute)
-			continue
+	walletSet := make(map[string]bool)
+	for _, token := range tokens {
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
 
-		walletSet := make(map[string]bool)
-		for _, token := range tokens {
-			// Get Holders
-			holders, err := client.GetTokenHolders(context.Background(), token.TokenAddress)
-			if err == nil {
-				for _, h := range holders {
-					walletSet[h.OwnerAddress] = true
-				}
+		// Get Holders
+		holders, err := client.GetTokenHolders(ctx, token.TokenAddress)
+		if err == nil {
+			for _, h := range holders {
+				walletSet[h.OwnerAddress] = true
 			}
-
-			// Get Top Traders (if enabled)
-			if cfg.APISettings.FetchTraders {
-				traders, err := client.FetchTopTraders(context.Background(), token.TokenAddress)
-				if err == nil {
-					for _, t := range traders {
-						walletSet[t] = true
-					}
-				}
-				time.Sleep(200 * time.Millisecond) // Rate limit
+			if _, err := recordHolderStats(ctx, token.TokenAddress, holders); err != nil {
+				log.Printf("⚠️ Holder concentration for %s: %v", token.TokenAddress, err)
 			}
-
-			time.Sleep(200 * time.Millisecond) // Faster fetching
 		}
 
-		wallets := make([]string, 0, len(walletSet))
-		for w := range walletSet {
-			wallets = append(wallets, w)
+		// Get Top Traders (if enabled)
+		if cfg.APISettings.FetchTraders {
+			traders, err := client.FetchTopTraders(ctx, token.TokenAddress)
+			if err == nil {
+				for _, t := range traders {
+					walletSet[t] = true
+				}
+			}
+			time.Sleep(200 * time.Millisecond) // Rate limit
 		}
 
-		scanner.mu.Lock()
-		scanner.totalWallets = len(wallets)
-		scanner.mu.Unlock()
-
-		log.Printf("📊 Scanning %d wallets...", len(wallets))
-
-		// Publish initial scan progress
-		publishScanProgress(0, len(wallets), true, 0)
+		time.Sleep(200 * time.Millisecond) // Faster fetching
+	}
 
-		// Use filters from config
-		a := analyzer.NewAnalyzer(6, cfg.AnalysisFilters.MinWinrate, cfg.AnalysisFilters.MinRealizedPnL)
-		results, err := a.AnalyzeWallets(context.Background(), wallets, func(r *analyzer.WalletStats) {
-			scanner.mu.Lock()
-			w := &storage.WalletData{
-				Wallet:      r.Wallet,
-				Winrate:     r.Winrate,
-				RealizedPnL: r.RealizedPnL,
-				ScannedAt:   time.Now().Unix(),
-			}
+	wallets := make([]string, 0, len(walletSet))
+	for w := range walletSet {
+		wallets = append(wallets, w)
+	}
 
-			// Save to DB and Cache
-			if err := scanner.db.SaveWallet(w); err != nil {
-				log.Printf("DB Error: %v", err)
+	// Leave out wallets that recently missed the filters, so the scan
+	// budget goes to wallets not seen yet
+	cooldown := cfg.ScanSettings.BelowThresholdCooldown()
+	if cooldown > 0 {
+		if kept, err := scanner.db.FilterBelowThreshold(wallets); err != nil {
+			log.Printf("⚠️ Failed to filter below-threshold wallets: %v", err)
+		} else {
+			if skipped := len(wallets) - len(kept); skipped > 0 {
+				log.Printf("⏭️ Skipped %d wallets below threshold within the last %s", skipped, cooldown)
 			}
+			wallets = kept
+		}
+	}
 
-			// Check if new wallet for the list
-			if _, exists := scanner.walletsCache[w.Wallet]; !exists {
-				scanner.walletsList = append(scanner.walletsList, w)
+	scanner.mu.Lock()
+	scanner.totalWallets = len(wallets)
+	scanner.mu.Unlock()
+
+	// Running searches decide how many pages the cycle gets and which
+	// wallets go first
+	demands := activeScanDemands()
+	pages := scanPages(demands, cfg.Analyzer.Pages)
+	log.Printf("📊 Scanning %d wallets on %d pages (%d searches running)...", len(wallets), pages, len(demands))
+
+	// Publish initial scan progress
+	publishScanProgress(0, len(wallets), true, 0)
+
+	// Use filters from config
+	a := analyzer.NewAnalyzer(pages, cfg.AnalysisFilters.MinWinrate, cfg.AnalysisFilters.MinRealizedPnL, cfg.Analyzer.WalletURLTemplate)
+	a.SetPriority(walletPriorities(demands, wallets))
+	if cooldown > 0 {
+		a.OnBelowThreshold(func(wallet string) {
+			if ctx.Err() != nil {
+				return
 			}
-
-			scanner.walletsCache[w.Wallet] = w
-			scanner.scannedCount++ // Increment progress counter
-
-			// Publish progress update every 10 wallets
-			if scanner.scannedCount%10 == 0 {
-				publishScanProgress(scanner.scannedCount, scanner.totalWallets, true, len(scanner.walletsList))
+			if err := scanner.db.MarkWalletBelowThreshold(wallet, cooldown); err != nil {
+				log.Printf("DB Error: %v", err)
 			}
-			scanner.mu.Unlock()
 		})
+	}
+	results, err := a.AnalyzeWallets(ctx, wallets, func(r *analyzer.WalletStats) {
+		if ctx.Err() != nil {
+			return
+		}
 
+		// Resolve wallet age before taking the lock; it's cached after
+		// the first lookup
+		lookupCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+		// A truncated lookup still bounds the wallet's age from below
+		firstSeenAt, _, err := firstSeen.FirstSeen(lookupCtx, r.Wallet)
+		cancel()
 		if err != nil {
-			log.Printf("Analysis error: %v", err)
+			log.Printf("⚠️ First-seen lookup failed for %s: %v", r.Wallet, err)
 		}
 
-		// Update final stats
 		scanner.mu.Lock()
-		scanner.scannedCount = len(results)
-		scanner.isScanning = false
-		foundCount := len(scanner.walletsList)
+		w := &storage.WalletData{
+			Wallet:         r.Wallet,
+			Winrate:        r.Winrate,
+			RealizedPnLPct: r.RealizedPnLPct,
+			RealizedPnLUSD: r.RealizedPnLUSD,
+			TradeCount:     r.TradeCount,
+			ScannedAt:      time.Now().Unix(),
+			FirstSeen:      firstSeenAt,
+		}
+
+		// Save to DB and Cache
+		if err := scanner.db.SaveWallet(w); err != nil {
+			log.Printf("DB Error: %v", err)
+		}
+
+		scanner.addWallet(w)
+		scanner.scannedCount++ // Increment progress counter
+
+		// Publish progress update every 10 wallets
+		if scanner.scannedCount%10 == 0 {
+			publishScanProgress(scanner.scannedCount, scanner.totalWallets, true, len(scanner.walletsList))
+		}
 		scanner.mu.Unlock()
 
-		// Publish scan complete to Redis
-		publishScanProgress(len(results), len(results), false, foundCount)
+		pushFoundWallet(cfg.Webhook, w)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 
-		log.Printf("✅ Scan complete: %d wallets stored", len(results))
-		time.Sleep(30 * time.Minute)
+	if err != nil {
+		log.Printf("Analysis error: %v", err)
 	}
+	recordScanCycle(cycleStart, fetched, len(wallets), len(results), err)
+
+	// Update final stats
+	scanner.mu.Lock()
+	scanner.scannedCount = len(results)
+	scanner.isScanning = false
+	foundCount := len(scanner.walletsList)
+	scanner.mu.Unlock()
+
+	// Publish scan complete to Redis
+	publishScanProgress(len(results), len(results), false, foundCount)
+
+	log.Printf("✅ Scan complete: %d wallets stored", len(results))
+	return nil
 }
 
 func handleMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
 
+	if allowed, warn := updateLimiter.Allow(chatID); !allowed {
+		if warn {
+			sendWarning(bot, chatID, "Slow down! You're sending commands too quickly.")
+		}
+		return
+	}
+
 	if msg.IsCommand() {
 		switch msg.Command() {
 		case "start":
+			sendMenuKeyboard(bot, chatID)
+
 			// Check if user exists
 			user, err := scanner.db.GetUser(chatID)
 			if err != nil {
@@ -366,42 +619,86 @@ func handleMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		case "balance":
 			handleBalanceCommand(bot, chatID)
 
+		case "portfolio":
+			handlePortfolioCommand(bot, chatID)
+		case "monitor":
+			handleMonitorCommand(bot, chatID)
+
 		case "wallets":
 			handleWalletsCommand(bot, chatID)
 		case "admin":
 			handleAdminCommand(bot, chatID)
 		case "menu":
+			sendMenuKeyboard(bot, chatID)
 			showMainMenu(bot, chatID)
+		case "hidekeyboard":
+			handleHideKeyboard(bot, chatID)
 		case "copytrade":
 			handleCopyTradeCommand(bot, chatID)
+		case "copystats":
+			handleCopyStatsCommand(bot, chatID)
+		case "papertrade":
+			handlePaperTradeCommand(bot, chatID, msg.CommandArguments())
+		case "autosell":
+			handleAutoSellCommand(bot, chatID, msg.CommandArguments())
+		case "orders":
+			handleOrdersCommand(bot, chatID)
+		case "cancelorder":
+			handleCancelOrderCommand(bot, chatID, msg.CommandArguments())
+		case "token":
+			handleTokenCommand(bot, chatID, msg.CommandArguments())
 		case "buy":
 			handleStartBuy(bot, chatID)
 		case "sell":
 			handleStartSell(bot, chatID)
+		case "panic":
+			handlePanicCommand(bot, chatID)
+		case "rank":
+			handleRankCommand(bot, chatID, msg.CommandArguments())
+		case "backup":
+			handleBackupCommand(bot, chatID)
+		case "restore":
+			handleRestoreCommand(bot, chatID)
+		case "killswitch":
+			handleKillSwitchCommand(bot, chatID, msg.CommandArguments())
+		case "tradelog":
+			handleTradeLogCommand(bot, chatID, msg.CommandArguments())
+		case "scanhistory":
+			handleScanHistoryCommand(bot, chatID, msg.CommandArguments())
+		case "ping":
+			handlePingCommand(bot, chatID)
+		case "deliver":
+			handleDeliverCommand(bot, chatID, msg.CommandArguments())
+		case "cancelscan":
+			handleCancelPendingScan(bot, chatID)
+		case "rescan":
+			handleRescanCommand(bot, chatID)
 		}
 		return
 	}
 
 	// Handle Persistent Menu Commands
-	if msg.Text == "🔍 Dev Finder" {
+	if msg.Text == menuDevFinder {
 		showScanTypeModal(bot, chatID)
 		return
-	} else if msg.Text == "💰 My Credits" {
+	} else if msg.Text == menuCredits {
 		handleBalanceCommand(bot, chatID)
 		return
-	} else if msg.Text == "❓ Help/FAQ" {
+	} else if msg.Text == menuHelp {
 		send(bot, chatID, "📚 *Help & FAQ*\n\n*Credits*: 1 Credit is deducted for every wallet processed during a scan.\n*Dev Finder*: Scans Solana for profitable wallets based on your Win Rate and PnL filters.\n\nNeed more help? Contact support.")
 		return
-	} else if msg.Text == "⚙️ Settings" {
+	} else if msg.Text == menuSettings {
 		handleSettings(bot, chatID)
 		return
 	}
 
-	sessMu.RLock()
-	session, exists := sessions[chatID]
-	sessMu.RUnlock()
+	session, expired := freshSession(chatID, time.Now())
+	if expired {
+		handleExpiredSession(bot, msg, session.State)
+		return
+	}
 
-	if exists {
+	if session != nil {
 		if strings.HasPrefix(session.State, "admin_") {
 			handleAdminInput(bot, msg)
 			return
@@ -414,24 +711,44 @@ func handleMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 			handleWinrateInputV2(bot, msg)
 		} else if session.State == "awaiting_pnl_v2" {
 			handlePnlInputV2(bot, msg)
+		} else if session.State == "awaiting_min_age_v2" {
+			handleMinAgeInputV2(bot, msg)
 		} else if session.State == "awaiting_wallet_address" {
 			handleWalletAddressInput(bot, msg)
 		} else if session.State == "awaiting_wallet_name" {
 			handleWalletNameInput(bot, msg)
+		} else if session.State == "awaiting_wallet_rename" {
+			handleWalletRenameInput(bot, msg)
 		} else if session.State == "awaiting_wallet_password" {
 			handleWalletPassword(bot, msg)
 		} else if session.State == "awaiting_buy_token" {
 			handleBuyTokenInput(bot, msg)
 		} else if session.State == "awaiting_buy_amount" {
 			handleBuyAmountInput(bot, msg)
+		} else if session.State == "awaiting_buy_large_confirm" {
+			handleBuyLargeConfirmInput(bot, msg)
 		} else if session.State == "awaiting_buy_password" {
 			handleBuyPassword(bot, msg)
+		} else if session.State == "awaiting_sell_large_confirm" {
+			handleSellLargeConfirmInput(bot, msg)
 		} else if session.State == "awaiting_sell_password" {
 			handleSellPassword(bot, msg)
+		} else if session.State == "awaiting_panic_password" {
+			handlePanicPassword(bot, msg)
+		} else if session.State == "awaiting_order_price" {
+			handleOrderPriceInput(bot, msg)
+		} else if session.State == "awaiting_order_password" {
+			handleOrderPassword(bot, msg)
 		} else if session.State == "awaiting_copy_target" {
 			handleCopyTargetInput(bot, msg)
 		} else if session.State == "awaiting_copy_amount" {
 			handleCopyAmountInput(bot, msg)
+		} else if session.State == "awaiting_copy_min_sol" {
+			handleCopyMinSOLInput(bot, msg)
+		} else if session.State == "awaiting_payment_signature" {
+			handlePaymentSignatureInput(bot, msg)
+		} else if session.State == "awaiting_timezone" {
+			handleTimezoneInput(bot, msg)
 		}
 	}
 }
@@ -439,7 +756,7 @@ func handleMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 // handleWalletAddressInput processes wallet address input
 func handleWalletAddressInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
-	address := strings.TrimSpace(msg.Text)
+	address := storage.NormalizeWalletAddress(msg.Text)
 
 	// Validate Solana address
 	_, err := solana.PublicKeyFromBase58(address)
@@ -448,10 +765,17 @@ func handleWalletAddressInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		return
 	}
 
+	if walletLimitReached(bot, chatID) {
+		sessMu.Lock()
+		delete(sessions, chatID)
+		sessMu.Unlock()
+		return
+	}
+
 	// Check if wallet already exists
 	wallets, _ := scanner.db.GetUserWallets(chatID)
 	for _, w := range wallets {
-		if w.WalletAddress == address {
+		if storage.NormalizeWalletAddress(w.WalletAddress) == address {
 			sendWarning(bot, chatID, "This wallet is already added!")
 			sessMu.Lock()
 			delete(sessions, chatID)
@@ -467,23 +791,26 @@ func handleWalletAddressInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	sessMu.Unlock()
 
 	// Store address in session (we'll use a temp variable)
-	tempWalletAddr[chatID] = address
+	tempWalletAddr.Set(chatID, address)
 
 	send(bot, chatID, "✅ Valid address!\n\nNow give this wallet a name (e.g., 'Main Wallet', 'Trading'):")
 }
 
+// maxWalletNameLen limits wallet names shown in menus
+const maxWalletNameLen = 50
+
 // handleWalletNameInput processes wallet name input
 func handleWalletNameInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
 	name := strings.TrimSpace(msg.Text)
 
-	if len(name) > 50 {
-		sendError(bot, chatID, "Name too long (max 50 characters). Please try again:")
+	if utf8.RuneCountInString(name) > maxWalletNameLen {
+		sendError(bot, chatID, fmt.Sprintf("Name too long (max %d characters). Please try again:", maxWalletNameLen))
 		return
 	}
 
 	// Get stored address
-	address, ok := tempWalletAddr[chatID]
+	address, ok := tempWalletAddr.Get(chatID)
 	if !ok {
 		sendError(bot, chatID, "Session expired. Please start again with /wallets")
 		sessMu.Lock()
@@ -492,14 +819,19 @@ func handleWalletNameInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		return
 	}
 
-	// Add wallet to database
-	err := scanner.db.AddUserWallet(chatID, address, name)
-	if err != nil {
+	// Add wallet to database; the cap is checked again in case wallets
+	// were added from another session meanwhile
+	err := scanner.db.AddUserWalletWithLimit(chatID, address, name, globalCfg.Wallets.MaxPerUser)
+	if errors.Is(err, storage.ErrWalletLimitReached) {
+		sendWarning(bot, chatID, fmt.Sprintf("Wallet limit reached (%d).\n\nRemove a wallet from /wallets before adding another.", globalCfg.Wallets.MaxPerUser))
+	} else if err != nil {
 		sendError(bot, chatID, fmt.Sprintf("Error adding wallet: %v", err))
+	}
+	if err != nil {
 		sessMu.Lock()
 		delete(sessions, chatID)
 		sessMu.Unlock()
-		delete(tempWalletAddr, chatID)
+		tempWalletAddr.Delete(chatID)
 		return
 	}
 
@@ -513,20 +845,28 @@ func handleWalletNameInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	sessMu.Lock()
 	delete(sessions, chatID)
 	sessMu.Unlock()
-	delete(tempWalletAddr, chatID)
+	tempWalletAddr.Delete(chatID)
 
-	send(bot, chatID, fmt.Sprintf("✅ Wallet added successfully!\n\n*%s*\n`%s`", name, address))
+	send(bot, chatID, fmt.Sprintf("✅ Wallet added successfully!\n\n*%s*\n`%s`", escapeMarkdown(name), address))
 	handleWalletsCommand(bot, chatID)
 }
 
 func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
-	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
 	chatID := callback.Message.Chat.ID
 	data := callback.Data
 
+	if allowed, _ := updateLimiter.Allow(chatID); !allowed {
+		bot.Request(tgbotapi.NewCallback(callback.ID, "⏳ Slow down! Too many requests."))
+		return
+	}
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
 	if data == "show_scan_options" {
 		showScanTypeModal(bot, chatID)
+	} else if strings.HasPrefix(data, "select_plan:") {
+		handleTrialSelection(bot, chatID, strings.TrimPrefix(data, "select_plan:"))
 	} else if data == "btn_trial_credits" {
+		// Legacy buttons from welcome messages sent before the plan catalog
 		handleTrialSelection(bot, chatID, "credits_1000")
 	} else if data == "btn_trial_time" {
 		handleTrialSelection(bot, chatID, "trial_3day")
@@ -540,6 +880,8 @@ func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 		startDevFinder(bot, chatID)
 	} else if data == "dev_finder_v2" {
 		startDevFinderImproved(bot, chatID)
+	} else if data == "cancel_pending_scan" {
+		handleCancelPendingScan(bot, chatID)
 	} else if strings.HasPrefix(data, "cancel_search_") {
 		handleCancelSearch(bot, callback)
 		return
@@ -551,6 +893,8 @@ func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 		return
 	} else if data == "check_balance" || data == "refresh_balance" {
 		handleBalanceCommand(bot, chatID)
+	} else if data == "portfolio" || data == "refresh_portfolio" {
+		handlePortfolioCommand(bot, chatID)
 	} else if data == "manage_wallets" {
 		handleWalletsCommand(bot, chatID)
 	} else if data == "add_wallet" {
@@ -587,6 +931,9 @@ func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 	} else if strings.HasPrefix(data, "select_wallet:") {
 		walletAddr := strings.TrimPrefix(data, "select_wallet:")
 		handleSelectWallet(bot, chatID, walletAddr)
+	} else if strings.HasPrefix(data, "rename_wallet:") {
+		walletAddr := strings.TrimPrefix(data, "rename_wallet:")
+		handleRenameWalletStart(bot, chatID, walletAddr)
 	} else if strings.HasPrefix(data, "confirm_remove:") {
 		walletAddr := strings.TrimPrefix(data, "confirm_remove:")
 		handleConfirmRemove(bot, chatID, walletAddr)
@@ -596,6 +943,8 @@ func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 		handleStartSell(bot, chatID)
 	} else if data == "confirm_buy" {
 		handleConfirmBuy(bot, chatID)
+	} else if data == "confirm_buy_impact" {
+		handleAcceptBuyImpact(bot, chatID)
 	} else if data == "cancel_buy" {
 		sendError(bot, chatID, "Purchase cancelled")
 		cleanupBuySession(chatID)
@@ -616,12 +965,40 @@ func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 	} else if strings.HasPrefix(data, "set_prio_") {
 		lamports := parsePriorityCallback(data)
 		handleSetPriority(bot, chatID, lamports)
+	} else if data == "settings_impact" {
+		handleSettingsPriceImpact(bot, chatID)
+	} else if strings.HasPrefix(data, "set_impact:") {
+		handleSetPriceImpact(bot, chatID, strings.TrimPrefix(data, "set_impact:"))
+	} else if data == "settings_confirm" {
+		handleSettingsConfirm(bot, chatID)
+	} else if strings.HasPrefix(data, "set_confirm_above:") {
+		handleSetConfirmAbove(bot, chatID, strings.TrimPrefix(data, "set_confirm_above:"))
+	} else if data == "toggle_auto_confirm" {
+		handleToggleAutoConfirm(bot, chatID)
+	} else if data == "settings_reserve" {
+		handleSettingsSOLReserve(bot, chatID)
+	} else if strings.HasPrefix(data, "set_reserve:") {
+		handleSetSOLReserve(bot, chatID, strings.TrimPrefix(data, "set_reserve:"))
+	} else if data == "settings_execution" {
+		handleSettingsExecution(bot, chatID)
+	} else if strings.HasPrefix(data, "set_exec:") {
+		handleSetExecutionMode(bot, chatID, strings.TrimPrefix(data, "set_exec:"))
 	} else if data == "settings_copytrade" {
 		handleSettingsCopyTrade(bot, chatID)
 	} else if data == "toggle_copy_autobuy_on" {
 		handleToggleCopyTradeAutoBuy(bot, chatID, true)
 	} else if data == "toggle_copy_autobuy_off" {
 		handleToggleCopyTradeAutoBuy(bot, chatID, false)
+	} else if data == "settings_notify" {
+		handleSettingsNotifications(bot, chatID)
+	} else if strings.HasPrefix(data, "set_notify:") {
+		handleSetNotifyLevel(bot, chatID, strings.TrimPrefix(data, "set_notify:"))
+	} else if strings.HasPrefix(data, "set_quiet:") {
+		handleSetQuietHours(bot, chatID, strings.TrimPrefix(data, "set_quiet:"))
+	} else if strings.HasPrefix(data, "set_digest_hour:") {
+		handleSetDigestHour(bot, chatID, strings.TrimPrefix(data, "set_digest_hour:"))
+	} else if data == "set_timezone" {
+		handleSetTimezoneStart(bot, chatID)
 	} else if strings.HasPrefix(data, "sell_token:") {
 		tokenMint := strings.TrimPrefix(data, "sell_token:")
 		handleSellToken(bot, chatID, tokenMint)
@@ -634,12 +1011,24 @@ func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 		}
 	} else if data == "confirm_sell" {
 		handleConfirmSell(bot, chatID)
+	} else if data == "confirm_sell_impact" {
+		handleAcceptSellImpact(bot, chatID)
+	} else if strings.HasPrefix(data, "token_buy:") {
+		handleTokenBuy(bot, chatID, strings.TrimPrefix(data, "token_buy:"))
+	} else if strings.HasPrefix(data, "order_cancel:") {
+		handleOrderCancel(bot, chatID, strings.TrimPrefix(data, "order_cancel:"))
+	} else if strings.HasPrefix(data, "order_edit:") {
+		handleOrderEdit(bot, chatID, strings.TrimPrefix(data, "order_edit:"))
+	} else if data == "panic_confirm" {
+		handlePanicConfirm(bot, chatID)
+	} else if data == "panic_cancel" {
+		handlePanicCancel(bot, chatID)
 	} else if data == "back_to_menu" {
 		showMainMenu(bot, chatID)
 	} else if strings.HasPrefix(data, "admin_") {
 		handleAdminCallback(bot, callback)
 	} else if data == "top_up_credits" {
-		send(bot, chatID, "💎 *Top Up Credits*\n\nTo purchase more credits, please contact the admin:\n@AdminUser\n\nPackages:\n• 100 Credits: 0.1 SOL\n• 500 Credits: 0.4 SOL\n• 1000 Credits: 0.7 SOL")
+		handleTopUpCredits(bot, chatID)
 	} else if data == "help" {
 		send(bot, chatID, "📚 *Help & FAQ*\n\n*Credits*: 1 Credit is deducted for every wallet processed during a scan.\n*Dev Finder*: Scans Solana for profitable wallets based on your Win Rate and PnL filters.\n\nNeed more help? Contact support.")
 	} else if data == "copytrade" {
@@ -648,66 +1037,60 @@ func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 		handleAddCopyTargetStart(bot, chatID)
 	} else if data == "copy_list_targets" {
 		handleListCopyTargets(bot, chatID)
+	} else if data == "copy_stats" {
+		handleCopyStatsCommand(bot, chatID)
 	} else if strings.HasPrefix(data, "stop_copy:") {
 		target := strings.TrimPrefix(data, "stop_copy:")
 		handleStopCopyTarget(bot, chatID, target)
+	} else if data == "copy_min_skip" {
+		saveCopyTarget(bot, chatID, 0)
+	} else if strings.HasPrefix(data, "copy_sides:") {
+		handleCopySidesChoice(bot, chatID, strings.TrimPrefix(data, "copy_sides:"))
+	} else if strings.HasPrefix(data, "copy_toggle_buys:") {
+		handleToggleCopySide(bot, chatID, strings.TrimPrefix(data, "copy_toggle_buys:"), true)
+	} else if strings.HasPrefix(data, "copy_toggle_sells:") {
+		handleToggleCopySide(bot, chatID, strings.TrimPrefix(data, "copy_toggle_sells:"), false)
+	} else if strings.HasPrefix(data, "copy_toggle_paper:") {
+		handleToggleCopyPaper(bot, chatID, strings.TrimPrefix(data, "copy_toggle_paper:"))
 	}
 }
 
 func showTrialOptions(bot *tgbotapi.BotAPI, chatID int64) {
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💎 Get 1000 Credits", "btn_trial_credits"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⏳ 3-Day Free Trial", "btn_trial_time"),
-		),
-	)
-
+	var rows [][]tgbotapi.InlineKeyboardButton
 	text := "👋 *Welcome to Solana Wallet Scanner!*\n\n" +
-		"To get started, please choose your *Free Trial* plan:\n\n" +
-		"💎 *1000 Credits*\n" +
-		"• 1 Credit = 1 Wallet Scan\n" +
-		"• Use for Real-Time or Slow scans\n" +
-		"• No time limit\n\n" +
-		"⏳ *3-Day Free Trial*\n" +
-		"• Unlimited Scans\n" +
-		"• *Note:* Results delayed by 5-10 minutes\n" +
-		"• Expires in 3 days\n\n" +
-		"Select an option below:"
+		"To get started, please choose your *Free Trial* plan:\n\n"
+
+	for _, plan := range globalCfg.WelcomePlans() {
+		emoji := planEmoji(&plan)
+		text += fmt.Sprintf("%s *%s*\n%s\n\n", emoji, plan.Name, plan.Description)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(emoji+" "+plan.Name, "select_plan:"+plan.ID),
+		))
+	}
+	text += "Select an option below:"
 
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = keyboard
+	if len(rows) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
 	bot.Send(msg)
 }
 
-func handleTrialSelection(bot *tgbotapi.BotAPI, chatID int64, planType string) {
-	var text string
-	if planType == "credits_1000" {
-		err := scanner.db.SetUserPlan(chatID, "credits_1000", 1000, 0)
-		if err != nil {
-			log.Printf("Error setting plan: %v", err)
-			sendError(bot, chatID, "Error setting plan. Please try again.")
-			return
-		}
-		text = "✅ *Plan Activated: 1000 Credits*\n\n" +
-			"You have 1000 credits. Each scan costs 1 credit.\n" +
-			"You can use both Real-Time and Slow scans."
-	} else {
-		expiresAt := time.Now().Add(3 * 24 * time.Hour).Unix()
-		err := scanner.db.SetUserPlan(chatID, "trial_3day", 0, expiresAt)
-		if err != nil {
-			log.Printf("Error setting plan: %v", err)
-			sendError(bot, chatID, "Error setting plan. Please try again.")
-			return
-		}
-		text = "✅ *Plan Activated: 3-Day Free Trial*\n\n" +
-			"You have unlimited scans for 3 days.\n" +
-			"⚠️ *Note:* All your scans will have a 5-10 minute delay."
+func handleTrialSelection(bot *tgbotapi.BotAPI, chatID int64, planID string) {
+	plan := globalCfg.FindPlan(planID)
+	if plan == nil || !plan.Welcome {
+		sendError(bot, chatID, "This plan is no longer available.")
+		return
 	}
 
-	msg := tgbotapi.NewMessage(chatID, text)
+	if err := activatePlan(chatID, plan); err != nil {
+		log.Printf("Error setting plan: %v", err)
+		sendError(bot, chatID, "Error setting plan. Please try again.")
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, planActivatedText(plan))
 	msg.ParseMode = "Markdown"
 	bot.Send(msg)
 
@@ -741,11 +1124,11 @@ func showMainMenu(bot *tgbotapi.BotAPI, chatID int64) {
 	// Get User Plan for Header
 	user, _ := scanner.db.GetUser(chatID)
 	planBadge := ""
-	if user != nil {
-		if user.PlanType == "credits_1000" {
+	if plan := userPlan(user); plan != nil {
+		if plan.IsCredits() {
 			planBadge = fmt.Sprintf("\n💎 *%d Credits Available*", user.Credits)
-		} else if user.PlanType == "trial_3day" {
-			timeLeft := time.Until(time.Unix(user.TrialExpiresAt, 0))
+		} else if plan.IsTrial() {
+			timeLeft := user.TrialTimeLeft(scanner.db.Now())
 			days := int(timeLeft.Hours() / 24)
 			hours := int(timeLeft.Hours()) % 24
 			planBadge = fmt.Sprintf("\n⏰ *Free Trial: %dd %dh Left*", days, hours)
@@ -768,8 +1151,28 @@ func showMainMenu(bot *tgbotapi.BotAPI, chatID int64) {
 	bot.Send(msg)
 }
 
+// walletLimitReached tells the user and returns true when they already
+// have the maximum number of wallets
+func walletLimitReached(bot *tgbotapi.BotAPI, chatID int64) bool {
+	count, err := scanner.db.CountUserWallets(chatID)
+	if err != nil {
+		log.Printf("Error counting wallets: %v", err)
+		return false
+	}
+	if count < globalCfg.Wallets.MaxPerUser {
+		return false
+	}
+	sendWarning(bot, chatID, fmt.Sprintf("Wallet limit reached (%d/%d).\n\nRemove a wallet from /wallets before adding another.",
+		count, globalCfg.Wallets.MaxPerUser))
+	return true
+}
+
 // handleAddWalletStart starts the add wallet flow
 func handleAddWalletStart(bot *tgbotapi.BotAPI, chatID int64) {
+	if walletLimitReached(bot, chatID) {
+		return
+	}
+
 	sessMu.Lock()
 	sessions[chatID] = &UserSession{
 		State:       "awaiting_wallet_address",
@@ -819,18 +1222,81 @@ func handleSelectWallet(bot *tgbotapi.BotAPI, chatID int64, walletAddr string) {
 	}
 
 	send(bot, chatID, fmt.Sprintf("✅ Wallet activated!\n\n`%s`", walletAddr))
+}
+
+// handleRenameWalletStart asks for a wallet's new name
+func handleRenameWalletStart(bot *tgbotapi.BotAPI, chatID int64, walletAddr string) {
+	sessMu.Lock()
+	sessions[chatID] = &UserSession{
+		State:       "awaiting_wallet_rename",
+		RequestedAt: time.Now().Unix(),
+	}
+	sessMu.Unlock()
+	tempWalletAddr.Set(chatID, walletAddr)
+
+	send(bot, chatID, fmt.Sprintf("✏️ *Rename Wallet*\n\n`%s`\n\nSend the new name (max %d characters):", walletAddr, maxWalletNameLen))
+}
+
+// handleWalletRenameInput processes the new wallet name
+func handleWalletRenameInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	name := strings.TrimSpace(msg.Text)
+
+	if name == "" {
+		sendError(bot, chatID, "Name can't be empty. Please try again:")
+		return
+	}
+	if utf8.RuneCountInString(name) > maxWalletNameLen {
+		sendError(bot, chatID, fmt.Sprintf("Name too long (max %d characters). Please try again:", maxWalletNameLen))
+		return
+	}
+
+	address, ok := tempWalletAddr.Get(chatID)
+	if !ok {
+		sendError(bot, chatID, "Session expired. Please start again with /wallets")
+		sessMu.Lock()
+		delete(sessions, chatID)
+		sessMu.Unlock()
+		return
+	}
+
+	err := scanner.db.RenameUserWallet(chatID, address, name)
+	if errors.Is(err, storage.ErrWalletNameTaken) {
+		sendWarning(bot, chatID, fmt.Sprintf("You already have a wallet named *%s*. Please choose another name:", escapeMarkdown(name)))
+		return
+	}
+
+	sessMu.Lock()
+	delete(sessions, chatID)
+	sessMu.Unlock()
+	tempWalletAddr.Delete(chatID)
+
+	if errors.Is(err, storage.ErrWalletNotFound) {
+		sendError(bot, chatID, "Wallet not found. It may have been removed.")
+		return
+	}
+	if err != nil {
+		sendError(bot, chatID, fmt.Sprintf("Error renaming wallet: %v", err))
+		return
+	}
+
+	send(bot, chatID, fmt.Sprintf("✅ Wallet renamed to *%s*", escapeMarkdown(name)))
 	handleWalletsCommand(bot, chatID)
 }
 
 // handleConfirmRemove removes a wallet
 func handleConfirmRemove(bot *tgbotapi.BotAPI, chatID int64, walletAddr string) {
-	err := scanner.db.RemoveUserWallet(chatID, walletAddr)
+	activated, err := scanner.db.RemoveUserWallet(chatID, walletAddr)
 	if err != nil {
 		sendError(bot, chatID, "Error removing wallet")
 		return
 	}
 
-	send(bot, chatID, "✅ Wallet removed successfully")
+	if activated != nil {
+		send(bot, chatID, fmt.Sprintf("✅ Wallet removed successfully\n\n⭐ *%s* is now your active wallet", escapeMarkdown(activated.WalletName)))
+	} else {
+		send(bot, chatID, "✅ Wallet removed successfully")
+	}
 	handleWalletsCommand(bot, chatID)
 }
 
@@ -900,7 +1366,7 @@ func handleWinrateInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	sessions[chatID].Winrate = winrate
 	sessMu.Unlock()
 
-	send(bot, chatID, "✅ Enter minimum *PnL* (e.g. 100):")
+	send(bot, chatID, "✅ Enter minimum *PnL %* (e.g. 100 for +100%):")
 }
 
 func handlePnlInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
@@ -924,27 +1390,45 @@ func handlePnlInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 
 func searchAndRespond(bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float64, startCount int) {
 	scanner.mu.RLock()
-	var matches []*storage.WalletData
-	for _, w := range scanner.walletsCache {
-		if w.Winrate >= winrate && w.RealizedPnL >= pnl {
-			matches = append(matches, w)
-		}
-	}
-	totalScanned := len(scanner.walletsCache)
-	isScanning := scanner.isScanning
-	totalWallets := scanner.totalWallets
+	matches := scanner.index.match(winrate, pnlFilter{Min: pnl})
+	snap := takeScanSnapshot()
 	scanner.mu.RUnlock()
 
 	if len(matches) == 0 {
-		msg := sendWaitingMessage(bot, chatID, winrate, pnl, startCount, totalScanned, totalWallets, isScanning)
-		go updateProgress(bot, chatID, msg.MessageID, winrate, pnl, startCount)
+		msg := sendWaitingMessage(bot, chatID, winrate, pnl, startCount, snap)
+		go updateProgress(bot, chatID, msg.MessageID, winrate, pnl, startCount, snap.cycleStart)
 		return
 	}
 
 	sendResults(bot, chatID, matches, winrate, pnl)
 }
 
-func updateProgress(bot *tgbotapi.BotAPI, chatID int64, messageID int, winrate, pnl float64, startCount int) {
+// scanSnapshot is a consistent view of the scanner's counters, read under
+// a single lock so the scanned and total counts belong to the same cycle
+type scanSnapshot struct {
+	totalScanned int // wallets in the cache across all cycles
+	cycleScanned int // wallets analysed in the current cycle
+	totalWallets int // wallets queued in the current cycle, 0 while collecting
+	cycleStart   int64
+	isScanning   bool
+}
+
+// takeScanSnapshot reads the scanner counters. The caller must hold
+// scanner.mu.
+func takeScanSnapshot() scanSnapshot {
+	return scanSnapshot{
+		totalScanned: len(scanner.walletsCache),
+		cycleScanned: scanner.scannedCount,
+		totalWallets: scanner.totalWallets,
+		cycleStart:   scanner.lastScanStart,
+		isScanning:   scanner.isScanning,
+	}
+}
+
+// updateProgress refreshes the waiting message until matches turn up, for
+// at most 30 ticks. It gives up early once a new scan cycle starts, since
+// the counters it reports on have been reset.
+func updateProgress(bot *tgbotapi.BotAPI, chatID int64, messageID int, winrate, pnl float64, startCount int, cycleStart int64) {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
@@ -952,15 +1436,8 @@ func updateProgress(bot *tgbotapi.BotAPI, chatID int64, messageID int, winrate,
 		<-ticker.C
 
 		scanner.mu.RLock()
-		var matches []*storage.WalletData
-		for _, w := range scanner.walletsCache {
-			if w.Winrate >= winrate && w.RealizedPnL >= pnl {
-				matches = append(matches, w)
-			}
-		}
-		totalScanned := len(scanner.walletsCache)
-		isScanning := scanner.isScanning
-		totalWallets := scanner.totalWallets
+		matches := scanner.index.match(winrate, pnlFilter{Min: pnl})
+		snap := takeScanSnapshot()
 		scanner.mu.RUnlock()
 
 		if len(matches) > 0 {
@@ -968,50 +1445,36 @@ func updateProgress(bot *tgbotapi.BotAPI, chatID int64, messageID int, winrate,
 			return
 		}
 
-		newScanned := totalScanned - startCount
-		progress := 0.0
-		if totalWallets > 0 {
-			progress = float64(totalScanned) / float64(totalWallets) * 100
+		if snap.cycleStart != cycleStart {
+			editMessage(bot, chatID, messageID, fmt.Sprintf("🔄 *Scan Cycle Restarted*\n\n"+
+				"Filters: WR ≥ %.2f%%, PnL ≥ %.2f%%\n\n"+
+				"No matches yet. Search again to follow the new cycle.", winrate, pnl))
+			return
 		}
 
-		progressBar := createProgressBar(progress)
-		text := fmt.Sprintf("⏳ *Scanning in Progress*\n\n"+
-			"Filters: WR ≥ %.2f%%, PnL ≥ %.2f%%\n\n"+
-			"%s\n"+
-			"Progress: %.1f%%\n\n"+
-			"📊 Total Wallets Scanned From Tokens: %d\n"+
-			"⏱️ Status: %s",
-			winrate, pnl, progressBar, progress, newScanned,
-			map[bool]string{true: "Scanning...", false: "Waiting for next cycle"}[isScanning])
-
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-		edit.ParseMode = "Markdown"
-		bot.Send(edit)
+		editMessage(bot, chatID, messageID, waitingText(winrate, pnl, startCount, snap, i+1))
 	}
 }
 
-func sendWaitingMessage(bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float64, startCount, totalScanned, totalWallets int, isScanning bool) tgbotapi.Message {
-	newScanned := totalScanned - startCount
-	progress := 0.0
-	if totalWallets > 0 {
-		progress = float64(totalScanned) / float64(totalWallets) * 100
-	}
-
-	progressBar := createProgressBar(progress)
+func sendWaitingMessage(bot *tgbotapi.BotAPI, chatID int64, winrate, pnl float64, startCount int, snap scanSnapshot) tgbotapi.Message {
+	msg := tgbotapi.NewMessage(chatID, waitingText(winrate, pnl, startCount, snap, 0))
+	msg.ParseMode = "Markdown"
+	sentMsg, _ := bot.Send(msg)
+	return sentMsg
+}
 
-	text := fmt.Sprintf("⏳ *Scanning in Progress*\n\n"+
+// waitingText renders the no-matches-yet message; tick advances the
+// spinner while the wallet list is still being collected
+func waitingText(winrate, pnl float64, startCount int, snap scanSnapshot, tick int) string {
+	progressBar, progress := scanProgress(snap.cycleScanned, snap.totalWallets, tick)
+	return fmt.Sprintf("⏳ *Scanning in Progress*\n\n"+
 		"Filters: WR ≥ %.2f%%, PnL ≥ %.2f%%\n\n"+
 		"%s\n"+
-		"Progress: %.1f%%\n\n"+
+		"Progress: %s\n\n"+
 		"📊 Total Wallets Scanned From Tokens: %d\n"+
 		"⏱️ Status: %s",
-		winrate, pnl, progressBar, progress, newScanned,
-		map[bool]string{true: "Scanning...", false: "Waiting for next cycle"}[isScanning])
-
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
-	sentMsg, _ := bot.Send(msg)
-	return sentMsg
+		winrate, pnl, progressBar, progress, snap.totalScanned-startCount,
+		map[bool]string{true: "Scanning...", false: "Waiting for next cycle"}[snap.isScanning])
 }
 
 func sendResults(bot *tgbotapi.BotAPI, chatID int64, matches []*storage.WalletData, winrate, pnl float64) {
@@ -1024,17 +1487,38 @@ func sendResults(bot *tgbotapi.BotAPI, chatID int64, matches []*storage.WalletDa
 			text += fmt.Sprintf("\n_... and %d more_", len(matches)-15)
 			break
 		}
-		text += fmt.Sprintf("`%s`\n💹 WR: %.2f%% | 💰 PnL: %.2f%%\n\n",
-			w.Wallet, w.Winrate, w.RealizedPnL)
+		text += fmt.Sprintf("`%s`\n%s\n\n", w.Wallet, walletStatsLine(w))
 	}
 
 	send(bot, chatID, text)
 }
 
+// spinnerFrames animate the progress line while a scan's wallet total is
+// still unknown
+var spinnerFrames = []string{"◐", "◓", "◑", "◒"}
+
+// scanProgress renders the bar and percentage for scanned out of total,
+// clamped to 0-100. With no total yet (the cycle is still collecting
+// wallets) it shows a spinner rather than a misleading 0.0%.
+func scanProgress(scanned, total, tick int) (bar, percent string) {
+	if total <= 0 {
+		return spinnerFrames[tick%len(spinnerFrames)] + " Collecting wallets...", "—"
+	}
+	progress := float64(scanned) / float64(total) * 100
+	if progress < 0 {
+		progress = 0
+	} else if progress > 100 {
+		progress = 100
+	}
+	return createProgressBar(progress), fmt.Sprintf("%.1f%%", progress)
+}
+
 func createProgressBar(percent float64) string {
 	filled := int(percent / 5)
 	if filled > 20 {
 		filled = 20
+	} else if filled < 0 {
+		filled = 0
 	}
 	empty := 20 - filled
 
@@ -1089,32 +1573,34 @@ func sendWarning(bot *tgbotapi.BotAPI, chatID int64, text string) {
 	send(bot, chatID, "⚠️ "+text)
 }
 
-// publishScanProgress publishes scan progress to Redis for monitor TUI
+// publishScanProgress publishes scan progress to Redis for /monitor and
+// scan progress subscribers
 func publishScanProgress(scanned, total int, isScanning bool, foundWallets int) {
+	publishProgress(engine.ScanProgress{
+		IsScanning:   isScanning,
+		ScannedCount: scanned,
+		TotalWallets: total,
+		FoundWallets: foundWallets,
+	})
+}
+
+// publishScanFailure reports a failed scan cycle on scan:progress
+func publishScanFailure(cycleErr error, foundWallets int) {
+	publishProgress(engine.ScanProgress{FoundWallets: foundWallets, Error: cycleErr.Error()})
+}
+
+func publishProgress(progress engine.ScanProgress) {
 	if redisClient == nil {
 		return
 	}
 
 	scanner.mu.RLock()
-	scanStartTime := scanner.lastScanStart
+	progress.ScanStartTime = scanner.lastScanStart
 	scanner.mu.RUnlock()
 
-	progress := map[string]interface{}{
-		"is_scanning":     isScanning,
-		"scanned_count":   scanned,
-		"total_wallets":   total,
-		"last_update":     time.Now().Unix(),
-		"scan_start_time": scanStartTime,
-		"found_wallets":   foundWallets,
+	if err := engine.PublishScanProgress(context.Background(), redisClient, progress); err != nil {
+		log.Printf("Failed to publish scan progress: %v", err)
 	}
-
-	data, err := json.Marshal(progress)
-	if err != nil {
-		return
-	}
-
-	// Publish to Redis with 1 hour expiry
-	redisClient.Set(context.Background(), "scan:progress", string(data), 1*time.Hour)
 }
 
 func sendInfo(bot *tgbotapi.BotAPI, chatID int64, message string) {
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"solana-orchestrator/api"
+	"solana-orchestrator/storage"
+)
+
+// recordHolderStats computes a token's holder concentration from its top
+// holders and saves it
+func recordHolderStats(ctx context.Context, mint string, holders []api.Holder) (*storage.TokenHolderStats, error) {
+	supply, err := api.GetRawTokenSupply(ctx, getShyftRPCURL(), mint)
+	if err != nil {
+		return nil, err
+	}
+	pct, err := api.HolderConcentration(holders, supply, api.TopHolderCount)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &storage.TokenHolderStats{Mint: mint, TopHolderPct: pct, HolderCount: len(holders), UpdatedAt: time.Now().Unix()}
+	if err := scanner.db.SaveTokenHolderStats(stats); err != nil {
+		log.Printf("⚠️ Failed to save holder stats for %s: %v", mint, err)
+	}
+	return stats, nil
+}
+
+// fetchHolderStats fetches a token's holders and records their
+// concentration
+func fetchHolderStats(ctx context.Context, mint string) (*storage.TokenHolderStats, error) {
+	holders, err := newAPIClient(globalCfg).GetTokenHolders(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+	return recordHolderStats(ctx, mint, holders)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-orchestrator/api"
+	"solana-orchestrator/trading"
+
+	"github.com/gagliardetto/solana-go"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// tokenReportTTL is how long a token's report is reused, so /token and
+// its Buy button don't fetch everything twice
+const tokenReportTTL = 30 * time.Second
+
+// tokenReportTimeout bounds fetching a report; sources that haven't
+// answered by then are left out
+const tokenReportTimeout = 10 * time.Second
+
+// tokenReport is everything the bot looks up about a token. Any source
+// that failed is nil.
+type tokenReport struct {
+	Mint      string
+	Info      *trading.TokenInfo // DexScreener market data
+	Meta      *api.ShyftMetadata // on-chain name, symbol and supply
+	Safety    *trading.SafetyReport
+	FetchedAt time.Time
+}
+
+// tokenReports caches recent reports by mint
+var tokenReports = struct {
+	sync.Mutex
+	m map[string]*tokenReport
+}{m: make(map[string]*tokenReport)}
+
+// loadTokenReport returns a token's report, fetching it unless a recent
+// one is cached
+func loadTokenReport(mint string) *tokenReport {
+	tokenReports.Lock()
+	r, ok := tokenReports.m[mint]
+	tokenReports.Unlock()
+	if ok && time.Since(r.FetchedAt) < tokenReportTTL {
+		return r
+	}
+
+	r = fetchTokenReport(mint)
+
+	tokenReports.Lock()
+	defer tokenReports.Unlock()
+	for k, cached := range tokenReports.m {
+		if time.Since(cached.FetchedAt) >= tokenReportTTL {
+			delete(tokenReports.m, k)
+		}
+	}
+	tokenReports.m[mint] = r
+	return r
+}
+
+// fetchTokenReport looks a token up on DexScreener and Shyft and runs the
+// safety checks, all at once
+func fetchTokenReport(mint string) *tokenReport {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenReportTimeout)
+	defer cancel()
+
+	infoCh := make(chan *trading.TokenInfo, 1)
+	metaCh := make(chan *api.ShyftMetadata, 1)
+	safetyCh := make(chan *trading.SafetyReport, 1)
+	go func() {
+		info, err := trading.GetTokenInfo(ctx, mint)
+		if err != nil {
+			log.Printf("DexScreener failed for %s: %v", mint, err)
+		}
+		infoCh <- info
+	}()
+	go func() {
+		meta, err := api.GetShyftMetadata(getShyftRPCURL(), mint)
+		if err != nil {
+			log.Printf("⚠️ Shyft metadata failed for %s: %v", mint, err)
+		}
+		metaCh <- meta
+	}()
+	go func() { safetyCh <- fetchTokenSafety(mint) }()
+
+	return &tokenReport{
+		Mint:      mint,
+		Info:      await(ctx, infoCh),
+		Meta:      await(ctx, metaCh),
+		Safety:    await(ctx, safetyCh),
+		FetchedAt: time.Now(),
+	}
+}
+
+// await receives from ch, or returns the zero value once ctx is done
+func await[T any](ctx context.Context, ch <-chan T) (v T) {
+	select {
+	case v = <-ch:
+	case <-ctx.Done():
+	}
+	return v
+}
+
+// TokenInfo merges the report's sources into the token info the buy flow
+// uses, or nil when no source knows the token
+func (r *tokenReport) TokenInfo() *trading.TokenInfo {
+	info := trading.TokenInfo{Address: r.Mint, Name: "Unknown", Symbol: "Unknown", PriceUSD: "N/A"}
+	if r.Info != nil {
+		info = *r.Info
+	}
+	if m := r.Meta; m != nil {
+		// Shyft reports tokens without on-chain metadata as Unknown,
+		// which shouldn't hide DexScreener's name
+		if m.Name != "Unknown" {
+			info.Name = m.Name
+		}
+		if m.Symbol != "Unknown" {
+			info.Symbol = m.Symbol
+		}
+		info.TotalSupply = m.TotalSupply
+	}
+	if info.Name == "Unknown" && info.Symbol == "Unknown" {
+		return nil
+	}
+	return &info
+}
+
+// handleTokenCommand shows a card with everything the bot knows about a
+// token and a button to buy it
+func handleTokenCommand(bot *tgbotapi.BotAPI, chatID int64, args string) {
+	mint := strings.TrimSpace(args)
+	if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+		sendWarning(bot, chatID, "Usage: `/token <mint address>`")
+		return
+	}
+
+	loadingMsg := tgbotapi.NewMessage(chatID, "⏳ Looking up token...")
+	sentMsg, _ := bot.Send(loadingMsg)
+
+	report := loadTokenReport(mint)
+	info := report.TokenInfo()
+	if info == nil {
+		editMessage(bot, chatID, sentMsg.MessageID, "❌ Token not found on DexScreener or Solana Chain. Please check the address.")
+		return
+	}
+	bot.Request(tgbotapi.NewDeleteMessage(chatID, sentMsg.MessageID))
+
+	message := fmt.Sprintf("🪙 *%s (%s)*\n`%s`\n\n", escapeMarkdown(info.Name), escapeMarkdown(info.Symbol), mint)
+	if report.Info != nil {
+		message += fmt.Sprintf("💰 *Price:* $%s", info.PriceUSD)
+		if info.PriceSOL != "" {
+			message += fmt.Sprintf(" (%s SOL)", info.PriceSOL)
+		}
+		message += "\n"
+		message += fmt.Sprintf("🏦 *Market Cap:* $%.0f\n", info.MarketCap)
+		message += fmt.Sprintf("💧 *Liquidity:* $%.0f\n", info.Liquidity)
+		message += fmt.Sprintf("📈 *Volume 24h:* $%.0f\n", info.Volume24h)
+		message += fmt.Sprintf("📊 *Change:* 1h %.2f%% | 24h %.2f%%\n", info.Change1h, info.Change24h)
+		message += fmt.Sprintf("🔥 *Buys/Sells (1h):* %d / %d\n", info.Buys1h, info.Sells1h)
+	} else {
+		message += "💰 *Market data:* unavailable\n"
+	}
+	if info.TotalSupply != "" {
+		message += fmt.Sprintf("📦 *Supply:* %s\n", info.TotalSupply)
+	}
+	message += "\n"
+
+	safety := report.Safety
+	if safety != nil && safety.TopHolderPct == nil {
+		// Fall back to the concentration the last scan recorded
+		if stats, err := scanner.db.GetTokenHolderStats(mint); err == nil && stats != nil {
+			withStats := *safety
+			withStats.TopHolderPct = &stats.TopHolderPct
+			safety = &withStats
+		}
+	}
+	if safety != nil {
+		message += safetyText(safety)
+	}
+	message += fmt.Sprintf("_As of %s_", report.FetchedAt.UTC().Format("15:04:05 UTC"))
+
+	sendWithKeyboard(bot, chatID, message, tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🛒 Buy", "token_buy:"+mint),
+			tgbotapi.NewInlineKeyboardButtonURL("📊 Chart", "https://dexscreener.com/solana/"+mint),
+		),
+	))
+}
+
+// handleTokenBuy starts the buy flow for the token on a /token card
+func handleTokenBuy(bot *tgbotapi.BotAPI, chatID int64, mint string) {
+	if !startBuySession(bot, chatID) {
+		return
+	}
+	showBuyPreview(bot, chatID, mint)
+}
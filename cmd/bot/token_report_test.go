@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"solana-orchestrator/api"
+	"solana-orchestrator/trading"
+)
+
+// TestTokenReportInfo tests merging DexScreener and Shyft into the buy
+// flow's token info when either source is missing
+func TestTokenReportInfo(t *testing.T) {
+	dex := &trading.TokenInfo{Name: "Bonk", Symbol: "BONK", PriceUSD: "0.00002", Liquidity: 1e6}
+	unnamed := &api.ShyftMetadata{Name: "Unknown", Symbol: "Unknown", TotalSupply: "88000000000000"}
+
+	tests := []struct {
+		name       string
+		report     tokenReport
+		wantNil    bool
+		wantName   string
+		wantPrice  string
+		wantSupply string
+	}{
+		{"nothing", tokenReport{Mint: "mint"}, true, "", "", ""},
+		{"dexscreener only", tokenReport{Mint: "mint", Info: dex}, false, "Bonk", "0.00002", ""},
+		{"shyft only", tokenReport{Mint: "mint", Meta: &api.ShyftMetadata{Name: "Bonk2", Symbol: "BONK2", TotalSupply: "1000"}}, false, "Bonk2", "N/A", "1000"},
+		{"shyft without metadata", tokenReport{Mint: "mint", Info: dex, Meta: unnamed}, false, "Bonk", "0.00002", "88000000000000"},
+		{"neither knows the name", tokenReport{Mint: "mint", Meta: unnamed}, true, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.report.TokenInfo()
+			if (info == nil) != tt.wantNil {
+				t.Fatalf("Expected nil info %v, got %+v", tt.wantNil, info)
+			}
+			if info == nil {
+				return
+			}
+			if info.Name != tt.wantName || info.PriceUSD != tt.wantPrice || info.TotalSupply != tt.wantSupply {
+				t.Errorf("Unexpected info %+v", info)
+			}
+		})
+	}
+
+	// The buy flow gets its own copy
+	r := tokenReport{Mint: "mint", Info: dex}
+	r.TokenInfo().Name = "changed"
+	if dex.Name != "Bonk" {
+		t.Error("Expected TokenInfo to copy the cached DexScreener info")
+	}
+}
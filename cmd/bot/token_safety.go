@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"solana-orchestrator/api"
+	"solana-orchestrator/trading"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// safetyCheckTimeout bounds the buy preview's safety checks; any still
+// running are left out of the report
+const safetyCheckTimeout = 5 * time.Second
+
+// fetchTokenSafety runs the buy preview's safety checks on a token
+// concurrently
+func fetchTokenSafety(tokenAddress string) *trading.SafetyReport {
+	ctx, cancel := context.WithTimeout(context.Background(), safetyCheckTimeout)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		report trading.SafetyReport
+	)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		authorities, err := trading.GetMintAuthorities(ctx, rpc.New(getShyftRPCURL()), tokenAddress)
+		if err != nil {
+			log.Printf("⚠️ Safety check: mint authorities for %s: %v", tokenAddress, err)
+			return
+		}
+		report.Authorities = authorities
+	}()
+	go func() {
+		defer wg.Done()
+		stats, err := fetchHolderStats(ctx, tokenAddress)
+		if err != nil {
+			log.Printf("⚠️ Safety check: holders for %s: %v", tokenAddress, err)
+			return
+		}
+		report.TopHolderPct = &stats.TopHolderPct
+	}()
+	go func() {
+		defer wg.Done()
+		roundTrip, err := trading.CheckRoundTrip(ctx, tokenAddress)
+		if err != nil {
+			log.Printf("⚠️ Safety check: round trip for %s: %v", tokenAddress, err)
+			return
+		}
+		report.RoundTrip = roundTrip
+	}()
+	wg.Wait()
+
+	return &report
+}
+
+// safetyText is the buy preview's safety section
+func safetyText(r *trading.SafetyReport) string {
+	if r.Checked() == 0 {
+		return "🛡️ *Safety:* checks unavailable\n\n"
+	}
+
+	icon := map[string]string{trading.RiskLow: "🟢", trading.RiskMedium: "🟡", trading.RiskHigh: "🔴"}[r.Risk()]
+	message := fmt.Sprintf("🛡️ *Safety:* %s %s risk (%d/100)\n", icon, r.Risk(), r.Score())
+
+	if r.Authorities != nil {
+		message += fmt.Sprintf("  • Mint authority: %s\n", authorityText(r.Authorities.Mint != nil))
+		message += fmt.Sprintf("  • Freeze authority: %s\n", authorityText(r.Authorities.Freeze != nil))
+	} else {
+		message += "  • Authorities: unknown\n"
+	}
+	if r.TopHolderPct != nil {
+		message += fmt.Sprintf("  • Top %d holders: %.1f%% of supply\n", api.TopHolderCount, *r.TopHolderPct)
+	} else {
+		message += "  • Holder concentration: unknown\n"
+	}
+	switch rt := r.RoundTrip; {
+	case rt == nil:
+		message += "  • Sell check: unknown\n"
+	case !rt.Sellable:
+		message += "  • Sell check: 🚨 *no sell route – possible honeypot*\n"
+	default:
+		message += fmt.Sprintf("  • Sell check: sellable, %.1f%% lost on a round trip\n", rt.LossPct)
+	}
+	return message + "\n"
+}
+
+// authorityText describes whether a mint or freeze authority is still set
+func authorityText(active bool) string {
+	if active {
+		return "⚠️ active"
+	}
+	return "✅ revoked"
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"solana-orchestrator/trading"
+)
+
+// tradeErrorMessage turns a trade failure into a message users can act on.
+// Unclassified errors keep their details after the action that failed.
+func tradeErrorMessage(action string, err error) string {
+	log.Printf("Trade failed (%s): %v", action, err)
+
+	switch err = trading.ClassifySubmitError(err); {
+	case errors.Is(err, trading.ErrNoRoute):
+		return "❌ No trading route available for this token.\n\nIt may not be listed on a DEX yet, or its pool is too thin to route through."
+	case errors.Is(err, trading.ErrInsufficientLiquidity):
+		return "❌ Not enough liquidity to trade this token right now.\n\nTry a smaller amount or another token."
+	case errors.Is(err, trading.ErrSlippageExceeded):
+		return "❌ Price moved more than your slippage allows.\n\nTry again or raise slippage in ⚙️ Settings."
+	case errors.Is(err, trading.ErrRPCUnavailable):
+		return "❌ The Solana network or trading API is unreachable right now.\n\nPlease try again in a minute."
+	case errors.Is(err, trading.ErrQuoteFailed):
+		return "❌ Couldn't get a price quote for this trade.\n\nPlease try again."
+	case errors.Is(err, trading.ErrTransactionExpired):
+		return "⌛ Transaction expired before landing — retry."
+	}
+	return fmt.Sprintf("❌ %s: %v", action, err)
+}
+
+// tradeErrorReason is a one-line form of tradeErrorMessage for batch
+// reports
+func tradeErrorReason(err error) string {
+	switch err = trading.ClassifySubmitError(err); {
+	case errors.Is(err, trading.ErrNoRoute):
+		return "no trading route"
+	case errors.Is(err, trading.ErrInsufficientLiquidity):
+		return "not enough liquidity"
+	case errors.Is(err, trading.ErrSlippageExceeded):
+		return "slippage exceeded"
+	case errors.Is(err, trading.ErrRPCUnavailable):
+		return "network unavailable"
+	case errors.Is(err, trading.ErrQuoteFailed):
+		return "quote failed"
+	case errors.Is(err, trading.ErrTransactionExpired):
+		return "expired before landing"
+	}
+	return err.Error()
+}
+
+// quoteErrorMessage explains a failed quote, adding what DexScreener knows
+// about the token's liquidity when Jupiter has no route for it
+func quoteErrorMessage(err error, info *trading.TokenInfo) string {
+	msg := tradeErrorMessage("Failed to get quote", err)
+	if !errors.Is(err, trading.ErrNoRoute) {
+		return msg
+	}
+	if info == nil || info.PairAddress == "" {
+		return msg + "\n\n💧 No liquidity pool found for this token."
+	}
+	return msg + fmt.Sprintf("\n\n💧 *Liquidity:* %s on %s\n📊 *24h Volume:* %s",
+		formatUSD(info.Liquidity), escapeMarkdown(info.DexID), formatUSD(info.Volume24h))
+}
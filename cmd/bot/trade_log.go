@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"solana-orchestrator/engine"
+	"solana-orchestrator/trading"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultConfirmTimeout bounds confirmation polling when the config
+// doesn't set trading_settings.confirm_timeout_sec
+const defaultConfirmTimeout = 90 * time.Second
+
+// tradeLogger records the lifecycle of every buy and sell
+var tradeLogger *engine.TradeLogger
+
+// trackConfirmation polls a submitted transaction until it lands, fails,
+// or its blockhash expires, records the confirm stage, and tells the user
+// when it didn't land
+func trackConfirmation(bot *tgbotapi.BotAPI, chatID int64, trade *engine.TradeLog, rpcURL string, sig solana.Signature, lastValidBlockHeight uint64) {
+	timeout := defaultConfirmTimeout
+	if globalCfg != nil && globalCfg.TradingSettings.ConfirmTimeoutSec > 0 {
+		timeout = time.Duration(globalCfg.TradingSettings.ConfirmTimeoutSec) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	status, err := trading.WaitForConfirmationUntilBlockHeight(ctx, rpc.New(rpcURL), sig, lastValidBlockHeight)
+	trade.Stage(engine.StageConfirm, err, string(status))
+	if err == nil {
+		return
+	}
+
+	log.Printf("Transaction %s for %d did not land: %v", engine.AbbreviateSignature(sig.String()), chatID, err)
+	switch {
+	case errors.Is(err, trading.ErrTransactionExpired):
+		send(bot, chatID, fmt.Sprintf("⌛ *Transaction expired before landing — retry.*\n\n🔗 `%s`\n\nNothing was spent.", sig))
+	case errors.Is(err, context.DeadlineExceeded):
+		send(bot, chatID, fmt.Sprintf("⏳ Transaction `%s` wasn't confirmed within %s.\n\nCheck /portfolio before retrying.", sig, timeout))
+	default:
+		send(bot, chatID, fmt.Sprintf("❌ Transaction `%s` failed on-chain.\n\nCheck /portfolio before retrying.", sig))
+	}
+}
+
+// handleTradeLogCommand shows the recorded lifecycle of the trade that
+// produced a signature: /tradelog <signature>
+func handleTradeLogCommand(bot *tgbotapi.BotAPI, chatID int64, args string) {
+	if !isAdmin(chatID) {
+		return // Silent ignore for non-admins
+	}
+
+	signature := strings.TrimSpace(args)
+	if signature == "" {
+		sendWarning(bot, chatID, "Usage: `/tradelog <signature>`")
+		return
+	}
+
+	entries, err := tradeLogger.Lookup(signature)
+	if err != nil {
+		sendError(bot, chatID, "Failed to read trade logs")
+		log.Printf("Error reading trade logs: %v", err)
+		return
+	}
+	if len(entries) == 0 {
+		send(bot, chatID, "🔎 No trade logs found for that signature.")
+		return
+	}
+
+	message := "🧾 *Trade Log*\n"
+	tradeID := ""
+	var start, prev int64
+	for _, e := range entries {
+		if e.TradeID != tradeID {
+			tradeID = e.TradeID
+			start, prev = e.CreatedAt, e.CreatedAt
+			message += fmt.Sprintf("\n*Trade* `%s` · %s · user `%d`\n", e.TradeID, e.Kind, e.UserID)
+			message += fmt.Sprintf("🕒 %s\n", time.UnixMilli(e.CreatedAt).UTC().Format("2006-01-02 15:04:05.000 UTC"))
+		}
+
+		icon := "✅"
+		if e.Status != "ok" {
+			icon = "❌"
+		}
+		message += fmt.Sprintf("%s *%s* +%dms (Δ%dms)\n", icon, e.Stage, e.CreatedAt-start, e.CreatedAt-prev)
+		if e.Detail != "" {
+			message += fmt.Sprintf("   `%s`\n", strings.ReplaceAll(e.Detail, "`", "'"))
+		}
+		prev = e.CreatedAt
+	}
+	send(bot, chatID, message)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"solana-orchestrator/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxMinWalletAgeDays bounds the Dev Finder minimum wallet age filter
+const maxMinWalletAgeDays = 3650
+
+// walletAgeLabel renders how old a wallet is, e.g. "2y 14d" or "45d"
+func walletAgeLabel(w *storage.WalletData, now time.Time) string {
+	if w.FirstSeen <= 0 {
+		return "unknown"
+	}
+	days := int(w.Age(now).Hours() / 24)
+	switch {
+	case days < 1:
+		return "<1d"
+	case days < 365:
+		return fmt.Sprintf("%dd", days)
+	default:
+		return fmt.Sprintf("%dy %dd", days/365, days%365)
+	}
+}
+
+// walletStatsLine renders a wallet's win rate, PnL and age for result lists
+func walletStatsLine(w *storage.WalletData) string {
+	return fmt.Sprintf("💹 WR: %.2f%% | 💰 PnL: %+.2f%% (%s) | 🕰 Age: %s",
+		w.Winrate, w.RealizedPnLPct, formatUSD(w.RealizedPnLUSD), walletAgeLabel(w, time.Now()))
+}
+
+// ageFilterText appends the minimum age to a filter summary when one is set
+func ageFilterText(minAgeDays int) string {
+	if minAgeDays <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(", Age ≥ %dd", minAgeDays)
+}
+
+// handleMinAgeInputV2 reads the optional minimum wallet age and starts the search
+func handleMinAgeInputV2(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	minAgeDays := 0
+	if text := strings.TrimSpace(msg.Text); !strings.EqualFold(text, "skip") {
+		days, err := strconv.Atoi(text)
+		if err != nil || days < 0 || days > maxMinWalletAgeDays {
+			sendError(bot, chatID, fmt.Sprintf("Invalid input. Enter 0-%d days, or 0 to skip", maxMinWalletAgeDays))
+			return
+		}
+		minAgeDays = days
+	}
+
+	sessMu.Lock()
+	session := sessions[chatID]
+	if session == nil {
+		sessMu.Unlock()
+		sendError(bot, chatID, "Session expired.")
+		return
+	}
+	winrate := session.Winrate
+	pnl := session.PnL
+	scanType := session.ScanType
+	delete(sessions, chatID)
+	sessMu.Unlock()
+
+	startRealTimeSearch(bot, chatID, winrate, pnl, minAgeDays, 0, scanType)
+}
@@ -125,6 +125,7 @@ func handleWalletPassword(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		encWallet.Nonce,
 		encWallet.PasswordHash,
 		crypto.EncodeToBase64(encMnemonic.EncryptedKey),
+		int(encWallet.KDFVersion),
 	)
 	if err != nil {
 		send(bot, chatID, fmt.Sprintf("❌ Database error: %v", err))
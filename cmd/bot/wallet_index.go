@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+
+	"solana-orchestrator/storage"
+)
+
+// walletIndex keeps the cached wallets sorted, best first, by each Dev
+// Finder filter. A search binary-searches every sorted list for its
+// thresholds and only visits the shortest candidate range, so matching
+// costs O(log n + k) for the k wallets past the most selective threshold
+// instead of O(n) over the whole cache. Adding a wallet costs O(n) for
+// the shifted slice tail, a memmove, paid once per scanned wallet rather
+// than once per search tick per user.
+type walletIndex struct {
+	byWinrate []*storage.WalletData
+	byPnLPct  []*storage.WalletData
+	byPnLUSD  []*storage.WalletData
+}
+
+// walletKey is the value a sorted list is ordered by
+type walletKey func(w *storage.WalletData) float64
+
+func winrateKey(w *storage.WalletData) float64 { return w.Winrate }
+func pnlPctKey(w *storage.WalletData) float64  { return w.RealizedPnLPct }
+func pnlUSDKey(w *storage.WalletData) float64  { return w.RealizedPnLUSD }
+
+// put adds w to the index, replacing old, the wallet's previous entry,
+// when it was already indexed
+func (x *walletIndex) put(old, w *storage.WalletData) {
+	if old != nil {
+		x.byWinrate = removeSorted(x.byWinrate, old, winrateKey)
+		x.byPnLPct = removeSorted(x.byPnLPct, old, pnlPctKey)
+		x.byPnLUSD = removeSorted(x.byPnLUSD, old, pnlUSDKey)
+	}
+	x.byWinrate = insertSorted(x.byWinrate, w, winrateKey)
+	x.byPnLPct = insertSorted(x.byPnLPct, w, pnlPctKey)
+	x.byPnLUSD = insertSorted(x.byPnLUSD, w, pnlUSDKey)
+}
+
+// match returns the wallets meeting both filters, best first by
+// whichever filter was more selective
+func (x *walletIndex) match(winrate float64, pnl pnlFilter) []*storage.WalletData {
+	candidates := atLeast(x.byWinrate, winrate, winrateKey)
+	byPnL := atLeast(x.byPnLPct, pnl.Min, pnlPctKey)
+	if pnl.USD {
+		byPnL = atLeast(x.byPnLUSD, pnl.Min, pnlUSDKey)
+	}
+	if len(byPnL) < len(candidates) {
+		candidates = byPnL
+	}
+
+	var matches []*storage.WalletData
+	for _, w := range candidates {
+		if w.Winrate >= winrate && pnl.Matches(w) {
+			matches = append(matches, w)
+		}
+	}
+	return matches
+}
+
+// atLeast returns the prefix of a descending list whose keys are >= min
+func atLeast(list []*storage.WalletData, min float64, key walletKey) []*storage.WalletData {
+	i := sort.Search(len(list), func(i int) bool { return key(list[i]) < min })
+	return list[:i]
+}
+
+// insertSorted inserts w into a descending list after any equal keys
+func insertSorted(list []*storage.WalletData, w *storage.WalletData, key walletKey) []*storage.WalletData {
+	k := key(w)
+	i := sort.Search(len(list), func(i int) bool { return key(list[i]) < k })
+	list = append(list, nil)
+	copy(list[i+1:], list[i:])
+	list[i] = w
+	return list
+}
+
+// removeSorted removes w from a descending list, if present
+func removeSorted(list []*storage.WalletData, w *storage.WalletData, key walletKey) []*storage.WalletData {
+	k := key(w)
+	for i := sort.Search(len(list), func(i int) bool { return key(list[i]) <= k }); i < len(list) && key(list[i]) == k; i++ {
+		if list[i] == w {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"log"
+
+	"solana-orchestrator/crypto"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Wallet unlock failures the buy and sell flows report separately
+var (
+	errWalletLoad       = errors.New("failed to load wallet")
+	errInvalidWalletKey = errors.New("invalid private key in wallet")
+)
+
+// unlockWallet decrypts the user's trading wallet. Wallets sealed with
+// older KDF parameters are re-encrypted with the current ones while the
+// password is at hand; a failed upgrade is logged and retried next unlock.
+func unlockWallet(chatID int64, password string) (solana.PrivateKey, error) {
+	secrets, err := scanner.db.GetEncryptedWalletForCrypto(chatID)
+	if err != nil || secrets == nil {
+		log.Printf("Error loading wallet for %d: %v", chatID, err)
+		return nil, errWalletLoad
+	}
+
+	encWallet := &crypto.EncryptedWallet{
+		EncryptedKey: secrets.EncryptedKey,
+		Salt:         secrets.Salt,
+		Nonce:        secrets.Nonce,
+		PasswordHash: secrets.PasswordHash,
+		KDFVersion:   byte(secrets.KDFVersion),
+	}
+
+	privateKeyStr, err := crypto.DecryptPrivateKey(encWallet, password)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.ZeroString(&privateKeyStr)
+
+	privateKey, err := solana.PrivateKeyFromBase58(privateKeyStr)
+	if err != nil {
+		return nil, errInvalidWalletKey
+	}
+
+	if encWallet.NeedsUpgrade() {
+		upgradeWalletKDF(chatID, privateKeyStr, password, encWallet.KDFVersion)
+	}
+	return privateKey, nil
+}
+
+// upgradeWalletKDF re-encrypts a wallet with the current KDF version
+func upgradeWalletKDF(chatID int64, privateKey, password string, from byte) {
+	upgraded, err := crypto.EncryptPrivateKey(privateKey, password)
+	if err != nil {
+		log.Printf("⚠️ KDF upgrade for %d failed: %v", chatID, err)
+		return
+	}
+	err = scanner.db.UpdateWalletEncryption(chatID, upgraded.EncryptedKey, upgraded.Salt, upgraded.Nonce,
+		upgraded.PasswordHash, int(upgraded.KDFVersion))
+	if err != nil {
+		log.Printf("⚠️ KDF upgrade for %d failed: %v", chatID, err)
+		return
+	}
+	log.Printf("🔐 Upgraded wallet encryption for %d from KDF v%d to v%d", chatID, from, upgraded.KDFVersion)
+}
+
+// unlockErrorMessage turns an unlockWallet error into a user message
+func unlockErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, errWalletLoad):
+		return "❌ Failed to load wallet"
+	case errors.Is(err, errInvalidWalletKey):
+		return "❌ Invalid private key in wallet"
+	default:
+		return "❌ Incorrect password!"
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 
+	"solana-orchestrator/config"
+
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
@@ -15,8 +18,12 @@ var MetadataProgramID = solana.MustPublicKeyFromBase58("metaqbxxUerdq28cj1RbAWkY
 
 func main() {
 	// Shyft RPC URL
-	rpcURL := "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR"
-	fmt.Printf("🔌 Connecting to Shyft RPC: %s\n", rpcURL)
+	cfg := &config.Config{ShyftAPIKey: os.Getenv(config.EnvPrefix + "SHYFT_API_KEY")}
+	rpcURL := cfg.ShyftRPCURL()
+	if rpcURL == "" {
+		log.Fatalf("❌ %sSHYFT_API_KEY environment variable required", config.EnvPrefix)
+	}
+	fmt.Printf("🔌 Connecting to Shyft RPC: %s\n", config.ShyftRPCEndpoint)
 
 	client := rpc.New(rpcURL)
 
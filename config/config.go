@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"time"
 )
 
 type Config struct {
@@ -19,11 +20,22 @@ type Config struct {
 	Programs            ProgramsConfig     `json:"programs"`
 	Sniper              SniperConfig       `json:"sniper"`
 	RateLimits          RateLimits         `json:"rate_limits"`
+	Plans               []PlanConfig       `json:"plans"`
+	Payments            PaymentsConfig     `json:"payments"`
+	CopyTrading         CopyTradingConfig  `json:"copy_trading"`
+	Wallets             WalletsConfig      `json:"wallets"`
+	Analyzer            AnalyzerConfig     `json:"analyzer"`
+	ScanSettings        ScanSettings       `json:"scan_settings"`
+	Sessions            SessionsConfig     `json:"sessions"`
+	Webhook             WebhookConfig      `json:"webhook"`
+	ResultsAPI          ResultsAPIConfig   `json:"results_api"`
+	Janitor             JanitorConfig      `json:"janitor"`
+	Ranking             RankingConfig      `json:"ranking"`
 }
 
 type AnalysisFilters struct {
 	MinWinrate     float64 `json:"min_winrate"`
-	MinRealizedPnL float64 `json:"min_realized_pnl"`
+	MinRealizedPnL float64 `json:"min_realized_pnl"` // percent, not USD
 }
 
 type APISettings struct {
@@ -31,6 +43,45 @@ type APISettings struct {
 	TokenLimit   int    `json:"token_limit"`
 	TokenSource  string `json:"token_source"` // "birdeye" or "moralis"
 	FetchTraders bool   `json:"fetch_traders"`
+	// MinTokenLiquidityUSD skips tokens reporting less liquidity before
+	// their holders are fetched; 0 disables the pre-filter
+	MinTokenLiquidityUSD float64 `json:"min_token_liquidity_usd"`
+	// Base URLs replace the public Moralis and Birdeye endpoints, e.g.
+	// with a caching gateway; empty uses the public ones
+	MoralisBaseURL string `json:"moralis_base_url"`
+	BirdeyeBaseURL string `json:"birdeye_base_url"`
+	// TokenCacheTTLSec is how long fetched token lists are reused across
+	// scan cycles and instances; negative disables the cache
+	TokenCacheTTLSec int `json:"token_cache_ttl_sec"`
+	// The Birdeye token list asks for tokens with liquidity in
+	// [BirdeyeMinLiquidity, BirdeyeMaxLiquidity] USD, 0 leaving a bound
+	// out, sorted descending by BirdeyeSortBy. Tokens under
+	// BirdeyeMinVolumeUSD of 24h volume are dropped from the response.
+	BirdeyeMinLiquidity float64 `json:"birdeye_min_liquidity"`
+	BirdeyeMaxLiquidity float64 `json:"birdeye_max_liquidity"`
+	BirdeyeMinVolumeUSD float64 `json:"birdeye_min_volume_usd"`
+	BirdeyeSortBy       string  `json:"birdeye_sort_by"`
+}
+
+// DefaultTokenCacheTTLSec is the token list cache lifetime when unset
+const DefaultTokenCacheTTLSec = 300
+
+// Birdeye token list liquidity band used when neither bound is set
+const (
+	DefaultBirdeyeMinLiquidity = 100000
+	DefaultBirdeyeMaxLiquidity = 500000
+)
+
+// BirdeyeSortFields are the token list fields birdeye_sort_by accepts
+var BirdeyeSortFields = []string{"liquidity", "v24hUSD", "mc", "v24hChangePercent"}
+
+// TokenCacheTTL is how long a fetched token list is reused, 0 if caching
+// is disabled
+func (a APISettings) TokenCacheTTL() time.Duration {
+	if a.TokenCacheTTLSec < 0 {
+		return 0
+	}
+	return time.Duration(a.TokenCacheTTLSec) * time.Second
 }
 
 type TradingSettings struct {
@@ -39,17 +90,26 @@ type TradingSettings struct {
 	JitoPrivateKey     string `json:"jito_private_key"`
 	DefaultSlippageBps int    `json:"default_slippage_bps"`
 	MaxSlippageBps     int    `json:"max_slippage_bps"`
+	// ConfirmTimeoutSec caps how long a submitted swap is polled; it
+	// usually ends sooner, once the swap's blockhash expires
+	ConfirmTimeoutSec int `json:"confirm_timeout_sec"`
 }
 
 type WebSocketSettings struct {
-	ShyftWSURL       string `json:"shyft_ws_url"`
-	ReconnectDelayMs int    `json:"reconnect_delay_ms"`
-	PingIntervalMs   int    `json:"ping_interval_ms"`
+	ShyftWSURL       string   `json:"shyft_ws_url"`
+	ReconnectDelayMs int      `json:"reconnect_delay_ms"`
+	PingIntervalMs   int      `json:"ping_interval_ms"`
+	ShardCount       int      `json:"shard_count"`    // fan-out connections sharing subscriptions
+	BackupWSURLs     []string `json:"backup_ws_urls"` // tried in order when a shard stays down
+	FailoverAfterMs  int      `json:"failover_after_ms"`
 }
 
 type RateLimits struct {
-	ShyftRPS       int `json:"shyft_rps"`
-	ShyftAPIPerSec int `json:"shyft_api_per_sec"`
+	ShyftRPS           int     `json:"shyft_rps"`
+	ShyftAPIPerSec     int     `json:"shyft_api_per_sec"`
+	UserUpdatesPerSec  float64 `json:"user_updates_per_sec"`
+	UserBurst          int     `json:"user_burst"`
+	UserIdleTimeoutSec int     `json:"user_idle_timeout_sec"`
 }
 
 type FanOutEngineConfig struct {
@@ -59,6 +119,18 @@ type FanOutEngineConfig struct {
 	TelegramBatchSize     int `json:"telegram_batch_size"`
 	ReconnectDelaySeconds int `json:"reconnect_delay_seconds"`
 	MaxReconnectAttempts  int `json:"max_reconnect_attempts"`
+	DeadLetterPerMinute   int `json:"dead_letter_per_minute"`
+	ReconcileIntervalSec  int `json:"reconcile_interval_sec"` // negative disables
+	// Log queue policy: "drop_newest" or "prefilter", which sheds messages
+	// from unmonitored signers once depth passes HighWaterRatio of the buffer
+	DropPolicy     string  `json:"drop_policy"`
+	HighWaterRatio float64 `json:"high_water_ratio"`
+	// Copy-trade execution limits; excess waits up to the queue timeout
+	MaxConcurrentExecutions int `json:"max_concurrent_executions"`
+	MaxExecutionsPerUser    int `json:"max_executions_per_user"`
+	ExecutionQueueTimeoutMs int `json:"execution_queue_timeout_ms"`
+	// RPCURL is where detected swaps are fetched; empty uses the Shyft RPC
+	RPCURL string `json:"rpc_url"`
 }
 
 type RedisConfig struct {
@@ -70,9 +142,10 @@ type RedisConfig struct {
 }
 
 type ProgramsConfig struct {
-	JupiterLimitOrder string `json:"jupiter_limit_order"`
-	RaydiumAMMV4      string `json:"raydium_amm_v4"`
-	RaydiumCLMM       string `json:"raydium_clmm"`
+	JupiterLimitOrder string                `json:"jupiter_limit_order"`
+	RaydiumAMMV4      string                `json:"raydium_amm_v4"`
+	RaydiumCLMM       string                `json:"raydium_clmm"`
+	Subscriptions     []ProgramSubscription `json:"subscriptions"`
 }
 
 type SniperConfig struct {
@@ -83,6 +156,175 @@ type SniperConfig struct {
 	BlacklistTokens  []string `json:"blacklist_tokens"`
 }
 
+type PaymentsConfig struct {
+	TreasuryAddress string `json:"treasury_address"`
+	RPCURL          string `json:"rpc_url"`
+	MaxTxAgeHours   int    `json:"max_tx_age_hours"`
+	// LowCreditThreshold is the balance at which credit users are told to
+	// top up; negative disables the notice
+	LowCreditThreshold int `json:"low_credit_threshold"`
+}
+
+// CopyTradingConfig controls automatic pausing of copy targets whose
+// copied trades lose money, and when idle targets are flagged
+type CopyTradingConfig struct {
+	AutoDisable        bool    `json:"auto_disable"`
+	MaxLossSOL         float64 `json:"max_loss_sol"` // net loss over the window that pauses a target
+	WindowHours        int     `json:"window_hours"`
+	MinTrades          int     `json:"min_trades"`           // closed trades needed before a target can be paused
+	InactiveAfterHours int     `json:"inactive_after_hours"` // targets without a swap this long are flagged inactive
+}
+
+// DefaultInactiveAfterHours flags copy targets idle for a week
+const DefaultInactiveAfterHours = 7 * 24
+
+// WebhookConfig pushes wallets found by the scanner to an external URL as
+// signed JSON. An empty URL disables it; the filters only narrow what
+// analysis_filters already let through.
+type WebhookConfig struct {
+	URL            string  `json:"url"`
+	Secret         string  `json:"secret"` // HMAC-SHA256 key for the signature header
+	MinWinrate     float64 `json:"min_winrate"`
+	MinRealizedPnL float64 `json:"min_realized_pnl"` // percent, not USD
+	MaxRetries     int     `json:"max_retries"`
+}
+
+// Matches reports whether a found wallet passes the webhook's filters
+func (w WebhookConfig) Matches(winrate, realizedPnLPct float64) bool {
+	return winrate >= w.MinWinrate && realizedPnLPct >= w.MinRealizedPnL
+}
+
+// DefaultResultsPageSize is how many wallets a results API page holds
+// unless the request asks for fewer
+const DefaultResultsPageSize = 50
+
+// ResultsAPIConfig serves scan results over HTTP to external consumers.
+// An empty listen address disables it; every request must carry APIKey
+// in the X-API-Key header.
+type ResultsAPIConfig struct {
+	ListenAddr  string `json:"listen_addr"` // e.g. "127.0.0.1:8090"
+	APIKey      string `json:"api_key"`
+	MaxPageSize int    `json:"max_page_size"`
+}
+
+// DefaultMaxWalletsPerUser caps how many wallets one user can track
+const DefaultMaxWalletsPerUser = 20
+
+// WalletsConfig limits the wallets users can add
+type WalletsConfig struct {
+	MaxPerUser int `json:"max_per_user"`
+}
+
+// DefaultWalletURLTemplate is the DexCheck wallet page the analyzer scrapes
+const DefaultWalletURLTemplate = "https://dexcheck.ai/app/wallet-analyzer/%s"
+
+// AnalyzerConfig selects the page the wallet analyzer scrapes
+type AnalyzerConfig struct {
+	WalletURLTemplate string `json:"wallet_url_template"` // %s is replaced by the wallet address
+	// Pages is how many browser pages a scan cycle analyzes wallets with
+	// when no plan with more is searching
+	Pages int `json:"pages"`
+}
+
+// Analyzer page bounds
+const (
+	DefaultAnalyzerPages = 6
+	MaxAnalyzerPages     = 32
+)
+
+// Default scanned wallet windows. Wallets stay searchable for the display
+// window and are deleted once the retention window passes.
+const (
+	DefaultWalletDisplayHours   = 5
+	DefaultWalletRetentionHours = 24
+)
+
+// DefaultMaxScanCycleMinutes bounds one scanner cycle unless configured
+const DefaultMaxScanCycleMinutes = 120
+
+// DefaultBelowThresholdCooldownHours is how long a wallet that missed the
+// analysis filters is left out of scans unless configured
+const DefaultBelowThresholdCooldownHours = 24
+
+// ScanSettings controls how long scanned wallets are kept and how long a
+// scan cycle may run. Retention must be at least the display window, or
+// wallets are deleted while they should still show up in results.
+type ScanSettings struct {
+	WalletDisplayHours   int `json:"wallet_display_hours"`
+	WalletRetentionHours int `json:"wallet_retention_hours"`
+	MaxCycleMinutes      int `json:"max_cycle_minutes"`
+	// BelowThresholdCooldownHours skips wallets that missed the analysis
+	// filters in later cycles for this long; negative disables
+	BelowThresholdCooldownHours int `json:"below_threshold_cooldown_hours"`
+}
+
+// MaxCycle is how long a scan cycle may run before it is cancelled
+func (s ScanSettings) MaxCycle() time.Duration {
+	return time.Duration(s.MaxCycleMinutes) * time.Minute
+}
+
+// BelowThresholdCooldown is how long a wallet that missed the analysis
+// filters is skipped, 0 if it never is
+func (s ScanSettings) BelowThresholdCooldown() time.Duration {
+	if s.BelowThresholdCooldownHours < 0 {
+		return 0
+	}
+	return time.Duration(s.BelowThresholdCooldownHours) * time.Hour
+}
+
+// WalletDisplayWindow is how long after a scan a wallet shows up in results
+func (s ScanSettings) WalletDisplayWindow() time.Duration {
+	return time.Duration(s.WalletDisplayHours) * time.Hour
+}
+
+// WalletRetention is how long after a scan a wallet is deleted
+func (s ScanSettings) WalletRetention() time.Duration {
+	return time.Duration(s.WalletRetentionHours) * time.Hour
+}
+
+// DefaultSessionTTLMinutes is how long a multi-step bot flow may sit idle
+const DefaultSessionTTLMinutes = 15
+
+// SessionsConfig controls bot conversation state. A flow idle for longer
+// than the TTL is dropped, so a later message isn't taken as its input.
+type SessionsConfig struct {
+	TTLMinutes int `json:"ttl_minutes"`
+}
+
+// TTL is how long a multi-step flow may wait for the user's next input
+func (s SessionsConfig) TTL() time.Duration {
+	return time.Duration(s.TTLMinutes) * time.Minute
+}
+
+// JanitorConfig paces the cleanup of expired limit orders. Each run
+// cancels at most MaxBatchesPerRun batches of BatchSize orders, leaving a
+// larger backlog to later runs; a run that hits errors waits
+// exponentially longer, up to MaxBackoffSec, before the next.
+type JanitorConfig struct {
+	BatchSize        int `json:"batch_size"`
+	IntervalSec      int `json:"interval_sec"`
+	MaxBatchesPerRun int `json:"max_batches_per_run"`
+	MaxBackoffSec    int `json:"max_backoff_sec"`
+}
+
+// Janitor defaults used when unset
+const (
+	DefaultJanitorBatchSize        = 50
+	DefaultJanitorIntervalSec      = 60
+	DefaultJanitorMaxBatchesPerRun = 10
+	DefaultJanitorMaxBackoffSec    = 900
+)
+
+// Interval is how often the janitor looks for expired orders
+func (j JanitorConfig) Interval() time.Duration {
+	return time.Duration(j.IntervalSec) * time.Second
+}
+
+// MaxBackoff caps how long the janitor waits after failed runs
+func (j JanitorConfig) MaxBackoff() time.Duration {
+	return time.Duration(j.MaxBackoffSec) * time.Second
+}
+
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -94,19 +336,140 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	// Environment overrides win over the file
+	cfg.applyEnv(os.LookupEnv)
+
 	// Set defaults if not specified
+	if cfg.APISettings.TokenCacheTTLSec == 0 {
+		cfg.APISettings.TokenCacheTTLSec = DefaultTokenCacheTTLSec
+	}
+	if cfg.APISettings.BirdeyeMinLiquidity == 0 && cfg.APISettings.BirdeyeMaxLiquidity == 0 {
+		cfg.APISettings.BirdeyeMinLiquidity = DefaultBirdeyeMinLiquidity
+		cfg.APISettings.BirdeyeMaxLiquidity = DefaultBirdeyeMaxLiquidity
+	}
+	if cfg.APISettings.BirdeyeSortBy == "" {
+		cfg.APISettings.BirdeyeSortBy = "liquidity"
+	}
 	if cfg.FanOutEngine.WorkerCount == 0 {
 		cfg.FanOutEngine.WorkerCount = 20
 	}
 	if cfg.FanOutEngine.LogBufferSize == 0 {
 		cfg.FanOutEngine.LogBufferSize = 50000
 	}
+	if cfg.WebSocketSettings.ShardCount == 0 {
+		cfg.WebSocketSettings.ShardCount = 1
+	}
+	if cfg.WebSocketSettings.FailoverAfterMs == 0 {
+		cfg.WebSocketSettings.FailoverAfterMs = 15000
+	}
+	if cfg.FanOutEngine.DropPolicy == "" {
+		cfg.FanOutEngine.DropPolicy = "prefilter"
+	}
+	if cfg.FanOutEngine.HighWaterRatio == 0 {
+		cfg.FanOutEngine.HighWaterRatio = 0.8
+	}
+	if cfg.FanOutEngine.ReconcileIntervalSec == 0 {
+		cfg.FanOutEngine.ReconcileIntervalSec = 300
+	}
+	if cfg.TradingSettings.ConfirmTimeoutSec == 0 {
+		cfg.TradingSettings.ConfirmTimeoutSec = 90
+	}
 	if cfg.Redis.Address == "" {
 		cfg.Redis.Address = "localhost:6379"
 	}
 	if cfg.Redis.PoolSize == 0 {
 		cfg.Redis.PoolSize = 50
 	}
+	if cfg.RateLimits.UserUpdatesPerSec == 0 {
+		cfg.RateLimits.UserUpdatesPerSec = 1
+	}
+	if cfg.RateLimits.UserBurst == 0 {
+		cfg.RateLimits.UserBurst = 5
+	}
+	if cfg.RateLimits.UserIdleTimeoutSec == 0 {
+		cfg.RateLimits.UserIdleTimeoutSec = 600
+	}
+	if len(cfg.Programs.Subscriptions) == 0 {
+		cfg.Programs.Subscriptions = DefaultProgramSubscriptions(cfg.Programs)
+	}
+	if err := cfg.validateSubscriptions(); err != nil {
+		return nil, err
+	}
+	if len(cfg.Plans) == 0 {
+		cfg.Plans = DefaultPlans()
+	}
+	if cfg.Payments.RPCURL == "" {
+		cfg.Payments.RPCURL = "https://api.mainnet-beta.solana.com"
+	}
+	if cfg.Payments.MaxTxAgeHours == 0 {
+		cfg.Payments.MaxTxAgeHours = 24
+	}
+	if cfg.Payments.LowCreditThreshold == 0 {
+		cfg.Payments.LowCreditThreshold = 10
+	}
+	if cfg.CopyTrading.MaxLossSOL == 0 {
+		cfg.CopyTrading.MaxLossSOL = 0.5
+	}
+	if cfg.CopyTrading.WindowHours == 0 {
+		cfg.CopyTrading.WindowHours = 24
+	}
+	if cfg.CopyTrading.MinTrades == 0 {
+		cfg.CopyTrading.MinTrades = 3
+	}
+	if cfg.CopyTrading.InactiveAfterHours == 0 {
+		cfg.CopyTrading.InactiveAfterHours = DefaultInactiveAfterHours
+	}
+	if cfg.Wallets.MaxPerUser == 0 {
+		cfg.Wallets.MaxPerUser = DefaultMaxWalletsPerUser
+	}
+	if cfg.Analyzer.WalletURLTemplate == "" {
+		cfg.Analyzer.WalletURLTemplate = DefaultWalletURLTemplate
+	}
+	if cfg.Analyzer.Pages == 0 {
+		cfg.Analyzer.Pages = DefaultAnalyzerPages
+	}
+	if cfg.ScanSettings.WalletDisplayHours == 0 {
+		cfg.ScanSettings.WalletDisplayHours = DefaultWalletDisplayHours
+	}
+	if cfg.ScanSettings.WalletRetentionHours == 0 {
+		cfg.ScanSettings.WalletRetentionHours = DefaultWalletRetentionHours
+		if cfg.ScanSettings.WalletDisplayHours > DefaultWalletRetentionHours {
+			cfg.ScanSettings.WalletRetentionHours = cfg.ScanSettings.WalletDisplayHours
+		}
+	}
+	if cfg.ScanSettings.MaxCycleMinutes == 0 {
+		cfg.ScanSettings.MaxCycleMinutes = DefaultMaxScanCycleMinutes
+	}
+	if cfg.ResultsAPI.MaxPageSize == 0 {
+		cfg.ResultsAPI.MaxPageSize = DefaultResultsPageSize
+	}
+	if cfg.Webhook.MaxRetries == 0 {
+		cfg.Webhook.MaxRetries = 3
+	}
+	if cfg.ScanSettings.BelowThresholdCooldownHours == 0 {
+		cfg.ScanSettings.BelowThresholdCooldownHours = DefaultBelowThresholdCooldownHours
+	}
+	if cfg.Sessions.TTLMinutes == 0 {
+		cfg.Sessions.TTLMinutes = DefaultSessionTTLMinutes
+	}
+	if cfg.Janitor.BatchSize == 0 {
+		cfg.Janitor.BatchSize = DefaultJanitorBatchSize
+	}
+	if cfg.Janitor.IntervalSec == 0 {
+		cfg.Janitor.IntervalSec = DefaultJanitorIntervalSec
+	}
+	if cfg.Janitor.MaxBatchesPerRun == 0 {
+		cfg.Janitor.MaxBatchesPerRun = DefaultJanitorMaxBatchesPerRun
+	}
+	if cfg.Janitor.MaxBackoffSec == 0 {
+		cfg.Janitor.MaxBackoffSec = DefaultJanitorMaxBackoffSec
+	}
+	if len(cfg.Ranking.Profiles) == 0 {
+		cfg.Ranking.Profiles = DefaultScoringProfiles()
+	}
+	if cfg.Ranking.DefaultProfile == "" {
+		cfg.Ranking.DefaultProfile = DefaultScoringProfile
+	}
 
 	return &cfg, nil
 }
@@ -1,14 +1,21 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"solana-orchestrator/storage"
 )
 
 func TestLoadConfig(t *testing.T) {
 	// Test loading valid config
 	t.Run("LoadValidConfig", func(t *testing.T) {
-		cfg, err := Load("config.json")
+		cfg, err := Load("config.example.json")
 		if err != nil {
 			t.Fatalf("Failed to load config: %v", err)
 		}
@@ -29,6 +36,10 @@ func TestLoadConfig(t *testing.T) {
 			t.Error("TokenLimit should be positive")
 		}
 
+		if _, ok := cfg.Ranking.Profiles[cfg.Ranking.DefaultProfile]; !ok {
+			t.Errorf("Default scoring profile %q is missing from %v", cfg.Ranking.DefaultProfile, cfg.Ranking.Profiles)
+		}
+
 		t.Logf("Config loaded: TokenSource=%s, TokenLimit=%d",
 			cfg.APISettings.TokenSource, cfg.APISettings.TokenLimit)
 	})
@@ -61,7 +72,7 @@ func TestLoadConfig(t *testing.T) {
 }
 
 func TestConfigValidation(t *testing.T) {
-	cfg, err := Load("config.json")
+	cfg, err := Load("config.example.json")
 	if err != nil {
 		t.Skip("Skipping validation test - config not found")
 	}
@@ -98,5 +109,455 @@ func TestConfigValidation(t *testing.T) {
 		if cfg.RateLimits.ShyftAPIPerSec <= 0 {
 			t.Error("Shyft API per sec should be positive")
 		}
+
+		if cfg.RateLimits.UserUpdatesPerSec <= 0 || cfg.RateLimits.UserBurst <= 0 {
+			t.Error("Per-user update rate and burst should be positive")
+		}
+	})
+}
+
+func TestPlanCatalog(t *testing.T) {
+	t.Run("DefaultsApplied", func(t *testing.T) {
+		cfg, err := Load("config.example.json")
+		if err != nil {
+			t.Skip("Skipping plan test - config not found")
+		}
+		if len(cfg.Plans) == 0 {
+			t.Fatal("Expected default plans when none configured")
+		}
+		if len(cfg.WelcomePlans()) == 0 {
+			t.Error("Expected at least one welcome plan")
+		}
+	})
+
+	t.Run("CustomPlans", func(t *testing.T) {
+		tmpfile, err := os.CreateTemp("", "plans_*.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmpfile.Name())
+
+		tmpfile.WriteString(`{"plans": [
+			{"id": "credits_5000", "name": "5000 Credits", "kind": "credits", "credits": 5000, "realtime_scans": true, "price_sol": 3, "max_wallets_per_search": 500, "analyzer_pages": 16, "scan_priority": 5},
+			{"id": "trial_7day", "name": "7-Day Trial", "kind": "trial", "duration_hours": 168, "scan_delay_min_sec": 600, "scan_delay_max_sec": 900, "welcome": true}
+		]}`)
+		tmpfile.Close()
+
+		cfg, err := Load(tmpfile.Name())
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+
+		plan := cfg.FindPlan("credits_5000")
+		if plan == nil || !plan.IsCredits() || plan.Credits != 5000 {
+			t.Fatalf("Unexpected credits plan: %+v", plan)
+		}
+		if plan.ExpiresAt(time.Now()) != 0 {
+			t.Error("Credit plans should not expire")
+		}
+		if min, max := plan.ScanDelayRange(); min != DefaultScanDelayMinSec || max != DefaultScanDelayMaxSec {
+			t.Errorf("Expected default delay range, got %d-%d", min, max)
+		}
+		if got := plan.SearchCap(); got != 500 {
+			t.Errorf("Expected search cap 500, got %d", got)
+		}
+		if pages, priority := plan.ScanPages(cfg.Analyzer.Pages), plan.Priority(); pages != 16 || priority != 5 {
+			t.Errorf("Expected 16 pages at priority 5, got %d at %d", pages, priority)
+		}
+
+		trial := cfg.FindPlan("trial_7day")
+		if trial == nil || !trial.IsTrial() {
+			t.Fatalf("Unexpected trial plan: %+v", trial)
+		}
+		now := time.Now()
+		if got := trial.ExpiresAt(now); got != now.Add(168*time.Hour).Unix() {
+			t.Errorf("Unexpected trial expiry %d", got)
+		}
+		if min, max := trial.ScanDelayRange(); min != 600 || max != 900 {
+			t.Errorf("Expected 600-900 delay, got %d-%d", min, max)
+		}
+		if got := trial.SearchCap(); got != DefaultTrialMaxWalletsPerSearch {
+			t.Errorf("Expected trial search cap %d, got %d", DefaultTrialMaxWalletsPerSearch, got)
+		}
+		if pages, priority := trial.ScanPages(cfg.Analyzer.Pages), trial.Priority(); pages != DefaultAnalyzerPages || priority != DefaultTrialScanPriority {
+			t.Errorf("Expected the base %d pages at trial priority, got %d at %d", DefaultAnalyzerPages, pages, priority)
+		}
+		if credits := (&PlanConfig{Kind: PlanKindCredits}); credits.ScanPages(DefaultAnalyzerPages) != DefaultCreditsAnalyzerPages || credits.Priority() <= trial.Priority() {
+			t.Error("Expected credit plans to default to more pages and a higher priority than trials")
+		}
+
+		if welcome := cfg.WelcomePlans(); len(welcome) != 1 || welcome[0].ID != "trial_7day" {
+			t.Errorf("Unexpected welcome plans: %+v", welcome)
+		}
+		if cfg.FindPlan("credits_1000") != nil {
+			t.Error("Configured catalog should replace the defaults")
+		}
+	})
+	t.Run("CreditPackages", func(t *testing.T) {
+		cfg := &Config{Plans: DefaultPlans()}
+
+		packages := cfg.CreditPackages()
+		if len(packages) != 3 {
+			t.Fatalf("Expected 3 purchasable packages, got %d", len(packages))
+		}
+		if packages[0].ID != "pack_100" {
+			t.Errorf("Expected cheapest package first, got %s", packages[0].ID)
+		}
+
+		tests := []struct {
+			lamports uint64
+			want     string
+		}{
+			{50_000_000, ""},
+			{100_000_000, "pack_100"},
+			{399_999_999, ""},
+			{400_000_000, "pack_500"},
+			{700_000_000, "pack_1000"},
+			{2_000_000_000, ""},
+		}
+		for _, tt := range tests {
+			got := cfg.PackageForPayment(tt.lamports)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("%d lamports: expected no package, got %s", tt.lamports, got.ID)
+				}
+				continue
+			}
+			if got == nil || got.ID != tt.want {
+				t.Errorf("%d lamports: expected %s, got %+v", tt.lamports, tt.want, got)
+			}
+		}
+		if prices := cfg.PackagePrices(); len(prices) != 3 || prices[0] != 100_000_000 {
+			t.Errorf("Unexpected package prices: %v", prices)
+		}
+	})
+}
+
+func TestProgramSubscriptions(t *testing.T) {
+	t.Run("DefaultsApplied", func(t *testing.T) {
+		cfg := &Config{Programs: ProgramsConfig{Subscriptions: DefaultProgramSubscriptions(ProgramsConfig{})}}
+		if err := cfg.validateSubscriptions(); err != nil {
+			t.Fatalf("Default subscriptions should be valid: %v", err)
+		}
+
+		programs := cfg.EnabledPrograms()
+		for _, want := range []string{ProgramPumpFun, ProgramMeteoraDLMM, ProgramOrcaWhirlpool} {
+			found := false
+			for _, p := range programs {
+				if p == want {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected %s in default programs", want)
+			}
+		}
+	})
+
+	t.Run("DisabledAndDuplicates", func(t *testing.T) {
+		cfg := &Config{Programs: ProgramsConfig{Subscriptions: []ProgramSubscription{
+			{Name: "Pump.fun", ProgramID: ProgramPumpFun, Enabled: true},
+			{Name: "Pump.fun again", ProgramID: ProgramPumpFun, Enabled: true},
+			{Name: "Orca", ProgramID: ProgramOrcaWhirlpool, Enabled: false},
+			{Name: "Broken", ProgramID: "not-a-key", Enabled: false},
+		}}}
+		if err := cfg.validateSubscriptions(); err != nil {
+			t.Fatalf("Disabled entries should not be validated: %v", err)
+		}
+		if programs := cfg.EnabledPrograms(); len(programs) != 1 || programs[0] != ProgramPumpFun {
+			t.Errorf("Unexpected enabled programs: %v", programs)
+		}
+	})
+
+	t.Run("InvalidProgramID", func(t *testing.T) {
+		tmpfile, err := os.CreateTemp("", "programs_*.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmpfile.Name())
+
+		tmpfile.WriteString(`{"programs": {"subscriptions": [
+			{"name": "Typo", "program_id": "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6", "enabled": true}
+		]}}`)
+		tmpfile.Close()
+
+		if _, err := Load(tmpfile.Name()); err == nil {
+			t.Error("Expected error for invalid program ID")
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("ExampleConfigNeedsSecrets", func(t *testing.T) {
+		cfg, err := Load("config.example.json")
+		if err != nil {
+			t.Fatalf("Failed to load example config: %v", err)
+		}
+		err = cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "placeholder") {
+			t.Errorf("Expected placeholder problems, got %v", err)
+		}
+
+		t.Setenv("SOLORCH_MORALIS_API_KEY", "moralis")
+		t.Setenv("SOLORCH_BIRDEYE_API_KEY", "birdeye")
+		t.Setenv("SOLORCH_SHYFT_API_KEY", "shyft")
+		cfg, err = Load("config.example.json")
+		if err != nil {
+			t.Fatalf("Failed to load example config: %v", err)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Expected example config plus env secrets to validate, got %v", err)
+		}
+	})
+
+	valid := func() *Config {
+		cfg := &Config{
+			BirdeyeAPIKey: "birdeye",
+			ShyftAPIKey:   "shyft",
+		}
+		cfg.WebSocketSettings.ShyftWSURL = "wss://rpc.shyft.to"
+		cfg.WebSocketSettings.ShardCount = 1
+		cfg.FanOutEngine.WorkerCount = 4
+		cfg.FanOutEngine.LogBufferSize = 1000
+		cfg.Payments.RPCURL = "https://api.mainnet-beta.solana.com"
+		cfg.CopyTrading.MaxLossSOL = 0.5
+		cfg.CopyTrading.WindowHours = 24
+		return cfg
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Expected baseline config to validate, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"MissingBirdeyeKey", func(c *Config) { c.BirdeyeAPIKey = "" }, "birdeye_api_key"},
+		{"MissingMoralisKey", func(c *Config) { c.APISettings.TokenSource = "moralis" }, "moralis_api_key"},
+		{"HTTPWebSocketURL", func(c *Config) { c.WebSocketSettings.ShyftWSURL = "https://rpc.shyft.to" }, "scheme must be ws or wss"},
+		{"MissingShyftKey", func(c *Config) { c.ShyftAPIKey = "" }, "shyft_api_key"},
+		{"NegativeInactiveHours", func(c *Config) { c.CopyTrading.InactiveAfterHours = -1 }, "inactive_after_hours"},
+		{"ZeroWorkers", func(c *Config) { c.FanOutEngine.WorkerCount = 0 }, "worker_count"},
+		{"HugeBuffer", func(c *Config) { c.FanOutEngine.LogBufferSize = 50_000_000 }, "log_buffer_size"},
+		{"BadFanOutRPCURL", func(c *Config) { c.FanOutEngine.RPCURL = "rpc.example.com" }, "fanout_engine.rpc_url"},
+		{"BadJitoURL", func(c *Config) { c.TradingSettings.JitoBlockEngineURL = "block-engine" }, "jito_block_engine_url"},
+		{"BadMoralisBaseURL", func(c *Config) { c.APISettings.MoralisBaseURL = "ftp://gateway" }, "moralis_base_url"},
+		{"BadBirdeyeBaseURL", func(c *Config) { c.APISettings.BirdeyeBaseURL = "localhost:8080" }, "birdeye_base_url"},
+		{"NegativeBirdeyeLiquidity", func(c *Config) { c.APISettings.BirdeyeMinLiquidity = -1 }, "birdeye_min_liquidity"},
+		{"InvertedBirdeyeLiquidity", func(c *Config) {
+			c.APISettings.BirdeyeMinLiquidity, c.APISettings.BirdeyeMaxLiquidity = 500000, 100000
+		}, "birdeye_max_liquidity (100000) must be at least birdeye_min_liquidity (500000)"},
+		{"NegativeBirdeyeVolume", func(c *Config) { c.APISettings.BirdeyeMinVolumeUSD = -5 }, "birdeye_min_volume_usd"},
+		{"UnknownBirdeyeSort", func(c *Config) { c.APISettings.BirdeyeSortBy = "holders" }, "birdeye_sort_by"},
+		{"BadWebhookURL", func(c *Config) { c.Webhook.URL, c.Webhook.Secret = "hooks.example.com", "s3cret" }, "webhook.url"},
+		{"WebhookWithoutSecret", func(c *Config) { c.Webhook.URL = "https://hooks.example.com/wallets" }, "webhook.secret"},
+		{"BadResultsAPIAddr", func(c *Config) { c.ResultsAPI.ListenAddr, c.ResultsAPI.APIKey = "8090", "key" }, "results_api.listen_addr"},
+		{"ResultsAPIWithoutKey", func(c *Config) { c.ResultsAPI.ListenAddr = "127.0.0.1:8090" }, "results_api.api_key"},
+		{"NegativeConfirmTimeout", func(c *Config) { c.TradingSettings.ConfirmTimeoutSec = -1 }, "confirm_timeout_sec"},
+		{"NegativeMinLiquidity", func(c *Config) { c.APISettings.MinTokenLiquidityUSD = -1 }, "min_token_liquidity_usd"},
+		{"BadTreasury", func(c *Config) { c.Payments.TreasuryAddress = "not-a-key" }, "treasury_address"},
+		{"AnalyzerURLNoPlaceholder", func(c *Config) { c.Analyzer.WalletURLTemplate = "https://dexcheck.ai/app/wallet-analyzer/" }, "exactly one %s"},
+		{"TooManyAnalyzerPages", func(c *Config) { c.Analyzer.Pages = 100 }, "analyzer.pages"},
+		{"NegativePlanPages", func(c *Config) { c.Plans = []PlanConfig{{ID: "p", AnalyzerPages: -1}} }, "plans[p].analyzer_pages"},
+		{"NegativePlanPriority", func(c *Config) { c.Plans = []PlanConfig{{ID: "p", ScanPriority: -1}} }, "plans[p].scan_priority"},
+		{"AnalyzerURLBadScheme", func(c *Config) { c.Analyzer.WalletURLTemplate = "ftp://mirror.local/%s" }, "wallet_url_template"},
+		{"NegativeWalletDisplay", func(c *Config) { c.ScanSettings.WalletDisplayHours = -1 }, "wallet_display_hours"},
+		{"RetentionBelowDisplay", func(c *Config) {
+			c.ScanSettings.WalletDisplayHours = 48
+			c.ScanSettings.WalletRetentionHours = 24
+		}, "must be at least wallet_display_hours"},
+		{"NegativeMaxScanCycle", func(c *Config) { c.ScanSettings.MaxCycleMinutes = -1 }, "max_cycle_minutes"},
+		{"NegativeSessionTTL", func(c *Config) { c.Sessions.TTLMinutes = -1 }, "sessions.ttl_minutes"},
+		{"NegativeJanitorBatch", func(c *Config) { c.Janitor.BatchSize = -1 }, "janitor.batch_size"},
+		{"JanitorBackoffBelowInterval", func(c *Config) {
+			c.Janitor.IntervalSec = 60
+			c.Janitor.MaxBackoffSec = 30
+		}, "max_backoff_sec (30) must be at least interval_sec (60)"},
+		{"NegativeScoringWeight", func(c *Config) {
+			c.Ranking.Profiles = map[string]storage.ScoreWeights{"custom": {Winrate: 1, PnL: -0.5}}
+		}, "ranking.profiles[custom]"},
+		{"ZeroScoringWeights", func(c *Config) {
+			c.Ranking.Profiles = map[string]storage.ScoreWeights{"custom": {}}
+		}, "at least one positive weight"},
+		{"UnknownDefaultProfile", func(c *Config) {
+			c.Ranking.Profiles = map[string]storage.ScoreWeights{"custom": {Winrate: 1}}
+			c.Ranking.DefaultProfile = DefaultScoringProfile
+		}, "ranking.default_profile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("LeakedKey", func(t *testing.T) {
+		sum := sha256.Sum256([]byte("leaked"))
+		leakedKeys[hex.EncodeToString(sum[:])] = true
+		defer delete(leakedKeys, hex.EncodeToString(sum[:]))
+
+		for _, modify := range []func(*Config){
+			func(c *Config) { c.ShyftAPIKey = "leaked" },
+			func(c *Config) { c.WebSocketSettings.ShyftWSURL = "wss://rpc.shyft.to?api_key=leaked" },
+			func(c *Config) { c.TradingSettings.JitoPrivateKey = "leaked" },
+		} {
+			cfg := valid()
+			modify(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), "known leaked key") {
+				t.Errorf("Expected leaked key error, got %v", err)
+			}
+		}
+	})
+
+	t.Run("ReportsEveryProblem", func(t *testing.T) {
+		cfg := valid()
+		cfg.BirdeyeAPIKey = ""
+		cfg.FanOutEngine.WorkerCount = 0
+		cfg.WebSocketSettings.ShyftWSURL = "wss://?api_key=secret"
+
+		var verr *ValidationError
+		if err := cfg.Validate(); !errors.As(err, &verr) {
+			t.Fatalf("Expected *ValidationError, got %v", err)
+		}
+		if len(verr.Problems) != 3 {
+			t.Errorf("Expected 3 problems, got %v", verr.Problems)
+		}
+		if strings.Contains(verr.Error(), "secret") {
+			t.Error("Validation errors must not leak API keys")
+		}
 	})
 }
+
+func TestEnvOverrides(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "env_*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	tmpfile.WriteString(`{
+		"shyft_api_key": "from-file",
+		"birdeye_api_key": "birdeye-file",
+		"trading_settings": {"jito_private_key": "jito-file"},
+		"redis": {"address": "file:6379"}
+	}`)
+	tmpfile.Close()
+
+	t.Run("EnvBeatsFile", func(t *testing.T) {
+		t.Setenv("SOLORCH_SHYFT_API_KEY", "from-env")
+		t.Setenv("SOLORCH_JITO_PRIVATE_KEY", "jito-env")
+		t.Setenv("SOLORCH_MORALIS_FALLBACK_KEYS", "k1, k2,,")
+		t.Setenv("SOLORCH_BIRDEYE_API_KEY", "")
+
+		cfg, err := Load(tmpfile.Name())
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.ShyftAPIKey != "from-env" || cfg.TradingSettings.JitoPrivateKey != "jito-env" {
+			t.Errorf("Expected env values, got shyft=%q jito=%q", cfg.ShyftAPIKey, cfg.TradingSettings.JitoPrivateKey)
+		}
+		if len(cfg.MoralisFallbackKeys) != 2 || cfg.MoralisFallbackKeys[1] != "k2" {
+			t.Errorf("Expected 2 fallback keys, got %v", cfg.MoralisFallbackKeys)
+		}
+		if cfg.BirdeyeAPIKey != "birdeye-file" {
+			t.Errorf("Empty env var should not clear file value, got %q", cfg.BirdeyeAPIKey)
+		}
+	})
+
+	t.Run("PrefixedBeatsLegacy", func(t *testing.T) {
+		t.Setenv("REDIS_ADDR", "legacy:6379")
+		cfg, err := Load(tmpfile.Name())
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Redis.Address != "legacy:6379" {
+			t.Errorf("Expected legacy env over file, got %q", cfg.Redis.Address)
+		}
+
+		t.Setenv("SOLORCH_REDIS_ADDR", "prefixed:6379")
+		cfg, err = Load(tmpfile.Name())
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Redis.Address != "prefixed:6379" {
+			t.Errorf("Expected SOLORCH_ env over legacy, got %q", cfg.Redis.Address)
+		}
+	})
+
+	t.Run("SeparateRPCURLs", func(t *testing.T) {
+		t.Setenv("SOLORCH_RPC_URL", "https://legacy.example.com")
+		t.Setenv("SOLORCH_FANOUT_RPC_URL", "https://fanout.example.com")
+		cfg, err := Load(tmpfile.Name())
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Payments.RPCURL != "https://legacy.example.com" || cfg.FanOutEngine.RPCURL != "https://fanout.example.com" {
+			t.Errorf("Unexpected RPC URLs: payments=%q fanout=%q", cfg.Payments.RPCURL, cfg.FanOutEngine.RPCURL)
+		}
+
+		t.Setenv("SOLORCH_PAYMENTS_RPC_URL", "https://payments.example.com")
+		cfg, err = Load(tmpfile.Name())
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Payments.RPCURL != "https://payments.example.com" || cfg.FanOutEngine.RPCURL != "https://fanout.example.com" {
+			t.Errorf("Unexpected RPC URLs: payments=%q fanout=%q", cfg.Payments.RPCURL, cfg.FanOutEngine.RPCURL)
+		}
+	})
+
+	t.Run("FileUsedWithoutEnv", func(t *testing.T) {
+		cfg, err := Load(tmpfile.Name())
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.ShyftAPIKey != "from-file" {
+			t.Errorf("Expected file value, got %q", cfg.ShyftAPIKey)
+		}
+	})
+}
+
+func TestShyftURLs(t *testing.T) {
+	cfg := &Config{ShyftAPIKey: "key"}
+	if got := cfg.ShyftRPCURL(); got != "https://rpc.shyft.to?api_key=key" {
+		t.Errorf("Unexpected RPC URL %q", got)
+	}
+	if got := cfg.ShyftWSURL(); got != "wss://rpc.shyft.to?api_key=key" {
+		t.Errorf("Unexpected WS URL %q", got)
+	}
+
+	// A key embedded in the URL is used as-is
+	cfg = &Config{}
+	cfg.WebSocketSettings.ShyftWSURL = "wss://rpc.shyft.to?api_key=url-key"
+	if got := cfg.ShyftKey(); got != "url-key" {
+		t.Errorf("Expected key from URL, got %q", got)
+	}
+	if got := cfg.ShyftWSURL(); got != cfg.WebSocketSettings.ShyftWSURL {
+		t.Errorf("URL with key should be unchanged, got %q", got)
+	}
+
+	if got := (&Config{}).ShyftRPCURL(); got != "" {
+		t.Errorf("Expected no RPC URL without a key, got %q", got)
+	}
+
+	// The fan-out engine uses its own RPC, else Shyft's, never the payments one
+	cfg = &Config{ShyftAPIKey: "key"}
+	cfg.Payments.RPCURL = "https://api.mainnet-beta.solana.com"
+	if got := cfg.FanOutRPCURL(); got != cfg.ShyftRPCURL() {
+		t.Errorf("Expected the Shyft RPC by default, got %q", got)
+	}
+	cfg.FanOutEngine.RPCURL = "https://rpc.example.com"
+	if got := cfg.FanOutRPCURL(); got != "https://rpc.example.com" {
+		t.Errorf("Expected the fan-out RPC, got %q", got)
+	}
+	cfg = &Config{}
+	cfg.Payments.RPCURL = "https://api.mainnet-beta.solana.com"
+	if got := cfg.FanOutRPCURL(); got != "" {
+		t.Errorf("Expected no fan-out RPC without a key, got %q", got)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import "strings"
+
+// EnvPrefix prefixes every environment override, e.g. SOLORCH_SHYFT_API_KEY
+const EnvPrefix = "SOLORCH_"
+
+// envOverride binds an environment variable to a config field. Legacy is an
+// older, full variable name still honoured for existing deployments.
+type envOverride struct {
+	name   string
+	legacy string
+	str    *string
+	list   *[]string // comma-separated
+}
+
+// envOverrides lists every sensitive field that can be set from the
+// environment
+func (c *Config) envOverrides() []envOverride {
+	return []envOverride{
+		{name: "MORALIS_API_KEY", str: &c.MoralisAPIKey},
+		{name: "MORALIS_FALLBACK_KEYS", list: &c.MoralisFallbackKeys},
+		{name: "BIRDEYE_API_KEY", str: &c.BirdeyeAPIKey},
+		{name: "SHYFT_API_KEY", legacy: "SHYFT_API_KEY", str: &c.ShyftAPIKey},
+		{name: "SHYFT_WS_URL", str: &c.WebSocketSettings.ShyftWSURL},
+		{name: "BACKUP_WS_URLS", list: &c.WebSocketSettings.BackupWSURLs},
+		{name: "JITO_PRIVATE_KEY", str: &c.TradingSettings.JitoPrivateKey},
+		{name: "JITO_BLOCK_ENGINE_URL", str: &c.TradingSettings.JitoBlockEngineURL},
+		{name: "PAYMENTS_RPC_URL", legacy: EnvPrefix + "RPC_URL", str: &c.Payments.RPCURL},
+		{name: "FANOUT_RPC_URL", str: &c.FanOutEngine.RPCURL},
+		{name: "TREASURY_ADDRESS", str: &c.Payments.TreasuryAddress},
+		{name: "REDIS_ADDR", legacy: "REDIS_ADDR", str: &c.Redis.Address},
+		{name: "REDIS_PASSWORD", legacy: "REDIS_PASSWORD", str: &c.Redis.Password},
+		{name: "WEBHOOK_URL", str: &c.Webhook.URL},
+		{name: "WEBHOOK_SECRET", str: &c.Webhook.Secret},
+		{name: "RESULTS_API_KEY", str: &c.ResultsAPI.APIKey},
+	}
+}
+
+// applyEnv overrides config fields from the environment. Precedence is
+// SOLORCH_ variable > legacy variable > config file. Empty variables are
+// ignored so an unset secret never blanks a file value.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) {
+	for _, o := range c.envOverrides() {
+		value, ok := lookup(EnvPrefix + o.name)
+		if (!ok || value == "") && o.legacy != "" {
+			value, ok = lookup(o.legacy)
+		}
+		if !ok || value == "" {
+			continue
+		}
+
+		if o.str != nil {
+			*o.str = value
+			continue
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*o.list = items
+	}
+}
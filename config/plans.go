@@ -0,0 +1,243 @@
+package config
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Plan kinds
+const (
+	PlanKindCredits = "credits"
+	PlanKindTrial   = "trial"
+)
+
+// Default slow-scan delay for users without a plan entry
+const (
+	DefaultScanDelayMinSec = 300
+	DefaultScanDelayMaxSec = 3600
+)
+
+// Default per-search wallet caps for plans that don't set one
+const (
+	DefaultMaxWalletsPerSearch      = 200
+	DefaultTrialMaxWalletsPerSearch = 50
+)
+
+// Default scan performance for plans that don't set it. Credit users'
+// searches have their wallets analyzed first and on more pages.
+const (
+	DefaultCreditsAnalyzerPages = 10
+	DefaultCreditsScanPriority  = 2
+	DefaultTrialScanPriority    = 1
+)
+
+// PlanConfig describes a subscription plan offered by the bot
+type PlanConfig struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	Description     string  `json:"description"`
+	Kind            string  `json:"kind"` // "credits" or "trial"
+	Credits         int     `json:"credits"`
+	DurationHours   int     `json:"duration_hours"`
+	RealtimeScans   bool    `json:"realtime_scans"`
+	ScanDelayMinSec int     `json:"scan_delay_min_sec"`
+	ScanDelayMaxSec int     `json:"scan_delay_max_sec"`
+	PriceSOL        float64 `json:"price_sol"`
+	Welcome         bool    `json:"welcome"` // Offered as a free plan on /start
+	// MaxWalletsPerSearch caps the wallets one search can return; 0 uses
+	// the default for the plan kind
+	MaxWalletsPerSearch int `json:"max_wallets_per_search"`
+	// AnalyzerPages is how many browser pages scan cycles use while a
+	// user on the plan is searching; 0 uses the default for the plan kind
+	AnalyzerPages int `json:"analyzer_pages"`
+	// ScanPriority orders the analysis of wallets users' searches want,
+	// highest first; 0 uses the default for the plan kind
+	ScanPriority int `json:"scan_priority"`
+}
+
+// DefaultPlans returns the built-in plan catalog
+func DefaultPlans() []PlanConfig {
+	return []PlanConfig{
+		{
+			ID:              "credits_1000",
+			Name:            "1000 Credits",
+			Description:     "• 1 Credit = 1 Wallet Scan\n• Use for Real-Time or Slow scans\n• No time limit",
+			Kind:            PlanKindCredits,
+			Credits:         1000,
+			RealtimeScans:   true,
+			ScanDelayMinSec: 300,
+			ScanDelayMaxSec: 3600,
+			Welcome:         true,
+		},
+		{
+			ID:              "trial_3day",
+			Name:            "3-Day Free Trial",
+			Description:     "• Unlimited Scans\n• *Note:* Results delayed by 5-10 minutes\n• Expires in 3 days",
+			Kind:            PlanKindTrial,
+			DurationHours:   72,
+			RealtimeScans:   false,
+			ScanDelayMinSec: 300,
+			ScanDelayMaxSec: 600,
+			Welcome:         true,
+		},
+		{
+			ID:            "pack_100",
+			Name:          "100 Credit Pack",
+			Description:   "• 100 wallet scans\n• Real-Time and Slow scans",
+			Kind:          PlanKindCredits,
+			Credits:       100,
+			RealtimeScans: true,
+			PriceSOL:      0.1,
+		},
+		{
+			ID:            "pack_500",
+			Name:          "500 Credit Pack",
+			Description:   "• 500 wallet scans\n• Real-Time and Slow scans",
+			Kind:          PlanKindCredits,
+			Credits:       500,
+			RealtimeScans: true,
+			PriceSOL:      0.4,
+		},
+		{
+			ID:            "pack_1000",
+			Name:          "1000 Credit Pack",
+			Description:   "• 1000 wallet scans\n• Real-Time and Slow scans",
+			Kind:          PlanKindCredits,
+			Credits:       1000,
+			RealtimeScans: true,
+			PriceSOL:      0.7,
+		},
+	}
+}
+
+// FindPlan looks up a plan by ID
+func (c *Config) FindPlan(id string) *PlanConfig {
+	for i := range c.Plans {
+		if c.Plans[i].ID == id {
+			return &c.Plans[i]
+		}
+	}
+	return nil
+}
+
+// WelcomePlans returns the plans offered to new users
+func (c *Config) WelcomePlans() []PlanConfig {
+	var plans []PlanConfig
+	for _, p := range c.Plans {
+		if p.Welcome {
+			plans = append(plans, p)
+		}
+	}
+	return plans
+}
+
+// CreditPackages returns the credit plans that can be bought with SOL,
+// cheapest first
+func (c *Config) CreditPackages() []PlanConfig {
+	var packages []PlanConfig
+	for _, p := range c.Plans {
+		if p.IsCredits() && p.PriceSOL > 0 && p.Credits > 0 {
+			packages = append(packages, p)
+		}
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].PriceSOL < packages[j].PriceSOL
+	})
+	return packages
+}
+
+// PackageForPayment returns the credit package priced at exactly lamports,
+// or nil if none is. Under- and overpayments match no package.
+func (c *Config) PackageForPayment(lamports uint64) *PlanConfig {
+	for _, p := range c.CreditPackages() {
+		if p.PriceLamports() == lamports {
+			return &p
+		}
+	}
+	return nil
+}
+
+// PackagePrices returns the credit packages' prices in lamports
+func (c *Config) PackagePrices() []uint64 {
+	var prices []uint64
+	for _, p := range c.CreditPackages() {
+		prices = append(prices, p.PriceLamports())
+	}
+	return prices
+}
+
+// IsCredits reports whether the plan is credit based
+func (p *PlanConfig) IsCredits() bool {
+	return p.Kind == PlanKindCredits
+}
+
+// IsTrial reports whether the plan is time limited
+func (p *PlanConfig) IsTrial() bool {
+	return p.Kind == PlanKindTrial
+}
+
+// Duration returns how long a trial plan lasts
+func (p *PlanConfig) Duration() time.Duration {
+	return time.Duration(p.DurationHours) * time.Hour
+}
+
+// ExpiresAt returns the expiry timestamp for a plan activated at now, or 0
+func (p *PlanConfig) ExpiresAt(now time.Time) int64 {
+	if p.DurationHours <= 0 {
+		return 0
+	}
+	return now.Add(p.Duration()).Unix()
+}
+
+// ScanDelayRange returns the slow-scan delivery delay bounds in seconds
+func (p *PlanConfig) ScanDelayRange() (int, int) {
+	min, max := p.ScanDelayMinSec, p.ScanDelayMaxSec
+	if min <= 0 && max <= 0 {
+		return DefaultScanDelayMinSec, DefaultScanDelayMaxSec
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+// SearchCap returns how many wallets a single search may return
+func (p *PlanConfig) SearchCap() int {
+	switch {
+	case p.MaxWalletsPerSearch > 0:
+		return p.MaxWalletsPerSearch
+	case p.IsTrial():
+		return DefaultTrialMaxWalletsPerSearch
+	}
+	return DefaultMaxWalletsPerSearch
+}
+
+// ScanPages returns how many analyzer pages a scan cycle may use for the
+// plan, never fewer than the configured base
+func (p *PlanConfig) ScanPages(base int) int {
+	pages := p.AnalyzerPages
+	if pages == 0 && p.IsCredits() {
+		pages = DefaultCreditsAnalyzerPages
+	}
+	return max(pages, base)
+}
+
+// Priority returns the plan's scan priority
+func (p *PlanConfig) Priority() int {
+	switch {
+	case p.ScanPriority > 0:
+		return p.ScanPriority
+	case p.IsCredits():
+		return DefaultCreditsScanPriority
+	}
+	return DefaultTrialScanPriority
+}
+
+// PriceLamports returns the plan price converted to lamports
+func (p *PlanConfig) PriceLamports() uint64 {
+	if p.PriceSOL <= 0 {
+		return 0
+	}
+	return uint64(math.Round(p.PriceSOL * 1e9))
+}
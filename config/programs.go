@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/mr-tron/base58"
+)
+
+// Well-known DEX program IDs
+const (
+	ProgramJupiterV6       = "JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4"
+	ProgramPumpFun         = "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"
+	ProgramMeteoraDLMM     = "LBUZKhRxPF3XUpBCjp4YzTKgLccjZhTSDM9YuVaPwxo"
+	ProgramOrcaWhirlpool   = "whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc"
+	ProgramJupiterLimit    = "JUP4Fb2cqiRUcaTHdrPC8h2gNsA2ETXiPDD33WcGuJB"
+	ProgramRaydiumAMMV4    = "675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"
+	ProgramRaydiumCLMM     = "CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK"
+	ProgramRaydiumCPMM     = "CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C"
+	programPubkeyByteCount = 32
+)
+
+// ProgramSubscription is a program whose transactions the fan-out
+// listener watches for copy-target swaps
+type ProgramSubscription struct {
+	Name      string `json:"name"`
+	ProgramID string `json:"program_id"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// DefaultProgramSubscriptions returns the built-in subscription list,
+// seeded from the legacy single-program fields when they are set
+func DefaultProgramSubscriptions(p ProgramsConfig) []ProgramSubscription {
+	or := func(id, fallback string) string {
+		if id != "" {
+			return id
+		}
+		return fallback
+	}
+
+	subs := []ProgramSubscription{
+		{Name: "Jupiter Limit Order", ProgramID: or(p.JupiterLimitOrder, ProgramJupiterLimit), Enabled: true},
+		{Name: "Raydium AMM v4", ProgramID: or(p.RaydiumAMMV4, ProgramRaydiumAMMV4), Enabled: true},
+		{Name: "Raydium CLMM", ProgramID: or(p.RaydiumCLMM, ProgramRaydiumCLMM), Enabled: true},
+		{Name: "Jupiter v6", ProgramID: ProgramJupiterV6, Enabled: true},
+		{Name: "Pump.fun", ProgramID: ProgramPumpFun, Enabled: true},
+		{Name: "Meteora DLMM", ProgramID: ProgramMeteoraDLMM, Enabled: true},
+		{Name: "Orca Whirlpool", ProgramID: ProgramOrcaWhirlpool, Enabled: true},
+	}
+	return subs
+}
+
+// EnabledPrograms returns the program IDs of enabled subscriptions,
+// without duplicates
+func (c *Config) EnabledPrograms() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, s := range c.Programs.Subscriptions {
+		if !s.Enabled || seen[s.ProgramID] {
+			continue
+		}
+		seen[s.ProgramID] = true
+		ids = append(ids, s.ProgramID)
+	}
+	return ids
+}
+
+// validateSubscriptions checks every enabled subscription is a real pubkey
+func (c *Config) validateSubscriptions() error {
+	for _, s := range c.Programs.Subscriptions {
+		if !s.Enabled {
+			continue
+		}
+		if err := validatePubkey(s.ProgramID); err != nil {
+			return fmt.Errorf("program subscription %q: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// validatePubkey reports whether s is a base58 encoded 32-byte public key
+func validatePubkey(s string) error {
+	if s == "" {
+		return fmt.Errorf("empty program ID")
+	}
+	b, err := base58.Decode(s)
+	if err != nil {
+		return fmt.Errorf("invalid base58 program ID %q: %w", s, err)
+	}
+	if len(b) != programPubkeyByteCount {
+		return fmt.Errorf("program ID %q is %d bytes, want %d", s, len(b), programPubkeyByteCount)
+	}
+	return nil
+}
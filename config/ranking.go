@@ -0,0 +1,22 @@
+package config
+
+import "solana-orchestrator/storage"
+
+// DefaultScoringProfile is the profile /rank uses when none is given
+const DefaultScoringProfile = "balanced"
+
+// RankingConfig sets the scoring profiles users can rank wallets by
+type RankingConfig struct {
+	Profiles       map[string]storage.ScoreWeights `json:"profiles"`
+	DefaultProfile string                          `json:"default_profile"`
+}
+
+// DefaultScoringProfiles returns the built-in weight presets
+func DefaultScoringProfiles() map[string]storage.ScoreWeights {
+	return map[string]storage.ScoreWeights{
+		"balanced": {Winrate: 0.4, PnL: 0.3, TradeCount: 0.2, Recency: 0.1},
+		"winrate":  {Winrate: 0.7, PnL: 0.1, TradeCount: 0.15, Recency: 0.05},
+		"pnl":      {Winrate: 0.15, PnL: 0.7, TradeCount: 0.1, Recency: 0.05},
+		"active":   {Winrate: 0.25, PnL: 0.2, TradeCount: 0.4, Recency: 0.15},
+	}
+}
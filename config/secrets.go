@@ -0,0 +1,116 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Shyft endpoints; the API key is appended from config or the environment
+const (
+	ShyftRPCEndpoint = "https://rpc.shyft.to"
+	ShyftWSEndpoint  = "wss://rpc.shyft.to"
+)
+
+// placeholderPrefix marks unfilled values in config.example.json
+const placeholderPrefix = "YOUR_"
+
+// leakedKeys holds SHA-256 fingerprints of credentials that were once
+// committed to this repository. They are public and must be rotated, so
+// Validate refuses to start with any of them. Only hashes are kept here.
+var leakedKeys = map[string]bool{
+	"e6d36f95f7a18d07605a4c7b21add61c721ea1d870814af7451064d2dcbfd763": true, // shyft
+	"965a8bed444aeeb2c8b3da716da440e1455413106c03bcde0b399b3ffd002e84": true, // birdeye
+	"c77766ab24f91ccbbaf946838962a2603b6fb3135f5563ee557839c4ee008e8b": true, // moralis
+	"ff403028e986a91296b6a2fb774749bcad2fde2254eac9809c953c89fe10f8fc": true, // moralis fallback
+	"5081dd1c929daa17e5d3a2e6e9a4a8e7ba68d896940a2fa06002908b0a39f069": true, // moralis fallback
+	"9dbdad6a9311fb9b5c258a95431aa2980bf0d11534858b656e5d545587a4387e": true, // moralis (legacy python)
+}
+
+// IsLeakedKey reports whether key is a credential known to have leaked
+func IsLeakedKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(key))
+	return leakedKeys[hex.EncodeToString(sum[:])]
+}
+
+// ShyftKey returns the Shyft API key from shyft_api_key, falling back to
+// the api_key parameter of the WebSocket URL
+func (c *Config) ShyftKey() string {
+	if c.ShyftAPIKey != "" {
+		return c.ShyftAPIKey
+	}
+	return urlAPIKey(c.WebSocketSettings.ShyftWSURL)
+}
+
+// ShyftRPCURL returns the Shyft RPC endpoint with the API key, or "" if no
+// key is configured
+func (c *Config) ShyftRPCURL() string {
+	key := c.ShyftKey()
+	if key == "" {
+		return ""
+	}
+	return ShyftRPCEndpoint + "?api_key=" + url.QueryEscape(key)
+}
+
+// FanOutRPCURL returns the RPC endpoint the fan-out engine fetches swaps
+// from, or "" if neither fanout_engine.rpc_url nor a Shyft key is set
+func (c *Config) FanOutRPCURL() string {
+	if c.FanOutEngine.RPCURL != "" {
+		return c.FanOutEngine.RPCURL
+	}
+	return c.ShyftRPCURL()
+}
+
+// ShyftWSURL returns the configured WebSocket URL with the API key added
+// when the URL doesn't carry one
+func (c *Config) ShyftWSURL() string {
+	raw := c.WebSocketSettings.ShyftWSURL
+	if raw == "" {
+		raw = ShyftWSEndpoint
+	}
+	if urlAPIKey(raw) != "" || c.ShyftAPIKey == "" {
+		return raw
+	}
+	sep := "?"
+	if strings.Contains(raw, "?") {
+		sep = "&"
+	}
+	return raw + sep + "api_key=" + url.QueryEscape(c.ShyftAPIKey)
+}
+
+// secretFields lists every credential with its config name, for leak and
+// placeholder checks
+func (c *Config) secretFields() map[string]string {
+	fields := map[string]string{
+		"moralis_api_key":                   c.MoralisAPIKey,
+		"birdeye_api_key":                   c.BirdeyeAPIKey,
+		"shyft_api_key":                     c.ShyftAPIKey,
+		"websocket_settings.shyft_ws_url":   urlAPIKey(c.WebSocketSettings.ShyftWSURL),
+		"trading_settings.jito_private_key": c.TradingSettings.JitoPrivateKey,
+		"payments.rpc_url":                  urlAPIKey(c.Payments.RPCURL),
+		"fanout_engine.rpc_url":             urlAPIKey(c.FanOutEngine.RPCURL),
+		"webhook.secret":                    c.Webhook.Secret,
+		"results_api.api_key":               c.ResultsAPI.APIKey,
+	}
+	for i, key := range c.MoralisFallbackKeys {
+		fields[fmt.Sprintf("moralis_fallback_keys[%d]", i)] = key
+	}
+	for i, backup := range c.WebSocketSettings.BackupWSURLs {
+		fields[fmt.Sprintf("websocket_settings.backup_ws_urls[%d]", i)] = urlAPIKey(backup)
+	}
+	return fields
+}
+
+// urlAPIKey extracts the api_key query parameter from raw
+func urlAPIKey(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("api_key")
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Sanity bounds for Validate
+const (
+	maxLogBufferSize = 1_000_000
+	maxShardCount    = 32
+	maxSlippageBps   = 10000
+)
+
+// ValidationError lists every problem found in a config, so all of them
+// can be fixed in one pass
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config (%d problems):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate checks that required settings are present and well-formed.
+// It returns a *ValidationError listing every problem, or nil.
+func (c *Config) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// API keys
+	switch c.APISettings.TokenSource {
+	case "", "birdeye", "moralis":
+	default:
+		addf("api_settings.token_source must be \"birdeye\" or \"moralis\", got %q", c.APISettings.TokenSource)
+	}
+	if c.MoralisAPIKey == "" && c.APISettings.TokenSource == "moralis" {
+		addf("moralis_api_key is required when api_settings.token_source is \"moralis\"")
+	}
+	if c.BirdeyeAPIKey == "" {
+		addf("birdeye_api_key is required")
+	}
+	if c.APISettings.MinTokenLiquidityUSD < 0 {
+		addf("api_settings.min_token_liquidity_usd must not be negative")
+	}
+	if c.APISettings.MoralisBaseURL != "" {
+		if err := checkURL(c.APISettings.MoralisBaseURL, "http", "https"); err != nil {
+			addf("api_settings.moralis_base_url: %v", err)
+		}
+	}
+	if c.APISettings.BirdeyeBaseURL != "" {
+		if err := checkURL(c.APISettings.BirdeyeBaseURL, "http", "https"); err != nil {
+			addf("api_settings.birdeye_base_url: %v", err)
+		}
+	}
+	minLiq, maxLiq := c.APISettings.BirdeyeMinLiquidity, c.APISettings.BirdeyeMaxLiquidity
+	if minLiq < 0 || maxLiq < 0 {
+		addf("api_settings.birdeye_min_liquidity and birdeye_max_liquidity must not be negative")
+	} else if maxLiq > 0 && maxLiq < minLiq {
+		addf("api_settings.birdeye_max_liquidity (%g) must be at least birdeye_min_liquidity (%g)", maxLiq, minLiq)
+	}
+	if c.APISettings.BirdeyeMinVolumeUSD < 0 {
+		addf("api_settings.birdeye_min_volume_usd must not be negative")
+	}
+	if sortBy := c.APISettings.BirdeyeSortBy; sortBy != "" && !slices.Contains(BirdeyeSortFields, sortBy) {
+		addf("api_settings.birdeye_sort_by must be one of %s, got %q", strings.Join(BirdeyeSortFields, ", "), sortBy)
+	}
+
+	// WebSocket
+	if err := checkURL(c.WebSocketSettings.ShyftWSURL, "ws", "wss"); err != nil {
+		addf("websocket_settings.shyft_ws_url: %v", err)
+	} else if c.ShyftKey() == "" {
+		addf("shyft_api_key is required (or pass api_key in websocket_settings.shyft_ws_url)")
+	}
+	for i, backup := range c.WebSocketSettings.BackupWSURLs {
+		if err := checkURL(backup, "ws", "wss"); err != nil {
+			addf("websocket_settings.backup_ws_urls[%d]: %v", i, err)
+		}
+	}
+	if n := c.WebSocketSettings.ShardCount; n < 1 || n > maxShardCount {
+		addf("websocket_settings.shard_count must be between 1 and %d, got %d", maxShardCount, n)
+	}
+
+	// Fan-out engine
+	if c.FanOutEngine.WorkerCount <= 0 {
+		addf("fanout_engine.worker_count must be positive, got %d", c.FanOutEngine.WorkerCount)
+	}
+	if n := c.FanOutEngine.LogBufferSize; n <= 0 || n > maxLogBufferSize {
+		addf("fanout_engine.log_buffer_size must be between 1 and %d, got %d", maxLogBufferSize, n)
+	}
+	switch c.FanOutEngine.DropPolicy {
+	case "", "drop_newest", "prefilter":
+	default:
+		addf("fanout_engine.drop_policy must be \"drop_newest\" or \"prefilter\", got %q", c.FanOutEngine.DropPolicy)
+	}
+	if r := c.FanOutEngine.HighWaterRatio; r < 0 || r > 1 {
+		addf("fanout_engine.high_water_ratio must be between 0 and 1, got %g", r)
+	}
+	if c.FanOutEngine.RPCURL != "" {
+		if err := checkURL(c.FanOutEngine.RPCURL, "http", "https"); err != nil {
+			addf("fanout_engine.rpc_url: %v", err)
+		}
+	}
+
+	// Trading
+	if c.TradingSettings.JitoBlockEngineURL != "" {
+		if err := checkURL(c.TradingSettings.JitoBlockEngineURL, "http", "https"); err != nil {
+			addf("trading_settings.jito_block_engine_url: %v", err)
+		}
+	} else if c.TradingSettings.JitoPrivateKey != "" {
+		addf("trading_settings.jito_block_engine_url is required when jito_private_key is set")
+	}
+	if c.TradingSettings.JitoTipLamports < 0 {
+		addf("trading_settings.jito_tip_lamports must not be negative")
+	}
+	if bps := c.TradingSettings.DefaultSlippageBps; bps < 0 || bps > maxSlippageBps {
+		addf("trading_settings.default_slippage_bps must be between 0 and %d, got %d", maxSlippageBps, bps)
+	}
+	if c.TradingSettings.MaxSlippageBps < c.TradingSettings.DefaultSlippageBps {
+		addf("trading_settings.max_slippage_bps (%d) must be >= default_slippage_bps (%d)",
+			c.TradingSettings.MaxSlippageBps, c.TradingSettings.DefaultSlippageBps)
+	}
+	if c.TradingSettings.ConfirmTimeoutSec < 0 {
+		addf("trading_settings.confirm_timeout_sec must be positive, got %d", c.TradingSettings.ConfirmTimeoutSec)
+	}
+
+	// Payments and copy trading
+	if c.Payments.TreasuryAddress != "" {
+		if err := validatePubkey(c.Payments.TreasuryAddress); err != nil {
+			addf("payments.treasury_address: %v", err)
+		}
+	}
+	if err := checkURL(c.Payments.RPCURL, "http", "https"); err != nil {
+		addf("payments.rpc_url: %v", err)
+	}
+	if c.CopyTrading.MaxLossSOL <= 0 || c.CopyTrading.WindowHours <= 0 {
+		addf("copy_trading.max_loss_sol and window_hours must be positive")
+	}
+	if c.CopyTrading.InactiveAfterHours < 0 {
+		addf("copy_trading.inactive_after_hours must not be negative")
+	}
+	for _, p := range c.Plans {
+		if p.MaxWalletsPerSearch < 0 {
+			addf("plans[%s].max_wallets_per_search must not be negative", p.ID)
+		}
+		if p.AnalyzerPages < 0 || p.AnalyzerPages > MaxAnalyzerPages {
+			addf("plans[%s].analyzer_pages must be between 0 and %d, got %d", p.ID, MaxAnalyzerPages, p.AnalyzerPages)
+		}
+		if p.ScanPriority < 0 {
+			addf("plans[%s].scan_priority must not be negative", p.ID)
+		}
+	}
+	if c.Wallets.MaxPerUser < 0 {
+		addf("wallets.max_per_user must be positive, got %d", c.Wallets.MaxPerUser)
+	}
+
+	// Scanned wallet windows
+	display, retention := c.ScanSettings.WalletDisplayHours, c.ScanSettings.WalletRetentionHours
+	if display < 0 || retention < 0 {
+		addf("scan_settings.wallet_display_hours and wallet_retention_hours must be positive")
+	} else if display > 0 && retention > 0 && retention < display {
+		addf("scan_settings.wallet_retention_hours (%d) must be at least wallet_display_hours (%d)", retention, display)
+	}
+	if c.ScanSettings.MaxCycleMinutes < 0 {
+		addf("scan_settings.max_cycle_minutes must be positive, got %d", c.ScanSettings.MaxCycleMinutes)
+	}
+
+	if c.Sessions.TTLMinutes < 0 {
+		addf("sessions.ttl_minutes must be positive, got %d", c.Sessions.TTLMinutes)
+	}
+
+	// Janitor
+	if c.Janitor.BatchSize < 0 || c.Janitor.IntervalSec < 0 || c.Janitor.MaxBatchesPerRun < 0 || c.Janitor.MaxBackoffSec < 0 {
+		addf("janitor.batch_size, interval_sec, max_batches_per_run and max_backoff_sec must be positive")
+	} else if b, i := c.Janitor.MaxBackoffSec, c.Janitor.IntervalSec; b > 0 && b < i {
+		addf("janitor.max_backoff_sec (%d) must be at least interval_sec (%d)", b, i)
+	}
+
+	// Ranking
+	for name, w := range c.Ranking.Profiles {
+		if w.Winrate < 0 || w.PnL < 0 || w.TradeCount < 0 || w.Recency < 0 {
+			addf("ranking.profiles[%s] weights must not be negative", name)
+		} else if w.Winrate+w.PnL+w.TradeCount+w.Recency == 0 {
+			addf("ranking.profiles[%s] needs at least one positive weight", name)
+		}
+	}
+	if p := c.Ranking.DefaultProfile; p != "" && len(c.Ranking.Profiles) > 0 {
+		if _, ok := c.Ranking.Profiles[p]; !ok {
+			addf("ranking.default_profile %q is not one of ranking.profiles", p)
+		}
+	}
+
+	// Analyzer
+	if c.Analyzer.Pages < 0 || c.Analyzer.Pages > MaxAnalyzerPages {
+		addf("analyzer.pages must be between 1 and %d, got %d", MaxAnalyzerPages, c.Analyzer.Pages)
+	}
+	if tmpl := c.Analyzer.WalletURLTemplate; tmpl != "" {
+		if n := strings.Count(tmpl, "%s"); n != 1 {
+			addf("analyzer.wallet_url_template must contain exactly one %%s placeholder, got %d", n)
+		} else if err := checkURL(strings.Replace(tmpl, "%s", "wallet", 1), "http", "https"); err != nil {
+			addf("analyzer.wallet_url_template: %v", err)
+		}
+	}
+
+	// Found-wallet webhook
+	if c.Webhook.URL != "" {
+		if err := checkURL(c.Webhook.URL, "http", "https"); err != nil {
+			addf("webhook.url: %v", err)
+		}
+		if c.Webhook.Secret == "" {
+			addf("webhook.secret is required when webhook.url is set")
+		}
+	}
+	if c.Webhook.MaxRetries < 0 {
+		addf("webhook.max_retries must not be negative")
+	}
+
+	// Results API
+	if addr := c.ResultsAPI.ListenAddr; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addf("results_api.listen_addr: %v", err)
+		}
+		if c.ResultsAPI.APIKey == "" {
+			addf("results_api.api_key is required when results_api.listen_addr is set")
+		}
+	}
+	if c.ResultsAPI.MaxPageSize < 0 {
+		addf("results_api.max_page_size must be positive, got %d", c.ResultsAPI.MaxPageSize)
+	}
+
+	// Credentials: refuse placeholders and keys that leaked in git history
+	secrets := c.secretFields()
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch value := secrets[name]; {
+		case strings.HasPrefix(value, placeholderPrefix):
+			addf("%s still holds the config.example.json placeholder", name)
+		case IsLeakedKey(value):
+			addf("%s is a known leaked key; rotate it and set the new one via %s variables", name, EnvPrefix)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkURL reports whether raw is an absolute URL with one of schemes
+func checkURL(raw string, schemes ...string) error {
+	if raw == "" {
+		return fmt.Errorf("is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("malformed URL: %v", err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host in %q", redactURL(u))
+	}
+	for _, s := range schemes {
+		if u.Scheme == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("scheme must be %s, got %q", strings.Join(schemes, " or "), u.Scheme)
+}
+
+// redactURL drops the query string, which often carries API keys
+func redactURL(u *url.URL) string {
+	clean := *u
+	clean.RawQuery = ""
+	return clean.String()
+}
@@ -7,18 +7,57 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/crypto/pbkdf2"
 )
 
+// KDF versions. Each encrypted wallet records the version it was sealed
+// with so parameters can be strengthened without breaking old wallets;
+// append new versions and never change the parameters of an existing one.
+const (
+	KDFPBKDF2   byte = 1 // PBKDF2-SHA256, 100k iterations; also wallets stored before versioning
+	KDFArgon2id byte = 2 // Argon2id, 3 passes, 64 MiB, 4 lanes
+
+	CurrentKDFVersion = KDFArgon2id
+)
+
+// Argon2id parameters for KDFArgon2id
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+)
+
+// Decryption failures
+var (
+	ErrInvalidPassword  = errors.New("invalid password")
+	ErrDecryptionFailed = errors.New("decryption failed")
+)
+
 // EncryptedWallet represents an encrypted private key
 type EncryptedWallet struct {
 	EncryptedKey []byte
 	Salt         []byte
 	Nonce        []byte
 	PasswordHash string
+	KDFVersion   byte // 0 is read as KDFPBKDF2
+}
+
+// NeedsUpgrade reports whether the wallet was sealed with older KDF
+// parameters and should be re-encrypted next time it is unlocked
+func (w *EncryptedWallet) NeedsUpgrade() bool {
+	return w.kdfVersion() < CurrentKDFVersion
+}
+
+func (w *EncryptedWallet) kdfVersion() byte {
+	if w.KDFVersion == 0 {
+		return KDFPBKDF2
+	}
+	return w.KDFVersion
 }
 
 // GenerateSalt creates a random salt
@@ -35,6 +74,19 @@ func DeriveKey(password string, salt []byte) []byte {
 	return pbkdf2.Key([]byte(password), salt, 100000, 32, sha256.New)
 }
 
+// DeriveKeyVersion derives an encryption key with the parameters of the
+// given KDF version
+func DeriveKeyVersion(version byte, password string, salt []byte) ([]byte, error) {
+	switch version {
+	case 0, KDFPBKDF2:
+		return DeriveKey(password, salt), nil
+	case KDFArgon2id:
+		return argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, 32), nil
+	default:
+		return nil, fmt.Errorf("unknown KDF version %d", version)
+	}
+}
+
 // HashPassword hashes a password using bcrypt
 func HashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -44,7 +96,8 @@ func HashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
-// VerifyPassword checks if password matches the hash
+// VerifyPassword checks if password matches the hash. bcrypt compares
+// the digests in constant time; never compare password hashes with ==.
 func VerifyPassword(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
@@ -59,7 +112,10 @@ func EncryptPrivateKey(privateKey string, password string) (*EncryptedWallet, er
 	}
 
 	// Derive encryption key from password
-	key := DeriveKey(password, salt)
+	key, err := DeriveKeyVersion(CurrentKDFVersion, password, salt)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create AES cipher
 	block, err := aes.NewCipher(key)
@@ -93,6 +149,7 @@ func EncryptPrivateKey(privateKey string, password string) (*EncryptedWallet, er
 		Salt:         salt,
 		Nonce:        nonce,
 		PasswordHash: passwordHash,
+		KDFVersion:   CurrentKDFVersion,
 	}, nil
 }
 
@@ -100,11 +157,14 @@ func EncryptPrivateKey(privateKey string, password string) (*EncryptedWallet, er
 func DecryptPrivateKey(encWallet *EncryptedWallet, password string) (string, error) {
 	// Verify password first
 	if !VerifyPassword(password, encWallet.PasswordHash) {
-		return "", errors.New("invalid password")
+		return "", ErrInvalidPassword
 	}
 
 	// Derive the same encryption key
-	key := DeriveKey(password, encWallet.Salt)
+	key, err := DeriveKeyVersion(encWallet.kdfVersion(), password, encWallet.Salt)
+	if err != nil {
+		return "", err
+	}
 
 	// Create AES cipher
 	block, err := aes.NewCipher(key)
@@ -121,7 +181,7 @@ func DecryptPrivateKey(encWallet *EncryptedWallet, password string) (string, err
 	// Decrypt
 	plaintext, err := gcm.Open(nil, encWallet.Nonce, encWallet.EncryptedKey, nil)
 	if err != nil {
-		return "", errors.New("decryption failed")
+		return "", ErrDecryptionFailed
 	}
 
 	return string(plaintext), nil
@@ -1,6 +1,9 @@
 package crypto
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
 	"testing"
 )
 
@@ -144,3 +147,66 @@ func TestBase64Encoding(t *testing.T) {
 
 	t.Log("✅ Base64 encoding/decoding working")
 }
+
+// sealLegacy encrypts the way wallets were stored before KDF versioning
+func sealLegacy(t *testing.T, plaintext, password string) *EncryptedWallet {
+	t.Helper()
+	salt, _ := GenerateSalt()
+	block, _ := aes.NewCipher(DeriveKey(password, salt))
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	hash, err := HashPassword(password)
+	if err != nil {
+		t.Fatalf("Password hashing failed: %v", err)
+	}
+	return &EncryptedWallet{
+		EncryptedKey: gcm.Seal(nil, nonce, []byte(plaintext), nil),
+		Salt:         salt,
+		Nonce:        nonce,
+		PasswordHash: hash,
+	}
+}
+
+func TestKDFVersions(t *testing.T) {
+	privateKey := "5JKCvJNKqH7Xz8p9YQW3kRvP2mF8nL6sX9wT4vB7cD1eG2fH3aJ4iK5lM6nO7pQ8rS9tU0vW1xY2zA3bC4dE5f"
+	password := "MySecurePassword123!"
+
+	t.Run("NewWalletsUseCurrentVersion", func(t *testing.T) {
+		encWallet, err := EncryptPrivateKey(privateKey, password)
+		if err != nil {
+			t.Fatalf("Encryption failed: %v", err)
+		}
+		if encWallet.KDFVersion != CurrentKDFVersion || encWallet.NeedsUpgrade() {
+			t.Errorf("Expected KDF version %d, got %d", CurrentKDFVersion, encWallet.KDFVersion)
+		}
+	})
+
+	t.Run("LegacyRecordDecrypts", func(t *testing.T) {
+		legacy := sealLegacy(t, privateKey, password)
+		if !legacy.NeedsUpgrade() {
+			t.Error("Unversioned wallet should need an upgrade")
+		}
+
+		decrypted, err := DecryptPrivateKey(legacy, password)
+		if err != nil {
+			t.Fatalf("Legacy decryption failed: %v", err)
+		}
+		if decrypted != privateKey {
+			t.Error("Decrypted key doesn't match original")
+		}
+	})
+
+	t.Run("VersionMismatchFails", func(t *testing.T) {
+		legacy := sealLegacy(t, privateKey, password)
+		legacy.KDFVersion = KDFArgon2id
+		if _, err := DecryptPrivateKey(legacy, password); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("Expected ErrDecryptionFailed, got %v", err)
+		}
+	})
+
+	t.Run("UnknownVersion", func(t *testing.T) {
+		if _, err := DeriveKeyVersion(99, password, []byte("salt")); err == nil {
+			t.Error("Expected an error for an unknown KDF version")
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package crypto_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"solana-orchestrator/crypto"
+	"solana-orchestrator/storage"
+)
+
+// TestWalletStorageRoundTrip tests encrypt → store → load → decrypt
+// through the real database encoding
+func TestWalletStorageRoundTrip(t *testing.T) {
+	privateKey := "5JKCvJNKqH7Xz8p9YQW3kRvP2mF8nL6sX9wT4vB7cD1eG2fH3aJ4iK5lM6nO7pQ8rS9tU0vW1xY2zA3bC4dE5f"
+	password := "MySecurePassword123!"
+
+	db, err := storage.New(filepath.Join(t.TempDir(), "roundtrip.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	encWallet, err := crypto.EncryptPrivateKey(privateKey, password)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	err = db.SaveEncryptedWallet(42, "pub", encWallet.EncryptedKey, encWallet.Salt, encWallet.Nonce,
+		encWallet.PasswordHash, "", int(encWallet.KDFVersion))
+	if err != nil {
+		t.Fatalf("SaveEncryptedWallet failed: %v", err)
+	}
+
+	load := func(t *testing.T) *crypto.EncryptedWallet {
+		secrets, err := db.GetEncryptedWalletForCrypto(42)
+		if err != nil {
+			t.Fatalf("GetEncryptedWalletForCrypto failed: %v", err)
+		}
+		return &crypto.EncryptedWallet{
+			EncryptedKey: secrets.EncryptedKey,
+			Salt:         secrets.Salt,
+			Nonce:        secrets.Nonce,
+			PasswordHash: secrets.PasswordHash,
+			KDFVersion:   byte(secrets.KDFVersion),
+		}
+	}
+
+	t.Run("CorrectPassword", func(t *testing.T) {
+		decrypted, err := crypto.DecryptPrivateKey(load(t), password)
+		if err != nil {
+			t.Fatalf("Decryption failed: %v", err)
+		}
+		if decrypted != privateKey {
+			t.Error("Decrypted key doesn't match original")
+		}
+	})
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		decrypted, err := crypto.DecryptPrivateKey(load(t), "WrongPassword")
+		if !errors.Is(err, crypto.ErrInvalidPassword) {
+			t.Errorf("Expected ErrInvalidPassword, got %v", err)
+		}
+		if decrypted != "" {
+			t.Error("Wrong password must not return key material")
+		}
+	})
+}
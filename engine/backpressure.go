@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"expvar"
+	"log"
+	"sync"
+	"time"
+)
+
+// Log queue drop policies
+const (
+	// DropPolicyNewest drops incoming messages only once the queue is full
+	DropPolicyNewest = "drop_newest"
+	// DropPolicyPrefilter additionally drops messages whose signer isn't
+	// monitored once the queue passes its high-water mark
+	DropPolicyPrefilter = "prefilter"
+)
+
+// Log queue defaults
+const (
+	DefaultHighWaterRatio = 0.8
+	dropWarnInterval      = 30 * time.Second
+)
+
+// logQueueStats publishes the current depth and capacity of each log queue
+// at /debug/vars; drop counts live in fanout_dropped_messages
+var logQueueStats = expvar.NewMap("fanout_log_queue")
+
+// logQueue is the buffered channel between the WebSocket listener and the
+// workers. Under pressure it sheds the messages least likely to matter and
+// warns, rate limited, while dropping persists.
+type logQueue struct {
+	ch        chan string
+	source    string
+	policy    string
+	highWater int
+	keep      func(raw string) bool // cheap relevance check for prefilter
+
+	depth    *expvar.Int
+	mu       sync.Mutex
+	lastWarn time.Time
+	dropped  int // since lastWarn
+}
+
+// newLogQueue creates a queue of size messages. keep reports whether a
+// message should survive prefiltering.
+func newLogQueue(source string, size int, policy string, highWaterRatio float64, keep func(string) bool) *logQueue {
+	if size <= 0 {
+		size = 1
+	}
+	if policy == "" {
+		policy = DropPolicyNewest
+	}
+	if highWaterRatio <= 0 || highWaterRatio > 1 {
+		highWaterRatio = DefaultHighWaterRatio
+	}
+
+	depth := new(expvar.Int)
+	logQueueStats.Set(source+":depth", depth)
+	capacity := new(expvar.Int)
+	capacity.Set(int64(size))
+	logQueueStats.Set(source+":capacity", capacity)
+
+	return &logQueue{
+		ch:        make(chan string, size),
+		source:    source,
+		policy:    policy,
+		highWater: int(float64(size) * highWaterRatio),
+		keep:      keep,
+		depth:     depth,
+	}
+}
+
+// Offer enqueues raw without blocking and reports whether it was kept
+func (q *logQueue) Offer(raw string) bool {
+	depth := len(q.ch)
+	q.depth.Set(int64(depth))
+
+	if q.policy == DropPolicyPrefilter && depth >= q.highWater && q.keep != nil && !q.keep(raw) {
+		q.drop("prefiltered", depth)
+		return false
+	}
+
+	select {
+	case q.ch <- raw:
+		return true
+	default:
+		q.drop("buffer_full", depth)
+		return false
+	}
+}
+
+// drop counts a shed message and warns at most once per interval
+func (q *logQueue) drop(reason string, depth int) {
+	droppedMessages.Add(q.source+":"+reason, 1)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dropped++
+	now := time.Now()
+	if now.Sub(q.lastWarn) < dropWarnInterval {
+		return
+	}
+	log.Printf("⚠️ WARN [%s] log queue under backpressure: %d messages dropped since last warning (depth %d/%d, policy %s)",
+		q.source, q.dropped, depth, cap(q.ch), q.policy)
+	q.lastWarn = now
+	q.dropped = 0
+}
+
+// Depth returns how many messages are waiting
+func (q *logQueue) Depth() int {
+	return len(q.ch)
+}
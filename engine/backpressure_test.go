@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogQueue(t *testing.T) {
+	keep := func(raw string) bool { return strings.HasPrefix(raw, "monitored") }
+
+	t.Run("PrefilterUnderPressure", func(t *testing.T) {
+		q := newLogQueue("test_prefilter", 4, DropPolicyPrefilter, 0.5, keep)
+
+		// Below the high-water mark everything is kept
+		for _, raw := range []string{"other1", "other2"} {
+			if !q.Offer(raw) {
+				t.Fatalf("Expected %s kept below high water", raw)
+			}
+		}
+
+		if q.Offer("other3") {
+			t.Error("Expected unmonitored message prefiltered at high water")
+		}
+		if !q.Offer("monitored1") || !q.Offer("monitored2") {
+			t.Fatal("Expected monitored messages kept while room remains")
+		}
+		if q.Offer("monitored3") {
+			t.Error("Expected drop once the queue is full")
+		}
+
+		if got := DroppedCount("test_prefilter", "prefiltered"); got != 1 {
+			t.Errorf("Expected 1 prefiltered drop, got %d", got)
+		}
+		if got := DroppedCount("test_prefilter", "buffer_full"); got != 1 {
+			t.Errorf("Expected 1 buffer_full drop, got %d", got)
+		}
+		if q.Depth() != 4 {
+			t.Errorf("Expected depth 4, got %d", q.Depth())
+		}
+	})
+
+	t.Run("DropNewestKeepsUntilFull", func(t *testing.T) {
+		q := newLogQueue("test_newest", 2, DropPolicyNewest, 0.5, keep)
+		if !q.Offer("other1") || !q.Offer("other2") {
+			t.Fatal("Expected messages kept until full")
+		}
+		if q.Offer("monitored1") {
+			t.Error("Expected drop once full")
+		}
+		if got := DroppedCount("test_newest", "prefiltered"); got != 0 {
+			t.Errorf("drop_newest should never prefilter, got %d", got)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"expvar"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dead-letter defaults
+const (
+	DefaultDeadLetterPerMinute = 10
+	deadLetterSampleLen        = 300
+)
+
+// droppedMessages counts unparseable messages by "source:reason" and is
+// published at /debug/vars when an HTTP server is running
+var droppedMessages = expvar.NewMap("fanout_dropped_messages")
+
+// secretPattern matches credentials that may appear in provider payloads
+var secretPattern = regexp.MustCompile(`(?i)(api[_-]?key|token|secret|password)(["=:\s]+)[^"&\s,}]+`)
+
+// DeadLetterLogger records a sampled subset of messages the fan-out
+// workers couldn't parse, so format mismatches are visible without
+// flooding the log under high volume
+type DeadLetterLogger struct {
+	source    string
+	perWindow int
+	window    time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	logged      int
+	suppressed  int
+}
+
+// NewDeadLetterLogger creates a logger that writes at most perMinute
+// samples per minute for source
+func NewDeadLetterLogger(source string, perMinute int) *DeadLetterLogger {
+	if perMinute <= 0 {
+		perMinute = DefaultDeadLetterPerMinute
+	}
+	return &DeadLetterLogger{
+		source:    source,
+		perWindow: perMinute,
+		window:    time.Minute,
+	}
+}
+
+// Record counts a dropped message and logs a sanitized sample if the
+// current window still has room. reason should be a short fixed string
+// so the metric keys stay bounded.
+func (d *DeadLetterLogger) Record(reason, raw string) {
+	droppedMessages.Add(d.source+":"+reason, 1)
+
+	d.mu.Lock()
+	now := time.Now()
+	if now.Sub(d.windowStart) >= d.window {
+		if d.suppressed > 0 {
+			log.Printf("🪦 [%s] %d unparseable messages suppressed in the last window", d.source, d.suppressed)
+		}
+		d.windowStart = now
+		d.logged = 0
+		d.suppressed = 0
+	}
+	if d.logged >= d.perWindow {
+		d.suppressed++
+		d.mu.Unlock()
+		return
+	}
+	d.logged++
+	d.mu.Unlock()
+
+	log.Printf("🪦 [%s] dropped message (%s): %s", d.source, reason, sanitizeSample(raw))
+}
+
+// DroppedCount returns how many messages source has dropped for reason
+func DroppedCount(source, reason string) int64 {
+	if v, ok := droppedMessages.Get(source + ":" + reason).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// sanitizeSample redacts credentials, flattens newlines and truncates a
+// raw message for logging
+func sanitizeSample(raw string) string {
+	s := secretPattern.ReplaceAllString(raw, "${1}${2}REDACTED")
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if len(s) > deadLetterSampleLen {
+		s = s[:deadLetterSampleLen] + "..."
+	}
+	return s
+}
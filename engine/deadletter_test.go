@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeadLetterLogger(t *testing.T) {
+	t.Run("CountsEveryDrop", func(t *testing.T) {
+		d := NewDeadLetterLogger("test_counts", 2)
+		for i := 0; i < 5; i++ {
+			d.Record("no_wallet", `{"method":"logsNotification"}`)
+		}
+		d.Record("swap_parse_error", `{}`)
+
+		if got := DroppedCount("test_counts", "no_wallet"); got != 5 {
+			t.Errorf("Expected 5 no_wallet drops, got %d", got)
+		}
+		if got := DroppedCount("test_counts", "swap_parse_error"); got != 1 {
+			t.Errorf("Expected 1 swap_parse_error drop, got %d", got)
+		}
+	})
+
+	t.Run("SamplesPerWindow", func(t *testing.T) {
+		d := NewDeadLetterLogger("test_sampling", 3)
+		for i := 0; i < 10; i++ {
+			d.Record("no_wallet", "raw")
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.logged != 3 || d.suppressed != 7 {
+			t.Errorf("Expected 3 logged and 7 suppressed, got %d and %d", d.logged, d.suppressed)
+		}
+	})
+
+	t.Run("SanitizesSample", func(t *testing.T) {
+		raw := `{"url":"wss://rpc.shyft.to?api_key=s3cr3tk3y","token":"abc"}` + "\n" + strings.Repeat("x", 1000)
+		got := sanitizeSample(raw)
+
+		if strings.Contains(got, "s3cr3tk3y") || strings.Contains(got, `"abc"`) {
+			t.Errorf("Credentials not redacted: %s", got)
+		}
+		if strings.Contains(got, "\n") {
+			t.Error("Newlines should be escaped")
+		}
+		if len(got) > deadLetterSampleLen+3 {
+			t.Errorf("Sample not truncated: %d bytes", len(got))
+		}
+	})
+}
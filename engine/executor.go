@@ -2,41 +2,70 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"solana-orchestrator/storage"
 	"solana-orchestrator/trading"
 
 	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
 )
 
-// ExecuteCopyTrade executes a copy trade for a user
-func ExecuteCopyTrade(ctx context.Context, db *storage.DB, userID int64, wallet *solana.PrivateKey, swapInfo *SwapInfo, copyAmount float64) error {
+// copyBuyFeeLamports is the fee estimate a copied buy keeps back on top of
+// the copier's SOL reserve
+const copyBuyFeeLamports = 1_000_000 // ~0.001 SOL
+
+// copyConfirmTimeout bounds waiting for a copied swap to land before its
+// executed amounts are booked
+const copyConfirmTimeout = 60 * time.Second
+
+// ErrBelowReserve is returned for buys that would take the wallet below
+// the user's minimum SOL reserve
+var ErrBelowReserve = errors.New("buy would spend the SOL reserve")
+
+// ExecuteCopyTrade executes a copy trade for a user. Buys are sized by
+// sizing from the target's parsed swap amounts. Balances are read and
+// plain RPC swaps sent through rpcURL, the configured fan-out RPC.
+func ExecuteCopyTrade(ctx context.Context, db *storage.DB, rpcURL string, userID int64, wallet *solana.PrivateKey, swapInfo *SwapInfo, sizing CopySizing) error {
 	// 1. Get user settings
 	settings, err := db.GetUserSettings(userID)
 	if err != nil {
 		return fmt.Errorf("failed to get settings: %w", err)
 	}
 
-	// 3. Determine trade direction
+	// 2. Determine trade direction
 	// If input is SOL, they are buying. If output is SOL, they are selling.
 	// Note: This assumes SOL is the quote token. For USDC pairs, logic might differ.
-	isBuy := swapInfo.InputMint == "So11111111111111111111111111111111111111112"
-	isSell := swapInfo.OutputMint == "So11111111111111111111111111111111111111112"
+	isBuy := swapInfo.IsBuy()
+	isSell := swapInfo.IsSell()
+
+	// 3. Respect the copier's choice of copying buys, sells or both
+	if isBuy || isSell {
+		target, err := db.GetCopyTarget(userID, swapInfo.Wallet)
+		if err != nil {
+			return fmt.Errorf("failed to get copy target: %w", err)
+		}
+		if target != nil && !target.CopiesSide(isBuy) {
+			return ErrSideNotCopied
+		}
+	}
 
 	var signature string
 	var tradeType string
 	var tokenAddr string
 	var solAmount float64
-	var tokenAmount float64
+	var tokenAmount uint64 // base units of tokenAddr
 
 	if isBuy {
 		tradeType = "buy"
 		tokenAddr = swapInfo.OutputMint
-		solAmount = copyAmount
+		solAmount = sizing.BuySOL(swapInfo)
 
 		// Execute Buy
-		signature, err = ExecuteBuy(ctx, wallet, tokenAddr, solAmount, settings)
+		signature, tokenAmount, err = ExecuteBuy(ctx, rpcURL, wallet, tokenAddr, solAmount, settings)
 	} else if isSell {
 		tradeType = "sell"
 		tokenAddr = swapInfo.InputMint
@@ -60,40 +89,105 @@ func ExecuteCopyTrade(ctx context.Context, db *storage.DB, userID int64, wallet
 		// I'll use 100% for now as a safe default for "exit position".
 
 		percentage := 100.0
-		signature, err = ExecuteSell(ctx, wallet, tokenAddr, percentage, settings)
+		signature, solAmount, tokenAmount, err = ExecuteSell(ctx, rpcURL, wallet, tokenAddr, percentage, settings)
 	} else {
 		return fmt.Errorf("neither buy nor sell (not SOL pair)")
 	}
 
 	if err != nil {
 		// Log failed trade
-		db.SaveTrade(userID, wallet.PublicKey().String(), "", tradeType, tokenAddr, solAmount, tokenAmount, 0, float64(settings.JitoTipLamports)/1e9, "failed")
+		db.SaveTrade(userID, wallet.PublicKey().String(), "", tradeType, tokenAddr, solAmount, float64(tokenAmount), 0, float64(settings.JitoTipLamports)/1e9, "failed")
 		return err
 	}
 
-	// Log successful trade
-	// We don't have the exact token amount or price yet, we'd get that from the confirmed tx.
-	// For now, log what we know.
-	err = db.SaveTrade(userID, wallet.PublicKey().String(), signature, tradeType, tokenAddr, solAmount, tokenAmount, 0, float64(settings.JitoTipLamports)/1e9, "pending")
+	// Log the submitted trade with the quoted amounts until it confirms
+	err = db.SaveTrade(userID, wallet.PublicKey().String(), signature, tradeType, tokenAddr, solAmount, float64(tokenAmount), pricePerToken(solAmount, tokenAmount), float64(settings.JitoTipLamports)/1e9, "pending")
+	if err != nil {
+		return err
+	}
 
-	return err
+	// The quote can differ from the fill within slippage, so the copied
+	// PnL is booked from what the wallet actually traded
+	executed, err := confirmedSwap(ctx, rpcURL, signature, wallet.PublicKey())
+	if err != nil {
+		return fmt.Errorf("copy trade %s not confirmed: %w", signature, err)
+	}
+	if err := db.UpdateTradeStatus(signature, "confirmed", time.Now().Unix()); err != nil {
+		return err
+	}
+	if isBuy {
+		solAmount, tokenAmount = float64(executed.InputAmount)/1e9, executed.OutputAmount
+	} else {
+		solAmount, tokenAmount = float64(executed.OutputAmount)/1e9, executed.InputAmount
+	}
+	return bookCopyTrade(db, userID, swapInfo.Wallet, isBuy, tokenAddr, solAmount, tokenAmount)
 }
 
-// ExecuteBuy executes a buy transaction
-func ExecuteBuy(ctx context.Context, wallet *solana.PrivateKey, tokenMint string, solAmount float64, settings *storage.UserSettings) (string, error) {
-	lamports := uint64(solAmount * 1e9)
+// confirmedSwap waits for a submitted copy swap to confirm and returns the
+// amounts the wallet actually traded
+func confirmedSwap(ctx context.Context, rpcURL, signature string, wallet solana.PublicKey) (*SwapInfo, error) {
+	sig, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, copyConfirmTimeout)
+	defer cancel()
+
+	client := rpc.New(rpcURL)
+	if _, err := trading.WaitForConfirmationUntilBlockHeight(ctx, client, sig, 0); err != nil {
+		return nil, err
+	}
+	return NewRPCSwapFetcher(client).FetchAndParseSwap(ctx, signature, wallet.String())
+}
+
+// bookCopyTrade books a copied trade against its target so the target's
+// copied PnL can be tracked, and registers the target's take-profit on
+// buys when it auto-sells
+func bookCopyTrade(db *storage.DB, userID int64, targetWallet string, isBuy bool, tokenAddr string, solAmount float64, tokenAmount uint64) error {
+	if isBuy {
+		if err := db.RecordCopyBuy(userID, targetWallet, tokenAddr, solAmount); err != nil {
+			return err
+		}
+		_, err := db.CreateCopyTakeProfit(userID, targetWallet, tokenAddr, solAmount, tokenAmount)
+		return err
+	}
+	if _, err := db.RecordCopySell(userID, targetWallet, tokenAddr, solAmount); err != nil && !errors.Is(err, storage.ErrNoCopyPosition) {
+		return err
+	}
+	return nil
+}
+
+// pricePerToken is the SOL paid or received per token base unit, 0 when
+// no tokens were quoted
+func pricePerToken(solAmount float64, tokenAmount uint64) float64 {
+	if tokenAmount == 0 {
+		return 0
+	}
+	return solAmount / float64(tokenAmount)
+}
+
+// ExecuteBuy executes a buy transaction and returns its signature and the
+// token base units the quote expects to receive
+func ExecuteBuy(ctx context.Context, rpcURL string, wallet *solana.PrivateKey, tokenMint string, solAmount float64, settings *storage.UserSettings) (string, uint64, error) {
+	lamports, err := trading.ToRawAmount(solAmount, trading.SOLDecimals)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := checkReserve(ctx, rpcURL, wallet.PublicKey(), lamports, settings); err != nil {
+		return "", 0, err
+	}
 
 	// Get Quote
 	quote, err := trading.GetBuyQuote(ctx, tokenMint, lamports, settings.SlippageBps)
 	if err != nil {
-		return "", fmt.Errorf("failed to get quote: %w", err)
+		return "", 0, fmt.Errorf("failed to get quote: %w", err)
 	}
 
 	// Get Swap Tx
 	// Note: GetSwapTransaction signature might need adjustment based on existing code
-	txResp, err := trading.GetSwapTransaction(ctx, quote, wallet.PublicKey().String(), settings.PriorityFeeLamports)
+	txResp, err := trading.GetSwapTransaction(ctx, quote, wallet.PublicKey().String(), priorityFee(ctx, rpcURL, tokenMint, settings))
 	if err != nil {
-		return "", fmt.Errorf("failed to get swap tx: %w", err)
+		return "", 0, fmt.Errorf("failed to get swap tx: %w", err)
 	}
 
 	// Sign and Submit via Jito
@@ -112,36 +206,50 @@ func ExecuteBuy(ctx context.Context, wallet *solana.PrivateKey, tokenMint string
 
 	tx, err := solana.TransactionFromBase64(txResp.SwapTransaction)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode tx: %w", err)
+		return "", 0, fmt.Errorf("failed to decode tx: %w", err)
 	}
 
-	tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
 		if key.Equals(wallet.PublicKey()) {
 			return wallet
 		}
 		return nil
-	})
+	}); err != nil {
+		return "", 0, fmt.Errorf("failed to sign tx: %w", err)
+	}
 
-	// Submit via Jito
-	// Create Jito client and submit bundle
-	jitoClient := trading.NewJitoClient("https://mainnet.block-engine.jito.wtf", uint64(settings.JitoTipLamports))
-	bundleResult, err := jitoClient.SubmitBundle(ctx, []solana.Transaction{*tx})
-	if err != nil {
-		return "", fmt.Errorf("failed to submit bundle: %w", err)
+	if _, err := submitSwap(ctx, rpcURL, tx, tokenMint, settings); err != nil {
+		return "", 0, err
 	}
 
-	return bundleResult.BundleID, nil
+	outAmount, _ := strconv.ParseUint(quote.OutAmount, 10, 64)
+	return tx.Signatures[0].String(), outAmount, nil
+}
+
+// checkReserve fails with ErrBelowReserve when buying lamports of SOL
+// would leave owner with less than the user's reserve after fees
+func checkReserve(ctx context.Context, rpcURL string, owner solana.PublicKey, lamports uint64, settings *storage.UserSettings) error {
+	balance, err := trading.NewBalanceManager(rpcURL, nil).GetSOLBalance(ctx, owner)
+	if err != nil {
+		return fmt.Errorf("failed to get SOL balance: %w", err)
+	}
+	if !settings.LeavesReserve(balance, lamports, copyBuyFeeLamports) {
+		return fmt.Errorf("%w: %.6f SOL of %.6f SOL, keeping %g SOL", ErrBelowReserve,
+			trading.FormatSOL(lamports), trading.FormatSOL(balance), settings.MinSOLReserve)
+	}
+	return nil
 }
 
-// ExecuteSell executes a sell transaction
-func ExecuteSell(ctx context.Context, wallet *solana.PrivateKey, tokenMint string, percentage float64, settings *storage.UserSettings) (string, error) {
+// ExecuteSell executes a sell transaction and returns its signature, the
+// SOL the quote expects to receive and the token base units sold
+func ExecuteSell(ctx context.Context, rpcURL string, wallet *solana.PrivateKey, tokenMint string, percentage float64, settings *storage.UserSettings) (string, float64, uint64, error) {
 	// Get Token Balance using BalanceManager
-	// For now, we'll create a minimal balance manager
+	// Without an API client balances come from getTokenAccountsByOwner
 	// In practice, these should be cached or passed from the engine
-	balanceMgr := trading.NewBalanceManager("", nil, nil)
+	balanceMgr := trading.NewBalanceManager(rpcURL, nil)
 	balances, err := balanceMgr.GetTokenBalances(ctx, wallet.PublicKey())
 	if err != nil {
-		return "", fmt.Errorf("failed to get balance: %w", err)
+		return "", 0, 0, fmt.Errorf("failed to get balance: %w", err)
 	}
 
 	// Find the token balance for the specified mint
@@ -154,7 +262,7 @@ func ExecuteSell(ctx context.Context, wallet *solana.PrivateKey, tokenMint strin
 	}
 
 	if balance == 0 {
-		return "", fmt.Errorf("no balance to sell")
+		return "", 0, 0, fmt.Errorf("no balance to sell")
 	}
 
 	sellAmount := uint64(float64(balance) * (percentage / 100.0))
@@ -162,38 +270,83 @@ func ExecuteSell(ctx context.Context, wallet *solana.PrivateKey, tokenMint strin
 	// Get Quote
 	quote, err := trading.GetSellQuote(ctx, tokenMint, sellAmount, settings.SlippageBps)
 	if err != nil {
-		return "", fmt.Errorf("failed to get quote: %w", err)
+		return "", 0, 0, fmt.Errorf("failed to get quote: %w", err)
 	}
 
 	// Get Swap Tx
-	txResp, err := trading.GetSwapTransaction(ctx, quote, wallet.PublicKey().String(), settings.PriorityFeeLamports)
+	txResp, err := trading.GetSwapTransaction(ctx, quote, wallet.PublicKey().String(), priorityFee(ctx, rpcURL, tokenMint, settings))
 	if err != nil {
-		return "", fmt.Errorf("failed to get swap tx: %w", err)
+		return "", 0, 0, fmt.Errorf("failed to get swap tx: %w", err)
 	}
 
 	// Decode and Sign
 	tx, err := solana.TransactionFromBase64(txResp.SwapTransaction)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode tx: %w", err)
+		return "", 0, 0, fmt.Errorf("failed to decode tx: %w", err)
 	}
 
-	tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
 		if key.Equals(wallet.PublicKey()) {
 			return wallet
 		}
 		return nil
-	})
+	}); err != nil {
+		return "", 0, 0, fmt.Errorf("failed to sign tx: %w", err)
+	}
 
-	// Submit via Jito
-	jitoClient := trading.NewJitoClient("https://mainnet.block-engine.jito.wtf", uint64(settings.JitoTipLamports))
+	if _, err := submitSwap(ctx, rpcURL, tx, tokenMint, settings); err != nil {
+		return "", 0, 0, err
+	}
+
+	outLamports, _ := strconv.ParseUint(quote.OutAmount, 10, 64)
+	return tx.Signatures[0].String(), float64(outLamports) / 1e9, sellAmount, nil
+}
+
+// submitSwap sends a signed swap as a Jito bundle or through the RPC, as
+// the user's execution mode picks for tokenMint, and returns the bundle
+// ID or the transaction signature
+func submitSwap(ctx context.Context, rpcURL string, tx *solana.Transaction, tokenMint string, settings *storage.UserSettings) (string, error) {
+	if !swapUsesJito(ctx, tokenMint, settings) {
+		sig, err := rpc.New(rpcURL).SendTransaction(ctx, tx)
+		if err != nil {
+			return "", fmt.Errorf("failed to send transaction: %w", err)
+		}
+		return sig.String(), nil
+	}
+
+	jitoClient := trading.NewJitoClient("https://mainnet.block-engine.jito.wtf", settings.JitoTip())
 	bundleResult, err := jitoClient.SubmitBundle(ctx, []solana.Transaction{*tx})
 	if err != nil {
 		return "", fmt.Errorf("failed to submit bundle: %w", err)
 	}
-
 	return bundleResult.BundleID, nil
 }
 
+// priorityFee resolves the user's priority fee for a swap of tokenMint,
+// estimating it from recent fees on the mint when set to auto
+func priorityFee(ctx context.Context, rpcURL, tokenMint string, settings *storage.UserSettings) int64 {
+	mint, err := solana.PublicKeyFromBase58(tokenMint)
+	if err != nil {
+		return settings.PriorityFeeLamports
+	}
+	return trading.ResolvePriorityFee(ctx, rpc.New(rpcURL), settings.PriorityFeeLamports, []solana.PublicKey{mint})
+}
+
+// swapUsesJito applies the user's execution mode, looking up the token's
+// liquidity and 1h move only in auto mode. Tokens DexScreener doesn't
+// know count as thin.
+func swapUsesJito(ctx context.Context, tokenMint string, settings *storage.UserSettings) bool {
+	switch settings.ExecutionMode {
+	case storage.ExecutionJito, storage.ExecutionRPC:
+		return settings.UseJito(0, 0)
+	}
+	info, err := trading.GetTokenInfo(ctx, tokenMint)
+	if err != nil || info == nil {
+		return settings.UseJito(0, 0)
+	}
+	return settings.UseJito(info.Liquidity, info.Change1h)
+}
+
 // CheckAndExecuteSnipe checks if a new pool matches criteria and executes snipe
 func CheckAndExecuteSnipe(ctx context.Context, db *storage.DB, poolInfo *PoolInfo) error {
 	// 1. Check filters (liquidity, etc.)
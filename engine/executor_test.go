@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+
+	"solana-orchestrator/storage"
+)
+
+// TestCopyBuyTakeProfit tests that booking a copied buy registers the
+// target's take-profit, and that sells and plain targets don't
+func TestCopyBuyTakeProfit(t *testing.T) {
+	db, err := storage.New(filepath.Join(t.TempDir(), "copybuy.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	const user = int64(7)
+	db.AddCopyTarget(user, "tpWallet", 0.5)
+	db.AddCopyTarget(user, "plainWallet", 0.5)
+	if err := db.SetCopyTargetAutoSell(user, "tpWallet", 100, 2); err != nil {
+		t.Fatalf("SetCopyTargetAutoSell failed: %v", err)
+	}
+
+	if err := bookCopyTrade(db, user, "plainWallet", true, "mintA", 0.5, 1_000_000); err != nil {
+		t.Fatalf("bookCopyTrade failed: %v", err)
+	}
+	if err := bookCopyTrade(db, user, "tpWallet", true, "mintB", 0.5, 1_000_000); err != nil {
+		t.Fatalf("bookCopyTrade failed: %v", err)
+	}
+	if err := bookCopyTrade(db, user, "tpWallet", false, "mintB", 0.6, 1_000_000); err != nil {
+		t.Fatalf("bookCopyTrade failed for a sell: %v", err)
+	}
+
+	triggers, err := db.GetPendingTakeProfits(user)
+	if err != nil || len(triggers) != 1 {
+		t.Fatalf("Expected one take-profit, got %v (%v)", triggers, err)
+	}
+	tp := triggers[0]
+	if tp.TargetWallet != "tpWallet" || tp.TokenAddress != "mintB" || tp.TokenAmount != 1_000_000 || tp.TriggerPriceSOL != 1e-6 {
+		t.Errorf("Expected all of mintB sold at 2x, got %+v", tp)
+	}
+}
@@ -2,9 +2,12 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/redis/go-redis/v9"
@@ -15,38 +18,123 @@ import (
 	"solana-orchestrator/trading"
 )
 
-type FanOutEngine struct {
-	db  *storage.DB
-	bot *tgbotapi.BotAPI
-	rdb *redis.Client
-	cfg *config.Config
-	ws  *trading.WSClient
+// programsSubscriptionKey names the single transaction subscription that
+// covers every watched DEX program
+const programsSubscriptionKey = "fanout_programs"
+
+// Source streams transaction notifications for a set of accounts
+type Source interface {
+	Connect(ctx context.Context) error
+	SubscribeTransactions(ctx context.Context, key string, accounts []string) (<-chan interface{}, error)
+	Close() error
+}
+
+// ShardedSource is a Source that reports per-connection health
+type ShardedSource interface {
+	Source
+	Status() []trading.ShardStatus
+}
+
+// WalletIndex answers which wallets are copied and by whom
+type WalletIndex interface {
+	Sync(ctx context.Context, targets []*storage.CopyTradeTarget) error
+	IsMonitored(ctx context.Context, wallet string) (bool, error)
+	Owners(ctx context.Context, wallet string) (map[int64]float64, error)
+	Reconcile(ctx context.Context, targets []*storage.CopyTradeTarget) (*ReconcileReport, error)
+}
+
+// SwapFetcher resolves a transaction signature into the swap wallet made
+type SwapFetcher interface {
+	FetchAndParseSwap(ctx context.Context, signature, wallet string) (*SwapInfo, error)
+}
 
-	logChan          chan string
+// TargetStore lists the copy targets to monitor and who copies a wallet,
+// and records when each target last swapped
+type TargetStore interface {
+	GetAllActiveCopyTargets() ([]*storage.CopyTradeTarget, error)
+	GetUsersWatchingWallet(wallet string) ([]*storage.CopyTradeTarget, error)
+	UpdateTargetLastActivity(targetWallet string, at int64) error
+}
+
+// Sender delivers Telegram messages
+type Sender interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+}
+
+type FanOutEngine struct {
+	db     TargetStore
+	sender Sender
+	cfg    *config.Config
+	source Source
+	index  WalletIndex
+	swaps  SwapFetcher
+	guard  *PerformanceGuard
+	gate   TradingGate       // nil means trading is always on
+	prefs  NotificationPrefs // nil sends every notification at once
+	paper  PaperLedger       // nil drops paper trades
+
+	deadLetters *DeadLetterLogger
+	limiter     *ExecutionLimiter
+	// execute runs one user's copy of a swap; it defaults to notifyCopyTrade
+	execute func(ctx context.Context, userID int64, copyAmount float64, swap *SwapInfo)
+
+	logs             *logQueue
 	notificationChan chan Notification
+	notifyRate       *rate.Limiter // shared by live notifications and digests
 	stopChan         chan struct{}
+	stopOnce         sync.Once
 	wg               sync.WaitGroup
 
-	monitoredCount int
-	mu             sync.RWMutex
+	monitored map[string]bool // local copy of the index for prefiltering
+	mu        sync.RWMutex
 }
 
 type Notification struct {
 	UserID  int64
+	Kind    NotificationKind
 	Message string
 }
 
-func NewFanOutEngine(db *storage.DB, bot *tgbotapi.BotAPI, rdb *redis.Client, cfg *config.Config) *FanOutEngine {
-	return &FanOutEngine{
-		db:               db,
-		bot:              bot,
-		rdb:              rdb,
-		cfg:              cfg,
-		ws:               trading.NewWSClient(cfg.WebSocketSettings.ShyftWSURL),
-		logChan:          make(chan string, cfg.FanOutEngine.LogBufferSize),
+// NewFanOutEngine wires the engine to the Shyft WebSocket, Redis and RPC
+func NewFanOutEngine(db *storage.DB, bot *tgbotapi.BotAPI, rdb *redis.Client, cfg *config.Config) (*FanOutEngine, error) {
+	rpcURL := cfg.FanOutRPCURL()
+	if rpcURL == "" {
+		return nil, errors.New("fan-out engine has no RPC endpoint: set fanout_engine.rpc_url or shyft_api_key")
+	}
+
+	ws := cfg.WebSocketSettings
+	urls := append([]string{cfg.ShyftWSURL()}, ws.BackupWSURLs...)
+	source := trading.NewWSPool(urls, ws.ShardCount, time.Duration(ws.FailoverAfterMs)*time.Millisecond)
+
+	e := newFanOutEngine(cfg, db, bot, source,
+		NewRedisWalletIndex(rdb),
+		newDefaultSwapFetcher(rpcURL))
+	e.guard = NewPerformanceGuard(db, cfg.CopyTrading)
+	e.gate = NewKillSwitch(rdb)
+	e.prefs = db
+	e.paper = db
+	return e, nil
+}
+
+func newFanOutEngine(cfg *config.Config, db TargetStore, sender Sender, source Source, index WalletIndex, swaps SwapFetcher) *FanOutEngine {
+	e := &FanOutEngine{
+		db:          db,
+		sender:      sender,
+		cfg:         cfg,
+		source:      source,
+		index:       index,
+		swaps:       swaps,
+		deadLetters: NewDeadLetterLogger("fanout", cfg.FanOutEngine.DeadLetterPerMinute),
+		limiter: NewExecutionLimiter(cfg.FanOutEngine.MaxConcurrentExecutions, cfg.FanOutEngine.MaxExecutionsPerUser,
+			time.Duration(cfg.FanOutEngine.ExecutionQueueTimeoutMs)*time.Millisecond),
 		notificationChan: make(chan Notification, 10000),
+		notifyRate:       rate.NewLimiter(25, 1), // 25 msgs/sec
 		stopChan:         make(chan struct{}),
 	}
+	e.logs = newLogQueue("fanout", cfg.FanOutEngine.LogBufferSize, cfg.FanOutEngine.DropPolicy,
+		cfg.FanOutEngine.HighWaterRatio, e.mentionsMonitored)
+	e.execute = e.notifyCopyTrade
+	return e
 }
 
 func (e *FanOutEngine) Start() {
@@ -66,14 +154,30 @@ func (e *FanOutEngine) Start() {
 	// 3. Start Notification Worker
 	e.wg.Add(1)
 	go e.notificationWorker()
+	if e.prefs != nil {
+		e.wg.Add(1)
+		go e.digestLoop()
+	}
 
 	// 4. Start WebSocket Listener
 	e.wg.Add(1)
 	go e.StartShyftListener()
+
+	// 5. Start the copy target performance guard
+	if e.guard != nil && e.cfg.CopyTrading.AutoDisable {
+		e.wg.Add(1)
+		go e.performanceLoop()
+	}
+
+	// 6. Start Redis reconciliation
+	if interval := e.cfg.FanOutEngine.ReconcileIntervalSec; interval > 0 {
+		e.wg.Add(1)
+		go e.reconcileLoop(time.Duration(interval) * time.Second)
+	}
 }
 
 func (e *FanOutEngine) Shutdown() {
-	close(e.stopChan)
+	e.stopOnce.Do(func() { close(e.stopChan) })
 	e.wg.Wait()
 	log.Println("Fan-Out Engine stopped")
 }
@@ -87,10 +191,37 @@ func (e *FanOutEngine) IsRunning() bool {
 	}
 }
 
+// ConnectionStatus reports the source's per-shard health, or nil if the
+// source isn't sharded
+func (e *FanOutEngine) ConnectionStatus() []trading.ShardStatus {
+	if sharded, ok := e.source.(ShardedSource); ok {
+		return sharded.Status()
+	}
+	return nil
+}
+
 func (e *FanOutEngine) GetMonitoredCount() int {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
-	return e.monitoredCount
+	return len(e.monitored)
+}
+
+// LogQueueDepth returns how many messages are waiting for a worker
+func (e *FanOutEngine) LogQueueDepth() int {
+	return e.logs.Depth()
+}
+
+// mentionsMonitored is the cheap pre-enqueue check used under
+// backpressure: it parses only the signer and consults the local wallet
+// set instead of Redis
+func (e *FanOutEngine) mentionsMonitored(raw string) bool {
+	note, err := ParseTransactionNotification(raw)
+	if err != nil {
+		return false
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.monitored[note.Wallet]
 }
 
 func (e *FanOutEngine) SyncMonitoredWallets() error {
@@ -103,7 +234,7 @@ func (e *FanOutEngine) SyncMonitoredWallets() error {
 	}
 
 	// Sync to Redis
-	if err := SyncWalletsToRedis(ctx, e.rdb, targets); err != nil {
+	if err := e.index.Sync(ctx, targets); err != nil {
 		return fmt.Errorf("failed to sync to redis: %w", err)
 	}
 
@@ -114,79 +245,127 @@ func (e *FanOutEngine) SyncMonitoredWallets() error {
 	}
 
 	e.mu.Lock()
-	e.monitoredCount = len(uniqueWallets)
+	e.monitored = uniqueWallets
 	e.mu.Unlock()
 
 	return nil
 }
 
+// ReconcileMonitoredWallets re-syncs the wallet index from the DB, removing
+// stale entries and adding missing ones, and logs any drift it repaired
+func (e *FanOutEngine) ReconcileMonitoredWallets() (*ReconcileReport, error) {
+	ctx := context.Background()
+
+	targets, err := e.db.GetAllActiveCopyTargets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch targets: %w", err)
+	}
+
+	report, err := e.index.Reconcile(ctx, targets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile redis: %w", err)
+	}
+
+	uniqueWallets := make(map[string]bool)
+	for _, t := range targets {
+		uniqueWallets[t.TargetWallet] = true
+	}
+
+	e.mu.Lock()
+	previous := len(e.monitored)
+	e.monitored = uniqueWallets
+	e.mu.Unlock()
+
+	if report.HasDrift() || previous != len(uniqueWallets) {
+		log.Printf("🔄 Wallet index drift repaired: %d added %v, %d removed %v, %d owner entries fixed, monitored %d → %d",
+			len(report.Added), report.Added, len(report.Removed), report.Removed,
+			report.OwnersFixed, previous, len(uniqueWallets))
+	}
+	return report, nil
+}
+
+// reconcileLoop periodically reconciles the wallet index, so a flushed
+// Redis or targets changed by another instance don't go unnoticed
+func (e *FanOutEngine) reconcileLoop(interval time.Duration) {
+	defer e.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case <-ticker.C:
+			if _, err := e.ReconcileMonitoredWallets(); err != nil {
+				log.Printf("Error reconciling monitored wallets: %v", err)
+			}
+		}
+	}
+}
+
 func (e *FanOutEngine) StartShyftListener() {
 	defer e.wg.Done()
+	defer e.source.Close()
 
-	// Connect using existing WSClient
-	if err := e.ws.Connect(context.Background()); err != nil {
+	ctx := context.Background()
+	if err := e.source.Connect(ctx); err != nil {
 		log.Printf("Failed to connect WS: %v", err)
-		// Retry logic could go here, but WSClient handles some reconnection
+		<-e.stopChan
+		return
 	}
 
-	// Subscribe to programs
-	programs := []string{
-		e.cfg.Programs.JupiterLimitOrder,
-		e.cfg.Programs.RaydiumAMMV4,
+	// One transactionSubscribe covering all programs; notifications carry
+	// the account keys, so the signer can be matched without extra RPC
+	programs := e.cfg.EnabledPrograms()
+	if len(programs) == 0 {
+		log.Printf("No program subscriptions enabled, fan-out listener idle")
+		<-e.stopChan
+		return
 	}
-	if e.cfg.Programs.RaydiumCLMM != "" {
-		programs = append(programs, e.cfg.Programs.RaydiumCLMM)
+	log.Printf("📡 Watching %d programs for copy-target swaps", len(programs))
+
+	sub, err := e.source.SubscribeTransactions(ctx, programsSubscriptionKey, programs)
+	if err != nil {
+		log.Printf("Failed to subscribe to programs: %v", err)
+		<-e.stopChan
+		return
 	}
 
-	for _, prog := range programs {
-		if prog == "" {
-			continue
-		}
-		sub, err := e.ws.SubscribeProgramLogs(context.Background(), prog)
-		if err != nil {
-			log.Printf("Failed to subscribe to %s: %v", prog, err)
-			continue
-		}
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case msg, ok := <-sub:
+			if !ok {
+				<-e.stopChan
+				return
+			}
 
-		// Forward logs to logChan
-		e.wg.Add(1)
-		go func(ch <-chan interface{}) {
-			defer e.wg.Done()
-			for msg := range ch {
-				// Convert to string (assuming WSClient returns string or byte slice)
-				// WSClient returns interface{}, usually map[string]interface{} or string
-				// We need to ensure we pass the raw log string to parser
-				// If WSClient returns parsed JSON, we might need to marshal it back or adjust parser.
-				// Assuming WSClient returns the raw message or we can cast.
-				// Actually, `SubscribeProgramLogs` in `websocket.go` returns `<-chan interface{}`.
-				// Let's assume it sends the raw message string or bytes.
-
-				strMsg, ok := msg.(string)
-				if !ok {
-					// Try bytes
-					if b, ok := msg.([]byte); ok {
-						strMsg = string(b)
-					} else {
-						// Try fmt
-						strMsg = fmt.Sprintf("%v", msg)
-					}
-				}
-
-				select {
-				case e.logChan <- strMsg:
-				default:
-					// Drop
-				}
-
-				if !e.IsRunning() {
-					return
-				}
+			raw, err := messageString(msg)
+			if err != nil {
+				e.deadLetters.Record("encode_error", fmt.Sprintf("%v", msg))
+				continue
 			}
-		}(sub)
+
+			e.logs.Offer(raw)
+		}
 	}
+}
 
-	<-e.stopChan
-	e.ws.Close()
+// messageString turns a subscription message back into its JSON text
+func messageString(msg interface{}) (string, error) {
+	switch m := msg.(type) {
+	case string:
+		return m, nil
+	case []byte:
+		return string(m), nil
+	default:
+		b, err := json.Marshal(m)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
 }
 
 func (e *FanOutEngine) worker(id int) {
@@ -197,69 +376,176 @@ func (e *FanOutEngine) worker(id int) {
 		select {
 		case <-e.stopChan:
 			return
-		case rawLog := <-e.logChan:
-			// 1. Extract wallet (fast path)
-			// Note: For program logs, we might not get the wallet directly in the top level.
-			// But assuming we do or we parse it:
-			wallet, err := ParseLogForWallet(rawLog)
-			if err != nil || wallet == "" {
+		case rawLog := <-e.logs.ch:
+			// 1. Extract signer (fast path, no RPC)
+			note, err := ParseTransactionNotification(rawLog)
+			if errors.Is(err, ErrNotNotification) {
+				continue
+			}
+			if err != nil {
+				e.deadLetters.Record(err.Error(), rawLog)
+				continue
+			}
+			if note.Failed {
 				continue
 			}
 
 			// 2. Check Redis
-			isMember, err := e.rdb.SIsMember(ctx, "monitored_wallets", wallet).Result()
+			isMember, err := e.index.IsMonitored(ctx, note.Wallet)
 			if err != nil || !isMember {
 				continue
 			}
 
 			// 3. Process Match
-			e.processMatch(ctx, wallet, rawLog)
+			e.processMatch(ctx, note)
 		}
 	}
 }
 
-func (e *FanOutEngine) processMatch(ctx context.Context, wallet string, rawLog string) {
+func (e *FanOutEngine) processMatch(ctx context.Context, note *TxNotification) {
+	// Copy trades are dropped, not queued, while the kill switch is off
+	if e.gate != nil && !e.gate.TradingEnabled(ctx) {
+		e.deadLetters.Record("trading_disabled", note.Signature)
+		return
+	}
+
 	// 1. Get Users
-	owners, err := GetWalletOwners(ctx, e.rdb, wallet)
+	owners, err := e.index.Owners(ctx, note.Wallet)
 	if err != nil || len(owners) == 0 {
 		return
 	}
 
-	// 2. Parse Transaction
-	swapInfo, err := ParseSwapInstruction(rawLog)
+	// 2. Fetch and parse the confirmed transaction
+	swapInfo, err := e.swaps.FetchAndParseSwap(ctx, note.Signature, note.Wallet)
+	if errors.Is(err, ErrNotSwap) {
+		return
+	}
 	if err != nil {
+		e.deadLetters.Record("swap_fetch_error", note.Signature+": "+err.Error())
 		return
 	}
+	e.recordActivity(swapInfo)
 
-	// 3. Execute for each user
-	for userID, copyAmount := range owners {
-		go func(uid int64, amt float64) {
-			// We cannot execute trade without password.
-			// Send alert instead.
-			e.notificationChan <- Notification{
-				UserID:  uid,
-				Message: fmt.Sprintf("🔔 Copy Trade Triggered!\nTarget: %s\nTx: %s\n\n(Auto-trade disabled: Wallet locked)", wallet, swapInfo.Signature),
+	// 3. Drop users who don't copy this side or size of trade, and set
+	// aside those only paper trading the target
+	owners, paper, err := e.copiersOf(note.Wallet, swapInfo, owners)
+	if err != nil {
+		e.deadLetters.Record("target_lookup_error", note.Signature+": "+err.Error())
+		return
+	}
+	for userID, amount := range paper {
+		e.paperTrade(ctx, userID, amount, swapInfo)
+	}
+
+	// 4. Execute for each user, bounded globally and per user. Slots that
+	// can't be had in time are shed and counted in copytrade_executions.
+	// Each user waits for a slot on their own goroutine, so one at their
+	// limit doesn't hold up the others or the worker.
+	for userID, amount := range owners {
+		e.wg.Add(1)
+		go func(userID int64, amount float64) {
+			defer e.wg.Done()
+			release, err := e.limiter.Acquire(ctx, userID, swapInfo.Signature)
+			if err != nil {
+				return
 			}
+			defer release()
+			e.execute(ctx, userID, amount, swapInfo)
+		}(userID, amount)
+	}
+}
 
-			// If we had the password (e.g. session cache), we would:
-			// 1. Decrypt wallet
-			// 2. ExecuteCopyTrade(ctx, e.db, uid, privKey, swapInfo, amt)
-		}(userID, copyAmount)
+// recordActivity marks the swap's wallet as active at the swap's block
+// time, or now if the transaction had none
+func (e *FanOutEngine) recordActivity(swap *SwapInfo) {
+	at := swap.Timestamp
+	if at == 0 {
+		at = time.Now().Unix()
+	}
+	if err := e.db.UpdateTargetLastActivity(swap.Wallet, at); err != nil {
+		log.Printf("⚠️ Failed to record activity for %s: %v", swap.Wallet, err)
+	}
+}
+
+// copiersOf keeps the owners whose target copies the swap's side and
+// whose minimum target trade it meets, split into live copiers and those
+// paper trading the target. Swaps without a SOL side, such as token to
+// token, can't be sided or valued: they are left to the executor for live
+// copiers and skipped on paper. Owners missing from the DB, which the
+// index will drop on its next reconcile, are skipped.
+func (e *FanOutEngine) copiersOf(wallet string, swap *SwapInfo, owners map[int64]float64) (live, paper map[int64]float64, err error) {
+	targets, err := e.db.GetUsersWatchingWallet(wallet)
+	if err != nil {
+		return nil, nil, err
+	}
+	byUser := make(map[int64]*storage.CopyTradeTarget, len(targets))
+	for _, t := range targets {
+		byUser[t.UserID] = t
+	}
+
+	sided := swap.IsBuy() || swap.IsSell()
+	solAmount := swap.SOLAmount()
+	live = make(map[int64]float64, len(owners))
+	paper = make(map[int64]float64)
+	for userID, amount := range owners {
+		t := byUser[userID]
+		switch {
+		case !sided:
+			if t == nil || !t.Paper {
+				live[userID] = amount
+			}
+		case t == nil || !t.CopiesSide(swap.IsBuy()) || !t.MeetsMinimum(solAmount):
+		case t.Paper:
+			paper[userID] = amount
+		default:
+			live[userID] = amount
+		}
+	}
+	return live, paper, nil
+}
+
+// tokenLabel shows a mint as "SYMBOL (mint)", or just the mint when its
+// symbol can't be resolved
+func tokenLabel(ctx context.Context, mint string) string {
+	symbol, err := trading.ResolveSymbol(ctx, mint)
+	if err != nil || symbol == "" {
+		return mint
+	}
+	return fmt.Sprintf("%s (%s)", symbol, mint)
+}
+
+// notifyCopyTrade alerts a user that a target they copy traded. We cannot
+// execute trades without the wallet password; with a session cache we
+// would decrypt the wallet and call ExecuteCopyTrade(ctx, e.db,
+// e.cfg.FanOutRPCURL(), uid, privKey, swapInfo, FixedCopySizing(amt)).
+// performanceLoop then checks the target once the copied sell is booked.
+func (e *FanOutEngine) notifyCopyTrade(ctx context.Context, userID int64, copyAmount float64, swapInfo *SwapInfo) {
+	note := Notification{
+		UserID: userID,
+		Kind:   NoticeSwap,
+		Message: fmt.Sprintf("🔔 Copy Trade Triggered!\nTarget: %s\nSwap: %s → %s\nTx: %s\n\n(Auto-trade disabled: Wallet locked)",
+			swapInfo.Wallet, tokenLabel(ctx, swapInfo.InputMint), tokenLabel(ctx, swapInfo.OutputMint), swapInfo.Signature),
+	}
+	select {
+	case e.notificationChan <- note:
+	case <-e.stopChan:
 	}
 }
 
 func (e *FanOutEngine) notificationWorker() {
 	defer e.wg.Done()
-	limiter := rate.NewLimiter(25, 1) // 25 msgs/sec
 
 	for {
 		select {
 		case <-e.stopChan:
 			return
 		case note := <-e.notificationChan:
-			limiter.Wait(context.Background())
+			if !e.routeNote(note, time.Now()) {
+				continue
+			}
+			e.notifyRate.Wait(context.Background())
 			msg := tgbotapi.NewMessage(note.UserID, note.Message)
-			e.bot.Send(msg)
+			e.sender.Send(msg)
 		}
 	}
 }
@@ -0,0 +1,426 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/storage"
+)
+
+// fakeSource is an in-memory WebSocket source
+type fakeSource struct {
+	ch       chan interface{}
+	accounts []string
+	closed   bool
+	mu       sync.Mutex
+}
+
+func (f *fakeSource) Connect(ctx context.Context) error { return nil }
+
+func (f *fakeSource) SubscribeTransactions(ctx context.Context, key string, accounts []string) (<-chan interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.accounts = accounts
+	return f.ch, nil
+}
+
+func (f *fakeSource) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+// fakeIndex is an in-memory WalletIndex
+type fakeIndex struct {
+	mu     sync.Mutex
+	owners map[string]map[int64]float64
+}
+
+func (f *fakeIndex) Sync(ctx context.Context, targets []*storage.CopyTradeTarget) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.owners = make(map[string]map[int64]float64)
+	for _, t := range targets {
+		if f.owners[t.TargetWallet] == nil {
+			f.owners[t.TargetWallet] = make(map[int64]float64)
+		}
+		f.owners[t.TargetWallet][t.UserID] = t.CopyAmountSOL
+	}
+	return nil
+}
+
+func (f *fakeIndex) IsMonitored(ctx context.Context, wallet string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.owners[wallet]) > 0, nil
+}
+
+func (f *fakeIndex) Owners(ctx context.Context, wallet string) (map[int64]float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.owners[wallet], nil
+}
+
+func (f *fakeIndex) Reconcile(ctx context.Context, targets []*storage.CopyTradeTarget) (*ReconcileReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	members := make(map[string]bool)
+	for wallet := range f.owners {
+		members[wallet] = true
+	}
+	desired := desiredOwners(targets)
+	diff := diffIndex(members, f.owners, desired)
+	f.owners = desired
+	return &diff.report, nil
+}
+
+// fakeTargets serves a fixed list of copy targets
+type fakeTargets []*storage.CopyTradeTarget
+
+func (f fakeTargets) GetAllActiveCopyTargets() ([]*storage.CopyTradeTarget, error) {
+	return f, nil
+}
+
+func (f fakeTargets) GetUsersWatchingWallet(wallet string) ([]*storage.CopyTradeTarget, error) {
+	var watching []*storage.CopyTradeTarget
+	for _, t := range f {
+		if t.TargetWallet == wallet {
+			watching = append(watching, t)
+		}
+	}
+	return watching, nil
+}
+
+func (f fakeTargets) UpdateTargetLastActivity(targetWallet string, at int64) error {
+	for _, t := range f {
+		if t.TargetWallet == targetWallet && at > t.LastActivity {
+			t.LastActivity = at
+		}
+	}
+	return nil
+}
+
+// fakeSwaps returns a 1 SOL buy for every signature except "not_swap",
+// "rpc_down", those starting with "sell", which are 1 SOL sells, and
+// those starting with "dust", which are 0.001 SOL buys
+type fakeSwaps struct{}
+
+func (fakeSwaps) FetchAndParseSwap(ctx context.Context, signature, wallet string) (*SwapInfo, error) {
+	if signature == "not_swap" {
+		return nil, ErrNotSwap
+	}
+	if signature == "rpc_down" {
+		return nil, errors.New("connection refused")
+	}
+	if strings.HasPrefix(signature, "sell") {
+		return &SwapInfo{Signature: signature, Wallet: wallet, InputMint: "TokenMint111", OutputMint: SolMint, OutputAmount: 1_000_000_000}, nil
+	}
+	amount := uint64(1_000_000_000)
+	if strings.HasPrefix(signature, "dust") {
+		amount = 1_000_000
+	}
+	return &SwapInfo{Signature: signature, Wallet: wallet, InputMint: SolMint, OutputMint: "TokenMint111", InputAmount: amount}, nil
+}
+
+// fakeSender records sent messages
+type fakeSender struct {
+	sent chan tgbotapi.MessageConfig
+}
+
+func (f *fakeSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if msg, ok := c.(tgbotapi.MessageConfig); ok {
+		f.sent <- msg
+	}
+	return tgbotapi.Message{}, nil
+}
+
+func txNotification(signature, signer string) map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "transactionNotification",
+		"params": map[string]interface{}{
+			"subscription": 7,
+			"result": map[string]interface{}{
+				"signature": signature,
+				"transaction": map[string]interface{}{
+					"transaction": map[string]interface{}{
+						"message": map[string]interface{}{
+							"accountKeys": []interface{}{
+								map[string]interface{}{"pubkey": signer, "signer": true},
+							},
+						},
+					},
+					"meta": map[string]interface{}{"err": nil},
+				},
+			},
+		},
+	}
+}
+
+func TestFanOutEngine(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.FanOutEngine.WorkerCount = 2
+	cfg.FanOutEngine.LogBufferSize = 100
+	cfg.Programs.Subscriptions = []config.ProgramSubscription{
+		{Name: "Jupiter", ProgramID: config.ProgramJupiterV6, Enabled: true},
+		{Name: "Pump.fun", ProgramID: config.ProgramPumpFun, Enabled: true},
+		{Name: "Orca", ProgramID: config.ProgramOrcaWhirlpool, Enabled: false},
+	}
+
+	source := &fakeSource{ch: make(chan interface{}, 10)}
+	sender := &fakeSender{sent: make(chan tgbotapi.MessageConfig, 10)}
+	targets := fakeTargets{
+		{UserID: 1, TargetWallet: "targetWallet", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true},
+		{UserID: 2, TargetWallet: "targetWallet", CopyAmountSOL: 0.5, CopyBuys: true, CopySells: true},
+	}
+
+	e := newFanOutEngine(cfg, targets, sender, source, &fakeIndex{}, fakeSwaps{})
+	e.Start()
+
+	if got := e.GetMonitoredCount(); got != 1 {
+		t.Errorf("Expected 1 monitored wallet, got %d", got)
+	}
+
+	// Unmonitored signer, non-swap, failed fetch and an ack are all ignored
+	source.ch <- txNotification("sig_other", "someoneElse")
+	source.ch <- txNotification("not_swap", "targetWallet")
+	source.ch <- txNotification("rpc_down", "targetWallet")
+	source.ch <- map[string]interface{}{"jsonrpc": "2.0", "result": 7, "id": 1}
+	source.ch <- txNotification("sig_match", "targetWallet")
+
+	recipients := make(map[int64]bool)
+	for len(recipients) < 2 {
+		select {
+		case msg := <-sender.sent:
+			recipients[msg.ChatID] = true
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for notifications, got %v", recipients)
+		}
+	}
+	if !recipients[1] || !recipients[2] {
+		t.Errorf("Expected both copiers notified, got %v", recipients)
+	}
+
+	select {
+	case msg := <-sender.sent:
+		t.Errorf("Unexpected extra notification: %s", msg.Text)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if DroppedCount("fanout", "swap_fetch_error") == 0 {
+		t.Error("Expected failed swap fetch to be dead-lettered")
+	}
+
+	e.Shutdown()
+	if e.IsRunning() {
+		t.Error("Engine should report stopped")
+	}
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if !source.closed {
+		t.Error("Source should be closed on shutdown")
+	}
+	if len(source.accounts) != 2 {
+		t.Errorf("Expected 2 subscribed programs, got %v", source.accounts)
+	}
+}
+
+func TestReconcileMonitoredWallets(t *testing.T) {
+	cfg := &config.Config{}
+	targets := fakeTargets{
+		{UserID: 1, TargetWallet: "walletA", CopyAmountSOL: 0.1},
+		{UserID: 2, TargetWallet: "walletB", CopyAmountSOL: 0.2},
+	}
+	index := &fakeIndex{owners: map[string]map[int64]float64{
+		"walletA": {1: 0.1},
+		"stale":   {3: 1},
+	}}
+	e := newFanOutEngine(cfg, targets, &fakeSender{}, &fakeSource{}, index, fakeSwaps{})
+
+	report, err := e.ReconcileMonitoredWallets()
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(report.Added) != 1 || report.Added[0] != "walletB" {
+		t.Errorf("Expected walletB added, got %v", report.Added)
+	}
+	if len(report.Removed) != 1 || report.Removed[0] != "stale" {
+		t.Errorf("Expected stale removed, got %v", report.Removed)
+	}
+	if got := e.GetMonitoredCount(); got != 2 {
+		t.Errorf("Expected 2 monitored wallets, got %d", got)
+	}
+
+	report, err = e.ReconcileMonitoredWallets()
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if report.HasDrift() {
+		t.Errorf("Expected no drift on second pass, got %+v", report)
+	}
+}
+
+// TestFanOutCopySides tests that buys and sells only reach the copiers
+// who copy that side
+func TestFanOutCopySides(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.FanOutEngine.MaxConcurrentExecutions = 4
+	cfg.FanOutEngine.MaxExecutionsPerUser = 1
+	cfg.FanOutEngine.ExecutionQueueTimeoutMs = 1000
+
+	const both, buysOnly, sellsOnly, stale = 1, 2, 3, 4
+	targets := fakeTargets{
+		{UserID: both, TargetWallet: "walletA", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true},
+		{UserID: buysOnly, TargetWallet: "walletA", CopyAmountSOL: 0.1, CopyBuys: true},
+		{UserID: sellsOnly, TargetWallet: "walletA", CopyAmountSOL: 0.1, CopySells: true},
+	}
+	index := &fakeIndex{}
+	index.Sync(context.Background(), targets)
+	// An owner the index still lists after its target was removed
+	index.owners["walletA"][stale] = 0.1
+
+	e := newFanOutEngine(cfg, targets, &fakeSender{}, &fakeSource{}, index, fakeSwaps{})
+
+	var mu sync.Mutex
+	var copied map[int64]bool
+	e.execute = func(ctx context.Context, userID int64, copyAmount float64, swap *SwapInfo) {
+		mu.Lock()
+		copied[userID] = true
+		mu.Unlock()
+	}
+
+	tests := []struct {
+		signature string
+		want      map[int64]bool
+	}{
+		{"buy1", map[int64]bool{both: true, buysOnly: true}},
+		{"sell1", map[int64]bool{both: true, sellsOnly: true}},
+	}
+	for _, tt := range tests {
+		copied = make(map[int64]bool)
+		e.processMatch(context.Background(), &TxNotification{Signature: tt.signature, Wallet: "walletA"})
+		e.wg.Wait()
+
+		mu.Lock()
+		if len(copied) != len(tt.want) {
+			t.Errorf("%s: expected copiers %v, got %v", tt.signature, tt.want, copied)
+		}
+		for userID := range tt.want {
+			if !copied[userID] {
+				t.Errorf("%s: user %d should have copied, got %v", tt.signature, userID, copied)
+			}
+		}
+		mu.Unlock()
+	}
+
+	for _, target := range targets {
+		if target.LastActivity == 0 {
+			t.Errorf("Expected the swaps to mark %d's target active", target.UserID)
+		}
+	}
+}
+
+// TestFanOutMinTargetSOL tests that target swaps below a copier's
+// minimum don't fire a copy
+func TestFanOutMinTargetSOL(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.FanOutEngine.MaxConcurrentExecutions = 2
+	cfg.FanOutEngine.MaxExecutionsPerUser = 1
+	cfg.FanOutEngine.ExecutionQueueTimeoutMs = 1000
+
+	const everything, filtered = 1, 2
+	targets := fakeTargets{
+		{UserID: everything, TargetWallet: "walletA", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true},
+		{UserID: filtered, TargetWallet: "walletA", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true, MinTargetSOL: 0.5},
+	}
+	index := &fakeIndex{}
+	index.Sync(context.Background(), targets)
+	e := newFanOutEngine(cfg, targets, &fakeSender{}, &fakeSource{}, index, fakeSwaps{})
+
+	var mu sync.Mutex
+	copies := make(map[string][]int64)
+	e.execute = func(ctx context.Context, userID int64, copyAmount float64, swap *SwapInfo) {
+		mu.Lock()
+		copies[swap.Signature] = append(copies[swap.Signature], userID)
+		mu.Unlock()
+	}
+
+	e.processMatch(context.Background(), &TxNotification{Signature: "dust1", Wallet: "walletA"})
+	e.processMatch(context.Background(), &TxNotification{Signature: "buy1", Wallet: "walletA"})
+	e.wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := copies["dust1"]; len(got) != 1 || got[0] != everything {
+		t.Errorf("Expected only the unfiltered copier to copy the dust buy, got %v", got)
+	}
+	if got := copies["buy1"]; len(got) != 2 {
+		t.Errorf("Expected both copiers to copy a 1 SOL buy, got %v", got)
+	}
+}
+
+// TestFanOutWorker tests a single worker against the in-memory index:
+// logs signed by a monitored wallet reach the executor and the rest are
+// dropped before any swap is fetched
+func TestFanOutWorker(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.FanOutEngine.LogBufferSize = 10
+	cfg.FanOutEngine.MaxConcurrentExecutions = 2
+	cfg.FanOutEngine.MaxExecutionsPerUser = 1
+	cfg.FanOutEngine.ExecutionQueueTimeoutMs = 1000
+
+	targets := fakeTargets{
+		{UserID: 1, TargetWallet: "targetWallet", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true},
+	}
+	index := &fakeIndex{}
+	index.Sync(context.Background(), targets)
+	e := newFanOutEngine(cfg, targets, &fakeSender{}, &fakeSource{}, index, fakeSwaps{})
+
+	copied := make(chan string, 4)
+	e.execute = func(ctx context.Context, userID int64, copyAmount float64, swap *SwapInfo) {
+		copied <- swap.Signature
+	}
+
+	e.wg.Add(1)
+	go e.worker(0)
+	defer e.Shutdown()
+
+	for _, note := range []map[string]interface{}{
+		txNotification("sig_other", "someoneElse"),
+		txNotification("sig_match", "targetWallet"),
+	} {
+		raw, err := json.Marshal(note)
+		if err != nil {
+			t.Fatalf("Failed to encode notification: %v", err)
+		}
+		if !e.logs.Offer(string(raw)) {
+			t.Fatal("Log queue rejected the notification")
+		}
+	}
+
+	// The worker takes logs in order, so the unmonitored one has been
+	// dropped by the time the match is copied
+	select {
+	case sig := <-copied:
+		if sig != "sig_match" {
+			t.Errorf("Expected the monitored wallet's swap copied, got %s", sig)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for the copy")
+	}
+	select {
+	case sig := <-copied:
+		t.Errorf("Unexpected copy of %s", sig)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TradingEnabledKey holds the global trading flag. It is written without a
+// TTL so a halt survives restarts; a missing key means trading is on.
+const TradingEnabledKey = "trading:enabled"
+
+// TradingGate reports whether trade execution is currently allowed
+type TradingGate interface {
+	TradingEnabled(ctx context.Context) bool
+}
+
+// KillSwitch is the Redis-backed TradingGate shared by the bot, the
+// fan-out engine and the janitor
+type KillSwitch struct {
+	rdb  *redis.Client
+	last atomic.Bool // last value read, used while Redis is unreachable
+}
+
+// NewKillSwitch creates a kill switch on top of a Redis client. A nil
+// client leaves trading permanently enabled.
+func NewKillSwitch(rdb *redis.Client) *KillSwitch {
+	k := &KillSwitch{rdb: rdb}
+	k.last.Store(true)
+	return k
+}
+
+// TradingEnabled reports whether trading is on. If Redis can't be read the
+// last known state is kept, so an outage neither halts nor resumes trading.
+func (k *KillSwitch) TradingEnabled(ctx context.Context) bool {
+	if k == nil || k.rdb == nil {
+		return true
+	}
+
+	val, err := k.rdb.Get(ctx, TradingEnabledKey).Result()
+	if errors.Is(err, redis.Nil) {
+		k.last.Store(true)
+		return true
+	}
+	if err != nil {
+		log.Printf("⚠️ Kill switch read failed, keeping last state: %v", err)
+		return k.last.Load()
+	}
+
+	enabled := parseTradingFlag(val)
+	k.last.Store(enabled)
+	return enabled
+}
+
+// SetTradingEnabled turns trading on or off for every process sharing
+// the Redis instance
+func (k *KillSwitch) SetTradingEnabled(ctx context.Context, enabled bool) error {
+	if k == nil || k.rdb == nil {
+		return errors.New("kill switch requires Redis")
+	}
+	if err := k.rdb.Set(ctx, TradingEnabledKey, strconv.FormatBool(enabled), 0).Err(); err != nil {
+		return err
+	}
+	k.last.Store(enabled)
+	return nil
+}
+
+// parseTradingFlag reads a stored flag. Anything that isn't a recognisable
+// "off" keeps trading on, matching the default.
+func parseTradingFlag(val string) bool {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "false", "0", "off":
+		return false
+	}
+	return true
+}
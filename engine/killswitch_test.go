@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"solana-orchestrator/config"
+)
+
+// fakeGate is a TradingGate an operator can flip in tests
+type fakeGate struct {
+	enabled atomic.Bool
+}
+
+func (f *fakeGate) TradingEnabled(ctx context.Context) bool { return f.enabled.Load() }
+
+func TestParseTradingFlag(t *testing.T) {
+	tests := map[string]bool{
+		"true":    true,
+		"1":       true,
+		"on":      true,
+		"":        true,
+		"garbage": true,
+		"false":   false,
+		"0":       false,
+		"OFF":     false,
+	}
+	for val, want := range tests {
+		if got := parseTradingFlag(val); got != want {
+			t.Errorf("parseTradingFlag(%q) = %v, want %v", val, got, want)
+		}
+	}
+}
+
+func TestNilKillSwitchAllowsTrading(t *testing.T) {
+	if !NewKillSwitch(nil).TradingEnabled(context.Background()) {
+		t.Error("Expected trading enabled without Redis")
+	}
+	if err := NewKillSwitch(nil).SetTradingEnabled(context.Background(), false); err == nil {
+		t.Error("Expected an error toggling the switch without Redis")
+	}
+}
+
+// TestKillSwitchHaltsCopyTrades tests that matches are dropped while
+// trading is off and executed again once it is back on
+func TestKillSwitchHaltsCopyTrades(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.FanOutEngine.MaxConcurrentExecutions = 2
+	cfg.FanOutEngine.MaxExecutionsPerUser = 1
+	cfg.FanOutEngine.ExecutionQueueTimeoutMs = 1000
+
+	targets := fakeTargets{{UserID: 1, TargetWallet: "walletA", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true}}
+	index := &fakeIndex{}
+	index.Sync(context.Background(), targets)
+
+	e := newFanOutEngine(cfg, targets, &fakeSender{}, &fakeSource{}, index, fakeSwaps{})
+	gate := &fakeGate{}
+	e.gate = gate
+
+	var runs atomic.Int32
+	e.execute = func(ctx context.Context, userID int64, copyAmount float64, swap *SwapInfo) {
+		runs.Add(1)
+	}
+
+	e.processMatch(context.Background(), &TxNotification{Signature: "halted", Wallet: "walletA"})
+	e.wg.Wait()
+	if got := runs.Load(); got != 0 {
+		t.Fatalf("Expected no executions while halted, got %d", got)
+	}
+
+	gate.enabled.Store(true)
+	e.processMatch(context.Background(), &TxNotification{Signature: "resumed", Wallet: "walletA"})
+	e.wg.Wait()
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected 1 execution after resuming, got %d", got)
+	}
+}
@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Execution limiter defaults
+const (
+	DefaultMaxConcurrentExecutions = 50
+	DefaultMaxExecutionsPerUser    = 2
+	DefaultExecutionQueueTimeout   = 2 * time.Second
+)
+
+// Execution shedding errors
+var (
+	ErrDuplicateExecution = errors.New("signature already executing for user")
+	ErrExecutionShed      = errors.New("execution limit reached")
+)
+
+// executionStats counts copy-trade executions by outcome and is published
+// at /debug/vars when an HTTP server is running
+var executionStats = expvar.NewMap("copytrade_executions")
+
+// userSlots is one user's execution semaphore; refs counts callers holding
+// or waiting on it so idle users can be dropped
+type userSlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+// ExecutionLimiter bounds concurrent copy-trade executions globally and
+// per user. Excess work waits up to a queue timeout and is then shed. A
+// signature already executing or queued for the same user is shed
+// immediately, so bursts drop repeats before distinct trades.
+type ExecutionLimiter struct {
+	global  chan struct{}
+	perUser int
+	timeout time.Duration
+
+	mu       sync.Mutex
+	users    map[int64]*userSlots
+	inFlight map[string]bool
+}
+
+// NewExecutionLimiter creates a limiter; non-positive values use defaults
+func NewExecutionLimiter(maxGlobal, maxPerUser int, queueTimeout time.Duration) *ExecutionLimiter {
+	if maxGlobal <= 0 {
+		maxGlobal = DefaultMaxConcurrentExecutions
+	}
+	if maxPerUser <= 0 {
+		maxPerUser = DefaultMaxExecutionsPerUser
+	}
+	if queueTimeout <= 0 {
+		queueTimeout = DefaultExecutionQueueTimeout
+	}
+	return &ExecutionLimiter{
+		global:   make(chan struct{}, maxGlobal),
+		perUser:  maxPerUser,
+		timeout:  queueTimeout,
+		users:    make(map[int64]*userSlots),
+		inFlight: make(map[string]bool),
+	}
+}
+
+// Acquire waits for an execution slot for userID. The returned release
+// must be called once the execution finishes.
+func (l *ExecutionLimiter) Acquire(ctx context.Context, userID int64, signature string) (func(), error) {
+	key := fmt.Sprintf("%d:%s", userID, signature)
+
+	l.mu.Lock()
+	if l.inFlight[key] {
+		l.mu.Unlock()
+		executionStats.Add("shed_duplicate", 1)
+		return nil, ErrDuplicateExecution
+	}
+	l.inFlight[key] = true
+	slots := l.users[userID]
+	if slots == nil {
+		slots = &userSlots{sem: make(chan struct{}, l.perUser)}
+		l.users[userID] = slots
+	}
+	slots.refs++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+
+	select {
+	case slots.sem <- struct{}{}:
+	case <-timer.C:
+		l.forget(userID, key)
+		executionStats.Add("shed_user_limit", 1)
+		return nil, ErrExecutionShed
+	case <-ctx.Done():
+		l.forget(userID, key)
+		return nil, ctx.Err()
+	}
+
+	select {
+	case l.global <- struct{}{}:
+	case <-timer.C:
+		<-slots.sem
+		l.forget(userID, key)
+		executionStats.Add("shed_global_limit", 1)
+		return nil, ErrExecutionShed
+	case <-ctx.Done():
+		<-slots.sem
+		l.forget(userID, key)
+		return nil, ctx.Err()
+	}
+
+	executionStats.Add("started", 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-l.global
+			<-slots.sem
+			l.forget(userID, key)
+		})
+	}, nil
+}
+
+// forget drops the in-flight marker and the user's slots once unused
+func (l *ExecutionLimiter) forget(userID int64, key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.inFlight, key)
+	if slots := l.users[userID]; slots != nil {
+		slots.refs--
+		if slots.refs == 0 {
+			delete(l.users, userID)
+		}
+	}
+}
+
+// ExecutionCount returns how many executions ended with outcome, e.g.
+// "started" or "shed_duplicate"
+func ExecutionCount(outcome string) int64 {
+	if v, ok := executionStats.Get(outcome).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/storage"
+)
+
+func TestExecutionLimiter(t *testing.T) {
+	t.Run("BurstStaysBounded", func(t *testing.T) {
+		const maxGlobal, maxPerUser = 4, 1
+		l := NewExecutionLimiter(maxGlobal, maxPerUser, 5*time.Second)
+
+		var mu sync.Mutex
+		var active, peak int
+		perUser := make(map[int64]int)
+		userPeak := 0
+
+		var wg sync.WaitGroup
+		for user := int64(0); user < 10; user++ {
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func(user int64, sig string) {
+					defer wg.Done()
+					release, err := l.Acquire(context.Background(), user, sig)
+					if err != nil {
+						t.Errorf("Unexpected shed for %d/%s: %v", user, sig, err)
+						return
+					}
+					defer release()
+
+					mu.Lock()
+					active++
+					perUser[user]++
+					if active > peak {
+						peak = active
+					}
+					if perUser[user] > userPeak {
+						userPeak = perUser[user]
+					}
+					mu.Unlock()
+
+					time.Sleep(5 * time.Millisecond)
+
+					mu.Lock()
+					active--
+					perUser[user]--
+					mu.Unlock()
+				}(user, fmt.Sprintf("sig%d", i))
+			}
+		}
+		wg.Wait()
+
+		if peak > maxGlobal {
+			t.Errorf("Global concurrency %d exceeded limit %d", peak, maxGlobal)
+		}
+		if userPeak > maxPerUser {
+			t.Errorf("Per-user concurrency %d exceeded limit %d", userPeak, maxPerUser)
+		}
+	})
+
+	t.Run("ShedsDuplicateSignature", func(t *testing.T) {
+		l := NewExecutionLimiter(4, 2, time.Second)
+		release, err := l.Acquire(context.Background(), 1, "sig")
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+
+		before := ExecutionCount("shed_duplicate")
+		if _, err := l.Acquire(context.Background(), 1, "sig"); !errors.Is(err, ErrDuplicateExecution) {
+			t.Errorf("Expected ErrDuplicateExecution, got %v", err)
+		}
+		if ExecutionCount("shed_duplicate") != before+1 {
+			t.Error("Expected duplicate to be counted")
+		}
+
+		// The same signature for another user is a distinct trade
+		other, err := l.Acquire(context.Background(), 2, "sig")
+		if err != nil {
+			t.Fatalf("Expected other user's copy to run, got %v", err)
+		}
+		other()
+
+		release()
+		again, err := l.Acquire(context.Background(), 1, "sig")
+		if err != nil {
+			t.Fatalf("Expected slot after release, got %v", err)
+		}
+		again()
+	})
+
+	t.Run("ShedsAfterQueueTimeout", func(t *testing.T) {
+		l := NewExecutionLimiter(1, 1, 20*time.Millisecond)
+		release, err := l.Acquire(context.Background(), 1, "sigA")
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		defer release()
+
+		if _, err := l.Acquire(context.Background(), 2, "sigB"); !errors.Is(err, ErrExecutionShed) {
+			t.Errorf("Expected ErrExecutionShed, got %v", err)
+		}
+		if len(l.users) != 1 || len(l.inFlight) != 1 {
+			t.Errorf("Shed request should leave no state behind, got %d users, %d in flight", len(l.users), len(l.inFlight))
+		}
+	})
+}
+
+func TestFanOutExecutionBurst(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.FanOutEngine.LogBufferSize = 10
+	cfg.FanOutEngine.MaxConcurrentExecutions = 3
+	cfg.FanOutEngine.MaxExecutionsPerUser = 1
+	cfg.FanOutEngine.ExecutionQueueTimeoutMs = 5000
+
+	var targets fakeTargets
+	for user := int64(1); user <= 20; user++ {
+		targets = append(targets, &storage.CopyTradeTarget{UserID: user, TargetWallet: "popularWallet", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true})
+	}
+	index := &fakeIndex{}
+	index.Sync(context.Background(), targets)
+
+	e := newFanOutEngine(cfg, targets, &fakeSender{}, &fakeSource{}, index, fakeSwaps{})
+
+	var mu sync.Mutex
+	var active, peak, runs int
+	e.execute = func(ctx context.Context, userID int64, copyAmount float64, swap *SwapInfo) {
+		mu.Lock()
+		active++
+		runs++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}
+
+	for i := 0; i < 5; i++ {
+		e.processMatch(context.Background(), &TxNotification{Signature: fmt.Sprintf("burst%d", i), Wallet: "popularWallet"})
+	}
+	e.wg.Wait()
+
+	if peak > 3 {
+		t.Errorf("Concurrent executions %d exceeded limit 3", peak)
+	}
+	if runs != 100 {
+		t.Errorf("Expected all 100 distinct copies to run, got %d", runs)
+	}
+}
+
+func TestFanOutBusyUserDoesNotStall(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.FanOutEngine.LogBufferSize = 10
+	cfg.FanOutEngine.MaxConcurrentExecutions = 10
+	cfg.FanOutEngine.MaxExecutionsPerUser = 1
+	cfg.FanOutEngine.ExecutionQueueTimeoutMs = 5000
+
+	targets := fakeTargets{
+		{UserID: 1, TargetWallet: "sharedWallet", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true},
+		{UserID: 2, TargetWallet: "sharedWallet", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true},
+	}
+	index := &fakeIndex{}
+	index.Sync(context.Background(), targets)
+
+	e := newFanOutEngine(cfg, targets, &fakeSender{}, &fakeSource{}, index, fakeSwaps{})
+
+	unblock := make(chan struct{})
+	ran := make(chan string, 4)
+	e.execute = func(ctx context.Context, userID int64, copyAmount float64, swap *SwapInfo) {
+		if userID == 1 {
+			<-unblock
+		}
+		ran <- fmt.Sprintf("%d:%s", userID, swap.Signature)
+	}
+
+	// User 1's first copy holds their only slot until unblocked
+	e.processMatch(context.Background(), &TxNotification{Signature: "first", Wallet: "sharedWallet"})
+	<-ran // user 2's copy of "first"
+
+	start := time.Now()
+	e.processMatch(context.Background(), &TxNotification{Signature: "second", Wallet: "sharedWallet"})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("processMatch blocked %v behind a user at their limit", elapsed)
+	}
+
+	select {
+	case got := <-ran:
+		if got != "2:second" {
+			t.Errorf("Expected user 2's copy to run first, got %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Error("User 2's copy waited behind user 1's limit")
+	}
+
+	close(unblock)
+	e.wg.Wait()
+}
@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"solana-orchestrator/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// NotificationKind says how important a notification is to its user
+type NotificationKind int
+
+const (
+	// NoticeSwap reports a swap by a copied target
+	NoticeSwap NotificationKind = iota
+	// NoticeAlert reports a change to the user's copy setup, such as a
+	// paused target
+	NoticeAlert
+	// NoticeTrade confirms a trade executed for the user. It is always
+	// sent at once, even in quiet hours.
+	NoticeTrade
+)
+
+// NotificationPrefs reads users' notification settings, holds
+// notifications for digests and summarizes the day's activity
+type NotificationPrefs interface {
+	GetUserSettings(chatID int64) (*storage.UserSettings, error)
+	QueueDigest(userID int64, message string) error
+	DigestBacklogs() ([]storage.DigestBacklog, error)
+	TakeDigest(userID int64) ([]string, error)
+	DigestUsers() ([]int64, error)
+	GetCopyActivity(userID int64, since int64) (*storage.CopyActivity, error)
+	MarkDigestSent(chatID int64, at time.Time) error
+}
+
+// Digest timing. Digest users get a summary once a day at their chosen
+// local hour; anyone else's held notifications are released when their
+// quiet hours end.
+const (
+	digestCheckInterval = 10 * time.Minute
+	// maxDigestLen keeps a digest under Telegram's 4096 character limit
+	maxDigestLen = 3500
+)
+
+type noticeRoute int
+
+const (
+	routeSend noticeRoute = iota
+	routeQueue
+	routeDrop
+)
+
+// routeNotification decides whether a notification of kind is sent now,
+// held for a digest or dropped under settings s. Nil settings send
+// everything.
+func routeNotification(kind NotificationKind, s *storage.UserSettings, now time.Time) noticeRoute {
+	if kind == NoticeTrade || s == nil {
+		return routeSend
+	}
+	switch s.NotifyLevel {
+	case storage.NotifyTrades:
+		if kind == NoticeSwap {
+			return routeDrop
+		}
+	case storage.NotifyDigest:
+		return routeQueue
+	}
+	if s.InQuietHours(now) {
+		return routeQueue
+	}
+	return routeSend
+}
+
+// routeNote applies the user's preferences to note and reports whether
+// it should be sent now
+func (e *FanOutEngine) routeNote(note Notification, now time.Time) bool {
+	if e.prefs == nil {
+		return true
+	}
+	settings, err := e.prefs.GetUserSettings(note.UserID)
+	if err != nil {
+		// Better a message in quiet hours than a lost trade notice
+		log.Printf("⚠️ Failed to load notification settings for %d: %v", note.UserID, err)
+		return true
+	}
+
+	switch routeNotification(note.Kind, settings, now) {
+	case routeQueue:
+		if err := e.prefs.QueueDigest(note.UserID, note.Message); err != nil {
+			log.Printf("⚠️ Failed to queue digest for %d, sending now: %v", note.UserID, err)
+			return true
+		}
+		return false
+	case routeDrop:
+		return false
+	}
+	return true
+}
+
+// digestLoop periodically sends held notifications that are due
+func (e *FanOutEngine) digestLoop() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case <-ticker.C:
+			e.flushDigests(time.Now())
+		}
+	}
+}
+
+// flushDigests sends the daily digests and held notifications that are
+// due at now
+func (e *FanOutEngine) flushDigests(now time.Time) {
+	e.sendDailyDigests(now)
+
+	backlogs, err := e.prefs.DigestBacklogs()
+	if err != nil {
+		log.Printf("⚠️ Failed to list notification digests: %v", err)
+		return
+	}
+
+	for _, b := range backlogs {
+		settings, err := e.prefs.GetUserSettings(b.UserID)
+		if err != nil {
+			log.Printf("⚠️ Failed to load notification settings for %d: %v", b.UserID, err)
+			continue
+		}
+		if !digestDue(settings, b, now) {
+			continue
+		}
+
+		messages, err := e.prefs.TakeDigest(b.UserID)
+		if err != nil {
+			log.Printf("⚠️ Failed to take digest for %d: %v", b.UserID, err)
+			continue
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		e.sendDigest(b.UserID, formatDigest(messages))
+	}
+}
+
+// sendDailyDigests sends each digest user's summary once their local
+// digest hour has passed
+func (e *FanOutEngine) sendDailyDigests(now time.Time) {
+	users, err := e.prefs.DigestUsers()
+	if err != nil {
+		log.Printf("⚠️ Failed to list digest users: %v", err)
+		return
+	}
+
+	for _, userID := range users {
+		settings, err := e.prefs.GetUserSettings(userID)
+		if err != nil {
+			log.Printf("⚠️ Failed to load notification settings for %d: %v", userID, err)
+			continue
+		}
+		if !settings.DigestDue(now) || settings.InQuietHours(now) {
+			continue
+		}
+
+		since := settings.LastDigestTime(now).AddDate(0, 0, -1)
+		activity, err := e.prefs.GetCopyActivity(userID, since.Unix())
+		if err != nil {
+			log.Printf("⚠️ Failed to load copy activity for %d: %v", userID, err)
+			continue
+		}
+		// Marked first so a failure can't send the same day twice. A quiet
+		// day is marked done without a message.
+		if err := e.prefs.MarkDigestSent(userID, now); err != nil {
+			log.Printf("⚠️ Failed to mark digest sent for %d: %v", userID, err)
+			continue
+		}
+		messages, err := e.prefs.TakeDigest(userID)
+		if err != nil {
+			log.Printf("⚠️ Failed to take digest for %d: %v", userID, err)
+			continue
+		}
+		if activity.Buys+activity.Sells+activity.Failed+activity.Closed == 0 && len(messages) == 0 {
+			continue
+		}
+		e.sendDigest(userID, formatDailyDigest(activity, messages))
+	}
+}
+
+// sendDigest sends one digest under the notification rate limit
+func (e *FanOutEngine) sendDigest(userID int64, text string) {
+	e.notifyRate.Wait(context.Background())
+	if _, err := e.sender.Send(tgbotapi.NewMessage(userID, text)); err != nil {
+		log.Printf("⚠️ Failed to send digest to %d: %v", userID, err)
+	}
+}
+
+// digestDue reports whether a user's held notifications should be
+// released. Digest users' are sent with their daily summary instead.
+func digestDue(s *storage.UserSettings, b storage.DigestBacklog, now time.Time) bool {
+	if s.NotifyLevel == storage.NotifyDigest {
+		return false
+	}
+	return !s.InQuietHours(now)
+}
+
+// formatDigest joins held notifications into one message, summarizing
+// what doesn't fit
+func formatDigest(messages []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📬 Notification Digest (%d)\n", len(messages))
+	writeHeld(&b, messages)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// formatDailyDigest summarizes a day of copy trading followed by the
+// notifications held during it
+func formatDailyDigest(a *storage.CopyActivity, messages []string) string {
+	var b strings.Builder
+	b.WriteString("📬 Daily Digest\n\n")
+	fmt.Fprintf(&b, "🟢 Buys: %d (%.4f SOL)\n", a.Buys, a.SOLSpent)
+	fmt.Fprintf(&b, "🔴 Sells: %d (%.4f SOL)\n", a.Sells, a.SOLReceived)
+	if a.Failed > 0 {
+		fmt.Fprintf(&b, "❌ Failed: %d\n", a.Failed)
+	}
+	fmt.Fprintf(&b, "💰 Realized PnL: %+.4f SOL over %d closed copies\n", a.PnLSOL, a.Closed)
+	if len(messages) > 0 {
+		fmt.Fprintf(&b, "\n👀 Target activity you missed (%d):\n", len(messages))
+		writeHeld(&b, messages)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeHeld appends held notifications to b until maxDigestLen, noting
+// how many were left out
+func writeHeld(b *strings.Builder, messages []string) {
+	for i, m := range messages {
+		if b.Len()+len(m)+2 > maxDigestLen {
+			fmt.Fprintf(b, "\n…and %d more", len(messages)-i)
+			break
+		}
+		b.WriteString("\n")
+		b.WriteString(m)
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRouteNotification(t *testing.T) {
+	night := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	settings := func(level string) *storage.UserSettings {
+		return &storage.UserSettings{NotifyLevel: level, QuietStartHour: 22, QuietEndHour: 7}
+	}
+
+	tests := []struct {
+		name string
+		kind NotificationKind
+		s    *storage.UserSettings
+		now  time.Time
+		want noticeRoute
+	}{
+		{"NoSettings", NoticeSwap, nil, night, routeSend},
+		{"AllDaytime", NoticeSwap, settings(storage.NotifyAll), day, routeSend},
+		{"AllQuietHours", NoticeSwap, settings(storage.NotifyAll), night, routeQueue},
+		{"TradeInQuietHours", NoticeTrade, settings(storage.NotifyAll), night, routeSend},
+		{"TradesOnlyDropsSwaps", NoticeSwap, settings(storage.NotifyTrades), day, routeDrop},
+		{"TradesOnlyKeepsAlerts", NoticeAlert, settings(storage.NotifyTrades), day, routeSend},
+		{"DigestSwap", NoticeSwap, settings(storage.NotifyDigest), day, routeQueue},
+		{"DigestTrade", NoticeTrade, settings(storage.NotifyDigest), day, routeSend},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routeNotification(tt.kind, tt.s, tt.now); got != tt.want {
+				t.Errorf("Expected route %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// fixedClock is a storage.Clock stopped at one time
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+// TestFlushDigests tests that held notifications go out once quiet hours
+// end, and digest users get one summary a day at their local hour
+func TestFlushDigests(t *testing.T) {
+	db, err := storage.New(filepath.Join(t.TempDir(), "digest.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	// 09:00 in Tokyo is 00:00 UTC
+	const quietUser, digestUser, idleUser = 1, 2, 3
+	night := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	db.SetClock(fixedClock(night))
+	db.UpdateQuietHours(quietUser, 22, 7)
+	for _, userID := range []int64{digestUser, idleUser} {
+		db.UpdateNotifyLevel(userID, storage.NotifyDigest)
+		db.UpdateTimezone(userID, "Asia/Tokyo")
+		db.MarkDigestSent(userID, night)
+	}
+	db.SaveTrade(digestUser, "w", "sig1", "buy", "mint1", 0.5, 0, 0, 0, "confirmed")
+
+	sender := &fakeSender{sent: make(chan tgbotapi.MessageConfig, 10)}
+	e := newFanOutEngine(&config.Config{}, fakeTargets{}, sender, &fakeSource{}, &fakeIndex{}, fakeSwaps{})
+	e.prefs = db
+
+	for _, userID := range []int64{quietUser, digestUser} {
+		if e.routeNote(Notification{UserID: userID, Kind: NoticeSwap, Message: "swap"}, night) {
+			t.Fatalf("Expected user %d's swap to be held", userID)
+		}
+	}
+	if !e.routeNote(Notification{UserID: quietUser, Kind: NoticeTrade, Message: "trade"}, night) {
+		t.Fatal("Expected a trade confirmation to be sent in quiet hours")
+	}
+
+	sent := func() map[int64]string {
+		got := make(map[int64]string)
+		for {
+			select {
+			case msg := <-sender.sent:
+				got[msg.ChatID] = msg.Text
+			default:
+				return got
+			}
+		}
+	}
+
+	e.flushDigests(night.Add(30 * time.Minute))
+	if got := sent(); len(got) != 0 {
+		t.Errorf("Nothing is due before the digest hour, got %v", got)
+	}
+
+	e.flushDigests(night.Add(time.Hour))
+	got := sent()
+	for _, want := range []string{"Daily Digest", "Buys: 1 (0.5000 SOL)", "missed (1)", "swap"} {
+		if !strings.Contains(got[digestUser], want) {
+			t.Errorf("Expected %q in the daily digest, got %q", want, got[digestUser])
+		}
+	}
+	if _, ok := got[quietUser]; ok {
+		t.Error("Quiet user's notifications must wait for quiet hours to end")
+	}
+	if _, ok := got[idleUser]; ok {
+		t.Error("A day without activity must not send a digest")
+	}
+
+	morning := night.Add(9 * time.Hour)
+	e.flushDigests(morning)
+	got = sent()
+	if !strings.Contains(got[quietUser], "swap") {
+		t.Errorf("Expected the quiet user's digest after quiet hours, got %q", got[quietUser])
+	}
+	if _, ok := got[digestUser]; ok {
+		t.Error("Expected one daily digest per day")
+	}
+	if backlogs, _ := db.DigestBacklogs(); len(backlogs) != 0 {
+		t.Errorf("Expected every digest delivered, got %+v", backlogs)
+	}
+}
+
+func TestFormatDigest(t *testing.T) {
+	if got := formatDigest([]string{"a", "b"}); got != "📬 Notification Digest (2)\n\na\n\nb" {
+		t.Errorf("Unexpected digest %q", got)
+	}
+
+	long := make([]string, 20)
+	for i := range long {
+		long[i] = strings.Repeat("x", 500)
+	}
+	got := formatDigest(long)
+	if len(got) > maxDigestLen || !strings.HasSuffix(got, "more") {
+		t.Errorf("Expected a truncated digest under %d bytes, got %d bytes", maxDigestLen, len(got))
+	}
+}
+
+func TestFormatDailyDigest(t *testing.T) {
+	a := &storage.CopyActivity{Buys: 2, Sells: 1, SOLSpent: 0.75, SOLReceived: 0.8, Closed: 1, PnLSOL: -0.05}
+	got := formatDailyDigest(a, nil)
+	want := "📬 Daily Digest\n\n🟢 Buys: 2 (0.7500 SOL)\n🔴 Sells: 1 (0.8000 SOL)\n💰 Realized PnL: -0.0500 SOL over 1 closed copies"
+	if got != want {
+		t.Errorf("Unexpected digest %q", got)
+	}
+
+	a.Failed = 1
+	got = formatDailyDigest(a, []string{"swap"})
+	if !strings.Contains(got, "❌ Failed: 1") || !strings.HasSuffix(got, "missed (1):\n\nswap") {
+		t.Errorf("Unexpected digest %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"solana-orchestrator/storage"
+)
+
+// PaperLedger records the copy trades simulated for paper targets
+type PaperLedger interface {
+	RecordPaperBuy(userID int64, targetWallet, tokenAddr, signature string, solSpent float64, tokens uint64) error
+	RecordPaperSell(userID int64, targetWallet, tokenAddr, signature string, solPerUnit float64) (*storage.PaperTrade, error)
+}
+
+// paperTrade records what copying swap with copyAmount SOL would have done
+// at the target's fill price and tells the user. Nothing is sent on chain.
+// Paper sells close the whole position, like copied sells; a sell with no
+// paper buy to close is ignored.
+func (e *FanOutEngine) paperTrade(ctx context.Context, userID int64, copyAmount float64, swap *SwapInfo) {
+	if e.paper == nil {
+		return
+	}
+
+	var message string
+	switch {
+	case swap.IsBuy() && swap.InputAmount > 0 && swap.OutputAmount > 0:
+		tokens := uint64(float64(swap.OutputAmount) * copyAmount / swap.SOLAmount())
+		if err := e.paper.RecordPaperBuy(userID, swap.Wallet, swap.OutputMint, swap.Signature, copyAmount, tokens); err != nil {
+			e.deadLetters.Record("paper_trade_error", swap.Signature+": "+err.Error())
+			return
+		}
+		message = fmt.Sprintf("📝 Paper Buy\nTarget: %s\nToken: %s\nSpent: %.4f SOL\nTx: %s\n\n(Simulated, no transaction sent)",
+			swap.Wallet, tokenLabel(ctx, swap.OutputMint), copyAmount, swap.Signature)
+
+	case swap.IsSell() && swap.InputAmount > 0:
+		sale, err := e.paper.RecordPaperSell(userID, swap.Wallet, swap.InputMint, swap.Signature, swap.SOLAmount()/float64(swap.InputAmount))
+		if errors.Is(err, storage.ErrNoCopyPosition) {
+			return
+		}
+		if err != nil {
+			e.deadLetters.Record("paper_trade_error", swap.Signature+": "+err.Error())
+			return
+		}
+		message = fmt.Sprintf("📝 Paper Sell\nTarget: %s\nToken: %s\nReceived: %.4f SOL\nPnL: %+.4f SOL\nTx: %s\n\n(Simulated, no transaction sent)",
+			swap.Wallet, tokenLabel(ctx, swap.InputMint), sale.SOLAmount, sale.PnLSOL, swap.Signature)
+
+	default:
+		return
+	}
+
+	select {
+	case e.notificationChan <- Notification{UserID: userID, Kind: NoticeSwap, Message: message}:
+	case <-e.stopChan:
+	}
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/storage"
+)
+
+// paperSwaps returns a buy of 1M units for 1 SOL, or a sale of them for 2
+// SOL for signatures starting with "sell"
+type paperSwaps struct{}
+
+func (paperSwaps) FetchAndParseSwap(ctx context.Context, signature, wallet string) (*SwapInfo, error) {
+	if strings.HasPrefix(signature, "sell") {
+		return &SwapInfo{Signature: signature, Wallet: wallet, InputMint: "TokenMint111", OutputMint: SolMint, InputAmount: 1_000_000, OutputAmount: 2_000_000_000}, nil
+	}
+	return &SwapInfo{Signature: signature, Wallet: wallet, InputMint: SolMint, OutputMint: "TokenMint111", InputAmount: 1_000_000_000, OutputAmount: 1_000_000}, nil
+}
+
+// TestFanOutPaperTrades tests that paper targets are simulated and
+// reported without reaching the executor
+func TestFanOutPaperTrades(t *testing.T) {
+	db, err := storage.New(filepath.Join(t.TempDir(), "paper.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{}
+	cfg.FanOutEngine.MaxConcurrentExecutions = 2
+	cfg.FanOutEngine.MaxExecutionsPerUser = 1
+	cfg.FanOutEngine.ExecutionQueueTimeoutMs = 1000
+
+	const live, paper = 1, 2
+	targets := fakeTargets{
+		{UserID: live, TargetWallet: "walletA", CopyAmountSOL: 0.1, CopyBuys: true, CopySells: true},
+		{UserID: paper, TargetWallet: "walletA", CopyAmountSOL: 0.5, CopyBuys: true, CopySells: true, Paper: true},
+	}
+	index := &fakeIndex{}
+	index.Sync(context.Background(), targets)
+	e := newFanOutEngine(cfg, targets, &fakeSender{}, &fakeSource{}, index, paperSwaps{})
+	e.paper = db
+
+	var mu sync.Mutex
+	var executed []int64
+	e.execute = func(ctx context.Context, userID int64, copyAmount float64, swap *SwapInfo) {
+		mu.Lock()
+		executed = append(executed, userID)
+		mu.Unlock()
+	}
+
+	e.processMatch(context.Background(), &TxNotification{Signature: "buy1", Wallet: "walletA"})
+	e.processMatch(context.Background(), &TxNotification{Signature: "sell1", Wallet: "walletA"})
+	e.wg.Wait()
+
+	mu.Lock()
+	if len(executed) != 2 || executed[0] != live || executed[1] != live {
+		t.Errorf("Expected only the live copier executed, got %v", executed)
+	}
+	mu.Unlock()
+
+	// 0.5 SOL bought 500k units at the target's price; selling them at
+	// twice that returns 1 SOL
+	reports, err := db.GetPaperTradeReport(paper)
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("Expected one paper target, got %v (%v)", reports, err)
+	}
+	r := reports[0]
+	if r.Buys != 1 || r.Sells != 1 || math.Abs(r.RealizedPnLSOL-0.5) > 1e-9 {
+		t.Errorf("Expected a +0.5 SOL round trip, got %+v", r)
+	}
+
+	for _, want := range []string{"📝 Paper Buy", "📝 Paper Sell"} {
+		note := <-e.notificationChan
+		if note.UserID != paper || !strings.HasPrefix(note.Message, want) {
+			t.Errorf("Expected %q for the paper copier, got %+v", want, note)
+		}
+	}
+}
@@ -18,6 +18,28 @@ type SwapInfo struct {
 	Timestamp    int64
 }
 
+// IsBuy reports whether the swap spends SOL on a token
+func (s *SwapInfo) IsBuy() bool {
+	return s.InputMint == SolMint && s.OutputMint != SolMint
+}
+
+// IsSell reports whether the swap sells a token for SOL
+func (s *SwapInfo) IsSell() bool {
+	return s.OutputMint == SolMint && s.InputMint != SolMint
+}
+
+// SOLAmount returns the SOL spent by a buy or received by a sell, and 0
+// for swaps without a SOL side
+func (s *SwapInfo) SOLAmount() float64 {
+	switch {
+	case s.IsBuy():
+		return float64(s.InputAmount) / 1e9
+	case s.IsSell():
+		return float64(s.OutputAmount) / 1e9
+	}
+	return 0
+}
+
 type PoolInfo struct {
 	PoolAddress string
 	BaseMint    string
@@ -37,107 +59,109 @@ type LimitOrderInfo struct {
 	Timestamp  int64
 }
 
-// ParseLogForWallet quickly checks if a log is relevant for a wallet
-func ParseLogForWallet(rawLog string) (string, error) {
-	// Fast path: extract pubkey directly
-	// Structure: params.result.value.pubkey (for account notifications)
-	// For logs, we might need to look at the transaction or instruction
-	// But the user's plan says: gjson.Get(rawLog, "params.result.value.pubkey")
-	// This implies we are looking at account notifications or a specific log format.
-	// However, for program logs, the format is usually params.result.value.logs
-	// But let's follow the user's plan which says: "Use gjson.Get(rawLog, "params.result.value.pubkey") to extract wallet address"
-
-	val := gjson.Get(rawLog, "params.result.value.pubkey")
-	if !val.Exists() {
-		return "", nil
-	}
-	return val.String(), nil
-}
+// SolMint is the wrapped SOL mint; native SOL changes are reported under it
+const SolMint = "So11111111111111111111111111111111111111112"
 
-// ParseSwapInstruction parses a transaction log for swap details
-func ParseSwapInstruction(rawLog string) (*SwapInfo, error) {
-	// Extract signature
-	sig := gjson.Get(rawLog, "params.result.value.signature").String()
-	if sig == "" {
-		return nil, errors.New("signature not found")
-	}
+// ErrNotSwap is returned when a wallet's balance changes don't form a swap
+var ErrNotSwap = errors.New("no token balance change for wallet")
 
-	logs := gjson.Get(rawLog, "params.result.value.logs").Array()
-	if len(logs) == 0 {
-		return nil, errors.New("no logs found")
-	}
+// ErrSideNotCopied is returned when a copier has turned off the side of
+// the target's trade, its buys or its sells
+var ErrSideNotCopied = errors.New("copier does not copy this side of the trade")
 
-	// Check for Jupiter or Raydium program IDs
-	var programID string
-	isJupiter := false
-	isRaydium := false
+// Notification parse errors, also used as dead-letter reasons
+var (
+	ErrNotNotification = errors.New("not_notification")
+	ErrNoSignature     = errors.New("no_signature")
+	ErrNoWallet        = errors.New("no_wallet")
+)
 
-	for _, log := range logs {
-		logStr := log.String()
-		if strings.Contains(logStr, "JUP4Fb2cqiRUcaTHdrPC8h2gNsA2ETXiPDD33WcGuJB") {
-			isJupiter = true
-			programID = "JUP4Fb2cqiRUcaTHdrPC8h2gNsA2ETXiPDD33WcGuJB"
-		}
-		if strings.Contains(logStr, "675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8") {
-			isRaydium = true
-			programID = "675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"
-		}
+// TxNotification is the part of a transactionNotification the fan-out
+// needs to decide whether a copy target traded
+type TxNotification struct {
+	Signature string
+	Wallet    string // fee payer / first signer
+	Failed    bool
+}
+
+// ParseTransactionNotification extracts the signature and fee payer from a
+// transactionSubscribe notification
+func ParseTransactionNotification(raw string) (*TxNotification, error) {
+	if gjson.Get(raw, "method").String() != "transactionNotification" {
+		return nil, ErrNotNotification
 	}
 
-	if !isJupiter && !isRaydium {
-		return nil, errors.New("not a swap transaction")
+	result := gjson.Get(raw, "params.result")
+	sig := result.Get("signature").String()
+	if sig == "" {
+		sig = result.Get("transaction.transaction.signatures.0").String()
+	}
+	if sig == "" {
+		return nil, ErrNoSignature
 	}
 
-	// Heuristic: Extract wallet from the first non-program account if available in "accounts"
-	// Note: logsSubscribe usually doesn't include accounts unless jsonParsed or specific config.
-	// Assuming the payload contains account keys in a standard RPC format if available.
-	// If not, we try to find the signer from the logs or use a placeholder if we can't.
-	// The user instruction says: "Use gjson to extract the transaction’s accounts array".
-	// We'll try to find "params.result.value.transaction.message.accountKeys" or similar.
-
-	wallet := ""
-	accounts := gjson.Get(rawLog, "params.result.value.transaction.message.accountKeys").Array()
-	if len(accounts) > 0 {
-		// The first account is usually the fee payer/signer
-		wallet = accounts[0].String()
-	} else {
-		// Fallback: Try to find a "signer" field or similar if Shyft provides enriched data
-		// Or use the "pubkey" field from the notification if it represents the user
-		wallet = gjson.Get(rawLog, "params.result.value.pubkey").String()
+	// jsonParsed encoding gives objects with a pubkey, others plain strings
+	first := result.Get("transaction.transaction.message.accountKeys.0")
+	wallet := first.Get("pubkey").String()
+	if wallet == "" && first.Type == gjson.String {
+		wallet = first.String()
+	}
+	if wallet == "" {
+		return nil, ErrNoWallet
 	}
 
-	// Heuristic for Mints:
-	// We need to find the input and output mints.
-	// Without full instruction parsing, we can check for "Transfer" or "Swap" logs and extract mints if present.
-	// Or we can look at `preTokenBalances` and `postTokenBalances` if available.
+	errField := result.Get("transaction.meta.err")
+	return &TxNotification{
+		Signature: sig,
+		Wallet:    wallet,
+		Failed:    errField.Exists() && errField.Type != gjson.Null,
+	}, nil
+}
 
-	inputMint := ""
-	outputMint := ""
+// swapFromDeltas picks the sold and bought mints from wallet balance
+// changes. Wrapped SOL is merged with native SOL, and SOL is only used as
+// a side of the swap when no token moved in that direction, since its
+// balance also absorbs rent and tips.
+func swapFromDeltas(signature, wallet string, solDelta int64, tokenDeltas map[string]int64) (*SwapInfo, error) {
+	solDelta += tokenDeltas[SolMint]
+
+	swap := &SwapInfo{Signature: signature, Wallet: wallet}
+	var maxIn, maxOut int64
+	for mint, delta := range tokenDeltas {
+		if mint == SolMint {
+			continue
+		}
+		if delta < 0 && -delta > maxIn {
+			maxIn = -delta
+			swap.InputMint = mint
+		}
+		if delta > 0 && delta > maxOut {
+			maxOut = delta
+			swap.OutputMint = mint
+		}
+	}
 
-	// Try to extract from postTokenBalances
-	postBalances := gjson.Get(rawLog, "params.result.value.meta.postTokenBalances").Array()
-	if len(postBalances) >= 2 {
-		// Simplified: Assume the first two modified token accounts correspond to the swap
-		inputMint = postBalances[0].Get("mint").String()
-		outputMint = postBalances[1].Get("mint").String()
+	if swap.InputMint == "" && swap.OutputMint == "" {
+		return nil, ErrNotSwap
 	}
 
-	// If still empty, use placeholders for SOL pairs to ensure copy trade logic triggers
-	if inputMint == "" {
-		inputMint = "So11111111111111111111111111111111111111112" // SOL
+	swap.InputAmount = uint64(maxIn)
+	swap.OutputAmount = uint64(maxOut)
+	if swap.InputMint == "" {
+		if solDelta >= 0 {
+			return nil, ErrNotSwap
+		}
+		swap.InputMint = SolMint
+		swap.InputAmount = uint64(-solDelta)
 	}
-	if outputMint == "" {
-		outputMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v" // USDC (Example)
+	if swap.OutputMint == "" {
+		if solDelta <= 0 {
+			return nil, ErrNotSwap
+		}
+		swap.OutputMint = SolMint
+		swap.OutputAmount = uint64(solDelta)
 	}
-
-	return &SwapInfo{
-		Signature:  sig,
-		Wallet:     wallet,
-		ProgramID:  programID,
-		InputMint:  inputMint,
-		OutputMint: outputMint,
-		// Amounts would need precise parsing
-	}, nil
+	return swap, nil
 }
 
 // ParseRaydiumInitPool parses pool initialization logs
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseTransactionNotification(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr error
+		wallet  string
+		failed  bool
+	}{
+		{
+			name: "JSONParsedKeys",
+			raw: `{"method":"transactionNotification","params":{"subscription":1,"result":{"signature":"sig1",
+				"transaction":{"transaction":{"message":{"accountKeys":[{"pubkey":"walletA","signer":true},{"pubkey":"prog"}]}},"meta":{"err":null}}}}}`,
+			wallet: "walletA",
+		},
+		{
+			name: "PlainKeysFailedTx",
+			raw: `{"method":"transactionNotification","params":{"result":{
+				"transaction":{"transaction":{"signatures":["sig2"],"message":{"accountKeys":["walletB"]}},"meta":{"err":{"InstructionError":[0,"Custom"]}}}}}}`,
+			wallet: "walletB",
+			failed: true,
+		},
+		{
+			name:    "SubscriptionAck",
+			raw:     `{"jsonrpc":"2.0","result":42,"id":1}`,
+			wantErr: ErrNotNotification,
+		},
+		{
+			name:    "MissingSignature",
+			raw:     `{"method":"transactionNotification","params":{"result":{"transaction":{}}}}`,
+			wantErr: ErrNoSignature,
+		},
+		{
+			name:    "MissingAccounts",
+			raw:     `{"method":"transactionNotification","params":{"result":{"signature":"sig3","transaction":{}}}}`,
+			wantErr: ErrNoWallet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note, err := ParseTransactionNotification(tt.raw)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if note.Wallet != tt.wallet || note.Failed != tt.failed {
+				t.Errorf("Unexpected notification: %+v", note)
+			}
+		})
+	}
+}
+
+func TestSwapFromDeltas(t *testing.T) {
+	const token = "TokenMint111"
+
+	t.Run("Buy", func(t *testing.T) {
+		swap, err := swapFromDeltas("sig", "w", -500_000_000, map[string]int64{token: 1_000})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if swap.InputMint != SolMint || swap.OutputMint != token || swap.InputAmount != 500_000_000 || swap.OutputAmount != 1_000 {
+			t.Errorf("Unexpected buy: %+v", swap)
+		}
+	})
+
+	t.Run("SellIntoWrappedSOL", func(t *testing.T) {
+		swap, err := swapFromDeltas("sig", "w", -5_000, map[string]int64{token: -1_000, SolMint: 300_000_000})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if swap.InputMint != token || swap.OutputMint != SolMint || swap.OutputAmount != 299_995_000 {
+			t.Errorf("Unexpected sell: %+v", swap)
+		}
+	})
+
+	t.Run("TokenToToken", func(t *testing.T) {
+		swap, err := swapFromDeltas("sig", "w", -2_039_280, map[string]int64{token: -10, "Other": 20})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if swap.InputMint != token || swap.OutputMint != "Other" {
+			t.Errorf("Unexpected swap: %+v", swap)
+		}
+	})
+
+	t.Run("TransferIsNotSwap", func(t *testing.T) {
+		if _, err := swapFromDeltas("sig", "w", 0, map[string]int64{token: 1_000}); !errors.Is(err, ErrNotSwap) {
+			t.Errorf("Expected ErrNotSwap, got %v", err)
+		}
+		if _, err := swapFromDeltas("sig", "w", -10_000, nil); !errors.Is(err, ErrNotSwap) {
+			t.Errorf("Expected ErrNotSwap, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"solana-orchestrator/config"
+)
+
+// performanceCheckInterval is how often every copy target's copied PnL is
+// checked against the loss limit
+const performanceCheckInterval = 5 * time.Minute
+
+// PerformanceStore is the PnL bookkeeping the guard needs
+type PerformanceStore interface {
+	TargetWindowPnL(userID int64, targetWallet string, since int64) (float64, int, error)
+	RemoveCopyTarget(userID int64, targetWallet string) error
+}
+
+// TargetPaused describes a copy target the guard deactivated
+type TargetPaused struct {
+	UserID       int64
+	TargetWallet string
+	WindowPnLSOL float64
+	Trades       int
+}
+
+// PerformanceGuard pauses copy targets whose copied trades net more than
+// the configured loss over a rolling window
+type PerformanceGuard struct {
+	store PerformanceStore
+	cfg   config.CopyTradingConfig
+	now   func() time.Time
+}
+
+// NewPerformanceGuard creates a guard using the copy trading settings
+func NewPerformanceGuard(store PerformanceStore, cfg config.CopyTradingConfig) *PerformanceGuard {
+	return &PerformanceGuard{store: store, cfg: cfg, now: time.Now}
+}
+
+// Check deactivates the target if it crossed the loss threshold and
+// returns what was paused, or nil if the target is still within limits
+func (g *PerformanceGuard) Check(userID int64, targetWallet string) (*TargetPaused, error) {
+	if !g.cfg.AutoDisable {
+		return nil, nil
+	}
+
+	since := g.now().Add(-time.Duration(g.cfg.WindowHours) * time.Hour).Unix()
+	pnl, trades, err := g.store.TargetWindowPnL(userID, targetWallet, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target pnl: %w", err)
+	}
+	if trades < g.cfg.MinTrades || pnl > -g.cfg.MaxLossSOL {
+		return nil, nil
+	}
+
+	if err := g.store.RemoveCopyTarget(userID, targetWallet); err != nil {
+		return nil, fmt.Errorf("failed to deactivate target: %w", err)
+	}
+	return &TargetPaused{
+		UserID:       userID,
+		TargetWallet: targetWallet,
+		WindowPnLSOL: pnl,
+		Trades:       trades,
+	}, nil
+}
+
+// performanceLoop runs the performance guard over every active copy target
+// periodically, so a target is paused however its copied sells closed
+func (e *FanOutEngine) performanceLoop() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(performanceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case <-ticker.C:
+			e.checkAllTargets()
+		}
+	}
+}
+
+// checkAllTargets runs CheckTargetPerformance for each active copy target
+func (e *FanOutEngine) checkAllTargets() {
+	targets, err := e.db.GetAllActiveCopyTargets()
+	if err != nil {
+		log.Printf("⚠️ Failed to list copy targets for performance check: %v", err)
+		return
+	}
+	for _, t := range targets {
+		if err := e.CheckTargetPerformance(t.UserID, t.TargetWallet); err != nil {
+			log.Printf("⚠️ Performance check failed for %s (user %d): %v", t.TargetWallet, t.UserID, err)
+		}
+	}
+}
+
+// CheckTargetPerformance runs the performance guard on one copy target. A
+// paused target is dropped from the wallet index and the
+// follower is notified.
+func (e *FanOutEngine) CheckTargetPerformance(userID int64, targetWallet string) error {
+	if e.guard == nil {
+		return nil
+	}
+
+	paused, err := e.guard.Check(userID, targetWallet)
+	if err != nil || paused == nil {
+		return err
+	}
+
+	if err := e.SyncMonitoredWallets(); err != nil {
+		return fmt.Errorf("failed to sync wallets after pausing target: %w", err)
+	}
+
+	note := Notification{
+		UserID: userID,
+		Kind:   NoticeAlert,
+		Message: fmt.Sprintf("⏸ Copy Target Paused\nTarget: %s\nCopied trades lost %.4f SOL over %d trades in the last %dh (limit %.2f SOL).\n\nRe-add it from /copytrade to resume.",
+			paused.TargetWallet, -paused.WindowPnLSOL, paused.Trades, e.cfg.CopyTrading.WindowHours, e.cfg.CopyTrading.MaxLossSOL),
+	}
+	select {
+	case e.notificationChan <- note:
+	case <-e.stopChan:
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"solana-orchestrator/config"
+)
+
+// fakePerformance serves a fixed window PnL and records removals
+type fakePerformance struct {
+	pnl     float64
+	trades  int
+	removed []string
+}
+
+func (f *fakePerformance) TargetWindowPnL(userID int64, targetWallet string, since int64) (float64, int, error) {
+	return f.pnl, f.trades, nil
+}
+
+func (f *fakePerformance) RemoveCopyTarget(userID int64, targetWallet string) error {
+	f.removed = append(f.removed, targetWallet)
+	return nil
+}
+
+func TestPerformanceGuard(t *testing.T) {
+	cfg := config.CopyTradingConfig{AutoDisable: true, MaxLossSOL: 0.5, WindowHours: 24, MinTrades: 3}
+
+	tests := []struct {
+		name       string
+		cfg        config.CopyTradingConfig
+		pnl        float64
+		trades     int
+		wantPaused bool
+	}{
+		{"WithinLimit", cfg, -0.4, 5, false},
+		{"TooFewTrades", cfg, -2, 2, false},
+		{"LossExceeded", cfg, -0.5, 3, true},
+		{"Disabled", config.CopyTradingConfig{MaxLossSOL: 0.5, WindowHours: 24}, -5, 10, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakePerformance{pnl: tt.pnl, trades: tt.trades}
+			paused, err := NewPerformanceGuard(store, tt.cfg).Check(1, "targetWallet")
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if (paused != nil) != tt.wantPaused {
+				t.Fatalf("Expected paused=%v, got %+v", tt.wantPaused, paused)
+			}
+			if tt.wantPaused && (len(store.removed) != 1 || store.removed[0] != "targetWallet") {
+				t.Errorf("Expected target removed, got %v", store.removed)
+			}
+			if !tt.wantPaused && len(store.removed) != 0 {
+				t.Errorf("Expected no removals, got %v", store.removed)
+			}
+		})
+	}
+
+	t.Run("NotifiesFollower", func(t *testing.T) {
+		engineCfg := &config.Config{CopyTrading: cfg}
+		engineCfg.FanOutEngine.LogBufferSize = 10
+		sender := &fakeSender{sent: make(chan tgbotapi.MessageConfig, 1)}
+		e := newFanOutEngine(engineCfg, fakeTargets{}, sender, &fakeSource{}, &fakeIndex{}, fakeSwaps{})
+		e.guard = NewPerformanceGuard(&fakePerformance{pnl: -1, trades: 4}, cfg)
+
+		if err := e.CheckTargetPerformance(9, "targetWallet"); err != nil {
+			t.Fatalf("CheckTargetPerformance failed: %v", err)
+		}
+		select {
+		case note := <-e.notificationChan:
+			if note.UserID != 9 {
+				t.Errorf("Expected notification for user 9, got %d", note.UserID)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected pause notification")
+		}
+	})
+
+	t.Run("ChecksAllTargets", func(t *testing.T) {
+		engineCfg := &config.Config{CopyTrading: cfg}
+		engineCfg.FanOutEngine.LogBufferSize = 10
+		targets := fakeTargets{
+			{UserID: 1, TargetWallet: "walletA"},
+			{UserID: 2, TargetWallet: "walletB"},
+		}
+		e := newFanOutEngine(engineCfg, targets, &fakeSender{}, &fakeSource{}, &fakeIndex{}, fakeSwaps{})
+		store := &fakePerformance{pnl: -1, trades: 4}
+		e.guard = NewPerformanceGuard(store, cfg)
+
+		e.checkAllTargets()
+		if len(store.removed) != 2 {
+			t.Errorf("Expected both losing targets paused, got %v", store.removed)
+		}
+		if len(e.notificationChan) != 2 {
+			t.Errorf("Expected 2 pause notifications, got %d", len(e.notificationChan))
+		}
+	})
+}
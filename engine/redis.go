@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"solana-orchestrator/storage"
@@ -119,3 +120,164 @@ func RemoveMonitoredWallet(ctx context.Context, rdb *redis.Client, wallet string
 
 	return nil
 }
+
+// RedisWalletIndex is the Redis-backed WalletIndex: monitored_wallets is a
+// set for O(1) membership checks and wallet_owner:<wallet> maps users to
+// their copy amounts
+type RedisWalletIndex struct {
+	rdb *redis.Client
+}
+
+// NewRedisWalletIndex creates a wallet index on top of a Redis client
+func NewRedisWalletIndex(rdb *redis.Client) *RedisWalletIndex {
+	return &RedisWalletIndex{rdb: rdb}
+}
+
+// Sync replaces the index with the given copy targets
+func (r *RedisWalletIndex) Sync(ctx context.Context, targets []*storage.CopyTradeTarget) error {
+	return SyncWalletsToRedis(ctx, r.rdb, targets)
+}
+
+// IsMonitored reports whether any user copies wallet
+func (r *RedisWalletIndex) IsMonitored(ctx context.Context, wallet string) (bool, error) {
+	return r.rdb.SIsMember(ctx, "monitored_wallets", wallet).Result()
+}
+
+// Owners returns the users copying wallet and their copy amounts
+func (r *RedisWalletIndex) Owners(ctx context.Context, wallet string) (map[int64]float64, error) {
+	return GetWalletOwners(ctx, r.rdb, wallet)
+}
+
+// ReconcileReport summarizes the drift a reconciliation pass repaired
+type ReconcileReport struct {
+	Added       []string // wallets copied in the DB but missing from the index
+	Removed     []string // wallets in the index that nobody copies anymore
+	OwnersFixed int      // owner entries added, removed or corrected
+}
+
+// HasDrift reports whether the index differed from the DB
+func (r *ReconcileReport) HasDrift() bool {
+	return len(r.Added) > 0 || len(r.Removed) > 0 || r.OwnersFixed > 0
+}
+
+// indexDiff is the set of writes that turns the current index into the
+// desired one
+type indexDiff struct {
+	report ReconcileReport
+	set    map[string]map[int64]float64 // wallet -> owners to write
+	unset  map[string][]int64           // wallet -> owners to delete
+}
+
+// desiredOwners groups copy targets into wallet -> user -> copy amount
+func desiredOwners(targets []*storage.CopyTradeTarget) map[string]map[int64]float64 {
+	desired := make(map[string]map[int64]float64)
+	for _, t := range targets {
+		if desired[t.TargetWallet] == nil {
+			desired[t.TargetWallet] = make(map[int64]float64)
+		}
+		desired[t.TargetWallet][t.UserID] = t.CopyAmountSOL
+	}
+	return desired
+}
+
+// diffIndex compares the monitored set and owner hashes against the
+// desired state. owners only needs entries for desired wallets.
+func diffIndex(members map[string]bool, owners, desired map[string]map[int64]float64) indexDiff {
+	diff := indexDiff{
+		set:   make(map[string]map[int64]float64),
+		unset: make(map[string][]int64),
+	}
+
+	for wallet := range members {
+		if _, ok := desired[wallet]; !ok {
+			diff.report.Removed = append(diff.report.Removed, wallet)
+		}
+	}
+
+	for wallet, want := range desired {
+		if !members[wallet] {
+			diff.report.Added = append(diff.report.Added, wallet)
+		}
+
+		have := owners[wallet]
+		for userID, amount := range want {
+			if current, ok := have[userID]; !ok || current != amount {
+				if diff.set[wallet] == nil {
+					diff.set[wallet] = make(map[int64]float64)
+				}
+				diff.set[wallet][userID] = amount
+				diff.report.OwnersFixed++
+			}
+		}
+		for userID := range have {
+			if _, ok := want[userID]; !ok {
+				diff.unset[wallet] = append(diff.unset[wallet], userID)
+				diff.report.OwnersFixed++
+			}
+		}
+	}
+
+	sort.Strings(diff.report.Added)
+	sort.Strings(diff.report.Removed)
+	return diff
+}
+
+// Reconcile repairs the index against targets, the source of truth,
+// touching only the entries that drifted
+func (r *RedisWalletIndex) Reconcile(ctx context.Context, targets []*storage.CopyTradeTarget) (*ReconcileReport, error) {
+	desired := desiredOwners(targets)
+
+	current, err := r.rdb.SMembers(ctx, "monitored_wallets").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read monitored wallets: %w", err)
+	}
+	members := make(map[string]bool, len(current))
+	for _, wallet := range current {
+		members[wallet] = true
+	}
+
+	owners := make(map[string]map[int64]float64, len(desired))
+	for wallet := range desired {
+		o, err := GetWalletOwners(ctx, r.rdb, wallet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read owners of %s: %w", wallet, err)
+		}
+		owners[wallet] = o
+	}
+
+	diff := diffIndex(members, owners, desired)
+	if !diff.report.HasDrift() {
+		return &diff.report, nil
+	}
+
+	pipe := r.rdb.TxPipeline()
+	for _, wallet := range diff.report.Removed {
+		pipe.SRem(ctx, "monitored_wallets", wallet)
+		pipe.Del(ctx, fmt.Sprintf("wallet_owner:%s", wallet))
+	}
+	for wallet, users := range diff.unset {
+		fields := make([]string, 0, len(users))
+		for _, userID := range users {
+			fields = append(fields, fmt.Sprintf("%d", userID))
+		}
+		pipe.HDel(ctx, fmt.Sprintf("wallet_owner:%s", wallet), fields...)
+	}
+	for wallet, users := range diff.set {
+		key := fmt.Sprintf("wallet_owner:%s", wallet)
+		for userID, amount := range users {
+			pipe.HSet(ctx, key, fmt.Sprintf("%d", userID), amount)
+		}
+	}
+	if len(diff.report.Added) > 0 {
+		added := make([]interface{}, 0, len(diff.report.Added))
+		for _, wallet := range diff.report.Added {
+			added = append(added, wallet)
+		}
+		pipe.SAdd(ctx, "monitored_wallets", added...)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to apply reconciliation: %w", err)
+	}
+	return &diff.report, nil
+}
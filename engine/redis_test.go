@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"testing"
+
+	"solana-orchestrator/storage"
+)
+
+func TestDiffIndex(t *testing.T) {
+	desired := desiredOwners([]*storage.CopyTradeTarget{
+		{UserID: 1, TargetWallet: "walletA", CopyAmountSOL: 0.1},
+		{UserID: 2, TargetWallet: "walletA", CopyAmountSOL: 0.5},
+		{UserID: 1, TargetWallet: "walletB", CopyAmountSOL: 0.3},
+	})
+
+	t.Run("InSync", func(t *testing.T) {
+		members := map[string]bool{"walletA": true, "walletB": true}
+		diff := diffIndex(members, desired, desired)
+		if diff.report.HasDrift() {
+			t.Errorf("Expected no drift, got %+v", diff.report)
+		}
+	})
+
+	t.Run("FlushedRedis", func(t *testing.T) {
+		diff := diffIndex(map[string]bool{}, map[string]map[int64]float64{}, desired)
+		if len(diff.report.Added) != 2 || diff.report.Added[0] != "walletA" {
+			t.Errorf("Expected both wallets added, got %v", diff.report.Added)
+		}
+		if diff.report.OwnersFixed != 3 {
+			t.Errorf("Expected 3 owner entries fixed, got %d", diff.report.OwnersFixed)
+		}
+	})
+
+	t.Run("StaleEntries", func(t *testing.T) {
+		members := map[string]bool{"walletA": true, "walletB": true, "walletC": true}
+		owners := map[string]map[int64]float64{
+			"walletA": {1: 0.1, 2: 0.4, 9: 1},
+			"walletB": {1: 0.3},
+		}
+		diff := diffIndex(members, owners, desired)
+
+		if len(diff.report.Removed) != 1 || diff.report.Removed[0] != "walletC" {
+			t.Errorf("Expected walletC removed, got %v", diff.report.Removed)
+		}
+		if len(diff.report.Added) != 0 {
+			t.Errorf("Expected nothing added, got %v", diff.report.Added)
+		}
+		if diff.set["walletA"][2] != 0.5 {
+			t.Errorf("Expected corrected copy amount, got %v", diff.set["walletA"])
+		}
+		if len(diff.unset["walletA"]) != 1 || diff.unset["walletA"][0] != 9 {
+			t.Errorf("Expected user 9 removed, got %v", diff.unset["walletA"])
+		}
+		if diff.report.OwnersFixed != 2 {
+			t.Errorf("Expected 2 owner entries fixed, got %d", diff.report.OwnersFixed)
+		}
+	})
+}
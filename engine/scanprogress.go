@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Scan progress keys. The latest snapshot lives under ScanProgressKey for
+// pollers; every update is also published on ScanProgressChannel.
+const (
+	ScanProgressKey     = "scan:progress"
+	ScanProgressChannel = "scan:progress:updates"
+	scanProgressTTL     = time.Hour
+)
+
+// ScanProgress is a snapshot of the wallet scanner
+type ScanProgress struct {
+	IsScanning    bool   `json:"is_scanning"`
+	ScannedCount  int    `json:"scanned_count"`
+	TotalWallets  int    `json:"total_wallets"`
+	FoundWallets  int    `json:"found_wallets"`
+	LastUpdate    int64  `json:"last_update"`     // unix seconds
+	ScanStartTime int64  `json:"scan_start_time"` // unix seconds, 0 if never started
+	Error         string `json:"error,omitempty"` // why the last cycle failed, if it did
+}
+
+// Percent returns how much of the scan is done, 0-100
+func (p *ScanProgress) Percent() float64 {
+	if p.TotalWallets <= 0 {
+		return 0
+	}
+	pct := float64(p.ScannedCount) / float64(p.TotalWallets) * 100
+	if pct > 100 {
+		pct = 100
+	}
+	return pct
+}
+
+// Elapsed returns how long the scan has run, up to now while scanning and
+// up to the last update once finished
+func (p *ScanProgress) Elapsed(now time.Time) time.Duration {
+	if p.ScanStartTime == 0 {
+		return 0
+	}
+	end := now
+	if !p.IsScanning && p.LastUpdate > 0 {
+		end = time.Unix(p.LastUpdate, 0)
+	}
+	if elapsed := end.Sub(time.Unix(p.ScanStartTime, 0)); elapsed > 0 {
+		return elapsed
+	}
+	return 0
+}
+
+// PublishScanProgress stores p as the latest snapshot and notifies
+// subscribers
+func PublishScanProgress(ctx context.Context, rdb *redis.Client, p ScanProgress) error {
+	if p.LastUpdate == 0 {
+		p.LastUpdate = time.Now().Unix()
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, ScanProgressKey, data, scanProgressTTL)
+	pipe.Publish(ctx, ScanProgressChannel, data)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetScanProgress returns the latest snapshot, or nil if no scan has
+// reported within the last hour
+func GetScanProgress(ctx context.Context, rdb *redis.Client) (*ScanProgress, error) {
+	data, err := rdb.Get(ctx, ScanProgressKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeScanProgress(data)
+}
+
+// SubscribeScanProgress pushes every published snapshot until ctx is
+// cancelled, then closes the channel
+func SubscribeScanProgress(ctx context.Context, rdb *redis.Client) (<-chan ScanProgress, error) {
+	sub := rdb.Subscribe(ctx, ScanProgressChannel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("subscribe %s: %w", ScanProgressChannel, err)
+	}
+
+	out := make(chan ScanProgress, 16)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+
+		msgs := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				p, err := decodeScanProgress([]byte(msg.Payload))
+				if err != nil {
+					log.Printf("⚠️ Ignoring malformed scan progress: %v", err)
+					continue
+				}
+				select {
+				case out <- *p:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func decodeScanProgress(data []byte) (*ScanProgress, error) {
+	var p ScanProgress
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("decode scan progress: %w", err)
+	}
+	return &p, nil
+}
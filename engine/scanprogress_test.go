@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// TestScanProgress tests decoding and the derived progress figures
+func TestScanProgress(t *testing.T) {
+	raw := `{"is_scanning":true,"scanned_count":25,"total_wallets":100,"found_wallets":3,"last_update":1700000060,"scan_start_time":1700000000}`
+	p, err := decodeScanProgress([]byte(raw))
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if p.ScannedCount != 25 || p.TotalWallets != 100 || p.FoundWallets != 3 {
+		t.Errorf("Unexpected snapshot %+v", p)
+	}
+	if p.Percent() != 25 {
+		t.Errorf("Expected 25%%, got %v", p.Percent())
+	}
+
+	now := time.Unix(1700000090, 0)
+	if got := p.Elapsed(now); got != 90*time.Second {
+		t.Errorf("Expected elapsed to run to now while scanning, got %v", got)
+	}
+	p.IsScanning = false
+	if got := p.Elapsed(now); got != 60*time.Second {
+		t.Errorf("Expected elapsed to stop at last update, got %v", got)
+	}
+
+	empty := &ScanProgress{}
+	if empty.Percent() != 0 || empty.Elapsed(now) != 0 {
+		t.Error("Expected zero progress for an empty snapshot")
+	}
+
+	if _, err := decodeScanProgress([]byte("{")); err == nil {
+		t.Error("Expected malformed JSON to fail")
+	}
+}
@@ -0,0 +1,32 @@
+package engine
+
+// CopySizing decides how much SOL a copied buy spends. With a zero Ratio
+// every buy spends FixedSOL; otherwise the copy spends Ratio of the SOL
+// the target spent, capped at MaxSOL when set. Swaps whose SOL side
+// wasn't parsed fall back to FixedSOL.
+type CopySizing struct {
+	FixedSOL float64
+	Ratio    float64 // e.g. 0.1 copies 10% of the target's buy
+	MaxSOL   float64 // 0 means no cap
+}
+
+// FixedCopySizing spends the same amount on every copied buy
+func FixedCopySizing(sol float64) CopySizing {
+	return CopySizing{FixedSOL: sol}
+}
+
+// BuySOL returns the SOL to spend copying swap
+func (s CopySizing) BuySOL(swap *SwapInfo) float64 {
+	if s.Ratio <= 0 {
+		return s.FixedSOL
+	}
+	targetSOL := swap.SOLAmount()
+	if targetSOL <= 0 {
+		return s.FixedSOL
+	}
+	sol := targetSOL * s.Ratio
+	if s.MaxSOL > 0 && sol > s.MaxSOL {
+		sol = s.MaxSOL
+	}
+	return sol
+}
@@ -0,0 +1,46 @@
+package engine
+
+import "testing"
+
+// TestCopySizing tests fixed and proportional buy sizing from a parsed
+// swap's amounts
+func TestCopySizing(t *testing.T) {
+	const token = "TokenMint111"
+
+	// A sample buy: 2 SOL for 1,000 tokens
+	buy, err := swapFromDeltas("sig", "w", -2_000_000_000, map[string]int64{token: 1_000})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buy.InputAmount != 2_000_000_000 || buy.OutputAmount != 1_000 {
+		t.Fatalf("Unexpected parsed amounts: %+v", buy)
+	}
+
+	tests := []struct {
+		name   string
+		sizing CopySizing
+		swap   *SwapInfo
+		want   float64
+	}{
+		{"Fixed", FixedCopySizing(0.1), buy, 0.1},
+		{"Proportional", CopySizing{FixedSOL: 0.1, Ratio: 0.25}, buy, 0.5},
+		{"ProportionalCapped", CopySizing{FixedSOL: 0.1, Ratio: 0.5, MaxSOL: 0.3}, buy, 0.3},
+		{"UnparsedFallsBackToFixed", CopySizing{FixedSOL: 0.1, Ratio: 0.5}, &SwapInfo{InputMint: SolMint, OutputMint: token}, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sizing.BuySOL(tt.swap); got != tt.want {
+				t.Errorf("Expected %g SOL, got %g", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPricePerToken(t *testing.T) {
+	if got := pricePerToken(0.5, 1_000); got != 0.0005 {
+		t.Errorf("Expected 0.0005 SOL per unit, got %g", got)
+	}
+	if got := pricePerToken(0.5, 0); got != 0 {
+		t.Errorf("Expected 0 without a token amount, got %g", got)
+	}
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"solana-orchestrator/trading"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Swap fetch errors
+var (
+	ErrSwapNotFound = errors.New("transaction not found")
+	ErrSwapFailed   = errors.New("transaction failed on-chain")
+)
+
+// RPCSwapFetcher resolves a signature into the swap a wallet made from
+// the transaction's swap instructions and the wallet's balance changes
+type RPCSwapFetcher struct {
+	client trading.TransactionClient
+}
+
+// NewRPCSwapFetcher creates a swap fetcher backed by an RPC client
+func NewRPCSwapFetcher(client trading.TransactionClient) *RPCSwapFetcher {
+	return &RPCSwapFetcher{client: client}
+}
+
+// newDefaultSwapFetcher builds the swap fetcher used by NewFanOutEngine
+func newDefaultSwapFetcher(rpcURL string) SwapFetcher {
+	return NewRPCSwapFetcher(rpc.New(rpcURL))
+}
+
+// FetchAndParseSwap fetches a confirmed transaction and derives which mint
+// wallet sold and which it bought. Transactions without a known DEX swap
+// instruction, top-level or inner, return ErrNotSwap.
+func (f *RPCSwapFetcher) FetchAndParseSwap(ctx context.Context, signature, wallet string) (*SwapInfo, error) {
+	sig, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	owner, err := solana.PublicKeyFromBase58(wallet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet: %w", err)
+	}
+
+	maxVersion := uint64(0)
+	result, err := f.client.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && (result == nil || result.Meta == nil || result.Transaction == nil)) {
+		return nil, ErrSwapNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if result.Meta.Err != nil {
+		return nil, ErrSwapFailed
+	}
+
+	tx, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	if tx == nil {
+		return nil, ErrSwapNotFound
+	}
+
+	// Native SOL change, with the fee added back if wallet paid it
+	var solDelta int64
+	for i, key := range transactionKeys(tx, result.Meta) {
+		if !key.Equals(owner) {
+			continue
+		}
+		if i < len(result.Meta.PreBalances) && i < len(result.Meta.PostBalances) {
+			solDelta = int64(result.Meta.PostBalances[i]) - int64(result.Meta.PreBalances[i])
+			if i == 0 {
+				solDelta += int64(result.Meta.Fee)
+			}
+		}
+		break
+	}
+
+	tokenDeltas := make(map[string]int64)
+	addBalances := func(balances []rpc.TokenBalance, sign int64) {
+		for _, b := range balances {
+			if b.Owner == nil || !b.Owner.Equals(owner) || b.UiTokenAmount == nil {
+				continue
+			}
+			amount, err := strconv.ParseInt(b.UiTokenAmount.Amount, 10, 64)
+			if err != nil {
+				continue
+			}
+			tokenDeltas[b.Mint.String()] += sign * amount
+		}
+	}
+	addBalances(result.Meta.PreTokenBalances, -1)
+	addBalances(result.Meta.PostTokenBalances, 1)
+
+	// Balance changes alone can't tell a swap from a transfer that
+	// happened to cost rent, so require a DEX swap instruction
+	swaps := DecodeSwapInstructions(tx, result.Meta)
+	if len(swaps) == 0 {
+		return nil, fmt.Errorf("%w: no DEX swap instruction", ErrNotSwap)
+	}
+	swap, err := swapFromInstruction(signature, wallet, &swaps[0], solDelta, tokenDeltas)
+	if err != nil {
+		return nil, err
+	}
+	if result.BlockTime != nil {
+		swap.Timestamp = int64(*result.BlockTime)
+	}
+	return swap, nil
+}
+
+// swapFromInstruction builds the swap from the outermost swap
+// instruction. When the instruction names both mints they are trusted
+// over the balance changes, which can include unrelated transfers; the
+// changes still give the executed amounts, falling back to the
+// instruction's own amounts when the wallet's balances didn't move.
+func swapFromInstruction(signature, wallet string, ix *SwapInstruction, solDelta int64, tokenDeltas map[string]int64) (*SwapInfo, error) {
+	if ix.InputMint == "" || ix.OutputMint == "" {
+		swap, err := swapFromDeltas(signature, wallet, solDelta, tokenDeltas)
+		if err != nil {
+			return nil, err
+		}
+		swap.ProgramID = ix.ProgramID
+		return swap, nil
+	}
+
+	delta := func(mint string) int64 {
+		if mint == SolMint {
+			return solDelta + tokenDeltas[SolMint]
+		}
+		return tokenDeltas[mint]
+	}
+	swap := &SwapInfo{
+		Signature:    signature,
+		Wallet:       wallet,
+		ProgramID:    ix.ProgramID,
+		InputMint:    ix.InputMint,
+		OutputMint:   ix.OutputMint,
+		InputAmount:  ix.AmountIn,
+		OutputAmount: ix.AmountOut,
+	}
+	if d := delta(ix.InputMint); d < 0 {
+		swap.InputAmount = uint64(-d)
+	}
+	if d := delta(ix.OutputMint); d > 0 {
+		swap.OutputAmount = uint64(d)
+	}
+	return swap, nil
+}
@@ -0,0 +1,260 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"solana-orchestrator/config"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SwapInstruction is a DEX swap found in a transaction's instructions.
+// Mints are set only when the instruction's accounts name them, and
+// amounts only when its data carries them.
+type SwapInstruction struct {
+	ProgramID  string
+	Name       string // e.g. "shared_accounts_route"
+	Inner      bool   // invoked by another program, e.g. a Jupiter route hop
+	InputMint  string
+	OutputMint string
+	AmountIn   uint64 // exact or maximum input
+	AmountOut  uint64 // exact, quoted or minimum output
+}
+
+// rawInstruction is an instruction with its account indexes resolved
+type rawInstruction struct {
+	ProgramID solana.PublicKey
+	Accounts  []solana.PublicKey
+	Data      []byte
+	Inner     bool
+}
+
+// swapLayout decodes one swap instruction variant. decode may be nil for
+// programs we only need to recognise.
+type swapLayout struct {
+	name   string
+	decode func(ix *SwapInstruction, accounts []solana.PublicKey, data []byte)
+}
+
+// swapProgram maps a program's instruction tags to swap layouts. Anchor
+// programs use 8-byte discriminators, Raydium AMM v4 a 1-byte tag.
+type swapProgram struct {
+	tagLen  int
+	layouts map[string]swapLayout
+}
+
+// swapPrograms covers every program the fan-out subscribes to, plus
+// Raydium CPMM which Jupiter routes through
+var swapPrograms = map[string]swapProgram{
+	config.ProgramJupiterV6: anchorProgram(map[string]swapLayout{
+		"route":                   {decode: jupiterDecoder(-1, 5, false, false)},
+		"route_with_token_ledger": {decode: jupiterDecoder(-1, 5, false, true)},
+		"exact_out_route":         {decode: jupiterDecoder(5, 6, true, false)},
+		"shared_accounts_route":   {decode: jupiterDecoder(7, 8, false, false)},
+		"shared_accounts_route_with_token_ledger": {decode: jupiterDecoder(7, 8, false, true)},
+		"shared_accounts_exact_out_route":         {decode: jupiterDecoder(7, 8, true, false)},
+	}),
+	config.ProgramRaydiumAMMV4: {tagLen: 1, layouts: map[string]swapLayout{
+		"\x09": {name: "swap_base_in", decode: amountsDecoder(1)},
+		"\x0b": {name: "swap_base_out", decode: amountsDecoder(1)},
+		"\x10": {name: "swap_base_in_v2", decode: amountsDecoder(1)},
+		"\x11": {name: "swap_base_out_v2", decode: amountsDecoder(1)},
+	}},
+	config.ProgramRaydiumCPMM: anchorProgram(map[string]swapLayout{
+		"swap_base_input":  {decode: mintsDecoder(10, 11, amountsDecoder(8))},
+		"swap_base_output": {decode: mintsDecoder(10, 11, amountsDecoder(8))},
+	}),
+	config.ProgramRaydiumCLMM: anchorProgram(map[string]swapLayout{
+		"swap":    {decode: clmmDecoder},
+		"swap_v2": {decode: mintsDecoder(11, 12, clmmDecoder)},
+	}),
+	config.ProgramMeteoraDLMM: anchorProgram(map[string]swapLayout{
+		"swap": {}, "swap_exact_out": {}, "swap_with_price_impact": {},
+		"swap2": {}, "swap_exact_out2": {}, "swap_with_price_impact2": {},
+	}),
+	config.ProgramOrcaWhirlpool: anchorProgram(map[string]swapLayout{
+		"swap": {}, "swap_v2": {}, "two_hop_swap": {}, "two_hop_swap_v2": {},
+	}),
+	config.ProgramPumpFun: anchorProgram(map[string]swapLayout{
+		"buy": {}, "sell": {},
+	}),
+}
+
+// anchorProgram keys layouts by the Anchor discriminator of their name
+func anchorProgram(layouts map[string]swapLayout) swapProgram {
+	byTag := make(map[string]swapLayout, len(layouts))
+	for name, layout := range layouts {
+		layout.name = name
+		sum := sha256.Sum256([]byte("global:" + name))
+		byTag[string(sum[:8])] = layout
+	}
+	return swapProgram{tagLen: 8, layouts: byTag}
+}
+
+// DecodeSwapInstructions returns the DEX swaps in a transaction in
+// execution order, each top-level instruction followed by the inner
+// instructions it invoked
+func DecodeSwapInstructions(tx *solana.Transaction, meta *rpc.TransactionMeta) []SwapInstruction {
+	var swaps []SwapInstruction
+	for _, ix := range transactionInstructions(tx, meta) {
+		if swap, ok := decodeSwapInstruction(ix); ok {
+			swaps = append(swaps, swap)
+		}
+	}
+	return swaps
+}
+
+// transactionInstructions flattens top-level and inner instructions,
+// resolving account indexes against static and lookup-table keys
+func transactionInstructions(tx *solana.Transaction, meta *rpc.TransactionMeta) []rawInstruction {
+	keys := transactionKeys(tx, meta)
+
+	var out []rawInstruction
+	add := func(programIndex int, accounts []solana.PublicKey, ok bool, data []byte, inner bool) {
+		if !ok || programIndex < 0 || programIndex >= len(keys) {
+			return
+		}
+		out = append(out, rawInstruction{ProgramID: keys[programIndex], Accounts: accounts, Data: data, Inner: inner})
+	}
+
+	for i, ix := range tx.Message.Instructions {
+		accounts, ok := resolveAccounts(keys, ix.Accounts)
+		add(int(ix.ProgramIDIndex), accounts, ok, ix.Data, false)
+		if meta == nil {
+			continue
+		}
+		for _, inner := range meta.InnerInstructions {
+			if int(inner.Index) != i {
+				continue
+			}
+			for _, innerIx := range inner.Instructions {
+				accounts, ok := resolveAccounts(keys, innerIx.Accounts)
+				add(int(innerIx.ProgramIDIndex), accounts, ok, innerIx.Data, true)
+			}
+		}
+	}
+	return out
+}
+
+// transactionKeys lists the static account keys followed by those loaded
+// from lookup tables, the order instruction indexes refer to
+func transactionKeys(tx *solana.Transaction, meta *rpc.TransactionMeta) solana.PublicKeySlice {
+	keys := append(solana.PublicKeySlice{}, tx.Message.AccountKeys...)
+	if meta != nil {
+		keys = append(keys, meta.LoadedAddresses.Writable...)
+		keys = append(keys, meta.LoadedAddresses.ReadOnly...)
+	}
+	return keys
+}
+
+// resolveAccounts maps account indexes to keys, failing on any index
+// outside the transaction's key list
+func resolveAccounts[T ~uint8 | ~uint16 | ~int | ~int64](keys solana.PublicKeySlice, indexes []T) ([]solana.PublicKey, bool) {
+	accounts := make([]solana.PublicKey, len(indexes))
+	for i, idx := range indexes {
+		if int(idx) < 0 || int(idx) >= len(keys) {
+			return nil, false
+		}
+		accounts[i] = keys[idx]
+	}
+	return accounts, true
+}
+
+// decodeSwapInstruction recognises a swap by program ID and
+// discriminator and extracts what its accounts and data carry
+func decodeSwapInstruction(ix rawInstruction) (SwapInstruction, bool) {
+	program, ok := swapPrograms[ix.ProgramID.String()]
+	if !ok || len(ix.Data) < program.tagLen {
+		return SwapInstruction{}, false
+	}
+	layout, ok := program.layouts[string(ix.Data[:program.tagLen])]
+	if !ok {
+		return SwapInstruction{}, false
+	}
+
+	swap := SwapInstruction{ProgramID: ix.ProgramID.String(), Name: layout.name, Inner: ix.Inner}
+	if layout.decode != nil {
+		layout.decode(&swap, ix.Accounts, ix.Data)
+	}
+	return swap, true
+}
+
+// jupiterDecoder reads a Jupiter v6 route. Every route variant ends with
+// two u64 amounts, slippage_bps (u16) and platform_fee_bps (u8); the
+// token-ledger variants carry only the quoted output since the input is
+// whatever the ledger holds. A mint index of -1 means the variant has no
+// such account.
+func jupiterDecoder(inputMint, outputMint int, exactOut, tokenLedger bool) func(*SwapInstruction, []solana.PublicKey, []byte) {
+	return func(ix *SwapInstruction, accounts []solana.PublicKey, data []byte) {
+		ix.InputMint = accountAt(accounts, inputMint)
+		ix.OutputMint = accountAt(accounts, outputMint)
+
+		const fees = 2 + 1 // slippage_bps, platform_fee_bps
+		if tokenLedger {
+			// quoted_out_amount
+			if len(data) >= 8+8+fees {
+				ix.AmountOut = binary.LittleEndian.Uint64(data[len(data)-8-fees:])
+			}
+			return
+		}
+		if len(data) < 8+16+fees {
+			return
+		}
+		first := binary.LittleEndian.Uint64(data[len(data)-16-fees:])
+		second := binary.LittleEndian.Uint64(data[len(data)-8-fees:])
+		if exactOut {
+			// out_amount, quoted_in_amount
+			ix.AmountIn, ix.AmountOut = second, first
+			return
+		}
+		// in_amount, quoted_out_amount
+		ix.AmountIn, ix.AmountOut = first, second
+	}
+}
+
+// amountsDecoder reads the two u64 amounts at offset. Exact-input swaps
+// give (amount in, minimum out) and exact-output swaps (maximum in,
+// amount out), so both map onto AmountIn and AmountOut in order.
+func amountsDecoder(offset int) func(*SwapInstruction, []solana.PublicKey, []byte) {
+	return func(ix *SwapInstruction, accounts []solana.PublicKey, data []byte) {
+		if len(data) < offset+16 {
+			return
+		}
+		ix.AmountIn = binary.LittleEndian.Uint64(data[offset:])
+		ix.AmountOut = binary.LittleEndian.Uint64(data[offset+8:])
+	}
+}
+
+// clmmDecoder reads a Raydium CLMM swap: amount, other_amount_threshold,
+// sqrt_price_limit_x64 (u128) and is_base_input
+func clmmDecoder(ix *SwapInstruction, accounts []solana.PublicKey, data []byte) {
+	if len(data) < 8+8+8+16+1 {
+		return
+	}
+	amount := binary.LittleEndian.Uint64(data[8:])
+	threshold := binary.LittleEndian.Uint64(data[16:])
+	if data[40] != 0 {
+		ix.AmountIn, ix.AmountOut = amount, threshold
+		return
+	}
+	ix.AmountIn, ix.AmountOut = threshold, amount
+}
+
+// mintsDecoder reads the input and output mints from fixed account
+// positions before running next
+func mintsDecoder(inputMint, outputMint int, next func(*SwapInstruction, []solana.PublicKey, []byte)) func(*SwapInstruction, []solana.PublicKey, []byte) {
+	return func(ix *SwapInstruction, accounts []solana.PublicKey, data []byte) {
+		ix.InputMint = accountAt(accounts, inputMint)
+		ix.OutputMint = accountAt(accounts, outputMint)
+		next(ix, accounts, data)
+	}
+}
+
+func accountAt(accounts []solana.PublicKey, i int) string {
+	if i < 0 || i >= len(accounts) {
+		return ""
+	}
+	return accounts[i].String()
+}
@@ -0,0 +1,255 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"solana-orchestrator/config"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/mr-tron/base58"
+)
+
+const (
+	bonkMint = "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263"
+	usdcMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+)
+
+// ixData builds instruction data from a tag followed by little-endian
+// fields
+func ixData(tag []byte, fields ...interface{}) []byte {
+	data := append([]byte{}, tag...)
+	for _, f := range fields {
+		switch v := f.(type) {
+		case uint64:
+			data = binary.LittleEndian.AppendUint64(data, v)
+		case uint16:
+			data = binary.LittleEndian.AppendUint16(data, v)
+		case uint8:
+			data = append(data, v)
+		case bool:
+			if v {
+				data = append(data, 1)
+			} else {
+				data = append(data, 0)
+			}
+		case []byte:
+			data = append(data, v...)
+		}
+	}
+	return data
+}
+
+func anchorTag(name string) []byte {
+	sum := sha256.Sum256([]byte("global:" + name))
+	return sum[:8]
+}
+
+// swapTx is a transaction under construction: account keys plus the
+// meta JSON an RPC node would return for it
+type swapTx struct {
+	keys   solana.PublicKeySlice
+	loaded []solana.PublicKey // readonly lookup-table addresses
+	tx     solana.Transaction
+	inner  []map[string]interface{}
+}
+
+// key returns the index of k, adding it to the static keys if needed
+func (s *swapTx) key(k string) uint16 {
+	pk := solana.MustPublicKeyFromBase58(k)
+	for i, existing := range s.keys {
+		if existing.Equals(pk) {
+			return uint16(i)
+		}
+	}
+	s.keys = append(s.keys, pk)
+	return uint16(len(s.keys) - 1)
+}
+
+func (s *swapTx) accounts(keys ...string) []uint16 {
+	idx := make([]uint16, len(keys))
+	for i, k := range keys {
+		idx[i] = s.key(k)
+	}
+	return idx
+}
+
+func (s *swapTx) top(program string, accounts []uint16, data []byte) {
+	s.tx.Message.Instructions = append(s.tx.Message.Instructions, solana.CompiledInstruction{
+		ProgramIDIndex: s.key(program),
+		Accounts:       accounts,
+		Data:           data,
+	})
+}
+
+func (s *swapTx) innerOf(index int, program uint16, accounts []uint16, data []byte) {
+	s.inner = append(s.inner, map[string]interface{}{
+		"index": index,
+		"instructions": []map[string]interface{}{
+			{"programIdIndex": program, "accounts": accounts, "data": base58.Encode(data)},
+		},
+	})
+}
+
+func (s *swapTx) build(t *testing.T) (*solana.Transaction, *rpc.TransactionMeta) {
+	t.Helper()
+	raw, err := json.Marshal(map[string]interface{}{
+		"innerInstructions": s.inner,
+		"loadedAddresses":   map[string]interface{}{"writable": []string{}, "readonly": s.loaded},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta rpc.TransactionMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		t.Fatalf("Failed to decode meta: %v", err)
+	}
+	s.tx.Message.AccountKeys = s.keys
+	return &s.tx, &meta
+}
+
+func newKey() string { return solana.NewWallet().PublicKey().String() }
+
+// TestDecodeSwapInstructions tests recognising swaps by program and
+// discriminator, including CPI hops and lookup-table accounts
+func TestDecodeSwapInstructions(t *testing.T) {
+	t.Run("JupiterRouteWithInnerRaydium", func(t *testing.T) {
+		var s swapTx
+		wallet := newKey()
+		s.key(wallet)
+		jupAccounts := s.accounts(
+			"TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA", newKey(), wallet, newKey(), newKey(), newKey(), newKey(),
+			SolMint, bonkMint,
+		)
+		// route_plan bytes vary with the hops; the amounts sit at the end
+		s.top(config.ProgramJupiterV6, jupAccounts,
+			ixData(anchorTag("shared_accounts_route"), uint8(3), []byte{1, 0, 0, 0, 7, 100, 0, 1}, uint64(1_000_000_000), uint64(42_000_000_000), uint16(50), uint8(0)))
+		s.innerOf(0, s.key(config.ProgramRaydiumAMMV4), s.accounts(newKey(), newKey()),
+			ixData([]byte{9}, uint64(1_000_000_000), uint64(41_790_000_000)))
+		// Compute budget and token program calls are not swaps
+		s.top("ComputeBudget111111111111111111111111111111", nil, []byte{2, 0, 0, 0, 0})
+
+		swaps := DecodeSwapInstructions(s.build(t))
+		if len(swaps) != 2 {
+			t.Fatalf("Expected 2 swaps, got %+v", swaps)
+		}
+		jup := swaps[0]
+		if jup.Name != "shared_accounts_route" || jup.Inner || jup.InputMint != SolMint || jup.OutputMint != bonkMint ||
+			jup.AmountIn != 1_000_000_000 || jup.AmountOut != 42_000_000_000 {
+			t.Errorf("Unexpected Jupiter swap: %+v", jup)
+		}
+		ray := swaps[1]
+		if ray.ProgramID != config.ProgramRaydiumAMMV4 || ray.Name != "swap_base_in" || !ray.Inner ||
+			ray.InputMint != "" || ray.AmountIn != 1_000_000_000 || ray.AmountOut != 41_790_000_000 {
+			t.Errorf("Unexpected Raydium hop: %+v", ray)
+		}
+	})
+
+	t.Run("JupiterExactOut", func(t *testing.T) {
+		var s swapTx
+		s.top(config.ProgramJupiterV6, s.accounts(newKey(), newKey(), newKey(), newKey(), newKey(), bonkMint, usdcMint),
+			ixData(anchorTag("exact_out_route"), uint8(0), uint64(5_000_000), uint64(123_000), uint16(100), uint8(0)))
+
+		swaps := DecodeSwapInstructions(s.build(t))
+		if len(swaps) != 1 || swaps[0].InputMint != bonkMint || swaps[0].OutputMint != usdcMint ||
+			swaps[0].AmountIn != 123_000 || swaps[0].AmountOut != 5_000_000 {
+			t.Errorf("Unexpected exact-out swap: %+v", swaps)
+		}
+	})
+
+	t.Run("CPMMMintsFromLookupTable", func(t *testing.T) {
+		var s swapTx
+		accounts := s.accounts(newKey(), newKey(), newKey(), newKey(), newKey(), newKey(), newKey(), newKey(),
+			"TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA", "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
+		program := s.key(config.ProgramRaydiumCPMM)
+		// Both mints are loaded from a lookup table, after the static keys
+		s.loaded = []solana.PublicKey{solana.MustPublicKeyFromBase58(bonkMint), solana.MustPublicKeyFromBase58(SolMint)}
+		base := uint16(len(s.keys))
+		s.tx.Message.Instructions = append(s.tx.Message.Instructions, solana.CompiledInstruction{
+			ProgramIDIndex: program,
+			Accounts:       append(accounts, base, base+1),
+			Data:           ixData(anchorTag("swap_base_input"), uint64(7_000_000), uint64(150_000_000)),
+		})
+
+		swaps := DecodeSwapInstructions(s.build(t))
+		if len(swaps) != 1 || swaps[0].InputMint != bonkMint || swaps[0].OutputMint != SolMint ||
+			swaps[0].AmountIn != 7_000_000 || swaps[0].AmountOut != 150_000_000 {
+			t.Errorf("Unexpected CPMM swap: %+v", swaps)
+		}
+	})
+
+	t.Run("CLMMExactOutput", func(t *testing.T) {
+		var s swapTx
+		s.top(config.ProgramRaydiumCLMM, s.accounts(newKey()),
+			ixData(anchorTag("swap"), uint64(2_000), uint64(9_000), uint64(0), uint64(0), false))
+
+		swaps := DecodeSwapInstructions(s.build(t))
+		if len(swaps) != 1 || swaps[0].AmountIn != 9_000 || swaps[0].AmountOut != 2_000 {
+			t.Errorf("Expected threshold as input for an exact-output swap, got %+v", swaps)
+		}
+	})
+
+	t.Run("RecogniseOnly", func(t *testing.T) {
+		var s swapTx
+		s.top(config.ProgramPumpFun, s.accounts(newKey()), ixData(anchorTag("buy"), uint64(1), uint64(2)))
+		s.top(config.ProgramPumpFun, s.accounts(newKey()), ixData(anchorTag("create"), uint64(1)))
+		s.top(config.ProgramOrcaWhirlpool, s.accounts(newKey()), ixData(anchorTag("two_hop_swap")))
+
+		swaps := DecodeSwapInstructions(s.build(t))
+		if len(swaps) != 2 || swaps[0].Name != "buy" || swaps[1].Name != "two_hop_swap" {
+			t.Errorf("Expected pump.fun buy and Orca two-hop only, got %+v", swaps)
+		}
+	})
+
+	t.Run("BadAccountIndex", func(t *testing.T) {
+		var s swapTx
+		s.top(config.ProgramRaydiumAMMV4, []uint16{40}, ixData([]byte{9}, uint64(1), uint64(1)))
+		if swaps := DecodeSwapInstructions(s.build(t)); len(swaps) != 0 {
+			t.Errorf("Expected instructions with unknown accounts to be skipped, got %+v", swaps)
+		}
+	})
+}
+
+func TestSwapFromInstruction(t *testing.T) {
+	t.Run("InstructionMintsBeatStrayTransfers", func(t *testing.T) {
+		ix := &SwapInstruction{ProgramID: config.ProgramJupiterV6, InputMint: SolMint, OutputMint: bonkMint, AmountIn: 1_000, AmountOut: 40_000}
+		// An airdrop of a larger token amount in the same transaction
+		deltas := map[string]int64{bonkMint: 41_000, "Airdrop111": 9_000_000}
+		swap, err := swapFromInstruction("sig", "w", ix, -1_500, deltas)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if swap.InputMint != SolMint || swap.OutputMint != bonkMint || swap.InputAmount != 1_500 ||
+			swap.OutputAmount != 41_000 || swap.ProgramID != config.ProgramJupiterV6 {
+			t.Errorf("Unexpected swap: %+v", swap)
+		}
+	})
+
+	t.Run("RelayedSwapUsesInstructionAmounts", func(t *testing.T) {
+		ix := &SwapInstruction{InputMint: bonkMint, OutputMint: SolMint, AmountIn: 5, AmountOut: 7}
+		swap, err := swapFromInstruction("sig", "w", ix, 0, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if swap.InputAmount != 5 || swap.OutputAmount != 7 {
+			t.Errorf("Expected instruction amounts, got %+v", swap)
+		}
+	})
+
+	t.Run("NoMintsFallsBackToDeltas", func(t *testing.T) {
+		ix := &SwapInstruction{ProgramID: config.ProgramPumpFun}
+		swap, err := swapFromInstruction("sig", "w", ix, -500_000_000, map[string]int64{bonkMint: 1_000})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if swap.OutputMint != bonkMint || swap.ProgramID != config.ProgramPumpFun {
+			t.Errorf("Unexpected swap: %+v", swap)
+		}
+		if _, err := swapFromInstruction("sig", "w", ix, 0, nil); err == nil {
+			t.Error("Expected ErrNotSwap without mints or balance changes")
+		}
+	})
+}
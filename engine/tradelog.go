@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-orchestrator/storage"
+)
+
+// Trade lifecycle stages
+const (
+	StageQuote   = "quote"
+	StageBuild   = "build"
+	StageSign    = "sign"
+	StageSubmit  = "submit"
+	StageConfirm = "confirm"
+)
+
+// tradeLogDetailLen caps how much of an error is stored per stage
+const tradeLogDetailLen = 500
+
+// base58Secret matches base58 runs long enough to be a private key or a
+// full signature (64 bytes encode to 86-88 characters)
+var base58Secret = regexp.MustCompile(`[1-9A-HJ-NP-Za-km-z]{64,}`)
+
+// TradeLogStore persists trade stages
+type TradeLogStore interface {
+	InsertTradeLog(e *storage.TradeLogEntry) error
+	GetTradeLogsBySignatureHash(hash string) ([]*storage.TradeLogEntry, error)
+}
+
+// TradeLogger records each stage of a trade for debugging failed trades.
+// Details are redacted and signatures are stored abbreviated, with a hash
+// of the full signature for admin lookups.
+type TradeLogger struct {
+	store TradeLogStore
+}
+
+// NewTradeLogger creates a trade logger on top of store
+func NewTradeLogger(store TradeLogStore) *TradeLogger {
+	return &TradeLogger{store: store}
+}
+
+// TradeLog is the log of a single trade. A nil *TradeLog is valid and
+// records nothing.
+type TradeLog struct {
+	logger *TradeLogger
+	ID     string
+	userID int64
+	kind   string
+
+	mu        sync.Mutex
+	signature string
+	sigHash   string
+}
+
+// Begin starts logging a new trade of kind (buy, sell or copy) for userID
+func (l *TradeLogger) Begin(userID int64, kind string) *TradeLog {
+	if l == nil {
+		return nil
+	}
+	return &TradeLog{logger: l, ID: newTradeID(), userID: userID, kind: kind}
+}
+
+// SetSignature attaches the transaction signature to every later stage
+func (t *TradeLog) SetSignature(signature string) {
+	if t == nil || signature == "" {
+		return
+	}
+	t.mu.Lock()
+	t.signature = AbbreviateSignature(signature)
+	t.sigHash = HashSignature(signature)
+	t.mu.Unlock()
+}
+
+// Stage records that stage finished, failing with err if non-nil. detail
+// is redacted before it is stored. Storage failures are logged and never
+// interrupt the trade.
+func (t *TradeLog) Stage(stage string, err error, detail string) {
+	if t == nil {
+		return
+	}
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+		if detail != "" {
+			detail += ": "
+		}
+		detail += err.Error()
+	}
+
+	t.mu.Lock()
+	entry := &storage.TradeLogEntry{
+		TradeID:       t.ID,
+		UserID:        t.userID,
+		Kind:          t.kind,
+		Stage:         stage,
+		Status:        status,
+		Signature:     t.signature,
+		SignatureHash: t.sigHash,
+		Detail:        RedactTradeDetail(detail),
+		CreatedAt:     time.Now().UnixMilli(),
+	}
+	t.mu.Unlock()
+
+	if err := t.logger.store.InsertTradeLog(entry); err != nil {
+		log.Printf("⚠️ Failed to record trade %s stage %s: %v", t.ID, stage, err)
+	}
+}
+
+// Lookup returns every stage of the trades that produced signature
+func (l *TradeLogger) Lookup(signature string) ([]*storage.TradeLogEntry, error) {
+	return l.store.GetTradeLogsBySignatureHash(HashSignature(strings.TrimSpace(signature)))
+}
+
+// HashSignature is the lookup key stored for a full signature
+func HashSignature(signature string) string {
+	sum := sha256.Sum256([]byte(signature))
+	return hex.EncodeToString(sum[:])
+}
+
+// AbbreviateSignature keeps enough of a signature to recognise it in logs
+func AbbreviateSignature(signature string) string {
+	if len(signature) <= 16 {
+		return signature
+	}
+	return signature[:8] + "…" + signature[len(signature)-8:]
+}
+
+// RedactTradeDetail strips credentials, keys and full signatures from a
+// stage detail and truncates it
+func RedactTradeDetail(detail string) string {
+	s := secretPattern.ReplaceAllString(detail, "${1}${2}REDACTED")
+	s = base58Secret.ReplaceAllString(s, "REDACTED")
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if len(s) > tradeLogDetailLen {
+		s = s[:tradeLogDetailLen] + "..."
+	}
+	return s
+}
+
+// newTradeID returns a random internal trade ID
+func newTradeID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
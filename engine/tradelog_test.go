@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"solana-orchestrator/storage"
+)
+
+// fakeTradeLogStore keeps trade stages in memory
+type fakeTradeLogStore struct {
+	mu      sync.Mutex
+	entries []*storage.TradeLogEntry
+}
+
+func (f *fakeTradeLogStore) InsertTradeLog(e *storage.TradeLogEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, e)
+	return nil
+}
+
+func (f *fakeTradeLogStore) GetTradeLogsBySignatureHash(hash string) ([]*storage.TradeLogEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tradeIDs := make(map[string]bool)
+	for _, e := range f.entries {
+		if e.SignatureHash == hash {
+			tradeIDs[e.TradeID] = true
+		}
+	}
+	var out []*storage.TradeLogEntry
+	for _, e := range f.entries {
+		if tradeIDs[e.TradeID] {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestTradeLogger(t *testing.T) {
+	const signature = "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW"
+	const privateKey = "4Z7cXSyeFR8wNGMVXUE1TwtKn5D5Vu7FzEv69dokLv7KrQk7h6pu4LF8ZRR9yQBhc7uSM6RTTZtU1fmaxiNrxXrs"
+
+	store := &fakeTradeLogStore{}
+	logger := NewTradeLogger(store)
+
+	trade := logger.Begin(42, "buy")
+	trade.Stage(StageQuote, nil, "100000 lamports")
+	trade.SetSignature(signature)
+	trade.Stage(StageSign, nil, "")
+	trade.Stage(StageSubmit, errors.New("send failed for "+privateKey), "https://rpc.shyft.to?api_key=abc123")
+
+	entries, err := logger.Lookup(signature)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 stages, got %d", len(entries))
+	}
+
+	t.Run("StagesInOrder", func(t *testing.T) {
+		if entries[0].Stage != StageQuote || entries[0].Signature != "" {
+			t.Errorf("Expected unsigned quote stage first, got %+v", entries[0])
+		}
+		if entries[2].Status != "error" || entries[2].CreatedAt < entries[0].CreatedAt {
+			t.Errorf("Expected failed submit stage last, got %+v", entries[2])
+		}
+	})
+
+	t.Run("Redacted", func(t *testing.T) {
+		for _, e := range entries {
+			for _, secret := range []string{signature, privateKey, "abc123"} {
+				if strings.Contains(e.Detail, secret) || strings.Contains(e.Signature, secret) {
+					t.Errorf("Stage %s leaks %q: %+v", e.Stage, secret, e)
+				}
+			}
+		}
+		if entries[1].Signature != "5VERv8NM…diSZkQUW" {
+			t.Errorf("Expected abbreviated signature, got %q", entries[1].Signature)
+		}
+	})
+
+	t.Run("NilTradeLog", func(t *testing.T) {
+		var nilLogger *TradeLogger
+		trade := nilLogger.Begin(1, "sell")
+		trade.SetSignature(signature)
+		trade.Stage(StageQuote, nil, "")
+	})
+}
@@ -2,11 +2,15 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"solana-orchestrator/config"
 	"solana-orchestrator/internal/solana"
 	"solana-orchestrator/storage"
 )
@@ -17,38 +21,88 @@ type Notification struct {
 	Msg    string
 }
 
+// TradingGate reports whether trade execution is currently allowed
+type TradingGate interface {
+	TradingEnabled(ctx context.Context) bool
+}
+
+// OrderStore is the janitor's view of the limit order table
+type OrderStore interface {
+	GetExpiredOrdersBatch(limit int) ([]*storage.LimitOrder, error)
+	CloseOpenOrder(id int64, status string) (bool, error)
+}
+
+// Clock tells the janitor the time, so tests can step through backoff
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// maxConcurrentCancels bounds the cancel bundles in flight to Jito
+const maxConcurrentCancels = 5
+
+// janitorStats counts orders processed and failed, in total and for the
+// last run
+var janitorStats = expvar.NewMap("janitor_orders")
+
 // Janitor cleans up expired orders
 type Janitor struct {
-	DB         *storage.DB
+	DB         OrderStore
 	JitoClient *solana.JitoClient
 	// We need a way to build cancel tx. Assuming a helper in solana package or we inject a client.
 	// For now, let's assume we have a LimitOrderManager or similar, or we use JitoClient if we add helper there.
 	// The prompt implies `j.Solana.BuildCancelOrderTx`.
 	// I'll define an interface or struct for Solana interactions needed here.
 	SolanaClient *solana.LimitOrderManager
-	Notify       chan Notification
-	stopChan     chan struct{}
+	// Gate pauses cancellations while the global kill switch is off; nil
+	// means always on
+	Gate   TradingGate
+	Notify chan Notification
+	// Cancel sends the cancel of an expired order; it defaults to
+	// building it with SolanaClient and sending it through Jito
+	Cancel func(ctx context.Context, o *storage.LimitOrder) error
+	Clock  Clock
+
+	cfg      config.JanitorConfig
+	backoff  time.Duration // wait after the last failed run, 0 after a clean one
+	retryAt  time.Time
+	stopChan chan struct{}
+}
+
+// JanitorRun summarises one pass over the expired orders
+type JanitorRun struct {
+	Processed int   // orders cancelled and refunded
+	Failed    int   // orders whose cancel failed, retried on a later run
+	Err       error // why the run stopped early, if it did
 }
 
 // NewJanitor creates a new Janitor
-func NewJanitor(db *storage.DB, jito *solana.JitoClient, solClient *solana.LimitOrderManager) *Janitor {
-	return &Janitor{
+func NewJanitor(db *storage.DB, jito *solana.JitoClient, solClient *solana.LimitOrderManager, gate TradingGate, cfg config.JanitorConfig) *Janitor {
+	j := &Janitor{
 		DB:           db,
 		JitoClient:   jito,
 		SolanaClient: solClient,
+		Gate:         gate,
 		Notify:       make(chan Notification, 100),
+		Clock:        systemClock{},
+		cfg:          cfg,
 		stopChan:     make(chan struct{}),
 	}
+	j.Cancel = j.sendCancel
+	return j
 }
 
 // Start begins the background cleanup process
 func (j *Janitor) Start() {
-	ticker := time.NewTicker(1 * time.Minute)
+	ticker := time.NewTicker(j.cfg.Interval())
 	go func() {
 		for {
 			select {
 			case <-ticker.C:
-				j.processExpiredOrders()
+				j.tick()
 			case <-j.stopChan:
 				ticker.Stop()
 				return
@@ -62,14 +116,62 @@ func (j *Janitor) Stop() {
 	close(j.stopChan)
 }
 
-func (j *Janitor) processExpiredOrders() {
-	batchSize := 50 // Keep memory footprint tiny
+// tick runs a pass unless the janitor is backing off after failures
+func (j *Janitor) tick() {
+	if j.Clock.Now().Before(j.retryAt) {
+		return
+	}
+	j.finishRun(j.processExpiredOrders())
+}
+
+// finishRun records a run's metrics and backs off when it failed: the
+// first failure waits an interval, each further one twice as long up to
+// the configured maximum
+func (j *Janitor) finishRun(run JanitorRun) {
+	janitorStats.Add("runs", 1)
+	janitorStats.Add("processed", int64(run.Processed))
+	janitorStats.Add("failed", int64(run.Failed))
+	last := new(expvar.Int)
+	last.Set(int64(run.Processed))
+	janitorStats.Set("last_run_processed", last)
+	lastFailed := new(expvar.Int)
+	lastFailed.Set(int64(run.Failed))
+	janitorStats.Set("last_run_failed", lastFailed)
+
+	if run.Err == nil && run.Failed == 0 {
+		j.backoff = 0
+		return
+	}
+	if j.backoff == 0 {
+		j.backoff = j.cfg.Interval()
+	} else {
+		j.backoff *= 2
+	}
+	if limit := j.cfg.MaxBackoff(); j.backoff > limit {
+		j.backoff = limit
+	}
+	j.retryAt = j.Clock.Now().Add(j.backoff)
+	log.Printf("⚠️ Janitor: %d processed, %d failed (%v), retrying in %s", run.Processed, run.Failed, run.Err, j.backoff)
+}
+
+// processExpiredOrders cancels up to MaxBatchesPerRun batches of expired
+// orders. A larger backlog is left for later runs, and a batch with
+// failures ends the run, since its failed orders would just be fetched
+// again.
+func (j *Janitor) processExpiredOrders() JanitorRun {
+	var run JanitorRun
 
-	for {
+	// Expired orders stay pending and are picked up once trading resumes
+	if j.Gate != nil && !j.Gate.TradingEnabled(context.Background()) {
+		log.Printf("⏸️ Janitor: trading disabled, skipping expired orders")
+		return run
+	}
+
+	for batch := 0; batch < j.cfg.MaxBatchesPerRun; batch++ {
 		// 1. Fetch Batch (Optimized SQL)
-		orders, err := j.DB.GetExpiredOrdersBatch(batchSize)
+		orders, err := j.DB.GetExpiredOrdersBatch(j.cfg.BatchSize)
 		if err != nil {
-			log.Printf("❌ Janitor DB error: %v", err)
+			run.Err = fmt.Errorf("fetch expired orders: %w", err)
 			break
 		}
 		if len(orders) == 0 {
@@ -77,52 +179,106 @@ func (j *Janitor) processExpiredOrders() {
 		}
 
 		log.Printf("🧹 Janitor: Processing batch of %d orders...", len(orders))
-
-		// 2. Process Batch (Parallel Jito Requests)
-		// We use a semaphore to limit concurrent Jito requests to avoid network saturation
-		sem := make(chan struct{}, 5) // Max 5 concurrent cancellations
-		var wg sync.WaitGroup
-
-		for _, order := range orders {
-			wg.Add(1)
-			sem <- struct{}{} // Acquire token
-
-			go func(o *storage.LimitOrder) {
-				defer wg.Done()
-				defer func() { <-sem }() // Release token
-
-				// Build Cancel Transaction
-				// Assuming BuildCancelOrderTx returns *solana.Transaction
-				tx, err := j.SolanaClient.BuildCancelOrderTx(context.Background(), o.OrderPubkey)
-				if err != nil {
-					log.Printf("❌ Failed to build cancel tx for %s: %v", o.OrderPubkey, err)
-					return
-				}
-
-				// Send via Jito (High Priority)
-				// Using a small tip for cleanup
-				_, err = j.JitoClient.SendJitoBundle(context.Background(), tx, 10000) // 10k lamports tip
-
-				if err == nil {
-					// 3. Mark as CANCELLED in DB
-					j.DB.UpdateOrderStatus(o.ID, "EXPIRED_REFUNDED")
-
-					// 4. Notify User (Non-blocking)
-					select {
-					case j.Notify <- Notification{
-						UserID: o.UserID,
-						Msg:    fmt.Sprintf("⏳ Order Expired & Refunded\n\nToken: %s\nReason: Time Limit Reached", o.TokenSymbol),
-					}:
-					default:
-					}
-				} else {
-					log.Printf("❌ Failed to send cancel bundle for %s: %v", o.OrderPubkey, err)
-				}
-			}(order)
+		processed, failed := j.cancelBatch(orders)
+		run.Processed += processed
+		run.Failed += failed
+		if failed > 0 || len(orders) < j.cfg.BatchSize {
+			break
 		}
-		wg.Wait()
+	}
+	return run
+}
+
+// cancelBatch cancels a batch of expired orders in parallel, bounded so
+// Jito isn't flooded, and notifies their users
+func (j *Janitor) cancelBatch(orders []*storage.LimitOrder) (processed, failed int) {
+	sem := make(chan struct{}, maxConcurrentCancels)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, order := range orders {
+		wg.Add(1)
+		sem <- struct{}{} // Acquire token
+
+		go func(o *storage.LimitOrder) {
+			defer wg.Done()
+			defer func() { <-sem }() // Release token
+
+			ok := j.refund(o)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				processed++
+			} else {
+				failed++
+			}
+		}(order)
+	}
+	wg.Wait()
+	return processed, failed
+}
+
+// refund cancels one expired order and tells its user, reporting false if
+// the cancel failed
+func (j *Janitor) refund(o *storage.LimitOrder) bool {
+	if err := j.Cancel(context.Background(), o); err != nil {
+		log.Printf("❌ Failed to cancel expired order %s: %v", o.OrderPubkey, err)
+		return false
+	}
 
-		// 3. Optional: Small sleep to let CPU breathe between batches
-		time.Sleep(100 * time.Millisecond)
+	// Mark as refunded in DB. Only the first transition notifies, so a
+	// retried refund isn't reported twice.
+	refunded, err := j.DB.CloseOpenOrder(o.ID, storage.OrderExpiredRefunded)
+	if err != nil {
+		log.Printf("❌ Failed to mark %s refunded: %v", o.OrderPubkey, err)
+		return false
+	}
+	if !refunded {
+		return true
+	}
+
+	// Notify User (Non-blocking)
+	select {
+	case j.Notify <- Notification{UserID: o.UserID, Msg: refundNotice(o)}:
+	default:
+		log.Printf("⚠️ Janitor notification queue full, dropped refund notice for order %d", o.ID)
+	}
+	return true
+}
+
+// sendCancel builds an order's cancel transaction and sends it via Jito
+// with a small tip for cleanup
+func (j *Janitor) sendCancel(ctx context.Context, o *storage.LimitOrder) error {
+	if j.JitoClient == nil {
+		return errors.New("jito is not configured")
+	}
+	tx, err := j.SolanaClient.BuildCancelOrderTx(ctx, o.OrderPubkey)
+	if errors.Is(err, solana.ErrOrderClosed) {
+		// Filled before it expired: nothing to refund or announce
+		_, err = j.DB.CloseOpenOrder(o.ID, storage.OrderFilled)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("build cancel tx: %w", err)
+	}
+	_, err = j.JitoClient.SendJitoBundle(ctx, tx, 10000) // 10k lamports tip
+	return err
+}
+
+// refundNotice tells a user their expired order was cancelled and its
+// rent returned
+func refundNotice(o *storage.LimitOrder) string {
+	token := o.TokenSymbol
+	if token == "" {
+		token = o.TokenMint
+	}
+	msg := "⏳ Order Expired & Refunded\n\n"
+	msg += fmt.Sprintf("Order: #%d %s %s\n", o.ID, strings.ToUpper(o.Side), token)
+	msg += fmt.Sprintf("Target Price: %.9g SOL\n", o.Price)
+	msg += fmt.Sprintf("Amount: %g\n", o.Amount)
+	if o.InitialRentSOL > 0 {
+		msg += fmt.Sprintf("Rent Refunded: %.6f SOL\n", o.InitialRentSOL)
 	}
+	msg += "Reason: Time Limit Reached"
+	return msg
 }
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"solana-orchestrator/config"
+	"solana-orchestrator/storage"
+)
+
+// fakeOrders is an in-memory OrderStore; fetchErr fails every fetch
+type fakeOrders struct {
+	mu       sync.Mutex
+	orders   []*storage.LimitOrder
+	fetchErr error
+	fetches  int
+}
+
+func (f *fakeOrders) GetExpiredOrdersBatch(limit int) ([]*storage.LimitOrder, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetches++
+	if f.fetchErr != nil {
+		return nil, f.fetchErr
+	}
+	var batch []*storage.LimitOrder
+	for _, o := range f.orders {
+		if o.Status == storage.OrderOpen && len(batch) < limit {
+			copied := *o
+			batch = append(batch, &copied)
+		}
+	}
+	return batch, nil
+}
+
+func (f *fakeOrders) CloseOpenOrder(id int64, status string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range f.orders {
+		if o.ID == id && o.Status == storage.OrderOpen {
+			o.Status = status
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeOrders) open() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, o := range f.orders {
+		if o.Status == storage.OrderOpen {
+			n++
+		}
+	}
+	return n
+}
+
+// stepClock is a clock tests move by hand
+type stepClock struct{ now time.Time }
+
+func (c *stepClock) Now() time.Time { return c.now }
+
+func newTestJanitor(store *fakeOrders, clock *stepClock) *Janitor {
+	return &Janitor{
+		DB:     store,
+		Notify: make(chan Notification, 100),
+		Cancel: func(ctx context.Context, o *storage.LimitOrder) error { return nil },
+		Clock:  clock,
+		cfg: config.JanitorConfig{
+			BatchSize:        10,
+			IntervalSec:      60,
+			MaxBatchesPerRun: 2,
+			MaxBackoffSec:    300,
+		},
+	}
+}
+
+func expiredOrders(n int) []*storage.LimitOrder {
+	orders := make([]*storage.LimitOrder, n)
+	for i := range orders {
+		orders[i] = &storage.LimitOrder{ID: int64(i + 1), UserID: 7, TokenSymbol: "BONK", Side: "buy", Status: storage.OrderOpen}
+	}
+	return orders
+}
+
+// TestJanitorDrainsBacklog tests that a backlog larger than a run is
+// cancelled across ticks, notifying each user once
+func TestJanitorDrainsBacklog(t *testing.T) {
+	store := &fakeOrders{orders: expiredOrders(25)}
+	clock := &stepClock{now: time.Unix(1_700_000_000, 0)}
+	j := newTestJanitor(store, clock)
+
+	run := j.processExpiredOrders()
+	if run.Processed != 20 || run.Failed != 0 || run.Err != nil {
+		t.Fatalf("Expected two batches of 10 in the first run, got %+v", run)
+	}
+	if got := store.open(); got != 5 {
+		t.Errorf("Expected 5 orders left for the next run, got %d", got)
+	}
+
+	j.finishRun(run)
+	clock.now = clock.now.Add(time.Minute)
+	j.tick()
+	if got := store.open(); got != 0 {
+		t.Errorf("Expected the backlog drained, got %d open", got)
+	}
+	if got := len(j.Notify); got != 25 {
+		t.Errorf("Expected one refund notice per order, got %d", got)
+	}
+
+	// Orders already refunded, e.g. by a retried cancel, aren't
+	// announced again
+	if ok := j.refund(store.orders[0]); !ok || len(j.Notify) != 25 {
+		t.Errorf("Expected a repeated refund to succeed silently, got %v with %d notices", ok, len(j.Notify))
+	}
+}
+
+// TestJanitorBackoff tests that failed runs back off exponentially up to
+// the cap and a clean run resets it
+func TestJanitorBackoff(t *testing.T) {
+	store := &fakeOrders{orders: expiredOrders(3), fetchErr: errors.New("database is locked")}
+	clock := &stepClock{now: time.Unix(1_700_000_000, 0)}
+	j := newTestJanitor(store, clock)
+
+	wantBackoff := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+	for i, want := range wantBackoff {
+		j.tick()
+		if store.fetches != i+1 {
+			t.Fatalf("Run %d: expected %d fetches, got %d", i+1, i+1, store.fetches)
+		}
+		if j.backoff != want {
+			t.Errorf("Run %d: expected backoff %s, got %s", i+1, want, j.backoff)
+		}
+
+		// Ticks inside the backoff don't touch the store
+		clock.now = clock.now.Add(want - time.Second)
+		j.tick()
+		if store.fetches != i+1 {
+			t.Errorf("Run %d: expected no fetch while backing off", i+1)
+		}
+		clock.now = clock.now.Add(time.Second)
+	}
+
+	store.fetchErr = nil
+	j.tick()
+	if j.backoff != 0 || store.open() != 0 {
+		t.Errorf("Expected a clean run to reset the backoff, got %s with %d open", j.backoff, store.open())
+	}
+
+	// Failed cancels back off too, and stay open for the retry
+	store.orders = expiredOrders(3)
+	j.Cancel = func(ctx context.Context, o *storage.LimitOrder) error { return errors.New("bundle rejected") }
+	run := j.processExpiredOrders()
+	if run.Failed != 3 || run.Processed != 0 {
+		t.Fatalf("Expected 3 failed cancels, got %+v", run)
+	}
+	j.finishRun(run)
+	if j.backoff != time.Minute || store.open() != 3 {
+		t.Errorf("Expected a backoff with the orders still open, got %s with %d open", j.backoff, store.open())
+	}
+}
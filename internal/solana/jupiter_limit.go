@@ -0,0 +1,202 @@
+package solana
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// JupiterLimitOrderProgramID is the Jupiter limit order program
+var JupiterLimitOrderProgramID = solana.MustPublicKeyFromBase58("jupoNjAxXgZ4rjzxzPMP4oxduvQsQtZzyknqvzYNrNu")
+
+// Anchor discriminators of the limit order program's instructions and
+// order account
+var (
+	initializeOrderDiscriminator    = anchorDiscriminator("global:initialize_order")
+	cancelOrderDiscriminator        = anchorDiscriminator("global:cancel_order")
+	cancelExpiredOrderDiscriminator = anchorDiscriminator("global:cancel_expired_order")
+	orderAccountDiscriminator       = anchorDiscriminator("account:Order")
+)
+
+// errBadOrderAccount is returned for account data that isn't a limit order
+var errBadOrderAccount = errors.New("not a Jupiter limit order account")
+
+// anchorDiscriminator returns the first 8 bytes of the SHA-256 of name,
+// which is how Anchor tags instructions and accounts
+func anchorDiscriminator(name string) []byte {
+	sum := sha256.Sum256([]byte(name))
+	return sum[:8]
+}
+
+// jupiterOrder is the on-chain state of a limit order. Amounts are what
+// is left to fill, in base units of the input and output mints.
+type jupiterOrder struct {
+	Maker              solana.PublicKey
+	InputMint          solana.PublicKey
+	OutputMint         solana.PublicKey
+	MakingAmount       uint64
+	TakingAmount       uint64
+	MakerInputAccount  solana.PublicKey
+	MakerOutputAccount solana.PublicKey
+	Reserve            solana.PublicKey
+	ExpiredAt          *int64
+	Base               solana.PublicKey
+}
+
+// decodeJupiterOrder parses an order account's data
+func decodeJupiterOrder(data []byte) (*jupiterOrder, error) {
+	r := &orderReader{data: data}
+	if !bytes.Equal(r.next(8), orderAccountDiscriminator) {
+		return nil, errBadOrderAccount
+	}
+
+	o := &jupiterOrder{}
+	o.Maker = r.pubkey()
+	o.InputMint = r.pubkey()
+	o.OutputMint = r.pubkey()
+	r.next(1)  // waiting
+	r.next(16) // original making and taking amounts
+	o.MakingAmount = r.u64()
+	o.TakingAmount = r.u64()
+	o.MakerInputAccount = r.pubkey()
+	o.MakerOutputAccount = r.pubkey()
+	o.Reserve = r.pubkey()
+	r.next(8) // borrowed making amount
+	if r.next(1)[0] == 1 {
+		expiredAt := int64(r.u64())
+		o.ExpiredAt = &expiredAt
+	}
+	o.Base = r.pubkey()
+
+	if r.short {
+		return nil, errBadOrderAccount
+	}
+	return o, nil
+}
+
+// orderReader reads fixed-size fields, zero-filling past the end and
+// flagging short data instead of panicking
+type orderReader struct {
+	data  []byte
+	short bool
+}
+
+func (r *orderReader) next(n int) []byte {
+	if len(r.data) < n {
+		r.short = true
+		r.data = nil
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *orderReader) pubkey() solana.PublicKey {
+	return solana.PublicKeyFromBytes(r.next(32))
+}
+
+func (r *orderReader) u64() uint64 {
+	return binary.LittleEndian.Uint64(r.next(8))
+}
+
+// orderAddress derives the order account the maker opens with base
+func orderAddress(maker, base solana.PublicKey) (solana.PublicKey, error) {
+	addr, _, err := solana.FindProgramAddress([][]byte{[]byte("order"), maker[:], base[:]}, JupiterLimitOrderProgramID)
+	return addr, err
+}
+
+// reserveAddress derives the account holding an order's input tokens
+func reserveAddress(order solana.PublicKey) (solana.PublicKey, error) {
+	addr, _, err := solana.FindProgramAddress([][]byte{[]byte("reserve"), order[:]}, JupiterLimitOrderProgramID)
+	return addr, err
+}
+
+// newCancelOrderIx cancels an order with the maker's signature, returning
+// the unfilled input to the maker
+func newCancelOrderIx(orderKey solana.PublicKey, o *jupiterOrder) solana.Instruction {
+	return solana.NewInstruction(JupiterLimitOrderProgramID, solana.AccountMetaSlice{
+		solana.Meta(orderKey).WRITE(),
+		solana.Meta(o.Maker).WRITE().SIGNER(),
+		solana.Meta(o.Reserve).WRITE(),
+		solana.Meta(o.MakerInputAccount).WRITE(),
+		solana.Meta(solana.TokenProgramID),
+		solana.Meta(o.InputMint),
+	}, append([]byte{}, cancelOrderDiscriminator...))
+}
+
+// newCancelExpiredOrderIx cancels an order past its expiry. It needs no
+// maker signature, so any fee payer can refund the maker.
+func newCancelExpiredOrderIx(orderKey solana.PublicKey, o *jupiterOrder) solana.Instruction {
+	return solana.NewInstruction(JupiterLimitOrderProgramID, solana.AccountMetaSlice{
+		solana.Meta(orderKey).WRITE(),
+		solana.Meta(o.Reserve).WRITE(),
+		solana.Meta(o.Maker).WRITE(),
+		solana.Meta(o.MakerInputAccount).WRITE(),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(solana.TokenProgramID),
+		solana.Meta(o.InputMint),
+	}, append([]byte{}, cancelExpiredOrderDiscriminator...))
+}
+
+// newInitializeOrderIx opens a new order for o's maker, mints and token
+// accounts with the given amounts. base must sign the transaction along
+// with the maker. It returns the new order's account.
+func newInitializeOrderIx(o *jupiterOrder, base solana.PublicKey, making, taking uint64) (solana.Instruction, solana.PublicKey, error) {
+	orderKey, err := orderAddress(o.Maker, base)
+	if err != nil {
+		return nil, solana.PublicKey{}, err
+	}
+	reserve, err := reserveAddress(orderKey)
+	if err != nil {
+		return nil, solana.PublicKey{}, err
+	}
+
+	data := append([]byte{}, initializeOrderDiscriminator...)
+	data = binary.LittleEndian.AppendUint64(data, making)
+	data = binary.LittleEndian.AppendUint64(data, taking)
+	if o.ExpiredAt == nil {
+		data = append(data, 0)
+	} else {
+		data = append(data, 1)
+		data = binary.LittleEndian.AppendUint64(data, uint64(*o.ExpiredAt))
+	}
+
+	return solana.NewInstruction(JupiterLimitOrderProgramID, solana.AccountMetaSlice{
+		solana.Meta(base).SIGNER(),
+		solana.Meta(o.Maker).WRITE().SIGNER(),
+		solana.Meta(orderKey).WRITE(),
+		solana.Meta(reserve).WRITE(),
+		solana.Meta(o.MakerInputAccount).WRITE(),
+		solana.Meta(o.InputMint),
+		solana.Meta(o.MakerOutputAccount),
+		solana.Meta(JupiterLimitOrderProgramID), // no referral
+		solana.Meta(o.OutputMint),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(solana.TokenProgramID),
+		solana.Meta(solana.SysVarRentPubkey),
+	}, data), orderKey, nil
+}
+
+// repricedTakingAmount scales what an order asks for when its target price
+// in SOL moves from oldPrice to newPrice. A buy pays SOL, so it asks for
+// fewer tokens as the price rises; a sell asks for more SOL.
+func repricedTakingAmount(o *jupiterOrder, oldPrice, newPrice float64) (uint64, error) {
+	if oldPrice <= 0 || newPrice <= 0 {
+		return 0, fmt.Errorf("invalid price change %g -> %g", oldPrice, newPrice)
+	}
+	ratio := newPrice / oldPrice
+	if o.InputMint.Equals(solana.WrappedSol) {
+		ratio = oldPrice / newPrice
+	}
+	taking := math.Round(float64(o.TakingAmount) * ratio)
+	if taking < 1 || taking >= math.MaxUint64 {
+		return 0, fmt.Errorf("new price %g gives an out of range amount", newPrice)
+	}
+	return uint64(taking), nil
+}
@@ -0,0 +1,132 @@
+package solana
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// encodeJupiterOrder lays out an order account the way the program does
+func encodeJupiterOrder(o *jupiterOrder) []byte {
+	data := append([]byte{}, orderAccountDiscriminator...)
+	data = append(data, o.Maker[:]...)
+	data = append(data, o.InputMint[:]...)
+	data = append(data, o.OutputMint[:]...)
+	data = append(data, 0)
+	data = binary.LittleEndian.AppendUint64(data, o.MakingAmount)
+	data = binary.LittleEndian.AppendUint64(data, o.TakingAmount)
+	data = binary.LittleEndian.AppendUint64(data, o.MakingAmount)
+	data = binary.LittleEndian.AppendUint64(data, o.TakingAmount)
+	data = append(data, o.MakerInputAccount[:]...)
+	data = append(data, o.MakerOutputAccount[:]...)
+	data = append(data, o.Reserve[:]...)
+	data = binary.LittleEndian.AppendUint64(data, 0)
+	if o.ExpiredAt == nil {
+		data = append(data, 0)
+	} else {
+		data = append(data, 1)
+		data = binary.LittleEndian.AppendUint64(data, uint64(*o.ExpiredAt))
+	}
+	data = append(data, o.Base[:]...)
+	return append(data, 0) // no referral
+}
+
+func newTestOrder(inputMint solana.PublicKey) *jupiterOrder {
+	expiredAt := int64(1_900_000_000)
+	return &jupiterOrder{
+		Maker:              solana.NewWallet().PublicKey(),
+		InputMint:          inputMint,
+		OutputMint:         solana.NewWallet().PublicKey(),
+		MakingAmount:       1_000_000_000,
+		TakingAmount:       5_000_000,
+		MakerInputAccount:  solana.NewWallet().PublicKey(),
+		MakerOutputAccount: solana.NewWallet().PublicKey(),
+		Reserve:            solana.NewWallet().PublicKey(),
+		ExpiredAt:          &expiredAt,
+		Base:               solana.NewWallet().PublicKey(),
+	}
+}
+
+// TestJupiterOrderAccount tests decoding order accounts
+func TestJupiterOrderAccount(t *testing.T) {
+	want := newTestOrder(solana.WrappedSol)
+	data := encodeJupiterOrder(want)
+
+	got, err := decodeJupiterOrder(data)
+	if err != nil {
+		t.Fatalf("decodeJupiterOrder failed: %v", err)
+	}
+	if got.Maker != want.Maker || got.Reserve != want.Reserve || got.Base != want.Base ||
+		got.MakingAmount != want.MakingAmount || got.TakingAmount != want.TakingAmount ||
+		got.ExpiredAt == nil || *got.ExpiredAt != *want.ExpiredAt {
+		t.Errorf("Decoded %+v, want %+v", got, want)
+	}
+
+	if _, err := decodeJupiterOrder(data[:100]); !errors.Is(err, errBadOrderAccount) {
+		t.Errorf("Expected truncated data rejected, got %v", err)
+	}
+	other := append([]byte{}, data...)
+	other[0]++
+	if _, err := decodeJupiterOrder(other); !errors.Is(err, errBadOrderAccount) {
+		t.Errorf("Expected another account type rejected, got %v", err)
+	}
+}
+
+// TestJupiterOrderInstructions tests the cancel and re-create instructions
+func TestJupiterOrderInstructions(t *testing.T) {
+	o := newTestOrder(solana.WrappedSol)
+	orderKey := solana.NewWallet().PublicKey()
+
+	cancel := newCancelOrderIx(orderKey, o)
+	data, _ := cancel.Data()
+	if !bytes.Equal(data, cancelOrderDiscriminator) {
+		t.Errorf("Unexpected cancel data %x", data)
+	}
+	if maker := cancel.Accounts()[1]; maker.PublicKey != o.Maker || !maker.IsSigner {
+		t.Errorf("Cancel must be signed by the maker, got %+v", maker)
+	}
+	for _, acc := range newCancelExpiredOrderIx(orderKey, o).Accounts() {
+		if acc.IsSigner {
+			t.Errorf("Expired cancel shouldn't need %s to sign", acc.PublicKey)
+		}
+	}
+
+	base := solana.NewWallet().PublicKey()
+	create, newOrder, err := newInitializeOrderIx(o, base, o.MakingAmount, 2_500_000)
+	if err != nil {
+		t.Fatalf("newInitializeOrderIx failed: %v", err)
+	}
+	if want, _ := orderAddress(o.Maker, base); newOrder != want {
+		t.Errorf("New order %s, want %s", newOrder, want)
+	}
+	data, _ = create.Data()
+	if len(data) != 8+8+8+1+8 || !bytes.Equal(data[:8], initializeOrderDiscriminator) {
+		t.Fatalf("Unexpected create data %x", data)
+	}
+	if making, taking := binary.LittleEndian.Uint64(data[8:]), binary.LittleEndian.Uint64(data[16:]); making != o.MakingAmount || taking != 2_500_000 {
+		t.Errorf("Create amounts %d/%d", making, taking)
+	}
+	if expiredAt := int64(binary.LittleEndian.Uint64(data[25:])); data[24] != 1 || expiredAt != *o.ExpiredAt {
+		t.Errorf("Create should keep the expiry, got %x", data[24:])
+	}
+}
+
+// TestRepricedTakingAmount tests how an edit changes what an order asks for
+func TestRepricedTakingAmount(t *testing.T) {
+	buy := newTestOrder(solana.WrappedSol)
+	if got, _ := repricedTakingAmount(buy, 0.001, 0.002); got != buy.TakingAmount/2 {
+		t.Errorf("Buy at twice the price should ask for half the tokens, got %d", got)
+	}
+
+	sell := newTestOrder(solana.NewWallet().PublicKey())
+	if got, _ := repricedTakingAmount(sell, 0.001, 0.002); got != sell.TakingAmount*2 {
+		t.Errorf("Sell at twice the price should ask for twice the SOL, got %d", got)
+	}
+
+	if _, err := repricedTakingAmount(buy, 0, 0.002); err == nil {
+		t.Error("Expected an order without a price to be rejected")
+	}
+}
@@ -2,6 +2,7 @@ package solana
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,13 @@ import (
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
+// ErrOrderClosed is returned when an order is no longer open, usually
+// because it filled after it was listed
+var ErrOrderClosed = errors.New("order is no longer open")
+
+// errJitoDisabled is returned for user order changes without a Jito client
+var errJitoDisabled = errors.New("jito is not configured")
+
 // LimitOrderManager handles limit order operations
 type LimitOrderManager struct {
 	RPCClient  *rpc.Client
@@ -124,19 +132,33 @@ func (m *LimitOrderManager) CreateTimedLimitOrder(ctx context.Context, userID in
 
 // UpdateLimitOrder atomically updates a limit order (Cancel + Create)
 func (m *LimitOrderManager) UpdateLimitOrder(ctx context.Context, oldOrder *storage.LimitOrder, newPrice float64, wallet *solana.PrivateKey) error {
+	if err := m.ensureOpen(oldOrder); err != nil {
+		return err
+	}
+	if m.JitoClient == nil {
+		return errJitoDisabled
+	}
+
 	// 1. Build Cancel Instruction
-	cancelInst, err := m.buildCancelIx(oldOrder.OrderPubkey)
+	orderKey, onchain, err := m.userOrderAccount(ctx, oldOrder, wallet.PublicKey())
 	if err != nil {
 		return err
 	}
+	if onchain.MakingAmount == 0 {
+		return ErrOrderClosed
+	}
+	cancelInst := newCancelOrderIx(orderKey, onchain)
 
-	// 2. Build New Create Instruction
-	// Placeholder
-	createInst := solana.NewInstruction(
-		solana.SystemProgramID,
-		solana.AccountMetaSlice{},
-		[]byte{2}, // Dummy
-	)
+	// 2. Build New Create Instruction for what's left of the old order
+	taking, err := repricedTakingAmount(onchain, oldOrder.Price, newPrice)
+	if err != nil {
+		return err
+	}
+	base := solana.NewWallet().PrivateKey
+	createInst, orderPubkey, err := newInitializeOrderIx(onchain, base.PublicKey(), onchain.MakingAmount, taking)
+	if err != nil {
+		return err
+	}
 
 	// 3. Build Atomic Transaction
 	latestBlockhash, err := m.RPCClient.GetRecentBlockhash(ctx, rpc.CommitmentProcessed)
@@ -154,8 +176,11 @@ func (m *LimitOrderManager) UpdateLimitOrder(ctx context.Context, oldOrder *stor
 	}
 
 	tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(wallet.PublicKey()) {
+		switch {
+		case key.Equals(wallet.PublicKey()):
 			return wallet
+		case key.Equals(base.PublicKey()):
+			return &base
 		}
 		return nil
 	})
@@ -166,57 +191,143 @@ func (m *LimitOrderManager) UpdateLimitOrder(ctx context.Context, oldOrder *stor
 		return err
 	}
 
-	// 5. Update DB
-	// Mark old as cancelled
-	m.DB.UpdateOrderStatus(oldOrder.ID, "CANCELLED_REPLACED")
+	// 5. Update DB: replace the old order with one at the new price
+	if _, err := m.DB.CloseOpenOrder(oldOrder.ID, storage.OrderReplaced); err != nil {
+		return err
+	}
+	replacement := *oldOrder
+	replacement.ID = 0
+	replacement.OrderPubkey = orderPubkey.String()
+	replacement.Price = newPrice
+	if oldOrder.Price > 0 {
+		replacement.TargetMCAP = oldOrder.TargetMCAP * newPrice / oldOrder.Price
+	}
+	replacement.Status = storage.OrderOpen
+	return m.DB.SaveLimitOrder(&replacement)
+}
+
+// CancelLimitOrder cancels one of the user's open orders with their
+// wallet and marks it cancelled
+func (m *LimitOrderManager) CancelLimitOrder(ctx context.Context, order *storage.LimitOrder, wallet *solana.PrivateKey) error {
+	if err := m.ensureOpen(order); err != nil {
+		return err
+	}
+	if m.JitoClient == nil {
+		return errJitoDisabled
+	}
 
-	// Create new order record...
+	orderKey, onchain, err := m.userOrderAccount(ctx, order, wallet.PublicKey())
+	if err != nil {
+		return err
+	}
+	tx, err := m.newTx(ctx, newCancelOrderIx(orderKey, onchain), wallet.PublicKey())
+	if err != nil {
+		return err
+	}
+	tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(wallet.PublicKey()) {
+			return wallet
+		}
+		return nil
+	})
+
+	if _, err := m.JitoClient.SendJitoBundle(ctx, tx, 10000); err != nil {
+		return err
+	}
+	_, err = m.DB.CloseOpenOrder(order.ID, storage.OrderCancelled)
+	return err
+}
+
+// ensureOpen fails with ErrOrderClosed unless the DB still has the order
+// open
+func (m *LimitOrderManager) ensureOpen(order *storage.LimitOrder) error {
+	current, err := m.DB.GetLimitOrder(order.UserID, order.ID)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.Status != storage.OrderOpen {
+		return ErrOrderClosed
+	}
 	return nil
 }
 
-// BuildCancelOrderTx builds a transaction to cancel an order
+// userOrderAccount loads an order's on-chain account and checks that
+// maker placed it. An order whose account is gone filled in the meantime,
+// so it is marked filled and ErrOrderClosed returned.
+func (m *LimitOrderManager) userOrderAccount(ctx context.Context, order *storage.LimitOrder, maker solana.PublicKey) (solana.PublicKey, *jupiterOrder, error) {
+	orderKey, onchain, err := m.orderAccount(ctx, order.OrderPubkey)
+	if errors.Is(err, ErrOrderClosed) {
+		m.DB.CloseOpenOrder(order.ID, storage.OrderFilled)
+		return orderKey, nil, err
+	}
+	if err != nil {
+		return orderKey, nil, err
+	}
+	if !onchain.Maker.Equals(maker) {
+		return orderKey, nil, fmt.Errorf("order %s was placed by another wallet", order.OrderPubkey)
+	}
+	return orderKey, onchain, nil
+}
+
+// orderAccount fetches and decodes an order's account, returning
+// ErrOrderClosed when it no longer exists
+func (m *LimitOrderManager) orderAccount(ctx context.Context, orderPubkey string) (solana.PublicKey, *jupiterOrder, error) {
+	orderKey, err := solana.PublicKeyFromBase58(orderPubkey)
+	if err != nil {
+		return orderKey, nil, fmt.Errorf("invalid order account %q: %w", orderPubkey, err)
+	}
+	info, err := m.RPCClient.GetAccountInfo(ctx, orderKey)
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && (info == nil || info.Value == nil)) {
+		return orderKey, nil, ErrOrderClosed
+	}
+	if err != nil {
+		return orderKey, nil, err
+	}
+	onchain, err := decodeJupiterOrder(info.Value.Data.GetBinary())
+	if err != nil {
+		return orderKey, nil, err
+	}
+	return orderKey, onchain, nil
+}
+
+// BuildCancelOrderTx builds a signed transaction cancelling an expired
+// order. The program lets anyone cancel an order past its expiry, so the
+// Jito key pays and the unfilled input goes back to the maker. It returns
+// ErrOrderClosed when the order account no longer exists.
 func (m *LimitOrderManager) BuildCancelOrderTx(ctx context.Context, orderPubkey string) (*solana.Transaction, error) {
-	inst, err := m.buildCancelIx(orderPubkey)
+	if m.JitoClient == nil {
+		return nil, errJitoDisabled
+	}
+	orderKey, onchain, err := m.orderAccount(ctx, orderPubkey)
 	if err != nil {
 		return nil, err
 	}
 
-	// We need a blockhash
-	latestBlockhash, err := m.RPCClient.GetRecentBlockhash(ctx, rpc.CommitmentProcessed)
+	keeper := m.JitoClient.privateKey
+	tx, err := m.newTx(ctx, newCancelExpiredOrderIx(orderKey, onchain), keeper.PublicKey())
 	if err != nil {
 		return nil, err
 	}
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(keeper.PublicKey()) {
+			return &keeper
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
 
-	// Note: We don't have the user's private key here to sign!
-	// The Janitor runs in background.
-	// If the order account allows "anyone" to cancel if expired (some protocols do), then we can sign with bot key.
-	// Jupiter Limit Orders usually allow anyone to cancel if expired?
-	// Or maybe we need the user's key?
-	// The prompt says: "The Janitor... Cancel and Refund... Send via Jito".
-	// If it's a "Janitor", it implies the bot has authority or the protocol allows it.
-	// Assuming the bot wallet (payer) can cancel or we have access to user keys (EncryptedWallet).
-	// Since `Janitor` struct has `SolanaClient`, and `BuildCancelOrderTx` returns a `Transaction`,
-	// the caller (Janitor) will likely need to sign it.
-	// But wait, `Janitor` doesn't have access to user private keys easily.
-	// If the protocol requires user signature, Janitor can't do it unless we decrypt user wallet.
-	// Let's assume for this implementation that we return an unsigned transaction
-	// and the Janitor will handle signing (maybe with a "Crank" key if protocol supports it,
-	// or we assume we can decrypt the user's key).
-
-	// For the purpose of this exercise, I'll return the transaction.
-
+// newTx builds a transaction of inst paid by payer on a fresh blockhash
+func (m *LimitOrderManager) newTx(ctx context.Context, inst solana.Instruction, payer solana.PublicKey) (*solana.Transaction, error) {
+	latestBlockhash, err := m.RPCClient.GetRecentBlockhash(ctx, rpc.CommitmentProcessed)
+	if err != nil {
+		return nil, err
+	}
 	return solana.NewTransaction(
 		[]solana.Instruction{inst},
 		latestBlockhash.Value.Blockhash,
-		solana.TransactionPayer(solana.PublicKey{}), // Placeholder payer
+		solana.TransactionPayer(payer),
 	)
 }
-
-func (m *LimitOrderManager) buildCancelIx(orderPubkey string) (solana.Instruction, error) {
-	// Placeholder for Jupiter Cancel Instruction
-	return solana.NewInstruction(
-		solana.SystemProgramID,
-		solana.AccountMetaSlice{},
-		[]byte{1}, // Dummy
-	), nil
-}
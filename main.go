@@ -35,24 +35,37 @@ func main() {
 	if err != nil {
 		log.Fatalf("Config error: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
 
 	client := api.NewClient(cfg.MoralisAPIKey, cfg.BirdeyeAPIKey, cfg.APISettings.MaxRetries, cfg.MoralisFallbackKeys)
+	client.SetBaseURLs(cfg.APISettings.MoralisBaseURL, cfg.APISettings.BirdeyeBaseURL)
+	defer client.Close()
+
+	ctx := context.Background()
 
 	yellow.Println("📊 Fetching tokens...")
-	tokens, err := client.FetchBirdeyeTokens(*limit)
+	tokens, err := client.FetchBirdeyeTokens(ctx, *limit)
 	if err != nil {
 		log.Fatalf("Token fetch failed: %v", err)
 	}
 	green.Printf("✅ %d tokens\n\n", len(tokens))
 
+	tokens, skipped := api.FilterTokens(tokens, cfg.APISettings.MinTokenLiquidityUSD)
+	if skipped > 0 {
+		yellow.Printf("⏭️  Skipped %d tokens below $%.0f liquidity\n\n", skipped, cfg.APISettings.MinTokenLiquidityUSD)
+	}
+
 	holdersMap := make(map[string][]api.Holder)
 	walletSet := make(map[string]bool)
 
 	cyan.Println("👥 Collecting holders...")
 	for i, token := range tokens {
-		fmt.Printf("\r[%d/%d] %s", i+1, len(tokens), token.TokenAddress[:8])
+		fmt.Printf("\r[%d/%d] %.8s", i+1, len(tokens), token.TokenAddress)
 
-		holders, err := client.GetTokenHolders(token.TokenAddress)
+		holders, err := client.GetTokenHolders(ctx, token.TokenAddress)
+		time.Sleep(2 * time.Second) // Rate limit, paid only when a request was made
 		if err != nil {
 			fmt.Printf(" ❌ Error: %v\n", err)
 			continue
@@ -67,8 +80,6 @@ func main() {
 		for _, h := range holders {
 			walletSet[h.OwnerAddress] = true
 		}
-
-		time.Sleep(2 * time.Second)
 	}
 	fmt.Println()
 
@@ -84,9 +95,9 @@ func main() {
 	saveJSON("data/holders.json", holdersMap)
 
 	cyan.Println("🔍 Analyzing wallets...")
-	a := analyzer.NewAnalyzer(*pages, cfg.AnalysisFilters.MinWinrate, cfg.AnalysisFilters.MinRealizedPnL)
+	a := analyzer.NewAnalyzer(*pages, cfg.AnalysisFilters.MinWinrate, cfg.AnalysisFilters.MinRealizedPnL, cfg.Analyzer.WalletURLTemplate)
 
-	goodWallets, err := a.AnalyzeWallets(context.Background(), wallets, func(stats *analyzer.WalletStats) {
+	goodWallets, err := a.AnalyzeWallets(ctx, wallets, func(stats *analyzer.WalletStats) {
 		// Callback for progress updates (optional)
 		log.Printf("Analyzed wallet: %s", stats.Wallet)
 	})
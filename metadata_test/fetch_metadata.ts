@@ -2,7 +2,8 @@ import { Connection, PublicKey } from "@solana/web3.js";
 import { Metaplex } from "@metaplex-foundation/js";
 import { performance } from "perf_hooks";
 
-const RPC_URL = "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR";
+const SHYFT_API_KEY = process.env.SOLORCH_SHYFT_API_KEY ?? "";
+const RPC_URL = `https://rpc.shyft.to?api_key=${SHYFT_API_KEY}`;
 const connection = new Connection(RPC_URL);
 
 /**
@@ -8,29 +8,14 @@ echo "=============================="
 export GOPATH=$HOME/go
 mkdir -p $GOPATH
 
-# Copy API keys from Python config if not already configured
-if [ ! -f "config/config.json" ] || grep -q "YOUR_" config/config.json; then
-    echo "📝 Configuring API keys..."
-    if [ -f "solana_orchestrator/config/config.json" ]; then
-        MORALIS_KEY=$(grep -o '"moralis_api_key": "[^"]*"' solana_orchestrator/config/config.json | cut -d'"' -f4)
-        BIRDEYE_KEY=$(grep -o '"birdeye_api_key": "[^"]*"' solana_orchestrator/config/config.json | cut -d'"' -f4)
-        
-        cat > config/config.json <<EOF
-{
-  "moralis_api_key": "$MORALIS_KEY",
-  "birdeye_api_key": "$BIRDEYE_KEY",
-  "analysis_filters": {
-    "min_winrate": 25,
-    "min_realized_pnl": 25
-  },
-  "api_settings": {
-    "max_retries": 3,
-    "token_limit": 100
-  }
-}
-EOF
-        echo "✅ API keys configured"
-    fi
+# Create config from the example; secrets come from SOLORCH_* env vars
+# or by replacing the YOUR_* placeholders (config/config.json is gitignored)
+if [ ! -f "config/config.json" ]; then
+    cp config/config.example.json config/config.json
+    echo "📝 Created config/config.json from config.example.json"
+fi
+if grep -q "YOUR_" config/config.json; then
+    echo "⚠️  config/config.json still has YOUR_* placeholders; set SOLORCH_* env vars or edit the file"
 fi
 
 # Install dependencies
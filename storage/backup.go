@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// restoreSuffix marks a staged backup that replaces the database on next start
+const restoreSuffix = ".restore"
+
+// Backup writes a consistent snapshot of the database to destPath.
+// VACUUM INTO reads inside a single transaction, so the snapshot is
+// consistent even while other connections write (including WAL mode).
+func (db *DB) Backup(destPath string) error {
+	if err := os.Remove(destPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to clear backup path: %w", err)
+	}
+
+	if _, err := db.Exec("VACUUM INTO ?", destPath); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// PendingRestorePath returns where a staged restore for dbPath is kept
+func PendingRestorePath(dbPath string) string {
+	return dbPath + restoreSuffix
+}
+
+// StageRestore validates a backup and stages it to replace dbPath on next start
+func StageRestore(dbPath string, src io.Reader) error {
+	tmpPath := PendingRestorePath(dbPath) + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create staging file: %w", err)
+	}
+
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write staging file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write staging file: %w", err)
+	}
+
+	if err := validateBackup(tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, PendingRestorePath(dbPath)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to stage restore: %w", err)
+	}
+	return nil
+}
+
+// walSuffixes are the files SQLite keeps beside a database in WAL mode
+var walSuffixes = []string{"-wal", "-shm"}
+
+// ApplyPendingRestore swaps a staged restore into place before the database
+// is opened. The previous database is kept alongside with a timestamp
+// suffix, together with its WAL, which can hold committed transactions not
+// yet checkpointed into the main file.
+func ApplyPendingRestore(dbPath string) (bool, error) {
+	restorePath := PendingRestorePath(dbPath)
+	if _, err := os.Stat(restorePath); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		oldPath := fmt.Sprintf("%s.pre-restore-%d", dbPath, time.Now().Unix())
+		if err := os.Rename(dbPath, oldPath); err != nil {
+			return false, fmt.Errorf("failed to move current database aside: %w", err)
+		}
+		for _, suffix := range walSuffixes {
+			if err := os.Rename(dbPath+suffix, oldPath+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return false, fmt.Errorf("failed to move current database's %s file aside: %w", suffix, err)
+			}
+		}
+	}
+
+	// WAL files left without their database must not be replayed into
+	// the restored one
+	for _, suffix := range walSuffixes {
+		os.Remove(dbPath + suffix)
+	}
+
+	if err := os.Rename(restorePath, dbPath); err != nil {
+		return false, fmt.Errorf("failed to apply restore: %w", err)
+	}
+	return true, nil
+}
+
+// validateBackup checks the file is an intact SQLite database with our schema
+func validateBackup(path string) error {
+	conn, err := sql.Open("sqlite3", path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer conn.Close()
+
+	var result string
+	if err := conn.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("backup is not a valid database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup failed integrity check: %s", result)
+	}
+
+	var count int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&count); err != nil || count == 0 {
+		return errors.New("backup is missing the users table")
+	}
+	return nil
+}
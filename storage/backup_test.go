@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "bot.db")
+	backupPath := filepath.Join(dir, "backup.db")
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	if err := db.CreateUser(42); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	t.Run("Backup", func(t *testing.T) {
+		if err := db.Backup(backupPath); err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		// Second backup to the same path must overwrite
+		if err := db.Backup(backupPath); err != nil {
+			t.Fatalf("Repeated backup failed: %v", err)
+		}
+	})
+
+	// Change data after the snapshot so the restore is observable
+	db.CreateUser(99)
+	db.Close()
+
+	t.Run("StageRejectsGarbage", func(t *testing.T) {
+		if err := StageRestore(dbPath, strings.NewReader("not a database")); err == nil {
+			t.Error("Expected invalid backup to be rejected")
+		}
+		if _, err := os.Stat(PendingRestorePath(dbPath)); err == nil {
+			t.Error("Invalid backup should not be staged")
+		}
+	})
+
+	t.Run("StageAndApply", func(t *testing.T) {
+		f, err := os.Open(backupPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		if err := StageRestore(dbPath, f); err != nil {
+			t.Fatalf("StageRestore failed: %v", err)
+		}
+
+		applied, err := ApplyPendingRestore(dbPath)
+		if err != nil || !applied {
+			t.Fatalf("ApplyPendingRestore = %v, %v", applied, err)
+		}
+
+		restored, err := New(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to open restored database: %v", err)
+		}
+		defer restored.Close()
+
+		if u, _ := restored.GetUser(42); u == nil {
+			t.Error("User from backup should exist")
+		}
+		if u, _ := restored.GetUser(99); u != nil {
+			t.Error("User created after backup should not exist")
+		}
+	})
+
+	t.Run("KeepsWALWithOldDatabase", func(t *testing.T) {
+		f, err := os.Open(backupPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := StageRestore(dbPath, f); err != nil {
+			t.Fatalf("StageRestore failed: %v", err)
+		}
+		// Stand-in for transactions not yet checkpointed
+		if err := os.WriteFile(dbPath+"-wal", []byte("uncheckpointed"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if applied, err := ApplyPendingRestore(dbPath); err != nil || !applied {
+			t.Fatalf("ApplyPendingRestore = %v, %v", applied, err)
+		}
+		if _, err := os.Stat(dbPath + "-wal"); !os.IsNotExist(err) {
+			t.Error("Expected the old WAL to leave the restored database")
+		}
+		moved, _ := filepath.Glob(dbPath + ".pre-restore-*-wal")
+		if len(moved) != 1 {
+			t.Fatalf("Expected the WAL kept beside the old database, got %v", moved)
+		}
+		if data, _ := os.ReadFile(moved[0]); string(data) != "uncheckpointed" {
+			t.Errorf("Expected the WAL contents kept, got %q", data)
+		}
+	})
+
+	t.Run("NothingPending", func(t *testing.T) {
+		applied, err := ApplyPendingRestore(dbPath)
+		if err != nil || applied {
+			t.Errorf("Expected no pending restore, got %v, %v", applied, err)
+		}
+	})
+}
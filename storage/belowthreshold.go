@@ -0,0 +1,43 @@
+package storage
+
+import "time"
+
+// MarkWalletBelowThreshold records that a wallet was analyzed and missed
+// the scan thresholds, so scans skip it for cooldown instead of
+// analyzing it again every cycle
+func (db *DB) MarkWalletBelowThreshold(wallet string, cooldown time.Duration) error {
+	_, err := db.Exec(`INSERT INTO below_threshold_wallets (wallet, skip_until) VALUES (?, ?)
+		ON CONFLICT(wallet) DO UPDATE SET skip_until = excluded.skip_until`,
+		wallet, db.Now().Add(cooldown).Unix())
+	return err
+}
+
+// FilterBelowThreshold returns the wallets not marked below threshold
+// within their cooldown, in their original order
+func (db *DB) FilterBelowThreshold(wallets []string) ([]string, error) {
+	rows, err := db.Query("SELECT wallet FROM below_threshold_wallets WHERE skip_until > ?", db.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skip := make(map[string]bool)
+	for rows.Next() {
+		var wallet string
+		if err := rows.Scan(&wallet); err != nil {
+			return nil, err
+		}
+		skip[wallet] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	kept := make([]string, 0, len(wallets))
+	for _, w := range wallets {
+		if !skip[w] {
+			kept = append(kept, w)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBelowThresholdCooldown(t *testing.T) {
+	db := newTestDB(t)
+
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	db.SetClock(clock)
+
+	candidates := []string{"walletA", "walletB", "walletC", "walletD"}
+	if err := db.MarkWalletBelowThreshold("walletB", 24*time.Hour); err != nil {
+		t.Fatalf("MarkWalletBelowThreshold failed: %v", err)
+	}
+	if err := db.MarkWalletBelowThreshold("walletD", 6*time.Hour); err != nil {
+		t.Fatalf("MarkWalletBelowThreshold failed: %v", err)
+	}
+
+	enqueued := func() string {
+		t.Helper()
+		kept, err := db.FilterBelowThreshold(candidates)
+		if err != nil {
+			t.Fatalf("FilterBelowThreshold failed: %v", err)
+		}
+		return fmt.Sprint(kept)
+	}
+
+	// Within the window neither marked wallet is enqueued again
+	if got := enqueued(); got != "[walletA walletC]" {
+		t.Errorf("Expected marked wallets skipped, got %s", got)
+	}
+
+	// Each marker lasts its own cooldown
+	clock.Advance(6 * time.Hour)
+	if got := enqueued(); got != "[walletA walletC walletD]" {
+		t.Errorf("Expected walletD back after its cooldown, got %s", got)
+	}
+
+	// Cleanup drops expired markers only
+	if _, err := db.CleanupOldData(); err != nil {
+		t.Fatalf("CleanupOldData failed: %v", err)
+	}
+	var markers int
+	if err := db.QueryRow("SELECT COUNT(*) FROM below_threshold_wallets").Scan(&markers); err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if markers != 1 {
+		t.Errorf("Expected 1 marker left after cleanup, got %d", markers)
+	}
+
+	// Failing again restarts the cooldown
+	clock.Advance(17 * time.Hour)
+	if err := db.MarkWalletBelowThreshold("walletB", 24*time.Hour); err != nil {
+		t.Fatalf("MarkWalletBelowThreshold failed: %v", err)
+	}
+	clock.Advance(2 * time.Hour)
+	if got := enqueued(); got != "[walletA walletC walletD]" {
+		t.Errorf("Expected walletB still skipped after a new mark, got %s", got)
+	}
+}
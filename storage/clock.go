@@ -0,0 +1,39 @@
+package storage
+
+import "time"
+
+// Clock tells the DB the time. Retention cutoffs, expiry checks and
+// timestamps written by DB methods all read it, so tests can control time
+// with SetClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the wall clock, used unless SetClock replaces it
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SetClock replaces the clock the DB reads; nil restores the wall clock
+func (db *DB) SetClock(c Clock) {
+	if c == nil {
+		c = systemClock{}
+	}
+	db.clock = c
+}
+
+// Now returns the current time according to the DB's clock
+func (db *DB) Now() time.Time {
+	return db.clock.Now()
+}
+
+// TrialTimeLeft returns how long the user's trial runs from now, zero or
+// negative once it has expired
+func (u *User) TrialTimeLeft(now time.Time) time.Duration {
+	return time.Unix(u.TrialExpiresAt, 0).Sub(now)
+}
+
+// TrialExpired reports whether the user's trial has ended by now
+func (u *User) TrialExpired(now time.Time) bool {
+	return now.Unix() > u.TrialExpiresAt
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock tests move by hand
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestClock(t *testing.T) {
+	db := newTestDB(t)
+
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	db.SetClock(clock)
+
+	t.Run("Timestamps", func(t *testing.T) {
+		if err := db.CreateUser(42); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		user, err := db.GetUser(42)
+		if err != nil || user == nil {
+			t.Fatalf("GetUser failed: %v", err)
+		}
+		if user.JoinedAt != clock.now.Unix() {
+			t.Errorf("Expected joined_at from the clock %d, got %d", clock.now.Unix(), user.JoinedAt)
+		}
+	})
+
+	t.Run("TrialExpiry", func(t *testing.T) {
+		expiresAt := clock.now.Add(72 * time.Hour).Unix()
+		if err := db.SetUserPlan(42, "trial_3day", 0, expiresAt); err != nil {
+			t.Fatalf("SetUserPlan failed: %v", err)
+		}
+		user, _ := db.GetUser(42)
+
+		if user.TrialExpired(db.Now()) {
+			t.Error("Trial should be active right after activation")
+		}
+		clock.Advance(71 * time.Hour)
+		if left := user.TrialTimeLeft(db.Now()); left != time.Hour {
+			t.Errorf("Expected 1h left, got %v", left)
+		}
+		clock.Advance(2 * time.Hour)
+		if !user.TrialExpired(db.Now()) {
+			t.Error("Trial should have expired")
+		}
+	})
+
+	t.Run("NilRestoresWallClock", func(t *testing.T) {
+		db.SetClock(nil)
+		if time.Since(db.Now()) > time.Minute {
+			t.Errorf("Expected the wall clock, got %v", db.Now())
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package storage
+
+import "fmt"
+
+// DefaultConfirmAboveSOL is the trade size above which the user types the
+// amount to confirm, unless they pick another threshold
+const DefaultConfirmAboveSOL = 1
+
+// MaxConfirmAboveSOL caps the large-trade threshold
+const MaxConfirmAboveSOL = 1000
+
+// NeedsLargeTradeConfirm reports whether a trade worth solAmount is over
+// the user's threshold and needs the extra confirmation step. A threshold
+// of 0 turns the step off.
+func (s *UserSettings) NeedsLargeTradeConfirm(solAmount float64) bool {
+	return s.ConfirmAboveSOL > 0 && solAmount > s.ConfirmAboveSOL
+}
+
+// SkipsConfirmation reports whether a trade worth solAmount goes straight
+// to the password: the user auto-confirms and the trade isn't large
+func (s *UserSettings) SkipsConfirmation(solAmount float64) bool {
+	return s.AutoConfirm && !s.NeedsLargeTradeConfirm(solAmount)
+}
+
+// UpdateConfirmAboveSOL sets the trade size above which the user must type
+// the amount to confirm, 0 for never
+func (db *DB) UpdateConfirmAboveSOL(chatID int64, sol float64) error {
+	if sol < 0 || sol > MaxConfirmAboveSOL {
+		return fmt.Errorf("confirmation threshold must be between 0 and %d SOL, got %g", MaxConfirmAboveSOL, sol)
+	}
+	query := `INSERT INTO user_settings (chat_id, confirm_above_sol, updated_at) VALUES (?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET confirm_above_sol = excluded.confirm_above_sol, updated_at = excluded.updated_at`
+	_, err := db.Exec(query, chatID, sol, db.Now().Unix())
+	return err
+}
+
+// UpdateAutoConfirm sets whether trades under the large-trade threshold
+// skip the confirmation screen
+func (db *DB) UpdateAutoConfirm(chatID int64, enabled bool) error {
+	val := 0
+	if enabled {
+		val = 1
+	}
+	query := `INSERT INTO user_settings (chat_id, auto_confirm, updated_at) VALUES (?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET auto_confirm = excluded.auto_confirm, updated_at = excluded.updated_at`
+	_, err := db.Exec(query, chatID, val, db.Now().Unix())
+	return err
+}
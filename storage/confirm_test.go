@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestLargeTradeConfirm(t *testing.T) {
+	db := newTestDB(t)
+
+	t.Run("Update", func(t *testing.T) {
+		if s, _ := db.GetUserSettings(42); s.ConfirmAboveSOL != DefaultConfirmAboveSOL || s.AutoConfirm {
+			t.Errorf("Expected the default threshold without auto-confirm, got %+v", s)
+		}
+
+		if err := db.UpdateConfirmAboveSOL(42, 2.5); err != nil {
+			t.Fatalf("UpdateConfirmAboveSOL failed: %v", err)
+		}
+		if err := db.UpdateAutoConfirm(42, true); err != nil {
+			t.Fatalf("UpdateAutoConfirm failed: %v", err)
+		}
+		if s, _ := db.GetUserSettings(42); s.ConfirmAboveSOL != 2.5 || !s.AutoConfirm {
+			t.Errorf("Unexpected settings %+v", s)
+		}
+		for _, sol := range []float64{-1, MaxConfirmAboveSOL + 1} {
+			if err := db.UpdateConfirmAboveSOL(42, sol); err == nil {
+				t.Errorf("Expected a %g SOL threshold to be rejected", sol)
+			}
+		}
+	})
+
+	t.Run("Thresholds", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			settings    UserSettings
+			sol         float64
+			large, skip bool
+		}{
+			{"below", UserSettings{ConfirmAboveSOL: 1}, 0.5, false, false},
+			{"at the threshold", UserSettings{ConfirmAboveSOL: 1}, 1, false, false},
+			{"above", UserSettings{ConfirmAboveSOL: 1}, 1.01, true, false},
+			{"below with auto-confirm", UserSettings{ConfirmAboveSOL: 1, AutoConfirm: true}, 0.5, false, true},
+			{"above with auto-confirm", UserSettings{ConfirmAboveSOL: 1, AutoConfirm: true}, 5, true, false},
+			{"threshold off", UserSettings{AutoConfirm: true}, 500, false, true},
+		}
+		for _, tt := range tests {
+			if got := tt.settings.NeedsLargeTradeConfirm(tt.sol); got != tt.large {
+				t.Errorf("%s: expected NeedsLargeTradeConfirm %v, got %v", tt.name, tt.large, got)
+			}
+			if got := tt.settings.SkipsConfirmation(tt.sol); got != tt.skip {
+				t.Errorf("%s: expected SkipsConfirmation %v, got %v", tt.name, tt.skip, got)
+			}
+		}
+	})
+}
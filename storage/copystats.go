@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ErrNoCopyPosition is returned when a copied sell has no recorded buy to
+// realize PnL against
+var ErrNoCopyPosition = errors.New("no open copy position")
+
+// CopyTargetStats is the realized performance of the trades copied from
+// one target wallet
+type CopyTargetStats struct {
+	TargetWallet   string  `json:"target_wallet"`
+	Trades         int     `json:"trades"`
+	Wins           int     `json:"wins"`
+	Losses         int     `json:"losses"`
+	RealizedPnLSOL float64 `json:"realized_pnl_sol"`
+	WindowPnLSOL   float64 `json:"window_pnl_sol"`
+}
+
+// RecordCopyBuy adds solSpent to the cost basis of a token bought by
+// copying targetWallet
+func (db *DB) RecordCopyBuy(userID int64, targetWallet, tokenAddr string, solSpent float64) error {
+	query := `
+		INSERT INTO copy_target_positions (user_id, target_wallet, token_address, cost_sol, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, target_wallet, token_address)
+		DO UPDATE SET cost_sol = cost_sol + excluded.cost_sol, updated_at = excluded.updated_at
+	`
+	_, err := db.Exec(query, userID, targetWallet, tokenAddr, solSpent, db.Now().Unix())
+	return err
+}
+
+// RecordCopySell closes a copied position and records its realized PnL
+// against targetWallet. Copied sells exit the whole position, so the full
+// cost basis is realized.
+func (db *DB) RecordCopySell(userID int64, targetWallet, tokenAddr string, solReceived float64) (float64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var cost float64
+	err = tx.QueryRow(`SELECT cost_sol FROM copy_target_positions WHERE user_id = ? AND target_wallet = ? AND token_address = ?`,
+		userID, targetWallet, tokenAddr).Scan(&cost)
+	if err == sql.ErrNoRows {
+		return 0, ErrNoCopyPosition
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	pnl := solReceived - cost
+	if _, err := tx.Exec(`DELETE FROM copy_target_positions WHERE user_id = ? AND target_wallet = ? AND token_address = ?`,
+		userID, targetWallet, tokenAddr); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`INSERT INTO copy_target_results (user_id, target_wallet, token_address, pnl_sol, created_at) VALUES (?, ?, ?, ?, ?)`,
+		userID, targetWallet, tokenAddr, pnl, db.Now().Unix()); err != nil {
+		return 0, err
+	}
+
+	return pnl, tx.Commit()
+}
+
+// TargetWindowPnL returns the net realized PnL and number of closed trades
+// copied from targetWallet since the given time. Results from before the
+// target was last added are ignored, so re-adding a paused target starts
+// it with a clean slate.
+func (db *DB) TargetWindowPnL(userID int64, targetWallet string, since int64) (float64, int, error) {
+	query := `
+		SELECT COALESCE(SUM(pnl_sol), 0), COUNT(*) FROM copy_target_results
+		WHERE user_id = ? AND target_wallet = ? AND created_at >= ?
+		AND created_at >= COALESCE((SELECT created_at FROM copy_trade_targets WHERE user_id = ? AND target_wallet = ?), 0)
+	`
+	var pnl float64
+	var trades int
+	err := db.QueryRow(query, userID, targetWallet, since, userID, targetWallet).Scan(&pnl, &trades)
+	return pnl, trades, err
+}
+
+// GetCopyTargetStats returns the performance of every target the user has
+// copied trades from, best performer first. WindowPnLSOL covers results
+// since the given time.
+func (db *DB) GetCopyTargetStats(userID int64, since int64) ([]*CopyTargetStats, error) {
+	query := `
+		SELECT target_wallet, COUNT(*),
+			SUM(CASE WHEN pnl_sol > 0 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN pnl_sol < 0 THEN 1 ELSE 0 END),
+			COALESCE(SUM(pnl_sol), 0),
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN pnl_sol ELSE 0 END), 0)
+		FROM copy_target_results
+		WHERE user_id = ?
+		GROUP BY target_wallet
+		ORDER BY 5 DESC
+	`
+	rows, err := db.Query(query, since, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*CopyTargetStats
+	for rows.Next() {
+		var s CopyTargetStats
+		if err := rows.Scan(&s.TargetWallet, &s.Trades, &s.Wins, &s.Losses, &s.RealizedPnLSOL, &s.WindowPnLSOL); err != nil {
+			return nil, err
+		}
+		stats = append(stats, &s)
+	}
+	return stats, rows.Err()
+}
+
+// CopyActivity sums a user's trading since a time for the daily digest
+type CopyActivity struct {
+	Buys        int
+	Sells       int
+	Failed      int
+	SOLSpent    float64
+	SOLReceived float64
+	Closed      int     // copied positions closed
+	PnLSOL      float64 // realized PnL of the closed positions
+}
+
+// GetCopyActivity returns the user's trades and realized copy PnL since
+// the given unix time. Trades are not tagged as copied, so the trade
+// counts include manual trades.
+func (db *DB) GetCopyActivity(userID int64, since int64) (*CopyActivity, error) {
+	var a CopyActivity
+	err := db.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN trade_type = 'buy' AND status != 'failed' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN trade_type = 'sell' AND status != 'failed' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN trade_type = 'buy' AND status != 'failed' THEN sol_amount ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN trade_type = 'sell' AND status != 'failed' THEN sol_amount ELSE 0 END), 0)
+		FROM trades WHERE chat_id = ? AND created_at >= ?
+	`, userID, since).Scan(&a.Buys, &a.Sells, &a.Failed, &a.SOLSpent, &a.SOLReceived)
+	if err != nil {
+		return nil, err
+	}
+	err = db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(pnl_sol), 0) FROM copy_target_results WHERE user_id = ? AND created_at >= ?`,
+		userID, since).Scan(&a.Closed, &a.PnLSOL)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCopyTargetPnL(t *testing.T) {
+	db := newTestDB(t)
+
+	const user, target = int64(7), "targetWallet"
+	if err := db.AddCopyTarget(user, target, 0.1); err != nil {
+		t.Fatalf("Failed to add target: %v", err)
+	}
+
+	t.Run("RealizesPnLOnSell", func(t *testing.T) {
+		db.RecordCopyBuy(user, target, "mintA", 0.1)
+		db.RecordCopyBuy(user, target, "mintA", 0.1)
+
+		pnl, err := db.RecordCopySell(user, target, "mintA", 0.15)
+		if err != nil {
+			t.Fatalf("RecordCopySell failed: %v", err)
+		}
+		if math.Abs(pnl-(-0.05)) > 1e-9 {
+			t.Errorf("Expected -0.05 SOL PnL, got %f", pnl)
+		}
+
+		if _, err := db.RecordCopySell(user, target, "mintA", 0.1); !errors.Is(err, ErrNoCopyPosition) {
+			t.Errorf("Expected ErrNoCopyPosition after position closed, got %v", err)
+		}
+	})
+
+	t.Run("AggregatesStats", func(t *testing.T) {
+		db.RecordCopyBuy(user, target, "mintB", 0.1)
+		db.RecordCopySell(user, target, "mintB", 0.3)
+
+		pnl, trades, err := db.TargetWindowPnL(user, target, 0)
+		if err != nil {
+			t.Fatalf("TargetWindowPnL failed: %v", err)
+		}
+		if trades != 2 || math.Abs(pnl-0.15) > 1e-9 {
+			t.Errorf("Expected 2 trades netting 0.15 SOL, got %d trades, %f SOL", trades, pnl)
+		}
+
+		stats, err := db.GetCopyTargetStats(user, time.Now().Add(time.Hour).Unix())
+		if err != nil {
+			t.Fatalf("GetCopyTargetStats failed: %v", err)
+		}
+		if len(stats) != 1 {
+			t.Fatalf("Expected stats for 1 target, got %d", len(stats))
+		}
+		s := stats[0]
+		if s.Wins != 1 || s.Losses != 1 || s.WindowPnLSOL != 0 {
+			t.Errorf("Unexpected stats: %+v", s)
+		}
+	})
+
+	t.Run("ReAddedTargetStartsClean", func(t *testing.T) {
+		db.RemoveCopyTarget(user, target)
+		if _, err := db.Exec(`INSERT INTO copy_trade_targets (user_id, target_wallet, copy_amount_sol, created_at) VALUES (?, ?, ?, ?)`,
+			user, target, 0.1, time.Now().Add(time.Minute).Unix()); err != nil {
+			t.Fatalf("Failed to re-add target: %v", err)
+		}
+
+		if _, trades, _ := db.TargetWindowPnL(user, target, 0); trades != 0 {
+			t.Errorf("Expected no trades counted after re-adding, got %d", trades)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCopyTargetSides(t *testing.T) {
+	db := newTestDB(t)
+
+	const user = int64(7)
+	tests := []struct {
+		wallet      string
+		buys, sells bool
+	}{
+		{"bothWallet", true, true},
+		{"buysWallet", true, false},
+		{"sellsWallet", false, true},
+	}
+	for _, tt := range tests {
+		if err := db.AddCopyTargetWithFilters(user, tt.wallet, 0.1, tt.buys, tt.sells, 0); err != nil {
+			t.Fatalf("Failed to add %s: %v", tt.wallet, err)
+		}
+	}
+	if err := db.AddCopyTargetWithFilters(user, "neitherWallet", 0.1, false, false, 0); !errors.Is(err, ErrNoCopySides) {
+		t.Errorf("Expected ErrNoCopySides for a target copying nothing, got %v", err)
+	}
+
+	t.Run("StoredAndChecked", func(t *testing.T) {
+		for _, tt := range tests {
+			target, err := db.GetCopyTarget(user, tt.wallet)
+			if err != nil || target == nil {
+				t.Fatalf("GetCopyTarget(%s) = %v, %v", tt.wallet, target, err)
+			}
+			if target.CopyBuys != tt.buys || target.CopySells != tt.sells {
+				t.Errorf("%s: expected buys=%v sells=%v, got %+v", tt.wallet, tt.buys, tt.sells, target)
+			}
+			if target.CopiesSide(true) != tt.buys || target.CopiesSide(false) != tt.sells {
+				t.Errorf("%s: CopiesSide disagrees with the flags", tt.wallet)
+			}
+		}
+
+		watching, err := db.GetUsersWatchingWallet("sellsWallet")
+		if err != nil || len(watching) != 1 || watching[0].CopyBuys || !watching[0].CopySells {
+			t.Errorf("Expected sells-only watcher, got %+v (%v)", watching, err)
+		}
+	})
+
+	t.Run("DefaultsToBothSides", func(t *testing.T) {
+		if err := db.AddCopyTarget(user, "defaultWallet", 0.1); err != nil {
+			t.Fatalf("AddCopyTarget failed: %v", err)
+		}
+		target, _ := db.GetCopyTarget(user, "defaultWallet")
+		if target == nil || !target.CopyBuys || !target.CopySells || target.MinTargetSOL != 0 {
+			t.Errorf("Expected every trade copied by default, got %+v", target)
+		}
+		if !target.MeetsMinimum(0) {
+			t.Error("Expected dust to pass a zero minimum")
+		}
+	})
+
+	t.Run("MinTargetSOL", func(t *testing.T) {
+		if err := db.AddCopyTargetWithFilters(user, "whaleWallet", 0.1, true, true, 0.5); err != nil {
+			t.Fatalf("AddCopyTargetWithFilters failed: %v", err)
+		}
+		target, _ := db.GetCopyTarget(user, "whaleWallet")
+		if target == nil || target.MinTargetSOL != 0.5 {
+			t.Fatalf("Expected a 0.5 SOL minimum, got %+v", target)
+		}
+		if target.MeetsMinimum(0.49) || !target.MeetsMinimum(0.5) {
+			t.Error("Expected swaps below 0.5 SOL to be filtered")
+		}
+		if err := db.AddCopyTargetWithFilters(user, "negativeWallet", 0.1, true, true, -1); err == nil {
+			t.Error("Expected a negative minimum to be rejected")
+		}
+	})
+
+	t.Run("Toggle", func(t *testing.T) {
+		if err := db.SetCopyTargetSides(user, "buysWallet", false, true); err != nil {
+			t.Fatalf("SetCopyTargetSides failed: %v", err)
+		}
+		target, _ := db.GetCopyTarget(user, "buysWallet")
+		if target.CopyBuys || !target.CopySells {
+			t.Errorf("Expected sells only after toggling, got %+v", target)
+		}
+		if err := db.SetCopyTargetSides(user, "buysWallet", false, false); !errors.Is(err, ErrNoCopySides) {
+			t.Errorf("Expected ErrNoCopySides, got %v", err)
+		}
+		if err := db.SetCopyTargetSides(user, "unknownWallet", true, true); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows for an unknown target, got %v", err)
+		}
+	})
+
+	t.Run("LastActivity", func(t *testing.T) {
+		added := time.Unix(1700000000, 0)
+		db.SetClock(&fakeClock{now: added})
+		defer db.SetClock(nil)
+		if err := db.AddCopyTarget(user, "quietWallet", 0.1); err != nil {
+			t.Fatalf("AddCopyTarget failed: %v", err)
+		}
+
+		// Activity counts from when the target was added until it swaps
+		target, _ := db.GetCopyTarget(user, "quietWallet")
+		if target.LastActivity != 0 || target.LastActive() != added.Unix() {
+			t.Fatalf("Expected no activity yet, got %+v", target)
+		}
+		week := 7 * 24 * time.Hour
+		if target.Inactive(added.Add(week-time.Second), week) || !target.Inactive(added.Add(week), week) {
+			t.Error("Expected the target inactive a week after it was added")
+		}
+
+		swapped := added.Add(week).Unix()
+		db.UpdateTargetLastActivity("quietWallet", swapped)
+		db.UpdateTargetLastActivity("quietWallet", swapped-60) // processed late
+		target, _ = db.GetCopyTarget(user, "quietWallet")
+		if target.LastActivity != swapped || target.Inactive(added.Add(week), week) {
+			t.Errorf("Expected activity at %d, got %+v", swapped, target)
+		}
+	})
+
+	t.Run("MissingTarget", func(t *testing.T) {
+		target, err := db.GetCopyTarget(user+1, "bothWallet")
+		if err != nil || target != nil {
+			t.Errorf("Expected no target for another user, got %+v (%v)", target, err)
+		}
+	})
+}
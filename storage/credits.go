@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// UniqueWallets returns wallets with repeated addresses dropped, keeping
+// the first occurrence
+func UniqueWallets(wallets []*WalletData) []*WalletData {
+	seen := make(map[string]bool, len(wallets))
+	unique := make([]*WalletData, 0, len(wallets))
+	for _, w := range wallets {
+		if !seen[w.Wallet] {
+			seen[w.Wallet] = true
+			unique = append(unique, w)
+		}
+	}
+	return unique
+}
+
+// SettleCredits refunds the credits charged beyond the number of results
+// actually delivered and returns the refund
+func (db *DB) SettleCredits(userID int64, charged, delivered int) (int, error) {
+	refund := charged - delivered
+	if refund <= 0 {
+		return 0, nil
+	}
+	if err := db.RefundUserCredits(userID, refund); err != nil {
+		return 0, err
+	}
+	return refund, nil
+}
+
+// ErrInsufficientCredits is returned when a user has no credits to spend
+var ErrInsufficientCredits = errors.New("insufficient credits")
+
+// SpendCredits deducts up to want credits from the user's balance and
+// returns how many were taken, fewer than want when the balance runs
+// short. Each attempt is a single compare-and-swap UPDATE on the balance
+// it read, so concurrent spenders for one user can never take more than
+// the balance between them.
+func (db *DB) SpendCredits(userID int64, want int) (int, error) {
+	if want <= 0 {
+		return 0, nil
+	}
+	for {
+		var balance int
+		err := db.QueryRow(`SELECT credits FROM users WHERE user_id = ?`, userID).Scan(&balance)
+		if err == sql.ErrNoRows {
+			return 0, errors.New("user not found")
+		}
+		if err != nil {
+			return 0, err
+		}
+		if balance <= 0 {
+			return 0, ErrInsufficientCredits
+		}
+
+		take := min(want, balance)
+		result, err := db.Exec(`UPDATE users SET credits = credits - ? WHERE user_id = ? AND credits = ?`, take, userID, balance)
+		if err != nil {
+			return 0, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if rows > 0 {
+			return take, nil
+		}
+		// The balance changed under us; read it again
+	}
+}
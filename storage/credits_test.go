@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMarkLowCreditNotified(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreateUser(42); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	now := time.Now()
+
+	if ok, err := db.MarkLowCreditNotified(42, now); err != nil || !ok {
+		t.Fatalf("First notice should be allowed, got %v, %v", ok, err)
+	}
+	if ok, _ := db.MarkLowCreditNotified(42, now.Add(time.Hour)); ok {
+		t.Error("Second notice on the same day should be suppressed")
+	}
+	if ok, _ := db.MarkLowCreditNotified(42, now.Add(25*time.Hour)); !ok {
+		t.Error("Notice should be allowed again after a day")
+	}
+	if ok, _ := db.MarkLowCreditNotified(7, now); ok {
+		t.Error("Unknown users should not be notified")
+	}
+}
+
+func TestRefundUserCredits(t *testing.T) {
+	db := newTestDB(t)
+
+	db.CreateUser(42)
+	db.UpdateUserCredits(42, 10)
+	if err := db.DecrementUserCredits(42, 4); err != nil {
+		t.Fatalf("DecrementUserCredits failed: %v", err)
+	}
+
+	if err := db.RefundUserCredits(42, 4); err != nil {
+		t.Fatalf("RefundUserCredits failed: %v", err)
+	}
+	if user, _ := db.GetUser(42); user.Credits != 10 {
+		t.Errorf("Expected 10 credits after refund, got %d", user.Credits)
+	}
+	if err := db.RefundUserCredits(7, 4); err == nil {
+		t.Error("Expected error refunding an unknown user")
+	}
+}
+
+// TestSettleCredits tests that a cancelled search which charged for the
+// same wallet twice gets the duplicate refunded
+func TestSettleCredits(t *testing.T) {
+	db := newTestDB(t)
+
+	db.CreateUser(42)
+	db.UpdateUserCredits(42, 10)
+
+	found := []*WalletData{{Wallet: "W1"}, {Wallet: "W2"}, {Wallet: "W1"}, {Wallet: "W3"}, {Wallet: "W2"}}
+	if err := db.DecrementUserCredits(42, len(found)); err != nil {
+		t.Fatalf("DecrementUserCredits failed: %v", err)
+	}
+
+	delivered := UniqueWallets(found)
+	if len(delivered) != 3 || delivered[0].Wallet != "W1" || delivered[2].Wallet != "W3" {
+		t.Fatalf("Unexpected unique wallets: %+v", delivered)
+	}
+
+	refund, err := db.SettleCredits(42, len(found), len(delivered))
+	if err != nil {
+		t.Fatalf("SettleCredits failed: %v", err)
+	}
+	if refund != 2 {
+		t.Errorf("Expected 2 credits refunded, got %d", refund)
+	}
+	if user, _ := db.GetUser(42); user.Credits != 7 {
+		t.Errorf("Expected 7 credits after settling, got %d", user.Credits)
+	}
+
+	if refund, _ := db.SettleCredits(42, 3, 3); refund != 0 {
+		t.Errorf("Expected no refund when everything was delivered, got %d", refund)
+	}
+}
+
+// TestSpendCreditsConcurrent tests that concurrent searches for one user
+// never spend more than the balance between them
+func TestSpendCreditsConcurrent(t *testing.T) {
+	db := newTestDB(t)
+
+	const balance = 50
+	db.CreateUser(42)
+	db.UpdateUserCredits(42, balance)
+
+	// Each search wants more than its fair share, batch by batch
+	const searches, batches, batchSize = 8, 5, 3
+	spent := make([]int, searches)
+	var wg sync.WaitGroup
+	for i := 0; i < searches; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for b := 0; b < batches; b++ {
+				n, err := db.SpendCredits(42, batchSize)
+				if errors.Is(err, ErrInsufficientCredits) {
+					return
+				}
+				if err != nil {
+					t.Errorf("SpendCredits failed: %v", err)
+					return
+				}
+				spent[i] += n
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range spent {
+		total += n
+	}
+	if total != balance {
+		t.Errorf("Expected exactly %d credits spent, got %d", balance, total)
+	}
+	if user, _ := db.GetUser(42); user.Credits != 0 {
+		t.Errorf("Expected an empty balance, got %d", user.Credits)
+	}
+
+	if n, err := db.SpendCredits(42, 1); n != 0 || !errors.Is(err, ErrInsufficientCredits) {
+		t.Errorf("Expected ErrInsufficientCredits, got %d, %v", n, err)
+	}
+}
+
+// TestSpendCreditsPartial tests that a batch larger than the balance
+// takes what is left
+func TestSpendCreditsPartial(t *testing.T) {
+	db := newTestDB(t)
+
+	db.CreateUser(42)
+	db.UpdateUserCredits(42, 4)
+
+	if n, err := db.SpendCredits(42, 10); err != nil || n != 4 {
+		t.Errorf("Expected 4 credits taken, got %d, %v", n, err)
+	}
+	if _, err := db.SpendCredits(7, 1); err == nil {
+		t.Error("Expected error spending for an unknown user")
+	}
+}
@@ -2,23 +2,38 @@ package storage
 
 import (
 	"database/sql"
-	"encoding/base64"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
+	"unicode"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// DefaultWalletWindow is how long scanned wallets are returned and kept
+// until SetWalletRetention says otherwise
+const DefaultWalletWindow = 5 * time.Hour
+
 type DB struct {
 	*sql.DB
+
+	// Scanned wallets are returned for walletWindow after their scan and
+	// deleted by CleanupOldData once walletRetention has passed
+	walletWindow    time.Duration
+	walletRetention time.Duration
+	clock           Clock
 }
 
 type WalletData struct {
-	Wallet      string  `json:"wallet"`
-	Winrate     float64 `json:"winrate"`
-	RealizedPnL float64 `json:"realized_pnl"`
-	ScannedAt   int64   `json:"scanned_at"`
+	Wallet         string  `json:"wallet"`
+	Winrate        float64 `json:"winrate"`
+	RealizedPnLPct float64 `json:"realized_pnl"`     // percent return on cost
+	RealizedPnLUSD float64 `json:"realized_pnl_usd"` // absolute profit in USD
+	TradeCount     int     `json:"trade_count"`
+	ScannedAt      int64   `json:"scanned_at"`
+	FirstSeen      int64   `json:"first_seen"` // unix seconds of the first transaction, 0 if unknown
 }
 
 type Alert struct {
@@ -42,8 +57,43 @@ type CopyTradeTarget struct {
 	UserID        int64   `json:"user_id"`
 	TargetWallet  string  `json:"target_wallet"`
 	CopyAmountSOL float64 `json:"copy_amount_sol"`
+	CopyBuys      bool    `json:"copy_buys"`
+	CopySells     bool    `json:"copy_sells"`
+	MinTargetSOL  float64 `json:"min_target_sol"` // target swaps worth less are not copied
+	Paper         bool    `json:"paper"`          // simulate copies without sending transactions
 	IsActive      bool    `json:"is_active"`
 	CreatedAt     int64   `json:"created_at"`
+	LastActivity  int64   `json:"last_activity"` // last swap seen from the target, 0 if none yet
+	// Copied buys sell AutoSellPct percent of the tokens once their price
+	// reaches AutoSellMultiple times the entry; 0 disables it
+	AutoSellPct      float64 `json:"auto_sell_pct"`
+	AutoSellMultiple float64 `json:"auto_sell_multiple"`
+}
+
+// LastActive returns when the target last swapped, or when it was added
+// if it hasn't been seen swapping since
+func (t *CopyTradeTarget) LastActive() int64 {
+	return max(t.LastActivity, t.CreatedAt)
+}
+
+// Inactive reports whether the target has gone idle without a swap
+func (t *CopyTradeTarget) Inactive(now time.Time, idle time.Duration) bool {
+	return now.Sub(time.Unix(t.LastActive(), 0)) >= idle
+}
+
+// CopiesSide reports whether the target's buys (buy true) or sells are
+// copied
+func (t *CopyTradeTarget) CopiesSide(buy bool) bool {
+	if buy {
+		return t.CopyBuys
+	}
+	return t.CopySells
+}
+
+// MeetsMinimum reports whether a target swap worth solAmount is large
+// enough to copy
+func (t *CopyTradeTarget) MeetsMinimum(solAmount float64) bool {
+	return solAmount >= t.MinTargetSOL
 }
 
 type LimitOrder struct {
@@ -72,7 +122,12 @@ func New(path string) (*DB, error) {
 		return nil, err
 	}
 
-	dbInstance := &DB{db}
+	dbInstance := &DB{
+		DB:              db,
+		walletWindow:    DefaultWalletWindow,
+		walletRetention: DefaultWalletWindow,
+		clock:           systemClock{},
+	}
 
 	// Configure connection pool
 	db.SetMaxOpenConns(50)
@@ -92,6 +147,7 @@ func (db *DB) initSchema() error {
 		wallet TEXT PRIMARY KEY,
 		winrate REAL,
 		realized_pnl REAL,
+		trade_count INTEGER DEFAULT 0,
 		scanned_at INTEGER
 	);
 
@@ -215,36 +271,46 @@ func (db *DB) initSchema() error {
 		return err
 	}
 
-	// Migration: Add copy_trade_auto_buy if not exists
-	// Check if column exists first
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('user_settings') WHERE name='copy_trade_auto_buy'").Scan(&count)
-	if err == nil && count == 0 {
-		if _, err := db.Exec("ALTER TABLE user_settings ADD COLUMN copy_trade_auto_buy INTEGER DEFAULT 0;"); err != nil {
-			log.Printf("Migration warning: %v", err)
-		}
-	}
-
-	return nil
+	return db.runMigrations()
 }
 
 func (db *DB) SaveWallet(w *WalletData) error {
 	query := `
-		INSERT INTO wallets (wallet, winrate, realized_pnl, scanned_at)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO wallets (wallet, winrate, realized_pnl, realized_pnl_usd, trade_count, scanned_at, first_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(wallet) DO UPDATE SET
 			winrate = excluded.winrate,
 			realized_pnl = excluded.realized_pnl,
-			scanned_at = excluded.scanned_at
+			realized_pnl_usd = excluded.realized_pnl_usd,
+			trade_count = excluded.trade_count,
+			scanned_at = excluded.scanned_at,
+			first_seen = CASE WHEN excluded.first_seen > 0 THEN excluded.first_seen ELSE wallets.first_seen END
 	`
-	_, err := db.Exec(query, w.Wallet, w.Winrate, w.RealizedPnL, w.ScannedAt)
+	_, err := db.Exec(query, w.Wallet, w.Winrate, w.RealizedPnLPct, w.RealizedPnLUSD, w.TradeCount, w.ScannedAt, w.FirstSeen)
 	return err
 }
 
+// SetWalletRetention sets how long scanned wallets are returned (display)
+// and how long they are kept before CleanupOldData deletes them. A
+// retention shorter than the display window is raised to match it.
+func (db *DB) SetWalletRetention(display, retention time.Duration) {
+	if retention < display {
+		retention = display
+	}
+	db.walletWindow = display
+	db.walletRetention = retention
+}
+
+// walletCutoff is the scan time before which wallets are no longer shown
+func (db *DB) walletCutoff() int64 {
+	return db.Now().Add(-db.walletWindow).Unix()
+}
+
+// GetWallets returns wallets scanned within the display window, best PnL
+// first
 func (db *DB) GetWallets() ([]*WalletData, error) {
-	// Only get wallets scanned in the last 5 hours
-	cutoff := time.Now().Add(-5 * time.Hour).Unix()
-	rows, err := db.Query("SELECT wallet, winrate, realized_pnl, scanned_at FROM wallets WHERE scanned_at > ? ORDER BY realized_pnl DESC", cutoff)
+	cutoff := db.walletCutoff()
+	rows, err := db.Query("SELECT wallet, winrate, realized_pnl, COALESCE(realized_pnl_usd, 0), COALESCE(trade_count, 0), scanned_at, COALESCE(first_seen, 0) FROM wallets WHERE scanned_at > ? ORDER BY realized_pnl DESC", cutoff)
 	if err != nil {
 		return nil, err
 	}
@@ -253,7 +319,7 @@ func (db *DB) GetWallets() ([]*WalletData, error) {
 	var wallets []*WalletData
 	for rows.Next() {
 		var w WalletData
-		if err := rows.Scan(&w.Wallet, &w.Winrate, &w.RealizedPnL, &w.ScannedAt); err != nil {
+		if err := rows.Scan(&w.Wallet, &w.Winrate, &w.RealizedPnLPct, &w.RealizedPnLUSD, &w.TradeCount, &w.ScannedAt, &w.FirstSeen); err != nil {
 			return nil, err
 		}
 		wallets = append(wallets, &w)
@@ -261,8 +327,29 @@ func (db *DB) GetWallets() ([]*WalletData, error) {
 	return wallets, nil
 }
 
+// GetWallet returns a scanned wallet's latest stats, or nil if it hasn't
+// been scanned or was cleaned up
+func (db *DB) GetWallet(address string) (*WalletData, error) {
+	var w WalletData
+	err := db.QueryRow("SELECT wallet, winrate, realized_pnl, COALESCE(realized_pnl_usd, 0), COALESCE(trade_count, 0), scanned_at, COALESCE(first_seen, 0) FROM wallets WHERE wallet = ?", address).
+		Scan(&w.Wallet, &w.Winrate, &w.RealizedPnLPct, &w.RealizedPnLUSD, &w.TradeCount, &w.ScannedAt, &w.FirstSeen)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// CleanupOldData deletes wallets scanned longer ago than the retention
+// window, and expired below-threshold markers. It returns the number of
+// wallets deleted.
 func (db *DB) CleanupOldData() (int64, error) {
-	cutoff := time.Now().Add(-5 * time.Hour).Unix()
+	if _, err := db.Exec("DELETE FROM below_threshold_wallets WHERE skip_until <= ?", db.Now().Unix()); err != nil {
+		return 0, err
+	}
+	cutoff := db.Now().Add(-db.walletRetention).Unix()
 	result, err := db.Exec("DELETE FROM wallets WHERE scanned_at <= ?", cutoff)
 	if err != nil {
 		return 0, err
@@ -272,7 +359,7 @@ func (db *DB) CleanupOldData() (int64, error) {
 
 func (db *DB) CreateAlert(chatID int64, minWinrate, minPnL float64) error {
 	query := `INSERT INTO alerts (chat_id, min_winrate, min_pnl, created_at) VALUES (?, ?, ?, ?)`
-	_, err := db.Exec(query, chatID, minWinrate, minPnL, time.Now().Unix())
+	_, err := db.Exec(query, chatID, minWinrate, minPnL, db.Now().Unix())
 	return err
 }
 
@@ -305,6 +392,16 @@ type UserSettings struct {
 	PriorityFeeLamports int64
 	AutoConfirm         bool
 	CopyTradeAutoBuy    bool
+	NotifyLevel         string // NotifyAll, NotifyTrades or NotifyDigest
+	QuietStartHour      int    // local hour quiet hours begin, QuietHoursOff when unset
+	QuietEndHour        int    // local hour quiet hours end, exclusive
+	Timezone            string // IANA name quiet hours and the digest use
+	DigestHour          int    // local hour the daily digest is sent
+	LastDigestAt        int64
+	ExecutionMode       string  // ExecutionAuto, ExecutionJito or ExecutionRPC
+	MaxPriceImpactPct   float64 // quotes moving the price more need reconfirming, 0 for no limit
+	MinSOLReserve       float64 // SOL buys always leave in the wallet for fees
+	ConfirmAboveSOL     float64 // trades worth more need the amount typed to confirm, 0 for never
 }
 
 // UserWallet represents a user's wallet
@@ -326,12 +423,22 @@ type EncryptedWallet struct {
 	Nonce               string
 	PasswordHash        string
 	MnemonicEncrypted   string
+	KDFVersion          int
 	CreatedAt           int64
 }
 
+// WalletSecrets is an encrypted wallet decoded for crypto.DecryptPrivateKey
+type WalletSecrets struct {
+	EncryptedKey []byte
+	Salt         []byte
+	Nonce        []byte
+	PasswordHash string
+	KDFVersion   int
+}
+
 // GetUserSettings retrieves settings for a user
 func (db *DB) GetUserSettings(chatID int64) (*UserSettings, error) {
-	query := `SELECT chat_id, slippage_bps, max_slippage_bps, jito_tip_lamports, priority_fee_lamports, auto_confirm, copy_trade_auto_buy FROM user_settings WHERE chat_id = ?`
+	query := `SELECT chat_id, slippage_bps, max_slippage_bps, jito_tip_lamports, priority_fee_lamports, auto_confirm, copy_trade_auto_buy, notify_level, quiet_start_hour, quiet_end_hour, timezone, digest_hour, last_digest_at, execution_mode, max_price_impact_pct, min_sol_reserve, confirm_above_sol FROM user_settings WHERE chat_id = ?`
 	row := db.QueryRow(query, chatID)
 
 	var s UserSettings
@@ -339,7 +446,8 @@ func (db *DB) GetUserSettings(chatID int64) (*UserSettings, error) {
 	var copyTradeAutoBuyInt int
 	// Handle potential missing column for old DBs by using a flexible scan or just ignoring if it fails?
 	// Actually, the migration above ensures column exists.
-	err := row.Scan(&s.ChatID, &s.SlippageBps, &s.MaxSlippageBps, &s.JitoTipLamports, &s.PriorityFeeLamports, &autoConfirmInt, &copyTradeAutoBuyInt)
+	err := row.Scan(&s.ChatID, &s.SlippageBps, &s.MaxSlippageBps, &s.JitoTipLamports, &s.PriorityFeeLamports, &autoConfirmInt, &copyTradeAutoBuyInt,
+		&s.NotifyLevel, &s.QuietStartHour, &s.QuietEndHour, &s.Timezone, &s.DigestHour, &s.LastDigestAt, &s.ExecutionMode, &s.MaxPriceImpactPct, &s.MinSOLReserve, &s.ConfirmAboveSOL)
 	if err == sql.ErrNoRows {
 		// Return defaults
 		return &UserSettings{
@@ -350,6 +458,15 @@ func (db *DB) GetUserSettings(chatID int64) (*UserSettings, error) {
 			PriorityFeeLamports: 5000,
 			AutoConfirm:         false,
 			CopyTradeAutoBuy:    false,
+			NotifyLevel:         NotifyAll,
+			QuietStartHour:      QuietHoursOff,
+			QuietEndHour:        QuietHoursOff,
+			Timezone:            "UTC",
+			DigestHour:          DefaultDigestHour,
+			ExecutionMode:       ExecutionAuto,
+			MaxPriceImpactPct:   DefaultMaxPriceImpactPct,
+			MinSOLReserve:       DefaultMinSOLReserve,
+			ConfirmAboveSOL:     DefaultConfirmAboveSOL,
 		}, nil
 	}
 	if err != nil {
@@ -368,7 +485,7 @@ func (db *DB) UpdateCopyTradeAutoBuy(chatID int64, enabled bool) error {
 	}
 	query := `INSERT INTO user_settings (chat_id, copy_trade_auto_buy, updated_at) VALUES (?, ?, ?)
 			  ON CONFLICT(chat_id) DO UPDATE SET copy_trade_auto_buy = excluded.copy_trade_auto_buy, updated_at = excluded.updated_at`
-	_, err := db.Exec(query, chatID, val, time.Now().Unix())
+	_, err := db.Exec(query, chatID, val, db.Now().Unix())
 	return err
 }
 
@@ -376,7 +493,7 @@ func (db *DB) UpdateCopyTradeAutoBuy(chatID int64, enabled bool) error {
 func (db *DB) UpdateSlippage(chatID int64, bps int) error {
 	query := `INSERT INTO user_settings (chat_id, slippage_bps, updated_at) VALUES (?, ?, ?)
 			  ON CONFLICT(chat_id) DO UPDATE SET slippage_bps = excluded.slippage_bps, updated_at = excluded.updated_at`
-	_, err := db.Exec(query, chatID, bps, time.Now().Unix())
+	_, err := db.Exec(query, chatID, bps, db.Now().Unix())
 	return err
 }
 
@@ -384,7 +501,7 @@ func (db *DB) UpdateSlippage(chatID int64, bps int) error {
 func (db *DB) UpdateJitoTip(chatID int64, lamports int64) error {
 	query := `INSERT INTO user_settings (chat_id, jito_tip_lamports, updated_at) VALUES (?, ?, ?)
 			  ON CONFLICT(chat_id) DO UPDATE SET jito_tip_lamports = excluded.jito_tip_lamports, updated_at = excluded.updated_at`
-	_, err := db.Exec(query, chatID, lamports, time.Now().Unix())
+	_, err := db.Exec(query, chatID, lamports, db.Now().Unix())
 	return err
 }
 
@@ -392,7 +509,7 @@ func (db *DB) UpdateJitoTip(chatID int64, lamports int64) error {
 func (db *DB) UpdatePriorityFee(chatID int64, lamports int64) error {
 	query := `INSERT INTO user_settings (chat_id, priority_fee_lamports, updated_at) VALUES (?, ?, ?)
 			  ON CONFLICT(chat_id) DO UPDATE SET priority_fee_lamports = excluded.priority_fee_lamports, updated_at = excluded.updated_at`
-	_, err := db.Exec(query, chatID, lamports, time.Now().Unix())
+	_, err := db.Exec(query, chatID, lamports, db.Now().Unix())
 	return err
 }
 
@@ -418,11 +535,55 @@ func (db *DB) GetUserWallets(chatID int64) ([]*UserWallet, error) {
 	return wallets, nil
 }
 
+// ErrWalletLimitReached is returned when a user already has the maximum
+// number of wallets
+var ErrWalletLimitReached = errors.New("wallet limit reached")
+
+// NormalizeWalletAddress strips whitespace and invisible characters that
+// pasted addresses often carry. Base58 is case-sensitive, so case is kept.
+func NormalizeWalletAddress(address string) string {
+	return strings.TrimFunc(address, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '\u200b' || r == '\ufeff'
+	})
+}
+
+// CountUserWallets returns how many wallets a user has added
+func (db *DB) CountUserWallets(chatID int64) (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM user_wallets WHERE chat_id = ?", chatID).Scan(&count)
+	return count, err
+}
+
 // AddUserWallet adds a new wallet for a user
 func (db *DB) AddUserWallet(chatID int64, address, name string) error {
+	return db.AddUserWalletWithLimit(chatID, address, name, 0)
+}
+
+// AddUserWalletWithLimit adds a wallet unless the user already has limit
+// wallets, checked in the same transaction as the insert. A limit of 0
+// means unlimited.
+func (db *DB) AddUserWalletWithLimit(chatID int64, address, name string, limit int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if limit > 0 {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM user_wallets WHERE chat_id = ?", chatID).Scan(&count); err != nil {
+			return err
+		}
+		if count >= limit {
+			return ErrWalletLimitReached
+		}
+	}
+
 	query := `INSERT INTO user_wallets (chat_id, wallet_address, wallet_name, created_at) VALUES (?, ?, ?, ?)`
-	_, err := db.Exec(query, chatID, address, name, time.Now().Unix())
-	return err
+	if _, err := tx.Exec(query, chatID, NormalizeWalletAddress(address), strings.TrimSpace(name), db.Now().Unix()); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // SetActiveWallet sets the active wallet for a user
@@ -449,10 +610,136 @@ func (db *DB) SetActiveWallet(chatID int64, address string) error {
 	return tx.Commit()
 }
 
-// RemoveUserWallet removes a wallet
-func (db *DB) RemoveUserWallet(chatID int64, address string) error {
-	_, err := db.Exec("DELETE FROM user_wallets WHERE chat_id = ? AND wallet_address = ?", chatID, address)
-	return err
+// Wallet rename failures
+var (
+	ErrWalletNotFound  = errors.New("wallet not found")
+	ErrWalletNameTaken = errors.New("wallet name already in use")
+)
+
+// RenameUserWallet changes a wallet's name. Names are unique per user,
+// ignoring case.
+func (db *DB) RenameUserWallet(chatID int64, address, name string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var taken int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM user_wallets WHERE chat_id = ? AND wallet_address != ? AND wallet_name = ? COLLATE NOCASE`,
+		chatID, address, name).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return ErrWalletNameTaken
+	}
+
+	result, err := tx.Exec("UPDATE user_wallets SET wallet_name = ? WHERE chat_id = ? AND wallet_address = ?", name, chatID, address)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return ErrWalletNotFound
+	}
+	return tx.Commit()
+}
+
+// RemoveUserWallet removes a wallet. If it was the active one, the most
+// recently created remaining wallet becomes active in the same
+// transaction and is returned; otherwise the result is nil.
+func (db *DB) RemoveUserWallet(chatID int64, address string) (*UserWallet, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var isActive int
+	err = tx.QueryRow("SELECT is_active FROM user_wallets WHERE chat_id = ? AND wallet_address = ?", chatID, address).Scan(&isActive)
+	if err == sql.ErrNoRows {
+		return nil, nil // Already gone
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_wallets WHERE chat_id = ? AND wallet_address = ?", chatID, address); err != nil {
+		return nil, err
+	}
+
+	if isActive != 1 {
+		return nil, tx.Commit()
+	}
+
+	// Promote the newest remaining wallet, if any
+	w := &UserWallet{}
+	err = tx.QueryRow(`
+		SELECT id, chat_id, wallet_address, wallet_name, created_at
+		FROM user_wallets
+		WHERE chat_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`, chatID).Scan(&w.ID, &w.ChatID, &w.WalletAddress, &w.WalletName, &w.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, tx.Commit()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE user_wallets SET is_active = 1 WHERE id = ?", w.ID); err != nil {
+		return nil, err
+	}
+	w.IsActive = true
+	return w, tx.Commit()
+}
+
+// ScanCycle summarizes one pass of the continuous scanner
+type ScanCycle struct {
+	ID             int64  `json:"id"`
+	StartedAt      int64  `json:"started_at"` // unix seconds
+	DurationMs     int64  `json:"duration_ms"`
+	TokensFetched  int    `json:"tokens_fetched"`
+	WalletsScanned int    `json:"wallets_scanned"` // candidate wallets sent to the analyzer
+	WalletsFound   int    `json:"wallets_found"`   // wallets the analyzer returned
+	Error          string `json:"error,omitempty"` // why the cycle stopped early, if it did
+}
+
+// SaveScanCycle records a finished scan cycle
+func (db *DB) SaveScanCycle(c *ScanCycle) error {
+	result, err := db.Exec(`INSERT INTO scan_cycles (started_at, duration_ms, tokens_fetched, wallets_scanned, wallets_found, error)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		c.StartedAt, c.DurationMs, c.TokensFetched, c.WalletsScanned, c.WalletsFound, c.Error)
+	if err != nil {
+		return err
+	}
+	c.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetRecentScanCycles returns the latest scan cycles, newest first
+func (db *DB) GetRecentScanCycles(limit int) ([]*ScanCycle, error) {
+	rows, err := db.Query(`SELECT id, started_at, duration_ms, tokens_fetched, wallets_scanned, wallets_found, error
+		FROM scan_cycles ORDER BY started_at DESC, id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cycles []*ScanCycle
+	for rows.Next() {
+		c := &ScanCycle{}
+		var errText sql.NullString
+		if err := rows.Scan(&c.ID, &c.StartedAt, &c.DurationMs, &c.TokensFetched, &c.WalletsScanned, &c.WalletsFound, &errText); err != nil {
+			return nil, err
+		}
+		c.Error = errText.String
+		cycles = append(cycles, c)
+	}
+	return cycles, rows.Err()
 }
 
 // HasEncryptedWallet checks if user has an encrypted wallet
@@ -477,12 +764,12 @@ func (db *DB) GetEncryptedWallet(chatID int64) (*EncryptedWallet, error) {
 
 // GetEncryptedWalletForDecryption retrieves the full encrypted wallet data
 func (db *DB) GetEncryptedWalletForDecryption(chatID int64) (*EncryptedWallet, error) {
-	query := `SELECT chat_id, public_key, encrypted_private_key, encryption_salt, nonce, password_hash, mnemonic_encrypted FROM encrypted_wallets WHERE chat_id = ?`
+	query := `SELECT chat_id, public_key, encrypted_private_key, encryption_salt, nonce, password_hash, mnemonic_encrypted, kdf_version FROM encrypted_wallets WHERE chat_id = ?`
 	row := db.QueryRow(query, chatID)
 
 	var w EncryptedWallet
 	var mnemonic sql.NullString
-	err := row.Scan(&w.ChatID, &w.PublicKey, &w.EncryptedPrivateKey, &w.EncryptionSalt, &w.Nonce, &w.PasswordHash, &mnemonic)
+	err := row.Scan(&w.ChatID, &w.PublicKey, &w.EncryptedPrivateKey, &w.EncryptionSalt, &w.Nonce, &w.PasswordHash, &mnemonic, &w.KDFVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -493,40 +780,33 @@ func (db *DB) GetEncryptedWalletForDecryption(chatID int64) (*EncryptedWallet, e
 }
 
 // GetEncryptedWalletForCrypto converts stored wallet data to crypto.EncryptedWallet
-func (db *DB) GetEncryptedWalletForCrypto(chatID int64) ([]byte, []byte, []byte, string, error) {
+func (db *DB) GetEncryptedWalletForCrypto(chatID int64) (*WalletSecrets, error) {
 	stored, err := db.GetEncryptedWalletForDecryption(chatID)
 	if err != nil || stored == nil {
-		return nil, nil, nil, "", err
-	}
-
-	// Decode Base64 fields
-	encryptedKey, err := base64.StdEncoding.DecodeString(stored.EncryptedPrivateKey)
-	if err != nil {
-		// Fallback for legacy raw strings? Or just error?
-		// Assuming migration or fresh start. Let's try to return raw if decode fails for backward compat if needed,
-		// but instructions say "run a one-time migration or handle both".
-		// For now, let's assume strict Base64 as per instruction "decode these base64 strings".
-		// If we want to be safe:
-		encryptedKey = []byte(stored.EncryptedPrivateKey) // Fallback to raw if decode fails?
-		// Actually, let's stick to the instruction: "decode these base64 strings back into []byte"
-		// If it fails, it might be legacy data.
-		// Let's try to decode, if error, assume it's raw string (legacy).
-		if _, ok := err.(base64.CorruptInputError); ok || err != nil {
-			encryptedKey = []byte(stored.EncryptedPrivateKey)
-		}
+		return nil, err
 	}
 
-	salt, err := base64.StdEncoding.DecodeString(stored.EncryptionSalt)
+	// Every field is strict base64; migration 7 normalized older raw rows
+	encryptedKey, err := decodeWalletField("encrypted_private_key", stored.EncryptedPrivateKey)
 	if err != nil {
-		salt = []byte(stored.EncryptionSalt)
+		return nil, err
 	}
-
-	nonce, err := base64.StdEncoding.DecodeString(stored.Nonce)
+	salt, err := decodeWalletField("encryption_salt", stored.EncryptionSalt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := decodeWalletField("nonce", stored.Nonce)
 	if err != nil {
-		nonce = []byte(stored.Nonce)
+		return nil, err
 	}
 
-	return encryptedKey, salt, nonce, stored.PasswordHash, nil
+	return &WalletSecrets{
+		EncryptedKey: encryptedKey,
+		Salt:         salt,
+		Nonce:        nonce,
+		PasswordHash: stored.PasswordHash,
+		KDFVersion:   stored.KDFVersion,
+	}, nil
 }
 
 // GetActiveWallet returns the active wallet for a user
@@ -551,28 +831,51 @@ func (db *DB) GetActiveWallet(chatID int64) (*UserWallet, error) {
 }
 
 // SaveEncryptedWallet saves an encrypted wallet to database
-func (db *DB) SaveEncryptedWallet(chatID int64, publicKey string, encryptedKey, salt, nonce []byte, passwordHash, mnemonicEnc string) error {
+func (db *DB) SaveEncryptedWallet(chatID int64, publicKey string, encryptedKey, salt, nonce []byte, passwordHash, mnemonicEnc string, kdfVersion int) error {
 	// Encode to Base64
-	encryptedKeyB64 := base64.StdEncoding.EncodeToString(encryptedKey)
-	saltB64 := base64.StdEncoding.EncodeToString(salt)
-	nonceB64 := base64.StdEncoding.EncodeToString(nonce)
+	encryptedKeyB64 := walletEncoding.EncodeToString(encryptedKey)
+	saltB64 := walletEncoding.EncodeToString(salt)
+	nonceB64 := walletEncoding.EncodeToString(nonce)
 
 	_, err := db.Exec(`
 		INSERT OR REPLACE INTO encrypted_wallets
-		(chat_id, public_key, encrypted_private_key, encryption_salt, nonce, password_hash, mnemonic_encrypted, created_at, last_used)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		(chat_id, public_key, encrypted_private_key, encryption_salt, nonce, password_hash, mnemonic_encrypted, kdf_version, created_at, last_used)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, chatID, publicKey,
 		encryptedKeyB64,
 		saltB64,
 		nonceB64,
 		passwordHash,
 		mnemonicEnc,
-		time.Now().Unix(),
-		time.Now().Unix(),
-		time.Now().Unix())
+		kdfVersion,
+		db.Now().Unix(),
+		db.Now().Unix())
 	return err
 }
 
+// UpdateWalletEncryption replaces a wallet's private key ciphertext after
+// it was re-encrypted, e.g. with stronger KDF parameters. The mnemonic is
+// left untouched.
+func (db *DB) UpdateWalletEncryption(chatID int64, encryptedKey, salt, nonce []byte, passwordHash string, kdfVersion int) error {
+	result, err := db.Exec(`
+		UPDATE encrypted_wallets
+		SET encrypted_private_key = ?, encryption_salt = ?, nonce = ?, password_hash = ?, kdf_version = ?, last_used = ?
+		WHERE chat_id = ?
+	`, walletEncoding.EncodeToString(encryptedKey),
+		walletEncoding.EncodeToString(salt),
+		walletEncoding.EncodeToString(nonce),
+		passwordHash, kdfVersion, db.Now().Unix(), chatID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return fmt.Errorf("no encrypted wallet for chat %d", chatID)
+	}
+	return nil
+}
+
 // GetUser retrieves a user by ID
 func (db *DB) GetUser(userID int64) (*User, error) {
 	query := `SELECT user_id, credits, trial_expires_at, plan_type, joined_at FROM users WHERE user_id = ?`
@@ -592,7 +895,7 @@ func (db *DB) GetUser(userID int64) (*User, error) {
 // CreateUser creates a new user
 func (db *DB) CreateUser(userID int64) error {
 	query := `INSERT INTO users (user_id, credits, trial_expires_at, plan_type, joined_at) VALUES (?, 0, 0, '', ?)`
-	_, err := db.Exec(query, userID, time.Now().Unix())
+	_, err := db.Exec(query, userID, db.Now().Unix())
 	return err
 }
 
@@ -614,66 +917,145 @@ func (db *DB) DecrementUserCredits(userID int64, amount int) error {
 		return err
 	}
 	if rows == 0 {
-		return errors.New("insufficient credits")
+		return ErrInsufficientCredits
+	}
+	return nil
+}
+
+// RefundUserCredits gives amount credits back to a user
+func (db *DB) RefundUserCredits(userID int64, amount int) error {
+	if amount <= 0 {
+		return nil
+	}
+	result, err := db.Exec(`UPDATE users SET credits = credits + ? WHERE user_id = ?`, amount, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("user not found")
 	}
 	return nil
 }
 
+// lowCreditNotifyInterval is the minimum gap between low-credit notices
+const lowCreditNotifyInterval = 24 * time.Hour
+
+// MarkLowCreditNotified records a low-credit notice for userID and reports
+// whether one may be sent, false if one already went out in the last day
+func (db *DB) MarkLowCreditNotified(userID int64, now time.Time) (bool, error) {
+	query := `UPDATE users SET low_credit_notified_at = ? WHERE user_id = ? AND low_credit_notified_at <= ?`
+	result, err := db.Exec(query, now.Unix(), userID, now.Add(-lowCreditNotifyInterval).Unix())
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
 func (db *DB) SetUserPlan(userID int64, planType string, credits int, expiresAt int64) error {
 	query := `UPDATE users SET plan_type = ?, credits = ?, trial_expires_at = ? WHERE user_id = ?`
 	_, err := db.Exec(query, planType, credits, expiresAt, userID)
 	return err
 }
 
-// AddCopyTarget adds a new copy trade target
+// ErrNoCopySides is returned when a copy target would copy neither buys
+// nor sells
+var ErrNoCopySides = errors.New("copy target must copy buys or sells")
+
+// copyTargetColumns is the column list scanCopyTargets expects
+const copyTargetColumns = `id, user_id, target_wallet, copy_amount_sol, copy_buys, copy_sells, min_target_sol, paper, is_active, created_at, last_activity_at, auto_sell_pct, auto_sell_multiple`
+
+// AddCopyTarget adds a new copy trade target that copies every buy and
+// sell
 func (db *DB) AddCopyTarget(userID int64, targetWallet string, amountSOL float64) error {
-	query := `INSERT INTO copy_trade_targets (user_id, target_wallet, copy_amount_sol, created_at) VALUES (?, ?, ?, ?)`
-	_, err := db.Exec(query, userID, targetWallet, amountSOL, time.Now().Unix())
+	return db.AddCopyTargetWithFilters(userID, targetWallet, amountSOL, true, true, 0)
+}
+
+// AddCopyTargetWithFilters adds a new copy trade target that copies only
+// the chosen sides of the target's trades, and only swaps worth at least
+// minTargetSOL
+func (db *DB) AddCopyTargetWithFilters(userID int64, targetWallet string, amountSOL float64, buys, sells bool, minTargetSOL float64) error {
+	if !buys && !sells {
+		return ErrNoCopySides
+	}
+	if minTargetSOL < 0 {
+		return fmt.Errorf("minimum target trade must not be negative, got %f", minTargetSOL)
+	}
+	query := `INSERT INTO copy_trade_targets (user_id, target_wallet, copy_amount_sol, copy_buys, copy_sells, min_target_sol, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.Exec(query, userID, targetWallet, amountSOL, buys, sells, minTargetSOL, db.Now().Unix())
 	return err
 }
 
-// GetCopyTargets retrieves all active targets for a user
-func (db *DB) GetCopyTargets(userID int64) ([]*CopyTradeTarget, error) {
-	query := `SELECT id, user_id, target_wallet, copy_amount_sol, is_active, created_at FROM copy_trade_targets WHERE user_id = ? AND is_active = 1`
-	rows, err := db.Query(query, userID)
+// SetCopyTargetSides changes which sides of a target's trades are copied
+func (db *DB) SetCopyTargetSides(userID int64, targetWallet string, buys, sells bool) error {
+	if !buys && !sells {
+		return ErrNoCopySides
+	}
+	result, err := db.Exec(`UPDATE copy_trade_targets SET copy_buys = ?, copy_sells = ? WHERE user_id = ? AND target_wallet = ?`,
+		buys, sells, userID, targetWallet)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetCopyTarget returns the user's target for a wallet, or nil if the
+// user doesn't copy it
+func (db *DB) GetCopyTarget(userID int64, targetWallet string) (*CopyTradeTarget, error) {
+	rows, err := db.Query(`SELECT `+copyTargetColumns+` FROM copy_trade_targets WHERE user_id = ? AND target_wallet = ?`, userID, targetWallet)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	targets, err := scanCopyTargets(rows)
+	if err != nil || len(targets) == 0 {
+		return nil, err
+	}
+	return targets[0], nil
+}
 
-	var targets []*CopyTradeTarget
-	for rows.Next() {
-		var t CopyTradeTarget
-		var isActiveInt int
-		if err := rows.Scan(&t.ID, &t.UserID, &t.TargetWallet, &t.CopyAmountSOL, &isActiveInt, &t.CreatedAt); err != nil {
-			return nil, err
-		}
-		t.IsActive = isActiveInt == 1
-		targets = append(targets, &t)
+// GetCopyTargets retrieves all active targets for a user
+func (db *DB) GetCopyTargets(userID int64) ([]*CopyTradeTarget, error) {
+	rows, err := db.Query(`SELECT `+copyTargetColumns+` FROM copy_trade_targets WHERE user_id = ? AND is_active = 1`, userID)
+	if err != nil {
+		return nil, err
 	}
-	return targets, nil
+	return scanCopyTargets(rows)
 }
 
 // GetAllActiveCopyTargets retrieves all active copy trade targets
 func (db *DB) GetAllActiveCopyTargets() ([]*CopyTradeTarget, error) {
-	query := `SELECT id, user_id, target_wallet, copy_amount_sol, is_active, created_at FROM copy_trade_targets WHERE is_active = 1`
-	rows, err := db.Query(query)
+	rows, err := db.Query(`SELECT ` + copyTargetColumns + ` FROM copy_trade_targets WHERE is_active = 1`)
 	if err != nil {
 		return nil, err
 	}
+	return scanCopyTargets(rows)
+}
+
+// scanCopyTargets reads copyTargetColumns rows and closes them
+func scanCopyTargets(rows *sql.Rows) ([]*CopyTradeTarget, error) {
 	defer rows.Close()
 
 	var targets []*CopyTradeTarget
 	for rows.Next() {
 		var t CopyTradeTarget
 		var isActiveInt int
-		if err := rows.Scan(&t.ID, &t.UserID, &t.TargetWallet, &t.CopyAmountSOL, &isActiveInt, &t.CreatedAt); err != nil {
+		if err := rows.Scan(&t.ID, &t.UserID, &t.TargetWallet, &t.CopyAmountSOL, &t.CopyBuys, &t.CopySells, &t.MinTargetSOL, &t.Paper, &isActiveInt, &t.CreatedAt, &t.LastActivity, &t.AutoSellPct, &t.AutoSellMultiple); err != nil {
 			return nil, err
 		}
 		t.IsActive = isActiveInt == 1
 		targets = append(targets, &t)
 	}
-	return targets, nil
+	return targets, rows.Err()
 }
 
 // RemoveCopyTarget deactivates a copy target
@@ -683,26 +1065,22 @@ func (db *DB) RemoveCopyTarget(userID int64, targetWallet string) error {
 	return err
 }
 
+// UpdateTargetLastActivity records a swap by targetWallet at the given
+// unix time for everyone copying it. Older times are ignored, so swaps
+// processed out of order don't move it back.
+func (db *DB) UpdateTargetLastActivity(targetWallet string, at int64) error {
+	_, err := db.Exec(`UPDATE copy_trade_targets SET last_activity_at = ? WHERE target_wallet = ? AND last_activity_at < ?`,
+		at, targetWallet, at)
+	return err
+}
+
 // GetUsersWatchingWallet returns all users watching a specific wallet
 func (db *DB) GetUsersWatchingWallet(wallet string) ([]*CopyTradeTarget, error) {
-	query := `SELECT id, user_id, target_wallet, copy_amount_sol, is_active, created_at FROM copy_trade_targets WHERE target_wallet = ? AND is_active = 1`
-	rows, err := db.Query(query, wallet)
+	rows, err := db.Query(`SELECT `+copyTargetColumns+` FROM copy_trade_targets WHERE target_wallet = ? AND is_active = 1`, wallet)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var targets []*CopyTradeTarget
-	for rows.Next() {
-		var t CopyTradeTarget
-		var isActiveInt int
-		if err := rows.Scan(&t.ID, &t.UserID, &t.TargetWallet, &t.CopyAmountSOL, &isActiveInt, &t.CreatedAt); err != nil {
-			return nil, err
-		}
-		t.IsActive = isActiveInt == 1
-		targets = append(targets, &t)
-	}
-	return targets, nil
+	return scanCopyTargets(rows)
 }
 
 // Trade represents a trade record
@@ -714,8 +1092,8 @@ type Trade struct {
 	TradeType     string // "buy" or "sell"
 	TokenAddress  string
 	SolAmount     float64
-	TokenAmount   float64
-	PricePerToken float64
+	TokenAmount   float64 // token base units
+	PricePerToken float64 // SOL per token base unit
 	JitoTip       float64
 	Status        string // "pending", "confirmed", "failed"
 	CreatedAt     int64
@@ -728,7 +1106,7 @@ func (db *DB) SaveTrade(userID int64, walletAddr, signature, tradeType, tokenAdd
 		INSERT INTO trades (chat_id, wallet_address, tx_signature, trade_type, token_address, sol_amount, token_amount, price_per_token, jito_tip, status, created_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := db.Exec(query, userID, walletAddr, signature, tradeType, tokenAddr, solAmount, tokenAmount, pricePerToken, jitoTip, status, time.Now().Unix())
+	_, err := db.Exec(query, userID, walletAddr, signature, tradeType, tokenAddr, solAmount, tokenAmount, pricePerToken, jitoTip, status, db.Now().Unix())
 	return err
 }
 
@@ -789,7 +1167,7 @@ func (db *DB) SaveLimitOrder(order *LimitOrder) error {
 		INSERT INTO limit_orders (user_id, order_pubkey, token_symbol, token_mint, side, price, amount, status, expires_at, target_mcap, initial_rent_sol, created_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := db.Exec(query, order.UserID, order.OrderPubkey, order.TokenSymbol, order.TokenMint, order.Side, order.Price, order.Amount, order.Status, order.ExpiresAt, order.TargetMCAP, order.InitialRentSOL, time.Now().Unix())
+	_, err := db.Exec(query, order.UserID, order.OrderPubkey, order.TokenSymbol, order.TokenMint, order.Side, order.Price, order.Amount, order.Status, order.ExpiresAt, order.TargetMCAP, order.InitialRentSOL, db.Now().Unix())
 	return err
 }
 
@@ -800,7 +1178,7 @@ func (db *DB) GetExpiredOrdersBatch(limit int) ([]*LimitOrder, error) {
 			  WHERE expires_at < ? AND status = 'OPEN' 
 			  LIMIT ?`
 
-	rows, err := db.Query(query, time.Now().Unix(), limit)
+	rows, err := db.Query(query, db.Now().Unix(), limit)
 	if err != nil {
 		return nil, err
 	}
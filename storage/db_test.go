@@ -1,34 +1,32 @@
 package storage
 
 import (
-	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
 
-func TestDatabaseOperations(t *testing.T) {
-	// Create temporary database
-	tmpfile, err := os.CreateTemp("", "test_*.db")
-	if err != nil {
-		t.Fatal(err)
-	}
-	dbPath := tmpfile.Name()
-	tmpfile.Close()
-	defer os.Remove(dbPath)
-
-	// Initialize database
-	db, err := New(dbPath)
+// newTestDB opens a fresh database in a temporary directory, closed when
+// the test ends
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
 	if err != nil {
 		t.Fatalf("Failed to create database: %v", err)
 	}
-	defer db.Close()
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestDatabaseOperations(t *testing.T) {
+	db := newTestDB(t)
 
 	t.Run("SaveWallet", func(t *testing.T) {
 		wallet := &WalletData{
-			Wallet:      "TestWallet123",
-			Winrate:     75.5,
-			RealizedPnL: 125.75,
-			ScannedAt:   time.Now().Unix(),
+			Wallet:         "TestWallet123",
+			Winrate:        75.5,
+			RealizedPnLPct: 125.75,
+			ScannedAt:      time.Now().Unix(),
 		}
 
 		err := db.SaveWallet(wallet)
@@ -56,7 +54,7 @@ func TestDatabaseOperations(t *testing.T) {
 
 		// Check if wallets are sorted by PnL descending
 		for i := 1; i < len(wallets); i++ {
-			if wallets[i-1].RealizedPnL < wallets[i].RealizedPnL {
+			if wallets[i-1].RealizedPnLPct < wallets[i].RealizedPnLPct {
 				t.Error("Wallets should be sorted by PnL descending")
 			}
 		}
@@ -67,19 +65,19 @@ func TestDatabaseOperations(t *testing.T) {
 	t.Run("CleanupOldData", func(t *testing.T) {
 		// Add old wallet
 		oldWallet := &WalletData{
-			Wallet:      "OldWallet",
-			Winrate:     50.0,
-			RealizedPnL: 30.0,
-			ScannedAt:   time.Now().Add(-6 * time.Hour).Unix(),
+			Wallet:         "OldWallet",
+			Winrate:        50.0,
+			RealizedPnLPct: 30.0,
+			ScannedAt:      time.Now().Add(-6 * time.Hour).Unix(),
 		}
 		db.SaveWallet(oldWallet)
 
 		// Add recent wallet
 		recentWallet := &WalletData{
-			Wallet:      "RecentWallet",
-			Winrate:     60.0,
-			RealizedPnL: 40.0,
-			ScannedAt:   time.Now().Unix(),
+			Wallet:         "RecentWallet",
+			Winrate:        60.0,
+			RealizedPnLPct: 40.0,
+			ScannedAt:      time.Now().Unix(),
 		}
 		db.SaveWallet(recentWallet)
 
@@ -104,13 +102,27 @@ func TestDatabaseOperations(t *testing.T) {
 		}
 	})
 
+	t.Run("GetWallet", func(t *testing.T) {
+		w, err := db.GetWallet("TestWallet123")
+		if err != nil || w == nil {
+			t.Fatalf("GetWallet failed: %v", err)
+		}
+		if w.Winrate != 80.0 || w.RealizedPnLPct != 125.75 {
+			t.Errorf("Expected the updated stats, got %+v", w)
+		}
+
+		if w, err := db.GetWallet("UnknownWallet"); err != nil || w != nil {
+			t.Errorf("Expected nil for an unscanned wallet, got %+v, %v", w, err)
+		}
+	})
+
 	t.Run("SaveWallet_InvalidData", func(t *testing.T) {
 		// Test with empty wallet address
 		wallet := &WalletData{
-			Wallet:      "",
-			Winrate:     75.5,
-			RealizedPnL: 125.75,
-			ScannedAt:   time.Now().Unix(),
+			Wallet:         "",
+			Winrate:        75.5,
+			RealizedPnLPct: 125.75,
+			ScannedAt:      time.Now().Unix(),
 		}
 
 		err := db.SaveWallet(wallet)
@@ -120,6 +132,29 @@ func TestDatabaseOperations(t *testing.T) {
 		}
 	})
 
+	t.Run("ScanCycles", func(t *testing.T) {
+		for i := int64(0); i < 3; i++ {
+			c := &ScanCycle{StartedAt: 1000 + i, DurationMs: 60000, TokensFetched: 50, WalletsScanned: 400, WalletsFound: int(10 - i)}
+			if err := db.SaveScanCycle(c); err != nil {
+				t.Fatalf("SaveScanCycle failed: %v", err)
+			}
+		}
+		db.SaveScanCycle(&ScanCycle{StartedAt: 999, Error: "token fetch: timeout"})
+
+		cycles, err := db.GetRecentScanCycles(2)
+		if err != nil {
+			t.Fatalf("GetRecentScanCycles failed: %v", err)
+		}
+		if len(cycles) != 2 || cycles[0].StartedAt != 1002 || cycles[0].WalletsFound != 8 {
+			t.Errorf("Expected the 2 newest cycles first, got %+v", cycles)
+		}
+
+		all, _ := db.GetRecentScanCycles(10)
+		if len(all) != 4 || all[3].Error != "token fetch: timeout" {
+			t.Errorf("Expected the failed cycle last with its error, got %+v", all)
+		}
+	})
+
 	t.Run("ConcurrentWrites", func(t *testing.T) {
 		// Test concurrent writes to check for race conditions
 		done := make(chan bool)
@@ -127,10 +162,10 @@ func TestDatabaseOperations(t *testing.T) {
 		for i := 0; i < 10; i++ {
 			go func(id int) {
 				wallet := &WalletData{
-					Wallet:      "ConcurrentWallet" + string(rune(id)),
-					Winrate:     float64(50 + id),
-					RealizedPnL: float64(100 + id),
-					ScannedAt:   time.Now().Unix(),
+					Wallet:         "ConcurrentWallet" + string(rune(id)),
+					Winrate:        float64(50 + id),
+					RealizedPnLPct: float64(100 + id),
+					ScannedAt:      time.Now().Unix(),
 				}
 				db.SaveWallet(wallet)
 				done <- true
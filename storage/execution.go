@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+)
+
+// Execution modes deciding how a user's trades are submitted
+const (
+	ExecutionAuto = "auto" // Jito for thin or volatile tokens, plain RPC otherwise
+	ExecutionJito = "jito" // always a Jito bundle, kept out of the public mempool
+	ExecutionRPC  = "rpc"  // always sendTransaction through the RPC
+)
+
+// In auto mode, tokens with less liquidity or a larger 1h move than these
+// are bought and sold through Jito, where sandwiching pays off most
+const (
+	AutoJitoMaxLiquidityUSD = 50000
+	AutoJitoMinMovePct      = 15
+)
+
+// MinJitoTipLamports is the tip sent when Jito is chosen without a
+// configured tip; bundles without one aren't picked up
+const MinJitoTipLamports = 1000
+
+// ValidExecutionMode reports whether mode is a known execution mode
+func ValidExecutionMode(mode string) bool {
+	switch mode {
+	case ExecutionAuto, ExecutionJito, ExecutionRPC:
+		return true
+	}
+	return false
+}
+
+// UseJito reports whether a trade in a token with liquidityUSD of
+// liquidity that moved change1hPct in the last hour should be sent as a
+// Jito bundle. A liquidity of 0 means it's unknown and counts as thin.
+func (s *UserSettings) UseJito(liquidityUSD, change1hPct float64) bool {
+	switch s.ExecutionMode {
+	case ExecutionJito:
+		return true
+	case ExecutionRPC:
+		return false
+	}
+	return liquidityUSD < AutoJitoMaxLiquidityUSD || math.Abs(change1hPct) >= AutoJitoMinMovePct
+}
+
+// JitoTip returns the tip for a Jito bundle, at least MinJitoTipLamports
+func (s *UserSettings) JitoTip() uint64 {
+	return uint64(max(s.JitoTipLamports, MinJitoTipLamports))
+}
+
+// UpdateExecutionMode sets how the user's trades are submitted
+func (db *DB) UpdateExecutionMode(chatID int64, mode string) error {
+	if !ValidExecutionMode(mode) {
+		return fmt.Errorf("unknown execution mode %q", mode)
+	}
+	query := `INSERT INTO user_settings (chat_id, execution_mode, updated_at) VALUES (?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET execution_mode = excluded.execution_mode, updated_at = excluded.updated_at`
+	_, err := db.Exec(query, chatID, mode, db.Now().Unix())
+	return err
+}
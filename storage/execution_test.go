@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestExecutionMode(t *testing.T) {
+	db := newTestDB(t)
+
+	t.Run("Update", func(t *testing.T) {
+		if s, _ := db.GetUserSettings(42); s.ExecutionMode != ExecutionAuto {
+			t.Errorf("Expected auto by default, got %q", s.ExecutionMode)
+		}
+		db.UpdateSlippage(42, 100)
+		if s, _ := db.GetUserSettings(42); s.ExecutionMode != ExecutionAuto {
+			t.Errorf("Expected auto on an existing row, got %q", s.ExecutionMode)
+		}
+
+		if err := db.UpdateExecutionMode(42, ExecutionRPC); err != nil {
+			t.Fatalf("UpdateExecutionMode failed: %v", err)
+		}
+		if s, _ := db.GetUserSettings(42); s.ExecutionMode != ExecutionRPC || s.SlippageBps != 100 {
+			t.Errorf("Unexpected settings %+v", s)
+		}
+		if err := db.UpdateExecutionMode(42, "flashbots"); err == nil {
+			t.Error("Expected an unknown mode to be rejected")
+		}
+	})
+
+	t.Run("UseJito", func(t *testing.T) {
+		tests := []struct {
+			mode      string
+			liquidity float64
+			change1h  float64
+			want      bool
+		}{
+			{ExecutionJito, 5_000_000, 0, true},
+			{ExecutionRPC, 1000, 80, false},
+			{ExecutionAuto, 5_000_000, 2, false},
+			{ExecutionAuto, 20_000, 2, true},      // thin pool
+			{ExecutionAuto, 0, 0, true},           // liquidity unknown
+			{ExecutionAuto, 5_000_000, -30, true}, // dumping
+			{ExecutionAuto, 5_000_000, 15, true},
+		}
+		for _, tt := range tests {
+			s := &UserSettings{ExecutionMode: tt.mode}
+			if got := s.UseJito(tt.liquidity, tt.change1h); got != tt.want {
+				t.Errorf("%s with $%.0f liquidity and %.0f%% 1h: expected Jito %v, got %v", tt.mode, tt.liquidity, tt.change1h, tt.want, got)
+			}
+		}
+	})
+
+	t.Run("JitoTip", func(t *testing.T) {
+		if tip := (&UserSettings{}).JitoTip(); tip != MinJitoTipLamports {
+			t.Errorf("Expected the minimum tip without one set, got %d", tip)
+		}
+		if tip := (&UserSettings{JitoTipLamports: 100000}).JitoTip(); tip != 100000 {
+			t.Errorf("Expected the configured tip, got %d", tip)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Age returns how long ago the wallet's first transaction happened, or 0
+// if it is unknown
+func (w *WalletData) Age(now time.Time) time.Duration {
+	if w.FirstSeen <= 0 {
+		return 0
+	}
+	if age := now.Sub(time.Unix(w.FirstSeen, 0)); age > 0 {
+		return age
+	}
+	return 0
+}
+
+// MeetsMinAge reports whether the wallet is at least minAgeDays old.
+// A wallet whose first transaction is unknown only passes when no minimum
+// is set.
+func (w *WalletData) MeetsMinAge(minAgeDays int, now time.Time) bool {
+	if minAgeDays <= 0 {
+		return true
+	}
+	if w.FirstSeen <= 0 {
+		return false
+	}
+	return !time.Unix(w.FirstSeen, 0).After(now.AddDate(0, 0, -minAgeDays))
+}
+
+// GetWalletFirstSeen returns the cached first transaction time of a wallet,
+// or 0 if it hasn't been looked up yet
+func (db *DB) GetWalletFirstSeen(wallet string) (int64, error) {
+	var firstSeen int64
+	err := db.QueryRow("SELECT first_seen FROM wallet_first_seen WHERE wallet = ?", wallet).Scan(&firstSeen)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return firstSeen, err
+}
+
+// SaveWalletFirstSeen caches a wallet's complete first transaction time.
+// The earliest value wins if two lookups save one.
+func (db *DB) SaveWalletFirstSeen(wallet string, firstSeen int64) error {
+	if firstSeen <= 0 {
+		return nil
+	}
+	_, err := db.Exec(`INSERT INTO wallet_first_seen (wallet, first_seen) VALUES (?, ?)
+		ON CONFLICT(wallet) DO UPDATE SET first_seen = MIN(first_seen, excluded.first_seen)`, wallet, firstSeen)
+	return err
+}
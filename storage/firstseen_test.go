@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWalletFirstSeen(t *testing.T) {
+	db := newTestDB(t)
+
+	t.Run("Cache", func(t *testing.T) {
+		if got, err := db.GetWalletFirstSeen("W1"); err != nil || got != 0 {
+			t.Fatalf("Expected 0 for an unknown wallet, got %d (%v)", got, err)
+		}
+		db.SaveWalletFirstSeen("W1", 2000)
+		db.SaveWalletFirstSeen("W1", 1000)
+		db.SaveWalletFirstSeen("W1", 3000)
+		if got, _ := db.GetWalletFirstSeen("W1"); got != 1000 {
+			t.Errorf("Expected the earliest first_seen 1000, got %d", got)
+		}
+	})
+
+	t.Run("SurvivesRescan", func(t *testing.T) {
+		now := time.Now().Unix()
+		db.SaveWallet(&WalletData{Wallet: "W2", Winrate: 80, ScannedAt: now, FirstSeen: 1234})
+		db.SaveWallet(&WalletData{Wallet: "W2", Winrate: 85, ScannedAt: now})
+
+		wallets, err := db.GetWallets()
+		if err != nil {
+			t.Fatalf("GetWallets failed: %v", err)
+		}
+		if len(wallets) != 1 || wallets[0].FirstSeen != 1234 || wallets[0].Winrate != 85 {
+			t.Errorf("Expected rescan to keep first_seen 1234, got %+v", wallets[0])
+		}
+	})
+
+	t.Run("MinAge", func(t *testing.T) {
+		now := time.Now()
+		old := &WalletData{FirstSeen: now.AddDate(0, 0, -40).Unix()}
+		fresh := &WalletData{FirstSeen: now.AddDate(0, 0, -2).Unix()}
+		unknown := &WalletData{}
+
+		if !old.MeetsMinAge(30, now) || fresh.MeetsMinAge(30, now) || unknown.MeetsMinAge(30, now) {
+			t.Error("Expected only the 40-day-old wallet to pass a 30-day minimum")
+		}
+		if !unknown.MeetsMinAge(0, now) {
+			t.Error("Expected unknown age to pass when no minimum is set")
+		}
+		if days := int(old.Age(now).Hours() / 24); days != 40 {
+			t.Errorf("Expected age 40 days, got %d", days)
+		}
+
+		db.SaveWallet(&WalletData{Wallet: "OLD", Winrate: 90, ScannedAt: now.Unix(), FirstSeen: old.FirstSeen})
+		db.SaveWallet(&WalletData{Wallet: "NEW", Winrate: 90, ScannedAt: now.Unix(), FirstSeen: fresh.FirstSeen})
+		ranked, err := db.GetWalletsRanked(balancedWeights, WalletFilters{MinWinrate: 90, MinAgeDays: 30})
+		if err != nil {
+			t.Fatalf("GetWalletsRanked failed: %v", err)
+		}
+		if len(ranked) != 1 || ranked[0].Wallet != "OLD" {
+			t.Errorf("Expected only OLD to pass the age filter, got %d results", len(ranked))
+		}
+	})
+}
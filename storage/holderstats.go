@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// TokenHolderStats is the holder concentration of a token, as of the last
+// scan or preview that fetched its holders
+type TokenHolderStats struct {
+	Mint         string
+	TopHolderPct float64 // share of supply held by the top holders
+	HolderCount  int     // holders the concentration was computed from
+	UpdatedAt    int64
+}
+
+// GetTokenHolderStats returns a mint's last holder stats, or nil if its
+// holders haven't been fetched yet
+func (db *DB) GetTokenHolderStats(mint string) (*TokenHolderStats, error) {
+	s := TokenHolderStats{Mint: mint}
+	err := db.QueryRow(`SELECT top_holder_pct, holder_count, updated_at FROM token_holder_stats WHERE mint = ?`, mint).
+		Scan(&s.TopHolderPct, &s.HolderCount, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SaveTokenHolderStats records a mint's holder stats, stamped with the
+// current time
+func (db *DB) SaveTokenHolderStats(s *TokenHolderStats) error {
+	query := `
+		INSERT INTO token_holder_stats (mint, top_holder_pct, holder_count, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(mint) DO UPDATE SET top_holder_pct = excluded.top_holder_pct,
+			holder_count = excluded.holder_count, updated_at = excluded.updated_at
+	`
+	_, err := db.Exec(query, s.Mint, s.TopHolderPct, s.HolderCount, db.Now().Unix())
+	return err
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenHolderStats(t *testing.T) {
+	db := newTestDB(t)
+
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	db.SetClock(clock)
+
+	if s, err := db.GetTokenHolderStats("mint1"); err != nil || s != nil {
+		t.Fatalf("Expected no stats before saving, got %+v, %v", s, err)
+	}
+
+	if err := db.SaveTokenHolderStats(&TokenHolderStats{Mint: "mint1", TopHolderPct: 42.5, HolderCount: 100}); err != nil {
+		t.Fatalf("SaveTokenHolderStats failed: %v", err)
+	}
+	s, err := db.GetTokenHolderStats("mint1")
+	if err != nil || s == nil {
+		t.Fatalf("GetTokenHolderStats failed: %v", err)
+	}
+	if s.TopHolderPct != 42.5 || s.HolderCount != 100 || s.UpdatedAt != clock.now.Unix() {
+		t.Errorf("Unexpected stats %+v", s)
+	}
+
+	// A later scan replaces them
+	clock.Advance(time.Hour)
+	if err := db.SaveTokenHolderStats(&TokenHolderStats{Mint: "mint1", TopHolderPct: 61, HolderCount: 80}); err != nil {
+		t.Fatalf("SaveTokenHolderStats failed: %v", err)
+	}
+	s, _ = db.GetTokenHolderStats("mint1")
+	if s.TopHolderPct != 61 || s.HolderCount != 80 || s.UpdatedAt != clock.now.Unix() {
+		t.Errorf("Unexpected refreshed stats %+v", s)
+	}
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDBIntegration walks the main DB flows against a real SQLite file
+func TestDBIntegration(t *testing.T) {
+	db := newTestDB(t)
+
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	db.SetClock(clock)
+
+	const userID = 1001
+
+	t.Run("Users", func(t *testing.T) {
+		if user, err := db.GetUser(userID); err != nil || user != nil {
+			t.Fatalf("Expected no user before creation, got %+v, %v", user, err)
+		}
+		if err := db.CreateUser(userID); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		user, err := db.GetUser(userID)
+		if err != nil || user == nil {
+			t.Fatalf("GetUser failed: %v", err)
+		}
+		if user.Credits != 0 || user.PlanType != "" || user.JoinedAt != clock.now.Unix() {
+			t.Errorf("Unexpected new user %+v", user)
+		}
+		if err := db.CreateUser(userID); err == nil {
+			t.Error("Expected creating the same user twice to fail")
+		}
+	})
+
+	t.Run("Plans", func(t *testing.T) {
+		expiresAt := clock.now.Add(30 * 24 * time.Hour).Unix()
+		if err := db.SetUserPlan(userID, "monthly", 100, expiresAt); err != nil {
+			t.Fatalf("SetUserPlan failed: %v", err)
+		}
+		user, _ := db.GetUser(userID)
+		if user.PlanType != "monthly" || user.Credits != 100 || user.TrialExpiresAt != expiresAt {
+			t.Errorf("Plan not applied, got %+v", user)
+		}
+	})
+
+	t.Run("Credits", func(t *testing.T) {
+		if err := db.DecrementUserCredits(userID, 30); err != nil {
+			t.Fatalf("DecrementUserCredits failed: %v", err)
+		}
+		if user, _ := db.GetUser(userID); user.Credits != 70 {
+			t.Errorf("Expected 70 credits, got %d", user.Credits)
+		}
+
+		if err := db.DecrementUserCredits(userID, 71); err == nil {
+			t.Error("Expected insufficient credits error")
+		}
+		if user, _ := db.GetUser(userID); user.Credits != 70 {
+			t.Errorf("Failed decrement must not change credits, got %d", user.Credits)
+		}
+
+		if err := db.DecrementUserCredits(userID, 70); err != nil {
+			t.Fatalf("Spending the exact balance failed: %v", err)
+		}
+		if user, _ := db.GetUser(userID); user.Credits != 0 {
+			t.Errorf("Expected 0 credits, got %d", user.Credits)
+		}
+		if err := db.DecrementUserCredits(9999, 1); err == nil {
+			t.Error("Expected an error for an unknown user")
+		}
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		s, err := db.GetUserSettings(userID)
+		if err != nil {
+			t.Fatalf("GetUserSettings failed: %v", err)
+		}
+		if s.SlippageBps != 500 || s.JitoTipLamports != 10000 || s.PriorityFeeLamports != 5000 {
+			t.Errorf("Unexpected defaults %+v", s)
+		}
+
+		if err := db.UpdateSlippage(userID, 1000); err != nil {
+			t.Fatalf("UpdateSlippage failed: %v", err)
+		}
+		if err := db.UpdateJitoTip(userID, 50000); err != nil {
+			t.Fatalf("UpdateJitoTip failed: %v", err)
+		}
+		if err := db.UpdatePriorityFee(userID, 20000); err != nil {
+			t.Fatalf("UpdatePriorityFee failed: %v", err)
+		}
+		s, _ = db.GetUserSettings(userID)
+		if s.SlippageBps != 1000 || s.JitoTipLamports != 50000 || s.PriorityFeeLamports != 20000 {
+			t.Errorf("Updates not kept, got %+v", s)
+		}
+	})
+
+	t.Run("Wallets", func(t *testing.T) {
+		for _, w := range []struct{ addr, name string }{{"walletA", "main"}, {"walletB", "alt"}} {
+			if err := db.AddUserWallet(userID, w.addr, w.name); err != nil {
+				t.Fatalf("AddUserWallet failed: %v", err)
+			}
+			clock.Advance(time.Second)
+		}
+		if err := db.SetActiveWallet(userID, "walletA"); err != nil {
+			t.Fatalf("SetActiveWallet failed: %v", err)
+		}
+		active, err := db.GetActiveWallet(userID)
+		if err != nil || active == nil || active.WalletAddress != "walletA" {
+			t.Fatalf("Expected walletA active, got %+v, %v", active, err)
+		}
+
+		if err := db.SetActiveWallet(userID, "walletB"); err != nil {
+			t.Fatalf("SetActiveWallet failed: %v", err)
+		}
+		wallets, _ := db.GetUserWallets(userID)
+		activeCount := 0
+		for _, w := range wallets {
+			if w.IsActive {
+				activeCount++
+			}
+		}
+		if len(wallets) != 2 || activeCount != 1 {
+			t.Errorf("Expected 2 wallets with one active, got %d with %d active", len(wallets), activeCount)
+		}
+
+		promoted, err := db.RemoveUserWallet(userID, "walletB")
+		if err != nil {
+			t.Fatalf("RemoveUserWallet failed: %v", err)
+		}
+		if promoted == nil || promoted.WalletAddress != "walletA" {
+			t.Errorf("Expected walletA promoted, got %+v", promoted)
+		}
+		if wallets, _ := db.GetUserWallets(userID); len(wallets) != 1 {
+			t.Errorf("Expected 1 wallet left, got %d", len(wallets))
+		}
+	})
+
+	t.Run("CopyTargets", func(t *testing.T) {
+		if err := db.AddCopyTarget(userID, "target1", 0.5); err != nil {
+			t.Fatalf("AddCopyTarget failed: %v", err)
+		}
+		if err := db.AddCopyTarget(userID, "target2", 1); err != nil {
+			t.Fatalf("AddCopyTarget failed: %v", err)
+		}
+		if err := db.AddCopyTarget(2002, "target1", 2); err != nil {
+			t.Fatalf("AddCopyTarget for another user failed: %v", err)
+		}
+		if err := db.AddCopyTarget(userID, "target1", 3); err == nil {
+			t.Error("Expected a duplicate target to be rejected")
+		}
+
+		targets, err := db.GetCopyTargets(userID)
+		if err != nil {
+			t.Fatalf("GetCopyTargets failed: %v", err)
+		}
+		if len(targets) != 2 {
+			t.Fatalf("Expected 2 targets, got %d", len(targets))
+		}
+		for _, target := range targets {
+			if !target.IsActive || !target.CopyBuys || !target.CopySells {
+				t.Errorf("Unexpected target %+v", target)
+			}
+		}
+		if all, _ := db.GetAllActiveCopyTargets(); len(all) != 3 {
+			t.Errorf("Expected 3 active targets across users, got %d", len(all))
+		}
+
+		if err := db.RemoveCopyTarget(userID, "target1"); err != nil {
+			t.Fatalf("RemoveCopyTarget failed: %v", err)
+		}
+		targets, _ = db.GetCopyTargets(userID)
+		if len(targets) != 1 || targets[0].TargetWallet != "target2" {
+			t.Errorf("Expected only target2 left, got %v", targets)
+		}
+		if watchers, _ := db.GetUsersWatchingWallet("target1"); len(watchers) != 1 || watchers[0].UserID != 2002 {
+			t.Errorf("Removal must not touch other users' targets, got %v", watchers)
+		}
+	})
+
+	t.Run("Trades", func(t *testing.T) {
+		if err := db.SaveTrade(userID, "walletA", "sig1", "buy", "mint1", 1, 1000, 0.001, 0.0001, "pending"); err != nil {
+			t.Fatalf("SaveTrade failed: %v", err)
+		}
+		clock.Advance(time.Second)
+		if err := db.SaveTrade(userID, "walletA", "sig2", "sell", "mint1", 1.2, 1000, 0.0012, 0.0001, "pending"); err != nil {
+			t.Fatalf("SaveTrade failed: %v", err)
+		}
+		if err := db.SaveTrade(userID, "walletA", "sig1", "buy", "mint1", 1, 1000, 0.001, 0.0001, "pending"); err == nil {
+			t.Error("Expected a duplicate signature to be rejected")
+		}
+
+		if err := db.UpdateTradeStatus("sig1", "confirmed", clock.now.Unix()); err != nil {
+			t.Fatalf("UpdateTradeStatus failed: %v", err)
+		}
+		trades, err := db.GetRecentTrades(userID, 10)
+		if err != nil {
+			t.Fatalf("GetRecentTrades failed: %v", err)
+		}
+		if len(trades) != 2 || trades[0].TxSignature != "sig2" {
+			t.Fatalf("Expected 2 trades newest first, got %v", trades)
+		}
+		if trades[1].Status != "confirmed" || trades[1].ConfirmedAt != clock.now.Unix() {
+			t.Errorf("Status update not kept, got %+v", trades[1])
+		}
+		if trades[0].Status != "pending" {
+			t.Errorf("Other trades must stay pending, got %q", trades[0].Status)
+		}
+	})
+
+	t.Run("LimitOrderLifecycle", func(t *testing.T) {
+		orders := []*LimitOrder{
+			{UserID: userID, OrderPubkey: "orderExpired", TokenSymbol: "BONK", TokenMint: "mint1", Side: "buy", Price: 0.001, Amount: 1, Status: "OPEN", ExpiresAt: clock.now.Add(time.Hour).Unix()},
+			{UserID: userID, OrderPubkey: "orderLive", TokenSymbol: "BONK", TokenMint: "mint1", Side: "sell", Price: 0.002, Amount: 1, Status: "OPEN", ExpiresAt: clock.now.Add(48 * time.Hour).Unix()},
+		}
+		for _, o := range orders {
+			if err := db.SaveLimitOrder(o); err != nil {
+				t.Fatalf("SaveLimitOrder failed: %v", err)
+			}
+		}
+		if err := db.SaveLimitOrder(orders[0]); err == nil {
+			t.Error("Expected a duplicate order pubkey to be rejected")
+		}
+
+		if expired, _ := db.GetExpiredOrdersBatch(10); len(expired) != 0 {
+			t.Errorf("Expected no expired orders yet, got %d", len(expired))
+		}
+
+		clock.Advance(2 * time.Hour)
+		expired, err := db.GetExpiredOrdersBatch(10)
+		if err != nil {
+			t.Fatalf("GetExpiredOrdersBatch failed: %v", err)
+		}
+		if len(expired) != 1 || expired[0].OrderPubkey != "orderExpired" {
+			t.Fatalf("Expected only orderExpired, got %v", expired)
+		}
+
+		if err := db.UpdateOrderStatus(expired[0].ID, "EXPIRED_REFUNDED"); err != nil {
+			t.Fatalf("UpdateOrderStatus failed: %v", err)
+		}
+		if expired, _ := db.GetExpiredOrdersBatch(10); len(expired) != 0 {
+			t.Errorf("Refunded orders must leave the batch, got %d", len(expired))
+		}
+
+		open, err := db.GetOpenOrders(userID)
+		if err != nil || len(open) != 1 || open[0].OrderPubkey != "orderLive" {
+			t.Fatalf("Expected only orderLive open, got %v (%v)", open, err)
+		}
+		if o, _ := db.GetLimitOrder(2002, open[0].ID); o != nil {
+			t.Error("Expected another user's order to be hidden")
+		}
+
+		// A cancel that races a fill loses
+		if ok, err := db.CloseOpenOrder(open[0].ID, OrderFilled); err != nil || !ok {
+			t.Fatalf("CloseOpenOrder = %v, %v", ok, err)
+		}
+		if ok, _ := db.CloseOpenOrder(open[0].ID, OrderCancelled); ok {
+			t.Error("Expected a filled order not to be cancelled")
+		}
+		if o, _ := db.GetLimitOrder(userID, open[0].ID); o == nil || o.Status != OrderFilled {
+			t.Errorf("Expected the order filled, got %+v", o)
+		}
+		if open, _ := db.GetOpenOrders(userID); len(open) != 0 {
+			t.Errorf("Expected no open orders, got %v", open)
+		}
+	})
+
+	t.Run("Alerts", func(t *testing.T) {
+		if err := db.CreateAlert(userID, 60, 100); err != nil {
+			t.Fatalf("CreateAlert failed: %v", err)
+		}
+		if err := db.CreateAlert(2002, 80, 50); err != nil {
+			t.Fatalf("CreateAlert failed: %v", err)
+		}
+
+		if ids, _ := db.GetMatchingAlerts(70, 200); len(ids) != 1 || ids[0] != userID {
+			t.Errorf("Expected only user %d to match, got %v", userID, ids)
+		}
+		if ids, _ := db.GetMatchingAlerts(90, 200); len(ids) != 2 {
+			t.Errorf("Expected both users to match, got %v", ids)
+		}
+		if ids, _ := db.GetMatchingAlerts(50, 10); len(ids) != 0 {
+			t.Errorf("Expected no matches, got %v", ids)
+		}
+	})
+}
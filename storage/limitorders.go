@@ -0,0 +1,66 @@
+package storage
+
+import "database/sql"
+
+// Limit order statuses
+const (
+	OrderOpen      = "OPEN"
+	OrderFilled    = "FILLED"
+	OrderCancelled = "CANCELLED"
+	OrderReplaced  = "CANCELLED_REPLACED"
+
+	OrderExpiredRefunded = "EXPIRED_REFUNDED"
+)
+
+const limitOrderColumns = `id, user_id, order_pubkey, token_symbol, token_mint, side, price, amount, status, expires_at, target_mcap, initial_rent_sol, created_at`
+
+func scanLimitOrder(row interface{ Scan(...interface{}) error }) (*LimitOrder, error) {
+	var o LimitOrder
+	err := row.Scan(&o.ID, &o.UserID, &o.OrderPubkey, &o.TokenSymbol, &o.TokenMint, &o.Side, &o.Price, &o.Amount,
+		&o.Status, &o.ExpiresAt, &o.TargetMCAP, &o.InitialRentSOL, &o.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// GetOpenOrders returns the user's open limit orders, oldest first
+func (db *DB) GetOpenOrders(userID int64) ([]*LimitOrder, error) {
+	rows, err := db.Query(`SELECT `+limitOrderColumns+` FROM limit_orders WHERE user_id = ? AND status = ? ORDER BY created_at, id`,
+		userID, OrderOpen)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*LimitOrder
+	for rows.Next() {
+		o, err := scanLimitOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+// GetLimitOrder returns one of the user's limit orders, or nil if they
+// have none with that ID
+func (db *DB) GetLimitOrder(userID, id int64) (*LimitOrder, error) {
+	o, err := scanLimitOrder(db.QueryRow(`SELECT `+limitOrderColumns+` FROM limit_orders WHERE id = ? AND user_id = ?`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return o, err
+}
+
+// CloseOpenOrder moves an order out of OrderOpen into status, reporting
+// false when it had already left it, e.g. filled while being cancelled
+func (db *DB) CloseOpenOrder(id int64, status string) (bool, error) {
+	result, err := db.Exec(`UPDATE limit_orders SET status = ? WHERE id = ? AND status = ?`, status, id, OrderOpen)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
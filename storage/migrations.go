@@ -0,0 +1,449 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migration is a numbered, idempotent schema change
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations are applied in order; append new ones with the next version
+// number and never renumber or edit one that has shipped
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "add user_settings.copy_trade_auto_buy",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "user_settings", "copy_trade_auto_buy", "INTEGER DEFAULT 0")
+		},
+	},
+	{
+		version: 2,
+		name:    "add wallets.trade_count",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "wallets", "trade_count", "INTEGER DEFAULT 0")
+		},
+	},
+	{
+		version: 3,
+		name:    "create processed_payments",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS processed_payments (
+				signature TEXT PRIMARY KEY,
+				user_id INTEGER NOT NULL,
+				lamports INTEGER NOT NULL,
+				plan_id TEXT,
+				credits INTEGER NOT NULL,
+				processed_at INTEGER
+			)`)
+			return err
+		},
+	},
+	{
+		version: 4,
+		name:    "create copy target pnl tables",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS copy_target_positions (
+				user_id INTEGER NOT NULL,
+				target_wallet TEXT NOT NULL,
+				token_address TEXT NOT NULL,
+				cost_sol REAL NOT NULL,
+				updated_at INTEGER,
+				PRIMARY KEY (user_id, target_wallet, token_address)
+			)`)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS copy_target_results (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				target_wallet TEXT NOT NULL,
+				token_address TEXT NOT NULL,
+				pnl_sol REAL NOT NULL,
+				created_at INTEGER
+			)`)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_copy_results_target
+				ON copy_target_results(user_id, target_wallet, created_at)`)
+			return err
+		},
+	},
+	{
+		version: 5,
+		name:    "create trade_logs",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS trade_logs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				trade_id TEXT NOT NULL,
+				user_id INTEGER NOT NULL,
+				kind TEXT NOT NULL,
+				stage TEXT NOT NULL,
+				status TEXT NOT NULL,
+				signature TEXT,
+				signature_hash TEXT,
+				detail TEXT,
+				created_at INTEGER NOT NULL
+			)`)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_trade_logs_trade ON trade_logs(trade_id, created_at)`)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_trade_logs_signature ON trade_logs(signature_hash)`)
+			return err
+		},
+	},
+	{
+		version: 6,
+		name:    "add encrypted_wallets.kdf_version",
+		up: func(tx *sql.Tx) error {
+			// Wallets stored before versioning used PBKDF2 (crypto.KDFPBKDF2)
+			return addColumnIfMissing(tx, "encrypted_wallets", "kdf_version", "INTEGER NOT NULL DEFAULT 1")
+		},
+	},
+	{
+		version: 7,
+		name:    "normalize encrypted_wallets fields to base64",
+		up:      normalizeWalletEncoding,
+	},
+	{
+		version: 8,
+		name:    "create scan_cycles",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS scan_cycles (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				started_at INTEGER NOT NULL,
+				duration_ms INTEGER NOT NULL,
+				tokens_fetched INTEGER NOT NULL,
+				wallets_scanned INTEGER NOT NULL,
+				wallets_found INTEGER NOT NULL,
+				error TEXT
+			)`)
+			return err
+		},
+	},
+	{
+		version: 9,
+		name:    "add wallets.first_seen and wallet_first_seen cache",
+		up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "wallets", "first_seen", "INTEGER DEFAULT 0"); err != nil {
+				return err
+			}
+			// Kept apart from wallets, which is pruned every few hours
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS wallet_first_seen (
+				wallet TEXT PRIMARY KEY,
+				first_seen INTEGER NOT NULL
+			)`)
+			return err
+		},
+	},
+	{
+		version: 10,
+		name:    "add wallets.realized_pnl_usd",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "wallets", "realized_pnl_usd", "REAL DEFAULT 0")
+		},
+	},
+	{
+		version: 11,
+		name:    "add users.low_credit_notified_at",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "users", "low_credit_notified_at", "INTEGER DEFAULT 0")
+		},
+	},
+	{
+		version: 12,
+		name:    "add copy_trade_targets.copy_buys and copy_sells",
+		up: func(tx *sql.Tx) error {
+			// Existing targets keep copying both sides
+			if err := addColumnIfMissing(tx, "copy_trade_targets", "copy_buys", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "copy_trade_targets", "copy_sells", "INTEGER NOT NULL DEFAULT 1")
+		},
+	},
+	{
+		version: 13,
+		name:    "add copy_trade_targets.min_target_sol",
+		up: func(tx *sql.Tx) error {
+			// 0 copies every trade, as before
+			return addColumnIfMissing(tx, "copy_trade_targets", "min_target_sol", "REAL NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 14,
+		name:    "add notification preferences and notification_digest",
+		up: func(tx *sql.Tx) error {
+			// Existing users keep every notification, with no quiet hours
+			for _, col := range []struct{ name, definition string }{
+				{"notify_level", "TEXT NOT NULL DEFAULT 'all'"},
+				{"quiet_start_hour", "INTEGER NOT NULL DEFAULT -1"},
+				{"quiet_end_hour", "INTEGER NOT NULL DEFAULT -1"},
+			} {
+				if err := addColumnIfMissing(tx, "user_settings", col.name, col.definition); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS notification_digest (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				message TEXT NOT NULL,
+				created_at INTEGER NOT NULL
+			)`)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_notification_digest_user ON notification_digest(user_id, id)`)
+			return err
+		},
+	},
+	{
+		version: 15,
+		name:    "add user_settings timezone and digest schedule",
+		up: func(tx *sql.Tx) error {
+			for _, col := range []struct{ name, definition string }{
+				{"timezone", "TEXT NOT NULL DEFAULT 'UTC'"},
+				{"digest_hour", "INTEGER NOT NULL DEFAULT 9"},
+				{"last_digest_at", "INTEGER NOT NULL DEFAULT 0"},
+			} {
+				if err := addColumnIfMissing(tx, "user_settings", col.name, col.definition); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 16,
+		name:    "add token_metadata cache",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS token_metadata (
+				mint TEXT PRIMARY KEY,
+				symbol TEXT NOT NULL DEFAULT '',
+				name TEXT NOT NULL DEFAULT '',
+				decimals INTEGER NOT NULL DEFAULT -1,
+				updated_at INTEGER NOT NULL
+			)`)
+			return err
+		},
+	},
+	{
+		version: 17,
+		name:    "add below_threshold_wallets",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS below_threshold_wallets (
+				wallet TEXT PRIMARY KEY,
+				skip_until INTEGER NOT NULL
+			)`)
+			return err
+		},
+	},
+	{
+		version: 18,
+		name:    "add user_settings.execution_mode",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "user_settings", "execution_mode", "TEXT NOT NULL DEFAULT 'auto'")
+		},
+	},
+	{
+		version: 19,
+		name:    "add copy_trade_targets.paper and paper_trades",
+		up: func(tx *sql.Tx) error {
+			// Existing targets keep trading live
+			if err := addColumnIfMissing(tx, "copy_trade_targets", "paper", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS paper_trades (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				target_wallet TEXT NOT NULL,
+				token_address TEXT NOT NULL,
+				side TEXT NOT NULL,
+				sol_amount REAL NOT NULL,
+				token_amount INTEGER NOT NULL,
+				pnl_sol REAL NOT NULL DEFAULT 0,
+				signature TEXT,
+				created_at INTEGER
+			)`)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_paper_trades_position
+				ON paper_trades(user_id, target_wallet, token_address)`)
+			return err
+		},
+	},
+	{
+		version: 20,
+		name:    "add copy_trade_targets.last_activity_at",
+		up: func(tx *sql.Tx) error {
+			// 0 until the target is seen swapping; activity counts from
+			// created_at until then
+			return addColumnIfMissing(tx, "copy_trade_targets", "last_activity_at", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 21,
+		name:    "add copy target auto-sell and take_profit_triggers",
+		up: func(tx *sql.Tx) error {
+			// 0 leaves copied buys without a take-profit, as before
+			for _, col := range []string{"auto_sell_pct", "auto_sell_multiple"} {
+				if err := addColumnIfMissing(tx, "copy_trade_targets", col, "REAL NOT NULL DEFAULT 0"); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS take_profit_triggers (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				target_wallet TEXT NOT NULL,
+				token_address TEXT NOT NULL,
+				token_amount INTEGER NOT NULL,
+				entry_price_sol REAL NOT NULL,
+				trigger_price_sol REAL NOT NULL,
+				status TEXT NOT NULL DEFAULT 'pending',
+				created_at INTEGER
+			)`)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_take_profit_pending
+				ON take_profit_triggers(status, user_id)`)
+			return err
+		},
+	},
+	{
+		version: 22,
+		name:    "add user_settings.max_price_impact_pct",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "user_settings", "max_price_impact_pct", "REAL NOT NULL DEFAULT 10")
+		},
+	},
+	{
+		version: 23,
+		name:    "add user_settings.min_sol_reserve",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "user_settings", "min_sol_reserve", "REAL NOT NULL DEFAULT 0.01")
+		},
+	},
+	{
+		version: 24,
+		name:    "add user_settings.confirm_above_sol",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "user_settings", "confirm_above_sol", "REAL NOT NULL DEFAULT 1")
+		},
+	},
+	{
+		version: 25,
+		name:    "add token_holder_stats",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS token_holder_stats (
+				mint TEXT PRIMARY KEY,
+				top_holder_pct REAL NOT NULL,
+				holder_count INTEGER NOT NULL,
+				updated_at INTEGER
+			)`)
+			return err
+		},
+	},
+}
+
+// runMigrations applies every migration not yet recorded in schema_migrations
+func (db *DB) runMigrations() error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT,
+		applied_at INTEGER
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+		}
+
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			m.version, m.name, db.Now().Unix()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+		}
+
+		log.Printf("📦 Applied migration %d: %s", m.version, m.name)
+	}
+
+	return nil
+}
+
+// appliedMigrations returns the set of recorded migration versions
+func (db *DB) appliedMigrations() (map[int]bool, error) {
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// SchemaVersion returns the highest applied migration version
+func (db *DB) SchemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// addColumnIfMissing adds a column unless the table already has it, so a
+// migration is safe on both fresh and pre-existing databases
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	var count int
+	err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMigrations(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "migrate.db")
+
+	t.Run("FreshDatabase", func(t *testing.T) {
+		db, err := New(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		version, err := db.SchemaVersion()
+		if err != nil {
+			t.Fatalf("Failed to get schema version: %v", err)
+		}
+		if version != migrations[len(migrations)-1].version {
+			t.Errorf("Expected version %d, got %d", migrations[len(migrations)-1].version, version)
+		}
+	})
+
+	t.Run("ReopenIsIdempotent", func(t *testing.T) {
+		db, err := New(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to reopen database: %v", err)
+		}
+		defer db.Close()
+
+		var count int
+		db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count)
+		if count != len(migrations) {
+			t.Errorf("Expected %d recorded migrations, got %d", len(migrations), count)
+		}
+	})
+
+	t.Run("LegacyDatabase", func(t *testing.T) {
+		legacyPath := filepath.Join(t.TempDir(), "legacy.db")
+
+		// Simulate a database created before copy_trade_auto_buy existed
+		raw, err := sql.Open("sqlite3", legacyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = raw.Exec(`CREATE TABLE user_settings (
+			chat_id INTEGER PRIMARY KEY,
+			slippage_bps INTEGER DEFAULT 500,
+			max_slippage_bps INTEGER DEFAULT 5000,
+			jito_tip_lamports INTEGER DEFAULT 10000,
+			priority_fee_lamports INTEGER DEFAULT 5000,
+			auto_confirm INTEGER DEFAULT 0,
+			created_at INTEGER,
+			updated_at INTEGER
+		)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw.Close()
+
+		db, err := New(legacyPath)
+		if err != nil {
+			t.Fatalf("Failed to migrate legacy database: %v", err)
+		}
+		defer db.Close()
+
+		if err := db.UpdateCopyTradeAutoBuy(1, true); err != nil {
+			t.Fatalf("copy_trade_auto_buy column missing after migration: %v", err)
+		}
+		settings, err := db.GetUserSettings(1)
+		if err != nil || !settings.CopyTradeAutoBuy {
+			t.Errorf("Expected copy trade auto buy enabled, got %v (err: %v)", settings, err)
+		}
+	})
+}
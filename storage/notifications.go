@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	// Users pick timezones by name; don't depend on the host's zoneinfo
+	_ "time/tzdata"
+)
+
+// Notification verbosity levels
+const (
+	NotifyAll    = "all"    // every copied target swap as it happens
+	NotifyTrades = "trades" // only trades executed for the user
+	NotifyDigest = "digest" // swap notices batched into a daily digest
+)
+
+// QuietHoursOff is the quiet hour value when quiet hours are disabled
+const QuietHoursOff = -1
+
+// DefaultDigestHour is the local hour daily digests go out unless the
+// user picks another
+const DefaultDigestHour = 9
+
+// ValidNotifyLevel reports whether level is a known verbosity level
+func ValidNotifyLevel(level string) bool {
+	switch level {
+	case NotifyAll, NotifyTrades, NotifyDigest:
+		return true
+	}
+	return false
+}
+
+// QuietHoursSet reports whether the user has a quiet hours window
+func (s *UserSettings) QuietHoursSet() bool {
+	return s.QuietStartHour >= 0 && s.QuietEndHour >= 0 && s.QuietStartHour != s.QuietEndHour
+}
+
+// Location returns the user's timezone, UTC if unset or unknown
+func (s *UserSettings) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// InQuietHours reports whether t falls in the user's quiet hours. The
+// window is in the user's timezone and may wrap past midnight, e.g. 22
+// to 7.
+func (s *UserSettings) InQuietHours(t time.Time) bool {
+	if !s.QuietHoursSet() {
+		return false
+	}
+	h := t.In(s.Location()).Hour()
+	if s.QuietStartHour < s.QuietEndHour {
+		return h >= s.QuietStartHour && h < s.QuietEndHour
+	}
+	return h >= s.QuietStartHour || h < s.QuietEndHour
+}
+
+// UpdateNotifyLevel sets the user's notification verbosity
+func (db *DB) UpdateNotifyLevel(chatID int64, level string) error {
+	if !ValidNotifyLevel(level) {
+		return fmt.Errorf("unknown notification level %q", level)
+	}
+	query := `INSERT INTO user_settings (chat_id, notify_level, updated_at) VALUES (?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET notify_level = excluded.notify_level, updated_at = excluded.updated_at`
+	_, err := db.Exec(query, chatID, level, db.Now().Unix())
+	return err
+}
+
+// UpdateQuietHours sets the user's quiet hours as local hours, end
+// exclusive. Passing QuietHoursOff for both disables them.
+func (db *DB) UpdateQuietHours(chatID int64, start, end int) error {
+	off := start == QuietHoursOff && end == QuietHoursOff
+	if !off && (start < 0 || start > 23 || end < 0 || end > 23) {
+		return fmt.Errorf("quiet hours must be between 0 and 23, got %d-%d", start, end)
+	}
+	query := `INSERT INTO user_settings (chat_id, quiet_start_hour, quiet_end_hour, updated_at) VALUES (?, ?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET quiet_start_hour = excluded.quiet_start_hour, quiet_end_hour = excluded.quiet_end_hour, updated_at = excluded.updated_at`
+	_, err := db.Exec(query, chatID, start, end, db.Now().Unix())
+	return err
+}
+
+// LastDigestTime returns the most recent scheduled digest time at or
+// before now in the user's timezone
+func (s *UserSettings) LastDigestTime(now time.Time) time.Time {
+	local := now.In(s.Location())
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), s.DigestHour, 0, 0, 0, local.Location())
+	if scheduled.After(local) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	return scheduled
+}
+
+// DigestDue reports whether the user's daily digest should be sent at now
+func (s *UserSettings) DigestDue(now time.Time) bool {
+	return s.LastDigestAt < s.LastDigestTime(now).Unix()
+}
+
+// UpdateTimezone sets the user's timezone by IANA name, e.g.
+// "Europe/Berlin"
+func (db *DB) UpdateTimezone(chatID int64, timezone string) error {
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+		return fmt.Errorf("unknown timezone %q", timezone)
+	}
+	query := `INSERT INTO user_settings (chat_id, timezone, updated_at) VALUES (?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET timezone = excluded.timezone, updated_at = excluded.updated_at`
+	_, err := db.Exec(query, chatID, timezone, db.Now().Unix())
+	return err
+}
+
+// UpdateDigestHour sets the local hour the user's daily digest is sent
+func (db *DB) UpdateDigestHour(chatID int64, hour int) error {
+	if hour < 0 || hour > 23 {
+		return fmt.Errorf("digest hour must be between 0 and 23, got %d", hour)
+	}
+	query := `INSERT INTO user_settings (chat_id, digest_hour, updated_at) VALUES (?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET digest_hour = excluded.digest_hour, updated_at = excluded.updated_at`
+	_, err := db.Exec(query, chatID, hour, db.Now().Unix())
+	return err
+}
+
+// MarkDigestSent records when the user's daily digest went out
+func (db *DB) MarkDigestSent(chatID int64, at time.Time) error {
+	_, err := db.Exec(`UPDATE user_settings SET last_digest_at = ? WHERE chat_id = ?`, at.Unix(), chatID)
+	return err
+}
+
+// DigestUsers returns the users who get a daily digest
+func (db *DB) DigestUsers() ([]int64, error) {
+	rows, err := db.Query(`SELECT chat_id FROM user_settings WHERE notify_level = ? ORDER BY chat_id`, NotifyDigest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+	return users, rows.Err()
+}
+
+// DigestBacklog summarizes the notifications queued for one user
+type DigestBacklog struct {
+	UserID int64
+	Count  int
+	Oldest int64 // unix time the oldest queued notification was held
+}
+
+// QueueDigest holds a notification for later batched delivery
+func (db *DB) QueueDigest(userID int64, message string) error {
+	_, err := db.Exec(`INSERT INTO notification_digest (user_id, message, created_at) VALUES (?, ?, ?)`,
+		userID, message, db.Now().Unix())
+	return err
+}
+
+// DigestBacklogs lists every user with queued notifications
+func (db *DB) DigestBacklogs() ([]DigestBacklog, error) {
+	rows, err := db.Query(`SELECT user_id, COUNT(*), MIN(created_at) FROM notification_digest GROUP BY user_id ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backlogs []DigestBacklog
+	for rows.Next() {
+		var b DigestBacklog
+		if err := rows.Scan(&b.UserID, &b.Count, &b.Oldest); err != nil {
+			return nil, err
+		}
+		backlogs = append(backlogs, b)
+	}
+	return backlogs, rows.Err()
+}
+
+// TakeDigest removes and returns the user's queued notifications, oldest
+// first
+func (db *DB) TakeDigest(userID int64) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, message FROM notification_digest WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	var lastID int64
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&lastID, &message); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	// Only what was read; anything queued meanwhile waits for the next run
+	if _, err := tx.Exec(`DELETE FROM notification_digest WHERE user_id = ? AND id <= ?`, userID, lastID); err != nil {
+		return nil, err
+	}
+	return messages, tx.Commit()
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationPreferences(t *testing.T) {
+	db := newTestDB(t)
+
+	t.Run("Defaults", func(t *testing.T) {
+		s, _ := db.GetUserSettings(42)
+		if s.NotifyLevel != NotifyAll || s.QuietHoursSet() {
+			t.Errorf("Expected every notification and no quiet hours, got %+v", s)
+		}
+		// A settings row created by another update keeps the defaults
+		db.UpdateSlippage(42, 100)
+		if s, _ := db.GetUserSettings(42); s.NotifyLevel != NotifyAll || s.QuietHoursSet() {
+			t.Errorf("Expected defaults on an existing row, got %+v", s)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		if err := db.UpdateNotifyLevel(42, NotifyDigest); err != nil {
+			t.Fatalf("UpdateNotifyLevel failed: %v", err)
+		}
+		if err := db.UpdateQuietHours(42, 22, 7); err != nil {
+			t.Fatalf("UpdateQuietHours failed: %v", err)
+		}
+		s, _ := db.GetUserSettings(42)
+		if s.NotifyLevel != NotifyDigest || s.QuietStartHour != 22 || s.QuietEndHour != 7 || s.SlippageBps != 100 {
+			t.Errorf("Unexpected settings %+v", s)
+		}
+
+		if err := db.UpdateNotifyLevel(42, "loud"); err == nil {
+			t.Error("Expected an unknown level to be rejected")
+		}
+		if err := db.UpdateQuietHours(42, 22, 24); err == nil {
+			t.Error("Expected an out of range hour to be rejected")
+		}
+		if err := db.UpdateQuietHours(42, QuietHoursOff, QuietHoursOff); err != nil {
+			t.Fatalf("Disabling quiet hours failed: %v", err)
+		}
+		if s, _ := db.GetUserSettings(42); s.QuietHoursSet() {
+			t.Errorf("Expected quiet hours off, got %d-%d", s.QuietStartHour, s.QuietEndHour)
+		}
+	})
+
+	t.Run("InQuietHours", func(t *testing.T) {
+		at := func(h int) time.Time { return time.Date(2024, 1, 1, h, 30, 0, 0, time.UTC) }
+		overnight := &UserSettings{QuietStartHour: 22, QuietEndHour: 7}
+		daytime := &UserSettings{QuietStartHour: 9, QuietEndHour: 17}
+		tests := []struct {
+			s    *UserSettings
+			hour int
+			want bool
+		}{
+			{overnight, 23, true},
+			{overnight, 3, true},
+			{overnight, 7, false},
+			{overnight, 12, false},
+			{daytime, 9, true},
+			{daytime, 17, false},
+			{daytime, 8, false},
+			{&UserSettings{QuietStartHour: QuietHoursOff, QuietEndHour: QuietHoursOff}, 3, false},
+		}
+		for _, tt := range tests {
+			if got := tt.s.InQuietHours(at(tt.hour)); got != tt.want {
+				t.Errorf("Quiet %d-%d at %d:30 = %v, want %v", tt.s.QuietStartHour, tt.s.QuietEndHour, tt.hour, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestNotificationDigest(t *testing.T) {
+	db := newTestDB(t)
+
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	db.SetClock(clock)
+
+	for _, msg := range []string{"first", "second"} {
+		if err := db.QueueDigest(1, msg); err != nil {
+			t.Fatalf("QueueDigest failed: %v", err)
+		}
+		clock.Advance(time.Minute)
+	}
+	db.QueueDigest(2, "other")
+
+	backlogs, err := db.DigestBacklogs()
+	if err != nil {
+		t.Fatalf("DigestBacklogs failed: %v", err)
+	}
+	if len(backlogs) != 2 || backlogs[0].UserID != 1 || backlogs[0].Count != 2 || backlogs[0].Oldest != 1700000000 {
+		t.Fatalf("Unexpected backlogs %+v", backlogs)
+	}
+
+	messages, err := db.TakeDigest(1)
+	if err != nil {
+		t.Fatalf("TakeDigest failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0] != "first" || messages[1] != "second" {
+		t.Errorf("Expected queued messages oldest first, got %v", messages)
+	}
+	if messages, _ := db.TakeDigest(1); len(messages) != 0 {
+		t.Errorf("Expected the digest emptied, got %v", messages)
+	}
+	if backlogs, _ := db.DigestBacklogs(); len(backlogs) != 1 || backlogs[0].UserID != 2 {
+		t.Errorf("Other users' digests must be kept, got %+v", backlogs)
+	}
+}
+
+func TestDigestSchedule(t *testing.T) {
+	db := newTestDB(t)
+
+	t.Run("Settings", func(t *testing.T) {
+		s, _ := db.GetUserSettings(42)
+		if s.Timezone != "UTC" || s.DigestHour != DefaultDigestHour || s.LastDigestAt != 0 {
+			t.Errorf("Unexpected digest defaults %+v", s)
+		}
+		if err := db.UpdateTimezone(42, "Asia/Tokyo"); err != nil {
+			t.Fatalf("UpdateTimezone failed: %v", err)
+		}
+		if err := db.UpdateDigestHour(42, 20); err != nil {
+			t.Fatalf("UpdateDigestHour failed: %v", err)
+		}
+		if err := db.UpdateTimezone(42, "Mars/Olympus"); err == nil {
+			t.Error("Expected an unknown timezone to be rejected")
+		}
+		if err := db.UpdateDigestHour(42, 24); err == nil {
+			t.Error("Expected an out of range hour to be rejected")
+		}
+		s, _ = db.GetUserSettings(42)
+		if s.Timezone != "Asia/Tokyo" || s.DigestHour != 20 {
+			t.Errorf("Updates not kept, got %+v", s)
+		}
+	})
+
+	t.Run("DigestDue", func(t *testing.T) {
+		// 20:00 in Tokyo is 11:00 UTC
+		s := &UserSettings{Timezone: "Asia/Tokyo", DigestHour: 20}
+		before := time.Date(2024, 1, 1, 10, 59, 0, 0, time.UTC)
+		after := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+
+		s.LastDigestAt = s.LastDigestTime(before).Unix()
+		if s.DigestDue(before) {
+			t.Error("Digest must not be due before the scheduled hour")
+		}
+		if !s.DigestDue(after) {
+			t.Error("Expected the digest due at the scheduled hour")
+		}
+		s.LastDigestAt = after.Unix()
+		if s.DigestDue(after.Add(23 * time.Hour)) {
+			t.Error("Expected one digest per day")
+		}
+		if !s.DigestDue(after.Add(24 * time.Hour)) {
+			t.Error("Expected the next day's digest due")
+		}
+	})
+
+	t.Run("DigestUsers", func(t *testing.T) {
+		db.UpdateNotifyLevel(42, NotifyDigest)
+		db.UpdateNotifyLevel(43, NotifyTrades)
+		db.UpdateNotifyLevel(44, NotifyDigest)
+		users, err := db.DigestUsers()
+		if err != nil {
+			t.Fatalf("DigestUsers failed: %v", err)
+		}
+		if len(users) != 2 || users[0] != 42 || users[1] != 44 {
+			t.Errorf("Expected users 42 and 44, got %v", users)
+		}
+
+		at := time.Unix(1700000000, 0)
+		if err := db.MarkDigestSent(42, at); err != nil {
+			t.Fatalf("MarkDigestSent failed: %v", err)
+		}
+		if s, _ := db.GetUserSettings(42); s.LastDigestAt != at.Unix() {
+			t.Errorf("Expected last digest %d, got %d", at.Unix(), s.LastDigestAt)
+		}
+	})
+
+	t.Run("CopyActivity", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(1700000000, 0)}
+		db.SetClock(clock)
+
+		db.SaveTrade(42, "w", "old", "buy", "mint1", 5, 0, 0, 0, "confirmed")
+		db.RecordCopyBuy(42, "target1", "mint0", 1)
+		db.RecordCopySell(42, "target1", "mint0", 9)
+		clock.Advance(time.Hour)
+		since := clock.now.Unix()
+
+		db.SaveTrade(42, "w", "sig1", "buy", "mint1", 0.5, 0, 0, 0, "confirmed")
+		db.SaveTrade(42, "w", "sig2", "buy", "mint2", 0.25, 0, 0, 0, "pending")
+		db.SaveTrade(42, "w", "sig3", "sell", "mint1", 0.8, 0, 0, 0, "confirmed")
+		db.SaveTrade(42, "w", "sig4", "buy", "mint3", 1, 0, 0, 0, "failed")
+		db.SaveTrade(43, "w", "sig5", "buy", "mint1", 1, 0, 0, 0, "confirmed")
+		db.RecordCopyBuy(42, "target1", "mint1", 0.5)
+		db.RecordCopyBuy(42, "target2", "mint2", 0.25)
+		db.RecordCopySell(42, "target1", "mint1", 0.8)
+		db.RecordCopySell(42, "target2", "mint2", 0.15)
+
+		a, err := db.GetCopyActivity(42, since)
+		if err != nil {
+			t.Fatalf("GetCopyActivity failed: %v", err)
+		}
+		if a.Buys != 2 || a.Sells != 1 || a.Failed != 1 {
+			t.Errorf("Expected 2 buys, 1 sell, 1 failed, got %+v", a)
+		}
+		if a.SOLSpent != 0.75 || a.SOLReceived != 0.8 {
+			t.Errorf("Expected 0.75 spent and 0.8 received, got %+v", a)
+		}
+		if a.Closed != 2 || a.PnLSOL < 0.199 || a.PnLSOL > 0.201 {
+			t.Errorf("Expected 2 closed for 0.2 SOL, got %+v", a)
+		}
+	})
+}
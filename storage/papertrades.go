@@ -0,0 +1,144 @@
+package storage
+
+import "database/sql"
+
+// Paper trade sides
+const (
+	PaperBuy  = "buy"
+	PaperSell = "sell"
+)
+
+// PaperTrade is a copy trade simulated for a target in paper mode. Amounts
+// are what the user would have spent or received at the target's price.
+type PaperTrade struct {
+	ID           int64   `json:"id"`
+	UserID       int64   `json:"user_id"`
+	TargetWallet string  `json:"target_wallet"`
+	TokenAddress string  `json:"token_address"`
+	Side         string  `json:"side"`
+	SOLAmount    float64 `json:"sol_amount"`
+	TokenAmount  uint64  `json:"token_amount"` // raw units
+	PnLSOL       float64 `json:"pnl_sol"`      // realized by a sell, 0 for buys
+	Signature    string  `json:"signature"`    // the target's transaction
+	CreatedAt    int64   `json:"created_at"`
+}
+
+// PaperTargetReport is the simulated performance of one paper target
+type PaperTargetReport struct {
+	TargetWallet   string  `json:"target_wallet"`
+	Buys           int     `json:"buys"`
+	Sells          int     `json:"sells"`
+	Wins           int     `json:"wins"`
+	Losses         int     `json:"losses"`
+	SOLSpent       float64 `json:"sol_spent"`
+	RealizedPnLSOL float64 `json:"realized_pnl_sol"`
+	OpenCostSOL    float64 `json:"open_cost_sol"` // spent on positions not yet sold
+}
+
+// paperPositionFilter matches the buys of a token since its last paper
+// sell, which closes the whole position like a copied sell
+const paperPositionFilter = `side = 'buy' AND id > COALESCE((
+	SELECT MAX(s.id) FROM paper_trades s
+	WHERE s.user_id = paper_trades.user_id AND s.target_wallet = paper_trades.target_wallet
+	AND s.token_address = paper_trades.token_address AND s.side = 'sell'), 0)`
+
+// RecordPaperBuy records a simulated buy of tokens raw units of tokenAddr
+// for solSpent, copying targetWallet's transaction signature
+func (db *DB) RecordPaperBuy(userID int64, targetWallet, tokenAddr, signature string, solSpent float64, tokens uint64) error {
+	query := `INSERT INTO paper_trades (user_id, target_wallet, token_address, side, sol_amount, token_amount, pnl_sol, signature, created_at)
+			  VALUES (?, ?, ?, 'buy', ?, ?, 0, ?, ?)`
+	_, err := db.Exec(query, userID, targetWallet, tokenAddr, solSpent, tokens, signature, db.Now().Unix())
+	return err
+}
+
+// RecordPaperSell closes the user's paper position in tokenAddr at
+// solPerUnit SOL per raw unit and returns the recorded sale. It returns
+// ErrNoCopyPosition when no paper buy is open.
+func (db *DB) RecordPaperSell(userID int64, targetWallet, tokenAddr, signature string, solPerUnit float64) (*PaperTrade, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var tokens uint64
+	var cost float64
+	err = tx.QueryRow(`SELECT COALESCE(SUM(token_amount), 0), COALESCE(SUM(sol_amount), 0) FROM paper_trades
+		WHERE user_id = ? AND target_wallet = ? AND token_address = ? AND `+paperPositionFilter,
+		userID, targetWallet, tokenAddr).Scan(&tokens, &cost)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == 0 {
+		return nil, ErrNoCopyPosition
+	}
+
+	sale := &PaperTrade{
+		UserID:       userID,
+		TargetWallet: targetWallet,
+		TokenAddress: tokenAddr,
+		Side:         PaperSell,
+		SOLAmount:    float64(tokens) * solPerUnit,
+		TokenAmount:  tokens,
+		Signature:    signature,
+		CreatedAt:    db.Now().Unix(),
+	}
+	sale.PnLSOL = sale.SOLAmount - cost
+	result, err := tx.Exec(`INSERT INTO paper_trades (user_id, target_wallet, token_address, side, sol_amount, token_amount, pnl_sol, signature, created_at)
+		VALUES (?, ?, ?, 'sell', ?, ?, ?, ?, ?)`,
+		userID, targetWallet, tokenAddr, sale.SOLAmount, sale.TokenAmount, sale.PnLSOL, signature, sale.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if sale.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+	return sale, tx.Commit()
+}
+
+// GetPaperTradeReport returns the simulated performance of every target
+// the user has paper traded, best performer first
+func (db *DB) GetPaperTradeReport(userID int64) ([]*PaperTargetReport, error) {
+	query := `
+		SELECT target_wallet,
+			SUM(CASE WHEN side = 'buy' THEN 1 ELSE 0 END),
+			SUM(CASE WHEN side = 'sell' THEN 1 ELSE 0 END),
+			SUM(CASE WHEN side = 'sell' AND pnl_sol > 0 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN side = 'sell' AND pnl_sol < 0 THEN 1 ELSE 0 END),
+			COALESCE(SUM(CASE WHEN side = 'buy' THEN sol_amount ELSE 0 END), 0),
+			COALESCE(SUM(pnl_sol), 0),
+			COALESCE(SUM(CASE WHEN ` + paperPositionFilter + ` THEN sol_amount ELSE 0 END), 0)
+		FROM paper_trades
+		WHERE user_id = ?
+		GROUP BY target_wallet
+		ORDER BY 7 DESC
+	`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []*PaperTargetReport
+	for rows.Next() {
+		var r PaperTargetReport
+		if err := rows.Scan(&r.TargetWallet, &r.Buys, &r.Sells, &r.Wins, &r.Losses, &r.SOLSpent, &r.RealizedPnLSOL, &r.OpenCostSOL); err != nil {
+			return nil, err
+		}
+		reports = append(reports, &r)
+	}
+	return reports, rows.Err()
+}
+
+// SetCopyTargetPaper switches a target between paper and live copying
+func (db *DB) SetCopyTargetPaper(userID int64, targetWallet string, paper bool) error {
+	result, err := db.Exec(`UPDATE copy_trade_targets SET paper = ? WHERE user_id = ? AND target_wallet = ?`,
+		paper, userID, targetWallet)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
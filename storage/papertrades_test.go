@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPaperTrades(t *testing.T) {
+	db := newTestDB(t)
+
+	const user = int64(7)
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	t.Run("TargetFlag", func(t *testing.T) {
+		if err := db.AddCopyTarget(user, "paperWallet", 0.5); err != nil {
+			t.Fatalf("AddCopyTarget failed: %v", err)
+		}
+		target, _ := db.GetCopyTarget(user, "paperWallet")
+		if target == nil || target.Paper {
+			t.Fatalf("Expected a new target to trade live, got %+v", target)
+		}
+		if err := db.SetCopyTargetPaper(user, "paperWallet", true); err != nil {
+			t.Fatalf("SetCopyTargetPaper failed: %v", err)
+		}
+		if target, _ := db.GetCopyTarget(user, "paperWallet"); target == nil || !target.Paper {
+			t.Errorf("Expected the target in paper mode, got %+v", target)
+		}
+		if err := db.SetCopyTargetPaper(user, "unknownWallet", true); err == nil {
+			t.Error("Expected an error for a wallet the user doesn't copy")
+		}
+	})
+
+	t.Run("SellClosesPosition", func(t *testing.T) {
+		if _, err := db.RecordPaperSell(user, "paperWallet", "mintA", "sig0", 1e-6); !errors.Is(err, ErrNoCopyPosition) {
+			t.Errorf("Expected ErrNoCopyPosition without a paper buy, got %v", err)
+		}
+
+		db.RecordPaperBuy(user, "paperWallet", "mintA", "sig1", 0.5, 100_000)
+		db.RecordPaperBuy(user, "paperWallet", "mintA", "sig2", 0.5, 50_000)
+		// 150k units at 10 lamports each
+		sale, err := db.RecordPaperSell(user, "paperWallet", "mintA", "sig3", 1e-5)
+		if err != nil {
+			t.Fatalf("RecordPaperSell failed: %v", err)
+		}
+		if sale.TokenAmount != 150_000 || !near(sale.SOLAmount, 1.5) || !near(sale.PnLSOL, 0.5) {
+			t.Errorf("Expected 150000 units sold for 1.5 SOL and +0.5 PnL, got %+v", sale)
+		}
+		if _, err := db.RecordPaperSell(user, "paperWallet", "mintA", "sig4", 1e-5); !errors.Is(err, ErrNoCopyPosition) {
+			t.Errorf("Expected the sell to close the position, got %v", err)
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		// A losing round trip and an open position on mintB
+		db.RecordPaperBuy(user, "paperWallet", "mintA", "sig5", 1, 100_000)
+		db.RecordPaperSell(user, "paperWallet", "mintA", "sig6", 4e-6)
+		db.RecordPaperBuy(user, "paperWallet", "mintB", "sig7", 0.25, 10)
+		db.RecordPaperBuy(user, "otherWallet", "mintA", "sig8", 0.1, 10)
+		db.RecordPaperBuy(user+1, "paperWallet", "mintA", "sig9", 3, 10)
+
+		reports, err := db.GetPaperTradeReport(user)
+		if err != nil {
+			t.Fatalf("GetPaperTradeReport failed: %v", err)
+		}
+		if len(reports) != 2 || reports[0].TargetWallet != "otherWallet" {
+			t.Fatalf("Expected two targets with the breakeven one first, got %+v", reports)
+		}
+		r := reports[1]
+		if r.Buys != 4 || r.Sells != 2 || r.Wins != 1 || r.Losses != 1 {
+			t.Errorf("Unexpected counts %+v", r)
+		}
+		if !near(r.SOLSpent, 2.25) || !near(r.RealizedPnLSOL, -0.1) || !near(r.OpenCostSOL, 0.25) {
+			t.Errorf("Expected 2.25 SOL spent, -0.1 realized and 0.25 open, got %+v", r)
+		}
+	})
+}
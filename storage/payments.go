@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPaymentAlreadyProcessed is returned when a payment signature has
+// already been credited
+var ErrPaymentAlreadyProcessed = errors.New("payment already processed")
+
+// Payment is a SOL transfer that was credited to a user
+type Payment struct {
+	Signature   string `json:"signature"`
+	UserID      int64  `json:"user_id"`
+	Lamports    uint64 `json:"lamports"`
+	PlanID      string `json:"plan_id"`
+	Credits     int    `json:"credits"`
+	ProcessedAt int64  `json:"processed_at"`
+}
+
+// IsPaymentProcessed reports whether a payment signature has been credited
+func (db *DB) IsPaymentProcessed(signature string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM processed_payments WHERE signature = ?", signature).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreditPayment records a payment signature and adds its credits to the user
+// in one transaction, so a signature can never be credited twice
+func (db *DB) CreditPayment(p *Payment) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var existing int
+	err = tx.QueryRow("SELECT COUNT(*) FROM processed_payments WHERE signature = ?", p.Signature).Scan(&existing)
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		return ErrPaymentAlreadyProcessed
+	}
+
+	if p.ProcessedAt == 0 {
+		p.ProcessedAt = db.Now().Unix()
+	}
+	_, err = tx.Exec(`INSERT INTO processed_payments (signature, user_id, lamports, plan_id, credits, processed_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		p.Signature, p.UserID, p.Lamports, p.PlanID, p.Credits, p.ProcessedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record payment: %w", err)
+	}
+
+	result, err := tx.Exec(`UPDATE users SET credits = credits + ? WHERE user_id = ?`, p.Credits, p.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to add credits: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return fmt.Errorf("user %d not found", p.UserID)
+	}
+
+	return tx.Commit()
+}
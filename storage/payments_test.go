@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreditPayment(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreateUser(42); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	payment := &Payment{Signature: "sig1", UserID: 42, Lamports: 100_000_000, PlanID: "pack_100", Credits: 100}
+
+	t.Run("CreditsUser", func(t *testing.T) {
+		if err := db.CreditPayment(payment); err != nil {
+			t.Fatalf("CreditPayment failed: %v", err)
+		}
+
+		user, _ := db.GetUser(42)
+		if user == nil || user.Credits != 100 {
+			t.Fatalf("Expected 100 credits, got %+v", user)
+		}
+		if processed, _ := db.IsPaymentProcessed("sig1"); !processed {
+			t.Error("Expected signature to be marked processed")
+		}
+	})
+
+	t.Run("RejectsReplay", func(t *testing.T) {
+		replay := *payment
+		if err := db.CreditPayment(&replay); !errors.Is(err, ErrPaymentAlreadyProcessed) {
+			t.Fatalf("Expected ErrPaymentAlreadyProcessed, got %v", err)
+		}
+
+		user, _ := db.GetUser(42)
+		if user.Credits != 100 {
+			t.Errorf("Replay must not add credits, got %d", user.Credits)
+		}
+	})
+
+	t.Run("UnknownUser", func(t *testing.T) {
+		err := db.CreditPayment(&Payment{Signature: "sig2", UserID: 7, Lamports: 1, Credits: 10})
+		if err == nil {
+			t.Fatal("Expected error for unknown user")
+		}
+		if processed, _ := db.IsPaymentProcessed("sig2"); processed {
+			t.Error("Failed payment must not be recorded")
+		}
+	})
+}
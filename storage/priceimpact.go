@@ -0,0 +1,26 @@
+package storage
+
+import "fmt"
+
+// DefaultMaxPriceImpactPct is the price impact above which trades need
+// reconfirming unless the user picks another limit
+const DefaultMaxPriceImpactPct = 10
+
+// PriceImpactExceeded reports whether a quote moving the price by
+// impactPct percent is over the user's limit. A limit of 0 allows any
+// impact.
+func (s *UserSettings) PriceImpactExceeded(impactPct float64) bool {
+	return s.MaxPriceImpactPct > 0 && impactPct > s.MaxPriceImpactPct
+}
+
+// UpdateMaxPriceImpact sets the price impact percentage above which the
+// user's trades need reconfirming, 0 for no limit
+func (db *DB) UpdateMaxPriceImpact(chatID int64, pct float64) error {
+	if pct < 0 || pct > 100 {
+		return fmt.Errorf("max price impact must be between 0 and 100, got %g", pct)
+	}
+	query := `INSERT INTO user_settings (chat_id, max_price_impact_pct, updated_at) VALUES (?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET max_price_impact_pct = excluded.max_price_impact_pct, updated_at = excluded.updated_at`
+	_, err := db.Exec(query, chatID, pct, db.Now().Unix())
+	return err
+}
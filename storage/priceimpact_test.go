@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestMaxPriceImpact(t *testing.T) {
+	db := newTestDB(t)
+
+	t.Run("Update", func(t *testing.T) {
+		if s, _ := db.GetUserSettings(42); s.MaxPriceImpactPct != DefaultMaxPriceImpactPct {
+			t.Errorf("Expected the default limit, got %g", s.MaxPriceImpactPct)
+		}
+		db.UpdateSlippage(42, 100)
+		if s, _ := db.GetUserSettings(42); s.MaxPriceImpactPct != DefaultMaxPriceImpactPct {
+			t.Errorf("Expected the default limit on an existing row, got %g", s.MaxPriceImpactPct)
+		}
+
+		if err := db.UpdateMaxPriceImpact(42, 3); err != nil {
+			t.Fatalf("UpdateMaxPriceImpact failed: %v", err)
+		}
+		if s, _ := db.GetUserSettings(42); s.MaxPriceImpactPct != 3 || s.SlippageBps != 100 {
+			t.Errorf("Unexpected settings %+v", s)
+		}
+		for _, pct := range []float64{-1, 101} {
+			if err := db.UpdateMaxPriceImpact(42, pct); err == nil {
+				t.Errorf("Expected %g%% to be rejected", pct)
+			}
+		}
+	})
+
+	t.Run("Exceeded", func(t *testing.T) {
+		tests := []struct {
+			limit, impact float64
+			want          bool
+		}{
+			{5, 4.99, false},
+			{5, 5, false},
+			{5, 5.01, true},
+			{5, 80, true},
+			{0, 80, false}, // no limit
+		}
+		for _, tt := range tests {
+			s := &UserSettings{MaxPriceImpactPct: tt.limit}
+			if got := s.PriceImpactExceeded(tt.impact); got != tt.want {
+				t.Errorf("%g%% impact against a %g%% limit: expected %v, got %v", tt.impact, tt.limit, tt.want, got)
+			}
+		}
+	})
+}
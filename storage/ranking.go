@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// ScoreWeights controls how much each metric contributes to a wallet's score
+type ScoreWeights struct {
+	Winrate    float64 `json:"winrate"`
+	PnL        float64 `json:"pnl"`
+	TradeCount float64 `json:"trade_count"`
+	Recency    float64 `json:"recency"`
+}
+
+// WalletFilters narrows the wallets considered for ranking
+type WalletFilters struct {
+	MinWinrate float64
+	MinPnL     *float64 // percent; nil leaves losing wallets in
+	MinPnLUSD  float64
+	MinTrades  int
+	MinAgeDays int // wallets with an unknown first transaction are excluded when set
+	Limit      int
+}
+
+// RankedWallet is a wallet with its composite score
+type RankedWallet struct {
+	*WalletData
+	Score float64 `json:"score"`
+}
+
+const (
+	// pnlHalfPoint is the PnL % that scores half of the PnL component
+	pnlHalfPoint = 100.0
+	// tradesHalfPoint is the trade count that scores half of the activity component
+	tradesHalfPoint = 20.0
+	// recencyWindow is how long a scan stays "fresh" for the recency component
+	recencyWindow = 5 * time.Hour
+)
+
+// ScoreWallet computes a 0-100 composite score for a wallet.
+// Each metric is normalized to [0,1] (PnL to [-1,1]) so a single
+// outlier can't dominate the ranking.
+func ScoreWallet(w *WalletData, weights ScoreWeights, now time.Time) float64 {
+	total := weights.Winrate + weights.PnL + weights.TradeCount + weights.Recency
+	if total <= 0 {
+		return 0
+	}
+
+	winrate := clamp(w.Winrate/100, 0, 1)
+
+	// Saturating curve: 100% PnL scores 0.5, 300% scores 0.75
+	pnl := w.RealizedPnLPct / (math.Abs(w.RealizedPnLPct) + pnlHalfPoint)
+
+	trades := 0.0
+	if w.TradeCount > 0 {
+		trades = float64(w.TradeCount) / (float64(w.TradeCount) + tradesHalfPoint)
+	}
+
+	age := now.Sub(time.Unix(w.ScannedAt, 0))
+	recency := clamp(1-float64(age)/float64(recencyWindow), 0, 1)
+
+	score := weights.Winrate*winrate + weights.PnL*pnl + weights.TradeCount*trades + weights.Recency*recency
+	return score / total * 100
+}
+
+// RankWallets scores and sorts wallets by descending score
+func RankWallets(wallets []*WalletData, weights ScoreWeights, now time.Time) []*RankedWallet {
+	ranked := make([]*RankedWallet, 0, len(wallets))
+	for _, w := range wallets {
+		ranked = append(ranked, &RankedWallet{WalletData: w, Score: ScoreWallet(w, weights, now)})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// GetWalletsRanked retrieves recent wallets matching filters ordered by composite score
+func (db *DB) GetWalletsRanked(weights ScoreWeights, filters WalletFilters) ([]*RankedWallet, error) {
+	cutoff := db.walletCutoff()
+	query := `SELECT wallet, winrate, realized_pnl, COALESCE(realized_pnl_usd, 0), COALESCE(trade_count, 0), scanned_at, COALESCE(first_seen, 0) FROM wallets
+			  WHERE scanned_at > ? AND winrate >= ? AND COALESCE(trade_count, 0) >= ?`
+	args := []interface{}{cutoff, filters.MinWinrate, filters.MinTrades}
+	if filters.MinPnL != nil {
+		query += ` AND realized_pnl >= ?`
+		args = append(args, *filters.MinPnL)
+	}
+	if filters.MinPnLUSD > 0 {
+		query += ` AND COALESCE(realized_pnl_usd, 0) >= ?`
+		args = append(args, filters.MinPnLUSD)
+	}
+	if filters.MinAgeDays > 0 {
+		query += ` AND first_seen > 0 AND first_seen <= ?`
+		args = append(args, db.Now().AddDate(0, 0, -filters.MinAgeDays).Unix())
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wallets []*WalletData
+	for rows.Next() {
+		var w WalletData
+		if err := rows.Scan(&w.Wallet, &w.Winrate, &w.RealizedPnLPct, &w.RealizedPnLUSD, &w.TradeCount, &w.ScannedAt, &w.FirstSeen); err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, &w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ranked := RankWallets(wallets, weights, db.Now())
+	if filters.Limit > 0 && len(ranked) > filters.Limit {
+		ranked = ranked[:filters.Limit]
+	}
+	return ranked, nil
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
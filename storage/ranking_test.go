@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Scoring weights the tests rank by
+var (
+	balancedWeights = ScoreWeights{Winrate: 0.4, PnL: 0.3, TradeCount: 0.2, Recency: 0.1}
+	pnlWeights      = ScoreWeights{Winrate: 0.15, PnL: 0.7, TradeCount: 0.1, Recency: 0.05}
+)
+
+func TestScoreWallet(t *testing.T) {
+	now := time.Unix(time.Now().Unix(), 0)
+	weights := balancedWeights
+
+	tests := []struct {
+		name     string
+		wallet   WalletData
+		expected float64
+	}{
+		{
+			name:     "Perfect fresh wallet",
+			wallet:   WalletData{Winrate: 100, RealizedPnLPct: 100, TradeCount: 20, ScannedAt: now.Unix()},
+			expected: (0.4*1 + 0.3*0.5 + 0.2*0.5 + 0.1*1) * 100,
+		},
+		{
+			name:     "Stale wallet without trades",
+			wallet:   WalletData{Winrate: 50, RealizedPnLPct: 0, TradeCount: 0, ScannedAt: now.Add(-6 * time.Hour).Unix()},
+			expected: 0.4 * 0.5 * 100,
+		},
+		{
+			name:     "Negative PnL lowers score",
+			wallet:   WalletData{Winrate: 50, RealizedPnLPct: -100, TradeCount: 0, ScannedAt: now.Add(-6 * time.Hour).Unix()},
+			expected: (0.4*0.5 - 0.3*0.5) * 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ScoreWallet(&tt.wallet, weights, now)
+			if math.Abs(result-tt.expected) > 1e-9 {
+				t.Errorf("ScoreWallet() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+
+	t.Run("Zero weights", func(t *testing.T) {
+		w := WalletData{Winrate: 90, RealizedPnLPct: 500, ScannedAt: now.Unix()}
+		if score := ScoreWallet(&w, ScoreWeights{}, now); score != 0 {
+			t.Errorf("Expected 0 score with zero weights, got %v", score)
+		}
+	})
+}
+
+func TestRankWallets(t *testing.T) {
+	now := time.Now()
+	wallets := []*WalletData{
+		// Single high-PnL outlier with a poor win rate
+		{Wallet: "Outlier", Winrate: 30, RealizedPnLPct: 5000, TradeCount: 2, ScannedAt: now.Unix()},
+		// Consistent trader
+		{Wallet: "Consistent", Winrate: 85, RealizedPnLPct: 300, TradeCount: 120, ScannedAt: now.Unix()},
+	}
+
+	t.Run("Balanced prefers consistency", func(t *testing.T) {
+		ranked := RankWallets(wallets, balancedWeights, now)
+		if ranked[0].Wallet != "Consistent" {
+			t.Errorf("Expected Consistent first, got %s", ranked[0].Wallet)
+		}
+	})
+
+	t.Run("Sorted descending", func(t *testing.T) {
+		ranked := RankWallets(wallets, pnlWeights, now)
+		for i := 1; i < len(ranked); i++ {
+			if ranked[i-1].Score < ranked[i].Score {
+				t.Error("Ranked wallets should be sorted by score descending")
+			}
+		}
+	})
+}
+
+func TestGetWalletsRanked(t *testing.T) {
+	db := newTestDB(t)
+
+	now := time.Now().Unix()
+	db.SaveWallet(&WalletData{Wallet: "A", Winrate: 90, RealizedPnLPct: 200, RealizedPnLUSD: 800, TradeCount: 50, ScannedAt: now})
+	db.SaveWallet(&WalletData{Wallet: "B", Winrate: 40, RealizedPnLPct: 900, RealizedPnLUSD: 45, TradeCount: 3, ScannedAt: now})
+	db.SaveWallet(&WalletData{Wallet: "C", Winrate: 70, RealizedPnLPct: 50, RealizedPnLUSD: 12000, TradeCount: 10, ScannedAt: now})
+	db.SaveWallet(&WalletData{Wallet: "D", Winrate: 30, RealizedPnLPct: -40, RealizedPnLUSD: -300, TradeCount: 8, ScannedAt: now})
+
+	t.Run("Filters applied", func(t *testing.T) {
+		minPnL := 100.0
+		ranked, err := db.GetWalletsRanked(balancedWeights, WalletFilters{MinWinrate: 60, MinPnL: &minPnL})
+		if err != nil {
+			t.Fatalf("GetWalletsRanked failed: %v", err)
+		}
+		if len(ranked) != 1 || ranked[0].Wallet != "A" {
+			t.Errorf("Expected only wallet A, got %d results", len(ranked))
+		}
+	})
+
+	t.Run("USD filter applied", func(t *testing.T) {
+		ranked, err := db.GetWalletsRanked(balancedWeights, WalletFilters{MinPnLUSD: 1000})
+		if err != nil {
+			t.Fatalf("GetWalletsRanked failed: %v", err)
+		}
+		if len(ranked) != 1 || ranked[0].Wallet != "C" || ranked[0].RealizedPnLUSD != 12000 {
+			t.Errorf("Expected only wallet C with $12000, got %d results", len(ranked))
+		}
+	})
+
+	t.Run("Losing wallets kept without a PnL filter", func(t *testing.T) {
+		ranked, err := db.GetWalletsRanked(balancedWeights, WalletFilters{})
+		if err != nil {
+			t.Fatalf("GetWalletsRanked failed: %v", err)
+		}
+		if len(ranked) != 4 || ranked[3].Wallet != "D" {
+			t.Errorf("Expected all 4 wallets with D last, got %d results", len(ranked))
+		}
+	})
+
+	t.Run("Limit applied", func(t *testing.T) {
+		ranked, err := db.GetWalletsRanked(balancedWeights, WalletFilters{Limit: 2})
+		if err != nil {
+			t.Fatalf("GetWalletsRanked failed: %v", err)
+		}
+		if len(ranked) != 2 {
+			t.Errorf("Expected 2 results, got %d", len(ranked))
+		}
+		if ranked[0].Wallet != "A" {
+			t.Errorf("Expected A ranked first, got %s", ranked[0].Wallet)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultMinSOLReserve is the SOL left in a wallet for fees unless the
+// user picks another reserve
+const DefaultMinSOLReserve = 0.01
+
+// MaxMinSOLReserve caps the reserve so a typo can't block every buy
+const MaxMinSOLReserve = 10
+
+// ReserveLamports is the user's SOL reserve in lamports
+func (s *UserSettings) ReserveLamports() uint64 {
+	return uint64(math.Round(s.MinSOLReserve * 1e9))
+}
+
+// SpendableLamports is how much of balance a buy can spend after fees
+// while keeping the reserve, 0 when the balance doesn't cover both
+func (s *UserSettings) SpendableLamports(balance, fees uint64) uint64 {
+	kept := fees + s.ReserveLamports()
+	if balance <= kept {
+		return 0
+	}
+	return balance - kept
+}
+
+// LeavesReserve reports whether spending spend plus fees out of balance
+// keeps the user's reserve in the wallet
+func (s *UserSettings) LeavesReserve(balance, spend, fees uint64) bool {
+	return spend+fees+s.ReserveLamports() <= balance
+}
+
+// UpdateMinSOLReserve sets the SOL the user's buys always leave in the
+// wallet, 0 for none
+func (db *DB) UpdateMinSOLReserve(chatID int64, sol float64) error {
+	if sol < 0 || sol > MaxMinSOLReserve {
+		return fmt.Errorf("SOL reserve must be between 0 and %d, got %g", MaxMinSOLReserve, sol)
+	}
+	query := `INSERT INTO user_settings (chat_id, min_sol_reserve, updated_at) VALUES (?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET min_sol_reserve = excluded.min_sol_reserve, updated_at = excluded.updated_at`
+	_, err := db.Exec(query, chatID, sol, db.Now().Unix())
+	return err
+}
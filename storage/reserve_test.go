@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestMinSOLReserve(t *testing.T) {
+	db := newTestDB(t)
+
+	t.Run("Update", func(t *testing.T) {
+		if s, _ := db.GetUserSettings(42); s.MinSOLReserve != DefaultMinSOLReserve {
+			t.Errorf("Expected the default reserve, got %g", s.MinSOLReserve)
+		}
+		db.UpdateSlippage(42, 100)
+		if s, _ := db.GetUserSettings(42); s.MinSOLReserve != DefaultMinSOLReserve {
+			t.Errorf("Expected the default reserve on an existing row, got %g", s.MinSOLReserve)
+		}
+
+		if err := db.UpdateMinSOLReserve(42, 0.05); err != nil {
+			t.Fatalf("UpdateMinSOLReserve failed: %v", err)
+		}
+		if s, _ := db.GetUserSettings(42); s.MinSOLReserve != 0.05 || s.SlippageBps != 100 {
+			t.Errorf("Unexpected settings %+v", s)
+		}
+		for _, sol := range []float64{-0.01, MaxMinSOLReserve + 1} {
+			if err := db.UpdateMinSOLReserve(42, sol); err == nil {
+				t.Errorf("Expected a %g SOL reserve to be rejected", sol)
+			}
+		}
+	})
+
+	t.Run("Boundaries", func(t *testing.T) {
+		const fees = 1_000_000
+		s := &UserSettings{MinSOLReserve: 0.01} // 10,000,000 lamports
+		if got := s.ReserveLamports(); got != 10_000_000 {
+			t.Fatalf("Expected 10,000,000 reserve lamports, got %d", got)
+		}
+
+		tests := []struct {
+			name           string
+			balance, spend uint64
+			leaves         bool
+			spendable      uint64
+		}{
+			{"exactly at the reserve", 111_000_000, 100_000_000, true, 100_000_000},
+			{"one lamport below", 110_999_999, 100_000_000, false, 99_999_999},
+			{"well above", 1_000_000_000, 100_000_000, true, 989_000_000},
+			{"only the reserve and fees", 11_000_000, 1, false, 0},
+			{"less than the reserve", 5_000_000, 1, false, 0},
+		}
+		for _, tt := range tests {
+			if got := s.LeavesReserve(tt.balance, tt.spend, fees); got != tt.leaves {
+				t.Errorf("%s: expected LeavesReserve %v, got %v", tt.name, tt.leaves, got)
+			}
+			if got := s.SpendableLamports(tt.balance, fees); got != tt.spendable {
+				t.Errorf("%s: expected %d spendable, got %d", tt.name, tt.spendable, got)
+			}
+		}
+
+		none := &UserSettings{}
+		if !none.LeavesReserve(2_000_000, 1_000_000, fees) {
+			t.Error("Expected spending everything but the fees to pass without a reserve")
+		}
+	})
+}
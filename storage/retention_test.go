@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWalletRetention(t *testing.T) {
+	db := newTestDB(t)
+
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	db.SetClock(clock)
+	now := clock.now
+	db.SetWalletRetention(12*time.Hour, 48*time.Hour)
+
+	for wallet, age := range map[string]time.Duration{
+		"Fresh":   time.Hour,
+		"Visible": 11 * time.Hour,
+		"Hidden":  13 * time.Hour,
+		"Expired": 49 * time.Hour,
+	} {
+		if err := db.SaveWallet(&WalletData{Wallet: wallet, Winrate: 70, ScannedAt: now.Add(-age).Unix()}); err != nil {
+			t.Fatalf("SaveWallet failed: %v", err)
+		}
+	}
+
+	shown := func() map[string]bool {
+		wallets, err := db.GetWallets()
+		if err != nil {
+			t.Fatalf("GetWallets failed: %v", err)
+		}
+		got := make(map[string]bool)
+		for _, w := range wallets {
+			got[w.Wallet] = true
+		}
+		return got
+	}
+
+	t.Run("DisplayWindow", func(t *testing.T) {
+		got := shown()
+		if !got["Fresh"] || !got["Visible"] || got["Hidden"] || got["Expired"] || len(got) != 2 {
+			t.Errorf("Expected only wallets inside the 12h window, got %v", got)
+		}
+		ranked, err := db.GetWalletsRanked(balancedWeights, WalletFilters{})
+		if err != nil {
+			t.Fatalf("GetWalletsRanked failed: %v", err)
+		}
+		if len(ranked) != 2 {
+			t.Errorf("Expected ranking to use the display window, got %d wallets", len(ranked))
+		}
+	})
+
+	t.Run("CleanupKeepsUntilRetention", func(t *testing.T) {
+		deleted, err := db.CleanupOldData()
+		if err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("Expected only the 49h old wallet deleted, got %d", deleted)
+		}
+
+		// Widening the display window brings back kept wallets
+		db.SetWalletRetention(48*time.Hour, 48*time.Hour)
+		if got := shown(); !got["Hidden"] || got["Expired"] {
+			t.Errorf("Expected the kept 13h old wallet to show again, got %v", got)
+		}
+	})
+
+	t.Run("ClockAdvances", func(t *testing.T) {
+		clock.Advance(48 * time.Hour)
+		deleted, err := db.CleanupOldData()
+		if err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if deleted != 3 {
+			t.Errorf("Expected the remaining 3 wallets deleted, got %d", deleted)
+		}
+	})
+
+	t.Run("RetentionAtLeastDisplay", func(t *testing.T) {
+		db.SetWalletRetention(10*time.Hour, time.Hour)
+		if db.walletRetention != 10*time.Hour {
+			t.Errorf("Expected retention raised to the display window, got %v", db.walletRetention)
+		}
+	})
+}
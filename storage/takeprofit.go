@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Take-profit trigger statuses
+const (
+	TakeProfitPending   = "pending"
+	TakeProfitFilled    = "filled"
+	TakeProfitCancelled = "cancelled"
+)
+
+// TakeProfitTrigger sells TokenAmount raw units of a token once its price
+// reaches TriggerPriceSOL. Triggers made for copied buys keep the target
+// they copied.
+type TakeProfitTrigger struct {
+	ID              int64   `json:"id"`
+	UserID          int64   `json:"user_id"`
+	TargetWallet    string  `json:"target_wallet"`
+	TokenAddress    string  `json:"token_address"`
+	TokenAmount     uint64  `json:"token_amount"`
+	EntryPriceSOL   float64 `json:"entry_price_sol"`   // SOL per raw unit paid
+	TriggerPriceSOL float64 `json:"trigger_price_sol"` // SOL per raw unit to sell at
+	Status          string  `json:"status"`
+	CreatedAt       int64   `json:"created_at"`
+}
+
+// AutoSells reports whether copied buys register a take-profit
+func (t *CopyTradeTarget) AutoSells() bool {
+	return t.AutoSellPct > 0 && t.AutoSellMultiple > 1
+}
+
+// SetCopyTargetAutoSell makes copied buys from a target take profit on pct
+// percent of the tokens at multiple times the entry price. Both 0 turn it
+// off.
+func (db *DB) SetCopyTargetAutoSell(userID int64, targetWallet string, pct, multiple float64) error {
+	if pct != 0 || multiple != 0 {
+		if pct <= 0 || pct > 100 {
+			return fmt.Errorf("auto-sell percentage must be between 0 and 100, got %g", pct)
+		}
+		if multiple <= 1 {
+			return fmt.Errorf("auto-sell multiple must be above 1, got %g", multiple)
+		}
+	}
+	result, err := db.Exec(`UPDATE copy_trade_targets SET auto_sell_pct = ?, auto_sell_multiple = ? WHERE user_id = ? AND target_wallet = ?`,
+		pct, multiple, userID, targetWallet)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateCopyTakeProfit registers the take-profit for a buy of tokens raw
+// units of tokenAddr for solSpent, copied from targetWallet. It returns
+// nil without a trigger when the target doesn't auto-sell or nothing was
+// bought.
+func (db *DB) CreateCopyTakeProfit(userID int64, targetWallet, tokenAddr string, solSpent float64, tokens uint64) (*TakeProfitTrigger, error) {
+	target, err := db.GetCopyTarget(userID, targetWallet)
+	if err != nil || target == nil || !target.AutoSells() || tokens == 0 {
+		return nil, err
+	}
+
+	entry := solSpent / float64(tokens)
+	trigger := &TakeProfitTrigger{
+		UserID:          userID,
+		TargetWallet:    targetWallet,
+		TokenAddress:    tokenAddr,
+		TokenAmount:     uint64(float64(tokens) * target.AutoSellPct / 100),
+		EntryPriceSOL:   entry,
+		TriggerPriceSOL: entry * target.AutoSellMultiple,
+		Status:          TakeProfitPending,
+		CreatedAt:       db.Now().Unix(),
+	}
+	result, err := db.Exec(`INSERT INTO take_profit_triggers (user_id, target_wallet, token_address, token_amount, entry_price_sol, trigger_price_sol, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		trigger.UserID, trigger.TargetWallet, trigger.TokenAddress, trigger.TokenAmount,
+		trigger.EntryPriceSOL, trigger.TriggerPriceSOL, trigger.Status, trigger.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if trigger.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+	return trigger, nil
+}
+
+// GetPendingTakeProfits returns the user's take-profits still waiting for
+// their price, oldest first
+func (db *DB) GetPendingTakeProfits(userID int64) ([]*TakeProfitTrigger, error) {
+	rows, err := db.Query(`SELECT id, user_id, target_wallet, token_address, token_amount, entry_price_sol, trigger_price_sol, status, created_at
+		FROM take_profit_triggers WHERE user_id = ? AND status = ? ORDER BY id`, userID, TakeProfitPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triggers []*TakeProfitTrigger
+	for rows.Next() {
+		var t TakeProfitTrigger
+		if err := rows.Scan(&t.ID, &t.UserID, &t.TargetWallet, &t.TokenAddress, &t.TokenAmount,
+			&t.EntryPriceSOL, &t.TriggerPriceSOL, &t.Status, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, &t)
+	}
+	return triggers, rows.Err()
+}
+
+// SetTakeProfitStatus marks a trigger filled or cancelled
+func (db *DB) SetTakeProfitStatus(id int64, status string) error {
+	_, err := db.Exec(`UPDATE take_profit_triggers SET status = ? WHERE id = ?`, status, id)
+	return err
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCopyTakeProfit(t *testing.T) {
+	db := newTestDB(t)
+
+	const user = int64(7)
+	db.AddCopyTarget(user, "plainWallet", 0.1)
+	db.AddCopyTarget(user, "tpWallet", 0.1)
+
+	t.Run("Validation", func(t *testing.T) {
+		for _, tt := range []struct{ pct, multiple float64 }{{0, 2}, {150, 2}, {50, 1}, {50, 0}} {
+			if err := db.SetCopyTargetAutoSell(user, "tpWallet", tt.pct, tt.multiple); err == nil {
+				t.Errorf("Expected %g%% at %gx to be rejected", tt.pct, tt.multiple)
+			}
+		}
+		if err := db.SetCopyTargetAutoSell(user, "unknownWallet", 50, 2); err == nil {
+			t.Error("Expected an error for a wallet the user doesn't copy")
+		}
+		if err := db.SetCopyTargetAutoSell(user, "tpWallet", 0, 0); err != nil {
+			t.Errorf("Expected turning auto-sell off to succeed, got %v", err)
+		}
+	})
+
+	t.Run("CreatedForAutoSellTargets", func(t *testing.T) {
+		if err := db.SetCopyTargetAutoSell(user, "tpWallet", 50, 2); err != nil {
+			t.Fatalf("SetCopyTargetAutoSell failed: %v", err)
+		}
+
+		if trigger, err := db.CreateCopyTakeProfit(user, "plainWallet", "mintA", 0.1, 1000); err != nil || trigger != nil {
+			t.Errorf("Expected no trigger without auto-sell, got %+v (%v)", trigger, err)
+		}
+
+		trigger, err := db.CreateCopyTakeProfit(user, "tpWallet", "mintA", 0.1, 1000)
+		if err != nil || trigger == nil {
+			t.Fatalf("CreateCopyTakeProfit = %+v, %v", trigger, err)
+		}
+		if trigger.TokenAmount != 500 || math.Abs(trigger.TriggerPriceSOL-2e-4) > 1e-12 || trigger.TargetWallet != "tpWallet" {
+			t.Errorf("Expected half the tokens sold at 2x the 0.0001 entry, got %+v", trigger)
+		}
+
+		pending, err := db.GetPendingTakeProfits(user)
+		if err != nil || len(pending) != 1 || pending[0].ID != trigger.ID {
+			t.Fatalf("Expected the trigger pending, got %v (%v)", pending, err)
+		}
+		db.SetTakeProfitStatus(trigger.ID, TakeProfitFilled)
+		if pending, _ := db.GetPendingTakeProfits(user); len(pending) != 0 {
+			t.Errorf("Expected no pending triggers once filled, got %v", pending)
+		}
+	})
+}
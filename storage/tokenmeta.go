@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// TokenMetadata is the cached display metadata of a token mint
+type TokenMetadata struct {
+	Mint      string
+	Symbol    string
+	Name      string
+	Decimals  int // -1 when unknown
+	UpdatedAt int64
+}
+
+// GetTokenMetadata returns the cached metadata of a mint, or nil if it
+// hasn't been looked up yet
+func (db *DB) GetTokenMetadata(mint string) (*TokenMetadata, error) {
+	m := TokenMetadata{Mint: mint}
+	err := db.QueryRow(`SELECT symbol, name, decimals, updated_at FROM token_metadata WHERE mint = ?`, mint).
+		Scan(&m.Symbol, &m.Name, &m.Decimals, &m.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SaveTokenMetadata caches a mint's metadata, stamped with the current
+// time. Unknown decimals don't overwrite known ones.
+func (db *DB) SaveTokenMetadata(m *TokenMetadata) error {
+	query := `
+		INSERT INTO token_metadata (mint, symbol, name, decimals, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(mint) DO UPDATE SET symbol = excluded.symbol, name = excluded.name,
+			decimals = CASE WHEN excluded.decimals >= 0 THEN excluded.decimals ELSE decimals END,
+			updated_at = excluded.updated_at
+	`
+	_, err := db.Exec(query, m.Mint, m.Symbol, m.Name, m.Decimals, db.Now().Unix())
+	return err
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenMetadata(t *testing.T) {
+	db := newTestDB(t)
+
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	db.SetClock(clock)
+
+	if m, err := db.GetTokenMetadata("mint1"); err != nil || m != nil {
+		t.Fatalf("Expected no metadata before saving, got %+v, %v", m, err)
+	}
+
+	if err := db.SaveTokenMetadata(&TokenMetadata{Mint: "mint1", Symbol: "BONK", Name: "Bonk", Decimals: 5}); err != nil {
+		t.Fatalf("SaveTokenMetadata failed: %v", err)
+	}
+	m, err := db.GetTokenMetadata("mint1")
+	if err != nil || m == nil {
+		t.Fatalf("GetTokenMetadata failed: %v", err)
+	}
+	if m.Symbol != "BONK" || m.Name != "Bonk" || m.Decimals != 5 || m.UpdatedAt != clock.now.Unix() {
+		t.Errorf("Unexpected metadata %+v", m)
+	}
+
+	// A refresh without decimals keeps the known ones
+	clock.Advance(time.Hour)
+	if err := db.SaveTokenMetadata(&TokenMetadata{Mint: "mint1", Symbol: "BONK2", Name: "Bonk", Decimals: -1}); err != nil {
+		t.Fatalf("SaveTokenMetadata failed: %v", err)
+	}
+	m, _ = db.GetTokenMetadata("mint1")
+	if m.Symbol != "BONK2" || m.Decimals != 5 || m.UpdatedAt != clock.now.Unix() {
+		t.Errorf("Unexpected refreshed metadata %+v", m)
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// TradeLogEntry is one stage of a trade's lifecycle. Signature is stored
+// abbreviated; the full signature is only kept as SignatureHash so it can
+// be matched but not searched for.
+type TradeLogEntry struct {
+	ID            int64  `json:"id"`
+	TradeID       string `json:"trade_id"`
+	UserID        int64  `json:"user_id"`
+	Kind          string `json:"kind"`   // buy, sell or copy
+	Stage         string `json:"stage"`  // quote, build, sign, submit, confirm
+	Status        string `json:"status"` // ok or error
+	Signature     string `json:"signature,omitempty"`
+	SignatureHash string `json:"-"`
+	Detail        string `json:"detail,omitempty"`
+	CreatedAt     int64  `json:"created_at"` // unix milliseconds
+}
+
+// InsertTradeLog records one trade stage
+func (db *DB) InsertTradeLog(e *TradeLogEntry) error {
+	if e.CreatedAt == 0 {
+		e.CreatedAt = db.Now().UnixMilli()
+	}
+	result, err := db.Exec(`INSERT INTO trade_logs (trade_id, user_id, kind, stage, status, signature, signature_hash, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.TradeID, e.UserID, e.Kind, e.Stage, e.Status, e.Signature, e.SignatureHash, e.Detail, e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert trade log: %w", err)
+	}
+	e.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetTradeLogs returns every stage of a trade in the order they happened
+func (db *DB) GetTradeLogs(tradeID string) ([]*TradeLogEntry, error) {
+	return db.queryTradeLogs(`SELECT id, trade_id, user_id, kind, stage, status, signature, signature_hash, detail, created_at
+		FROM trade_logs WHERE trade_id = ? ORDER BY created_at, id`, tradeID)
+}
+
+// GetTradeLogsBySignatureHash returns every stage of the trades that
+// produced the signature with the given hash, including the stages logged
+// before the signature was known
+func (db *DB) GetTradeLogsBySignatureHash(hash string) ([]*TradeLogEntry, error) {
+	return db.queryTradeLogs(`SELECT id, trade_id, user_id, kind, stage, status, signature, signature_hash, detail, created_at
+		FROM trade_logs WHERE trade_id IN (SELECT trade_id FROM trade_logs WHERE signature_hash = ?)
+		ORDER BY trade_id, created_at, id`, hash)
+}
+
+func (db *DB) queryTradeLogs(query string, args ...interface{}) ([]*TradeLogEntry, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trade logs: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*TradeLogEntry
+	for rows.Next() {
+		e := &TradeLogEntry{}
+		var signature, signatureHash, detail sql.NullString
+		if err := rows.Scan(&e.ID, &e.TradeID, &e.UserID, &e.Kind, &e.Stage, &e.Status,
+			&signature, &signatureHash, &detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Signature = signature.String
+		e.SignatureHash = signatureHash.String
+		e.Detail = detail.String
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestTradeLogs(t *testing.T) {
+	db := newTestDB(t)
+
+	stages := []*TradeLogEntry{
+		{TradeID: "t1", UserID: 42, Kind: "buy", Stage: "quote", Status: "ok", CreatedAt: 1000},
+		{TradeID: "t1", UserID: 42, Kind: "buy", Stage: "sign", Status: "ok", Signature: "5abc…xyz", SignatureHash: "h1", CreatedAt: 1200},
+		{TradeID: "t1", UserID: 42, Kind: "buy", Stage: "submit", Status: "error", Signature: "5abc…xyz", SignatureHash: "h1", Detail: "rpc unavailable", CreatedAt: 1500},
+		{TradeID: "t2", UserID: 7, Kind: "sell", Stage: "quote", Status: "ok", CreatedAt: 1100},
+	}
+	for _, e := range stages {
+		if err := db.InsertTradeLog(e); err != nil {
+			t.Fatalf("InsertTradeLog failed: %v", err)
+		}
+	}
+
+	t.Run("ByTradeID", func(t *testing.T) {
+		entries, err := db.GetTradeLogs("t1")
+		if err != nil {
+			t.Fatalf("GetTradeLogs failed: %v", err)
+		}
+		if len(entries) != 3 || entries[0].Stage != "quote" || entries[2].Stage != "submit" {
+			t.Fatalf("Expected 3 ordered stages, got %+v", entries)
+		}
+		if entries[2].Detail != "rpc unavailable" {
+			t.Errorf("Expected detail to round-trip, got %q", entries[2].Detail)
+		}
+	})
+
+	t.Run("BySignatureHash", func(t *testing.T) {
+		entries, err := db.GetTradeLogsBySignatureHash("h1")
+		if err != nil {
+			t.Fatalf("GetTradeLogsBySignatureHash failed: %v", err)
+		}
+		// The quote stage predates the signature but belongs to the same trade
+		if len(entries) != 3 || entries[0].Stage != "quote" {
+			t.Errorf("Expected the whole trade, got %+v", entries)
+		}
+	})
+
+	t.Run("UnknownSignature", func(t *testing.T) {
+		entries, err := db.GetTradeLogsBySignatureHash("missing")
+		if err != nil {
+			t.Fatalf("GetTradeLogsBySignatureHash failed: %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("Expected no entries, got %d", len(entries))
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrCorruptWallet is returned when a stored wallet field isn't valid base64
+var ErrCorruptWallet = errors.New("corrupt encrypted wallet")
+
+// walletEncoding is the only encoding used for encrypted wallet fields.
+// Strict mode rejects non-canonical padding so every value has exactly
+// one stored form.
+var walletEncoding = base64.StdEncoding.Strict()
+
+// decodeWalletField decodes one encrypted wallet column
+func decodeWalletField(column, value string) ([]byte, error) {
+	data, err := walletEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not base64: %v", ErrCorruptWallet, column, err)
+	}
+	return data, nil
+}
+
+// normalizeWalletEncoding re-encodes encrypted wallet fields that early
+// builds stored as raw bytes. A value that doesn't strictly decode as
+// base64 is taken to be raw and encoded; valid base64 is left as is.
+func normalizeWalletEncoding(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT chat_id, encrypted_private_key, encryption_salt, nonce, COALESCE(mnemonic_encrypted, '') FROM encrypted_wallets`)
+	if err != nil {
+		return err
+	}
+
+	type walletFields struct {
+		chatID                     int64
+		key, salt, nonce, mnemonic string
+	}
+	var fixes []walletFields
+	for rows.Next() {
+		var w walletFields
+		if err := rows.Scan(&w.chatID, &w.key, &w.salt, &w.nonce, &w.mnemonic); err != nil {
+			rows.Close()
+			return err
+		}
+
+		changed := false
+		for _, field := range []*string{&w.key, &w.salt, &w.nonce, &w.mnemonic} {
+			if *field == "" {
+				continue
+			}
+			if _, err := walletEncoding.DecodeString(*field); err != nil {
+				*field = walletEncoding.EncodeToString([]byte(*field))
+				changed = true
+			}
+		}
+		if changed {
+			fixes = append(fixes, w)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, w := range fixes {
+		_, err := tx.Exec(`UPDATE encrypted_wallets SET encrypted_private_key = ?, encryption_salt = ?, nonce = ?, mnemonic_encrypted = ? WHERE chat_id = ?`,
+			w.key, w.salt, w.nonce, w.mnemonic, w.chatID)
+		if err != nil {
+			return fmt.Errorf("failed to normalize wallet %d: %w", w.chatID, err)
+		}
+	}
+	return nil
+}
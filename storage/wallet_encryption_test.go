@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWalletEncryptionVersion(t *testing.T) {
+	db := newTestDB(t)
+
+	t.Run("LegacyRowsDefaultToPBKDF2", func(t *testing.T) {
+		// Rows written before kdf_version existed pick up the column default
+		_, err := db.Exec(`INSERT INTO encrypted_wallets (chat_id, public_key, encrypted_private_key, encryption_salt, nonce, password_hash)
+			VALUES (1, 'pub', 'a2V5', 'c2FsdA==', 'bm9uY2U=', 'hash')`)
+		if err != nil {
+			t.Fatalf("Failed to insert legacy wallet: %v", err)
+		}
+		secrets, err := db.GetEncryptedWalletForCrypto(1)
+		if err != nil {
+			t.Fatalf("GetEncryptedWalletForCrypto failed: %v", err)
+		}
+		if secrets.KDFVersion != 1 {
+			t.Errorf("Expected legacy KDF version 1, got %d", secrets.KDFVersion)
+		}
+	})
+
+	t.Run("SaveAndUpgrade", func(t *testing.T) {
+		if err := db.SaveEncryptedWallet(2, "pub2", []byte("key"), []byte("salt"), []byte("nonce"), "hash", "mnemonic", 1); err != nil {
+			t.Fatalf("SaveEncryptedWallet failed: %v", err)
+		}
+		if err := db.UpdateWalletEncryption(2, []byte("key2"), []byte("salt2"), []byte("nonce2"), "hash2", 2); err != nil {
+			t.Fatalf("UpdateWalletEncryption failed: %v", err)
+		}
+
+		secrets, err := db.GetEncryptedWalletForCrypto(2)
+		if err != nil {
+			t.Fatalf("GetEncryptedWalletForCrypto failed: %v", err)
+		}
+		if !bytes.Equal(secrets.EncryptedKey, []byte("key2")) || !bytes.Equal(secrets.Salt, []byte("salt2")) ||
+			secrets.PasswordHash != "hash2" || secrets.KDFVersion != 2 {
+			t.Errorf("Expected upgraded secrets, got %+v", secrets)
+		}
+
+		stored, _ := db.GetEncryptedWalletForDecryption(2)
+		if stored.MnemonicEncrypted != "mnemonic" {
+			t.Errorf("Upgrade must keep the mnemonic, got %q", stored.MnemonicEncrypted)
+		}
+	})
+
+	t.Run("UpgradeMissingWallet", func(t *testing.T) {
+		if err := db.UpdateWalletEncryption(99, nil, nil, nil, "", 2); err == nil {
+			t.Error("Expected an error for a missing wallet")
+		}
+	})
+}
+
+func TestNormalizeWalletEncoding(t *testing.T) {
+	db := newTestDB(t)
+
+	rawSalt := string([]byte{0xff, 0x00, 0x10, 0x80, 0x7f, 0xfe})
+	_, err := db.Exec(`INSERT INTO encrypted_wallets (chat_id, public_key, encrypted_private_key, encryption_salt, nonce, password_hash)
+		VALUES (1, 'pub', 'a2V5', ?, 'bm9uY2U=', 'hash')`, rawSalt)
+	if err != nil {
+		t.Fatalf("Failed to insert legacy wallet: %v", err)
+	}
+
+	if _, err := db.GetEncryptedWalletForCrypto(1); !errors.Is(err, ErrCorruptWallet) {
+		t.Fatalf("Expected ErrCorruptWallet before normalizing, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ { // the second pass must be a no-op
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := normalizeWalletEncoding(tx); err != nil {
+			tx.Rollback()
+			t.Fatalf("normalizeWalletEncoding failed: %v", err)
+		}
+		tx.Commit()
+	}
+
+	secrets, err := db.GetEncryptedWalletForCrypto(1)
+	if err != nil {
+		t.Fatalf("GetEncryptedWalletForCrypto failed: %v", err)
+	}
+	if string(secrets.Salt) != rawSalt {
+		t.Errorf("Expected raw salt preserved, got %x", secrets.Salt)
+	}
+	if string(secrets.EncryptedKey) != "key" || string(secrets.Nonce) != "nonce" {
+		t.Errorf("Base64 fields must not be re-encoded, got %q %q", secrets.EncryptedKey, secrets.Nonce)
+	}
+}
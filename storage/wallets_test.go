@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestUserWalletLimit(t *testing.T) {
+	db := newTestDB(t)
+
+	const limit = 3
+	for i := 0; i < limit; i++ {
+		if err := db.AddUserWalletWithLimit(1, fmt.Sprintf("wallet%d", i), "name", limit); err != nil {
+			t.Fatalf("Wallet %d under the cap rejected: %v", i, err)
+		}
+	}
+
+	if err := db.AddUserWalletWithLimit(1, "walletOver", "name", limit); !errors.Is(err, ErrWalletLimitReached) {
+		t.Fatalf("Expected ErrWalletLimitReached at the cap, got %v", err)
+	}
+	if count, _ := db.CountUserWallets(1); count != limit {
+		t.Errorf("Expected %d wallets, got %d", limit, count)
+	}
+
+	// The cap is per user
+	if err := db.AddUserWalletWithLimit(2, "walletOver", "name", limit); err != nil {
+		t.Errorf("Other users must not be affected by the cap: %v", err)
+	}
+}
+
+func TestAddUserWalletNormalizesAddress(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.AddUserWallet(1, " walletA\n", " Main "); err != nil {
+		t.Fatalf("AddUserWallet failed: %v", err)
+	}
+	if err := db.AddUserWallet(1, "\u200bwalletA ", "Copy"); err == nil {
+		t.Error("Expected the padded duplicate to hit the unique constraint")
+	}
+
+	wallets, _ := db.GetUserWallets(1)
+	if len(wallets) != 1 || wallets[0].WalletAddress != "walletA" || wallets[0].WalletName != "Main" {
+		t.Errorf("Expected one trimmed wallet, got %+v", wallets)
+	}
+}
+
+func TestRemoveActiveWallet(t *testing.T) {
+	db := newTestDB(t)
+
+	// created_at has one-second resolution, so set it explicitly
+	for i, addr := range []string{"oldest", "middle", "newest"} {
+		if err := db.AddUserWallet(1, addr, addr); err != nil {
+			t.Fatalf("AddUserWallet failed: %v", err)
+		}
+		db.Exec("UPDATE user_wallets SET created_at = ? WHERE wallet_address = ?", 1000+i, addr)
+	}
+	db.SetActiveWallet(1, "middle")
+
+	t.Run("InactiveWallet", func(t *testing.T) {
+		activated, err := db.RemoveUserWallet(1, "oldest")
+		if err != nil {
+			t.Fatalf("RemoveUserWallet failed: %v", err)
+		}
+		if activated != nil {
+			t.Errorf("Removing an inactive wallet must not reassign, got %+v", activated)
+		}
+		if active, _ := db.GetActiveWallet(1); active == nil || active.WalletAddress != "middle" {
+			t.Errorf("Expected middle to stay active, got %+v", active)
+		}
+	})
+
+	t.Run("ActiveWalletReassigned", func(t *testing.T) {
+		activated, err := db.RemoveUserWallet(1, "middle")
+		if err != nil {
+			t.Fatalf("RemoveUserWallet failed: %v", err)
+		}
+		if activated == nil || activated.WalletAddress != "newest" {
+			t.Fatalf("Expected newest to become active, got %+v", activated)
+		}
+		if active, _ := db.GetActiveWallet(1); active == nil || active.WalletAddress != "newest" {
+			t.Errorf("Expected newest active in the database, got %+v", active)
+		}
+	})
+
+	t.Run("LastWallet", func(t *testing.T) {
+		activated, err := db.RemoveUserWallet(1, "newest")
+		if err != nil {
+			t.Fatalf("RemoveUserWallet failed: %v", err)
+		}
+		if activated != nil {
+			t.Errorf("Expected no wallet to activate, got %+v", activated)
+		}
+		if active, _ := db.GetActiveWallet(1); active != nil {
+			t.Errorf("Expected no active wallet, got %+v", active)
+		}
+	})
+}
+
+func TestRenameUserWallet(t *testing.T) {
+	db := newTestDB(t)
+
+	db.AddUserWallet(1, "walletA", "Main")
+	db.AddUserWallet(1, "walletB", "Trading")
+	db.AddUserWallet(2, "walletC", "Savings")
+
+	t.Run("Renames", func(t *testing.T) {
+		if err := db.RenameUserWallet(1, "walletA", "Savings"); err != nil {
+			t.Fatalf("RenameUserWallet failed: %v", err)
+		}
+		wallets, _ := db.GetUserWallets(1)
+		for _, w := range wallets {
+			if w.WalletAddress == "walletA" && w.WalletName != "Savings" {
+				t.Errorf("Expected new name, got %q", w.WalletName)
+			}
+		}
+	})
+
+	t.Run("SameNameKeepsWallet", func(t *testing.T) {
+		if err := db.RenameUserWallet(1, "walletA", "savings"); err != nil {
+			t.Errorf("Renaming a wallet to its own name must succeed: %v", err)
+		}
+	})
+
+	t.Run("Collision", func(t *testing.T) {
+		if err := db.RenameUserWallet(1, "walletB", "SAVINGS"); !errors.Is(err, ErrWalletNameTaken) {
+			t.Errorf("Expected ErrWalletNameTaken, got %v", err)
+		}
+	})
+
+	t.Run("UnknownWallet", func(t *testing.T) {
+		if err := db.RenameUserWallet(1, "walletC", "Mine"); !errors.Is(err, ErrWalletNotFound) {
+			t.Errorf("Expected ErrWalletNotFound for another user's wallet, got %v", err)
+		}
+	})
+}
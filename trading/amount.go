@@ -0,0 +1,39 @@
+package trading
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// SOLDecimals is the number of decimals in one SOL (lamports per SOL = 10^9)
+const SOLDecimals uint8 = 9
+
+// ToRawAmount converts a UI amount to raw token units. The amount is taken
+// at its shortest decimal form (0.29, not 0.28999…) and scaled with big.Int
+// arithmetic, so no precision is lost to float multiplication. Digits
+// beyond the token's decimals are dropped, rounding down so a trade never
+// spends more than the user asked for. Amounts that don't fit in a uint64
+// return an error.
+func ToRawAmount(ui float64, decimals uint8) (uint64, error) {
+	if math.IsNaN(ui) || math.IsInf(ui, 0) || ui < 0 {
+		return 0, fmt.Errorf("invalid token amount %v", ui)
+	}
+
+	whole, frac, _ := strings.Cut(strconv.FormatFloat(ui, 'f', -1, 64), ".")
+	if len(frac) > int(decimals) {
+		frac = frac[:decimals]
+	}
+	frac += strings.Repeat("0", int(decimals)-len(frac))
+
+	raw, ok := new(big.Int).SetString(whole+frac, 10)
+	if !ok {
+		return 0, fmt.Errorf("invalid token amount %v", ui)
+	}
+	if !raw.IsUint64() {
+		return 0, fmt.Errorf("token amount %v with %d decimals overflows uint64", ui, decimals)
+	}
+	return raw.Uint64(), nil
+}
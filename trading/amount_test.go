@@ -0,0 +1,55 @@
+package trading
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToRawAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		ui       float64
+		decimals uint8
+		want     uint64
+	}{
+		{"WholeSOL", 1, 9, 1_000_000_000},
+		{"FloatArtifact", 0.29, 9, 290_000_000}, // 0.29*1e9 is 289999999.99999997 as a float
+		{"EighteenDecimals", 1.5, 18, 1_500_000_000_000_000_000},
+		{"EighteenDecimalsFraction", 0.000123456789012345, 18, 123_456_789_012_345},
+		{"DustOneUnit", 0.000000001, 9, 1},
+		{"DustBelowPrecision", 0.0000000001, 9, 0},
+		{"RoundsDown", 1.23456789, 6, 1_234_567},
+		{"NoDecimals", 42.9, 0, 42},
+		{"Zero", 0, 6, 0},
+		{"LargeBalance", 9_000_000_000, 9, 9_000_000_000_000_000_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToRawAmount(tt.ui, tt.decimals)
+			if err != nil {
+				t.Fatalf("ToRawAmount(%v, %d) failed: %v", tt.ui, tt.decimals, err)
+			}
+			if got != tt.want {
+				t.Errorf("ToRawAmount(%v, %d) = %d, want %d", tt.ui, tt.decimals, got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []struct {
+		name     string
+		ui       float64
+		decimals uint8
+	}{
+		{"Overflow18Decimals", 100, 18},
+		{"OverflowLargeBalance", 20_000_000_000, 9},
+		{"Negative", -1, 9},
+		{"NaN", math.NaN(), 9},
+		{"Inf", math.Inf(1), 9},
+	} {
+		t.Run(bad.name, func(t *testing.T) {
+			if got, err := ToRawAmount(bad.ui, bad.decimals); err == nil {
+				t.Errorf("Expected error for %v with %d decimals, got %d", bad.ui, bad.decimals, got)
+			}
+		})
+	}
+}
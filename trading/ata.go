@@ -0,0 +1,86 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TokenAccountSize is the data length of an SPL token account
+const TokenAccountSize = 165
+
+// DefaultTokenAccountRent is the rent-exempt minimum for a token account,
+// used when the RPC can't be asked for the current value
+const DefaultTokenAccountRent = 2039280
+
+// AccountClient is the subset of the RPC client needed for ATA checks
+type AccountClient interface {
+	GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error)
+	GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error)
+}
+
+// ATAStatus describes the associated token account of an owner for a mint
+type ATAStatus struct {
+	Address        solana.PublicKey
+	Exists         bool
+	NeedsCreation  bool
+	RentLamports   uint64 // extra SOL required to create the account, 0 if it exists
+	AccountBalance uint64 // lamports currently held by the account
+}
+
+// EnsureATA checks whether owner's associated token account for mint exists
+// and how much SOL is needed to create it if not. The ATA is derived under
+// the program owning the mint, so Token-2022 mints get theirs.
+func EnsureATA(ctx context.Context, client AccountClient, owner, mint solana.PublicKey) (*ATAStatus, error) {
+	tokenProgram, err := mintTokenProgram(ctx, client, mint)
+	if err != nil {
+		return nil, err
+	}
+	ata, err := associatedTokenAddress(owner, mint, tokenProgram)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ATAStatus{Address: ata}
+
+	info, err := client.GetAccountInfo(ctx, ata)
+	if err != nil && !errors.Is(err, rpc.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get ATA account info: %w", err)
+	}
+
+	if err == nil && info != nil && info.Value != nil {
+		status.Exists = true
+		status.AccountBalance = info.Value.Lamports
+		return status, nil
+	}
+
+	rent, err := client.GetMinimumBalanceForRentExemption(ctx, TokenAccountSize, rpc.CommitmentConfirmed)
+	if err != nil || rent == 0 {
+		rent = DefaultTokenAccountRent
+	}
+
+	status.NeedsCreation = true
+	status.RentLamports = rent
+	return status, nil
+}
+
+// EnsureATA checks the associated token account using the manager's RPC client
+func (bm *BalanceManager) EnsureATA(ctx context.Context, owner, mint solana.PublicKey) (*ATAStatus, error) {
+	return EnsureATA(ctx, bm.rpcClient, owner, mint)
+}
+
+// mintTokenProgram returns the token program owning mint: Token-2022 for
+// newer mints, the original token program otherwise
+func mintTokenProgram(ctx context.Context, client AccountInfoClient, mint solana.PublicKey) (solana.PublicKey, error) {
+	info, err := client.GetAccountInfo(ctx, mint)
+	if err != nil || info == nil || info.Value == nil {
+		return solana.PublicKey{}, fmt.Errorf("%w: failed to read mint: %v", ErrRPCUnavailable, err)
+	}
+	if info.Value.Owner.Equals(Token2022ProgramID) {
+		return Token2022ProgramID, nil
+	}
+	return solana.TokenProgramID, nil
+}
@@ -0,0 +1,115 @@
+package trading
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// fakeAccountClient serves account info from in-memory maps. Accounts
+// without an owner are owned by the original token program.
+type fakeAccountClient struct {
+	accounts map[solana.PublicKey]uint64
+	owners   map[solana.PublicKey]solana.PublicKey
+	rent     uint64
+}
+
+func (f *fakeAccountClient) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	lamports, ok := f.accounts[account]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	owner, ok := f.owners[account]
+	if !ok {
+		owner = solana.TokenProgramID
+	}
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{Lamports: lamports, Owner: owner}}, nil
+}
+
+func (f *fakeAccountClient) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+	return f.rent, nil
+}
+
+// TestEnsureATA tests ATA existence and rent checks
+func TestEnsureATA(t *testing.T) {
+	owner := solana.MustPublicKeyFromBase58("G4vTBDnAbBre4wqTpibXbLmwdVtFAbFCr2DM8t22UrmM")
+	mint := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	ata, _, _ := solana.FindAssociatedTokenAddress(owner, mint)
+
+	t.Run("MissingATA", func(t *testing.T) {
+		client := &fakeAccountClient{accounts: map[solana.PublicKey]uint64{mint: 1}, rent: 2039280}
+
+		status, err := EnsureATA(context.Background(), client, owner, mint)
+		if err != nil {
+			t.Fatalf("EnsureATA failed: %v", err)
+		}
+
+		if status.Exists || !status.NeedsCreation {
+			t.Error("Missing ATA should need creation")
+		}
+		if status.RentLamports != 2039280 {
+			t.Errorf("Expected rent 2039280, got %d", status.RentLamports)
+		}
+		if status.Address != ata {
+			t.Errorf("Expected ATA %s, got %s", ata, status.Address)
+		}
+	})
+
+	t.Run("ExistingATA", func(t *testing.T) {
+		client := &fakeAccountClient{accounts: map[solana.PublicKey]uint64{mint: 1, ata: 2039280}}
+
+		status, err := EnsureATA(context.Background(), client, owner, mint)
+		if err != nil {
+			t.Fatalf("EnsureATA failed: %v", err)
+		}
+
+		if !status.Exists || status.NeedsCreation {
+			t.Error("Existing ATA should not need creation")
+		}
+		if status.RentLamports != 0 {
+			t.Errorf("Expected no extra rent, got %d", status.RentLamports)
+		}
+	})
+
+	t.Run("RentFallback", func(t *testing.T) {
+		client := &fakeAccountClient{accounts: map[solana.PublicKey]uint64{mint: 1}}
+
+		status, err := EnsureATA(context.Background(), client, owner, mint)
+		if err != nil {
+			t.Fatalf("EnsureATA failed: %v", err)
+		}
+
+		if status.RentLamports != DefaultTokenAccountRent {
+			t.Errorf("Expected default rent %d, got %d", DefaultTokenAccountRent, status.RentLamports)
+		}
+	})
+
+	t.Run("Token2022Mint", func(t *testing.T) {
+		ata2022, err := associatedTokenAddress(owner, mint, Token2022ProgramID)
+		if err != nil {
+			t.Fatalf("associatedTokenAddress failed: %v", err)
+		}
+		client := &fakeAccountClient{
+			accounts: map[solana.PublicKey]uint64{mint: 1, ata2022: 2039280},
+			owners:   map[solana.PublicKey]solana.PublicKey{mint: Token2022ProgramID},
+		}
+
+		status, err := EnsureATA(context.Background(), client, owner, mint)
+		if err != nil {
+			t.Fatalf("EnsureATA failed: %v", err)
+		}
+		if status.Address != ata2022 || !status.Exists {
+			t.Errorf("Expected the existing Token-2022 ATA %s, got %+v", ata2022, status)
+		}
+	})
+
+	t.Run("MissingMint", func(t *testing.T) {
+		client := &fakeAccountClient{accounts: map[solana.PublicKey]uint64{}}
+
+		if _, err := EnsureATA(context.Background(), client, owner, mint); err == nil {
+			t.Error("Expected an error when the mint can't be read")
+		}
+	})
+}
@@ -2,27 +2,70 @@ package trading
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"math"
+	"math/big"
 	"solana-orchestrator/api"
+	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
+// Token program owners scanned when balances come from RPC
+var (
+	TokenProgramID     = solana.TokenProgramID
+	Token2022ProgramID = solana.MustPublicKeyFromBase58("TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb")
+)
+
+// RPC token account retry policy
+const (
+	tokenAccountAttempts   = 3
+	tokenAccountRetryDelay = 250 * time.Millisecond
+)
+
+// TokenBalanceAPI is the part of api.Client used for token balances
+type TokenBalanceAPI interface {
+	GetWalletTokenBalances(ctx context.Context, walletAddress string) ([]api.WalletToken, error)
+}
+
+// BalanceClient is the subset of the RPC client needed for balance queries
+type BalanceClient interface {
+	AccountClient
+	GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error)
+	GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error)
+}
+
 // BalanceManager handles wallet balance queries
 type BalanceManager struct {
-	rpcClient *rpc.Client
-	wsClient  *WSClient
-	apiClient *api.Client
+	rpcClient  BalanceClient
+	wsClient   *WSClient
+	apiClient  TokenBalanceAPI // optional; adds metadata and covers RPC failures
+	retryDelay time.Duration
 }
 
-// NewBalanceManager creates a new balance manager
-func NewBalanceManager(rpcURL string, wsClient *WSClient, apiClient *api.Client) *BalanceManager {
+// NewBalanceManager creates a balance manager that reads token balances
+// straight from RPC
+func NewBalanceManager(rpcURL string, wsClient *WSClient) *BalanceManager {
 	return &BalanceManager{
-		rpcClient: rpc.New(rpcURL),
-		wsClient:  wsClient,
-		apiClient: apiClient,
+		rpcClient:  rpc.New(rpcURL),
+		wsClient:   wsClient,
+		retryDelay: tokenAccountRetryDelay,
+	}
+}
+
+// NewBalanceManagerWithAPI creates a balance manager that also asks the
+// Moralis API for token balances, which adds symbols, names and logos and
+// keeps balances available when RPC rejects getTokenAccountsByOwner.
+// A nil apiClient behaves like NewBalanceManager.
+func NewBalanceManagerWithAPI(rpcURL string, wsClient *WSClient, apiClient *api.Client) *BalanceManager {
+	bm := NewBalanceManager(rpcURL, wsClient)
+	if apiClient != nil {
+		bm.apiClient = apiClient
 	}
+	return bm
 }
 
 // Balance represents wallet balances
@@ -43,6 +86,26 @@ type TokenBalance struct {
 	Logo     string
 }
 
+// Portion returns pct percent of the raw amount, rounded down. 100
+// returns the exact balance so a full sell leaves no dust. The product is
+// computed in integers since balances above 2^53 lose digits as floats.
+func (tb TokenBalance) Portion(pct int) uint64 {
+	if pct >= 100 {
+		return tb.Amount
+	}
+	if pct <= 0 {
+		return 0
+	}
+	portion := new(big.Int).SetUint64(tb.Amount)
+	portion.Mul(portion, big.NewInt(int64(pct)))
+	return portion.Div(portion, big.NewInt(100)).Uint64()
+}
+
+// ToUI converts a raw amount of this token to UI units using its decimals
+func (tb TokenBalance) ToUI(raw uint64) float64 {
+	return rawToUI(raw, int(tb.Decimals))
+}
+
 // GetSOLBalance fetches SOL balance for a wallet
 func (bm *BalanceManager) GetSOLBalance(ctx context.Context, wallet solana.PublicKey) (uint64, error) {
 	balance, err := bm.rpcClient.GetBalance(ctx, wallet, rpc.CommitmentFinalized)
@@ -52,15 +115,45 @@ func (bm *BalanceManager) GetSOLBalance(ctx context.Context, wallet solana.Publi
 	return balance.Value, nil
 }
 
-// GetTokenBalances fetches all token balances for a wallet using Moralis API
+// GetTokenBalances fetches all token balances for a wallet. RPC is the
+// source of truth for amounts; when an API client is configured it is
+// queried alongside and either source covers for the other failing.
 func (bm *BalanceManager) GetTokenBalances(ctx context.Context, wallet solana.PublicKey) ([]TokenBalance, error) {
 	if bm.apiClient == nil {
-		return nil, fmt.Errorf("API client not initialized")
+		return bm.getTokenBalancesRPC(ctx, wallet)
+	}
+
+	type apiResult struct {
+		balances []TokenBalance
+		err      error
 	}
+	apiDone := make(chan apiResult, 1)
+	go func() {
+		balances, err := bm.getTokenBalancesAPI(ctx, wallet)
+		apiDone <- apiResult{balances, err}
+	}()
 
+	rpcBalances, rpcErr := bm.getTokenBalancesRPC(ctx, wallet)
+	fromAPI := <-apiDone
+
+	switch {
+	case rpcErr != nil && fromAPI.err != nil:
+		return nil, fmt.Errorf("failed to get token balances: rpc: %v; api: %w", rpcErr, fromAPI.err)
+	case rpcErr != nil:
+		log.Printf("⚠️ Token accounts via RPC failed, using API balances: %v", rpcErr)
+		return fromAPI.balances, nil
+	case fromAPI.err != nil:
+		log.Printf("⚠️ Token balances via API failed, using RPC only: %v", fromAPI.err)
+		return rpcBalances, nil
+	}
+	return mergeTokenBalances(rpcBalances, fromAPI.balances), nil
+}
+
+// getTokenBalancesAPI fetches token balances from the Moralis API
+func (bm *BalanceManager) getTokenBalancesAPI(ctx context.Context, wallet solana.PublicKey) ([]TokenBalance, error) {
 	tokens, err := bm.apiClient.GetWalletTokenBalances(ctx, wallet.String())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get token balances: %w", err)
+		return nil, err
 	}
 
 	tokenBalances := make([]TokenBalance, 0, len(tokens))
@@ -69,7 +162,7 @@ func (bm *BalanceManager) GetTokenBalances(ctx context.Context, wallet solana.Pu
 		amount := parseUint64(t.Balance)
 
 		// Calculate UI Amount
-		uiAmount := float64(amount) / float64(pow10(t.Decimals))
+		uiAmount := rawToUI(amount, t.Decimals)
 
 		mint, _ := solana.PublicKeyFromBase58(t.TokenAddress)
 
@@ -87,6 +180,118 @@ func (bm *BalanceManager) GetTokenBalances(ctx context.Context, wallet solana.Pu
 	return tokenBalances, nil
 }
 
+// mergeTokenBalances keeps RPC amounts, fills in metadata from the API
+// and appends tokens only the API reported
+func mergeTokenBalances(fromRPC, fromAPI []TokenBalance) []TokenBalance {
+	byMint := make(map[solana.PublicKey]TokenBalance, len(fromAPI))
+	for _, tb := range fromAPI {
+		byMint[tb.Mint] = tb
+	}
+
+	merged := make([]TokenBalance, 0, len(fromRPC)+len(fromAPI))
+	seen := make(map[solana.PublicKey]bool, len(fromRPC))
+	for _, tb := range fromRPC {
+		if meta, ok := byMint[tb.Mint]; ok {
+			tb.Symbol, tb.Name, tb.Logo = meta.Symbol, meta.Name, meta.Logo
+		}
+		seen[tb.Mint] = true
+		merged = append(merged, tb)
+	}
+	for _, tb := range fromAPI {
+		if !seen[tb.Mint] && tb.Amount > 0 {
+			merged = append(merged, tb)
+		}
+	}
+	return merged
+}
+
+// getTokenBalancesRPC lists the wallet's SPL and Token-2022 accounts with
+// getTokenAccountsByOwner. Empty accounts are skipped.
+func (bm *BalanceManager) getTokenBalancesRPC(ctx context.Context, wallet solana.PublicKey) ([]TokenBalance, error) {
+	var tokenBalances []TokenBalance
+	for _, program := range []solana.PublicKey{TokenProgramID, Token2022ProgramID} {
+		result, err := bm.getTokenAccounts(ctx, wallet, program)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token accounts: %w", err)
+		}
+
+		for _, account := range result.Value {
+			if account == nil || account.Account.Data == nil {
+				continue
+			}
+			tb, err := parseTokenAccount(account.Account.Data.GetRawJSON())
+			if err != nil {
+				return nil, fmt.Errorf("token account %s: %w", account.Pubkey, err)
+			}
+			if tb.Amount > 0 {
+				tokenBalances = append(tokenBalances, tb)
+			}
+		}
+	}
+	return tokenBalances, nil
+}
+
+// getTokenAccounts calls getTokenAccountsByOwner for one token program,
+// retrying with a linear backoff
+func (bm *BalanceManager) getTokenAccounts(ctx context.Context, wallet, program solana.PublicKey) (*rpc.GetTokenAccountsResult, error) {
+	var lastErr error
+	for attempt := 0; attempt < tokenAccountAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * bm.retryDelay):
+			}
+		}
+
+		result, err := bm.rpcClient.GetTokenAccountsByOwner(ctx, wallet,
+			&rpc.GetTokenAccountsConfig{ProgramId: &program},
+			&rpc.GetTokenAccountsOpts{Encoding: solana.EncodingJSONParsed},
+		)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// parsedTokenAccount is the jsonParsed form of an SPL token account
+type parsedTokenAccount struct {
+	Parsed struct {
+		Info struct {
+			Mint        string `json:"mint"`
+			TokenAmount struct {
+				Amount   string `json:"amount"`
+				Decimals uint8  `json:"decimals"`
+			} `json:"tokenAmount"`
+		} `json:"info"`
+	} `json:"parsed"`
+}
+
+// parseTokenAccount decodes a jsonParsed token account into a TokenBalance
+func parseTokenAccount(raw []byte) (TokenBalance, error) {
+	var parsed parsedTokenAccount
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return TokenBalance{}, fmt.Errorf("failed to decode jsonParsed data: %w", err)
+	}
+
+	info := parsed.Parsed.Info
+	mint, err := solana.PublicKeyFromBase58(info.Mint)
+	if err != nil {
+		return TokenBalance{}, fmt.Errorf("invalid mint %q: %w", info.Mint, err)
+	}
+
+	amount := parseUint64(info.TokenAmount.Amount)
+	decimals := info.TokenAmount.Decimals
+	return TokenBalance{
+		Mint:     mint,
+		Amount:   amount,
+		Decimals: decimals,
+		UIAmount: rawToUI(amount, int(decimals)),
+	}, nil
+}
+
 // GetFullBalance retrieves both SOL and token balances
 func (bm *BalanceManager) GetFullBalance(ctx context.Context, wallet solana.PublicKey) (*Balance, error) {
 	solBalance, err := bm.GetSOLBalance(ctx, wallet)
@@ -125,10 +330,12 @@ func FormatSOL(lamports uint64) float64 {
 	return float64(lamports) / 1e9
 }
 
-func pow10(n int) uint64 {
-	result := uint64(1)
-	for i := 0; i < n; i++ {
-		result *= 10
+// rawToUI converts a raw token amount to UI units. The divisor is a
+// float64 so tokens with more than 19 decimals, whose 10^decimals
+// overflows a uint64, still convert correctly.
+func rawToUI(raw uint64, decimals int) float64 {
+	if decimals <= 0 {
+		return float64(raw)
 	}
-	return result
+	return float64(raw) / math.Pow10(decimals)
 }
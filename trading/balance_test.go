@@ -0,0 +1,242 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"solana-orchestrator/api"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// fakeBalanceClient serves getTokenAccountsByOwner from canned jsonParsed
+// responses keyed by token program
+type fakeBalanceClient struct {
+	fakeAccountClient
+	lamports uint64
+	accounts map[solana.PublicKey]string // program -> JSON result
+	failures int                         // calls to fail before answering
+	calls    int
+}
+
+func (f *fakeBalanceClient) GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	return &rpc.GetBalanceResult{Value: f.lamports}, nil
+}
+
+func (f *fakeBalanceClient) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, fmt.Errorf("Method not found")
+	}
+	if opts == nil || opts.Encoding != solana.EncodingJSONParsed {
+		return nil, fmt.Errorf("expected jsonParsed encoding")
+	}
+	var out rpc.GetTokenAccountsResult
+	raw, ok := f.accounts[*conf.ProgramId]
+	if !ok {
+		raw = `{"context":{"slot":1},"value":[]}`
+	}
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// fakeTokenAPI stands in for the Moralis wallet tokens endpoint
+type fakeTokenAPI struct {
+	tokens []api.WalletToken
+	err    error
+}
+
+func (f *fakeTokenAPI) GetWalletTokenBalances(ctx context.Context, walletAddress string) ([]api.WalletToken, error) {
+	return f.tokens, f.err
+}
+
+// tokenAccountsJSON builds a getTokenAccountsByOwner result holding one
+// account per mint/amount pair
+func tokenAccountsJSON(decimals int, holdings map[string]string) string {
+	value := "["
+	for mint, amount := range holdings {
+		if value != "[" {
+			value += ","
+		}
+		value += fmt.Sprintf(`{"pubkey":"11111111111111111111111111111111","account":{"lamports":2039280,"owner":"TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA","executable":false,"rentEpoch":0,"data":{"program":"spl-token","space":165,"parsed":{"type":"account","info":{"mint":%q,"tokenAmount":{"amount":%q,"decimals":%d}}}}}}`,
+			mint, amount, decimals)
+	}
+	return `{"context":{"slot":1},"value":` + value + `]}`
+}
+
+// TestTokenBalancesWithoutAPI tests the RPC path used when no API client
+// is configured
+func TestTokenBalancesWithoutAPI(t *testing.T) {
+	usdc := "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	bonk := "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263"
+	wallet := solana.MustPublicKeyFromBase58("G4vTBDnAbBre4wqTpibXbLmwdVtFAbFCr2DM8t22UrmM")
+
+	client := &fakeBalanceClient{
+		lamports: 1_500_000_000,
+		accounts: map[solana.PublicKey]string{
+			TokenProgramID:     tokenAccountsJSON(6, map[string]string{usdc: "2500000", bonk: "0"}),
+			Token2022ProgramID: tokenAccountsJSON(9, map[string]string{bonk: "42000000000"}),
+		},
+	}
+	bm := &BalanceManager{rpcClient: client}
+
+	balances, err := bm.GetTokenBalances(context.Background(), wallet)
+	if err != nil {
+		t.Fatalf("GetTokenBalances failed: %v", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("Expected both token programs to be queried, got %d calls", client.calls)
+	}
+	if len(balances) != 2 {
+		t.Fatalf("Expected 2 non-empty balances, got %d", len(balances))
+	}
+
+	byMint := make(map[string]TokenBalance)
+	for _, tb := range balances {
+		byMint[tb.Mint.String()] = tb
+	}
+	if tb := byMint[usdc]; tb.Amount != 2500000 || tb.Decimals != 6 || tb.UIAmount != 2.5 {
+		t.Errorf("Unexpected USDC balance %+v", tb)
+	}
+	if tb := byMint[bonk]; tb.Amount != 42000000000 || tb.Decimals != 9 || tb.UIAmount != 42 {
+		t.Errorf("Unexpected Token-2022 balance %+v", tb)
+	}
+
+	full, err := bm.GetFullBalance(context.Background(), wallet)
+	if err != nil {
+		t.Fatalf("GetFullBalance failed: %v", err)
+	}
+	if full.SOLBalance != 1_500_000_000 || len(full.TokenBalances) != 2 {
+		t.Errorf("Unexpected full balance %+v", full)
+	}
+}
+
+// TestTokenBalancesFallback tests retries and the RPC/API fallback used
+// when the RPC rejects getTokenAccountsByOwner (e.g. free-tier plans)
+func TestTokenBalancesFallback(t *testing.T) {
+	usdc := "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	bonk := "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263"
+	wallet := solana.MustPublicKeyFromBase58("G4vTBDnAbBre4wqTpibXbLmwdVtFAbFCr2DM8t22UrmM")
+	rpcAccounts := map[solana.PublicKey]string{
+		TokenProgramID: tokenAccountsJSON(6, map[string]string{usdc: "2500000"}),
+	}
+	moralis := &fakeTokenAPI{tokens: []api.WalletToken{
+		{TokenAddress: usdc, Balance: "2400000", Decimals: 6, Symbol: "USDC", Name: "USD Coin"},
+		{TokenAddress: bonk, Balance: "1000", Decimals: 5, Symbol: "BONK"},
+	}}
+
+	t.Run("RetriesTransientRPCErrors", func(t *testing.T) {
+		client := &fakeBalanceClient{accounts: rpcAccounts, failures: tokenAccountAttempts - 1}
+		bm := &BalanceManager{rpcClient: client, retryDelay: time.Millisecond}
+
+		balances, err := bm.GetTokenBalances(context.Background(), wallet)
+		if err != nil || len(balances) != 1 {
+			t.Fatalf("Expected retry to succeed, got %v, %v", balances, err)
+		}
+	})
+
+	t.Run("FallsBackToAPI", func(t *testing.T) {
+		client := &fakeBalanceClient{accounts: rpcAccounts, failures: 100}
+		bm := &BalanceManager{rpcClient: client, apiClient: moralis, retryDelay: time.Millisecond}
+
+		balances, err := bm.GetTokenBalances(context.Background(), wallet)
+		if err != nil {
+			t.Fatalf("Expected API fallback, got %v", err)
+		}
+		if len(balances) != 2 || balances[0].Symbol != "USDC" {
+			t.Errorf("Expected API balances, got %+v", balances)
+		}
+	})
+
+	t.Run("MergesRPCAndAPI", func(t *testing.T) {
+		client := &fakeBalanceClient{accounts: rpcAccounts}
+		bm := &BalanceManager{rpcClient: client, apiClient: moralis, retryDelay: time.Millisecond}
+
+		balances, err := bm.GetTokenBalances(context.Background(), wallet)
+		if err != nil {
+			t.Fatalf("GetTokenBalances failed: %v", err)
+		}
+		if len(balances) != 2 {
+			t.Fatalf("Expected 2 merged balances, got %+v", balances)
+		}
+		if balances[0].Amount != 2500000 || balances[0].Symbol != "USDC" {
+			t.Errorf("Expected RPC amount with API metadata, got %+v", balances[0])
+		}
+		if balances[1].Mint.String() != bonk {
+			t.Errorf("Expected API-only token to be kept, got %+v", balances[1])
+		}
+	})
+
+	t.Run("BothFail", func(t *testing.T) {
+		client := &fakeBalanceClient{accounts: rpcAccounts, failures: 100}
+		bm := &BalanceManager{rpcClient: client, apiClient: &fakeTokenAPI{err: fmt.Errorf("401")}, retryDelay: time.Millisecond}
+
+		if _, err := bm.GetTokenBalances(context.Background(), wallet); err == nil {
+			t.Error("Expected an error when RPC and API both fail")
+		}
+	})
+}
+
+// TestTokenBalanceUnits tests decimal-aware conversions used by the sell flow
+func TestTokenBalanceUnits(t *testing.T) {
+	usdc := TokenBalance{Amount: 2_500_001, Decimals: 6}
+
+	if got := usdc.ToUI(usdc.Amount); got != 2.500001 {
+		t.Errorf("Expected 2.500001, got %v", got)
+	}
+	if got := usdc.Portion(50); got != 1_250_000 {
+		t.Errorf("Expected half of the raw amount, got %d", got)
+	}
+	if got := usdc.Portion(100); got != usdc.Amount {
+		t.Errorf("Expected full sell to use the exact balance, got %d", got)
+	}
+	if got := usdc.Portion(0); got != 0 {
+		t.Errorf("Expected 0, got %d", got)
+	}
+
+	whale := TokenBalance{Amount: math.MaxUint64, Decimals: 18}
+	if got := whale.Portion(50); got != math.MaxUint64/2 {
+		t.Errorf("Expected exact half of a max balance, got %d", got)
+	}
+	if got := whale.Portion(99); got >= whale.Amount {
+		t.Errorf("Expected a 99%% portion below the balance, got %d", got)
+	}
+}
+
+// TestRawToUIHighDecimals tests conversions where 10^decimals doesn't fit
+// in a uint64
+func TestRawToUIHighDecimals(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      uint64
+		decimals int
+		want     float64
+	}{
+		{"NoDecimals", 42, 0, 42},
+		{"SOL", 1_500_000_000, 9, 1.5},
+		{"NineteenDecimals", 5_000_000_000_000_000_000, 19, 0.5},
+		{"TwentyDecimals", 18_000_000_000_000_000_000, 20, 0.18},
+		{"TwentyFourDecimals", 3_000_000_000_000_000_000, 24, 0.000003},
+		{"MaxUint64Amount", math.MaxUint64, 30, float64(math.MaxUint64) / 1e30},
+		{"NegativeDecimals", 7, -1, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rawToUI(tt.raw, tt.decimals)
+			if math.Abs(got-tt.want) > tt.want*1e-12 {
+				t.Errorf("rawToUI(%d, %d) = %v, want %v", tt.raw, tt.decimals, got, tt.want)
+			}
+		})
+	}
+
+	// u8 decimals above 308 can't happen, but 255 must not blow up either
+	if got := (TokenBalance{Amount: 1, Decimals: 255}).ToUI(1); got <= 0 || math.IsInf(got, 0) {
+		t.Errorf("Expected a tiny positive amount for 255 decimals, got %v", got)
+	}
+}
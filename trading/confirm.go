@@ -0,0 +1,80 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ConfirmPollInterval is how often a submitted transaction is polled
+var ConfirmPollInterval = 2 * time.Second
+
+// ConfirmationClient is the subset of the RPC client needed to follow a
+// submitted transaction
+type ConfirmationClient interface {
+	GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, transactionSignatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error)
+	GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+}
+
+// WaitForConfirmationUntilBlockHeight polls sig until it is confirmed,
+// fails on-chain, or the chain passes lastValidBlockHeight without it
+// landing, which returns ErrTransactionExpired. A lastValidBlockHeight of
+// 0 disables the expiry check, leaving only ctx as the deadline.
+func WaitForConfirmationUntilBlockHeight(ctx context.Context, client ConfirmationClient, sig solana.Signature, lastValidBlockHeight uint64) (rpc.ConfirmationStatusType, error) {
+	ticker := time.NewTicker(ConfirmPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("not confirmed: %w", ctx.Err())
+		case <-ticker.C:
+		}
+
+		status, found, err := signatureStatus(ctx, client, sig)
+		if err != nil {
+			continue // transient RPC error
+		}
+		if found {
+			if status.Err != nil {
+				return "", fmt.Errorf("transaction failed: %v", status.Err)
+			}
+			if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return status.ConfirmationStatus, nil
+			}
+			continue // processed, wait for confirmation
+		}
+		if lastValidBlockHeight == 0 {
+			continue
+		}
+
+		height, err := client.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+		if err != nil || height <= lastValidBlockHeight {
+			continue
+		}
+
+		// It may have landed between the two calls, so look once more
+		// before giving up on it
+		status, found, err = signatureStatus(ctx, client, sig)
+		if err != nil || found {
+			continue
+		}
+		return "", fmt.Errorf("%w: block height %d passed %d", ErrTransactionExpired, height, lastValidBlockHeight)
+	}
+}
+
+// signatureStatus returns the status of sig and whether the cluster has
+// seen it at all
+func signatureStatus(ctx context.Context, client ConfirmationClient, sig solana.Signature) (*rpc.SignatureStatusesResult, bool, error) {
+	out, err := client.GetSignatureStatuses(ctx, false, sig)
+	if err != nil {
+		return nil, false, err
+	}
+	if out == nil || len(out.Value) == 0 || out.Value[0] == nil {
+		return nil, false, nil
+	}
+	return out.Value[0], true, nil
+}
@@ -0,0 +1,84 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// fakeConfirmationClient reports a fixed status and a block height that
+// advances by one on every call
+type fakeConfirmationClient struct {
+	status *rpc.SignatureStatusesResult
+	height uint64
+}
+
+func (f *fakeConfirmationClient) GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, transactionSignatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+	return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{f.status}}, nil
+}
+
+func (f *fakeConfirmationClient) GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	f.height++
+	return f.height, nil
+}
+
+// TestWaitForConfirmationUntilBlockHeight tests confirmation, on-chain
+// failure and blockhash expiry
+func TestWaitForConfirmationUntilBlockHeight(t *testing.T) {
+	orig := ConfirmPollInterval
+	defer func() { ConfirmPollInterval = orig }()
+	ConfirmPollInterval = time.Millisecond
+
+	var sig solana.Signature
+
+	t.Run("Confirmed", func(t *testing.T) {
+		client := &fakeConfirmationClient{status: &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}}
+
+		status, err := WaitForConfirmationUntilBlockHeight(context.Background(), client, sig, 100)
+		if err != nil {
+			t.Fatalf("Expected confirmation, got %v", err)
+		}
+		if status != rpc.ConfirmationStatusConfirmed {
+			t.Errorf("Expected confirmed, got %q", status)
+		}
+	})
+
+	t.Run("FailedOnChain", func(t *testing.T) {
+		client := &fakeConfirmationClient{status: &rpc.SignatureStatusesResult{Err: "InstructionError"}}
+
+		_, err := WaitForConfirmationUntilBlockHeight(context.Background(), client, sig, 100)
+		if err == nil || errors.Is(err, ErrTransactionExpired) {
+			t.Errorf("Expected an on-chain failure, got %v", err)
+		}
+	})
+
+	t.Run("ExpiredPastBlockHeight", func(t *testing.T) {
+		client := &fakeConfirmationClient{height: 95}
+
+		_, err := WaitForConfirmationUntilBlockHeight(context.Background(), client, sig, 100)
+		if !errors.Is(err, ErrTransactionExpired) {
+			t.Fatalf("Expected ErrTransactionExpired, got %v", err)
+		}
+		if client.height <= 100 {
+			t.Errorf("Gave up at block height %d, before the blockhash expired", client.height)
+		}
+	})
+
+	t.Run("NoBlockHeightWaitsForContext", func(t *testing.T) {
+		client := &fakeConfirmationClient{}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := WaitForConfirmationUntilBlockHeight(ctx, client, sig, 0)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected a context deadline, got %v", err)
+		}
+		if client.height != 0 {
+			t.Errorf("Block height should not be polled without a last valid height")
+		}
+	})
+}
@@ -29,6 +29,7 @@ type TokenInfo struct {
 	Change1h    float64
 	Change5m    float64
 	Liquidity   float64
+	MarketCap   float64 // USD, falls back to FDV when DexScreener has no market cap
 	Volume24h   float64
 	Buys5m      int
 	Sells5m     int
@@ -56,6 +57,8 @@ type dexPair struct {
 	Volume      volume      `json:"volume"`
 	PriceChange priceChange `json:"priceChange"`
 	Liquidity   liquidity   `json:"liquidity"`
+	MarketCap   float64     `json:"marketCap"`
+	FDV         float64     `json:"fdv"`
 }
 
 type baseToken struct {
@@ -124,6 +127,10 @@ func GetTokenInfo(ctx context.Context, tokenAddress string) (*TokenInfo, error)
 
 	// Use the first (most liquid) pair
 	pair := dexResp.Pairs[0]
+	marketCap := pair.MarketCap
+	if marketCap == 0 {
+		marketCap = pair.FDV
+	}
 
 	return &TokenInfo{
 		Address:     pair.BaseToken.Address,
@@ -136,6 +143,7 @@ func GetTokenInfo(ctx context.Context, tokenAddress string) (*TokenInfo, error)
 		Change1h:    pair.PriceChange.H1,
 		Change5m:    pair.PriceChange.M5,
 		Liquidity:   pair.Liquidity.USD,
+		MarketCap:   marketCap,
 		Volume24h:   pair.Volume.H24,
 		Buys5m:      pair.Txns.M5.Buys,
 		Sells5m:     pair.Txns.M5.Sells,
@@ -0,0 +1,97 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Trade failure classes. Errors from quoting and submission wrap one of
+// these so callers can tell them apart with errors.Is.
+var (
+	ErrInsufficientLiquidity = errors.New("insufficient liquidity")
+	ErrSlippageExceeded      = errors.New("slippage tolerance exceeded")
+	ErrQuoteFailed           = errors.New("quote failed")
+	ErrRPCUnavailable        = errors.New("rpc unavailable")
+	ErrTransactionExpired    = errors.New("transaction expired before landing")
+
+	// ErrNoRoute narrows ErrInsufficientLiquidity to pairs Jupiter can't
+	// route at all; errors wrapping it wrap ErrInsufficientLiquidity too
+	ErrNoRoute = errors.New("no trading route")
+)
+
+// Jupiter error codes meaning no usable route for the pair
+var noRouteCodes = map[string]bool{
+	"COULD_NOT_FIND_ANY_ROUTE": true,
+	"NO_ROUTES_FOUND":          true,
+	"TOKEN_NOT_TRADABLE":       true,
+	"MARKET_NOT_FOUND":         true,
+}
+
+// slippageMarkers identify a swap that failed on its minimum-out check.
+// 0x1771 is the Jupiter program's SlippageToleranceExceeded (6001).
+var slippageMarkers = []string{"0x1771", "SlippageToleranceExceeded", "slippage tolerance exceeded"}
+
+// jupiterError is the error body Jupiter returns with non-200 responses
+type jupiterError struct {
+	Error     string `json:"error"`
+	ErrorCode string `json:"errorCode"`
+}
+
+// classifyJupiterError wraps a non-200 Jupiter response in the matching
+// trade error
+func classifyJupiterError(status int, body []byte) error {
+	var parsed jupiterError
+	json.Unmarshal(body, &parsed)
+	detail := fmt.Sprintf("Jupiter API error %d: %s", status, strings.TrimSpace(string(body)))
+
+	switch {
+	case noRouteCodes[parsed.ErrorCode] || strings.Contains(strings.ToLower(parsed.Error), "route"):
+		return fmt.Errorf("%w: %w: %s", ErrInsufficientLiquidity, ErrNoRoute, detail)
+	case containsAny(string(body), slippageMarkers):
+		return fmt.Errorf("%w: %s", ErrSlippageExceeded, detail)
+	case status == 429 || status >= 500:
+		return fmt.Errorf("%w: %s", ErrRPCUnavailable, detail)
+	default:
+		return fmt.Errorf("%w: %s", ErrQuoteFailed, detail)
+	}
+}
+
+// ClassifySubmitError wraps an error from sending or confirming a
+// transaction in the matching trade error. Errors that already carry a
+// class, and unrecognised ones, are returned unchanged.
+func ClassifySubmitError(err error) error {
+	if err == nil || IsTradeError(err) {
+		return err
+	}
+
+	msg := err.Error()
+	var netErr net.Error
+	switch {
+	case containsAny(msg, slippageMarkers):
+		return fmt.Errorf("%w: %v", ErrSlippageExceeded, err)
+	case errors.As(err, &netErr), errors.Is(err, context.DeadlineExceeded),
+		containsAny(msg, []string{"connection refused", "no such host", "429", "502", "503", "504", "EOF"}):
+		return fmt.Errorf("%w: %v", ErrRPCUnavailable, err)
+	}
+	return err
+}
+
+// IsTradeError reports whether err carries one of the trade error classes
+func IsTradeError(err error) bool {
+	return errors.Is(err, ErrInsufficientLiquidity) || errors.Is(err, ErrSlippageExceeded) ||
+		errors.Is(err, ErrQuoteFailed) || errors.Is(err, ErrRPCUnavailable) ||
+		errors.Is(err, ErrTransactionExpired)
+}
+
+func containsAny(s string, needles []string) bool {
+	for _, n := range needles {
+		if strings.Contains(s, n) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestClassifyJupiterError tests classification of representative Jupiter
+// error responses
+func TestClassifyJupiterError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"NoRoute", 400, `{"error":"Could not find any route","errorCode":"COULD_NOT_FIND_ANY_ROUTE"}`, ErrInsufficientLiquidity},
+		{"NotTradable", 400, `{"error":"The token is not tradable","errorCode":"TOKEN_NOT_TRADABLE"}`, ErrInsufficientLiquidity},
+		{"Slippage", 400, `{"error":"Simulation failed: custom program error: 0x1771"}`, ErrSlippageExceeded},
+		{"RateLimited", 429, `Too Many Requests`, ErrRPCUnavailable},
+		{"ServerError", 502, `Bad Gateway`, ErrRPCUnavailable},
+		{"BadRequest", 400, `{"error":"Invalid amount"}`, ErrQuoteFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyJupiterError(tt.status, []byte(tt.body))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestNoRouteError tests that a missing route is told apart from other
+// quote failures while still counting as a lack of liquidity
+func TestNoRouteError(t *testing.T) {
+	noRoute := classifyJupiterError(400, []byte(`{"error":"Could not find any route","errorCode":"COULD_NOT_FIND_ANY_ROUTE"}`))
+	if !errors.Is(noRoute, ErrNoRoute) || !errors.Is(noRoute, ErrInsufficientLiquidity) || !IsTradeError(noRoute) {
+		t.Errorf("Expected a no-route liquidity error, got %v", noRoute)
+	}
+	// Older responses only say so in the message
+	if err := classifyJupiterError(400, []byte(`{"error":"No routes found for the input and output mints"}`)); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Expected ErrNoRoute from the message, got %v", err)
+	}
+	if err := classifyJupiterError(400, []byte(`{"error":"Simulation failed: custom program error: 0x1771"}`)); errors.Is(err, ErrNoRoute) {
+		t.Errorf("Expected slippage not to be a missing route, got %v", err)
+	}
+}
+
+// TestClassifySubmitError tests classification of RPC submission failures
+func TestClassifySubmitError(t *testing.T) {
+	slippage := fmt.Errorf("(*jsonrpc.RPCError){Message: \"Transaction simulation failed: Error processing Instruction 3: custom program error: 0x1771\"}")
+	if err := ClassifySubmitError(slippage); !errors.Is(err, ErrSlippageExceeded) {
+		t.Errorf("Expected slippage, got %v", err)
+	}
+
+	down := fmt.Errorf("Post \"https://rpc\": dial tcp: connection refused")
+	if err := ClassifySubmitError(down); !errors.Is(err, ErrRPCUnavailable) {
+		t.Errorf("Expected rpc unavailable, got %v", err)
+	}
+
+	other := fmt.Errorf("blockhash not found")
+	if err := ClassifySubmitError(other); err != other {
+		t.Errorf("Expected unknown errors unchanged, got %v", err)
+	}
+
+	classified := fmt.Errorf("%w: no route", ErrInsufficientLiquidity)
+	if err := ClassifySubmitError(classified); err != classified {
+		t.Errorf("Expected classified errors unchanged, got %v", err)
+	}
+}
+
+// TestQuoteErrors tests that quote helpers return classified errors
+func TestQuoteErrors(t *testing.T) {
+	orig := SharedClient.Transport
+	defer func() { SharedClient.Transport = orig }()
+	SharedClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 400,
+			Body:       io.NopCloser(strings.NewReader(`{"error":"No routes found","errorCode":"NO_ROUTES_FOUND"}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	if _, err := GetBuyQuote(context.Background(), "mint", 1000, 100); !errors.Is(err, ErrInsufficientLiquidity) {
+		t.Errorf("Expected insufficient liquidity from GetBuyQuote, got %v", err)
+	}
+	if _, err := GetSellQuote(context.Background(), "mint", 1000, 100); !errors.Is(err, ErrInsufficientLiquidity) {
+		t.Errorf("Expected insufficient liquidity from GetSellQuote, got %v", err)
+	}
+
+	SharedClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("dial tcp: connection refused")
+	})
+	if _, err := GetBuyQuote(context.Background(), "mint", 1000, 100); !errors.Is(err, ErrRPCUnavailable) {
+		t.Errorf("Expected rpc unavailable when Jupiter is unreachable, got %v", err)
+	}
+}
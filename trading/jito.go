@@ -81,10 +81,13 @@ func (jc *JitoClient) SubmitBundle(ctx context.Context, transactions []solana.Tr
 
 	resp, err := jc.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send bundle: %w", err)
+		return nil, fmt.Errorf("%w: failed to send bundle: %v", ErrRPCUnavailable, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("%w: bundle submission failed with status: %d", ErrRPCUnavailable, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("bundle submission failed with status: %d", resp.StatusCode)
 	}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
 )
 
 const JUPITER_QUOTE_API = "https://quote-api.jup.ag/v6/quote"
@@ -15,6 +17,30 @@ const JUPITER_SWAP_API = "https://lite-api.jup.ag/swap/v1/swap"
 // SOL mint address
 const SOL_MINT = "So11111111111111111111111111111111111111112"
 
+// USDC mint address, the quote target for Jupiter health checks
+const USDC_MINT = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+
+// pingQuoteLamports is the SOL amount quoted by PingJupiter
+const pingQuoteLamports = 10_000_000
+
+// Priority fee bounds for swap transactions, in lamports
+const (
+	DefaultPriorityFeeLamports int64 = 10000
+	MaxPriorityFeeLamports     int64 = 10_000_000 // 0.01 SOL, the highest settings option
+)
+
+// ClampPriorityFee returns lamports bounded to MaxPriorityFeeLamports, or
+// the default when unset or PriorityFeeAuto wasn't resolved
+func ClampPriorityFee(lamports int64) int64 {
+	if lamports <= 0 {
+		return DefaultPriorityFeeLamports
+	}
+	if lamports > MaxPriorityFeeLamports {
+		return MaxPriorityFeeLamports
+	}
+	return lamports
+}
+
 // JupiterQuote represents a quote response from Jupiter
 type JupiterQuote struct {
 	InputMint            string                   `json:"inputMint"`
@@ -24,10 +50,23 @@ type JupiterQuote struct {
 	OtherAmountThreshold string                   `json:"otherAmountThreshold"`
 	SwapMode             string                   `json:"swapMode"`
 	SlippageBps          int                      `json:"slippageBps"`
-	PriceImpactPct       string                   `json:"priceImpactPct"` // Changed to string to match API response
+	PriceImpactPct       string                   `json:"priceImpactPct"` // a fraction, "0.05" for 5%
 	RoutePlan            []map[string]interface{} `json:"routePlan"`
 }
 
+// PriceImpact returns how far the quoted swap moves the price, in percent.
+// Jupiter reports it as a decimal string fraction; an empty one is 0.
+func (q *JupiterQuote) PriceImpact() (float64, error) {
+	if q.PriceImpactPct == "" {
+		return 0, nil
+	}
+	fraction, err := strconv.ParseFloat(q.PriceImpactPct, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid price impact %q", ErrQuoteFailed, q.PriceImpactPct)
+	}
+	return math.Abs(fraction) * 100, nil
+}
+
 // PrioritizationFee represents the fee structure
 type PrioritizationFee struct {
 	PriorityLevelWithMaxLamports *PriorityLevel `json:"priorityLevelWithMaxLamports,omitempty"`
@@ -51,7 +90,7 @@ type JupiterSwapRequest struct {
 // JupiterSwapResponse represents the swap transaction
 type JupiterSwapResponse struct {
 	SwapTransaction      string `json:"swapTransaction"`
-	LastValidBlockHeight int64  `json:"lastValidBlockHeight"`
+	LastValidBlockHeight uint64 `json:"lastValidBlockHeight"` // the transaction can't land after this block
 }
 
 // GetBuyQuote gets a quote for buying a token with SOL
@@ -66,23 +105,30 @@ func GetBuyQuote(ctx context.Context, tokenMint string, solAmount uint64, slippa
 
 	resp, err := SharedClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get quote: %w", err)
+		return nil, fmt.Errorf("%w: failed to get quote: %v", ErrRPCUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Jupiter API error %d: %s", resp.StatusCode, string(body))
+		return nil, classifyJupiterError(resp.StatusCode, body)
 	}
 
 	var quote JupiterQuote
 	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
-		return nil, fmt.Errorf("failed to parse quote: %w", err)
+		return nil, fmt.Errorf("%w: failed to parse quote: %v", ErrQuoteFailed, err)
 	}
 
 	return &quote, nil
 }
 
+// PingJupiter checks the Jupiter quote API by quoting a small SOL to USDC
+// swap
+func PingJupiter(ctx context.Context) error {
+	_, err := GetBuyQuote(ctx, USDC_MINT, pingQuoteLamports, 50)
+	return err
+}
+
 // GetSellQuote gets a quote for selling a token for SOL
 func GetSellQuote(ctx context.Context, tokenMint string, tokenAmount uint64, slippageBps int) (*JupiterQuote, error) {
 	url := fmt.Sprintf("%s?inputMint=%s&outputMint=%s&amount=%d&slippageBps=%d",
@@ -95,18 +141,18 @@ func GetSellQuote(ctx context.Context, tokenMint string, tokenAmount uint64, sli
 
 	resp, err := SharedClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get quote: %w", err)
+		return nil, fmt.Errorf("%w: failed to get quote: %v", ErrRPCUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Jupiter API error %d: %s", resp.StatusCode, string(body))
+		return nil, classifyJupiterError(resp.StatusCode, body)
 	}
 
 	var quote JupiterQuote
 	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
-		return nil, fmt.Errorf("failed to parse quote: %w", err)
+		return nil, fmt.Errorf("%w: failed to parse quote: %v", ErrQuoteFailed, err)
 	}
 
 	return &quote, nil
@@ -115,10 +161,10 @@ func GetSellQuote(ctx context.Context, tokenMint string, tokenAmount uint64, sli
 // GetSwapTransaction gets the swap transaction from Jupiter
 func GetSwapTransaction(ctx context.Context, quote *JupiterQuote, userPublicKey string, priorityFee int64) (*JupiterSwapResponse, error) {
 	// Construct prioritization fee object
-	// Using "veryHigh" and the provided fee as max lamports
+	// Using "veryHigh" and the clamped fee as max lamports
 	feeObj := PrioritizationFee{
 		PriorityLevelWithMaxLamports: &PriorityLevel{
-			MaxLamports:   priorityFee,
+			MaxLamports:   ClampPriorityFee(priorityFee),
 			PriorityLevel: "veryHigh",
 		},
 	}
@@ -144,13 +190,13 @@ func GetSwapTransaction(ctx context.Context, quote *JupiterQuote, userPublicKey
 
 	resp, err := SharedClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get swap transaction: %w", err)
+		return nil, fmt.Errorf("%w: failed to get swap transaction: %v", ErrRPCUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Jupiter swap API error %d: %s", resp.StatusCode, string(body))
+		return nil, classifyJupiterError(resp.StatusCode, body)
 	}
 
 	var swapResp JupiterSwapResponse
@@ -0,0 +1,115 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc lets a test answer HTTP requests in-process
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestSwapPriorityFee tests that the configured priority fee reaches the
+// Jupiter swap request, clamped to sane bounds
+func TestSwapPriorityFee(t *testing.T) {
+	var sent JupiterSwapRequest
+	var fee struct {
+		PriorityLevelWithMaxLamports PriorityLevel `json:"priorityLevelWithMaxLamports"`
+	}
+
+	orig := SharedClient.Transport
+	defer func() { SharedClient.Transport = orig }()
+	SharedClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		if err := json.Unmarshal(body, &sent); err != nil {
+			t.Fatalf("Bad request body: %v", err)
+		}
+		raw, _ := json.Marshal(sent.PrioritizationFeeLamports)
+		json.Unmarshal(raw, &fee)
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"swapTransaction":"dHg=","lastValidBlockHeight":1}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tests := []struct {
+		name       string
+		configured int64
+		want       int64
+	}{
+		{"UserSetting", 1_000_000, 1_000_000},
+		{"UnsetUsesDefault", 0, DefaultPriorityFeeLamports},
+		{"ClampedToMax", 1_000_000_000, MaxPriorityFeeLamports},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GetSwapTransaction(context.Background(), &JupiterQuote{}, "wallet", tt.configured); err != nil {
+				t.Fatalf("GetSwapTransaction failed: %v", err)
+			}
+			if got := fee.PriorityLevelWithMaxLamports.MaxLamports; got != tt.want {
+				t.Errorf("Expected maxLamports %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestPingJupiter tests that an unhealthy quote API is reported as
+// unavailable
+func TestPingJupiter(t *testing.T) {
+	orig := SharedClient.Transport
+	defer func() { SharedClient.Transport = orig }()
+
+	status := 200
+	SharedClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if !strings.Contains(req.URL.RawQuery, "outputMint="+USDC_MINT) {
+			t.Errorf("Expected a SOL to USDC quote, got %s", req.URL.RawQuery)
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(`{"outAmount":"1500000"}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	if err := PingJupiter(context.Background()); err != nil {
+		t.Errorf("Expected healthy API, got %v", err)
+	}
+
+	status = 503
+	if err := PingJupiter(context.Background()); !errors.Is(err, ErrRPCUnavailable) {
+		t.Errorf("Expected ErrRPCUnavailable, got %v", err)
+	}
+}
+
+func TestQuotePriceImpact(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    float64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"0.0125", 1.25, false},
+		{"-0.3", 30, false}, // reported negative on some sells
+		{"n/a", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := (&JupiterQuote{PriceImpactPct: tt.raw}).PriceImpact()
+		if (err != nil) != tt.wantErr || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("PriceImpact(%q) = %g, %v; expected %g", tt.raw, got, err, tt.want)
+		}
+		if tt.wantErr && !errors.Is(err, ErrQuoteFailed) {
+			t.Errorf("Expected ErrQuoteFailed for %q, got %v", tt.raw, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Payment verification errors
+var (
+	ErrPaymentNotFound     = errors.New("transaction not found")
+	ErrPaymentFailed       = errors.New("transaction failed on-chain")
+	ErrPaymentNoTransfer   = errors.New("transaction did not pay the treasury")
+	ErrPaymentTooOld       = errors.New("transaction is too old")
+	ErrPaymentInvalidInput = errors.New("invalid transaction signature")
+	ErrPaymentNoReference  = errors.New("transaction memo does not carry the payment reference")
+	ErrPaymentAmount       = errors.New("payment does not match a package price")
+	ErrPaymentReplayed     = errors.New("transaction was already credited")
+)
+
+// TransactionClient is the subset of the RPC client needed to verify payments
+type TransactionClient interface {
+	GetTransaction(ctx context.Context, txSig solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+}
+
+// memoProgramV1 is the original Memo program, which some wallets still use
+var memoProgramV1 = solana.MustPublicKeyFromBase58("Memo1UhkJRfHyvLMcVucJwxXeuD728EqVDDwQDxFMNo")
+
+// PaymentReceipt describes a verified SOL transfer to the treasury
+type PaymentReceipt struct {
+	Signature string
+	Payer     solana.PublicKey
+	Lamports  uint64
+	BlockTime time.Time
+}
+
+// PaymentCheck is what a transaction must match to count as a user's
+// payment
+type PaymentCheck struct {
+	Treasury solana.PublicKey
+	// Reference is unique to the user and must appear as a memo in the
+	// transaction, so nobody can claim a payment they saw on-chain
+	Reference string
+	// Prices are the accepted amounts in lamports. Anything else is under-
+	// or overpaid and rejected rather than credited.
+	Prices []uint64
+	// MaxAge rejects older transactions when non-zero
+	MaxAge time.Duration
+	// Processed reports whether a signature was already credited
+	Processed func(signature string) (bool, error)
+}
+
+// VerifySOLPayment fetches a finalized transaction and checks that it
+// carries the user's reference and paid the treasury exactly one of the
+// accepted prices
+func VerifySOLPayment(ctx context.Context, client TransactionClient, signature string, check PaymentCheck) (*PaymentReceipt, error) {
+	sig, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		return nil, ErrPaymentInvalidInput
+	}
+
+	if check.Processed != nil {
+		processed, err := check.Processed(signature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check payment: %w", err)
+		}
+		if processed {
+			return nil, ErrPaymentReplayed
+		}
+	}
+
+	maxVersion := uint64(0)
+	result, err := client.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentFinalized,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && result == nil) {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if result.Meta == nil || result.Transaction == nil {
+		return nil, ErrPaymentNotFound
+	}
+	if result.Meta.Err != nil {
+		return nil, ErrPaymentFailed
+	}
+
+	receipt := &PaymentReceipt{Signature: signature}
+	if result.BlockTime != nil {
+		receipt.BlockTime = result.BlockTime.Time()
+		if check.MaxAge > 0 && time.Since(receipt.BlockTime) > check.MaxAge {
+			return nil, ErrPaymentTooOld
+		}
+	}
+
+	tx, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	if tx == nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	// Balances are indexed by static keys followed by lookup-table keys
+	keys := append(solana.PublicKeySlice{}, tx.Message.AccountKeys...)
+	keys = append(keys, result.Meta.LoadedAddresses.Writable...)
+	keys = append(keys, result.Meta.LoadedAddresses.ReadOnly...)
+
+	if len(keys) == 0 {
+		return nil, ErrPaymentNoTransfer
+	}
+	receipt.Payer = keys[0]
+	if !hasMemo(tx, keys, check.Reference) {
+		return nil, ErrPaymentNoReference
+	}
+
+	for i, key := range keys {
+		if !key.Equals(check.Treasury) {
+			continue
+		}
+		if i >= len(result.Meta.PreBalances) || i >= len(result.Meta.PostBalances) {
+			break
+		}
+		pre, post := result.Meta.PreBalances[i], result.Meta.PostBalances[i]
+		if post > pre {
+			receipt.Lamports = post - pre
+		}
+		break
+	}
+
+	if receipt.Lamports == 0 {
+		return nil, ErrPaymentNoTransfer
+	}
+	for _, price := range check.Prices {
+		if receipt.Lamports == price {
+			return receipt, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: received %d lamports", ErrPaymentAmount, receipt.Lamports)
+}
+
+// hasMemo reports whether any top-level instruction of tx is a memo equal
+// to reference
+func hasMemo(tx *solana.Transaction, keys solana.PublicKeySlice, reference string) bool {
+	if reference == "" {
+		return false
+	}
+	for _, inst := range tx.Message.Instructions {
+		if int(inst.ProgramIDIndex) >= len(keys) {
+			continue
+		}
+		program := keys[inst.ProgramIDIndex]
+		if !program.Equals(solana.MemoProgramID) && !program.Equals(memoProgramV1) {
+			continue
+		}
+		if strings.TrimSpace(string(inst.Data)) == reference {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package trading
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// fakeTransactions serves transactions by signature
+type fakeTransactions map[solana.Signature]*rpc.GetTransactionResult
+
+func (f fakeTransactions) GetTransaction(_ context.Context, sig solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	result, ok := f[sig]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	return result, nil
+}
+
+// transferResult builds a finalized transaction moving lamports from payer
+// to recipient, with memo attached when it's not empty
+func transferResult(t *testing.T, payer, recipient solana.PublicKey, lamports uint64, memo string) *rpc.GetTransactionResult {
+	t.Helper()
+	instructions := []solana.Instruction{system.NewTransferInstruction(lamports, payer, recipient).Build()}
+	if memo != "" {
+		instructions = append(instructions, solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(payer).SIGNER()}, []byte(memo)))
+	}
+	tx, err := solana.NewTransaction(
+		instructions,
+		solana.Hash{},
+		solana.TransactionPayer(payer),
+	)
+	if err != nil {
+		t.Fatalf("Failed to build transaction: %v", err)
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode transaction: %v", err)
+	}
+	encoded, _ := json.Marshal([]string{base64.StdEncoding.EncodeToString(raw), "base64"})
+	envelope := new(rpc.TransactionResultEnvelope)
+	if err := envelope.UnmarshalJSON(encoded); err != nil {
+		t.Fatalf("Failed to wrap transaction: %v", err)
+	}
+
+	// Keys are payer, recipient, then the programs
+	const fee = 5000
+	pre := make([]uint64, len(tx.Message.AccountKeys))
+	post := make([]uint64, len(tx.Message.AccountKeys))
+	pre[0], post[0] = 10_000_000_000, 10_000_000_000-lamports-fee
+	post[1] = lamports
+	blockTime := solana.UnixTimeSeconds(time.Now().Unix())
+	return &rpc.GetTransactionResult{
+		BlockTime:   &blockTime,
+		Transaction: envelope,
+		Meta: &rpc.TransactionMeta{
+			PreBalances:  pre,
+			PostBalances: post,
+		},
+	}
+}
+
+// TestVerifySOLPayment tests which transactions count as a user's payment
+func TestVerifySOLPayment(t *testing.T) {
+	treasury := solana.NewWallet().PublicKey()
+	user := solana.NewWallet().PublicKey()
+	stranger := solana.NewWallet().PublicKey()
+	const price = 100_000_000
+	const ref, otherRef = "topup-42", "topup-43"
+
+	sigs := map[string]solana.Signature{}
+	client := fakeTransactions{}
+	add := func(name string, result *rpc.GetTransactionResult) {
+		var sig solana.Signature
+		copy(sig[:], name)
+		sigs[name] = sig
+		client[sig] = result
+	}
+	add("paid", transferResult(t, user, treasury, price, ref))
+	add("credited", transferResult(t, user, treasury, price, ref))
+	add("wrong recipient", transferResult(t, user, stranger, price, ref))
+	add("no memo", transferResult(t, user, treasury, price, ""))
+	add("other user's memo", transferResult(t, stranger, treasury, price, otherRef))
+	add("underpaid", transferResult(t, user, treasury, price-1, ref))
+	add("overpaid", transferResult(t, user, treasury, 2*price, ref))
+	failed := transferResult(t, user, treasury, price, ref)
+	failed.Meta.Err = map[string]interface{}{"InstructionError": []interface{}{0, "Custom"}}
+	add("failed", failed)
+	old := transferResult(t, user, treasury, price, ref)
+	oldTime := solana.UnixTimeSeconds(time.Now().Add(-48 * time.Hour).Unix())
+	old.BlockTime = &oldTime
+	add("old", old)
+	var missing solana.Signature
+	copy(missing[:], "missing")
+	sigs["missing"] = missing
+
+	check := PaymentCheck{
+		Treasury:  treasury,
+		Reference: ref,
+		Prices:    []uint64{price, 4 * price},
+		MaxAge:    24 * time.Hour,
+		Processed: func(signature string) (bool, error) {
+			return signature == sigs["credited"].String(), nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{"paid", nil},
+		{"credited", ErrPaymentReplayed},
+		{"wrong recipient", ErrPaymentNoTransfer},
+		{"no memo", ErrPaymentNoReference},
+		{"other user's memo", ErrPaymentNoReference},
+		{"underpaid", ErrPaymentAmount},
+		{"overpaid", ErrPaymentAmount},
+		{"failed", ErrPaymentFailed},
+		{"old", ErrPaymentTooOld},
+		{"missing", ErrPaymentNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt, err := VerifySOLPayment(context.Background(), client, sigs[tt.name].String(), check)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if receipt.Lamports != price || !receipt.Payer.Equals(user) {
+				t.Errorf("Unexpected receipt: %+v", receipt)
+			}
+		})
+	}
+
+	if _, err := VerifySOLPayment(context.Background(), client, "not a signature", check); !errors.Is(err, ErrPaymentInvalidInput) {
+		t.Errorf("Expected an invalid signature to be rejected, got %v", err)
+	}
+}
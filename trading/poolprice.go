@@ -0,0 +1,295 @@
+package trading
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// USDT mint address. Pools quoted in USDT or USDC are priced at $1 per
+// quote token.
+const USDT_MINT = "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"
+
+// ErrUnsupportedPool means a pool's price can't be derived from its
+// account data
+var ErrUnsupportedPool = errors.New("unsupported pool")
+
+// Raydium AMM v4 pool layout. Reserves live in the two vault token
+// accounts, not the pool account itself.
+const (
+	raydiumV4PoolSize      = 752
+	raydiumV4BaseDecimals  = 32
+	raydiumV4QuoteDecimals = 40
+	raydiumV4BaseVault     = 336
+	raydiumV4QuoteVault    = 368
+	raydiumV4BaseMint      = 400
+	raydiumV4QuoteMint     = 432
+)
+
+// Meteora DLMM LbPair layout, after the 8-byte account discriminator
+const (
+	dlmmActiveID   = 76
+	dlmmBinStep    = 80
+	dlmmTokenXMint = 88
+	dlmmTokenYMint = 120
+	dlmmMinSize    = 152
+)
+
+// SPL account layouts
+const (
+	tokenAccountAmount = 64 // u64 after mint and owner
+	mintDecimals       = 44 // u8 after mint authority and supply
+)
+
+// dlmmDiscriminator is the Anchor discriminator of an LbPair account
+var dlmmDiscriminator = anchorDiscriminator("LbPair")
+
+func anchorDiscriminator(account string) []byte {
+	sum := sha256.Sum256([]byte("account:" + account))
+	return sum[:8]
+}
+
+// PoolType identifies the program layout of a pool account
+type PoolType string
+
+// Pool layouts GetPoolPrice and PriceStream can decode
+const (
+	PoolUnknown     PoolType = ""
+	PoolRaydiumV4   PoolType = "raydium_v4"
+	PoolMeteoraDLMM PoolType = "meteora_dlmm"
+)
+
+// DetectPoolType tells Raydium AMM v4 pools from Meteora DLMM pairs by
+// account size and discriminator
+func DetectPoolType(data []byte) PoolType {
+	switch {
+	case len(data) == raydiumV4PoolSize:
+		return PoolRaydiumV4
+	case IsDLMMPair(data):
+		return PoolMeteoraDLMM
+	}
+	return PoolUnknown
+}
+
+// PoolPrice is the on-chain price of a SOL-paired pool's token
+type PoolPrice struct {
+	Pool      string
+	Type      PoolType
+	TokenMint string
+	PriceSOL  float64 // SOL per whole token
+}
+
+// GetPoolPrice reads a Raydium AMM v4 pool or Meteora DLMM pair and
+// returns the price of its non-SOL token in SOL, without going through
+// DexScreener or Birdeye
+func GetPoolPrice(ctx context.Context, client AccountInfoClient, poolAddress string) (*PoolPrice, error) {
+	poolKey, err := solana.PublicKeyFromBase58(poolAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool address: %w", err)
+	}
+	data, err := readAccount(ctx, client, poolKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PoolPrice{Pool: poolAddress, Type: DetectPoolType(data)}
+	var price float64
+	switch result.Type {
+	case PoolRaydiumV4:
+		pool, err := DecodeRaydiumV4Pool(data)
+		if err != nil {
+			return nil, err
+		}
+		reserves, err := raydiumReserves(ctx, client, pool)
+		if err != nil {
+			return nil, err
+		}
+		price = ReservePrice(reserves[0], reserves[1], pool.BaseDecimals, pool.QuoteDecimals)
+		result.TokenMint, price, err = solSide(pool.BaseMint, pool.QuoteMint, price)
+		if err != nil {
+			return nil, err
+		}
+	case PoolMeteoraDLMM:
+		pair, err := DecodeDLMMPair(data)
+		if err != nil {
+			return nil, err
+		}
+		decimals, err := dlmmDecimals(ctx, client, pair)
+		if err != nil {
+			return nil, err
+		}
+		price = pair.Price(decimals[0], decimals[1])
+		result.TokenMint, price, err = solSide(pair.TokenXMint, pair.TokenYMint, price)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s is not a Raydium AMM v4 pool or Meteora DLMM pair", ErrUnsupportedPool, poolAddress)
+	}
+
+	if price <= 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+		return nil, fmt.Errorf("%w: pool %s has no usable reserves", ErrInsufficientLiquidity, poolAddress)
+	}
+	result.PriceSOL = price
+	return result, nil
+}
+
+// solSide picks the non-SOL token of a base/quote pair and turns the
+// base-in-quote price into that token's price in SOL
+func solSide(base, quote solana.PublicKey, price float64) (string, float64, error) {
+	switch SOL_MINT {
+	case quote.String():
+		return base.String(), price, nil
+	case base.String():
+		if price == 0 {
+			return quote.String(), 0, nil
+		}
+		return quote.String(), 1 / price, nil
+	}
+	return "", 0, fmt.Errorf("%w: pool is not paired with SOL", ErrUnsupportedPool)
+}
+
+// raydiumReserves reads the base and quote vault balances of a pool
+func raydiumReserves(ctx context.Context, client AccountInfoClient, pool *RaydiumPool) ([2]uint64, error) {
+	var reserves [2]uint64
+	for i, vault := range []solana.PublicKey{pool.BaseVault, pool.QuoteVault} {
+		data, err := readAccount(ctx, client, vault)
+		if err != nil {
+			return reserves, err
+		}
+		if reserves[i], err = DecodeTokenAccountAmount(data); err != nil {
+			return reserves, err
+		}
+	}
+	return reserves, nil
+}
+
+// dlmmDecimals reads the decimals of a pair's X and Y mints
+func dlmmDecimals(ctx context.Context, client AccountInfoClient, pair *DLMMPair) ([2]uint8, error) {
+	var decimals [2]uint8
+	for i, mint := range []solana.PublicKey{pair.TokenXMint, pair.TokenYMint} {
+		data, err := readAccount(ctx, client, mint)
+		if err != nil {
+			return decimals, err
+		}
+		if decimals[i], err = DecodeMintDecimals(data); err != nil {
+			return decimals, err
+		}
+	}
+	return decimals, nil
+}
+
+// readAccount fetches the raw data of an account
+func readAccount(ctx context.Context, client AccountInfoClient, account solana.PublicKey) ([]byte, error) {
+	info, err := client.GetAccountInfo(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account %s: %w", account, err)
+	}
+	if info == nil || info.Value == nil {
+		return nil, fmt.Errorf("account %s not found", account)
+	}
+	return info.Value.Data.GetBinary(), nil
+}
+
+// RaydiumPool is the part of a Raydium AMM v4 pool needed for pricing
+type RaydiumPool struct {
+	BaseMint      solana.PublicKey
+	QuoteMint     solana.PublicKey
+	BaseVault     solana.PublicKey
+	QuoteVault    solana.PublicKey
+	BaseDecimals  uint8
+	QuoteDecimals uint8
+}
+
+// DecodeRaydiumV4Pool reads the mints, vaults and decimals of a Raydium
+// AMM v4 pool account
+func DecodeRaydiumV4Pool(data []byte) (*RaydiumPool, error) {
+	if len(data) != raydiumV4PoolSize {
+		return nil, fmt.Errorf("%w: raydium pool is %d bytes, want %d", ErrUnsupportedPool, len(data), raydiumV4PoolSize)
+	}
+	return &RaydiumPool{
+		BaseMint:      solana.PublicKeyFromBytes(data[raydiumV4BaseMint : raydiumV4BaseMint+32]),
+		QuoteMint:     solana.PublicKeyFromBytes(data[raydiumV4QuoteMint : raydiumV4QuoteMint+32]),
+		BaseVault:     solana.PublicKeyFromBytes(data[raydiumV4BaseVault : raydiumV4BaseVault+32]),
+		QuoteVault:    solana.PublicKeyFromBytes(data[raydiumV4QuoteVault : raydiumV4QuoteVault+32]),
+		BaseDecimals:  uint8(binary.LittleEndian.Uint64(data[raydiumV4BaseDecimals:])),
+		QuoteDecimals: uint8(binary.LittleEndian.Uint64(data[raydiumV4QuoteDecimals:])),
+	}, nil
+}
+
+// DLMMPair is the part of a Meteora DLMM pair needed for pricing
+type DLMMPair struct {
+	TokenXMint solana.PublicKey
+	TokenYMint solana.PublicKey
+	ActiveID   int32
+	BinStep    uint16
+}
+
+// IsDLMMPair reports whether data is a Meteora DLMM LbPair account
+func IsDLMMPair(data []byte) bool {
+	return len(data) >= dlmmMinSize+32 && string(data[:8]) == string(dlmmDiscriminator)
+}
+
+// DecodeDLMMPair reads the active bin and mints of a Meteora DLMM pair
+func DecodeDLMMPair(data []byte) (*DLMMPair, error) {
+	if !IsDLMMPair(data) {
+		return nil, fmt.Errorf("%w: not a DLMM pair", ErrUnsupportedPool)
+	}
+	return &DLMMPair{
+		TokenXMint: solana.PublicKeyFromBytes(data[dlmmTokenXMint : dlmmTokenXMint+32]),
+		TokenYMint: solana.PublicKeyFromBytes(data[dlmmTokenYMint : dlmmTokenYMint+32]),
+		ActiveID:   int32(binary.LittleEndian.Uint32(data[dlmmActiveID:])),
+		BinStep:    binary.LittleEndian.Uint16(data[dlmmBinStep:]),
+	}, nil
+}
+
+// Price returns the UI price of token X in token Y at the active bin
+func (p *DLMMPair) Price(decimalsX, decimalsY uint8) float64 {
+	perLamport := math.Pow(1+float64(p.BinStep)/10000, float64(p.ActiveID))
+	return perLamport * math.Pow10(int(decimalsX)-int(decimalsY))
+}
+
+// ReservePrice returns the UI price of the base token in the quote token
+// implied by a constant-product pool's reserves
+func ReservePrice(baseReserve, quoteReserve uint64, baseDecimals, quoteDecimals uint8) float64 {
+	if baseReserve == 0 {
+		return 0
+	}
+	return rawToUI(quoteReserve, int(quoteDecimals)) / rawToUI(baseReserve, int(baseDecimals))
+}
+
+// DecodeTokenAccountAmount reads the raw balance of an SPL token account
+func DecodeTokenAccountAmount(data []byte) (uint64, error) {
+	if len(data) < tokenAccountAmount+8 {
+		return 0, fmt.Errorf("token account is %d bytes", len(data))
+	}
+	return binary.LittleEndian.Uint64(data[tokenAccountAmount:]), nil
+}
+
+// DecodeMintDecimals reads the decimals of an SPL mint account
+func DecodeMintDecimals(data []byte) (uint8, error) {
+	if len(data) <= mintDecimals {
+		return 0, fmt.Errorf("mint account is %d bytes", len(data))
+	}
+	return data[mintDecimals], nil
+}
+
+// quoteUSD converts a price quoted in quoteMint to USD, returning false
+// for quote tokens without a known USD value
+func quoteUSD(price float64, quoteMint string, solPriceUSD float64) (float64, bool) {
+	switch quoteMint {
+	case SOL_MINT:
+		if solPriceUSD <= 0 {
+			return 0, false
+		}
+		return price * solPriceUSD, true
+	case USDC_MINT, USDT_MINT:
+		return price, true
+	}
+	return 0, false
+}
@@ -0,0 +1,114 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// TestGetPoolPrice tests pricing SOL pairs from Raydium and Meteora pool
+// accounts laid out as the programs store them
+func TestGetPoolPrice(t *testing.T) {
+	sol := solana.MustPublicKeyFromBase58(SOL_MINT)
+	usdc := solana.MustPublicKeyFromBase58(USDC_MINT)
+	bonk := solana.MustPublicKeyFromBase58("DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263")
+	key := func() solana.PublicKey { return solana.NewWallet().PublicKey() }
+
+	rayPool, rayBase, rayQuote := key(), key(), key()
+	invPool, invBase, invQuote := key(), key(), key()
+	usdcPool, usdcBase, usdcQuote := key(), key(), key()
+	emptyPool, emptyBase, emptyQuote := key(), key(), key()
+	dlmm, unknown := key(), key()
+
+	client := &fakePoolRPC{accounts: map[solana.PublicKey][]byte{
+		// 50B BONK (5 decimals) against 1,000 SOL
+		rayPool:  raydiumPool(bonk, sol, rayBase, rayQuote, 5, 9),
+		rayBase:  tokenAccount(5_000_000_000_000_000),
+		rayQuote: tokenAccount(1_000_000_000_000),
+		// SOL as base: 20 SOL against 4,000 tokens (6 decimals)
+		invPool:  raydiumPool(sol, bonk, invBase, invQuote, 9, 6),
+		invBase:  tokenAccount(20_000_000_000),
+		invQuote: tokenAccount(4_000_000_000),
+		// USDC-quoted pools have no SOL price
+		usdcPool:  raydiumPool(bonk, usdc, usdcBase, usdcQuote, 5, 6),
+		usdcBase:  tokenAccount(1),
+		usdcQuote: tokenAccount(1),
+		// Drained pool
+		emptyPool:  raydiumPool(bonk, sol, emptyBase, emptyQuote, 5, 9),
+		emptyBase:  tokenAccount(0),
+		emptyQuote: tokenAccount(0),
+		// DLMM with SOL as X at bin -100, bin step 10
+		dlmm: dlmmPair(sol, bonk, -100, 10),
+		sol:  mintAccount(9),
+		bonk: mintAccount(5),
+
+		unknown: make([]byte, 324),
+	}}
+
+	tests := []struct {
+		name     string
+		pool     solana.PublicKey
+		wantType PoolType
+		wantMint string
+		wantSOL  float64
+		wantErr  error
+	}{
+		{"raydium SOL quote", rayPool, PoolRaydiumV4, bonk.String(), 1000.0 / 50_000_000_000, nil},
+		{"raydium SOL base", invPool, PoolRaydiumV4, bonk.String(), 20.0 / 4000, nil},
+		{"meteora dlmm", dlmm, PoolMeteoraDLMM, bonk.String(), 1 / (math.Pow(1.001, -100) * 1e4), nil},
+		{"not paired with SOL", usdcPool, "", "", 0, ErrUnsupportedPool},
+		{"empty reserves", emptyPool, "", "", 0, ErrInsufficientLiquidity},
+		{"unknown layout", unknown, "", "", 0, ErrUnsupportedPool},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetPoolPrice(context.Background(), client, tt.pool.String())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetPoolPrice failed: %v", err)
+			}
+			if got.Type != tt.wantType || got.TokenMint != tt.wantMint || got.Pool != tt.pool.String() {
+				t.Errorf("Expected %s pool of %s, got %+v", tt.wantType, tt.wantMint, got)
+			}
+			if math.Abs(got.PriceSOL-tt.wantSOL)/tt.wantSOL > 1e-9 {
+				t.Errorf("Expected %g SOL, got %g", tt.wantSOL, got.PriceSOL)
+			}
+		})
+	}
+
+	if _, err := GetPoolPrice(context.Background(), client, key().String()); err == nil {
+		t.Error("Expected an error for a missing pool account")
+	}
+	if _, err := GetPoolPrice(context.Background(), client, "not-a-key"); err == nil {
+		t.Error("Expected an error for an invalid pool address")
+	}
+}
+
+// TestDetectPoolType tests layout detection on account sizes and the
+// DLMM discriminator
+func TestDetectPoolType(t *testing.T) {
+	a, b := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	if got := DetectPoolType(raydiumPool(a, b, a, b, 6, 9)); got != PoolRaydiumV4 {
+		t.Errorf("Expected raydium_v4, got %q", got)
+	}
+	if got := DetectPoolType(dlmmPair(a, b, 0, 1)); got != PoolMeteoraDLMM {
+		t.Errorf("Expected meteora_dlmm, got %q", got)
+	}
+	forged := dlmmPair(a, b, 0, 1)
+	forged[0] ^= 0xff
+	if got := DetectPoolType(forged); got != PoolUnknown {
+		t.Errorf("Expected a bad discriminator to be unknown, got %q", got)
+	}
+	if got := DetectPoolType(nil); got != PoolUnknown {
+		t.Errorf("Expected empty data to be unknown, got %q", got)
+	}
+}
@@ -0,0 +1,208 @@
+package trading
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Portfolio defaults
+const (
+	DefaultPriceWorkers = 5
+	tokenInfoTTL        = time.Minute
+)
+
+// PriceLookup fetches market data for a mint
+type PriceLookup func(ctx context.Context, mint string) (*TokenInfo, error)
+
+// TokenInfoCache caches DexScreener lookups so repeated portfolio views
+// don't refetch every token
+type TokenInfoCache struct {
+	cache sync.Map // map[string]cachedTokenInfo
+	fetch PriceLookup
+	ttl   time.Duration
+}
+
+type cachedTokenInfo struct {
+	info      *TokenInfo
+	expiresAt time.Time
+}
+
+// NewTokenInfoCache creates a cache backed by GetTokenInfo
+func NewTokenInfoCache() *TokenInfoCache {
+	return &TokenInfoCache{fetch: GetTokenInfo, ttl: tokenInfoTTL}
+}
+
+// Get returns token info from memory or fetches it if expired
+func (c *TokenInfoCache) Get(ctx context.Context, mint string) (*TokenInfo, error) {
+	if val, ok := c.cache.Load(mint); ok {
+		cached := val.(cachedTokenInfo)
+		if time.Now().Before(cached.expiresAt) {
+			return cached.info, nil
+		}
+	}
+
+	info, err := c.fetch(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Store(mint, cachedTokenInfo{info: info, expiresAt: time.Now().Add(c.ttl)})
+	return info, nil
+}
+
+// Holding is one priced position in a portfolio
+type Holding struct {
+	Mint      string
+	Symbol    string
+	Name      string
+	UIAmount  float64
+	PriceUSD  float64
+	ValueUSD  float64
+	Change24h float64 // percent
+	Priced    bool    // false when no price data was found
+}
+
+// Portfolio is a wallet's holdings valued in USD and SOL
+type Portfolio struct {
+	SOL          float64
+	SOLPriceUSD  float64 // 0 if SOL couldn't be priced
+	SOLChange24h float64
+	Holdings     []Holding // sorted by value, unpriced last
+	TotalUSD     float64
+	TotalSOL     float64
+	Change24hUSD float64
+	Change24hPct float64
+}
+
+// BuildPortfolio prices every holding in balance with at most workers
+// concurrent lookups and totals the result
+func BuildPortfolio(ctx context.Context, balance *Balance, lookup PriceLookup, workers int) *Portfolio {
+	if workers <= 0 {
+		workers = DefaultPriceWorkers
+	}
+
+	p := &Portfolio{SOL: FormatSOL(balance.SOLBalance)}
+	holdings := make([]Holding, len(balance.TokenBalances))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sem <- struct{}{}
+		defer func() { <-sem }()
+		if info, err := lookup(ctx, SOL_MINT); err == nil {
+			p.SOLPriceUSD = parsePrice(info.PriceUSD)
+			p.SOLChange24h = info.Change24h
+		}
+	}()
+
+	for i, tb := range balance.TokenBalances {
+		holdings[i] = Holding{
+			Mint:     tb.Mint.String(),
+			Symbol:   tb.Symbol,
+			Name:     tb.Name,
+			UIAmount: tb.UIAmount,
+		}
+
+		wg.Add(1)
+		go func(h *Holding) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			info, err := lookup(ctx, h.Mint)
+			if err != nil {
+				return
+			}
+			if h.Symbol == "" {
+				h.Symbol, h.Name = info.Symbol, info.Name
+			}
+			if price := parsePrice(info.PriceUSD); price > 0 {
+				h.PriceUSD = price
+				h.ValueUSD = price * h.UIAmount
+				h.Change24h = info.Change24h
+				h.Priced = true
+			}
+		}(&holdings[i])
+	}
+	wg.Wait()
+
+	sortHoldings(holdings)
+	p.Holdings = holdings
+	p.total()
+	return p
+}
+
+// SetPrice reprices the holding of mint, e.g. from a PriceStream, and
+// updates the totals. It reports whether the portfolio holds mint.
+func (p *Portfolio) SetPrice(mint string, priceUSD float64) bool {
+	found := false
+	for i := range p.Holdings {
+		h := &p.Holdings[i]
+		if h.Mint != mint {
+			continue
+		}
+		h.PriceUSD = priceUSD
+		h.ValueUSD = priceUSD * h.UIAmount
+		h.Priced = priceUSD > 0
+		found = true
+	}
+	if !found {
+		return false
+	}
+
+	sortHoldings(p.Holdings)
+	p.TotalUSD, p.TotalSOL, p.Change24hUSD, p.Change24hPct = 0, 0, 0, 0
+	p.total()
+	return true
+}
+
+// sortHoldings orders holdings by value, unpriced last
+func sortHoldings(holdings []Holding) {
+	sort.SliceStable(holdings, func(i, j int) bool {
+		if holdings[i].Priced != holdings[j].Priced {
+			return holdings[i].Priced
+		}
+		return holdings[i].ValueUSD > holdings[j].ValueUSD
+	})
+}
+
+// total sums values and the 24h change implied by each position's
+// percentage move
+func (p *Portfolio) total() {
+	var before float64
+	add := func(value, changePct float64) {
+		p.TotalUSD += value
+		if changePct > -100 {
+			before += value / (1 + changePct/100)
+		}
+	}
+
+	add(p.SOL*p.SOLPriceUSD, p.SOLChange24h)
+	for _, h := range p.Holdings {
+		if h.Priced {
+			add(h.ValueUSD, h.Change24h)
+		}
+	}
+
+	p.Change24hUSD = p.TotalUSD - before
+	if before > 0 {
+		p.Change24hPct = p.Change24hUSD / before * 100
+	}
+	if p.SOLPriceUSD > 0 {
+		p.TotalSOL = p.TotalUSD / p.SOLPriceUSD
+	}
+}
+
+// parsePrice parses a DexScreener price string, returning 0 if absent
+func parsePrice(s string) float64 {
+	price, err := strconv.ParseFloat(s, 64)
+	if err != nil || price < 0 {
+		return 0
+	}
+	return price
+}
@@ -0,0 +1,113 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// TestBuildPortfolio tests pricing, sorting and totals
+func TestBuildPortfolio(t *testing.T) {
+	usdc := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	bonk := solana.MustPublicKeyFromBase58("DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263")
+	dead := solana.MustPublicKeyFromBase58("G4vTBDnAbBre4wqTpibXbLmwdVtFAbFCr2DM8t22UrmM")
+
+	prices := map[string]*TokenInfo{
+		SOL_MINT:      {Symbol: "SOL", PriceUSD: "100", Change24h: 25},
+		usdc.String(): {Symbol: "USDC", PriceUSD: "1", Change24h: 0},
+		bonk.String(): {Symbol: "BONK", PriceUSD: "0.00002", Change24h: -50},
+	}
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	lookup := func(ctx context.Context, mint string) (*TokenInfo, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		if info, ok := prices[mint]; ok {
+			return info, nil
+		}
+		return nil, fmt.Errorf("token not found on DexScreener")
+	}
+
+	balance := &Balance{
+		SOLBalance: 2_000_000_000,
+		TokenBalances: []TokenBalance{
+			{Mint: bonk, UIAmount: 10_000_000},
+			{Mint: dead, UIAmount: 5, Symbol: "DEAD"},
+			{Mint: usdc, UIAmount: 50},
+		},
+	}
+
+	p := BuildPortfolio(context.Background(), balance, lookup, 2)
+
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 concurrent lookups, saw %d", maxInFlight)
+	}
+	if len(p.Holdings) != 3 {
+		t.Fatalf("Expected 3 holdings, got %d", len(p.Holdings))
+	}
+	if p.Holdings[0].Symbol != "BONK" || p.Holdings[1].Symbol != "USDC" {
+		t.Errorf("Expected holdings sorted by value, got %+v", p.Holdings)
+	}
+	if last := p.Holdings[2]; last.Priced || last.Symbol != "DEAD" {
+		t.Errorf("Expected unpriced token last, got %+v", last)
+	}
+
+	// 2 SOL * $100 + 10M BONK * $0.00002 + 50 USDC = $450
+	if math.Abs(p.TotalUSD-450) > 1e-9 || math.Abs(p.TotalSOL-4.5) > 1e-9 {
+		t.Errorf("Expected $450 / 4.5 SOL, got $%f / %f SOL", p.TotalUSD, p.TotalSOL)
+	}
+	// Yesterday: SOL $160, BONK $400, USDC $50 = $610
+	if math.Abs(p.Change24hUSD-(-160)) > 1e-9 {
+		t.Errorf("Expected -$160 over 24h, got %f", p.Change24hUSD)
+	}
+
+	// A streamed price for the unpriced token revalues and retotals
+	if !p.SetPrice(dead.String(), 10) {
+		t.Fatal("SetPrice should find a held mint")
+	}
+	if h := p.Holdings[2]; !h.Priced || h.Symbol != "DEAD" || h.ValueUSD != 50 {
+		t.Errorf("Expected DEAD priced at $50, got %+v", h)
+	}
+	if math.Abs(p.TotalUSD-500) > 1e-9 {
+		t.Errorf("Expected $500 after repricing, got $%f", p.TotalUSD)
+	}
+	if p.SetPrice(SOL_MINT, 1) {
+		t.Error("SetPrice should ignore mints that aren't holdings")
+	}
+}
+
+// TestTokenInfoCache tests that lookups are served from memory until expiry
+func TestTokenInfoCache(t *testing.T) {
+	calls := 0
+	cache := &TokenInfoCache{
+		fetch: func(ctx context.Context, mint string) (*TokenInfo, error) {
+			calls++
+			return &TokenInfo{Address: mint, PriceUSD: "1"}, nil
+		},
+		ttl: time.Hour,
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(context.Background(), SOL_MINT); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 fetch, got %d", calls)
+	}
+}
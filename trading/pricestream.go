@@ -0,0 +1,327 @@
+package trading
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MaxStreamedPositions bounds how many positions one PriceStream watches
+const MaxStreamedPositions = 10
+
+// ErrTooManyPositions is returned by Watch once the stream is full
+var ErrTooManyPositions = errors.New("too many streamed positions")
+
+var errStreamClosed = errors.New("price stream closed")
+
+// PriceUpdate is a new USD price for a watched mint
+type PriceUpdate struct {
+	Mint     string
+	PriceUSD float64
+}
+
+// AccountInfoClient is the subset of the RPC client needed to read pool
+// and mint accounts
+type AccountInfoClient interface {
+	GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error)
+}
+
+// AccountSubscriber streams raw account data; WSClient implements it
+type AccountSubscriber interface {
+	SubscribeAccountData(ctx context.Context, account string) (<-chan interface{}, error)
+	UnsubscribeAccountData(account string)
+}
+
+// PriceStream derives live token prices from pool account updates.
+// Raydium AMM v4 pools are priced from their vault reserves and Meteora
+// DLMM pairs from their active bin. Pools must be quoted in SOL, USDC or
+// USDT.
+type PriceStream struct {
+	client      AccountInfoClient
+	subscriber  AccountSubscriber
+	solPriceUSD float64
+	updates     chan PriceUpdate
+
+	mu       sync.Mutex
+	watched  map[string]bool // mints
+	accounts []string        // subscribed accounts
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewPriceStream creates a price stream. solPriceUSD converts prices of
+// SOL-quoted pools to USD.
+func NewPriceStream(client AccountInfoClient, subscriber AccountSubscriber, solPriceUSD float64) *PriceStream {
+	return &PriceStream{
+		client:      client,
+		subscriber:  subscriber,
+		solPriceUSD: solPriceUSD,
+		updates:     make(chan PriceUpdate, 64),
+		watched:     make(map[string]bool),
+	}
+}
+
+// Updates delivers a price every time a watched pool changes. It is
+// closed by Close.
+func (s *PriceStream) Updates() <-chan PriceUpdate {
+	return s.updates
+}
+
+// Watch starts streaming the price of mint from pool, which is usually
+// the pair address DexScreener reports for it
+func (s *PriceStream) Watch(ctx context.Context, mint, pool string) error {
+	s.mu.Lock()
+	switch {
+	case s.closed:
+		s.mu.Unlock()
+		return errStreamClosed
+	case s.watched[mint]:
+		s.mu.Unlock()
+		return nil
+	case len(s.watched) >= MaxStreamedPositions:
+		s.mu.Unlock()
+		return ErrTooManyPositions
+	}
+	s.watched[mint] = true
+	s.mu.Unlock()
+
+	err := s.watch(ctx, mint, pool)
+	if err != nil {
+		s.mu.Lock()
+		delete(s.watched, mint)
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *PriceStream) watch(ctx context.Context, mint, pool string) error {
+	poolKey, err := solana.PublicKeyFromBase58(pool)
+	if err != nil {
+		return fmt.Errorf("invalid pool address: %w", err)
+	}
+	data, err := readAccount(ctx, s.client, poolKey)
+	if err != nil {
+		return err
+	}
+
+	switch DetectPoolType(data) {
+	case PoolRaydiumV4:
+		pool, err := DecodeRaydiumV4Pool(data)
+		if err != nil {
+			return err
+		}
+		return s.watchRaydium(ctx, mint, pool)
+	case PoolMeteoraDLMM:
+		pair, err := DecodeDLMMPair(data)
+		if err != nil {
+			return err
+		}
+		return s.watchDLMM(ctx, mint, poolKey, pair)
+	}
+	return ErrUnsupportedPool
+}
+
+// watchRaydium follows both vaults of a Raydium pool and reprices on
+// every reserve change
+func (s *PriceStream) watchRaydium(ctx context.Context, mint string, pool *RaydiumPool) error {
+	invert := false
+	quoteMint := pool.QuoteMint.String()
+	switch mint {
+	case pool.BaseMint.String():
+	case pool.QuoteMint.String():
+		invert, quoteMint = true, pool.BaseMint.String()
+	default:
+		return fmt.Errorf("%w: pool does not trade %s", ErrUnsupportedPool, mint)
+	}
+	if _, ok := quoteUSD(1, quoteMint, s.solPriceUSD); !ok {
+		return fmt.Errorf("%w: can't price quote token %s", ErrUnsupportedPool, quoteMint)
+	}
+
+	// Seed both reserves so the first update of either prices the pool
+	reserves, err := raydiumReserves(ctx, s.client, pool)
+	if err != nil {
+		return err
+	}
+
+	baseCh, err := s.subscribe(ctx, pool.BaseVault.String())
+	if err != nil {
+		return err
+	}
+	quoteCh, err := s.subscribe(ctx, pool.QuoteVault.String())
+	if err != nil {
+		return err
+	}
+
+	price := func() float64 {
+		p := ReservePrice(reserves[0], reserves[1], pool.BaseDecimals, pool.QuoteDecimals)
+		if invert && p > 0 {
+			p = 1 / p
+		}
+		return p
+	}
+	return s.run(func() {
+		s.emit(mint, price(), quoteMint)
+		for baseCh != nil || quoteCh != nil {
+			var msg interface{}
+			var ok bool
+			var side int
+			select {
+			case msg, ok = <-baseCh:
+				if !ok {
+					baseCh = nil
+					continue
+				}
+			case msg, ok = <-quoteCh:
+				if !ok {
+					quoteCh = nil
+					continue
+				}
+				side = 1
+			}
+
+			data, err := NotificationAccountData(msg)
+			if err != nil {
+				continue
+			}
+			amount, err := DecodeTokenAccountAmount(data)
+			if err != nil {
+				continue
+			}
+			reserves[side] = amount
+			s.emit(mint, price(), quoteMint)
+		}
+	})
+}
+
+// watchDLMM follows a Meteora DLMM pair and reprices whenever its active
+// bin moves
+func (s *PriceStream) watchDLMM(ctx context.Context, mint string, poolKey solana.PublicKey, pair *DLMMPair) error {
+	invert := false
+	quoteMint := pair.TokenYMint.String()
+	switch mint {
+	case pair.TokenXMint.String():
+	case pair.TokenYMint.String():
+		invert, quoteMint = true, pair.TokenXMint.String()
+	default:
+		return fmt.Errorf("%w: pair does not trade %s", ErrUnsupportedPool, mint)
+	}
+	if _, ok := quoteUSD(1, quoteMint, s.solPriceUSD); !ok {
+		return fmt.Errorf("%w: can't price quote token %s", ErrUnsupportedPool, quoteMint)
+	}
+
+	decimals, err := dlmmDecimals(ctx, s.client, pair)
+	if err != nil {
+		return err
+	}
+
+	ch, err := s.subscribe(ctx, poolKey.String())
+	if err != nil {
+		return err
+	}
+
+	price := func(p *DLMMPair) float64 {
+		v := p.Price(decimals[0], decimals[1])
+		if invert && v > 0 {
+			v = 1 / v
+		}
+		return v
+	}
+	return s.run(func() {
+		s.emit(mint, price(pair), quoteMint)
+		for msg := range ch {
+			data, err := NotificationAccountData(msg)
+			if err != nil {
+				continue
+			}
+			updated, err := DecodeDLMMPair(data)
+			if err != nil {
+				continue
+			}
+			s.emit(mint, price(updated), quoteMint)
+		}
+	})
+}
+
+// subscribe opens a raw-data subscription that Close will drop
+func (s *PriceStream) subscribe(ctx context.Context, account string) (<-chan interface{}, error) {
+	ch, err := s.subscriber.SubscribeAccountData(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		s.subscriber.UnsubscribeAccountData(account)
+		return nil, errStreamClosed
+	}
+	s.accounts = append(s.accounts, account)
+	return ch, nil
+}
+
+// run starts a watcher unless the stream was closed meanwhile, so Close
+// never closes Updates under a running watcher
+func (s *PriceStream) run(watcher func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errStreamClosed
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		watcher()
+	}()
+	return nil
+}
+
+// emit publishes a price, dropping it if the reader has fallen behind
+// since a newer one will follow
+func (s *PriceStream) emit(mint string, price float64, quoteMint string) {
+	usd, ok := quoteUSD(price, quoteMint, s.solPriceUSD)
+	if !ok || usd <= 0 {
+		return
+	}
+	select {
+	case s.updates <- PriceUpdate{Mint: mint, PriceUSD: usd}:
+	default:
+	}
+}
+
+// Close drops every subscription and closes Updates once the watchers
+// have stopped
+func (s *PriceStream) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	accounts := s.accounts
+	s.mu.Unlock()
+
+	for _, account := range accounts {
+		s.subscriber.UnsubscribeAccountData(account)
+	}
+	s.wg.Wait()
+	close(s.updates)
+}
+
+// NotificationAccountData extracts the account data from a base64
+// accountNotification as delivered by WSClient
+func NotificationAccountData(msg interface{}) ([]byte, error) {
+	root, _ := msg.(map[string]interface{})
+	params, _ := root["params"].(map[string]interface{})
+	result, _ := params["result"].(map[string]interface{})
+	value, _ := result["value"].(map[string]interface{})
+	data, _ := value["data"].([]interface{})
+	if len(data) == 0 {
+		return nil, errors.New("notification has no account data")
+	}
+	encoded, _ := data[0].(string)
+	return base64.StdEncoding.DecodeString(encoded)
+}
@@ -0,0 +1,216 @@
+package trading
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// fakePoolRPC serves raw account data from memory
+type fakePoolRPC struct {
+	accounts map[solana.PublicKey][]byte
+}
+
+func (f *fakePoolRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	data, ok := f.accounts[account]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(data)}}, nil
+}
+
+// fakeSubscriber hands out one channel per account
+type fakeSubscriber struct {
+	mu   sync.Mutex
+	subs map[string]chan interface{}
+}
+
+func (f *fakeSubscriber) SubscribeAccountData(ctx context.Context, account string) (<-chan interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan interface{}, 4)
+	f.subs[account] = ch
+	return ch, nil
+}
+
+func (f *fakeSubscriber) UnsubscribeAccountData(account string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ch, ok := f.subs[account]; ok {
+		close(ch)
+		delete(f.subs, account)
+	}
+}
+
+func (f *fakeSubscriber) push(account string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subs[account] <- accountNotification(data)
+}
+
+// accountNotification builds a base64 accountNotification as WSClient
+// delivers it
+func accountNotification(data []byte) interface{} {
+	return map[string]interface{}{
+		"method": "accountNotification",
+		"params": map[string]interface{}{
+			"result": map[string]interface{}{
+				"value": map[string]interface{}{
+					"data": []interface{}{base64.StdEncoding.EncodeToString(data), "base64"},
+				},
+			},
+		},
+	}
+}
+
+func tokenAccount(amount uint64) []byte {
+	data := make([]byte, 165)
+	binary.LittleEndian.PutUint64(data[tokenAccountAmount:], amount)
+	return data
+}
+
+func mintAccount(decimals uint8) []byte {
+	data := make([]byte, 82)
+	data[mintDecimals] = decimals
+	return data
+}
+
+func raydiumPool(base, quote, baseVault, quoteVault solana.PublicKey, baseDecimals, quoteDecimals uint64) []byte {
+	data := make([]byte, raydiumV4PoolSize)
+	binary.LittleEndian.PutUint64(data[raydiumV4BaseDecimals:], baseDecimals)
+	binary.LittleEndian.PutUint64(data[raydiumV4QuoteDecimals:], quoteDecimals)
+	copy(data[raydiumV4BaseVault:], baseVault[:])
+	copy(data[raydiumV4QuoteVault:], quoteVault[:])
+	copy(data[raydiumV4BaseMint:], base[:])
+	copy(data[raydiumV4QuoteMint:], quote[:])
+	return data
+}
+
+func dlmmPair(x, y solana.PublicKey, activeID int32, binStep uint16) []byte {
+	data := make([]byte, 904)
+	copy(data, dlmmDiscriminator)
+	binary.LittleEndian.PutUint32(data[dlmmActiveID:], uint32(activeID))
+	binary.LittleEndian.PutUint16(data[dlmmBinStep:], binStep)
+	copy(data[dlmmTokenXMint:], x[:])
+	copy(data[dlmmTokenYMint:], y[:])
+	return data
+}
+
+func nextPrice(t *testing.T, s *PriceStream) PriceUpdate {
+	t.Helper()
+	select {
+	case u := <-s.Updates():
+		return u
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a price update")
+	}
+	return PriceUpdate{}
+}
+
+// TestPriceStreamRaydium tests pricing a SOL-quoted Raydium pool from its
+// vault reserves
+func TestPriceStreamRaydium(t *testing.T) {
+	token := solana.MustPublicKeyFromBase58("DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263")
+	pool := solana.NewWallet().PublicKey()
+	baseVault, quoteVault := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+
+	client := &fakePoolRPC{accounts: map[solana.PublicKey][]byte{
+		pool: raydiumPool(token, solana.MustPublicKeyFromBase58(SOL_MINT), baseVault, quoteVault, 6, 9),
+		// 1,000 tokens against 10 SOL: 0.01 SOL each
+		baseVault:  tokenAccount(1_000_000_000),
+		quoteVault: tokenAccount(10_000_000_000),
+	}}
+	subs := &fakeSubscriber{subs: make(map[string]chan interface{})}
+	stream := NewPriceStream(client, subs, 150)
+
+	if err := stream.Watch(context.Background(), token.String(), pool.String()); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if u := nextPrice(t, stream); u.Mint != token.String() || math.Abs(u.PriceUSD-1.5) > 1e-9 {
+		t.Fatalf("Expected $1.50 initial price, got %+v", u)
+	}
+
+	// A buy doubles the SOL side
+	subs.push(quoteVault.String(), tokenAccount(20_000_000_000))
+	if u := nextPrice(t, stream); math.Abs(u.PriceUSD-3) > 1e-9 {
+		t.Errorf("Expected $3 after the quote vault grew, got %+v", u)
+	}
+
+	stream.Close()
+	if _, ok := <-stream.Updates(); ok {
+		t.Error("Updates should be closed after Close")
+	}
+	if len(subs.subs) != 0 {
+		t.Errorf("Close should drop every subscription, %d left", len(subs.subs))
+	}
+}
+
+// TestPriceStreamDLMM tests pricing a Meteora DLMM pair from its active
+// bin, with the held token on the Y side
+func TestPriceStreamDLMM(t *testing.T) {
+	token := solana.NewWallet().PublicKey()
+	usdc := solana.MustPublicKeyFromBase58(USDC_MINT)
+	pair := solana.NewWallet().PublicKey()
+
+	client := &fakePoolRPC{accounts: map[solana.PublicKey][]byte{
+		pair:  dlmmPair(usdc, token, 0, 25),
+		usdc:  mintAccount(6),
+		token: mintAccount(9),
+	}}
+	subs := &fakeSubscriber{subs: make(map[string]chan interface{})}
+	stream := NewPriceStream(client, subs, 150)
+	defer stream.Close()
+
+	if err := stream.Watch(context.Background(), token.String(), pair.String()); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	// Bin 0: 1 USDC lamport per token lamport, so 1 token = 1000 USDC
+	if u := nextPrice(t, stream); math.Abs(u.PriceUSD-1000) > 1e-6 {
+		t.Fatalf("Expected $1000, got %+v", u)
+	}
+
+	subs.push(pair.String(), dlmmPair(usdc, token, 100, 25))
+	want := 1000 / math.Pow(1.0025, 100)
+	if u := nextPrice(t, stream); math.Abs(u.PriceUSD-want) > 1e-6 {
+		t.Errorf("Expected $%f after the bin moved, got %+v", want, u)
+	}
+}
+
+// TestPriceStreamRejects tests the pools and limits Watch refuses
+func TestPriceStreamRejects(t *testing.T) {
+	token := solana.NewWallet().PublicKey()
+	other := solana.NewWallet().PublicKey()
+	unknown := solana.NewWallet().PublicKey()
+	vault := solana.NewWallet().PublicKey()
+
+	client := &fakePoolRPC{accounts: map[solana.PublicKey][]byte{
+		unknown: make([]byte, 300),
+		other:   raydiumPool(token, solana.NewWallet().PublicKey(), vault, vault, 6, 6),
+		vault:   tokenAccount(1),
+	}}
+	stream := NewPriceStream(client, &fakeSubscriber{subs: make(map[string]chan interface{})}, 150)
+	defer stream.Close()
+
+	if err := stream.Watch(context.Background(), token.String(), unknown.String()); !errors.Is(err, ErrUnsupportedPool) {
+		t.Errorf("Expected ErrUnsupportedPool for an unknown layout, got %v", err)
+	}
+	if err := stream.Watch(context.Background(), token.String(), other.String()); !errors.Is(err, ErrUnsupportedPool) {
+		t.Errorf("Expected ErrUnsupportedPool for an unpriceable quote token, got %v", err)
+	}
+
+	stream.watched = make(map[string]bool)
+	for i := 0; i < MaxStreamedPositions; i++ {
+		stream.watched[solana.NewWallet().PublicKey().String()] = true
+	}
+	if err := stream.Watch(context.Background(), token.String(), unknown.String()); !errors.Is(err, ErrTooManyPositions) {
+		t.Errorf("Expected ErrTooManyPositions past the cap, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// PriorityFeeAuto as a priority fee setting prices each trade from the
+// fees recently paid on the accounts it touches
+const PriorityFeeAuto int64 = -1
+
+// Auto priority fee estimation
+const (
+	priorityFeePercentile = 75
+	priorityFeeCacheTTL   = 10 * time.Second
+	// swapComputeUnits is a typical routed swap, used to turn a per-unit
+	// price into the total Jupiter and the compute budget are given
+	swapComputeUnits = 300_000
+)
+
+// cachedFee is an estimate and when it was made
+type cachedFee struct {
+	lamports int64
+	at       time.Time
+}
+
+// feeCache keeps estimates briefly so a confirmation and the trade after
+// it don't both hit the RPC
+var feeCache = struct {
+	mu      sync.Mutex
+	entries map[string]cachedFee
+	now     func() time.Time
+}{entries: make(map[string]cachedFee), now: time.Now}
+
+// EstimatePriorityFee returns a priority fee in lamports for a swap
+// touching accounts: the 75th percentile of the per-unit fees recently
+// paid on them, over swapComputeUnits and within the priority fee bounds.
+// Slots where nobody paid a fee are ignored.
+func EstimatePriorityFee(ctx context.Context, client *rpc.Client, accounts []solana.PublicKey) (int64, error) {
+	keys := make([]string, len(accounts))
+	for i, a := range accounts {
+		keys[i] = a.String()
+	}
+	slices.Sort(keys)
+	key := strings.Join(keys, ",")
+
+	feeCache.mu.Lock()
+	cached, ok := feeCache.entries[key]
+	feeCache.mu.Unlock()
+	if ok && feeCache.now().Sub(cached.at) < priorityFeeCacheTTL {
+		return cached.lamports, nil
+	}
+
+	results, err := client.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to get prioritization fees: %v", ErrRPCUnavailable, err)
+	}
+	var perUnit []uint64 // micro-lamports per compute unit
+	for _, r := range results {
+		if r.PrioritizationFee > 0 {
+			perUnit = append(perUnit, r.PrioritizationFee)
+		}
+	}
+
+	var lamports int64
+	if len(perUnit) > 0 {
+		slices.Sort(perUnit)
+		price := perUnit[(len(perUnit)-1)*priorityFeePercentile/100]
+		lamports = int64(mulDiv(price, swapComputeUnits, 1_000_000))
+	}
+	lamports = ClampPriorityFee(lamports)
+
+	feeCache.mu.Lock()
+	feeCache.entries[key] = cachedFee{lamports: lamports, at: feeCache.now()}
+	feeCache.mu.Unlock()
+	return lamports, nil
+}
+
+// ResolvePriorityFee returns setting, or an estimate for accounts when it
+// is PriorityFeeAuto. If the estimate fails the default fee is used.
+func ResolvePriorityFee(ctx context.Context, client *rpc.Client, setting int64, accounts []solana.PublicKey) int64 {
+	if setting != PriorityFeeAuto {
+		return setting
+	}
+	lamports, err := EstimatePriorityFee(ctx, client, accounts)
+	if err != nil {
+		return DefaultPriorityFeeLamports
+	}
+	return lamports
+}
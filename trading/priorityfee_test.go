@@ -0,0 +1,85 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestEstimatePriorityFee(t *testing.T) {
+	// Per-unit fees in micro-lamports served for the next request
+	var fees []uint64
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		requests++
+		if req.Method != "getRecentPrioritizationFees" {
+			t.Errorf("Unexpected method %s", req.Method)
+		}
+		if fees == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		results := make([]string, len(fees))
+		for i, fee := range fees {
+			results[i] = fmt.Sprintf(`{"slot":%d,"prioritizationFee":%d}`, 1000+i, fee)
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":[%s]}`, req.ID, strings.Join(results, ","))
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1700000000, 0)
+	feeCache.now = func() time.Time { return now }
+	defer func() { feeCache.now = time.Now }()
+
+	client := rpc.New(srv.URL)
+	ctx := context.Background()
+	pool := []solana.PublicKey{solana.NewWallet().PublicKey()}
+
+	// Zero-fee slots are skipped: the 75th percentile of 1k, 2k, 5k and 50k
+	// is 5,000 micro-lamports, or 1,500 lamports over 300k units
+	fees = []uint64{0, 0, 1000, 2000, 5000, 50000}
+	if got, err := EstimatePriorityFee(ctx, client, pool); err != nil || got != 1500 {
+		t.Fatalf("Expected 1500 lamports, got %d (err: %v)", got, err)
+	}
+
+	// Estimates are reused briefly
+	fees = []uint64{100_000_000}
+	if got, _ := EstimatePriorityFee(ctx, client, pool); got != 1500 || requests != 1 {
+		t.Errorf("Expected the cached 1500 without a request, got %d after %d requests", got, requests)
+	}
+	now = now.Add(priorityFeeCacheTTL)
+	if got, _ := EstimatePriorityFee(ctx, client, pool); got != MaxPriorityFeeLamports {
+		t.Errorf("Expected a fresh estimate capped at %d, got %d", MaxPriorityFeeLamports, got)
+	}
+
+	// Quiet accounts get the default fee
+	fees = []uint64{0, 0}
+	quiet := []solana.PublicKey{solana.NewWallet().PublicKey()}
+	if got, _ := EstimatePriorityFee(ctx, client, quiet); got != DefaultPriorityFeeLamports {
+		t.Errorf("Expected the default fee without paid slots, got %d", got)
+	}
+
+	// Only the auto setting estimates, falling back to the default
+	fees = nil
+	failing := []solana.PublicKey{solana.NewWallet().PublicKey()}
+	if got := ResolvePriorityFee(ctx, client, PriorityFeeAuto, failing); got != DefaultPriorityFeeLamports {
+		t.Errorf("Expected the default fee when the RPC fails, got %d", got)
+	}
+	before := requests
+	if got := ResolvePriorityFee(ctx, client, 250000, failing); got != 250000 || requests != before {
+		t.Errorf("Expected a fixed fee kept without a request, got %d", got)
+	}
+}
@@ -0,0 +1,349 @@
+package trading
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ErrNotOnBondingCurve means a mint has no active Pump.fun bonding curve:
+// it wasn't launched on Pump.fun, or it has graduated and trades on an AMM
+// Jupiter routes through
+var ErrNotOnBondingCurve = errors.New("not on a pump.fun bonding curve")
+
+// Pump.fun program accounts
+var (
+	PumpFunProgramID      = solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P")
+	pumpFunGlobal         = solana.MustPublicKeyFromBase58("4wTV1YmiEkRvAtNtsSGPtUrqRYQMe5SKy2uB4Jjaxnjf")
+	pumpFunFeeRecipient   = solana.MustPublicKeyFromBase58("CebN5WGQ4jvEPvsVU4EoHEpgzq1VV7AbicfhtW4xC9iM")
+	pumpFunEventAuthority = solana.MustPublicKeyFromBase58("Ce6TQqeHC9p8KetsN6JsjHK7UTZk7nasjjnr7XxXp9F1")
+	computeBudgetProgram  = solana.MustPublicKeyFromBase58("ComputeBudget111111111111111111111111111111")
+)
+
+// BondingCurve account layout, after the 8-byte account discriminator.
+// Curves created before creator fees end at pumpCurveMinSize.
+const (
+	pumpCurveVirtualToken = 8
+	pumpCurveVirtualSOL   = 16
+	pumpCurveRealToken    = 24
+	pumpCurveRealSOL      = 32
+	pumpCurveSupply       = 40
+	pumpCurveComplete     = 48
+	pumpCurveCreator      = 49
+	pumpCurveMinSize      = 49
+)
+
+// pumpFunFeeBps is the program's trade fee, charged on the SOL side
+const pumpFunFeeBps = 100
+
+// pumpFunComputeUnits covers a buy including creating the user's token
+// account; the priority fee is spread over it
+const pumpFunComputeUnits = 120_000
+
+var (
+	pumpCurveDiscriminator = anchorDiscriminator("BondingCurve")
+	pumpBuyDiscriminator   = anchorInstruction("buy")
+	pumpSellDiscriminator  = anchorInstruction("sell")
+)
+
+func anchorInstruction(name string) []byte {
+	sum := sha256.Sum256([]byte("global:" + name))
+	return sum[:8]
+}
+
+// BondingCurve is the state of a Pump.fun token before graduation. Prices
+// follow a constant product over the virtual reserves.
+type BondingCurve struct {
+	Address              solana.PublicKey
+	VirtualTokenReserves uint64
+	VirtualSOLReserves   uint64
+	RealTokenReserves    uint64
+	RealSOLReserves      uint64
+	TokenTotalSupply     uint64
+	Complete             bool             // graduated; the curve no longer trades
+	Creator              solana.PublicKey // zero on curves predating creator fees
+}
+
+// DecodeBondingCurve reads a Pump.fun BondingCurve account
+func DecodeBondingCurve(data []byte) (*BondingCurve, error) {
+	if len(data) < pumpCurveMinSize || string(data[:8]) != string(pumpCurveDiscriminator) {
+		return nil, fmt.Errorf("%w: not a bonding curve account", ErrNotOnBondingCurve)
+	}
+	curve := &BondingCurve{
+		VirtualTokenReserves: binary.LittleEndian.Uint64(data[pumpCurveVirtualToken:]),
+		VirtualSOLReserves:   binary.LittleEndian.Uint64(data[pumpCurveVirtualSOL:]),
+		RealTokenReserves:    binary.LittleEndian.Uint64(data[pumpCurveRealToken:]),
+		RealSOLReserves:      binary.LittleEndian.Uint64(data[pumpCurveRealSOL:]),
+		TokenTotalSupply:     binary.LittleEndian.Uint64(data[pumpCurveSupply:]),
+		Complete:             data[pumpCurveComplete] != 0,
+	}
+	if len(data) >= pumpCurveCreator+32 {
+		curve.Creator = solana.PublicKeyFromBytes(data[pumpCurveCreator : pumpCurveCreator+32])
+	}
+	return curve, nil
+}
+
+// BuyQuote returns the tokens lamports buys, after the trade fee
+func (c *BondingCurve) BuyQuote(lamports uint64) uint64 {
+	cost := mulDiv(lamports, 10000, 10000+pumpFunFeeBps)
+	tokens := mulDiv(c.VirtualTokenReserves, cost, c.VirtualSOLReserves+cost)
+	return min(tokens, c.RealTokenReserves)
+}
+
+// SellQuote returns the lamports selling tokens pays, after the trade fee
+func (c *BondingCurve) SellQuote(tokens uint64) uint64 {
+	proceeds := mulDiv(c.VirtualSOLReserves, tokens, c.VirtualTokenReserves+tokens)
+	return proceeds - mulDiv(proceeds, pumpFunFeeBps, 10000)
+}
+
+// BuyImpact returns how far buying with lamports moves the price, in
+// percent like a Jupiter quote's: the share of the trade, fee aside, lost
+// against the curve's current price
+func (c *BondingCurve) BuyImpact(lamports uint64) float64 {
+	cost := mulDiv(lamports, 10000, 10000+pumpFunFeeBps)
+	if cost == 0 || c.VirtualTokenReserves == 0 {
+		return 0
+	}
+	atSpot := float64(c.BuyQuote(lamports)) * float64(c.VirtualSOLReserves) / float64(c.VirtualTokenReserves)
+	return max(1-atSpot/float64(cost), 0) * 100
+}
+
+// SellImpact returns how far selling tokens moves the price, in percent
+// like BuyImpact
+func (c *BondingCurve) SellImpact(tokens uint64) float64 {
+	if tokens == 0 {
+		return 0
+	}
+	return float64(tokens) / (float64(c.VirtualTokenReserves) + float64(tokens)) * 100
+}
+
+// mulDiv returns a*b/c without overflowing the product, 0 if c is 0
+func mulDiv(a, b, c uint64) uint64 {
+	if c == 0 {
+		return 0
+	}
+	var n big.Int
+	n.Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	return n.Div(&n, new(big.Int).SetUint64(c)).Uint64()
+}
+
+// PumpFunClient is the subset of the RPC client PumpFunSwap needs
+type PumpFunClient interface {
+	AccountInfoClient
+	GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error)
+}
+
+// PumpFunSwap builds buys and sells directly against the Pump.fun program,
+// for tokens still on their bonding curve that Jupiter can't route yet.
+// Instructions follow the program's creator-fee account layout.
+type PumpFunSwap struct {
+	client PumpFunClient
+}
+
+// PumpFunTx is an unsigned Pump.fun swap, paid for and signed by the user
+type PumpFunTx struct {
+	Transaction          *solana.Transaction
+	LastValidBlockHeight uint64
+	ExpectedOut          uint64  // tokens for a buy, lamports for a sell
+	PriceImpactPct       float64 // how far the swap moves the curve's price
+}
+
+// NewPumpFunSwap creates a Pump.fun swap builder
+func NewPumpFunSwap(client PumpFunClient) *PumpFunSwap {
+	return &PumpFunSwap{client: client}
+}
+
+// UsePumpFun reports whether a failed Jupiter quote should be retried on
+// the Pump.fun bonding curve: only a missing route points to a token that
+// hasn't graduated
+func UsePumpFun(quoteErr error) bool {
+	return errors.Is(quoteErr, ErrNoRoute)
+}
+
+// BondingCurve returns mint's active bonding curve, or ErrNotOnBondingCurve
+// if it has none or has graduated
+func (p *PumpFunSwap) BondingCurve(ctx context.Context, mint solana.PublicKey) (*BondingCurve, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{[]byte("bonding-curve"), mint.Bytes()}, PumpFunProgramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive bonding curve: %w", err)
+	}
+	info, err := p.client.GetAccountInfo(ctx, address)
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && (info == nil || info.Value == nil)) {
+		return nil, ErrNotOnBondingCurve
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read bonding curve: %v", ErrRPCUnavailable, err)
+	}
+
+	curve, err := DecodeBondingCurve(info.Value.Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	if curve.Complete {
+		return nil, fmt.Errorf("%w: graduated", ErrNotOnBondingCurve)
+	}
+	curve.Address = address
+	return curve, nil
+}
+
+// BuildBuy builds a buy of mint with lamports of SOL. The program is asked
+// for the tokens lamports buys now and may spend up to slippageBps more.
+func (p *PumpFunSwap) BuildBuy(ctx context.Context, user, mint solana.PublicKey, lamports uint64, slippageBps int, priorityFee int64) (*PumpFunTx, error) {
+	curve, accounts, tokenProgram, err := p.swapAccounts(ctx, user, mint)
+	if err != nil {
+		return nil, err
+	}
+	tokens := curve.BuyQuote(lamports)
+	if tokens == 0 {
+		return nil, fmt.Errorf("%w: bonding curve has no tokens left", ErrInsufficientLiquidity)
+	}
+	maxCost := mulDiv(lamports, uint64(10000+slippageBps), 10000)
+
+	// The user's token account usually doesn't exist for a fresh launch
+	createATA := solana.NewInstruction(
+		solana.SPLAssociatedTokenAccountProgramID,
+		solana.AccountMetaSlice{
+			solana.Meta(user).WRITE().SIGNER(),
+			solana.Meta(accounts.userATA).WRITE(),
+			solana.Meta(user),
+			solana.Meta(mint),
+			solana.Meta(solana.SystemProgramID),
+			solana.Meta(tokenProgram),
+		},
+		[]byte{1}, // CreateIdempotent
+	)
+	buy := solana.NewInstruction(PumpFunProgramID, solana.AccountMetaSlice{
+		solana.Meta(pumpFunGlobal),
+		solana.Meta(pumpFunFeeRecipient).WRITE(),
+		solana.Meta(mint),
+		solana.Meta(curve.Address).WRITE(),
+		solana.Meta(accounts.curveATA).WRITE(),
+		solana.Meta(accounts.userATA).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(tokenProgram),
+		solana.Meta(accounts.creatorVault).WRITE(),
+		solana.Meta(pumpFunEventAuthority),
+		solana.Meta(PumpFunProgramID),
+	}, pumpSwapData(pumpBuyDiscriminator, tokens, maxCost))
+
+	return p.transaction(ctx, user, tokens, curve.BuyImpact(lamports), priorityFee, createATA, buy)
+}
+
+// BuildSell builds a sale of tokens raw units of mint, failing on-chain if
+// it would pay more than slippageBps under the current quote
+func (p *PumpFunSwap) BuildSell(ctx context.Context, user, mint solana.PublicKey, tokens uint64, slippageBps int, priorityFee int64) (*PumpFunTx, error) {
+	curve, accounts, tokenProgram, err := p.swapAccounts(ctx, user, mint)
+	if err != nil {
+		return nil, err
+	}
+	lamports := curve.SellQuote(tokens)
+	if lamports == 0 {
+		return nil, fmt.Errorf("%w: sale pays nothing on the bonding curve", ErrInsufficientLiquidity)
+	}
+	minOut := mulDiv(lamports, uint64(max(10000-slippageBps, 0)), 10000)
+
+	sell := solana.NewInstruction(PumpFunProgramID, solana.AccountMetaSlice{
+		solana.Meta(pumpFunGlobal),
+		solana.Meta(pumpFunFeeRecipient).WRITE(),
+		solana.Meta(mint),
+		solana.Meta(curve.Address).WRITE(),
+		solana.Meta(accounts.curveATA).WRITE(),
+		solana.Meta(accounts.userATA).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(accounts.creatorVault).WRITE(),
+		solana.Meta(tokenProgram),
+		solana.Meta(pumpFunEventAuthority),
+		solana.Meta(PumpFunProgramID),
+	}, pumpSwapData(pumpSellDiscriminator, tokens, minOut))
+
+	return p.transaction(ctx, user, lamports, curve.SellImpact(tokens), priorityFee, sell)
+}
+
+// pumpAccounts are the per-trade accounts of a Pump.fun swap
+type pumpAccounts struct {
+	curveATA     solana.PublicKey
+	userATA      solana.PublicKey
+	creatorVault solana.PublicKey
+}
+
+// swapAccounts loads mint's bonding curve and derives the token accounts
+// and creator vault a swap touches
+func (p *PumpFunSwap) swapAccounts(ctx context.Context, user, mint solana.PublicKey) (*BondingCurve, *pumpAccounts, solana.PublicKey, error) {
+	curve, err := p.BondingCurve(ctx, mint)
+	if err != nil {
+		return nil, nil, solana.PublicKey{}, err
+	}
+
+	// Newer launches mint through Token-2022, which changes the ATAs
+	tokenProgram, err := mintTokenProgram(ctx, p.client, mint)
+	if err != nil {
+		return nil, nil, solana.PublicKey{}, err
+	}
+
+	accounts := &pumpAccounts{}
+	if accounts.curveATA, err = associatedTokenAddress(curve.Address, mint, tokenProgram); err != nil {
+		return nil, nil, solana.PublicKey{}, err
+	}
+	if accounts.userATA, err = associatedTokenAddress(user, mint, tokenProgram); err != nil {
+		return nil, nil, solana.PublicKey{}, err
+	}
+	if accounts.creatorVault, _, err = solana.FindProgramAddress([][]byte{[]byte("creator-vault"), curve.Creator.Bytes()}, PumpFunProgramID); err != nil {
+		return nil, nil, solana.PublicKey{}, fmt.Errorf("failed to derive creator vault: %w", err)
+	}
+	return curve, accounts, tokenProgram, nil
+}
+
+// transaction wraps instructions with a compute budget spreading
+// priorityFee over pumpFunComputeUnits
+func (p *PumpFunSwap) transaction(ctx context.Context, user solana.PublicKey, expectedOut uint64, impact float64, priorityFee int64, instructions ...solana.Instruction) (*PumpFunTx, error) {
+	blockhash, err := p.client.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil || blockhash == nil || blockhash.Value == nil {
+		return nil, fmt.Errorf("%w: failed to get blockhash: %v", ErrRPCUnavailable, err)
+	}
+
+	limit := make([]byte, 5)
+	limit[0] = 2 // SetComputeUnitLimit
+	binary.LittleEndian.PutUint32(limit[1:], pumpFunComputeUnits)
+	price := make([]byte, 9)
+	price[0] = 3 // SetComputeUnitPrice, in micro-lamports per unit
+	binary.LittleEndian.PutUint64(price[1:], uint64(ClampPriorityFee(priorityFee))*1_000_000/pumpFunComputeUnits)
+
+	all := append([]solana.Instruction{
+		solana.NewInstruction(computeBudgetProgram, solana.AccountMetaSlice{}, limit),
+		solana.NewInstruction(computeBudgetProgram, solana.AccountMetaSlice{}, price),
+	}, instructions...)
+
+	tx, err := solana.NewTransaction(all, blockhash.Value.Blockhash, solana.TransactionPayer(user))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build pump.fun transaction: %w", err)
+	}
+	return &PumpFunTx{Transaction: tx, LastValidBlockHeight: blockhash.Value.LastValidBlockHeight, ExpectedOut: expectedOut, PriceImpactPct: impact}, nil
+}
+
+// pumpSwapData encodes a buy or sell: the discriminator, the token amount
+// and the SOL bound
+func pumpSwapData(discriminator []byte, tokens, solBound uint64) []byte {
+	data := make([]byte, 24)
+	copy(data, discriminator)
+	binary.LittleEndian.PutUint64(data[8:], tokens)
+	binary.LittleEndian.PutUint64(data[16:], solBound)
+	return data
+}
+
+// associatedTokenAddress derives owner's ATA for mint under tokenProgram,
+// which FindAssociatedTokenAddress fixes to the original token program
+func associatedTokenAddress(owner, mint, tokenProgram solana.PublicKey) (solana.PublicKey, error) {
+	ata, _, err := solana.FindProgramAddress([][]byte{owner.Bytes(), tokenProgram.Bytes(), mint.Bytes()}, solana.SPLAssociatedTokenAccountProgramID)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive ATA: %w", err)
+	}
+	return ata, nil
+}
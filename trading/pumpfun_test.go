@@ -0,0 +1,173 @@
+package trading
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// fakePumpRPC serves accounts with their owners and a fixed blockhash
+type fakePumpRPC struct {
+	accounts map[solana.PublicKey][]byte
+	owners   map[solana.PublicKey]solana.PublicKey
+}
+
+func (f *fakePumpRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	data, ok := f.accounts[account]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{Owner: f.owners[account], Data: rpc.DataBytesOrJSONFromBytes(data)}}, nil
+}
+
+func (f *fakePumpRPC) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 1000}}, nil
+}
+
+// bondingCurve lays out a BondingCurve account at launch reserves
+func bondingCurve(complete bool, creator solana.PublicKey) []byte {
+	data := make([]byte, pumpCurveCreator+32)
+	copy(data, pumpCurveDiscriminator)
+	binary.LittleEndian.PutUint64(data[pumpCurveVirtualToken:], 1_073_000_000_000_000)
+	binary.LittleEndian.PutUint64(data[pumpCurveVirtualSOL:], 30_000_000_000)
+	binary.LittleEndian.PutUint64(data[pumpCurveRealToken:], 793_100_000_000_000)
+	binary.LittleEndian.PutUint64(data[pumpCurveSupply:], 1_000_000_000_000_000)
+	if complete {
+		data[pumpCurveComplete] = 1
+	}
+	copy(data[pumpCurveCreator:], creator[:])
+	return data
+}
+
+func TestBondingCurveQuotes(t *testing.T) {
+	curve, err := DecodeBondingCurve(bondingCurve(false, solana.PublicKey{}))
+	if err != nil {
+		t.Fatalf("DecodeBondingCurve failed: %v", err)
+	}
+	if curve.Complete || curve.VirtualSOLReserves != 30_000_000_000 {
+		t.Fatalf("Unexpected curve %+v", curve)
+	}
+
+	// 1 SOL less the 1% fee, against launch reserves
+	if got := curve.BuyQuote(1_000_000_000); got != 34_281_150_129_545 {
+		t.Errorf("Expected 34281150129545 tokens for 1 SOL, got %d", got)
+	}
+	if got := curve.SellQuote(10_000_000_000_000); got != 274_238_227 {
+		t.Errorf("Expected 274238227 lamports for 10M tokens, got %d", got)
+	}
+
+	// Launch reserves hold 30 SOL, so 0.99 SOL after fees moves the price
+	// 0.99/30.99 and 10M of 1.073B tokens about 0.92%
+	if got := curve.BuyImpact(1_000_000_000); math.Abs(got-3.1949) > 0.001 {
+		t.Errorf("Expected ~3.19%% impact for 1 SOL, got %.4f%%", got)
+	}
+	if got := curve.SellImpact(10_000_000_000_000); math.Abs(got-0.9234) > 0.001 {
+		t.Errorf("Expected ~0.92%% impact for 10M tokens, got %.4f%%", got)
+	}
+
+	// A buy can't take more than the curve still holds
+	curve.RealTokenReserves = 5
+	if got := curve.BuyQuote(1_000_000_000); got != 5 {
+		t.Errorf("Expected the buy capped at the real reserves, got %d", got)
+	}
+
+	if _, err := DecodeBondingCurve(make([]byte, 81)); !errors.Is(err, ErrNotOnBondingCurve) {
+		t.Errorf("Expected ErrNotOnBondingCurve for another account, got %v", err)
+	}
+}
+
+func TestPumpFunSwap(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	creator := solana.NewWallet().PublicKey()
+	fresh, graduated, unknown := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	curveOf := func(mint solana.PublicKey) solana.PublicKey {
+		addr, _, _ := solana.FindProgramAddress([][]byte{[]byte("bonding-curve"), mint.Bytes()}, PumpFunProgramID)
+		return addr
+	}
+
+	client := &fakePumpRPC{
+		accounts: map[solana.PublicKey][]byte{
+			fresh:              mintAccount(6),
+			curveOf(fresh):     bondingCurve(false, creator),
+			graduated:          mintAccount(6),
+			curveOf(graduated): bondingCurve(true, creator),
+			unknown:            mintAccount(6),
+		},
+		owners: map[solana.PublicKey]solana.PublicKey{fresh: Token2022ProgramID},
+	}
+	swap := NewPumpFunSwap(client)
+	ctx := context.Background()
+
+	for _, mint := range []solana.PublicKey{graduated, unknown} {
+		if _, err := swap.BuildBuy(ctx, user, mint, 1_000_000_000, 300, 0); !errors.Is(err, ErrNotOnBondingCurve) {
+			t.Errorf("Expected ErrNotOnBondingCurve for %s, got %v", mint, err)
+		}
+	}
+
+	// pumpInstruction returns the Pump.fun instruction's accounts and data
+	pumpInstruction := func(tx *solana.Transaction) ([]solana.PublicKey, []byte) {
+		t.Helper()
+		for _, ix := range tx.Message.Instructions {
+			if tx.Message.AccountKeys[ix.ProgramIDIndex].Equals(PumpFunProgramID) {
+				var accounts []solana.PublicKey
+				for _, i := range ix.Accounts {
+					accounts = append(accounts, tx.Message.AccountKeys[i])
+				}
+				return accounts, ix.Data
+			}
+		}
+		t.Fatal("No Pump.fun instruction in the transaction")
+		return nil, nil
+	}
+	userATA, _ := associatedTokenAddress(user, fresh, Token2022ProgramID)
+	vault, _, _ := solana.FindProgramAddress([][]byte{[]byte("creator-vault"), creator.Bytes()}, PumpFunProgramID)
+
+	buy, err := swap.BuildBuy(ctx, user, fresh, 1_000_000_000, 300, 0)
+	if err != nil {
+		t.Fatalf("BuildBuy failed: %v", err)
+	}
+	if buy.ExpectedOut != 34_281_150_129_545 || buy.LastValidBlockHeight != 1000 {
+		t.Errorf("Unexpected buy %+v", buy)
+	}
+	if !buy.Transaction.Message.AccountKeys[0].Equals(user) {
+		t.Errorf("Expected the user to pay, got %s", buy.Transaction.Message.AccountKeys[0])
+	}
+	accounts, data := pumpInstruction(buy.Transaction)
+	if string(data[:8]) != string(pumpBuyDiscriminator) ||
+		binary.LittleEndian.Uint64(data[8:]) != 34_281_150_129_545 ||
+		binary.LittleEndian.Uint64(data[16:]) != 1_030_000_000 {
+		t.Errorf("Expected a buy of the quoted tokens for at most 1.03 SOL, got %x", data)
+	}
+	if !accounts[3].Equals(curveOf(fresh)) || !accounts[5].Equals(userATA) || !accounts[8].Equals(Token2022ProgramID) || !accounts[9].Equals(vault) {
+		t.Errorf("Unexpected buy accounts %v", accounts)
+	}
+
+	sell, err := swap.BuildSell(ctx, user, fresh, 10_000_000_000_000, 300, 0)
+	if err != nil {
+		t.Fatalf("BuildSell failed: %v", err)
+	}
+	accounts, data = pumpInstruction(sell.Transaction)
+	if string(data[:8]) != string(pumpSellDiscriminator) ||
+		binary.LittleEndian.Uint64(data[8:]) != 10_000_000_000_000 ||
+		binary.LittleEndian.Uint64(data[16:]) != 266_011_080 {
+		t.Errorf("Expected a sale of 10M tokens for at least the quote less 3%%, got %x", data)
+	}
+	if !accounts[8].Equals(vault) || !accounts[9].Equals(Token2022ProgramID) {
+		t.Errorf("Unexpected sell accounts %v", accounts)
+	}
+}
+
+func TestUsePumpFun(t *testing.T) {
+	noRoute := classifyJupiterError(400, []byte(`{"errorCode": "COULD_NOT_FIND_ANY_ROUTE"}`))
+	if !UsePumpFun(noRoute) {
+		t.Error("Expected a missing route to try Pump.fun")
+	}
+	if UsePumpFun(classifyJupiterError(503, nil)) {
+		t.Error("Expected an unreachable Jupiter not to try Pump.fun")
+	}
+}
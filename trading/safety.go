@@ -0,0 +1,181 @@
+package trading
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// SPL mint layout: a COption<Pubkey> mint authority, the u64 supply, the
+// u8 decimals and is_initialized, then a COption<Pubkey> freeze authority
+const (
+	mintAuthorityOption = 0
+	mintFreezeOption    = 46
+	mintAccountSize     = 82
+	cOptionPubkeyLength = 36 // u32 tag then the key
+)
+
+// roundTripLamports is the buy CheckRoundTrip quotes, 0.01 SOL
+const roundTripLamports = 10_000_000
+
+// Risk levels for a SafetyReport's score
+const (
+	RiskLow    = "Low"
+	RiskMedium = "Medium"
+	RiskHigh   = "High"
+)
+
+// MintAuthorities are the keys still able to mint or freeze a token; nil
+// means the authority was revoked
+type MintAuthorities struct {
+	Mint   *solana.PublicKey
+	Freeze *solana.PublicKey
+}
+
+// RoundTrip is the result of quoting a small buy and selling it straight
+// back. A token with no sell route is a honeypot.
+type RoundTrip struct {
+	Sellable bool
+	LossPct  float64 // SOL lost buying and selling back, fees and impact included
+}
+
+// SafetyReport collects the checks a buy preview runs on a token. Checks
+// that failed to run are nil and don't count for or against the token.
+type SafetyReport struct {
+	Authorities  *MintAuthorities
+	TopHolderPct *float64 // share of supply held by the top holders
+	RoundTrip    *RoundTrip
+}
+
+// DecodeMintAuthorities reads the mint and freeze authorities from a mint
+// account
+func DecodeMintAuthorities(data []byte) (*MintAuthorities, error) {
+	if len(data) < mintAccountSize {
+		return nil, fmt.Errorf("mint account is %d bytes", len(data))
+	}
+	return &MintAuthorities{
+		Mint:   decodeCOptionPubkey(data[mintAuthorityOption : mintAuthorityOption+cOptionPubkeyLength]),
+		Freeze: decodeCOptionPubkey(data[mintFreezeOption : mintFreezeOption+cOptionPubkeyLength]),
+	}, nil
+}
+
+func decodeCOptionPubkey(data []byte) *solana.PublicKey {
+	if binary.LittleEndian.Uint32(data) == 0 {
+		return nil
+	}
+	key := solana.PublicKeyFromBytes(data[4:])
+	return &key
+}
+
+// GetMintAuthorities reads a token's mint and freeze authorities on chain
+func GetMintAuthorities(ctx context.Context, client AccountInfoClient, tokenMint string) (*MintAuthorities, error) {
+	mint, err := solana.PublicKeyFromBase58(tokenMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mint address: %w", err)
+	}
+	data, err := readAccount(ctx, client, mint)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMintAuthorities(data)
+}
+
+// CheckRoundTrip quotes buying a token with 0.01 SOL and selling the tokens
+// straight back
+func CheckRoundTrip(ctx context.Context, tokenMint string) (*RoundTrip, error) {
+	buy, err := GetBuyQuote(ctx, tokenMint, roundTripLamports, 50)
+	if err != nil {
+		return nil, fmt.Errorf("buy quote: %w", err)
+	}
+	tokens, err := strconv.ParseUint(buy.OutAmount, 10, 64)
+	if err != nil || tokens == 0 {
+		return nil, fmt.Errorf("%w: buy quote returns %q tokens", ErrQuoteFailed, buy.OutAmount)
+	}
+
+	sell, err := GetSellQuote(ctx, tokenMint, tokens, 50)
+	if errors.Is(err, ErrNoRoute) {
+		return &RoundTrip{Sellable: false, LossPct: 100}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sell quote: %w", err)
+	}
+	lamports, err := strconv.ParseUint(sell.OutAmount, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sell quote returns %q lamports", ErrQuoteFailed, sell.OutAmount)
+	}
+	return &RoundTrip{Sellable: true, LossPct: roundTripLoss(roundTripLamports, lamports)}, nil
+}
+
+// roundTripLoss is the percentage of in lost when only out comes back
+func roundTripLoss(in, out uint64) float64 {
+	if out >= in {
+		return 0
+	}
+	return float64(in-out) / float64(in) * 100
+}
+
+// Score rates a token from 0 (avoid) to 100 (no red flags found). A token
+// that can't be sold scores 0 whatever else is known about it.
+func (r *SafetyReport) Score() int {
+	if r.RoundTrip != nil && !r.RoundTrip.Sellable {
+		return 0
+	}
+
+	score := 100
+	if a := r.Authorities; a != nil {
+		if a.Mint != nil {
+			score -= 30 // supply can be inflated
+		}
+		if a.Freeze != nil {
+			score -= 30 // holders' accounts can be frozen
+		}
+	}
+	if pct := r.TopHolderPct; pct != nil {
+		switch {
+		case *pct > 50:
+			score -= 25
+		case *pct > 30:
+			score -= 10
+		}
+	}
+	if rt := r.RoundTrip; rt != nil {
+		switch {
+		case rt.LossPct > 20:
+			score -= 25
+		case rt.LossPct > 10:
+			score -= 10
+		}
+	}
+	return max(score, 0)
+}
+
+// Risk is the risk level for the report's score
+func (r *SafetyReport) Risk() string {
+	switch score := r.Score(); {
+	case score >= 80:
+		return RiskLow
+	case score >= 50:
+		return RiskMedium
+	default:
+		return RiskHigh
+	}
+}
+
+// Checked reports how many of the three checks ran
+func (r *SafetyReport) Checked() int {
+	n := 0
+	if r.Authorities != nil {
+		n++
+	}
+	if r.TopHolderPct != nil {
+		n++
+	}
+	if r.RoundTrip != nil {
+		n++
+	}
+	return n
+}
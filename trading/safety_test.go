@@ -0,0 +1,86 @@
+package trading
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// TestSafetyScore tests how the buy preview's checks add up to a score
+// and risk level
+func TestSafetyScore(t *testing.T) {
+	key := solana.NewWallet().PublicKey()
+	pct := func(v float64) *float64 { return &v }
+	revoked := &MintAuthorities{}
+
+	tests := []struct {
+		name      string
+		report    SafetyReport
+		wantScore int
+		wantRisk  string
+	}{
+		{"nothing known", SafetyReport{}, 100, RiskLow},
+		{"clean", SafetyReport{Authorities: revoked, TopHolderPct: pct(12), RoundTrip: &RoundTrip{Sellable: true, LossPct: 1.5}}, 100, RiskLow},
+		{"mint authority", SafetyReport{Authorities: &MintAuthorities{Mint: &key}}, 70, RiskMedium},
+		{"both authorities", SafetyReport{Authorities: &MintAuthorities{Mint: &key, Freeze: &key}}, 40, RiskHigh},
+		{"concentrated", SafetyReport{TopHolderPct: pct(35)}, 90, RiskLow},
+		{"very concentrated", SafetyReport{TopHolderPct: pct(80)}, 75, RiskMedium},
+		{"lossy round trip", SafetyReport{RoundTrip: &RoundTrip{Sellable: true, LossPct: 15}}, 90, RiskLow},
+		{"taxed round trip", SafetyReport{RoundTrip: &RoundTrip{Sellable: true, LossPct: 40}}, 75, RiskMedium},
+		{"everything wrong", SafetyReport{Authorities: &MintAuthorities{Mint: &key, Freeze: &key}, TopHolderPct: pct(90), RoundTrip: &RoundTrip{Sellable: true, LossPct: 50}}, 0, RiskHigh},
+		{"honeypot", SafetyReport{Authorities: revoked, TopHolderPct: pct(5), RoundTrip: &RoundTrip{Sellable: false, LossPct: 100}}, 0, RiskHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.report.Score(); got != tt.wantScore {
+				t.Errorf("Expected score %d, got %d", tt.wantScore, got)
+			}
+			if got := tt.report.Risk(); got != tt.wantRisk {
+				t.Errorf("Expected %s risk, got %s", tt.wantRisk, got)
+			}
+		})
+	}
+
+	full := SafetyReport{Authorities: revoked, TopHolderPct: pct(1), RoundTrip: &RoundTrip{Sellable: true}}
+	if full.Checked() != 3 || (&SafetyReport{TopHolderPct: pct(1)}).Checked() != 1 {
+		t.Error("Expected Checked to count the checks that ran")
+	}
+}
+
+// TestDecodeMintAuthorities tests reading the authorities' COptions from
+// a mint account
+func TestDecodeMintAuthorities(t *testing.T) {
+	mintAuth, freezeAuth := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	data := mintAccount(6)
+	binary.LittleEndian.PutUint32(data[mintAuthorityOption:], 1)
+	copy(data[mintAuthorityOption+4:], mintAuth[:])
+	binary.LittleEndian.PutUint32(data[mintFreezeOption:], 1)
+	copy(data[mintFreezeOption+4:], freezeAuth[:])
+
+	got, err := DecodeMintAuthorities(data)
+	if err != nil {
+		t.Fatalf("DecodeMintAuthorities failed: %v", err)
+	}
+	if got.Mint == nil || *got.Mint != mintAuth || got.Freeze == nil || *got.Freeze != freezeAuth {
+		t.Errorf("Expected both authorities, got %+v", got)
+	}
+	if d, _ := DecodeMintDecimals(data); d != 6 {
+		t.Errorf("Expected the authorities to leave the decimals alone, got %d", d)
+	}
+
+	got, err = DecodeMintAuthorities(mintAccount(6))
+	if err != nil || got.Mint != nil || got.Freeze != nil {
+		t.Errorf("Expected revoked authorities, got %+v (%v)", got, err)
+	}
+	if _, err := DecodeMintAuthorities(make([]byte, 40)); err == nil {
+		t.Error("Expected a short account to be rejected")
+	}
+
+	if loss := roundTripLoss(10_000_000, 9_000_000); loss != 10 {
+		t.Errorf("Expected a 10%% round-trip loss, got %g", loss)
+	}
+	if loss := roundTripLoss(10_000_000, 11_000_000); loss != 0 {
+		t.Errorf("Expected no loss when more comes back, got %g", loss)
+	}
+}
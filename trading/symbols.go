@@ -0,0 +1,112 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"solana-orchestrator/storage"
+)
+
+// symbolTTL is how long a cached symbol is trusted before it is refetched
+const symbolTTL = 24 * time.Hour
+
+// knownSymbols covers quote tokens so they never cost a lookup
+var knownSymbols = map[string]string{
+	SOL_MINT:  "SOL",
+	USDC_MINT: "USDC",
+	USDT_MINT: "USDT",
+}
+
+// SymbolStore persists token metadata between lookups; storage.DB
+// implements it
+type SymbolStore interface {
+	GetTokenMetadata(mint string) (*storage.TokenMetadata, error)
+	SaveTokenMetadata(m *storage.TokenMetadata) error
+}
+
+// symbolResolver looks up token symbols through a persistent cache
+type symbolResolver struct {
+	mu     sync.RWMutex
+	store  SymbolStore       // nil fetches on every lookup
+	client AccountInfoClient // nil leaves decimals unknown
+	fetch  func(ctx context.Context, mint string) (*TokenInfo, error)
+	now    func() time.Time
+}
+
+var symbols = &symbolResolver{fetch: GetTokenInfo, now: time.Now}
+
+// SetSymbolCache makes ResolveSymbol cache metadata in store. A non-nil
+// client is used to read mint decimals alongside.
+func SetSymbolCache(store SymbolStore, client AccountInfoClient) {
+	symbols.mu.Lock()
+	defer symbols.mu.Unlock()
+	symbols.store, symbols.client = store, client
+}
+
+// ResolveSymbol returns the symbol of a token mint. Cached symbols are
+// refetched from DexScreener once older than a day; if that fails the
+// stale symbol is still returned.
+func ResolveSymbol(ctx context.Context, mint string) (string, error) {
+	return symbols.resolve(ctx, mint)
+}
+
+func (r *symbolResolver) resolve(ctx context.Context, mint string) (string, error) {
+	if symbol, ok := knownSymbols[mint]; ok {
+		return symbol, nil
+	}
+
+	r.mu.RLock()
+	store, client := r.store, r.client
+	r.mu.RUnlock()
+
+	var cached *storage.TokenMetadata
+	if store != nil {
+		var err error
+		if cached, err = store.GetTokenMetadata(mint); err != nil {
+			log.Printf("⚠️ Failed to read cached metadata for %s: %v", mint, err)
+		}
+		if cached != nil && r.now().Sub(time.Unix(cached.UpdatedAt, 0)) < symbolTTL {
+			return cached.Symbol, nil
+		}
+	}
+
+	info, err := r.fetch(ctx, mint)
+	if err != nil {
+		if cached != nil {
+			return cached.Symbol, nil
+		}
+		return "", fmt.Errorf("failed to resolve symbol of %s: %w", mint, err)
+	}
+	if store == nil {
+		return info.Symbol, nil
+	}
+
+	meta := &storage.TokenMetadata{Mint: mint, Symbol: info.Symbol, Name: info.Name, Decimals: -1}
+	if client != nil {
+		if decimals, err := fetchMintDecimals(ctx, client, mint); err == nil {
+			meta.Decimals = int(decimals)
+		}
+	}
+	if err := store.SaveTokenMetadata(meta); err != nil {
+		log.Printf("⚠️ Failed to cache metadata for %s: %v", mint, err)
+	}
+	return info.Symbol, nil
+}
+
+// fetchMintDecimals reads the decimals of a mint account
+func fetchMintDecimals(ctx context.Context, client AccountInfoClient, mint string) (uint8, error) {
+	key, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mint: %w", err)
+	}
+	data, err := readAccount(ctx, client, key)
+	if err != nil {
+		return 0, err
+	}
+	return DecodeMintDecimals(data)
+}
@@ -0,0 +1,81 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"solana-orchestrator/storage"
+)
+
+// fakeSymbolStore is an in-memory SymbolStore stamped by a settable clock
+type fakeSymbolStore struct {
+	rows map[string]storage.TokenMetadata
+	now  time.Time
+}
+
+func (f *fakeSymbolStore) GetTokenMetadata(mint string) (*storage.TokenMetadata, error) {
+	if m, ok := f.rows[mint]; ok {
+		return &m, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeSymbolStore) SaveTokenMetadata(m *storage.TokenMetadata) error {
+	row := *m
+	row.UpdatedAt = f.now.Unix()
+	f.rows[m.Mint] = row
+	return nil
+}
+
+func TestResolveSymbol(t *testing.T) {
+	const bonk = "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263"
+	now := time.Unix(1700000000, 0)
+	store := &fakeSymbolStore{rows: make(map[string]storage.TokenMetadata), now: now}
+
+	calls := 0
+	var fetchErr error
+	r := &symbolResolver{
+		store: store,
+		fetch: func(ctx context.Context, mint string) (*TokenInfo, error) {
+			calls++
+			if fetchErr != nil {
+				return nil, fetchErr
+			}
+			return &TokenInfo{Address: mint, Symbol: "BONK", Name: "Bonk"}, nil
+		},
+		now: func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	if symbol, err := r.resolve(ctx, SOL_MINT); err != nil || symbol != "SOL" || calls != 0 {
+		t.Errorf("Expected SOL without a lookup, got %q, %v after %d fetches", symbol, err, calls)
+	}
+
+	for i := 0; i < 3; i++ {
+		if symbol, err := r.resolve(ctx, bonk); err != nil || symbol != "BONK" {
+			t.Fatalf("Expected BONK, got %q, %v", symbol, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 fetch, got %d", calls)
+	}
+	if m := store.rows[bonk]; m.Name != "Bonk" || m.Decimals != -1 {
+		t.Errorf("Unexpected cached metadata %+v", m)
+	}
+
+	// A day later the entry is refreshed, and kept if the refresh fails
+	now = now.Add(symbolTTL)
+	fetchErr = errors.New("rate limited")
+	if symbol, err := r.resolve(ctx, bonk); err != nil || symbol != "BONK" {
+		t.Errorf("Expected the stale symbol, got %q, %v", symbol, err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected a refresh attempt, got %d fetches", calls)
+	}
+
+	if _, err := r.resolve(ctx, "unknownMint"); err == nil {
+		t.Error("Expected an error for an uncached mint that can't be fetched")
+	}
+}
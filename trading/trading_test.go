@@ -2,16 +2,26 @@ package trading
 
 import (
 	"context"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
 )
 
+// shyftKey returns the Shyft API key for tests that hit the network,
+// skipping them when SOLORCH_SHYFT_API_KEY isn't set
+func shyftKey(t *testing.T) string {
+	key := os.Getenv("SOLORCH_SHYFT_API_KEY")
+	if key == "" {
+		t.Skip("SOLORCH_SHYFT_API_KEY not set")
+	}
+	return key
+}
+
 // TestWebSocketClient tests the WebSocket client functionality
 func TestWebSocketClient(t *testing.T) {
-	wsURL := "wss://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR"
-	client := NewWSClient(wsURL)
+	client := NewWSClient("wss://rpc.shyft.to?api_key=" + shyftKey(t))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -53,8 +63,9 @@ func TestWebSocketClient(t *testing.T) {
 
 // TestBalanceManager tests balance checking functionality
 func TestBalanceManager(t *testing.T) {
-	rpcURL := "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR"
-	wsClient := NewWSClient("wss://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR")
+	key := shyftKey(t)
+	rpcURL := "https://rpc.shyft.to?api_key=" + key
+	wsClient := NewWSClient("wss://rpc.shyft.to?api_key=" + key)
 
 	balanceMgr := NewBalanceManager(rpcURL, wsClient)
 
@@ -79,9 +90,7 @@ func TestBalanceManager(t *testing.T) {
 	t.Run("GetTokenBalances", func(t *testing.T) {
 		tokens, err := balanceMgr.GetTokenBalances(ctx, testWallet)
 		if err != nil {
-			t.Logf("Token balance fetch error (expected for free plan): %v", err)
-			// Don't fail - Shyft free plan doesn't support this
-			return
+			t.Fatalf("Failed to get token balances: %v", err)
 		}
 
 		t.Logf("Found %d token accounts", len(tokens))
@@ -146,7 +155,7 @@ func TestJitoClient(t *testing.T) {
 
 // TestRateLimiting tests rate limiter functionality
 func TestRateLimiting(t *testing.T) {
-	wsClient := NewWSClient("wss://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR")
+	wsClient := NewWSClient("wss://rpc.shyft.to")
 
 	t.Run("RPS Limit", func(t *testing.T) {
 		ctx := context.Background()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,12 +12,40 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// wsSubscription tracks one subscription and the server ID it was given
+type wsSubscription struct {
+	ch          chan interface{}
+	method      string        // e.g. "accountSubscribe"
+	params      []interface{} // resent on reconnect
+	serverID    uint64
+	hasServerID bool
+}
+
+// pendingSubscribe is a subscribe request waiting for the server's ack
+type pendingSubscribe struct {
+	key string
+	sub *wsSubscription
+}
+
+// wsMessage covers both subscription acks and notifications
+type wsMessage struct {
+	ID     *uint64         `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Method string          `json:"method"`
+	Params struct {
+		Subscription uint64 `json:"subscription"`
+	} `json:"params"`
+}
+
 // WSClient manages WebSocket connection to Shyft
 type WSClient struct {
 	url            string
 	conn           *websocket.Conn
 	mu             sync.RWMutex
-	subscriptions  map[string]chan interface{}
+	subscriptions  map[string]*wsSubscription
+	pending        map[uint64]pendingSubscribe // request ID -> subscribe awaiting ack
+	byServerID     map[uint64]string           // server subscription ID -> subscription key
+	nextRequestID  uint64
 	reconnectDelay time.Duration
 	pingInterval   time.Duration
 	rpsLimiter     *rate.Limiter // 20 RPS
@@ -29,7 +58,9 @@ type WSClient struct {
 func NewWSClient(url string) *WSClient {
 	return &WSClient{
 		url:            url,
-		subscriptions:  make(map[string]chan interface{}),
+		subscriptions:  make(map[string]*wsSubscription),
+		pending:        make(map[uint64]pendingSubscribe),
+		byServerID:     make(map[uint64]string),
 		reconnectDelay: 5 * time.Second,
 		pingInterval:   30 * time.Second,
 		rpsLimiter:     rate.NewLimiter(rate.Limit(20), 20), // 20 RPS
@@ -81,7 +112,12 @@ func (ws *WSClient) handleMessages() {
 			_, message, err := ws.conn.ReadMessage()
 			if err != nil {
 				fmt.Printf("WebSocket read error: %v\n", err)
-				// Trigger reconnection
+				// Mark the connection down before reconnecting, or
+				// reconnect may still see it up and give up, leaving
+				// every subscription dead
+				ws.mu.Lock()
+				ws.isConnected = false
+				ws.mu.Unlock()
 				go ws.reconnect()
 				return
 			}
@@ -92,24 +128,64 @@ func (ws *WSClient) handleMessages() {
 	}
 }
 
-// routeMessage distributes messages to subscribers
+// routeMessage records subscription acks and distributes notifications
+// to subscribers
 func (ws *WSClient) routeMessage(message []byte) {
+	var msg wsMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return
+	}
+
+	// Ack for a subscribe request: result is the server subscription ID
+	if msg.ID != nil && msg.Method == "" {
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+
+		p, ok := ws.pending[*msg.ID]
+		if !ok {
+			return
+		}
+		delete(ws.pending, *msg.ID)
+
+		var serverID uint64
+		if err := json.Unmarshal(msg.Result, &serverID); err != nil {
+			fmt.Printf("Subscription %s rejected: %s\n", p.key, string(message))
+			return
+		}
+
+		if ws.subscriptions[p.key] != p.sub {
+			// Unsubscribed or replaced before the ack arrived
+			ws.sendUnsubscribeLocked(unsubscribeMethod(p.sub.method), serverID)
+			return
+		}
+		p.sub.serverID = serverID
+		p.sub.hasServerID = true
+		ws.byServerID[serverID] = p.key
+		return
+	}
+
+	if !strings.HasSuffix(msg.Method, "Notification") {
+		return
+	}
+
 	var data map[string]interface{}
 	if err := json.Unmarshal(message, &data); err != nil {
 		return
 	}
 
-	// Extract subscription ID and send to appropriate channel
-	if subID, ok := data["subscription"].(string); ok {
-		ws.mu.RLock()
-		if ch, exists := ws.subscriptions[subID]; exists {
-			select {
-			case ch <- data:
-			default:
-				// Channel full, skip
-			}
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	key, ok := ws.byServerID[msg.Params.Subscription]
+	if !ok {
+		return
+	}
+	if sub, exists := ws.subscriptions[key]; exists {
+		select {
+		case sub.ch <- data:
+		default:
+			// Channel full, skip
 		}
-		ws.mu.RUnlock()
 	}
 }
 
@@ -166,41 +242,30 @@ func (ws *WSClient) reconnect() {
 	}
 }
 
-// resubscribeAll re-establishes all active subscriptions
+// resubscribeAll re-establishes all active subscriptions. Server IDs from
+// the old connection are dropped and re-learned from the new acks.
 func (ws *WSClient) resubscribeAll() {
-	ws.mu.RLock()
-	subs := make(map[string]chan interface{})
-	for subID, ch := range ws.subscriptions {
-		subs[subID] = ch
-	}
-	ws.mu.RUnlock()
-
-	// Re-send subscription requests for each account
-	for account := range subs {
-		req := map[string]interface{}{
-			"jsonrpc": "2.0",
-			"id":      1,
-			"method":  "accountSubscribe",
-			"params": []interface{}{
-				account,
-				map[string]string{"encoding": "jsonParsed"},
-			},
-		}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
 
-		ws.mu.Lock()
-		if ws.conn != nil && ws.isConnected {
-			if err := ws.conn.WriteJSON(req); err != nil {
-				fmt.Printf("Failed to resubscribe to %s: %v\n", account, err)
-			} else {
-				fmt.Printf("Resubscribed to account: %s\n", account)
-			}
+	ws.pending = make(map[uint64]pendingSubscribe)
+	ws.byServerID = make(map[uint64]string)
+
+	for key, sub := range ws.subscriptions {
+		sub.hasServerID = false
+		if ws.conn == nil || !ws.isConnected {
+			continue
+		}
+		if err := ws.sendSubscribeLocked(key, sub); err != nil {
+			fmt.Printf("Failed to resubscribe to %s: %v\n", key, err)
+		} else {
+			fmt.Printf("Resubscribed to: %s\n", key)
 		}
-		ws.mu.Unlock()
 	}
 }
 
-// SubscribeAccount subscribes to account updates
-func (ws *WSClient) SubscribeAccount(ctx context.Context, account string) (<-chan interface{}, error) {
+// subscribe registers a subscription under key and sends the request
+func (ws *WSClient) subscribe(ctx context.Context, key, method string, params []interface{}, bufSize int) (<-chan interface{}, error) {
 	// Wait for rate limit
 	if err := ws.rpsLimiter.Wait(ctx); err != nil {
 		return nil, err
@@ -213,93 +278,143 @@ func (ws *WSClient) SubscribeAccount(ctx context.Context, account string) (<-cha
 		return nil, fmt.Errorf("websocket not connected")
 	}
 
-	// Create channel for this subscription
-	ch := make(chan interface{}, 100)
-	ws.subscriptions[account] = ch
+	// Replace any existing subscription for the same key
+	if old, exists := ws.subscriptions[key]; exists {
+		ws.removeLocked(key, old)
+	}
 
-	// Send subscription request
-	req := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "accountSubscribe",
-		"params": []interface{}{
-			account,
-			map[string]string{"encoding": "jsonParsed"},
-		},
+	sub := &wsSubscription{
+		ch:     make(chan interface{}, bufSize),
+		method: method,
+		params: params,
 	}
+	ws.subscriptions[key] = sub
 
-	if err := ws.conn.WriteJSON(req); err != nil {
-		delete(ws.subscriptions, account)
-		close(ch)
+	if err := ws.sendSubscribeLocked(key, sub); err != nil {
+		delete(ws.subscriptions, key)
+		close(sub.ch)
 		return nil, fmt.Errorf("failed to send subscription: %w", err)
 	}
 
-	return ch, nil
+	return sub.ch, nil
 }
 
-// SubscribeLogs subscribes to transaction logs for an address
-func (ws *WSClient) SubscribeLogs(ctx context.Context, mention string) (<-chan interface{}, error) {
-	// Wait for rate limit
-	if err := ws.rpsLimiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
-	ws.mu.Lock()
-	defer ws.mu.Unlock()
+// sendSubscribeLocked writes a subscribe request and records it as pending.
+// Caller must hold ws.mu.
+func (ws *WSClient) sendSubscribeLocked(key string, sub *wsSubscription) error {
+	ws.nextRequestID++
+	id := ws.nextRequestID
 
-	if !ws.isConnected {
-		return nil, fmt.Errorf("websocket not connected")
+	req := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  sub.method,
+		"params":  sub.params,
 	}
+	if err := ws.conn.WriteJSON(req); err != nil {
+		return err
+	}
+	ws.pending[id] = pendingSubscribe{key: key, sub: sub}
+	return nil
+}
 
-	// Create channel for this subscription
-	ch := make(chan interface{}, 100)
-	ws.subscriptions[mention] = ch
+// sendUnsubscribeLocked tells the server to drop a subscription.
+// Caller must hold ws.mu.
+func (ws *WSClient) sendUnsubscribeLocked(method string, serverID uint64) {
+	if ws.conn == nil || !ws.isConnected || method == "" {
+		return
+	}
 
-	// Send subscription request
+	ws.nextRequestID++
 	req := map[string]interface{}{
 		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "logsSubscribe",
-		"params": []interface{}{
-			map[string]interface{}{
-				"mentions": []string{mention},
-			},
-			map[string]string{"commitment": "finalized"},
-		},
+		"id":      ws.nextRequestID,
+		"method":  method,
+		"params":  []interface{}{serverID},
 	}
-
 	if err := ws.conn.WriteJSON(req); err != nil {
-		delete(ws.subscriptions, mention)
-		close(ch)
-		return nil, fmt.Errorf("failed to send subscription: %w", err)
+		fmt.Printf("Failed to send %s for %d: %v\n", method, serverID, err)
+	}
+}
+
+// removeLocked unsubscribes on the server and closes the channel.
+// Caller must hold ws.mu.
+func (ws *WSClient) removeLocked(key string, sub *wsSubscription) {
+	if sub.hasServerID {
+		ws.sendUnsubscribeLocked(unsubscribeMethod(sub.method), sub.serverID)
+		delete(ws.byServerID, sub.serverID)
+	}
+	close(sub.ch)
+	delete(ws.subscriptions, key)
+}
+
+// unsubscribeMethod maps a subscribe method to its unsubscribe counterpart
+func unsubscribeMethod(subscribeMethod string) string {
+	if !strings.HasSuffix(subscribeMethod, "Subscribe") {
+		return ""
 	}
+	return strings.TrimSuffix(subscribeMethod, "Subscribe") + "Unsubscribe"
+}
 
-	return ch, nil
+// SubscribeAccount subscribes to account updates
+func (ws *WSClient) SubscribeAccount(ctx context.Context, account string) (<-chan interface{}, error) {
+	return ws.subscribe(ctx, account, "accountSubscribe", []interface{}{
+		account,
+		map[string]string{"encoding": "jsonParsed"},
+	}, 100)
 }
 
-// Unsubscribe removes a subscription
+// SubscribeAccountData subscribes to raw base64 account data, for accounts
+// jsonParsed can't decode such as AMM pools
+func (ws *WSClient) SubscribeAccountData(ctx context.Context, account string) (<-chan interface{}, error) {
+	return ws.subscribe(ctx, accountDataKey(account), "accountSubscribe", []interface{}{
+		account,
+		map[string]string{"encoding": "base64", "commitment": "confirmed"},
+	}, 16)
+}
+
+// UnsubscribeAccountData drops a SubscribeAccountData subscription
+func (ws *WSClient) UnsubscribeAccountData(account string) {
+	ws.Unsubscribe(accountDataKey(account))
+}
+
+// accountDataKey keeps raw-data subscriptions apart from jsonParsed ones
+// for the same account
+func accountDataKey(account string) string {
+	return "data:" + account
+}
+
+// SubscribeLogs subscribes to transaction logs for an address
+func (ws *WSClient) SubscribeLogs(ctx context.Context, mention string) (<-chan interface{}, error) {
+	return ws.subscribe(ctx, mention, "logsSubscribe", []interface{}{
+		map[string]interface{}{
+			"mentions": []string{mention},
+		},
+		map[string]string{"commitment": "finalized"},
+	}, 100)
+}
+
+// Unsubscribe removes a subscription and tells the server to drop it
 func (ws *WSClient) Unsubscribe(account string) {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 
-	if ch, exists := ws.subscriptions[account]; exists {
-		close(ch)
-		delete(ws.subscriptions, account)
+	if sub, exists := ws.subscriptions[account]; exists {
+		ws.removeLocked(account, sub)
 	}
 }
 
-// Close closes the WebSocket connection
+// Close unsubscribes everything and closes the WebSocket connection
 func (ws *WSClient) Close() error {
 	close(ws.closeChan)
 
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 
-	// Close all subscription channels
-	for _, ch := range ws.subscriptions {
-		close(ch)
+	// Unsubscribe on the server and close all subscription channels
+	for key, sub := range ws.subscriptions {
+		ws.removeLocked(key, sub)
 	}
-	ws.subscriptions = make(map[string]chan interface{})
 
 	if ws.conn != nil {
 		return ws.conn.Close()
@@ -314,42 +429,32 @@ func (ws *WSClient) IsConnected() bool {
 	return ws.isConnected
 }
 
+// SubscribeTransactions subscribes to full transactions touching any of
+// accounts. transactionSubscribe is an enhanced-RPC method (Shyft/Helius),
+// not part of the standard Solana WebSocket API.
+func (ws *WSClient) SubscribeTransactions(ctx context.Context, key string, accounts []string) (<-chan interface{}, error) {
+	return ws.subscribe(ctx, key, "transactionSubscribe", []interface{}{
+		map[string]interface{}{
+			"accountInclude": accounts,
+			"failed":         false,
+			"vote":           false,
+		},
+		map[string]interface{}{
+			"commitment":                     "confirmed",
+			"encoding":                       "jsonParsed",
+			"transactionDetails":             "full",
+			"maxSupportedTransactionVersion": 0,
+		},
+	}, 50000)
+}
+
 // SubscribeProgramLogs subscribes to logs for a specific program
 func (ws *WSClient) SubscribeProgramLogs(ctx context.Context, programID string) (<-chan interface{}, error) {
-	// Wait for rate limit
-	if err := ws.rpsLimiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
-	ws.mu.Lock()
-	defer ws.mu.Unlock()
-
-	if !ws.isConnected {
-		return nil, fmt.Errorf("websocket not connected")
-	}
-
-	// Create channel for this subscription
-	ch := make(chan interface{}, 50000) // Large buffer for program logs
-	ws.subscriptions[programID] = ch
-
-	// Send subscription request
-	req := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "logsSubscribe",
-		"params": []interface{}{
-			map[string]interface{}{
-				"mentions": []string{programID},
-			},
-			map[string]string{"commitment": "processed"},
+	// Large buffer for program logs
+	return ws.subscribe(ctx, programID, "logsSubscribe", []interface{}{
+		map[string]interface{}{
+			"mentions": []string{programID},
 		},
-	}
-
-	if err := ws.conn.WriteJSON(req); err != nil {
-		delete(ws.subscriptions, programID)
-		close(ch)
-		return nil, fmt.Errorf("failed to send subscription: %w", err)
-	}
-
-	return ch, nil
+		map[string]string{"commitment": "processed"},
+	}, 50000)
 }
@@ -0,0 +1,186 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeRPCServer acks every subscribe with a fixed ID and records requests
+func fakeRPCServer(t *testing.T, subID uint64, received chan<- map[string]interface{}) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var req map[string]interface{}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			received <- req
+
+			method, _ := req["method"].(string)
+			if strings.HasSuffix(method, "Subscribe") {
+				conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req["id"], "result": subID})
+				conn.WriteJSON(map[string]interface{}{
+					"jsonrpc": "2.0",
+					"method":  strings.TrimSuffix(method, "Subscribe") + "Notification",
+					"params":  map[string]interface{}{"subscription": subID, "result": "hello"},
+				})
+			}
+		}
+	}))
+}
+
+// nextRequest waits for the fake server to receive a request
+func nextRequest(t *testing.T, received <-chan map[string]interface{}) map[string]interface{} {
+	select {
+	case req := <-received:
+		return req
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for request")
+		return nil
+	}
+}
+
+// TestWSClientUnsubscribe verifies acks are mapped and unsubscribes are sent
+func TestWSClientUnsubscribe(t *testing.T) {
+	received := make(chan map[string]interface{}, 10)
+	server := fakeRPCServer(t, 4242, received)
+	defer server.Close()
+
+	client := NewWSClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	ch, err := client.SubscribeLogs(ctx, "G4vTBDnAbBre4wqTpibXbLmwdVtFAbFCr2DM8t22UrmM")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if req := nextRequest(t, received); req["method"] != "logsSubscribe" {
+		t.Fatalf("Expected logsSubscribe, got %v", req["method"])
+	}
+
+	// The notification is only routable once the ack has been mapped
+	select {
+	case msg := <-ch:
+		data, _ := json.Marshal(msg)
+		if !strings.Contains(string(data), "hello") {
+			t.Errorf("Unexpected notification: %s", data)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+
+	client.Unsubscribe("G4vTBDnAbBre4wqTpibXbLmwdVtFAbFCr2DM8t22UrmM")
+
+	req := nextRequest(t, received)
+	if req["method"] != "logsUnsubscribe" {
+		t.Fatalf("Expected logsUnsubscribe, got %v", req["method"])
+	}
+	params, _ := req["params"].([]interface{})
+	if len(params) != 1 || params[0] != float64(4242) {
+		t.Errorf("Expected unsubscribe params [4242], got %v", req["params"])
+	}
+
+	if _, open := <-ch; open {
+		t.Error("Subscription channel should be closed")
+	}
+
+	client.Close()
+}
+
+// TestWSClientResubscribesLogsAfterReconnect drops the connection after
+// the first subscribe and checks the logsSubscribe is replayed as sent
+// and its notifications still reach the subscriber
+func TestWSClientResubscribesLogsAfterReconnect(t *testing.T) {
+	received := make(chan map[string]interface{}, 10)
+	var conns atomic.Uint64
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		n := conns.Add(1)
+
+		for {
+			var req map[string]interface{}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			received <- req
+
+			// Each connection hands out its own subscription ID
+			subID := 100 * n
+			conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req["id"], "result": subID})
+			conn.WriteJSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"method":  "logsNotification",
+				"params":  map[string]interface{}{"subscription": subID, "result": map[string]interface{}{"conn": n}},
+			})
+			if n == 1 {
+				return // drop the first connection
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := NewWSClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	client.reconnectDelay = 10 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	const program = "675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"
+	ch, err := client.SubscribeProgramLogs(ctx, program)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	first := nextRequest(t, received)
+
+	replayed := nextRequest(t, received)
+	if replayed["method"] != "logsSubscribe" {
+		t.Fatalf("Expected logsSubscribe replayed, got %v", replayed["method"])
+	}
+	want, _ := json.Marshal(first["params"])
+	got, _ := json.Marshal(replayed["params"])
+	if string(got) != string(want) || !strings.Contains(string(got), program) || !strings.Contains(string(got), "processed") {
+		t.Errorf("Expected params %s replayed, got %s", want, got)
+	}
+
+	// Notifications from both connections reach the same channel
+	for _, conn := range []string{`"conn":1`, `"conn":2`} {
+		select {
+		case msg := <-ch:
+			data, _ := json.Marshal(msg)
+			if !strings.Contains(string(data), conn) {
+				t.Errorf("Expected a notification from %s, got %s", conn, data)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for the notification from %s", conn)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pool defaults
+const (
+	DefaultFailoverAfter = 15 * time.Second
+	poolBufferSize       = 10000
+	poolConnectTimeout   = 10 * time.Second
+)
+
+// shardClient is the part of WSClient a pool shard needs
+type shardClient interface {
+	Connect(ctx context.Context) error
+	SubscribeTransactions(ctx context.Context, key string, accounts []string) (<-chan interface{}, error)
+	Unsubscribe(key string)
+	IsConnected() bool
+	Close() error
+}
+
+// ShardStatus reports the health of one pool connection. Endpoint is the
+// index into the configured URLs (0 is the primary) so API keys embedded
+// in URLs never reach health output.
+type ShardStatus struct {
+	ID          int  `json:"id"`
+	Endpoint    int  `json:"endpoint"`
+	UsingBackup bool `json:"using_backup"`
+	Connected   bool `json:"connected"`
+	Accounts    int  `json:"accounts"`
+	Failovers   int  `json:"failovers"`
+}
+
+// wsShard is one connection of the pool and the accounts assigned to it
+type wsShard struct {
+	id        int
+	endpoint  int
+	client    shardClient
+	accounts  int
+	failovers int
+	downSince time.Time
+}
+
+// poolSubscription is a logical subscription split across shards. Every
+// shard's notifications are merged into out.
+type poolSubscription struct {
+	out   chan interface{}
+	parts map[int][]string // shard ID -> accounts
+}
+
+// WSPool shards transaction subscriptions across several WebSocket
+// connections so one slow or dropped socket doesn't stall every program.
+// A shard that stays disconnected is failed over to the next configured
+// URL and its subscriptions are replayed there.
+type WSPool struct {
+	urls          []string // primary first, then backups
+	shardCount    int
+	failoverAfter time.Duration
+	checkInterval time.Duration
+	newClient     func(url string) shardClient
+
+	mu     sync.Mutex
+	shards []*wsShard
+	subs   map[string]*poolSubscription
+
+	wg        sync.WaitGroup
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWSPool creates a pool of shardCount connections to urls[0], failing
+// over to the remaining URLs in order
+func NewWSPool(urls []string, shardCount int, failoverAfter time.Duration) *WSPool {
+	if shardCount <= 0 {
+		shardCount = 1
+	}
+	if failoverAfter <= 0 {
+		failoverAfter = DefaultFailoverAfter
+	}
+	return &WSPool{
+		urls:          urls,
+		shardCount:    shardCount,
+		failoverAfter: failoverAfter,
+		checkInterval: failoverAfter / 3,
+		newClient:     func(url string) shardClient { return NewWSClient(url) },
+		subs:          make(map[string]*poolSubscription),
+		closeChan:     make(chan struct{}),
+	}
+}
+
+// Connect opens every shard, trying backup URLs for shards whose primary
+// is unreachable, and starts the failover monitor
+func (p *WSPool) Connect(ctx context.Context) error {
+	if len(p.urls) == 0 {
+		return fmt.Errorf("no websocket URLs configured")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := 0; i < p.shardCount; i++ {
+		shard := &wsShard{id: i}
+		var lastErr error
+		for endpoint := range p.urls {
+			client := p.newClient(p.urls[endpoint])
+			if err := client.Connect(ctx); err != nil {
+				lastErr = err
+				continue
+			}
+			shard.client = client
+			shard.endpoint = endpoint
+			break
+		}
+		if shard.client == nil {
+			for _, s := range p.shards {
+				s.client.Close()
+			}
+			p.shards = nil
+			return fmt.Errorf("shard %d: all endpoints failed: %w", i, lastErr)
+		}
+		p.shards = append(p.shards, shard)
+	}
+
+	p.wg.Add(1)
+	go p.monitor()
+	return nil
+}
+
+// SubscribeTransactions splits accounts across the least loaded shards and
+// returns one channel carrying notifications from all of them
+func (p *WSPool) SubscribeTransactions(ctx context.Context, key string, accounts []string) (<-chan interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.shards) == 0 {
+		return nil, fmt.Errorf("websocket pool not connected")
+	}
+	if _, exists := p.subs[key]; exists {
+		return nil, fmt.Errorf("subscription %q already exists", key)
+	}
+
+	sub := &poolSubscription{
+		out:   make(chan interface{}, poolBufferSize),
+		parts: p.partition(accounts),
+	}
+
+	// Forwarding starts only once every shard has subscribed, so a partial
+	// failure can be torn down without leaving readers on an unread channel
+	channels := make(map[*wsShard]<-chan interface{})
+	for _, shard := range p.shards {
+		part := sub.parts[shard.id]
+		if len(part) == 0 {
+			continue
+		}
+		ch, err := shard.client.SubscribeTransactions(ctx, shardKey(key, shard.id), part)
+		if err != nil {
+			for s := range channels {
+				s.client.Unsubscribe(shardKey(key, s.id))
+				s.accounts -= len(sub.parts[s.id])
+			}
+			return nil, fmt.Errorf("shard %d: %w", shard.id, err)
+		}
+		shard.accounts += len(part)
+		channels[shard] = ch
+	}
+	for _, ch := range channels {
+		p.forward(ch, sub.out)
+	}
+
+	p.subs[key] = sub
+	return sub.out, nil
+}
+
+// partition assigns each account to the shard with the fewest accounts.
+// Caller must hold p.mu.
+func (p *WSPool) partition(accounts []string) map[int][]string {
+	load := make(map[int]int, len(p.shards))
+	for _, s := range p.shards {
+		load[s.id] = s.accounts
+	}
+
+	parts := make(map[int][]string)
+	for _, account := range accounts {
+		best := p.shards[0].id
+		for _, s := range p.shards[1:] {
+			if load[s.id] < load[best] {
+				best = s.id
+			}
+		}
+		parts[best] = append(parts[best], account)
+		load[best]++
+	}
+	return parts
+}
+
+// forward copies a shard channel into a merged subscription channel until
+// the shard channel closes or the pool shuts down
+func (p *WSPool) forward(in <-chan interface{}, out chan<- interface{}) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for msg := range in {
+			select {
+			case out <- msg:
+			case <-p.closeChan:
+				return
+			}
+		}
+	}()
+}
+
+// monitor fails over shards that have been disconnected too long
+func (p *WSPool) monitor() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.closeChan:
+			return
+		case <-ticker.C:
+			p.checkShards(time.Now())
+		}
+	}
+}
+
+// checkShards records when shards went down and fails over the ones that
+// stayed down past failoverAfter
+func (p *WSPool) checkShards(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, shard := range p.shards {
+		if shard.client.IsConnected() {
+			shard.downSince = time.Time{}
+			continue
+		}
+		if shard.downSince.IsZero() {
+			shard.downSince = now
+			continue
+		}
+		if now.Sub(shard.downSince) < p.failoverAfter || len(p.urls) < 2 {
+			continue
+		}
+		if err := p.failoverLocked(shard); err != nil {
+			log.Printf("WS shard %d failover failed: %v", shard.id, err)
+			shard.downSince = now
+		}
+	}
+}
+
+// failoverLocked moves a shard to the next URL and replays its
+// subscriptions there. Caller must hold p.mu.
+func (p *WSPool) failoverLocked(shard *wsShard) error {
+	next := (shard.endpoint + 1) % len(p.urls)
+	client := p.newClient(p.urls[next])
+
+	ctx, cancel := context.WithTimeout(context.Background(), poolConnectTimeout)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("endpoint %d: %w", next, err)
+	}
+
+	keys := make([]string, 0, len(p.subs))
+	for key := range p.subs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	channels := make(map[string]<-chan interface{})
+	for _, key := range keys {
+		part := p.subs[key].parts[shard.id]
+		if len(part) == 0 {
+			continue
+		}
+		ch, err := client.SubscribeTransactions(ctx, shardKey(key, shard.id), part)
+		if err != nil {
+			client.Close()
+			return fmt.Errorf("endpoint %d: resubscribe %s: %w", next, key, err)
+		}
+		channels[key] = ch
+	}
+
+	// Closing the old client closes its channels, ending their forwarders
+	shard.client.Close()
+	for key, ch := range channels {
+		p.forward(ch, p.subs[key].out)
+	}
+
+	log.Printf("🔀 WS shard %d failed over from endpoint %d to %d", shard.id, shard.endpoint, next)
+	shard.client = client
+	shard.endpoint = next
+	shard.failovers++
+	shard.downSince = time.Time{}
+	return nil
+}
+
+// Status reports the state of every shard for health checks
+func (p *WSPool) Status() []ShardStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := make([]ShardStatus, 0, len(p.shards))
+	for _, s := range p.shards {
+		status = append(status, ShardStatus{
+			ID:          s.id,
+			Endpoint:    s.endpoint,
+			UsingBackup: s.endpoint != 0,
+			Connected:   s.client.IsConnected(),
+			Accounts:    s.accounts,
+			Failovers:   s.failovers,
+		})
+	}
+	return status
+}
+
+// Close shuts down every shard and closes the merged channels
+func (p *WSPool) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closeChan)
+
+		p.mu.Lock()
+		for _, s := range p.shards {
+			s.client.Close()
+		}
+		p.mu.Unlock()
+
+		p.wg.Wait()
+
+		p.mu.Lock()
+		for key, sub := range p.subs {
+			close(sub.out)
+			delete(p.subs, key)
+		}
+		p.mu.Unlock()
+	})
+	return nil
+}
+
+// shardKey names a subscription's part on one shard
+func shardKey(key string, shard int) string {
+	return fmt.Sprintf("%s#%d", key, shard)
+}
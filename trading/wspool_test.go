@@ -0,0 +1,234 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeShardClient is an in-memory shardClient whose connection state the
+// test controls
+type fakeShardClient struct {
+	url        string
+	rejectSubs bool // SubscribeTransactions returns an error
+
+	mu        sync.Mutex
+	connected bool
+	subs      map[string][]string
+	chans     map[string]chan interface{}
+	closed    bool
+}
+
+func (f *fakeShardClient) Connect(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.url == "down" {
+		return fmt.Errorf("dial %s refused", f.url)
+	}
+	f.connected = true
+	return nil
+}
+
+func (f *fakeShardClient) SubscribeTransactions(ctx context.Context, key string, accounts []string) (<-chan interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rejectSubs {
+		return nil, fmt.Errorf("subscribe %s rejected", key)
+	}
+	ch := make(chan interface{}, 10)
+	f.subs[key] = accounts
+	f.chans[key] = ch
+	return ch, nil
+}
+
+func (f *fakeShardClient) Unsubscribe(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ch, ok := f.chans[key]; ok {
+		close(ch)
+		delete(f.chans, key)
+		delete(f.subs, key)
+	}
+}
+
+func (f *fakeShardClient) IsConnected() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connected
+}
+
+func (f *fakeShardClient) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.closed {
+		f.closed = true
+		for _, ch := range f.chans {
+			close(ch)
+		}
+	}
+	return nil
+}
+
+func (f *fakeShardClient) setConnected(up bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connected = up
+}
+
+func (f *fakeShardClient) send(key string, msg interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chans[key] <- msg
+}
+
+// newFakePool builds a pool whose clients are recorded in creation order
+func newFakePool(urls []string, shards int) (*WSPool, *[]*fakeShardClient) {
+	var mu sync.Mutex
+	clients := &[]*fakeShardClient{}
+	p := NewWSPool(urls, shards, time.Hour)
+	p.newClient = func(url string) shardClient {
+		mu.Lock()
+		defer mu.Unlock()
+		c := &fakeShardClient{url: url, subs: make(map[string][]string), chans: make(map[string]chan interface{})}
+		*clients = append(*clients, c)
+		return c
+	}
+	return p, clients
+}
+
+func receive(t *testing.T, ch <-chan interface{}) interface{} {
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return nil
+	}
+}
+
+func TestWSPool(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ShardsAndMerges", func(t *testing.T) {
+		p, clients := newFakePool([]string{"primary"}, 3)
+		if err := p.Connect(ctx); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer p.Close()
+
+		out, err := p.SubscribeTransactions(ctx, "programs", []string{"a", "b", "c", "d", "e", "f", "g"})
+		if err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+
+		for _, s := range p.Status() {
+			if s.Accounts < 2 || s.Accounts > 3 {
+				t.Errorf("Shard %d unbalanced with %d accounts", s.ID, s.Accounts)
+			}
+		}
+
+		(*clients)[0].send("programs#0", "from0")
+		(*clients)[2].send("programs#2", "from2")
+		got := map[interface{}]bool{receive(t, out): true, receive(t, out): true}
+		if !got["from0"] || !got["from2"] {
+			t.Errorf("Expected messages from both shards, got %v", got)
+		}
+	})
+
+	t.Run("ConnectFallsBackToBackup", func(t *testing.T) {
+		p, _ := newFakePool([]string{"down", "backup"}, 1)
+		if err := p.Connect(ctx); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer p.Close()
+
+		if s := p.Status()[0]; !s.UsingBackup || !s.Connected {
+			t.Errorf("Expected shard on backup, got %+v", s)
+		}
+	})
+
+	t.Run("FailsOverDisconnectedShard", func(t *testing.T) {
+		p, clients := newFakePool([]string{"primary", "backup"}, 2)
+		if err := p.Connect(ctx); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer p.Close()
+
+		out, err := p.SubscribeTransactions(ctx, "programs", []string{"a", "b", "c", "d"})
+		if err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+
+		primary := (*clients)[1]
+		primary.setConnected(false)
+
+		now := time.Now()
+		p.checkShards(now)
+		if p.Status()[1].Failovers != 0 {
+			t.Fatal("Shard should not fail over before the grace period")
+		}
+		p.checkShards(now.Add(2 * time.Hour))
+
+		status := p.Status()[1]
+		if !status.UsingBackup || status.Failovers != 1 || !status.Connected {
+			t.Fatalf("Expected shard 1 on backup, got %+v", status)
+		}
+		if p.Status()[0].UsingBackup {
+			t.Error("Healthy shard should stay on primary")
+		}
+
+		backup := (*clients)[len(*clients)-1]
+		if len(backup.subs["programs#1"]) != 2 {
+			t.Errorf("Expected shard 1 accounts replayed on backup, got %v", backup.subs)
+		}
+
+		backup.send("programs#1", "after failover")
+		if msg := receive(t, out); msg != "after failover" {
+			t.Errorf("Unexpected message %v", msg)
+		}
+	})
+
+	t.Run("PartialFailureUnsubscribes", func(t *testing.T) {
+		p, clients := newFakePool([]string{"primary"}, 3)
+		if err := p.Connect(ctx); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer p.Close()
+
+		(*clients)[2].rejectSubs = true
+		if _, err := p.SubscribeTransactions(ctx, "programs", []string{"a", "b", "c"}); err == nil {
+			t.Fatal("Expected subscribe to fail when a shard rejects it")
+		}
+
+		for i, c := range *clients {
+			if len(c.subs) != 0 {
+				t.Errorf("Client %d kept subscriptions %v after rollback", i, c.subs)
+			}
+		}
+		for _, s := range p.Status() {
+			if s.Accounts != 0 {
+				t.Errorf("Shard %d kept %d accounts after rollback", s.ID, s.Accounts)
+			}
+		}
+
+		(*clients)[2].rejectSubs = false
+		if _, err := p.SubscribeTransactions(ctx, "programs", []string{"a", "b", "c"}); err != nil {
+			t.Errorf("Retry after rollback failed: %v", err)
+		}
+	})
+
+	t.Run("CloseClosesMergedChannel", func(t *testing.T) {
+		p, _ := newFakePool([]string{"primary"}, 2)
+		if err := p.Connect(ctx); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		out, _ := p.SubscribeTransactions(ctx, "programs", []string{"a", "b"})
+
+		p.Close()
+		if _, ok := <-out; ok {
+			t.Error("Expected merged channel closed")
+		}
+	})
+}
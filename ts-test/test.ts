@@ -2,8 +2,9 @@ import { Connection, PublicKey } from "@solana/web3.js";
 import { Metaplex } from "@metaplex-foundation/js";
 import axios from "axios";
 
-// Using the Shyft RPC URL provided by the user
-const RPC_URL = "https://rpc.shyft.to?api_key=48KZbYxP-9e9SpqR";
+// Shyft key comes from the environment, never from source
+const SHYFT_API_KEY = process.env.SOLORCH_SHYFT_API_KEY ?? "";
+const RPC_URL = `https://rpc.shyft.to?api_key=${SHYFT_API_KEY}`;
 const connection = new Connection(RPC_URL);
 
 /**
@@ -32,7 +33,7 @@ async function getTokenMetadata(mintAddress: string) {
         const priceUrl = `https://api.shyft.to/sol/v1/market/token_price?network=mainnet-beta&token_address=${mintAddress}`;
         try {
             const response = await axios.get(priceUrl, {
-                headers: { 'x-api-key': '48KZbYxP-9e9SpqR' }
+                headers: { 'x-api-key': SHYFT_API_KEY }
             });
 
             if (response.data.success) {